@@ -25,6 +25,7 @@ var (
 	Network            string
 	NetworkArgs        []string
 	DNS                string
+	DataFilesDir       string
 	Security           []string
 	CgroupsPath        string
 	VMRAM              string
@@ -42,6 +43,7 @@ var (
 	IsContainAll    bool
 	IsWritable      bool
 	IsWritableTmpfs bool
+	DefaultBinds    bool
 	Nvidia          bool
 	Rocm            bool
 	NoHome          bool
@@ -142,6 +144,29 @@ var actionWorkdirFlag = cmdline.Flag{
 	ExcludedOS:   []string{cmdline.Darwin},
 }
 
+// --default-binds
+var actionDefaultBindsFlag = cmdline.Flag{
+	ID:           "actionDefaultBindsFlag",
+	Value:        &DefaultBinds,
+	DefaultValue: false,
+	Name:         "default-binds",
+	Usage:        "apply default bind paths recorded in the image",
+	EnvKeys:      []string{"DEFAULT_BINDS"},
+	ExcludedOS:   []string{cmdline.Darwin},
+}
+
+// --datafiles
+var actionDataFilesFlag = cmdline.Flag{
+	ID:           "actionDataFilesFlag",
+	Value:        &DataFilesDir,
+	DefaultValue: "",
+	Name:         "datafiles",
+	Usage:        "expose the data files stored in the SIF image in this container directory",
+	EnvKeys:      []string{"DATAFILES"},
+	Tag:          "<path>",
+	ExcludedOS:   []string{cmdline.Darwin},
+}
+
 // --disable-cache
 var actionDisableCacheFlag = cmdline.Flag{
 	ID:           "actionDisableCacheFlag",
@@ -658,13 +683,15 @@ func init() {
 
 		cmdManager.RegisterFlagForCmd(&actionAddCapsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionAllowSetuidFlag, actionsInstanceCmd...)
-		cmdManager.RegisterFlagForCmd(&actionAppFlag, actionsCmd...)
+		cmdManager.RegisterFlagForCmd(&actionAppFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionApplyCgroupsFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionBindFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionCleanEnvFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainAllFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionContainLibsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDefaultBindsFlag, actionsInstanceCmd...)
+		cmdManager.RegisterFlagForCmd(&actionDataFilesFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDisableCacheFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDNSFlag, actionsInstanceCmd...)
 		cmdManager.RegisterFlagForCmd(&actionDropCapsFlag, actionsInstanceCmd...)
@@ -32,8 +32,12 @@ const (
 )
 
 func getCacheHandle(cfg cache.Config) *cache.Handle {
+	parentDir := cfg.ParentDir
+	if parentDir == "" {
+		parentDir = os.Getenv(cache.DirEnv)
+	}
 	h, err := cache.New(cache.Config{
-		ParentDir: os.Getenv(cache.DirEnv),
+		ParentDir: parentDir,
 		Disable:   cfg.Disable,
 	})
 	if err != nil {
@@ -67,7 +71,7 @@ func handleOCI(ctx context.Context, imgCache *cache.Handle, cmd *cobra.Command,
 	if err != nil {
 		sylog.Fatalf("While creating Docker credentials: %v", err)
 	}
-	return oci.Pull(ctx, imgCache, pullFrom, tmpDir, ociAuth, noHTTPS, false)
+	return oci.Pull(ctx, imgCache, pullFrom, "", "", tmpDir, ociAuth, noHTTPS, false)
 }
 
 func handleOras(ctx context.Context, imgCache *cache.Handle, cmd *cobra.Command, pullFrom string) (string, error) {
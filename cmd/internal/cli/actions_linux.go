@@ -347,6 +347,11 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 			engineConfig.SetEncryptionKey(plaintextKey)
 		}
 
+		// default bind paths recorded in the image are applied on
+		// explicit request only as any image could bind arbitrary
+		// host paths, user bind paths are mounted after them
+		BindPaths = append(defaultBindPaths(img, DefaultBinds), BindPaths...)
+
 		// don't defer this call as in all cases it won't be
 		// called before execing starter, so it would leak the
 		// image file descriptor to the container process
@@ -368,6 +373,12 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 	}
 	engineConfig.SetNetwork(Network)
 	engineConfig.SetDNS(DNS)
+	if DataFilesDir != "" {
+		if !filepath.IsAbs(DataFilesDir) {
+			sylog.Fatalf("--datafiles requires an absolute container path")
+		}
+		engineConfig.SetDataFilesDir(filepath.Clean(DataFilesDir))
+	}
 	engineConfig.SetNetworkArgs(NetworkArgs)
 	engineConfig.SetOverlayImage(OverlayPath)
 	engineConfig.SetWritableImage(IsWritable)
@@ -552,6 +563,7 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		}
 	}
 
+	var fileEnv []string
 	if SingularityEnvFile != "" {
 		currentEnv := append(
 			os.Environ(),
@@ -564,29 +576,17 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 			sylog.Fatalf("Could not read %q environment file: %s", SingularityEnvFile, err)
 		}
 
-		env, err := interpreter.EvaluateEnv(content, args, currentEnv)
+		fileEnv, err = interpreter.EvaluateEnv(content, args, currentEnv)
 		if err != nil {
 			sylog.Fatalf("While processing %s: %s", SingularityEnvFile, err)
 		}
-		// --env variables will take precedence over variables
-		// defined by the environment file
 		sylog.Debugf("Setting environment variables from file %s", SingularityEnvFile)
-		SingularityEnv = append(env, SingularityEnv...)
 	}
 
-	// process --env and --env-file variables for injection
-	// into the environment by prefixing them with SINGULARITYENV_
-	for _, env := range SingularityEnv {
-		e := strings.SplitN(env, "=", 2)
-		if len(e) != 2 {
-			sylog.Warningf("Ignore environment variable %q: '=' is missing", env)
-			continue
-		}
-		os.Setenv("SINGULARITYENV_"+e[0], e[1])
-	}
-
-	// Copy and cache environment
-	environment := os.Environ()
+	// Copy and cache environment, --env and --env-file variables are
+	// injected into the environment by prefixing them with SINGULARITYENV_,
+	// taking precedence over the host SINGULARITYENV_ variables
+	environment := env.ApplyOverrides(os.Environ(), fileEnv, SingularityEnv)
 
 	// Clean environment
 	singularityEnv := env.SetContainerEnv(generator, environment, IsCleanEnv, engineConfig.GetHomeDest())
@@ -748,3 +748,36 @@ func execStarter(cobraCmd *cobra.Command, image string, args []string, name stri
 		sylog.Fatalf("%s", err)
 	}
 }
+
+// defaultBindPaths returns the default bind paths recorded in the image
+// whose source exists on the host if apply is true. Missing sources are
+// skipped with a warning, unless the bind path is required. If apply is
+// false, the image default bind paths are only reported.
+func defaultBindPaths(img *imgutil.Image, apply bool) []string {
+	binds, err := imgutil.GetDefaultBinds(img)
+	if err != nil {
+		sylog.Warningf("Could not read default bind paths from %s: %s", img.Path, err)
+		return nil
+	} else if len(binds) == 0 {
+		return nil
+	}
+
+	if !apply {
+		sylog.Verbosef("Ignoring %d default bind path(s) recorded in %s, use --default-binds to apply them", len(binds), img.Path)
+		return nil
+	}
+
+	paths := make([]string, 0, len(binds))
+	for _, b := range binds {
+		if _, err := os.Stat(b.Source()); err != nil {
+			if b.Required {
+				sylog.Fatalf("Required default bind path %s: %s", b.Bind, err)
+			}
+			sylog.Warningf("Skipping default bind path %s: %s", b.Bind, err)
+			continue
+		}
+		sylog.Verbosef("Applying default bind path %s recorded in the image", b.Bind)
+		paths = append(paths, b.Bind)
+	}
+	return paths
+}
@@ -6,14 +6,22 @@
 package cli
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	ocitypes "github.com/containers/image/v5/types"
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/internal/pkg/client"
 	scs "github.com/sylabs/singularity/internal/pkg/remote"
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/internal/pkg/util/interactive"
@@ -21,23 +29,110 @@ import (
 	"github.com/sylabs/singularity/pkg/build/types/parser"
 	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 var buildArgs struct {
-	sections   []string
-	arch       string
-	builderURL string
-	libraryURL string
-	detached   bool
-	encrypt    bool
-	fakeroot   bool
-	fixPerms   bool
-	isJSON     bool
-	noCleanUp  bool
-	noTest     bool
-	remote     bool
-	sandbox    bool
-	update     bool
+	sections      []string
+	allowWarnings []string
+	annotations   []string
+	buildArgs     []string
+	defaultBinds  []string
+	dnsServers    []string
+	excludePaths  []string
+	fileCaps      []string
+	platforms     []string
+	authFile      string
+	argsFile      string
+	baseKeyring   string
+	buildContext  string
+	cacheDir      string
+	fakeGIDMap    string
+	fakeUIDMap    string
+	fsType        string
+	helpFile      string
+	httpProxy     string
+	httpsProxy    string
+	labelPrefix   string
+	labelsFile    string
+	logfile       string
+	mountDev      string
+	noProxy       string
+	outputFormat  string
+	partName      string
+	platformMan   string
+	secretsIgnore string
+	seccompProf   string
+	postHook      string
+	preHook       string
+	progress      string
+	shellFlags    string
+	sourceEpoch   string
+	tmpSandbox    string
+	writeDeffile  string
+	arch          string
+	builderURL    string
+	libraryURL    string
+	libraryHost   string
+	ociCmd        string
+	ociEntrypoint string
+	compress      string
+	compressLevel int
+	filesJobs     int
+	fsSize        int
+	jobs          int
+	logMaxSize    int
+	progressFd    int
+	maxDownload   int
+	maxSize       int
+	retryPost     int
+	timeout       int
+	warnSize      int
+	allowExec     bool
+	batch         bool
+	debugPost     bool
+	detached      bool
+	dryRun        bool
+	encrypt       bool
+	fakeroot      bool
+	fixPerms      bool
+	fromCache     bool
+	interactPost  bool
+	isJSON        bool
+	jsonReport    bool
+	keepDockerEnv bool
+	keepLayers    bool
+	layered       bool
+	libNoHTTPS    bool
+	mountDevPts   bool
+	mountProc     bool
+	mountSys      bool
+	net           bool
+	noNet         bool
+	netPost       bool
+	noNetPost     bool
+	netTest       bool
+	noNetTest     bool
+	noCleanUp     bool
+	cleanOnOK     bool
+	noClobber     bool
+	noRunCheck    bool
+	noRunWrap     bool
+	noTest        bool
+	remote        bool
+	rootOwner     bool
+	shubNoHTTPS   bool
+	sandbox       bool
+	scanSecrets   bool
+	secretsStrict bool
+	squash        bool
+	squashLayers  bool
+	stats         bool
+	strictPkgs    bool
+	update        bool
+	verifyBase    bool
+	verifyIdemp   bool
+	warnAsError   bool
 }
 
 // -s|--sandbox
@@ -47,10 +142,21 @@ var buildSandboxFlag = cmdline.Flag{
 	DefaultValue: false,
 	Name:         "sandbox",
 	ShortHand:    "s",
-	Usage:        "build image as sandbox format (chroot directory structure)",
+	Usage:        "build image as sandbox format (chroot directory structure), alias of --output-format sandbox",
 	EnvKeys:      []string{"SANDBOX"},
 }
 
+// --output-format
+var buildOutputFormatFlag = cmdline.Flag{
+	ID:           "buildOutputFormatFlag",
+	Value:        &buildArgs.outputFormat,
+	DefaultValue: "",
+	Name:         "output-format",
+	Usage:        "format of the built image: sif (default), sandbox, oci (OCI image layout directory) or tar (root filesystem archive)",
+	EnvKeys:      []string{"OUTPUT_FORMAT"},
+	Tag:          "<format>",
+}
+
 // --section
 var buildSectionFlag = cmdline.Flag{
 	ID:           "buildSectionFlag",
@@ -61,6 +167,254 @@ var buildSectionFlag = cmdline.Flag{
 	EnvKeys:      []string{"SECTION"},
 }
 
+// --allow-label-exec
+var buildAllowLabelExecFlag = cmdline.Flag{
+	ID:           "buildAllowLabelExecFlag",
+	Value:        &buildArgs.allowExec,
+	DefaultValue: false,
+	Name:         "allow-label-exec",
+	Usage:        "allow %labels values computed by a $(command) executed on the host",
+	EnvKeys:      []string{"ALLOW_LABEL_EXEC"},
+}
+
+// --annotate
+var buildAnnotateFlag = cmdline.Flag{
+	ID:           "buildAnnotateFlag",
+	Value:        &buildArgs.annotations,
+	DefaultValue: []string{},
+	Name:         "annotate",
+	Usage:        "add an annotation to the SIF image, a value holding valid JSON is stored as is, any other value as a string, shown by inspect --annotations",
+	EnvKeys:      []string{"ANNOTATE"},
+	Tag:          "<key=value>",
+}
+
+// --authfile
+var buildAuthFileFlag = cmdline.Flag{
+	ID:           "buildAuthFileFlag",
+	Value:        &buildArgs.authFile,
+	DefaultValue: "",
+	Name:         "authfile",
+	Usage:        "read registry credentials from a podman/skopeo compatible auth.json file (default $REGISTRY_AUTH_FILE)",
+	Tag:          "<path>",
+	EnvKeys:      []string{"AUTHFILE"},
+}
+
+// --layered
+var buildLayeredFlag = cmdline.Flag{
+	ID:           "buildLayeredFlag",
+	Value:        &buildArgs.layered,
+	DefaultValue: false,
+	Name:         "layered",
+	Usage:        "store %files/%post changes in an overlay partition on top of the immutable bootstrapped root filesystem",
+	EnvKeys:      []string{"LAYERED"},
+}
+
+// --keep-layers
+var buildKeepLayersFlag = cmdline.Flag{
+	ID:           "buildKeepLayersFlag",
+	Value:        &buildArgs.keepLayers,
+	DefaultValue: false,
+	Name:         "keep-layers",
+	Usage:        "store each layer of a docker/OCI source image in its own SIF partition, below the --layered overlay partition",
+	EnvKeys:      []string{"KEEP_LAYERS"},
+}
+
+// --squash
+var buildSquashFlag = cmdline.Flag{
+	ID:           "buildSquashFlag",
+	Value:        &buildArgs.squash,
+	DefaultValue: false,
+	Name:         "squash",
+	Usage:        "flatten the layers of a --layered or --keep-layers build in a single root filesystem partition",
+	EnvKeys:      []string{"SQUASH"},
+}
+
+// --squash-layers
+var buildSquashLayersFlag = cmdline.Flag{
+	ID:           "buildSquashLayersFlag",
+	Value:        &buildArgs.squashLayers,
+	DefaultValue: false,
+	Name:         "squash-layers",
+	Usage:        "flatten the layers of a --layered build in a single root filesystem partition",
+	EnvKeys:      []string{"SQUASH_LAYERS"},
+}
+
+// --net
+var buildNetFlag = cmdline.Flag{
+	ID:           "buildNetFlag",
+	Value:        &buildArgs.net,
+	DefaultValue: false,
+	Name:         "net",
+	Usage:        "allow network access from %post and %test sections, overriding --no-net set from the environment (default)",
+}
+
+// --no-net
+var buildNoNetFlag = cmdline.Flag{
+	ID:           "buildNoNetFlag",
+	Value:        &buildArgs.noNet,
+	DefaultValue: false,
+	Name:         "no-net",
+	Usage:        "run %post and %test sections without network access",
+	EnvKeys:      []string{"NO_NET"},
+}
+
+// --net-post
+var buildNetPostFlag = cmdline.Flag{
+	ID:           "buildNetPostFlag",
+	Value:        &buildArgs.netPost,
+	DefaultValue: false,
+	Name:         "net-post",
+	Usage:        "allow network access from the %post section, overriding --no-net",
+}
+
+// --no-net-post
+var buildNoNetPostFlag = cmdline.Flag{
+	ID:           "buildNoNetPostFlag",
+	Value:        &buildArgs.noNetPost,
+	DefaultValue: false,
+	Name:         "no-net-post",
+	Usage:        "run the %post section without network access, overriding --net",
+	EnvKeys:      []string{"NO_NET_POST"},
+}
+
+// --net-test
+var buildNetTestFlag = cmdline.Flag{
+	ID:           "buildNetTestFlag",
+	Value:        &buildArgs.netTest,
+	DefaultValue: false,
+	Name:         "net-test",
+	Usage:        "allow network access from the %test section, overriding --no-net",
+}
+
+// --no-net-test
+var buildNoNetTestFlag = cmdline.Flag{
+	ID:           "buildNoNetTestFlag",
+	Value:        &buildArgs.noNetTest,
+	DefaultValue: false,
+	Name:         "no-net-test",
+	Usage:        "run the %test section without network access, overriding --net",
+	EnvKeys:      []string{"NO_NET_TEST"},
+}
+
+// --bind-mount-proc
+var buildBindMountProcFlag = cmdline.Flag{
+	ID:           "buildBindMountProcFlag",
+	Value:        &buildArgs.mountProc,
+	DefaultValue: true,
+	Name:         "bind-mount-proc",
+	Usage:        "mount /proc in %post and %test sections, disable with --bind-mount-proc=false",
+}
+
+// --bind-mount-sys
+var buildBindMountSysFlag = cmdline.Flag{
+	ID:           "buildBindMountSysFlag",
+	Value:        &buildArgs.mountSys,
+	DefaultValue: true,
+	Name:         "bind-mount-sys",
+	Usage:        "mount /sys in %post and %test sections, disable with --bind-mount-sys=false",
+}
+
+// --bind-mount-dev
+var buildBindMountDevFlag = cmdline.Flag{
+	ID:           "buildBindMountDevFlag",
+	Value:        &buildArgs.mountDev,
+	DefaultValue: "yes",
+	Name:         "bind-mount-dev",
+	Usage:        "/dev mount of %post and %test sections: yes to bind the host /dev, minimal or no",
+	Tag:          "<yes|minimal|no>",
+}
+
+// --bind-mount-devpts
+var buildBindMountDevPtsFlag = cmdline.Flag{
+	ID:           "buildBindMountDevPtsFlag",
+	Value:        &buildArgs.mountDevPts,
+	DefaultValue: false,
+	Name:         "bind-mount-devpts",
+	Usage:        "mount a devpts instance in the minimal /dev of %post and %test sections",
+}
+
+// --logfile
+var buildLogfileFlag = cmdline.Flag{
+	ID:           "buildLogfileFlag",
+	Value:        &buildArgs.logfile,
+	DefaultValue: "",
+	Name:         "logfile",
+	Usage:        "write the build output to a log file with timestamped lines, in addition to the terminal",
+	Tag:          "<path>",
+}
+
+// --logfile-max-size
+var buildLogfileMaxSizeFlag = cmdline.Flag{
+	ID:           "buildLogfileMaxSizeFlag",
+	Value:        &buildArgs.logMaxSize,
+	DefaultValue: 0,
+	Name:         "logfile-max-size",
+	Usage:        "rotate the log file to <path>.1 once it exceeds this size in MiB, 0 means no rotation",
+	Tag:          "<MiB>",
+}
+
+// --post-build-hook
+var buildPostBuildHookFlag = cmdline.Flag{
+	ID:           "buildPostBuildHookFlag",
+	Value:        &buildArgs.postHook,
+	DefaultValue: "",
+	Name:         "post-build-hook",
+	Usage:        "run a shell command once the image is written, the build fails if the command fails",
+	EnvKeys:      []string{"POST_BUILD_HOOK"},
+	Tag:          "<command>",
+}
+
+// --pre-build-hook
+var buildPreBuildHookFlag = cmdline.Flag{
+	ID:           "buildPreBuildHookFlag",
+	Value:        &buildArgs.preHook,
+	DefaultValue: "",
+	Name:         "pre-build-hook",
+	Usage:        "run a shell command before building the image, the build is aborted if the command fails",
+	EnvKeys:      []string{"PRE_BUILD_HOOK"},
+	Tag:          "<command>",
+}
+
+// --max-download-size
+var buildMaxDownloadSizeFlag = cmdline.Flag{
+	ID:           "buildMaxDownloadSizeFlag",
+	Value:        &buildArgs.maxDownload,
+	DefaultValue: int(client.DefaultMaxDownloadSize >> 20),
+	Name:         "max-download-size",
+	Usage:        "maximum size in MiB of a bootstrap image download, 0 means no limit",
+	EnvKeys:      []string{"MAX_DOWNLOAD_SIZE"},
+}
+
+// --max-size
+var buildMaxSizeFlag = cmdline.Flag{
+	ID:           "buildMaxSizeFlag",
+	Value:        &buildArgs.maxSize,
+	DefaultValue: 0,
+	Name:         "max-size",
+	Usage:        "fail the build if the built image exceeds this size in MiB, 0 means no limit",
+	EnvKeys:      []string{"MAX_SIZE"},
+}
+
+// --warn-size
+var buildWarnSizeFlag = cmdline.Flag{
+	ID:           "buildWarnSizeFlag",
+	Value:        &buildArgs.warnSize,
+	DefaultValue: 0,
+	Name:         "warn-size",
+	Usage:        "warn if the built image exceeds this size in MiB, 0 means no limit",
+	EnvKeys:      []string{"WARN_SIZE"},
+}
+
+// --download-timeout
+var buildDownloadTimeoutFlag = cmdline.Flag{
+	ID:           "buildDownloadTimeoutFlag",
+	Value:        &buildArgs.timeout,
+	DefaultValue: int(client.DefaultDownloadTimeout / time.Second),
+	Name:         "download-timeout",
+	Usage:        "maximum duration in seconds of a bootstrap image download, 0 means no timeout",
+	EnvKeys:      []string{"DOWNLOAD_TIMEOUT"},
+}
+
 // --json
 var buildJSONFlag = cmdline.Flag{
 	ID:           "buildJSONFlag",
@@ -71,6 +425,46 @@ var buildJSONFlag = cmdline.Flag{
 	EnvKeys:      []string{"JSON"},
 }
 
+// --json-report
+var buildJSONReportFlag = cmdline.Flag{
+	ID:           "buildJSONReportFlag",
+	Value:        &buildArgs.jsonReport,
+	DefaultValue: false,
+	Name:         "json-report",
+	Usage:        "print a JSON build report with the image SHA-256 digest and UUID on standard output",
+	EnvKeys:      []string{"JSON_REPORT"},
+}
+
+// --stats
+var buildStatsFlag = cmdline.Flag{
+	ID:           "buildStatsFlag",
+	Value:        &buildArgs.stats,
+	DefaultValue: false,
+	Name:         "stats",
+	Usage:        "print the build resource usage (peak memory, CPU time, bytes downloaded and written, section wall times) at the end of the build",
+	EnvKeys:      []string{"STATS"},
+}
+
+// --progress
+var buildProgressFlag = cmdline.Flag{
+	ID:           "buildProgressFlag",
+	Value:        &buildArgs.progress,
+	DefaultValue: "text",
+	Name:         "progress",
+	Usage:        "progress output format: text, or json to stream newline-delimited JSON build events",
+	EnvKeys:      []string{"PROGRESS"},
+}
+
+// --progress-fd
+var buildProgressFdFlag = cmdline.Flag{
+	ID:           "buildProgressFdFlag",
+	Value:        &buildArgs.progressFd,
+	DefaultValue: 2,
+	Name:         "progress-fd",
+	Usage:        "file descriptor the --progress json events are written to",
+	EnvKeys:      []string{"PROGRESS_FD"},
+}
+
 // -u|--update
 var buildUpdateFlag = cmdline.Flag{
 	ID:           "buildUpdateFlag",
@@ -104,6 +498,16 @@ var buildRemoteFlag = cmdline.Flag{
 	EnvKeys:      []string{"REMOTE"},
 }
 
+// --root-owner
+var buildRootOwnerFlag = cmdline.Flag{
+	ID:           "buildRootOwnerFlag",
+	Value:        &buildArgs.rootOwner,
+	DefaultValue: false,
+	Name:         "root-owner",
+	Usage:        "store all files of oci and tar images as owned by root, as under --fakeroot",
+	EnvKeys:      []string{"ROOT_OWNER"},
+}
+
 // --arch
 var buildArchFlag = cmdline.Flag{
 	ID:           "buildArchFlag",
@@ -114,6 +518,28 @@ var buildArchFlag = cmdline.Flag{
 	EnvKeys:      []string{"BUILD_ARCH"},
 }
 
+// --platform
+var buildPlatformFlag = cmdline.Flag{
+	ID:           "buildPlatformFlag",
+	Value:        &buildArgs.platforms,
+	DefaultValue: []string{},
+	Name:         "platform",
+	Usage:        "build a multi-architecture SIF image with a root filesystem for each platform, as linux/amd64,linux/arm64",
+	EnvKeys:      []string{"PLATFORM"},
+	Tag:          "<platforms>",
+}
+
+// --platform-manifest
+var buildPlatformManifestFlag = cmdline.Flag{
+	ID:           "buildPlatformManifestFlag",
+	Value:        &buildArgs.platformMan,
+	DefaultValue: "",
+	Name:         "platform-manifest",
+	Usage:        "bootstrap each --platform image from the base image digest of a JSON file mapping platforms to digests, as {\"linux/amd64\": \"sha256:<hex>\"}",
+	EnvKeys:      []string{"PLATFORM_MANIFEST"},
+	Tag:          "<file>",
+}
+
 // -d|--detached
 var buildDetachedFlag = cmdline.Flag{
 	ID:           "buildDetachedFlag",
@@ -145,6 +571,37 @@ var buildLibraryFlag = cmdline.Flag{
 	EnvKeys:      []string{"LIBRARY"},
 }
 
+// --library-host
+var buildLibraryHostFlag = cmdline.Flag{
+	ID:           "buildLibraryHostFlag",
+	Value:        &buildArgs.libraryHost,
+	DefaultValue: "",
+	Name:         "library-host",
+	Usage:        "library host that library:// references without a host are resolved against",
+	EnvKeys:      []string{"LIBRARY_HOST"},
+	Tag:          "<host>",
+}
+
+// --library-no-https
+var buildLibraryNoHTTPSFlag = cmdline.Flag{
+	ID:           "buildLibraryNoHTTPSFlag",
+	Value:        &buildArgs.libNoHTTPS,
+	DefaultValue: false,
+	Name:         "library-no-https",
+	Usage:        "do NOT use HTTPS with the library:// bootstrap source (insecure, for internal test libraries)",
+	EnvKeys:      []string{"LIBRARY_NO_HTTPS"},
+}
+
+// --shub-no-https
+var buildShubNoHTTPSFlag = cmdline.Flag{
+	ID:           "buildShubNoHTTPSFlag",
+	Value:        &buildArgs.shubNoHTTPS,
+	DefaultValue: false,
+	Name:         "shub-no-https",
+	Usage:        "do NOT use HTTPS with the shub:// bootstrap source (insecure, for internal test registries)",
+	EnvKeys:      []string{"SHUB_NO_HTTPS"},
+}
+
 // --disable-cache
 var buildDisableCacheFlag = cmdline.Flag{
 	ID:           "buildDisableCacheFlag",
@@ -161,10 +618,101 @@ var buildNoCleanupFlag = cmdline.Flag{
 	Value:        &buildArgs.noCleanUp,
 	DefaultValue: false,
 	Name:         "no-cleanup",
-	Usage:        "do NOT clean up bundle after failed build, can be helpful for debugging",
+	Usage:        "do NOT clean up bundle after build and display its location, can be helpful for debugging failed builds",
 	EnvKeys:      []string{"NO_CLEANUP"},
 }
 
+// --cleanup-on-success-only
+var buildCleanupOnSuccessOnlyFlag = cmdline.Flag{
+	ID:           "buildCleanupOnSuccessOnlyFlag",
+	Value:        &buildArgs.cleanOnOK,
+	DefaultValue: false,
+	Name:         "cleanup-on-success-only",
+	Usage:        "clean up bundle after a successful build only, a failed build bundle is preserved and its location displayed",
+	EnvKeys:      []string{"CLEANUP_ON_SUCCESS_ONLY"},
+}
+
+// --http-proxy
+var buildHTTPProxyFlag = cmdline.Flag{
+	ID:           "buildHTTPProxyFlag",
+	Value:        &buildArgs.httpProxy,
+	DefaultValue: "",
+	Name:         "http-proxy",
+	Usage:        "proxy URL used for http requests of this build, overrides HTTP_PROXY",
+	EnvKeys:      []string{"BUILD_HTTP_PROXY"},
+}
+
+// --https-proxy
+var buildHTTPSProxyFlag = cmdline.Flag{
+	ID:           "buildHTTPSProxyFlag",
+	Value:        &buildArgs.httpsProxy,
+	DefaultValue: "",
+	Name:         "https-proxy",
+	Usage:        "proxy URL used for https requests of this build, overrides HTTPS_PROXY",
+	EnvKeys:      []string{"BUILD_HTTPS_PROXY"},
+}
+
+// --no-runscript-check
+var buildNoRunscriptCheckFlag = cmdline.Flag{
+	ID:           "buildNoRunscriptCheckFlag",
+	Value:        &buildArgs.noRunCheck,
+	DefaultValue: false,
+	Name:         "no-runscript-check",
+	Usage:        "do not warn when the built image has no runscript or a runscript not running anything",
+	EnvKeys:      []string{"NO_RUNSCRIPT_CHECK"},
+}
+
+// --no-runscript-wrap
+var buildNoRunscriptWrapFlag = cmdline.Flag{
+	ID:           "buildNoRunscriptWrapFlag",
+	Value:        &buildArgs.noRunWrap,
+	DefaultValue: false,
+	Name:         "no-runscript-wrap",
+	Usage:        "run the last command of the runscript and startscript with exec so that it receives the container signals",
+	EnvKeys:      []string{"NO_RUNSCRIPT_WRAP"},
+}
+
+// --no-proxy
+var buildNoProxyFlag = cmdline.Flag{
+	ID:           "buildNoProxyFlag",
+	Value:        &buildArgs.noProxy,
+	DefaultValue: "",
+	Name:         "no-proxy",
+	Usage:        "comma separated hosts and domains reached without proxy by this build, overrides NO_PROXY",
+	EnvKeys:      []string{"BUILD_NO_PROXY"},
+}
+
+// --no-clobber
+var buildNoClobberFlag = cmdline.Flag{
+	ID:           "buildNoClobberFlag",
+	Value:        &buildArgs.noClobber,
+	DefaultValue: false,
+	Name:         "no-clobber",
+	Usage:        "fail if the build target already exists instead of asking to overwrite it",
+	EnvKeys:      []string{"NO_CLOBBER"},
+}
+
+// --dns
+var buildDNSFlag = cmdline.Flag{
+	ID:           "buildDNSFlag",
+	Value:        &buildArgs.dnsServers,
+	DefaultValue: []string{},
+	Name:         "dns",
+	Usage:        "name server address used by %post and %test in place of the host resolv.conf (can be specified multiple times)",
+	EnvKeys:      []string{"DNS"},
+	Tag:          "<address>",
+}
+
+// --exclude-paths
+var buildExcludePathsFlag = cmdline.Flag{
+	ID:           "buildExcludePathsFlag",
+	Value:        &buildArgs.excludePaths,
+	DefaultValue: []string{},
+	Name:         "exclude-paths",
+	Usage:        "remove the container paths matching a glob pattern from the image before packaging (can be specified multiple times)",
+	EnvKeys:      []string{"EXCLUDE_PATHS"},
+}
+
 // --fakeroot
 var buildFakerootFlag = cmdline.Flag{
 	ID:           "buildFakerootFlag",
@@ -176,6 +724,26 @@ var buildFakerootFlag = cmdline.Flag{
 	EnvKeys:      []string{"FAKEROOT"},
 }
 
+// --fakeroot-gidmap
+var buildFakerootGIDMapFlag = cmdline.Flag{
+	ID:           "buildFakerootGIDMapFlag",
+	Value:        &buildArgs.fakeGIDMap,
+	DefaultValue: "",
+	Name:         "fakeroot-gidmap",
+	Usage:        "map the comma separated <container gid>:<size> ranges onto your subordinate GIDs with --fakeroot",
+	EnvKeys:      []string{"FAKEROOT_GIDMAP"},
+}
+
+// --fakeroot-uidmap
+var buildFakerootUIDMapFlag = cmdline.Flag{
+	ID:           "buildFakerootUIDMapFlag",
+	Value:        &buildArgs.fakeUIDMap,
+	DefaultValue: "",
+	Name:         "fakeroot-uidmap",
+	Usage:        "map the comma separated <container uid>:<size> ranges onto your subordinate UIDs with --fakeroot",
+	EnvKeys:      []string{"FAKEROOT_UIDMAP"},
+}
+
 // -e|--encrypt
 var buildEncryptFlag = cmdline.Flag{
 	ID:           "buildEncryptFlag",
@@ -186,6 +754,413 @@ var buildEncryptFlag = cmdline.Flag{
 	Usage:        "build an image with an encrypted file system",
 }
 
+// --compress
+var buildCompressFlag = cmdline.Flag{
+	ID:           "buildCompressFlag",
+	Value:        &buildArgs.compress,
+	DefaultValue: "gzip",
+	Name:         "compress",
+	Usage:        "squashfs compression algorithm for SIF images (gzip, lzo, xz, zstd, none)",
+	EnvKeys:      []string{"COMPRESS"},
+}
+
+// --compress-level
+var buildCompressLevelFlag = cmdline.Flag{
+	ID:           "buildCompressLevelFlag",
+	Value:        &buildArgs.compressLevel,
+	DefaultValue: 0,
+	Name:         "compress-level",
+	Usage:        "squashfs compression level for SIF images (0 uses the mksquashfs default)",
+	EnvKeys:      []string{"COMPRESS_LEVEL"},
+}
+
+// --build-arg
+var buildBuildArgFlag = cmdline.Flag{
+	ID:           "buildBuildArgFlag",
+	Value:        &buildArgs.buildArgs,
+	DefaultValue: []string{},
+	Name:         "build-arg",
+	Usage:        "define a KEY=VALUE build argument substituted to {{ KEY }} references in %help",
+	EnvKeys:      []string{"BUILD_ARG"},
+}
+
+// --build-args-file
+var buildBuildArgsFileFlag = cmdline.Flag{
+	ID:           "buildBuildArgsFileFlag",
+	Value:        &buildArgs.argsFile,
+	DefaultValue: "",
+	Name:         "build-args-file",
+	Usage:        "read build arguments from a file of KEY=VALUE lines, --build-arg values take precedence",
+	EnvKeys:      []string{"BUILD_ARGS_FILE"},
+	Tag:          "<path>",
+}
+
+// --cache-dir
+var buildCacheDirFlag = cmdline.Flag{
+	ID:           "buildCacheDirFlag",
+	Value:        &buildArgs.cacheDir,
+	DefaultValue: "",
+	Name:         "cache-dir",
+	Usage:        "use the image cache of this directory for this build instead of the default cache",
+	EnvKeys:      []string{"CACHEDIR"},
+	Tag:          "<path>",
+}
+
+// --build-context
+var buildContextFlag = cmdline.Flag{
+	ID:           "buildContextFlag",
+	Value:        &buildArgs.buildContext,
+	DefaultValue: "",
+	Name:         "build-context",
+	Usage:        "resolve relative %files sources and %include paths from the given directory",
+	EnvKeys:      []string{"BUILD_CONTEXT"},
+}
+
+// --help-file
+var buildHelpFileFlag = cmdline.Flag{
+	ID:           "buildHelpFileFlag",
+	Value:        &buildArgs.helpFile,
+	DefaultValue: "",
+	Name:         "help-file",
+	Usage:        "use the content of the given file as container help, overrides %help section",
+	EnvKeys:      []string{"HELP_FILE"},
+}
+
+// --label-prefix
+var buildLabelPrefixFlag = cmdline.Flag{
+	ID:           "buildLabelPrefixFlag",
+	Value:        &buildArgs.labelPrefix,
+	DefaultValue: "",
+	Name:         "label-prefix",
+	Usage:        "prefix the names of the %labels section and labels file labels with a reverse domain name, as com.example.",
+	EnvKeys:      []string{"LABEL_PREFIX"},
+	Tag:          "<prefix>",
+}
+
+// --labels-file
+var buildLabelsFileFlag = cmdline.Flag{
+	ID:           "buildLabelsFileFlag",
+	Value:        &buildArgs.labelsFile,
+	DefaultValue: "",
+	Name:         "labels-file",
+	Usage:        "add the labels of a JSON file holding an object of string values, overrides %labels section",
+	EnvKeys:      []string{"LABELS_FILE"},
+}
+
+// --default-bind
+var buildDefaultBindFlag = cmdline.Flag{
+	ID:           "buildDefaultBindFlag",
+	Value:        &buildArgs.defaultBinds,
+	DefaultValue: []string{},
+	Name:         "default-bind",
+	Usage:        "record a bind path applied by default when running the SIF image, spec has the format src[:dest[:opts]], add the 'required' option to abort execution if src doesn't exist on the host",
+	EnvKeys:      []string{"DEFAULT_BIND"},
+	Tag:          "<spec>",
+}
+
+// --debug-post
+var buildDebugPostFlag = cmdline.Flag{
+	ID:           "buildDebugPostFlag",
+	Value:        &buildArgs.debugPost,
+	DefaultValue: false,
+	Name:         "debug-post",
+	Usage:        "trace commands executed by %setup, %post and %test sections",
+	EnvKeys:      []string{"DEBUG_POST"},
+}
+
+// --section-shell-flags
+var buildSectionShellFlagsFlag = cmdline.Flag{
+	ID:           "buildSectionShellFlagsFlag",
+	Value:        &buildArgs.shellFlags,
+	DefaultValue: "",
+	Name:         "section-shell-flags",
+	Usage:        "shell flags applied to the %pre, %setup, %post and %test sections, as for example \"-uo pipefail\"",
+	EnvKeys:      []string{"SECTION_SHELL_FLAGS"},
+	Tag:          "<flags>",
+}
+
+// --setcap
+var buildSetcapFlag = cmdline.Flag{
+	ID:           "buildSetcapFlag",
+	Value:        &buildArgs.fileCaps,
+	DefaultValue: []string{},
+	Name:         "setcap",
+	Usage:        "set file capabilities on a container file before packaging, spec has the format path=caps[+flags] as /usr/bin/server=cap_net_bind_service+ep (can be specified multiple times)",
+	EnvKeys:      []string{"SETCAP"},
+	Tag:          "<spec>",
+}
+
+// --verify-base
+var buildVerifyBaseFlag = cmdline.Flag{
+	ID:           "buildVerifyBaseFlag",
+	Value:        &buildArgs.verifyBase,
+	DefaultValue: false,
+	Name:         "verify-base",
+	Usage:        "verify the signature of library and docker bootstrap images with the keys of --base-keyring before using them",
+	EnvKeys:      []string{"VERIFY_BASE"},
+}
+
+// --verify-idempotent
+var buildVerifyIdempotentFlag = cmdline.Flag{
+	ID:           "buildVerifyIdempotentFlag",
+	Value:        &buildArgs.verifyIdemp,
+	DefaultValue: false,
+	Name:         "verify-idempotent",
+	Usage:        "run %post and each %post:NAME fragment a second time and fail if it changes the root filesystem, reporting the changed files (testing aid)",
+	EnvKeys:      []string{"VERIFY_IDEMPOTENT"},
+}
+
+// --base-keyring
+var buildBaseKeyringFlag = cmdline.Flag{
+	ID:           "buildBaseKeyringFlag",
+	Value:        &buildArgs.baseKeyring,
+	DefaultValue: "",
+	Name:         "base-keyring",
+	Usage:        "public keyring file used by --verify-base to verify the bootstrap image signature",
+	EnvKeys:      []string{"BASE_KEYRING"},
+	Tag:          "<path>",
+}
+
+// --source-date-epoch
+var buildSourceDateEpochFlag = cmdline.Flag{
+	ID:           "buildSourceDateEpochFlag",
+	Value:        &buildArgs.sourceEpoch,
+	DefaultValue: "",
+	Name:         "source-date-epoch",
+	Usage:        "Unix time recorded in the image build date label and timestamps instead of the current time, defaults to the SOURCE_DATE_EPOCH environment variable",
+	Tag:          "<seconds>",
+}
+
+// --fs
+var buildFsFlag = cmdline.Flag{
+	ID:           "buildFsFlag",
+	Value:        &buildArgs.fsType,
+	DefaultValue: "squashfs",
+	Name:         "fs",
+	Usage:        "file system of the SIF root filesystem partition: squashfs, or ext3 for a writable partition",
+	EnvKeys:      []string{"FS"},
+}
+
+// --fs-size
+var buildFsSizeFlag = cmdline.Flag{
+	ID:           "buildFsSizeFlag",
+	Value:        &buildArgs.fsSize,
+	DefaultValue: 0,
+	Name:         "fs-size",
+	Usage:        "size in MiB of an ext3 root filesystem partition, computed from the root filesystem size when 0",
+	EnvKeys:      []string{"FS_SIZE"},
+}
+
+// --partition-name
+var buildPartitionNameFlag = cmdline.Flag{
+	ID:           "buildPartitionNameFlag",
+	Value:        &buildArgs.partName,
+	DefaultValue: "",
+	Name:         "partition-name",
+	Usage:        "name of the SIF root filesystem partition",
+	EnvKeys:      []string{"PARTITION_NAME"},
+}
+
+// --from-cache-only
+var buildFromCacheOnlyFlag = cmdline.Flag{
+	ID:           "buildFromCacheOnlyFlag",
+	Value:        &buildArgs.fromCache,
+	DefaultValue: false,
+	Name:         "from-cache-only",
+	Usage:        "build from images already in the cache, without network access to their source",
+	EnvKeys:      []string{"FROM_CACHE_ONLY"},
+}
+
+// --files-jobs
+var buildFilesJobsFlag = cmdline.Flag{
+	ID:           "buildFilesJobsFlag",
+	Value:        &buildArgs.filesJobs,
+	DefaultValue: 1,
+	Name:         "files-jobs",
+	Usage:        "copy up to N %files entries concurrently, entries with overlapping destinations are copied in order",
+	EnvKeys:      []string{"FILES_JOBS"},
+	Tag:          "<N>",
+}
+
+// --interactive-post
+var buildInteractivePostFlag = cmdline.Flag{
+	ID:           "buildInteractivePostFlag",
+	Value:        &buildArgs.interactPost,
+	DefaultValue: false,
+	Name:         "interactive-post",
+	Usage:        "start an interactive shell in the build root filesystem when the %post section fails, exit with status 0 to resume the build, ignored if standard input is not a terminal",
+	EnvKeys:      []string{"INTERACTIVE_POST"},
+}
+
+// --retry-post
+var buildRetryPostFlag = cmdline.Flag{
+	ID:           "buildRetryPostFlag",
+	Value:        &buildArgs.retryPost,
+	DefaultValue: 0,
+	Name:         "retry-post",
+	Usage:        "run the %post section again up to N times with a backoff when it fails, only for idempotent %post sections",
+	EnvKeys:      []string{"RETRY_POST"},
+	Tag:          "<N>",
+}
+
+// --scan-secrets
+var buildScanSecretsFlag = cmdline.Flag{
+	ID:           "buildScanSecretsFlag",
+	Value:        &buildArgs.scanSecrets,
+	DefaultValue: false,
+	Name:         "scan-secrets",
+	Usage:        "scan the built image for secrets like private keys and access tokens, each one raising a W024 build warning",
+	EnvKeys:      []string{"SCAN_SECRETS"},
+}
+
+// --scan-secrets-ignore
+var buildScanSecretsIgnoreFlag = cmdline.Flag{
+	ID:           "buildScanSecretsIgnoreFlag",
+	Value:        &buildArgs.secretsIgnore,
+	DefaultValue: "",
+	Name:         "scan-secrets-ignore",
+	Usage:        "file of container path glob patterns, one per line, not scanned by --scan-secrets",
+	EnvKeys:      []string{"SCAN_SECRETS_IGNORE"},
+	Tag:          "<path>",
+}
+
+// --scan-secrets-strict
+var buildScanSecretsStrictFlag = cmdline.Flag{
+	ID:           "buildScanSecretsStrictFlag",
+	Value:        &buildArgs.secretsStrict,
+	DefaultValue: false,
+	Name:         "scan-secrets-strict",
+	Usage:        "fail the build when --scan-secrets finds secrets",
+	EnvKeys:      []string{"SCAN_SECRETS_STRICT"},
+}
+
+// --strict-packages
+var buildStrictPackagesFlag = cmdline.Flag{
+	ID:           "buildStrictPackagesFlag",
+	Value:        &buildArgs.strictPkgs,
+	DefaultValue: false,
+	Name:         "strict-packages",
+	Usage:        "fail yum and zypper bootstraps reporting missing packages or unreachable repositories",
+	EnvKeys:      []string{"STRICT_PACKAGES"},
+}
+
+// --tmp-sandbox
+var buildTmpSandboxFlag = cmdline.Flag{
+	ID:           "buildTmpSandboxFlag",
+	Value:        &buildArgs.tmpSandbox,
+	DefaultValue: "",
+	Name:         "tmp-sandbox",
+	Usage:        "directory where a SIF image is written before being moved to its destination (default: the destination directory)",
+	EnvKeys:      []string{"TMP_SANDBOX"},
+	Tag:          "<path>",
+}
+
+// --seccomp-profile
+var buildSeccompProfileFlag = cmdline.Flag{
+	ID:           "buildSeccompProfileFlag",
+	Value:        &buildArgs.seccompProf,
+	DefaultValue: "",
+	Name:         "seccomp-profile",
+	Usage:        "apply an OCI seccomp profile to the %pre, %setup, %post and %test sections",
+	EnvKeys:      []string{"SECCOMP_PROFILE"},
+	Tag:          "<path>",
+}
+
+// --dry-run
+var buildDryRunFlag = cmdline.Flag{
+	ID:           "buildDryRunFlag",
+	Value:        &buildArgs.dryRun,
+	DefaultValue: false,
+	Name:         "dry-run",
+	Usage:        "validate the definition file without building the image",
+	EnvKeys:      []string{"DRY_RUN"},
+}
+
+// --write-deffile
+var buildWriteDeffileFlag = cmdline.Flag{
+	ID:           "buildWriteDeffileFlag",
+	Value:        &buildArgs.writeDeffile,
+	DefaultValue: "",
+	Name:         "write-deffile",
+	Usage:        "write the resolved definition embedded in the image to a file",
+	EnvKeys:      []string{"WRITE_DEFFILE"},
+	Tag:          "<path>",
+}
+
+// --batch
+var buildBatchFlag = cmdline.Flag{
+	ID:           "buildBatchFlag",
+	Value:        &buildArgs.batch,
+	DefaultValue: false,
+	Name:         "batch",
+	Usage:        "build an image in the output directory for each definition file (*.def) found in the source directory",
+	EnvKeys:      []string{"BATCH"},
+}
+
+// -j|--jobs
+var buildJobsFlag = cmdline.Flag{
+	ID:           "buildJobsFlag",
+	Value:        &buildArgs.jobs,
+	DefaultValue: 1,
+	Name:         "jobs",
+	ShortHand:    "j",
+	Usage:        "maximum number of images built concurrently with --batch",
+	EnvKeys:      []string{"JOBS"},
+}
+
+// --oci-entrypoint
+var buildOCIEntrypointFlag = cmdline.Flag{
+	ID:           "buildOCIEntrypointFlag",
+	Value:        &buildArgs.ociEntrypoint,
+	DefaultValue: "",
+	Name:         "oci-entrypoint",
+	Usage:        "override the ENTRYPOINT of docker/oci source images, an empty value clears it (%runscript takes precedence)",
+	EnvKeys:      []string{"OCI_ENTRYPOINT"},
+	Tag:          "<command>",
+}
+
+// --oci-cmd
+var buildOCICmdFlag = cmdline.Flag{
+	ID:           "buildOCICmdFlag",
+	Value:        &buildArgs.ociCmd,
+	DefaultValue: "",
+	Name:         "oci-cmd",
+	Usage:        "override the CMD of docker/oci source images, an empty value clears it (%runscript takes precedence)",
+	EnvKeys:      []string{"OCI_CMD"},
+	Tag:          "<command>",
+}
+
+// --keep-docker-env
+var buildKeepDockerEnvFlag = cmdline.Flag{
+	ID:           "buildKeepDockerEnvFlag",
+	Value:        &buildArgs.keepDockerEnv,
+	DefaultValue: true,
+	Name:         "keep-docker-env",
+	Usage:        "keep the environment variables of docker/oci source images, use --keep-docker-env=false to discard them",
+	EnvKeys:      []string{"KEEP_DOCKER_ENV"},
+}
+
+// --warn-as-error
+var buildWarnAsErrorFlag = cmdline.Flag{
+	ID:           "buildWarnAsErrorFlag",
+	Value:        &buildArgs.warnAsError,
+	DefaultValue: false,
+	Name:         "warn-as-error",
+	Usage:        "abort the build on any build warning, except those allowed with --allow-warning",
+	EnvKeys:      []string{"WARN_AS_ERROR"},
+}
+
+// --allow-warning
+var buildAllowWarningFlag = cmdline.Flag{
+	ID:           "buildAllowWarningFlag",
+	Value:        &buildArgs.allowWarnings,
+	DefaultValue: []string{},
+	Name:         "allow-warning",
+	Usage:        "build warning, identified by its code (e.g. W001) or full identifier, not promoted to error with --warn-as-error",
+	EnvKeys:      []string{"ALLOW_WARNING"},
+	Tag:          "<id>",
+}
+
 // TODO: Deprecate at 3.6, remove at 3.8
 // --fix-perms
 var buildFixPermsFlag = cmdline.Flag{
@@ -201,21 +1176,107 @@ func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(buildCmd)
 
+		cmdManager.RegisterFlagForCmd(&buildAllowWarningFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildArchFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBatchFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAllowLabelExecFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAnnotateFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildAuthFileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBaseKeyringFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBindMountDevFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBindMountDevPtsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBindMountProcFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBindMountSysFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildBuilderFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBuildArgFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildBuildArgsFileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildCacheDirFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildContextFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildCompressFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildCompressLevelFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildDetachedFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildDisableCacheFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildDebugPostFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildDefaultBindFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildDNSFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildDownloadTimeoutFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildDryRunFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildEncryptFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildExcludePathsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFakerootFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFakerootGIDMapFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFakerootUIDMapFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFilesJobsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildFixPermsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFromCacheOnlyFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildFsSizeFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildHelpFileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildHTTPProxyFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildHTTPSProxyFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildInteractivePostFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildJobsFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildJSONFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildJSONReportFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildKeepDockerEnvFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildKeepLayersFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildLabelPrefixFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildLabelsFileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildLayeredFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildLibraryFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildLibraryHostFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildLibraryNoHTTPSFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildLogfileFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildLogfileMaxSizeFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildMaxDownloadSizeFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildMaxSizeFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNetFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNetPostFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNetTestFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoCleanupFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildCleanupOnSuccessOnlyFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoClobberFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoNetFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoNetPostFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoNetTestFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoProxyFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoRunscriptCheckFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildNoRunscriptWrapFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildNoTestFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildOCICmdFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildOCIEntrypointFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildPartitionNameFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildPlatformFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildPlatformManifestFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildPostBuildHookFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildPreBuildHookFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildProgressFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildProgressFdFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildRemoteFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildRetryPostFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildRootOwnerFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildOutputFormatFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildSandboxFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildScanSecretsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildScanSecretsIgnoreFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildScanSecretsStrictFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSeccompProfileFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildSectionFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSectionShellFlagsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSetcapFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildShubNoHTTPSFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSourceDateEpochFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSquashFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildSquashLayersFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildStatsFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildStrictPackagesFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildTmpSandboxFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&buildUpdateFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildVerifyBaseFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildVerifyIdempotentFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildWarnAsErrorFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildWarnSizeFlag, buildCmd)
+		cmdManager.RegisterFlagForCmd(&buildWriteDeffileFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonForceFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, buildCmd)
 		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, buildCmd)
@@ -244,10 +1305,19 @@ var buildCmd = &cobra.Command{
 }
 
 func preRun(cmd *cobra.Command, args []string) {
+	// the fakeroot build process runs as root without --fakeroot
+	if (buildArgs.fakeUIDMap != "" || buildArgs.fakeGIDMap != "") && !buildArgs.fakeroot && os.Getuid() != 0 {
+		sylog.Fatalf("--fakeroot-uidmap and --fakeroot-gidmap require --fakeroot")
+	}
+
 	if buildArgs.fakeroot && !buildArgs.remote {
 		fakerootExec(args)
 	}
 
+	if err := setBuildProxy(); err != nil {
+		sylog.Fatalf("While setting build proxy: %v", err)
+	}
+
 	// Always perform remote build when builder flag is set
 	if cmd.Flags().Lookup("builder").Changed {
 		cmd.Flags().Lookup("remote").Value.Set("true")
@@ -256,6 +1326,50 @@ func preRun(cmd *cobra.Command, args []string) {
 	sylabsToken(cmd, args)
 }
 
+// buildCacheHandle returns the image cache handle of the build, using
+// the --cache-dir directory, created if absent, before the directory set
+// by the SINGULARITY_CACHEDIR environment variable and the default cache.
+func buildCacheHandle() *cache.Handle {
+	cfg := cache.Config{Disable: disableCache}
+	if buildArgs.cacheDir != "" {
+		dir, err := fs.Abs(buildArgs.cacheDir)
+		if err != nil {
+			sylog.Fatalf("Failed to get absolute path of cache directory %s: %s", buildArgs.cacheDir, err)
+		}
+		cfg.ParentDir = dir
+	}
+
+	imgCache := getCacheHandle(cfg)
+	if imgCache == nil {
+		sylog.Fatalf("Failed to create an image cache handle")
+	}
+	if buildArgs.cacheDir != "" && !disableCache && imgCache.IsDisabled() {
+		sylog.Fatalf("Cache directory %s is not writable", cfg.ParentDir)
+	}
+	return imgCache
+}
+
+// checkOutputFormat checks the --output-format value, sif by default,
+// --sandbox being an alias of the sandbox format.
+func checkOutputFormat() error {
+	switch buildArgs.outputFormat {
+	case "", "sif", "sandbox", "oci", "tar":
+	default:
+		return fmt.Errorf("unknown output format %q, must be sif, sandbox, oci or tar", buildArgs.outputFormat)
+	}
+	if buildArgs.sandbox {
+		if buildArgs.outputFormat != "" && buildArgs.outputFormat != "sandbox" {
+			return fmt.Errorf("--sandbox can't be used with --output-format %s", buildArgs.outputFormat)
+		}
+		buildArgs.outputFormat = "sandbox"
+	}
+	if buildArgs.outputFormat == "" {
+		buildArgs.outputFormat = "sif"
+	}
+	buildArgs.sandbox = buildArgs.outputFormat == "sandbox"
+	return nil
+}
+
 // checkBuildTarget makes sure output target doesn't exist, or is ok to overwrite.
 // And checks that update flag will update an existing directory.
 func checkBuildTarget(path string) error {
@@ -267,7 +1381,13 @@ func checkBuildTarget(path string) error {
 	if !buildArgs.sandbox && buildArgs.update {
 		return fmt.Errorf("only sandbox update is supported: --sandbox flag is missing")
 	}
+	if buildArgs.noClobber && (forceOverwrite || buildArgs.update) {
+		return fmt.Errorf("--no-clobber can't be used with --force or --update")
+	}
 	if f, err := os.Stat(abspath); err == nil {
+		if buildArgs.noClobber {
+			return fmt.Errorf("build target %s already exists and --no-clobber is set", abspath)
+		}
 		if buildArgs.update && !f.IsDir() {
 			return fmt.Errorf("only sandbox update is supported: %s is not a directory", abspath)
 		}
@@ -292,6 +1412,10 @@ func checkBuildTarget(path string) error {
 			}
 		}
 		if !buildArgs.update && !forceOverwrite {
+			// don't destroy images when nobody can be asked
+			if !terminal.IsTerminal(int(os.Stdin.Fd())) {
+				return fmt.Errorf("build target %s already exists, use --force to overwrite it", abspath)
+			}
 
 			question := fmt.Sprintf("Build target '%s' already exists and will be deleted during the build process. Do you want to continue? [N/y]", f.Name())
 
@@ -317,6 +1441,19 @@ func checkBuildTarget(path string) error {
 // definitionFromSpec is specifically for parsing specs for the remote builder
 // it uses a different version the the definition struct and parser
 func definitionFromSpec(spec string) (types.Definition, error) {
+	// read definition from standard input
+	if spec == "-" {
+		raw, err := parser.ExpandIncludesReader(os.Stdin, ".")
+		if err != nil {
+			return types.Definition{}, err
+		}
+		raw, err = parser.ResolveConditions(raw, buildArgs.arch, nil)
+		if err != nil {
+			return types.Definition{}, err
+		}
+		return parser.ParseDefinitionFile(bytes.NewReader(raw))
+	}
+
 	// Try spec as URI first
 	def, err := types.NewDefinitionFromURI(spec)
 	if err == nil {
@@ -332,16 +1469,19 @@ func definitionFromSpec(spec string) (types.Definition, error) {
 
 	if isValid {
 		sylog.Debugf("Found valid definition: %s\n", spec)
-		// File exists and contains valid definition
-		var defFile *os.File
-		defFile, err = os.Open(spec)
+		// File exists and contains valid definition, the remote
+		// builder receives it with included fragments expanded and
+		// conditional sections resolved for the requested architecture
+		raw, err := parser.ExpandIncludes(spec)
+		if err != nil {
+			return types.Definition{}, err
+		}
+		raw, err = parser.ResolveConditions(raw, buildArgs.arch, nil)
 		if err != nil {
 			return types.Definition{}, err
 		}
 
-		defer defFile.Close()
-
-		return parser.ParseDefinitionFile(defFile)
+		return parser.ParseDefinitionFile(bytes.NewReader(raw))
 	}
 
 	// File exists and does NOT contain a valid definition
@@ -392,8 +1532,187 @@ func makeDockerCredentials(cmd *cobra.Command) (authConf *ocitypes.DockerAuthCon
 	return nil, nil
 }
 
+// registryAuthFile returns the path of the registry auth file set with
+// --authfile or the REGISTRY_AUTH_FILE environment variable, if any.
+func registryAuthFile() string {
+	path := buildArgs.authFile
+	if path == "" {
+		path = os.Getenv("REGISTRY_AUTH_FILE")
+	}
+	if path == "" {
+		return ""
+	}
+	if _, err := os.Stat(path); err != nil {
+		sylog.Fatalf("Could not access registry auth file: %s", err)
+	}
+	return path
+}
+
+// buildReport is the build report printed with --json-report.
+type buildReport struct {
+	Image  string `json:"image"`
+	SHA256 string `json:"sha256,omitempty"`
+	UUID   string `json:"uuid,omitempty"`
+	// Sources are the resolved references of the library and docker
+	// bootstrap images, with the default host, registry and tag applied.
+	Sources []string `json:"sources,omitempty"`
+	// Stats is the resource usage of the build, set with --stats.
+	Stats *buildStats `json:"stats,omitempty"`
+}
+
+// buildStats is the resource usage of a local build reported with --stats.
+type buildStats struct {
+	// PeakMemory is the maximum resident set size in bytes of the
+	// build process and of its largest child process.
+	PeakMemory int64 `json:"peak_memory"`
+	// CPUTime is the user and system CPU time in seconds of the build
+	// process and of its children.
+	CPUTime float64 `json:"cpu_time"`
+	// Downloaded is the number of bytes downloaded by the bootstrap
+	// agents and for the %files URL sources.
+	Downloaded int64 `json:"downloaded"`
+	// Written is the number of bytes of the file systems and images
+	// written by the packaging steps.
+	Written int64 `json:"written"`
+	// Sections are the wall times of the definition file sections.
+	Sections []progress.SectionStat `json:"sections,omitempty"`
+}
+
+// reportBuild displays the digest and UUID of the built SIF image, if
+// any, and the resource usage with --stats, and prints the JSON build
+// report when requested.
+func reportBuild(report buildReport) {
+	if report.SHA256 != "" {
+		sylog.Infof("Image SHA256: %s", report.SHA256)
+		sylog.Infof("Image UUID: %s", report.UUID)
+	}
+	if s := report.Stats; s != nil {
+		sylog.Infof("Peak memory: %d bytes", s.PeakMemory)
+		sylog.Infof("CPU time: %.2fs", s.CPUTime)
+		sylog.Infof("Downloaded: %d bytes", s.Downloaded)
+		sylog.Infof("Written: %d bytes", s.Written)
+		for _, sec := range s.Sections {
+			name := "%" + sec.Section
+			if sec.Stage != "" {
+				name = sec.Stage + " " + name
+			}
+			sylog.Infof("Section %s: %.2fs", name, sec.Duration)
+		}
+	}
+
+	if !buildArgs.jsonReport {
+		return
+	}
+	b, err := json.MarshalIndent(report, "", "\t")
+	if err != nil {
+		sylog.Fatalf("Could not format build report: %s", err)
+	}
+	fmt.Println(string(b))
+}
+
+// remoteImageDigest returns the digest and UUID of the SIF image
+// downloaded by a remote build, nothing is returned for images
+// pushed to the library or not downloaded.
+func remoteImageDigest(dest string) (string, string) {
+	if buildArgs.outputFormat != "sif" || buildArgs.detached || strings.HasPrefix(dest, "library://") {
+		return "", ""
+	}
+	d, err := singularity.GetSIFDigest(dest)
+	if err != nil {
+		sylog.Warningf("Could not compute image digest: %s", err)
+		return "", ""
+	}
+	return d.SHA256, d.UUID
+}
+
+// remoteUnsupportedFlags lists the build flags
+// which are not supported by remote builds.
+var remoteUnsupportedFlags = []string{
+	"allow-label-exec",
+	"allow-warning",
+	"annotate",
+	"authfile",
+	"base-keyring",
+	"bind-mount-dev",
+	"bind-mount-devpts",
+	"bind-mount-proc",
+	"bind-mount-sys",
+	"build-arg",
+	"build-args-file",
+	"build-context",
+	"compress",
+	"compress-level",
+	"debug-post",
+	"default-bind",
+	"dns",
+	"download-timeout",
+	"dry-run",
+	"exclude-paths",
+	"fakeroot-gidmap",
+	"fakeroot-uidmap",
+	"files-jobs",
+	"from-cache-only",
+	"fs",
+	"fs-size",
+	"help-file",
+	"http-proxy",
+	"https-proxy",
+	"interactive-post",
+	"keep-docker-env",
+	"keep-layers",
+	"label-prefix",
+	"labels-file",
+	"layered",
+	"library-host",
+	"library-no-https",
+	"max-download-size",
+	"max-size",
+	"net",
+	"net-post",
+	"net-test",
+	"no-net",
+	"no-net-post",
+	"no-net-test",
+	"no-proxy",
+	"no-runscript-check",
+	"no-runscript-wrap",
+	"oci-cmd",
+	"oci-entrypoint",
+	"partition-name",
+	"platform",
+	"platform-manifest",
+	"progress",
+	"progress-fd",
+	"retry-post",
+	"root-owner",
+	"scan-secrets",
+	"scan-secrets-ignore",
+	"scan-secrets-strict",
+	"seccomp-profile",
+	"section-shell-flags",
+	"setcap",
+	"shub-no-https",
+	"source-date-epoch",
+	"squash",
+	"squash-layers",
+	"stats",
+	"strict-packages",
+	"tmp-sandbox",
+	"verify-base",
+	"verify-idempotent",
+	"warn-as-error",
+	"warn-size",
+	"write-deffile",
+}
+
 // remote builds need to fail if we cannot resolve remote URLS
 func handleRemoteBuildFlags(cmd *cobra.Command) {
+	for _, name := range remoteUnsupportedFlags {
+		if cmd.Flags().Lookup(name).Changed {
+			sylog.Fatalf("--%s is not supported with remote builds", name)
+		}
+	}
+
 	// if we can load config and if default endpoint is set, use that
 	// otherwise fall back on regular authtoken and URI behavior
 	endpoint, err := sylabsRemote(remoteConfig)
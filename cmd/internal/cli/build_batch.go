@@ -0,0 +1,182 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	osExec "os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// batchBuild holds the state of a single image build
+// performed as part of a batch build.
+type batchBuild struct {
+	name     string
+	def      string
+	dest     string
+	log      string
+	duration time.Duration
+	err      error
+}
+
+// batchExcludedFlags are the build flags not passed to the
+// build of each image of a batch build.
+var batchExcludedFlags = map[string]bool{
+	"batch": true,
+	"jobs":  true,
+}
+
+// runBuildBatch builds a SIF image, or a sandbox with --sandbox, in outDir
+// for each definition file found in defDir. Each image is built by a separate
+// singularity process with the same flags as the batch build, up to
+// buildArgs.jobs builds are running concurrently. Existing images in outDir
+// are overwritten. The image cache is shared by all builds, fetches into
+// the OCI cache are serialized by the builds themselves.
+func runBuildBatch(cmd *cobra.Command, outDir, defDir string) {
+	if buildArgs.jobs < 1 {
+		sylog.Fatalf("--jobs must be greater than 0")
+	}
+	if cmd.Flags().Lookup("passphrase").Changed {
+		sylog.Fatalf("--passphrase is not supported with --batch, use SINGULARITY_ENCRYPTION_PASSPHRASE instead")
+	}
+
+	defs, err := filepath.Glob(filepath.Join(defDir, "*.def"))
+	if err != nil {
+		sylog.Fatalf("While looking for definition files: %s", err)
+	} else if len(defs) == 0 {
+		sylog.Fatalf("No definition file found in %s", defDir)
+	}
+
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		sylog.Fatalf("While creating output directory: %s", err)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		sylog.Fatalf("Could not determine singularity executable path: %s", err)
+	}
+	globalArgs := changedFlagsArgs(cmd.Root().PersistentFlags(), batchExcludedFlags)
+	buildFlagsArgs := changedFlagsArgs(cmd.Flags(), batchExcludedFlags)
+	// builds are not interactive, don't prompt for existing images
+	if !forceOverwrite && !buildArgs.update && !buildArgs.noClobber {
+		buildFlagsArgs = append(buildFlagsArgs, "--force")
+	}
+
+	builds := make([]*batchBuild, len(defs))
+	for i, def := range defs {
+		name := strings.TrimSuffix(filepath.Base(def), ".def")
+		dest := filepath.Join(outDir, name)
+		switch buildArgs.outputFormat {
+		case "sif":
+			dest += ".sif"
+		case "tar":
+			dest += ".tar"
+		}
+		builds[i] = &batchBuild{
+			name: name,
+			def:  def,
+			dest: dest,
+			log:  filepath.Join(outDir, name+".log"),
+		}
+	}
+
+	sylog.Infof("Building %d image(s) with %d job(s)", len(builds), buildArgs.jobs)
+
+	var wg sync.WaitGroup
+	jobs := make(chan *batchBuild)
+
+	for i := 0; i < buildArgs.jobs; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for b := range jobs {
+				args := append([]string{}, globalArgs...)
+				args = append(args, "build")
+				args = append(args, buildFlagsArgs...)
+				args = append(args, b.dest, b.def)
+
+				sylog.Infof("Building %s from %s", b.dest, b.def)
+				start := time.Now()
+				b.err = runBatchBuildCmd(exe, args, b.log)
+				b.duration = time.Since(start)
+				if b.err != nil {
+					sylog.Errorf("Build of %s failed, see %s", b.name, b.log)
+				}
+			}
+		}()
+	}
+
+	for _, b := range builds {
+		jobs <- b
+	}
+	close(jobs)
+	wg.Wait()
+
+	failed := 0
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(tw, "IMAGE\tSTATUS\tDURATION\tLOG\n")
+	for _, b := range builds {
+		status := "OK"
+		if b.err != nil {
+			status = "FAILED"
+			failed++
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", b.dest, status, b.duration.Round(time.Second), b.log)
+	}
+	tw.Flush()
+
+	if failed > 0 {
+		sylog.Errorf("%d of %d build(s) failed", failed, len(builds))
+		os.Exit(1)
+	}
+}
+
+// runBatchBuildCmd executes the singularity build command with args,
+// the command output is written to the logFile.
+func runBatchBuildCmd(exe string, args []string, logFile string) error {
+	f, err := os.Create(logFile)
+	if err != nil {
+		return fmt.Errorf("while creating log file: %s", err)
+	}
+	defer f.Close()
+
+	cmd := osExec.Command(exe, args...)
+	cmd.Stdout = f
+	cmd.Stderr = f
+
+	return cmd.Run()
+}
+
+// changedFlagsArgs returns the command line arguments corresponding
+// to the flags explicitly set in the flag set, except the excluded ones.
+func changedFlagsArgs(flags *pflag.FlagSet, excluded map[string]bool) []string {
+	var args []string
+
+	flags.Visit(func(f *pflag.Flag) {
+		if excluded[f.Name] {
+			return
+		}
+		if sv, ok := f.Value.(pflag.SliceValue); ok {
+			for _, v := range sv.GetSlice() {
+				args = append(args, fmt.Sprintf("--%s=%s", f.Name, v))
+			}
+			return
+		}
+		args = append(args, fmt.Sprintf("--%s=%s", f.Name, f.Value.String()))
+	})
+
+	return args
+}
@@ -18,6 +18,18 @@ func fakerootExec(cmdArgs []string) {
 }
 
 func runBuild(cmd *cobra.Command, args []string) {
+	if err := checkOutputFormat(); err != nil {
+		sylog.Fatalf("While checking output format: %v", err)
+	}
+	if buildArgs.outputFormat != "sif" {
+		sylog.Fatalf("Only SIF images can be built on this platform")
+	}
+
+	if buildArgs.batch {
+		runBuildBatch(cmd, args[0], args[1])
+		return
+	}
+
 	dest := args[0]
 	spec := args[1]
 
@@ -32,6 +44,8 @@ func runBuild(cmd *cobra.Command, args []string) {
 
 	handleRemoteBuildFlags(cmd)
 
+	runPreBuildHook(dest, spec)
+
 	// Submiting a remote build requires a valid authToken
 	if authToken == "" {
 		sylog.Fatalf("Unable to submit build job: %v", remoteWarning)
@@ -51,4 +65,9 @@ func runBuild(cmd *cobra.Command, args []string) {
 	if err != nil {
 		sylog.Fatalf("While performing build: %v", err)
 	}
+
+	report := buildReport{Image: dest}
+	report.SHA256, report.UUID = remoteImageDigest(dest)
+	reportBuild(report)
+	runPostBuildHook(spec, report)
 }
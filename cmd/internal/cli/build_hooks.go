@@ -0,0 +1,74 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+	osExec "os/exec"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+const (
+	preBuildHook  = "pre-build"
+	postBuildHook = "post-build"
+)
+
+// runBuildHook executes the hook command with /bin/sh, the image path
+// is passed as first argument. The SINGULARITY_BUILD_HOOK,
+// SINGULARITY_BUILD_SPEC and SINGULARITY_BUILD_IMAGE environment
+// variables hold the hook stage, the build spec and the image path, the
+// post-build hook also gets the image digest and UUID with
+// SINGULARITY_BUILD_SHA256 and SINGULARITY_BUILD_UUID.
+func runBuildHook(stage, hook, spec string, report buildReport) error {
+	sylog.Infof("Running %s hook: %s", stage, hook)
+
+	cmd := osExec.Command("/bin/sh", "-c", hook, stage+"-hook", report.Image)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(),
+		"SINGULARITY_BUILD_HOOK="+stage,
+		"SINGULARITY_BUILD_SPEC="+spec,
+		"SINGULARITY_BUILD_IMAGE="+report.Image,
+	)
+	if stage == postBuildHook {
+		cmd.Env = append(cmd.Env,
+			"SINGULARITY_BUILD_SHA256="+report.SHA256,
+			"SINGULARITY_BUILD_UUID="+report.UUID,
+		)
+	}
+
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s hook failed: %s", stage, err)
+	}
+	return nil
+}
+
+// runPreBuildHook executes the --pre-build-hook command, if any, a
+// failure aborts the build.
+func runPreBuildHook(dest, spec string) {
+	if buildArgs.preHook == "" {
+		return
+	}
+	if err := runBuildHook(preBuildHook, buildArgs.preHook, spec, buildReport{Image: dest}); err != nil {
+		sylog.Fatalf("%s, aborting build", err)
+	}
+}
+
+// runPostBuildHook executes the --post-build-hook command, if any, once
+// the image is written. A failure doesn't remove the image but makes the
+// build command fail, allowing the hook to veto the image publication
+// by the caller.
+func runPostBuildHook(spec string, report buildReport) {
+	if buildArgs.postHook == "" {
+		return
+	}
+	if err := runBuildHook(postBuildHook, buildArgs.postHook, spec, report); err != nil {
+		sylog.Fatalf("%s, image %s was written", err, report.Image)
+	}
+}
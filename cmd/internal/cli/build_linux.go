@@ -7,31 +7,50 @@ package cli
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
 	osExec "os/exec"
+	"path/filepath"
 	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/internal/pkg/build"
+	"github.com/sylabs/singularity/internal/pkg/build/assemblers"
 	"github.com/sylabs/singularity/internal/pkg/build/remotebuilder"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
-	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/internal/pkg/client/library"
+	"github.com/sylabs/singularity/internal/pkg/fakeroot"
+	"github.com/sylabs/singularity/internal/pkg/plugin"
 	scs "github.com/sylabs/singularity/internal/pkg/remote"
+	"github.com/sylabs/singularity/internal/pkg/runtime/engine/config/oci/generate"
 	fakerootConfig "github.com/sylabs/singularity/internal/pkg/runtime/engine/fakeroot/config"
+	"github.com/sylabs/singularity/internal/pkg/security/seccomp"
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/internal/pkg/util/interactive"
+	"github.com/sylabs/singularity/internal/pkg/util/shell"
 	"github.com/sylabs/singularity/internal/pkg/util/starter"
 	"github.com/sylabs/singularity/internal/pkg/util/user"
 	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/build/types/parser"
 	"github.com/sylabs/singularity/pkg/image"
+	buildcallback "github.com/sylabs/singularity/pkg/plugin/callback/build"
 	"github.com/sylabs/singularity/pkg/runtime/engine/config"
 	"github.com/sylabs/singularity/pkg/sylog"
 	"github.com/sylabs/singularity/pkg/util/crypt"
+	"github.com/sylabs/singularity/pkg/util/singularityconf"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
+// noSpaceExitCode is the exit status of builds running out of disk
+// space while packaging the image, the ENOSPC errno value.
+const noSpaceExitCode = 28
+
 func fakerootExec(cmdArgs []string) {
 	useSuid := buildcfg.SINGULARITY_SUID_INSTALL == 1
 
@@ -70,6 +89,18 @@ func fakerootExec(cmdArgs []string) {
 		Home:     user.Dir,
 		BuildEnv: true,
 	}
+	if buildArgs.fakeUIDMap != "" {
+		engineConfig.UIDMap, err = fakeroot.ParseIDMap(buildArgs.fakeUIDMap)
+		if err != nil {
+			sylog.Fatalf("While parsing --fakeroot-uidmap: %s", err)
+		}
+	}
+	if buildArgs.fakeGIDMap != "" {
+		engineConfig.GIDMap, err = fakeroot.ParseIDMap(buildArgs.fakeGIDMap)
+		if err != nil {
+			sylog.Fatalf("While parsing --fakeroot-gidmap: %s", err)
+		}
+	}
 
 	cfg := &config.Common{
 		EngineName:   fakerootConfig.Name,
@@ -88,10 +119,106 @@ func fakerootExec(cmdArgs []string) {
 func runBuild(cmd *cobra.Command, args []string) {
 	ctx := context.TODO()
 
+	if buildArgs.logfile != "" {
+		runBuildLogged(cmd, args)
+		return
+	}
+
+	if err := setBuildProgress(); err != nil {
+		sylog.Fatalf("While setting build progress: %v", err)
+	}
+	if err := checkOutputFormat(); err != nil {
+		sylog.Fatalf("While checking output format: %v", err)
+	}
+
 	if buildArgs.arch != runtime.GOARCH && !buildArgs.remote {
 		sylog.Fatalf("Requested architecture (%s) does not match host (%s). Cannot build locally.", buildArgs.arch, runtime.GOARCH)
 	}
 
+	if buildArgs.maxDownload < 0 || buildArgs.timeout < 0 {
+		sylog.Fatalf("--max-download-size and --download-timeout must be positive values")
+	}
+	if buildArgs.fsSize < 0 {
+		sylog.Fatalf("--fs-size must be a positive value")
+	}
+	if buildArgs.maxSize < 0 || buildArgs.warnSize < 0 {
+		sylog.Fatalf("--max-size and --warn-size must be positive values")
+	}
+	if buildArgs.retryPost < 0 {
+		sylog.Fatalf("--retry-post must be a positive value")
+	}
+	if buildArgs.filesJobs < 1 {
+		sylog.Fatalf("--files-jobs must be greater than 0")
+	}
+	if buildArgs.seccompProf != "" {
+		buildArgs.seccompProf = seccompProfile(buildArgs.seccompProf)
+	}
+
+	if buildArgs.squash {
+		buildArgs.squashLayers = true
+	}
+	if buildArgs.squashLayers && !buildArgs.layered && !buildArgs.keepLayers {
+		sylog.Fatalf("--squash-layers requires --layered or --keep-layers")
+	}
+	if buildArgs.layered && (buildArgs.outputFormat != "sif" || buildArgs.encrypt) {
+		sylog.Fatalf("--layered is only supported with unencrypted SIF images")
+	}
+	if buildArgs.keepLayers && (buildArgs.outputFormat != "sif" || buildArgs.encrypt) {
+		sylog.Fatalf("--keep-layers is only supported with unencrypted SIF images")
+	}
+	if len(buildArgs.annotations) > 0 && buildArgs.outputFormat != "sif" && buildArgs.outputFormat != "oci" {
+		sylog.Fatalf("--annotate is only supported with SIF and OCI images")
+	}
+	if buildArgs.rootOwner && buildArgs.outputFormat != "oci" && buildArgs.outputFormat != "tar" {
+		sylog.Fatalf("--root-owner is only supported with OCI images and tar archives")
+	}
+	if buildArgs.encrypt && (buildArgs.outputFormat == "oci" || buildArgs.outputFormat == "tar") {
+		sylog.Fatalf("--encrypt is not supported with %s images", buildArgs.outputFormat)
+	}
+	if buildArgs.platformMan != "" && len(buildArgs.platforms) == 0 {
+		sylog.Fatalf("--platform-manifest requires --platform")
+	}
+	if buildArgs.writeDeffile != "" && (buildArgs.batch || len(buildArgs.platforms) > 0) {
+		sylog.Fatalf("--write-deffile is not supported with --batch or --platform")
+	}
+	if (buildArgs.secretsStrict || buildArgs.secretsIgnore != "") && !buildArgs.scanSecrets {
+		sylog.Fatalf("--scan-secrets-strict and --scan-secrets-ignore require --scan-secrets")
+	}
+	if buildArgs.interactPost && !terminal.IsTerminal(int(os.Stdin.Fd())) {
+		sylog.Verbosef("Standard input is not a terminal, ignoring --interactive-post")
+		buildArgs.interactPost = false
+	}
+	if buildArgs.stats && buildArgs.batch {
+		sylog.Fatalf("--stats is not supported with --batch")
+	}
+
+	switch buildArgs.mountDev {
+	case "yes", "minimal", "no":
+	default:
+		sylog.Fatalf("--bind-mount-dev must be yes, minimal or no")
+	}
+	if buildArgs.mountDevPts && buildArgs.mountDev != "minimal" {
+		sylog.Fatalf("--bind-mount-devpts requires --bind-mount-dev minimal")
+	}
+
+	// --net takes precedence over --no-net, usually set from the environment
+	if buildArgs.net {
+		buildArgs.noNet = false
+	}
+	// section flags take precedence over both
+	buildArgs.noNetPost = sectionNoNet("post", buildArgs.netPost, buildArgs.noNetPost)
+	buildArgs.noNetTest = sectionNoNet("test", buildArgs.netTest, buildArgs.noNetTest)
+
+	if buildArgs.batch {
+		runBuildBatch(cmd, args[0], args[1])
+		return
+	}
+
+	if buildArgs.dryRun && !buildArgs.remote {
+		runBuildDryRun(args[1])
+		return
+	}
+
 	dest := args[0]
 	spec := args[1]
 
@@ -100,15 +227,31 @@ func runBuild(cmd *cobra.Command, args []string) {
 		sylog.Fatalf("While checking build target: %s", err)
 	}
 
+	runPreBuildHook(dest, spec)
+
+	report := buildReport{Image: dest}
 	if buildArgs.remote {
 		runBuildRemote(ctx, cmd, dest, spec)
+		report.SHA256, report.UUID = remoteImageDigest(dest)
+	} else if len(buildArgs.platforms) > 0 {
+		report.SHA256, report.UUID = runBuildPlatforms(ctx, cmd, dest, spec)
 	} else {
-		runBuildLocal(ctx, cmd, dest, spec)
+		report = runBuildLocal(ctx, cmd, dest, spec)
+	}
+	if buildArgs.stats {
+		report.Stats = getBuildStats()
 	}
 	sylog.Infof("Build complete: %s", dest)
+
+	reportBuild(report)
+	runPostBuildHook(spec, report)
 }
 
 func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
+	if buildArgs.outputFormat == "oci" || buildArgs.outputFormat == "tar" {
+		sylog.Fatalf("Only sif and sandbox outputs are supported with the remote builder")
+	}
+
 	// building encrypted containers on the remote builder is not currently supported
 	if buildArgs.encrypt {
 		sylog.Fatalf("Building encrypted container with the remote builder is not currently supported.")
@@ -116,6 +259,11 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 
 	handleRemoteBuildFlags(cmd)
 
+	// COPY sources of Dockerfiles are local files
+	if strings.HasPrefix(spec, parser.DockerfilePrefix) {
+		sylog.Fatalf("Building from a Dockerfile is not supported with remote builds")
+	}
+
 	// submitting a remote build requires a valid authToken
 	if authToken == "" {
 		sylog.Fatalf("Unable to submit build job: %v", remoteWarning)
@@ -141,10 +289,7 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 		// build from sif downloaded in tmp location
 		defer func() {
 			sylog.Debugf("Building sandbox from downloaded SIF")
-			imgCache := getCacheHandle(cache.Config{Disable: disableCache})
-			if imgCache == nil {
-				sylog.Fatalf("failed to create an image cache handle")
-			}
+			imgCache := buildCacheHandle()
 
 			d, err := types.NewDefinitionFromURI("localimage" + "://" + dest)
 			if err != nil {
@@ -154,9 +299,10 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 			b, err := build.New(
 				[]types.Definition{d},
 				build.Config{
-					Dest:      dst,
-					Format:    "sandbox",
-					NoCleanUp: buildArgs.noCleanUp,
+					Dest:                 dst,
+					Format:               "sandbox",
+					NoCleanUp:            buildArgs.noCleanUp,
+					CleanUpOnSuccessOnly: buildArgs.cleanOnOK,
 					Opts: types.Options{
 						ImgCache: imgCache,
 						NoCache:  disableCache,
@@ -170,7 +316,7 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 			}
 
 			if err = b.Full(ctx); err != nil {
-				sylog.Fatalf("While performing build: %v", err)
+				fatalBuildError(err)
 			}
 		}()
 	}
@@ -185,7 +331,20 @@ func runBuildRemote(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 	}
 }
 
-func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
+// runBuildLocal builds the image for the host architecture and returns
+// its build report, with its SHA-256 digest and UUID when a SIF image
+// was created.
+func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) buildReport {
+	report, err := buildLocal(ctx, cmd, localBuildOptions(cmd, spec), dst, spec, "", "")
+	if err != nil {
+		fatalBuildError(err)
+	}
+	return report
+}
+
+// localBuildOptions checks the local build flags and returns the build
+// options of spec, shared by the images of all the --platform values.
+func localBuildOptions(cmd *cobra.Command, spec string) types.Options {
 	var keyInfo *crypt.KeyInfo
 	if buildArgs.encrypt || promptForPassphrase || cmd.Flags().Lookup("pem-path").Changed {
 		if os.Getuid() != 0 {
@@ -205,12 +364,13 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 		}
 	}
 
-	imgCache := getCacheHandle(cache.Config{Disable: disableCache})
-	if imgCache == nil {
-		sylog.Fatalf("Failed to create an image cache handle")
+	imgCache := buildCacheHandle()
+	if buildArgs.fromCache && imgCache.IsDisabled() {
+		sylog.Fatalf("--from-cache-only requires the image cache, which is disabled")
 	}
 
-	if syscall.Getuid() != 0 && !buildArgs.fakeroot && fs.IsFile(spec) && !isImage(spec) {
+	isDefFile := spec == build.StdinSpec || strings.HasPrefix(spec, parser.DockerfilePrefix) || (fs.IsFile(spec) && !isImage(spec))
+	if syscall.Getuid() != 0 && !buildArgs.fakeroot && isDefFile {
 		sylog.Fatalf("You must be the root user, however you can use --remote or --fakeroot to build from a Singularity recipe file")
 	}
 
@@ -224,57 +384,386 @@ func runBuildLocal(ctx context.Context, cmd *cobra.Command, dst, spec string) {
 		sylog.Fatalf("While creating Docker credentials: %v", err)
 	}
 
+	buildVars := parseBuildVars()
+
+	shellFlags, err := build.ParseShellFlags(buildArgs.shellFlags)
+	if err != nil {
+		sylog.Fatalf("While parsing section shell flags: %v", err)
+	}
+
+	epoch, err := sourceDateEpoch()
+	if err != nil {
+		sylog.Fatalf("While parsing source date epoch: %v", err)
+	}
+
+	if buildArgs.helpFile != "" {
+		if !fs.IsFile(buildArgs.helpFile) {
+			sylog.Fatalf("Help file %s doesn't exist or is not a regular file", buildArgs.helpFile)
+		}
+		buildArgs.helpFile, err = fs.Abs(buildArgs.helpFile)
+		if err != nil {
+			sylog.Fatalf("While resolving help file path: %v", err)
+		}
+	}
+
+	var labels map[string]string
+	if buildArgs.labelsFile != "" {
+		labels, err = build.ReadLabelsFile(buildArgs.labelsFile)
+		if err != nil {
+			sylog.Fatalf("While reading labels file: %v", err)
+		}
+	}
+
+	if buildArgs.labelPrefix != "" {
+		buildArgs.labelPrefix, err = build.CheckLabelPrefix(buildArgs.labelPrefix)
+		if err != nil {
+			sylog.Fatalf("While checking label prefix: %v", err)
+		}
+	}
+
+	var secretsIgnore []string
+	if buildArgs.secretsIgnore != "" {
+		secretsIgnore, err = build.ReadSecretsIgnoreFile(buildArgs.secretsIgnore)
+		if err != nil {
+			sylog.Fatalf("While reading secrets ignore file: %v", err)
+		}
+	}
+
+	if err := build.CheckExcludePaths(buildArgs.excludePaths); err != nil {
+		sylog.Fatalf("While checking excluded paths: %v", err)
+	}
+
+	if err := build.CheckFileCaps(buildArgs.fileCaps); err != nil {
+		sylog.Fatalf("While checking file capabilities: %v", err)
+	}
+
+	if buildArgs.verifyBase && buildArgs.baseKeyring == "" {
+		sylog.Fatalf("--verify-base requires a public keyring file set with --base-keyring")
+	} else if !buildArgs.verifyBase && buildArgs.baseKeyring != "" {
+		sylog.Fatalf("--base-keyring requires --verify-base")
+	}
+	if buildArgs.baseKeyring != "" {
+		if !fs.IsFile(buildArgs.baseKeyring) {
+			sylog.Fatalf("Base keyring %s is not a file", buildArgs.baseKeyring)
+		}
+		// signature policies require absolute key paths
+		abs, err := filepath.Abs(buildArgs.baseKeyring)
+		if err != nil {
+			sylog.Fatalf("While resolving base keyring path: %v", err)
+		}
+		buildArgs.baseKeyring = abs
+	}
+
+	annotations, err := build.ParseAnnotations(buildArgs.annotations)
+	if err != nil {
+		sylog.Fatalf("While parsing annotations: %v", err)
+	}
+
+	var defaultBinds []image.DefaultBind
+	for _, spec := range buildArgs.defaultBinds {
+		b, err := image.ParseDefaultBind(spec)
+		if err != nil {
+			sylog.Fatalf("While parsing default bind path: %v", err)
+		}
+		defaultBinds = append(defaultBinds, b)
+	}
+
+	var ociEntrypoint, ociCmd []string
+	if cmd.Flags().Lookup("oci-entrypoint").Changed {
+		ociEntrypoint, err = shell.Split(buildArgs.ociEntrypoint)
+		if err != nil {
+			sylog.Fatalf("While parsing OCI entrypoint: %v", err)
+		}
+		if ociEntrypoint == nil {
+			ociEntrypoint = []string{}
+		}
+	}
+	if cmd.Flags().Lookup("oci-cmd").Changed {
+		ociCmd, err = shell.Split(buildArgs.ociCmd)
+		if err != nil {
+			sylog.Fatalf("While parsing OCI cmd: %v", err)
+		}
+		if ociCmd == nil {
+			ociCmd = []string{}
+		}
+	}
+
+	allowedWarnings := parseAllowedWarnings()
+
+	loadBootstrapPlugins()
+
+	return types.Options{
+		ImgCache:          imgCache,
+		TmpDir:            tmpDir,
+		NoCache:           disableCache,
+		Update:            buildArgs.update,
+		Force:             forceOverwrite,
+		Sections:          buildArgs.sections,
+		NoTest:            buildArgs.noTest,
+		NoHTTPS:           noHTTPS,
+		LibraryNoHTTPS:    buildArgs.libNoHTTPS,
+		ShubNoHTTPS:       buildArgs.shubNoHTTPS,
+		DockerAuthConfig:  authConf,
+		AuthFile:          registryAuthFile(),
+		Layered:           buildArgs.layered,
+		SquashLayers:      buildArgs.squashLayers,
+		KeepLayers:        buildArgs.keepLayers,
+		AllowLabelExec:    buildArgs.allowExec,
+		MaxDownloadSize:   int64(buildArgs.maxDownload) << 20,
+		DownloadTimeout:   time.Duration(buildArgs.timeout) * time.Second,
+		MaxImageSize:      int64(buildArgs.maxSize) << 20,
+		WarnImageSize:     int64(buildArgs.warnSize) << 20,
+		EncryptionKeyInfo: keyInfo,
+		FixPerms:          buildArgs.fixPerms,
+		SandboxTarget:     buildArgs.outputFormat == "sandbox",
+		Compression:       buildArgs.compress,
+		CompressionLevel:  buildArgs.compressLevel,
+		BuildVars:         buildVars,
+		HelpFile:          buildArgs.helpFile,
+		BuildContext:      buildContextDir(),
+		DefinitionDir:     definitionFileDir(spec),
+		ExcludePaths:      buildArgs.excludePaths,
+		FileCaps:          buildArgs.fileCaps,
+		RootOwner:         buildArgs.rootOwner,
+		FilesJobs:         buildArgs.filesJobs,
+		VerifyBase:        buildArgs.verifyBase,
+		BaseKeyring:       buildArgs.baseKeyring,
+		DNS:               buildArgs.dnsServers,
+		NoRunscriptCheck:  buildArgs.noRunCheck,
+		NoRunscriptWrap:   buildArgs.noRunWrap,
+		Labels:            labels,
+		LabelPrefix:       buildArgs.labelPrefix,
+		Annotations:       annotations,
+		TraceScripts:      buildArgs.debugPost,
+		ShellFlags:        shellFlags,
+		NoNetworkPost:     buildArgs.noNetPost,
+		NoNetworkTest:     buildArgs.noNetTest,
+		NoMountProc:       !buildArgs.mountProc,
+		NoMountSys:        !buildArgs.mountSys,
+		MountDev:          buildArgs.mountDev,
+		MountDevPts:       buildArgs.mountDevPts,
+		DefaultBinds:      defaultBinds,
+		OCIEntrypoint:     ociEntrypoint,
+		OCICmd:            ociCmd,
+		DropDockerEnv:     !buildArgs.keepDockerEnv,
+		WarnAsError:       buildArgs.warnAsError,
+		AllowedWarnings:   allowedWarnings,
+		SourceDateEpoch:   epoch,
+		FsType:            buildArgs.fsType,
+		FsSize:            int64(buildArgs.fsSize) << 20,
+		PartitionName:     buildArgs.partName,
+		RetryPost:         buildArgs.retryPost,
+		InteractivePost:   buildArgs.interactPost,
+		VerifyIdempotent:  buildArgs.verifyIdemp,
+		ScanSecrets:       buildArgs.scanSecrets,
+		ScanSecretsStrict: buildArgs.secretsStrict,
+		ScanSecretsIgnore: secretsIgnore,
+		FromCacheOnly:     buildArgs.fromCache,
+		StrictPackages:    buildArgs.strictPkgs,
+		SeccompProfile:    buildArgs.seccompProf,
+	}
+}
+
+// buildLocal builds the image of spec for the architecture arch, the host
+// one if empty, to dst with the build options opts. The base image is
+// pulled by its digest baseDigest if set.
+func buildLocal(ctx context.Context, cmd *cobra.Command, opts types.Options, dst, spec, arch, baseDigest string) (buildReport, error) {
 	// parse definition to determine build source
-	defs, err := build.MakeAllDefs(spec)
+	defs, err := build.MakeAllDefs(spec, opts.BuildContext, arch, opts.BuildVars)
 	if err != nil {
-		sylog.Fatalf("Unable to build from %s: %v", spec, err)
+		return buildReport{}, fmt.Errorf("unable to build from %s: %v", spec, err)
 	}
 
+	writeDeffile(defs, opts.BuildVars)
+
 	// only resolve remote endpoints if library is a build source
 	for _, d := range defs {
 		if d.Header["bootstrap"] == "library" {
-			handleBuildFlags(cmd)
-			continue
+			if err := handleBuildFlags(cmd); err != nil {
+				return buildReport{}, err
+			}
+			break
 		}
 	}
-
-	buildFormat := "sif"
-	sandboxTarget := false
-	if buildArgs.sandbox {
-		buildFormat = "sandbox"
-		sandboxTarget = true
-
-	}
+	opts.LibraryURL = buildArgs.libraryURL
+	opts.LibraryAuthToken = authToken
+	opts.Arch = arch
+	opts.BaseDigest = baseDigest
 
 	b, err := build.New(
 		defs,
 		build.Config{
-			Dest:      dst,
-			Format:    buildFormat,
-			NoCleanUp: buildArgs.noCleanUp,
-			Opts: types.Options{
-				ImgCache:          imgCache,
-				TmpDir:            tmpDir,
-				NoCache:           disableCache,
-				Update:            buildArgs.update,
-				Force:             forceOverwrite,
-				Sections:          buildArgs.sections,
-				NoTest:            buildArgs.noTest,
-				NoHTTPS:           noHTTPS,
-				LibraryURL:        buildArgs.libraryURL,
-				LibraryAuthToken:  authToken,
-				DockerAuthConfig:  authConf,
-				EncryptionKeyInfo: keyInfo,
-				FixPerms:          buildArgs.fixPerms,
-				SandboxTarget:     sandboxTarget,
-			},
+			Dest:                 dst,
+			Format:               buildArgs.outputFormat,
+			NoCleanUp:            buildArgs.noCleanUp,
+			CleanUpOnSuccessOnly: buildArgs.cleanOnOK,
+			TmpSandbox:           buildArgs.tmpSandbox,
+			Opts:                 opts,
 		})
 	if err != nil {
-		sylog.Fatalf("Unable to create build: %v", err)
+		return buildReport{}, fmt.Errorf("unable to create build: %v", err)
 	}
 
 	if err = b.Full(ctx); err != nil {
-		sylog.Fatalf("While performing build: %v", err)
+		return buildReport{}, err
+	}
+
+	report := buildReport{Image: dst, Sources: b.SourceRefs()}
+	report.SHA256, report.UUID, _ = b.ImageDigest()
+	return report, nil
+}
+
+// parseBuildVars returns the build variables of the --build-args-file
+// file and of the --build-arg values, which take precedence.
+func parseBuildVars() map[string]string {
+	buildVars, err := parser.ParseBuildArgs(buildArgs.buildArgs)
+	if err != nil {
+		sylog.Fatalf("While parsing build arguments: %v", err)
+	}
+	if buildArgs.argsFile == "" {
+		return buildVars
+	}
+
+	fileVars, err := parser.ReadBuildArgsFile(buildArgs.argsFile)
+	if err != nil {
+		sylog.Fatalf("While reading build arguments file: %v", err)
+	}
+	for k, v := range fileVars {
+		if _, ok := buildVars[k]; !ok {
+			buildVars[k] = v
+		}
+	}
+	return buildVars
+}
+
+// runBuildDryRun validates the definition file(s) found in spec
+// without building anything.
+func runBuildDryRun(spec string) {
+	if err := checkSections(); err != nil {
+		sylog.Fatalf("Could not check build sections: %v", err)
+	}
+	buildVars := parseBuildVars()
+
+	allowedWarnings := parseAllowedWarnings()
+
+	loadBootstrapPlugins()
+
+	defs, err := build.MakeAllDefs(spec, buildContextDir(), buildArgs.arch, buildVars)
+	if err != nil {
+		sylog.Fatalf("Unable to build from %s: %v", spec, err)
+	}
+
+	opts := types.Options{
+		WarnAsError:     buildArgs.warnAsError,
+		AllowedWarnings: allowedWarnings,
+	}
+	if err := build.Validate(defs, opts); err != nil {
+		sylog.Fatalf("Invalid definition %s: %v", spec, err)
+	}
+
+	sylog.Infof("Definition %s is valid: %d stage(s)", spec, len(defs))
+
+	writeDeffile(defs, buildVars)
+}
+
+// writeDeffile writes the definition embedded in the image, with includes
+// expanded, conditional sections resolved and build arguments substituted
+// from buildVars, to the --write-deffile path.
+func writeDeffile(defs []types.Definition, buildVars map[string]string) {
+	if buildArgs.writeDeffile == "" {
+		return
+	}
+	raw, err := resolvedDeffile(defs[len(defs)-1], buildVars)
+	if err != nil {
+		sylog.Fatalf("While writing definition: %v", err)
+	}
+	if err := ioutil.WriteFile(buildArgs.writeDeffile, raw, 0644); err != nil {
+		sylog.Fatalf("While writing definition: %v", err)
+	}
+	sylog.Infof("Definition written to %s", buildArgs.writeDeffile)
+}
+
+// resolvedDeffile returns the raw definition of def with its {{ NAME }}
+// build argument references replaced by the values of buildVars.
+func resolvedDeffile(def types.Definition, buildVars map[string]string) ([]byte, error) {
+	raw, err := parser.SubstituteVars(string(def.Raw), buildVars)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(raw), nil
+}
+
+// sourceDateEpoch returns the time set by --source-date-epoch or by the
+// SOURCE_DATE_EPOCH environment variable, or a zero time if not set.
+func sourceDateEpoch() (time.Time, error) {
+	v := buildArgs.sourceEpoch
+	if v == "" {
+		v = os.Getenv("SOURCE_DATE_EPOCH")
+	}
+	if v == "" {
+		return time.Time{}, nil
+	}
+	sec, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || sec < 0 {
+		return time.Time{}, fmt.Errorf("bad value %q: must be a positive number of seconds since the Unix epoch", v)
+	}
+	return time.Unix(sec, 0), nil
+}
+
+// buildContextDir returns the absolute path of the --build-context
+// directory, or an empty string if not set.
+func buildContextDir() string {
+	if buildArgs.buildContext == "" {
+		return ""
+	}
+	if !fs.IsDir(buildArgs.buildContext) {
+		sylog.Fatalf("Build context %s doesn't exist or is not a directory", buildArgs.buildContext)
+	}
+	dir, err := fs.Abs(buildArgs.buildContext)
+	if err != nil {
+		sylog.Fatalf("While resolving build context path: %v", err)
+	}
+	return dir
+}
+
+// definitionFileDir returns the absolute path of the directory of the
+// definition file spec, or an empty string if spec is not a definition
+// file.
+func definitionFileDir(spec string) string {
+	if spec == build.StdinSpec || strings.HasPrefix(spec, parser.DockerfilePrefix) || !fs.IsFile(spec) || isImage(spec) {
+		return ""
+	}
+	dir, err := fs.Abs(filepath.Dir(spec))
+	if err != nil {
+		sylog.Fatalf("While resolving definition file directory: %v", err)
+	}
+	return dir
+}
+
+// parseAllowedWarnings returns the build warnings set with --allow-warning.
+func parseAllowedWarnings() []types.WarningID {
+	var allowedWarnings []types.WarningID
+	for _, w := range buildArgs.allowWarnings {
+		id, err := types.ParseWarningID(w)
+		if err != nil {
+			sylog.Fatalf("While parsing allowed warnings: %v", err)
+		}
+		allowedWarnings = append(allowedWarnings, id)
+	}
+	return allowedWarnings
+}
+
+// loadBootstrapPlugins registers the bootstrap agents provided by plugins.
+func loadBootstrapPlugins() {
+	callbackType := (buildcallback.RegisterConveyorPacker)(nil)
+	callbacks, err := plugin.LoadCallbacks(callbackType)
+	if err != nil {
+		sylog.Fatalf("While loading plugins callbacks '%T': %s", callbackType, err)
+	}
+	for _, c := range callbacks {
+		if err := c.(buildcallback.RegisterConveyorPacker)(); err != nil {
+			sylog.Fatalf("While registering bootstrap agent: %s", err)
+		}
 	}
 }
 
@@ -308,19 +797,27 @@ func isImage(spec string) bool {
 }
 
 // standard builds should just warn and fall back to CLI default if we cannot resolve library URL
-func handleBuildFlags(cmd *cobra.Command) {
+func handleBuildFlags(cmd *cobra.Command) error {
+	// a default library host takes precedence over the library
+	// of the default remote endpoint
+	libraryURLSet := cmd.Flags().Lookup("library").Changed
+	if host := defaultLibraryHost(); host != "" && !libraryURLSet {
+		buildArgs.libraryURL = library.LibraryHostURL(host)
+		libraryURLSet = true
+	}
+
 	// if we can load config and if default endpoint is set, use that
 	// otherwise fall back on regular authtoken and URI behavior
 	endpoint, err := sylabsRemote(remoteConfig)
 	if err == scs.ErrNoDefault {
 		sylog.Warningf("No default remote in use, falling back to %v", buildArgs.libraryURL)
-		return
+		return nil
 	} else if err != nil {
-		sylog.Fatalf("Unable to load remote configuration: %v", err)
+		return fmt.Errorf("unable to load remote configuration: %v", err)
 	}
 
 	authToken = endpoint.Token
-	if !cmd.Flags().Lookup("library").Changed {
+	if !libraryURLSet {
 		uri, err := endpoint.GetServiceURI("library")
 		if err == nil {
 			buildArgs.libraryURL = uri
@@ -328,6 +825,37 @@ func handleBuildFlags(cmd *cobra.Command) {
 			sylog.Warningf("Unable to get library service URI: %v", err)
 		}
 	}
+	return nil
+}
+
+// defaultLibraryHost returns the library host set with --library-host or
+// by the library default host directive of singularity.conf, if any.
+func defaultLibraryHost() string {
+	if buildArgs.libraryHost != "" {
+		return buildArgs.libraryHost
+	}
+	if config := singularityconf.GetCurrentConfig(); config != nil {
+		return config.LibraryDefaultHost
+	}
+	return ""
+}
+
+// seccompProfile returns the absolute path of the --seccomp-profile
+// profile, once checked. On hosts without seccomp support the profile
+// is ignored with a warning.
+func seccompProfile(path string) string {
+	if !seccomp.Supported() {
+		sylog.Warningf("Seccomp filters are not supported on this host, --seccomp-profile is ignored")
+		return ""
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		sylog.Fatalf("While getting absolute path of %s: %v", path, err)
+	}
+	if err := seccomp.LoadProfileFromFile(abs, generate.New(nil)); err != nil {
+		sylog.Fatalf("While loading seccomp profile %s: %v", path, err)
+	}
+	return abs
 }
 
 // getEncryptionMaterial handles the setting of encryption environment and flag parameters to eventually be
@@ -411,3 +939,27 @@ func getEncryptionMaterial(cmd *cobra.Command) (crypt.KeyInfo, error) {
 
 	return crypt.KeyInfo{}, nil
 }
+
+// fatalBuildError reports a build failure and exits, with noSpaceExitCode
+// when the image packaging ran out of disk space.
+func fatalBuildError(err error) {
+	buildErrorEvent(err)
+	if errors.Is(err, assemblers.ErrNoSpace) {
+		sylog.Errorf("While performing build: %v", err)
+		os.Exit(noSpaceExitCode)
+	}
+	sylog.Fatalf("While performing build: %v", err)
+}
+
+// sectionNoNet returns if network access is disabled for the section,
+// --net-<section> and --no-net-<section> take precedence over --net and
+// --no-net.
+func sectionNoNet(section string, net, noNet bool) bool {
+	if net && noNet {
+		sylog.Fatalf("--net-%s and --no-net-%s are mutually exclusive", section, section)
+	}
+	if net || noNet {
+		return noNet
+	}
+	return buildArgs.noNet
+}
@@ -0,0 +1,30 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestResolvedDeffile(t *testing.T) {
+	def := types.Definition{
+		Raw: []byte("Bootstrap: docker\nFrom: alpine:{{ VERSION }}\n\n%post\n    echo {{FLAVOR}}\n"),
+	}
+
+	raw, err := resolvedDeffile(def, map[string]string{"VERSION": "3.12", "FLAVOR": "slim"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if want := "Bootstrap: docker\nFrom: alpine:3.12\n\n%post\n    echo slim\n"; string(raw) != want {
+		t.Errorf("got definition %q, want %q", raw, want)
+	}
+
+	if _, err := resolvedDeffile(def, map[string]string{"VERSION": "3.12"}); err == nil {
+		t.Errorf("unexpected success with an undefined build argument")
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	osExec "os/exec"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// logTimeFormat is the timestamp format of build log file lines.
+const logTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// logfileExcludedFlags are the build flags not passed to the build
+// command whose output is written to the log file.
+var logfileExcludedFlags = map[string]bool{
+	"logfile":          true,
+	"logfile-max-size": true,
+}
+
+// ansiRegexp matches the terminal escape sequences used for colors
+// and progress bars.
+var ansiRegexp = regexp.MustCompile(`\x1b\[[0-9;?]*[a-zA-Z]`)
+
+// buildLog writes timestamped lines to a log file, the log file is
+// rotated once it exceeds maxSize bytes if maxSize is positive.
+type buildLog struct {
+	sync.Mutex
+	path    string
+	maxSize int64
+	size    int64
+	f       *os.File
+}
+
+func newBuildLog(path string, maxSize int64) (*buildLog, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &buildLog{path: path, maxSize: maxSize, f: f}, nil
+}
+
+// writeLine writes line prefixed by the current time.
+func (l *buildLog) writeLine(line []byte) error {
+	l.Lock()
+	defer l.Unlock()
+
+	if l.maxSize > 0 && l.size >= l.maxSize {
+		if err := l.rotate(); err != nil {
+			return err
+		}
+	}
+
+	n, err := fmt.Fprintf(l.f, "%s %s\n", time.Now().Format(logTimeFormat), line)
+	l.size += int64(n)
+	return err
+}
+
+// rotate moves the current log file to path.1, replacing any previous
+// rotated log file, and starts a new log file.
+func (l *buildLog) rotate() error {
+	if err := l.f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(l.path, l.path+".1"); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	l.f = f
+	l.size = 0
+	return nil
+}
+
+func (l *buildLog) Close() error {
+	l.Lock()
+	defer l.Unlock()
+	return l.f.Close()
+}
+
+// lineWriter splits the output of a stream in lines written to the
+// build log without terminal escape sequences. Carriage returns, used
+// to redraw progress bars, are handled as line ends. Write never fails
+// to not interrupt the build output, the first log file write error is
+// returned by Flush.
+type lineWriter struct {
+	log *buildLog
+	buf []byte
+	err error
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexAny(w.buf, "\r\n")
+		if i < 0 {
+			return len(p), nil
+		}
+		w.writeLine(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+}
+
+func (w *lineWriter) writeLine(line []byte) {
+	line = ansiRegexp.ReplaceAll(line, nil)
+	if w.err != nil || len(bytes.TrimSpace(line)) == 0 {
+		return
+	}
+	w.err = w.log.writeLine(line)
+}
+
+// Flush writes the last incomplete line to the build log.
+func (w *lineWriter) Flush() error {
+	w.writeLine(w.buf)
+	w.buf = nil
+	return w.err
+}
+
+// runBuildLogged executes the build command in a separate singularity
+// process with the same flags, its standard output and error are passed
+// through and written to the log file. Running the build in a separate
+// process ensures that the log file is complete and closed whatever the
+// way the build ends, it exits with the build process exit code.
+func runBuildLogged(cmd *cobra.Command, args []string) {
+	if buildArgs.logMaxSize < 0 {
+		sylog.Fatalf("--logfile-max-size must be a positive value")
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		sylog.Fatalf("Could not determine singularity executable path: %s", err)
+	}
+
+	log, err := newBuildLog(buildArgs.logfile, int64(buildArgs.logMaxSize)<<20)
+	if err != nil {
+		sylog.Fatalf("While creating log file: %s", err)
+	}
+
+	cmdArgs := changedFlagsArgs(cmd.Root().PersistentFlags(), logfileExcludedFlags)
+	cmdArgs = append(cmdArgs, "build")
+	cmdArgs = append(cmdArgs, changedFlagsArgs(cmd.Flags(), logfileExcludedFlags)...)
+	cmdArgs = append(cmdArgs, args...)
+
+	stdout := &lineWriter{log: log}
+	stderr := &lineWriter{log: log}
+
+	// interrupts are received by the build process too, which
+	// is waited for to complete the log file
+	build := osExec.Command(exe, cmdArgs...)
+	build.Stdin = os.Stdin
+	build.Stdout = io.MultiWriter(os.Stdout, stdout)
+	build.Stderr = io.MultiWriter(os.Stderr, stderr)
+
+	err = build.Run()
+
+	for _, w := range []*lineWriter{stdout, stderr} {
+		if err := w.Flush(); err != nil {
+			sylog.Warningf("While writing log file: %s", err)
+		}
+	}
+	if err := log.Close(); err != nil {
+		sylog.Warningf("While closing log file: %s", err)
+	}
+
+	if exitErr, ok := err.(*osExec.ExitError); ok {
+		os.Exit(exitErr.ExitCode())
+	} else if err != nil {
+		sylog.Fatalf("While running build: %s", err)
+	}
+	os.Exit(0)
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func readLogLines(t *testing.T, path string) []string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("failed to read log file: %s", err)
+	}
+
+	var lines []string
+	for _, l := range strings.Split(strings.TrimSuffix(string(b), "\n"), "\n") {
+		fields := strings.SplitN(l, " ", 2)
+		if len(fields) != 2 {
+			t.Fatalf("bad log line %q", l)
+		}
+		if _, err := time.Parse(logTimeFormat, fields[0]); err != nil {
+			t.Errorf("bad log line timestamp %q: %s", fields[0], err)
+		}
+		lines = append(lines, fields[1])
+	}
+	return lines
+}
+
+func TestBuildLog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build.log")
+	log, err := newBuildLog(path, 0)
+	if err != nil {
+		t.Fatalf("failed to create log: %s", err)
+	}
+
+	w := &lineWriter{log: log}
+	w.Write([]byte("\x1b[34mINFO:   \x1b[0m Starting build...\nfirst "))
+	w.Write([]byte("line\n\n10%\r50%\r"))
+	w.Write([]byte("incomplete"))
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected flush error: %s", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("unexpected close error: %s", err)
+	}
+
+	got := readLogLines(t, path)
+	want := []string{"INFO:    Starting build...", "first line", "10%", "50%", "incomplete"}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("got log lines %q instead of %q", got, want)
+	}
+}
+
+func TestBuildLogRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "build-log-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "build.log")
+	log, err := newBuildLog(path, 1)
+	if err != nil {
+		t.Fatalf("failed to create log: %s", err)
+	}
+
+	w := &lineWriter{log: log}
+	for _, l := range []string{"one", "two", "three", "four"} {
+		w.Write([]byte(l + "\n"))
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatalf("unexpected flush error: %s", err)
+	}
+	if err := log.Close(); err != nil {
+		t.Fatalf("unexpected close error: %s", err)
+	}
+
+	// with a 1 byte size limit, the log is rotated after each line
+	if got := readLogLines(t, path+".1"); len(got) != 1 || !strings.HasPrefix(got[0], "three") {
+		t.Errorf("unexpected rotated log lines %q", got)
+	}
+	if got := readLogLines(t, path); len(got) != 1 || !strings.HasPrefix(got[0], "four") {
+		t.Errorf("unexpected log lines %q", got)
+	}
+}
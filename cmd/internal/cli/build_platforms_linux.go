@@ -0,0 +1,95 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/internal/pkg/build"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// runBuildPlatforms builds a multi-architecture SIF image holding a system
+// partition for each requested platform and returns its SHA-256 digest and
+// UUID. The image of each platform is built separately, from the base image
+// digest of the --platform-manifest file if set. The host platform image, or
+// the first one if not requested, provides the primary system partition and
+// the image metadata.
+func runBuildPlatforms(ctx context.Context, cmd *cobra.Command, dst, spec string) (string, string) {
+	if buildArgs.outputFormat != "sif" || buildArgs.layered || buildArgs.keepLayers || buildArgs.encrypt {
+		sylog.Fatalf("--platform is not supported with sandbox, oci, tar, layered or encrypted images")
+	}
+
+	var archs []string
+	seen := make(map[string]bool)
+	for _, p := range buildArgs.platforms {
+		arch, err := build.ParsePlatform(p)
+		if err != nil {
+			sylog.Fatalf("While parsing platforms: %s", err)
+		}
+		if seen[arch] {
+			sylog.Fatalf("Platform %s requested more than once", p)
+		}
+		seen[arch] = true
+		archs = append(archs, arch)
+	}
+	var digests map[string]string
+	if buildArgs.platformMan != "" {
+		var err error
+		digests, err = build.ReadPlatformManifest(buildArgs.platformMan, buildArgs.platforms)
+		if err != nil {
+			sylog.Fatalf("While reading platform manifest: %s", err)
+		}
+	}
+
+	sort.SliceStable(archs, func(i, j int) bool {
+		return archs[i] == runtime.GOARCH && archs[j] != runtime.GOARCH
+	})
+
+	opts := localBuildOptions(cmd, spec)
+
+	dir, err := ioutil.TempDir(tmpDir, "build-platforms-")
+	if err != nil {
+		sylog.Fatalf("Could not create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	images := make([]string, len(archs))
+	for i, arch := range archs {
+		images[i] = filepath.Join(dir, arch+".sif")
+		sylog.Infof("Building linux/%s image", arch)
+		if _, err := buildLocal(ctx, cmd, opts, images[i], spec, arch, digests[arch]); err != nil {
+			os.RemoveAll(dir)
+			fatalBuildError(err)
+		}
+	}
+
+	if len(images) > 1 {
+		sylog.Infof("Adding platform system partitions to linux/%s image", archs[0])
+		if err := build.MergePlatforms(images[0], images[1:]); err != nil {
+			os.RemoveAll(dir)
+			sylog.Fatalf("While merging platform images: %s", err)
+		}
+	}
+
+	if err := build.MoveImage(images[0], dst); err != nil {
+		os.RemoveAll(dir)
+		sylog.Fatalf("While moving image to %s: %s", dst, err)
+	}
+
+	d, err := singularity.GetSIFDigest(dst)
+	if err != nil {
+		sylog.Warningf("While computing image digest: %s", err)
+	}
+	return d.SHA256, d.UUID
+}
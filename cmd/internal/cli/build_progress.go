@@ -0,0 +1,41 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+)
+
+// setBuildProgress enables the build progress events with --progress json,
+// written to the --progress-fd file descriptor, the standard error by
+// default.
+func setBuildProgress() error {
+	switch buildArgs.progress {
+	case "text":
+		return nil
+	case "json":
+	default:
+		return fmt.Errorf("unknown progress format %q, must be text or json", buildArgs.progress)
+	}
+
+	if buildArgs.progressFd < 0 {
+		return fmt.Errorf("bad progress file descriptor %d", buildArgs.progressFd)
+	}
+	f := os.NewFile(uintptr(buildArgs.progressFd), "progress")
+	if _, err := f.Stat(); err != nil {
+		return fmt.Errorf("bad progress file descriptor %d: %v", buildArgs.progressFd, err)
+	}
+	progress.SetWriter(f)
+	return nil
+}
+
+// buildErrorEvent emits the error event of a failed build.
+func buildErrorEvent(err error) {
+	progress.Emit(progress.Event{Type: progress.Error, Message: err.Error()})
+}
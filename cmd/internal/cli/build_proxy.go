@@ -0,0 +1,82 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// setBuildProxy overrides the proxy environment of the build process with
+// the --http-proxy, --https-proxy and --no-proxy values, so they apply to
+// all HTTP clients of the bootstrap agents and to the tools they run. Both
+// upper and lower case variables are set as tools differ in the one they
+// honor. Proxy credentials are never logged.
+func setBuildProxy() error {
+	proxies := []struct {
+		name  string
+		value string
+		env   string
+	}{
+		{"--http-proxy", buildArgs.httpProxy, "HTTP_PROXY"},
+		{"--https-proxy", buildArgs.httpsProxy, "HTTPS_PROXY"},
+	}
+	for _, p := range proxies {
+		if p.value == "" {
+			continue
+		}
+		u, err := parseProxyURL(p.value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", p.name, err)
+		}
+		sylog.Verbosef("Using %s %s", p.env, redactProxyURL(u))
+		setProxyEnv(p.env, p.value)
+	}
+	if buildArgs.noProxy != "" {
+		sylog.Verbosef("Using NO_PROXY %s", buildArgs.noProxy)
+		setProxyEnv("NO_PROXY", buildArgs.noProxy)
+	}
+	return nil
+}
+
+// setProxyEnv sets the upper and lower case forms of the proxy variable.
+func setProxyEnv(name, value string) {
+	os.Setenv(name, value)
+	os.Setenv(strings.ToLower(name), value)
+}
+
+// parseProxyURL parses a proxy URL, which must have an http, https or
+// socks5 scheme and a host. The URL is kept out of errors as it may hold
+// credentials.
+func parseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL")
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf("proxy URL must start with http://, https:// or socks5://")
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy URL without host")
+	}
+	return u, nil
+}
+
+// redactProxyURL returns u with its password, if any, replaced by xxxxx.
+func redactProxyURL(u *url.URL) string {
+	if _, ok := u.User.Password(); !ok {
+		return u.String()
+	}
+	r := *u
+	r.User = url.UserPassword(u.User.Username(), "xxxxx")
+	return r.String()
+}
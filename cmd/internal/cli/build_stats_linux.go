@@ -0,0 +1,42 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"syscall"
+	"time"
+
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+	"github.com/sylabs/singularity/internal/pkg/client"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// getBuildStats returns the resource usage of the build so far, the
+// memory and CPU time of the child processes only account for those
+// which have terminated, as the section scripts.
+func getBuildStats() *buildStats {
+	s := &buildStats{
+		Downloaded: client.Downloaded(),
+		Written:    progress.Written(),
+		Sections:   progress.Sections(),
+	}
+
+	for _, who := range []int{syscall.RUSAGE_SELF, syscall.RUSAGE_CHILDREN} {
+		var ru syscall.Rusage
+		if err := syscall.Getrusage(who, &ru); err != nil {
+			sylog.Warningf("Could not get build resource usage: %s", err)
+			continue
+		}
+		// maximum resident set size is in kilobytes
+		if rss := int64(ru.Maxrss) * 1024; rss > s.PeakMemory {
+			s.PeakMemory = rss
+		}
+		cpu := time.Duration(ru.Utime.Nano() + ru.Stime.Nano())
+		s.CPUTime += cpu.Seconds()
+	}
+
+	return s
+}
@@ -0,0 +1,67 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterFlagForCmd(&cacheVerifyTypesFlag, cacheVerifyCmd)
+		cmdManager.RegisterFlagForCmd(&cacheVerifyFixFlag, cacheVerifyCmd)
+	})
+}
+
+var (
+	cacheVerifyTypes []string
+	cacheVerifyFix   bool
+
+	// -T|--type
+	cacheVerifyTypesFlag = cmdline.Flag{
+		ID:           "cacheVerifyTypes",
+		Value:        &cacheVerifyTypes,
+		DefaultValue: []string{"all"},
+		Name:         "type",
+		ShortHand:    "T",
+		Usage:        "a list of cache types to verify (possible values: library, oci-tmp, shub, blob, net, oras, post, all)",
+	}
+
+	// --fix
+	cacheVerifyFixFlag = cmdline.Flag{
+		ID:           "cacheVerifyFixFlag",
+		Value:        &cacheVerifyFix,
+		DefaultValue: false,
+		Name:         "fix",
+		Usage:        "remove the corrupted cache entries",
+	}
+
+	// cacheVerifyCmd is 'singularity cache verify' and will check the
+	// entries of your local singularity cache
+	cacheVerifyCmd = &cobra.Command{
+		DisableFlagsInUseLine: true,
+		Run: func(cmd *cobra.Command, args []string) {
+			imgCache := getCacheHandle(cache.Config{})
+			corrupted, err := singularity.VerifySingularityCache(imgCache, cacheVerifyTypes, cacheVerifyFix)
+			if err != nil {
+				sylog.Fatalf("Cache verification failed: %v", err)
+			}
+			if corrupted > 0 {
+				sylog.Fatalf("Found %d corrupted cache entries, use --fix to remove them", corrupted)
+			}
+		},
+
+		Use:     docs.CacheVerifyUse,
+		Short:   docs.CacheVerifyShort,
+		Long:    docs.CacheVerifyLong,
+		Example: docs.CacheVerifyExample,
+	}
+)
@@ -7,6 +7,9 @@ package cli
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -21,11 +24,17 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/sylabs/sif/pkg/sif"
 	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/internal/pkg/build/assemblers"
 	"github.com/sylabs/singularity/internal/pkg/util/env"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/build/types/parser"
 	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/image"
 	"github.com/sylabs/singularity/pkg/inspect"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/sypgp"
+	"golang.org/x/crypto/openpgp"
 )
 
 var errNoSIFMetadata = errors.New("no SIF metadata found")
@@ -39,9 +48,14 @@ var (
 	environment bool
 	helpfile    bool
 	listApps    bool
+	listArchs   bool
+	listLayers  bool
+	annotations bool
 	labels      bool
 	deffile     bool
 	jsonfmt     bool
+
+	inspectKeyRing string
 )
 
 // -l|--labels
@@ -130,7 +144,7 @@ var inspectEnvironmentFlag = cmdline.Flag{
 	DefaultValue: false,
 	Name:         "environment",
 	ShortHand:    "e",
-	Usage:        "show the environment settings for the image",
+	Usage:        "show the environment variables set by the image",
 }
 
 // -H|--helpfile
@@ -143,6 +157,33 @@ var inspectHelpfileFlag = cmdline.Flag{
 	Usage:        "inspect the runscript helpfile, if it exists",
 }
 
+// --list-archs
+var inspectArchsListFlag = cmdline.Flag{
+	ID:           "inspectArchsListFlag",
+	Value:        &listArchs,
+	DefaultValue: false,
+	Name:         "list-archs",
+	Usage:        "list the architectures of the root filesystems stored in a SIF image",
+}
+
+// --layers
+var inspectLayersFlag = cmdline.Flag{
+	ID:           "inspectLayersFlag",
+	Value:        &listLayers,
+	DefaultValue: false,
+	Name:         "layers",
+	Usage:        "list the digests of the source image layers kept in a SIF image, bottom layer first",
+}
+
+// --annotations
+var inspectAnnotationsFlag = cmdline.Flag{
+	ID:           "inspectAnnotationsFlag",
+	Value:        &annotations,
+	DefaultValue: false,
+	Name:         "annotations",
+	Usage:        "show the annotations recorded in a SIF image with build --annotate",
+}
+
 // --all
 var inspectAllFlag = cmdline.Flag{
 	ID:           "inspectAllFlag",
@@ -152,6 +193,15 @@ var inspectAllFlag = cmdline.Flag{
 	Usage:        "show all available data (imply --json option)",
 }
 
+// --keyring
+var inspectKeyRingFlag = cmdline.Flag{
+	ID:           "inspectKeyRingFlag",
+	Value:        &inspectKeyRing,
+	DefaultValue: "",
+	Name:         "keyring",
+	Usage:        "verify the signatures listed in json output with the public key(s) found in this keyring file",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(InspectCmd)
@@ -166,7 +216,11 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&inspectStartscriptFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectTestFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectAppsListFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectArchsListFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectLayersFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectAnnotationsFlag, InspectCmd)
 		cmdManager.RegisterFlagForCmd(&inspectAllFlag, InspectCmd)
+		cmdManager.RegisterFlagForCmd(&inspectKeyRingFlag, InspectCmd)
 	})
 }
 
@@ -428,12 +482,36 @@ func (c *command) addEnvironmentCommand() {
 	c.script += fmt.Sprintf(snippet, sectionDelim)
 }
 
+// addAppsCommand adds the labels and help of all apps, whatever
+// the app selected with --app.
+func (c *command) addAppsCommand() {
+	prefix := ""
+	if c.img.Type == image.SANDBOX {
+		prefix = c.img.Path
+	}
+
+	var snippet = `
+	for app in %[1]s/scif/apps/*; do
+		for file in labels.json:labels runscript.help:helpfile; do
+			if [ -f "$app/scif/${file%%:*}" ]; then
+				echo "%[2]s ${file##*:}:$app/scif/${file%%:*}"
+				cat "$app/scif/${file%%:*}"
+				echo ""
+			fi
+		done
+	done
+	`
+	c.script += fmt.Sprintf(snippet, prefix, sectionDelim)
+}
+
 func (c *command) addDefinitionCommand() {
 	var err error
 
 	c.metadata.Attributes.Deffile, err = inspectDeffilePartition(c.img)
 	if err == errNoSIFMetadata || err == errNoSIF {
 		c.addSingleFileCommand("Singularity", "deffile")
+	} else if errors.Is(err, assemblers.ErrCorruptDeffile) && !allData {
+		sylog.Fatalf("While inspecting deffile of %s: %s", c.img.Path, err)
 	} else if err != nil {
 		sylog.Warningf("Unable to inspect deffile: %s", err)
 	}
@@ -468,9 +546,167 @@ func inspectDeffilePartition(img *image.Image) (string, error) {
 	if err != nil {
 		return "", err
 	}
+	for _, section := range img.Sections {
+		if section.Type == uint32(sif.DataDeffile) {
+			if err := assemblers.CheckDeffile(section.Name, data); err != nil {
+				return "", err
+			}
+			break
+		}
+	}
 	return string(data), nil
 }
 
+// inspectArchitectures returns the architectures of the system partitions
+// of a SIF image, starting with the primary one.
+func inspectArchitectures(img *image.Image) ([]string, error) {
+	if img.Type != image.SIF {
+		return nil, errNoSIF
+	}
+
+	f, err := sif.LoadContainer(img.Path, true)
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF image: %s", err)
+	}
+	defer f.UnloadContainer()
+
+	var archs []string
+	for _, desc := range f.DescrArr {
+		if !desc.Used || desc.Datatype != sif.DataPartition {
+			continue
+		}
+		ptype, err := desc.GetPartType()
+		if err != nil || (ptype != sif.PartPrimSys && ptype != sif.PartSystem) {
+			continue
+		}
+		b, err := desc.GetArch()
+		if err != nil {
+			continue
+		}
+		arch := sif.GetGoArch(string(b[:sif.HdrArchLen-1]))
+		if ptype == sif.PartPrimSys {
+			archs = append([]string{arch}, archs...)
+		} else {
+			archs = append(archs, arch)
+		}
+	}
+	return archs, nil
+}
+
+// inspectLayers returns the digests of the source image layers kept in
+// a SIF image, bottom layer first.
+func inspectLayers(img *image.Image) ([]string, error) {
+	if img.Type != image.SIF {
+		return nil, errNoSIF
+	}
+
+	for i, section := range img.Sections {
+		if section.Type != uint32(sif.DataGenericJSON) || section.Name != types.LayersJSON+".json" {
+			continue
+		}
+		r, err := image.NewSectionReader(img, "", i)
+		if err != nil {
+			return nil, fmt.Errorf("while reading SIF section: %s", err)
+		}
+		var index []types.LayerEntry
+		if err := json.NewDecoder(r).Decode(&index); err != nil {
+			return nil, fmt.Errorf("while decoding layers index: %s", err)
+		}
+		digests := make([]string, 0, len(index))
+		for _, l := range index {
+			digests = append(digests, l.Digest)
+		}
+		return digests, nil
+	}
+	return nil, nil
+}
+
+// inspectAnnotations returns the annotations recorded in a SIF image.
+func inspectAnnotations(img *image.Image) (map[string]json.RawMessage, error) {
+	if img.Type != image.SIF {
+		return nil, errNoSIF
+	}
+
+	for i, section := range img.Sections {
+		if section.Type != uint32(sif.DataGenericJSON) || section.Name != types.AnnotationsJSON+".json" {
+			continue
+		}
+		r, err := image.NewSectionReader(img, "", i)
+		if err != nil {
+			return nil, fmt.Errorf("while reading SIF section: %s", err)
+		}
+		var annotations map[string]json.RawMessage
+		if err := json.NewDecoder(r).Decode(&annotations); err != nil {
+			return nil, fmt.Errorf("while decoding annotations: %s", err)
+		}
+		return annotations, nil
+	}
+	return nil, nil
+}
+
+// inspectBuildArgs returns the resolved build arguments recorded in a SIF
+// image, nil if there are none.
+func inspectBuildArgs(img *image.Image) (map[string]string, error) {
+	if img.Type != image.SIF {
+		return nil, errNoSIF
+	}
+
+	for i, section := range img.Sections {
+		if section.Type != uint32(sif.DataGenericJSON) || section.Name != types.BuildArgsJSON+".json" {
+			continue
+		}
+		r, err := image.NewSectionReader(img, "", i)
+		if err != nil {
+			return nil, fmt.Errorf("while reading SIF section: %s", err)
+		}
+		var args map[string]string
+		if err := json.NewDecoder(r).Decode(&args); err != nil {
+			return nil, fmt.Errorf("while decoding build arguments: %s", err)
+		}
+		return args, nil
+	}
+	return nil, nil
+}
+
+// printAnnotations prints the annotations sorted by key, string values
+// are printed unquoted and other values as JSON.
+func printAnnotations(m map[string]json.RawMessage) {
+	sorted := make([]string, 0, len(m))
+	for k := range m {
+		sorted = append(sorted, k)
+	}
+	sort.Strings(sorted)
+	for _, k := range sorted {
+		var s string
+		if err := json.Unmarshal(m[k], &s); err == nil {
+			fmt.Printf("%s: %s\n", k, s)
+		} else {
+			fmt.Printf("%s: %s\n", k, m[k])
+		}
+	}
+}
+
+// inspectSignatures returns the signatures of a SIF image, verified with the
+// --keyring public keys if set. Images without signatures, including non SIF
+// images, return an empty slice.
+func inspectSignatures(ctx context.Context, img *image.Image) ([]inspect.Signature, error) {
+	if img.Type != image.SIF {
+		return []inspect.Signature{}, nil
+	}
+
+	var kr openpgp.KeyRing
+	if inspectKeyRing != "" {
+		var err error
+		kr, err = sypgp.KeyRingFromFile(inspectKeyRing)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load keyring %s: %v", inspectKeyRing, err)
+		}
+	}
+	return singularity.SIFSignatures(ctx, img.Path, kr)
+}
+
+// printSortedApp prints the apps sorted by name, each followed by the
+// first line of its help and its labels.
 func printSortedApp(m map[string]*inspect.AppAttributes) {
 	sorted := make([]string, 0, len(m))
 	for k := range m {
@@ -478,10 +714,27 @@ func printSortedApp(m map[string]*inspect.AppAttributes) {
 	}
 	sort.Strings(sorted)
 	for _, k := range sorted {
-		fmt.Printf("%s\n", k)
+		if summary := helpSummary(m[k].Helpfile); summary != "" {
+			fmt.Printf("%s: %s\n", k, summary)
+		} else {
+			fmt.Printf("%s\n", k)
+		}
+		printSortedMap(m[k].Labels, func(l string) {
+			fmt.Printf("    %s: %s\n", l, m[k].Labels[l])
+		})
 	}
 }
 
+// helpSummary returns the first non empty line of a help text.
+func helpSummary(help string) string {
+	for _, line := range strings.Split(help, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			return line
+		}
+	}
+	return ""
+}
+
 func printSortedMap(m map[string]string, fn func(key string)) {
 	sorted := make([]string, 0, len(m))
 	for k := range m {
@@ -493,9 +746,21 @@ func printSortedMap(m map[string]string, fn func(key string)) {
 	}
 }
 
+// printVariables prints the environment variables as KEY=VALUE lines,
+// flagging the values depending on the runtime environment.
+func printVariables(vars []inspect.Variable) {
+	for _, v := range vars {
+		if v.Runtime {
+			fmt.Printf("%s=%s\t# depends on the runtime environment\n", v.Name, v.Value)
+		} else {
+			fmt.Printf("%s=%s\n", v.Name, v.Value)
+		}
+	}
+}
+
 // returns true if flags for other forms of information are unset.
 func defaultToLabels() bool {
-	return !(helpfile || deffile || runscript || startscript || testfile || environment || listApps)
+	return !(helpfile || deffile || runscript || startscript || testfile || environment || listApps || listArchs || listLayers || annotations)
 }
 
 // InspectCmd represents the 'inspect' command.
@@ -565,8 +830,9 @@ var InspectCmd = &cobra.Command{
 			inspectCmd.addEnvironmentCommand()
 		}
 
-		if listApps || allData {
+		if listApps && !allData {
 			sylog.Debugf("Listing all apps in container")
+			inspectCmd.addAppsCommand()
 		}
 
 		inspectData, err := inspectCmd.getMetadata()
@@ -574,6 +840,81 @@ var InspectCmd = &cobra.Command{
 			sylog.Fatalf("%s", err)
 		}
 
+		if deffile || allData {
+			attr := &inspectData.Data.Attributes
+			if attr.Deffile != "" {
+				sum := sha256.Sum256([]byte(attr.Deffile))
+				attr.DeffileDigest = "sha256:" + hex.EncodeToString(sum[:])
+				deffileArgs, err := inspectBuildArgs(img)
+				if err != nil && err != errNoSIF {
+					sylog.Fatalf("While reading build arguments: %s", err)
+				}
+				// images without recorded values only report the names
+				if deffileArgs == nil {
+					deffileArgs = make(map[string]string)
+					for _, name := range parser.ReferencedVars(attr.Deffile) {
+						deffileArgs[name] = ""
+					}
+				}
+				attr.DeffileArgs = deffileArgs
+			} else if !allData {
+				sylog.Fatalf("No definition file embedded in %s, the image was not built by Singularity from a definition file", args[0])
+			}
+		}
+
+		if environment || allData {
+			attr := &inspectData.Data.Attributes
+			attr.Variables = singularity.EnvironmentVariables(attr.Environment, nil)
+			for _, app := range attr.Apps {
+				if len(app.Environment) > 0 {
+					app.Variables = singularity.EnvironmentVariables(app.Environment, attr.Variables)
+				}
+			}
+		}
+
+		if listArchs || allData {
+			archs, err := inspectArchitectures(img)
+			if err == errNoSIF && !allData {
+				sylog.Fatalf("Architectures can only be listed for SIF images")
+			} else if err != nil && err != errNoSIF {
+				sylog.Fatalf("While listing architectures: %s", err)
+			}
+			inspectData.Data.Attributes.Architectures = archs
+		}
+
+		if listLayers || allData {
+			layers, err := inspectLayers(img)
+			if err == errNoSIF && !allData {
+				sylog.Fatalf("Layers can only be listed for SIF images")
+			} else if err != nil && err != errNoSIF {
+				sylog.Fatalf("While listing layers: %s", err)
+			}
+			if layers == nil && !allData {
+				sylog.Fatalf("No source image layers kept in %s, the image was not built with --keep-layers", args[0])
+			}
+			inspectData.Data.Attributes.Layers = layers
+		}
+
+		if annotations || allData {
+			a, err := inspectAnnotations(img)
+			if err == errNoSIF && !allData {
+				sylog.Fatalf("Annotations can only be shown for SIF images")
+			} else if err != nil && err != errNoSIF {
+				sylog.Fatalf("While reading annotations: %s", err)
+			}
+			inspectData.Data.Attributes.Annotations = a
+		}
+
+		if jsonfmt {
+			sigs, err := inspectSignatures(cmd.Context(), img)
+			if err != nil {
+				sylog.Fatalf("While listing signatures: %s", err)
+			}
+			inspectData.Data.Attributes.Signatures = sigs
+		} else if inspectKeyRing != "" {
+			sylog.Fatalf("--keyring requires --json")
+		}
+
 		for app := range inspectData.Data.Attributes.Apps {
 			if !listApps && !allData && AppName != app {
 				delete(inspectData.Data.Attributes.Apps, app)
@@ -593,6 +934,13 @@ var InspectCmd = &cobra.Command{
 			if listApps {
 				printSortedApp(inspectData.Data.Attributes.Apps)
 			}
+			for _, arch := range inspectData.Data.Attributes.Architectures {
+				fmt.Printf("%s\n", arch)
+			}
+			for _, layer := range inspectData.Data.Attributes.Layers {
+				fmt.Printf("%s\n", layer)
+			}
+			printAnnotations(inspectData.Data.Attributes.Annotations)
 
 			if inspectData.Data.Attributes.Deffile != "" {
 				fmt.Printf("%s\n", inspectData.Data.Attributes.Deffile)
@@ -615,14 +963,10 @@ var InspectCmd = &cobra.Command{
 			} else if appAttr != nil && appAttr.Helpfile != "" {
 				fmt.Printf("%s\n", appAttr.Helpfile)
 			}
-			if len(inspectData.Data.Attributes.Environment) > 0 {
-				printSortedMap(inspectData.Data.Attributes.Environment, func(k string) {
-					fmt.Printf("=== %s ===\n%s\n\n", k, inspectData.Data.Attributes.Environment[k])
-				})
-			} else if appAttr != nil && len(appAttr.Environment) > 0 {
-				printSortedMap(appAttr.Environment, func(k string) {
-					fmt.Printf("=== %s ===\n%s\n\n", k, appAttr.Environment[k])
-				})
+			if len(inspectData.Data.Attributes.Variables) > 0 {
+				printVariables(inspectData.Data.Attributes.Variables)
+			} else if appAttr != nil && len(appAttr.Variables) > 0 {
+				printVariables(appAttr.Variables)
 			}
 			if len(inspectData.Data.Attributes.Labels) > 0 {
 				printSortedMap(inspectData.Data.Attributes.Labels, func(k string) {
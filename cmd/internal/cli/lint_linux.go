@@ -0,0 +1,112 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+var (
+	lintJSON    bool
+	lintSkip    []string
+	lintMaxSize int
+)
+
+// --json
+var lintJSONFlag = cmdline.Flag{
+	ID:           "lintJSONFlag",
+	Value:        &lintJSON,
+	DefaultValue: false,
+	Name:         "json",
+	ShortHand:    "j",
+	Usage:        "print lint results in JSON format",
+}
+
+// --max-size
+var lintMaxSizeFlag = cmdline.Flag{
+	ID:           "lintMaxSizeFlag",
+	Value:        &lintMaxSize,
+	DefaultValue: 0,
+	Name:         "max-size",
+	Usage:        "image size budget in MiB checked by the size rule, 0 means no budget",
+}
+
+// --skip
+var lintSkipFlag = cmdline.Flag{
+	ID:           "lintSkipFlag",
+	Value:        &lintSkip,
+	DefaultValue: []string{},
+	Name:         "skip",
+	Usage:        "disable a lint rule (can be specified multiple times), see --help for the list of rules",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(LintCmd)
+
+		cmdManager.RegisterFlagForCmd(&lintJSONFlag, LintCmd)
+		cmdManager.RegisterFlagForCmd(&lintMaxSizeFlag, LintCmd)
+		cmdManager.RegisterFlagForCmd(&lintSkipFlag, LintCmd)
+	})
+}
+
+// LintCmd singularity lint
+var LintCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if lintMaxSize < 0 {
+			sylog.Fatalf("--max-size must be a positive value")
+		}
+
+		opts := singularity.LintOptions{
+			Disabled: lintSkip,
+			MaxSize:  int64(lintMaxSize) * 1024 * 1024,
+		}
+
+		results, err := singularity.Lint(args[0], opts)
+		if err != nil {
+			sylog.Fatalf("Failed to lint %s: %s", args[0], err)
+		}
+
+		if lintJSON {
+			b, err := json.MarshalIndent(results, "", "\t")
+			if err != nil {
+				sylog.Fatalf("Could not format lint results: %s", err)
+			}
+			fmt.Println(string(b))
+		} else {
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintf(tw, "RULE\tSTATUS\tMESSAGE\n")
+			for _, r := range results {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", r.Rule, strings.ToUpper(string(r.Status)), r.Message)
+			}
+			tw.Flush()
+		}
+
+		for _, r := range results {
+			if r.Status == singularity.LintFail {
+				os.Exit(1)
+			}
+		}
+	},
+
+	Use:     docs.LintUse,
+	Short:   docs.LintShort,
+	Long:    docs.LintLong,
+	Example: docs.LintExample,
+}
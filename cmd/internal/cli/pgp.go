@@ -188,6 +188,27 @@ func outputVerify(f *sif.FileImage, r integrity.VerifyResult) bool {
 	return false
 }
 
+// outputThreshold outputs the signing entities that satisfied the signature
+// threshold for the signed object(s) identified by link.
+func outputThreshold(f *sif.FileImage, link uint32, signers []*openpgp.Entity) {
+	fmt.Printf("Signature threshold satisfied for %s by:\n", signedObjects(link))
+	for _, e := range signers {
+		name := "unknown"
+		if id := primaryIdentity(e); id != nil {
+			name = id.Name
+		}
+		fmt.Printf("  %X %s\n", e.PrimaryKey.Fingerprint, name)
+	}
+}
+
+// signedObjects returns a description of the signed object(s) identified by link.
+func signedObjects(link uint32) string {
+	if link&sif.DescrGroupMask == sif.DescrGroupMask {
+		return fmt.Sprintf("group %d", link&^sif.DescrGroupMask)
+	}
+	return fmt.Sprintf("object %d", link)
+}
+
 type key struct {
 	Signer keyEntity
 }
@@ -202,10 +223,18 @@ type keyEntity struct {
 	DataCheck   bool
 }
 
+// thresholdSigners holds the keys which satisfied the signature threshold
+// for signed object(s), used for json output.
+type thresholdSigners struct {
+	Objects string
+	Keys    []keyEntity
+}
+
 // keyList is a list of one or more keys.
 type keyList struct {
 	Signatures int
 	SignerKeys []*key
+	Threshold  []*thresholdSigners `json:",omitempty"`
 }
 
 // getJSONCallback returns a singularity.VerifyCallback that appends to kl.
@@ -269,6 +298,28 @@ func getJSONCallback(kl *keyList) singularity.VerifyCallback {
 	}
 }
 
+// getJSONThresholdCallback returns a singularity.ThresholdCallback that appends
+// the keys which satisfied the signature threshold to kl.
+func getJSONThresholdCallback(kl *keyList) singularity.ThresholdCallback {
+	return func(f *sif.FileImage, link uint32, signers []*openpgp.Entity) {
+		ts := &thresholdSigners{Objects: signedObjects(link)}
+		for _, e := range signers {
+			name := "unknown"
+			if id := primaryIdentity(e); id != nil {
+				name = id.Name
+			}
+			ts.Keys = append(ts.Keys, keyEntity{
+				Name:        name,
+				Fingerprint: hex.EncodeToString(e.PrimaryKey.Fingerprint[:]),
+				KeyLocal:    isLocal(e),
+				KeyCheck:    true,
+				DataCheck:   true,
+			})
+		}
+		kl.Threshold = append(kl.Threshold, ts)
+	}
+}
+
 // outputJSON outputs a JSON representation of kl to w.
 func outputJSON(w io.Writer, kl keyList) error {
 	e := json.NewEncoder(w)
@@ -39,6 +39,8 @@ const (
 	HTTPSProtocol = "https"
 	// OrasProtocol holds the oras URI.
 	OrasProtocol = "oras"
+	// DockerProtocol holds the docker registry URI.
+	DockerProtocol = "docker"
 )
 
 var (
@@ -53,8 +55,11 @@ var (
 	// pullDir is the path that the containers will be pulled to, if set.
 	pullDir string
 	// pullArch is the architecture for which containers will be pulled from the
-	// SCS library.
+	// SCS library or docker/OCI registries.
 	pullArch string
+	// pullVariant is the architecture variant for which containers will be pulled
+	// from docker/OCI registries.
+	pullVariant string
 )
 
 // --arch
@@ -63,10 +68,20 @@ var pullArchFlag = cmdline.Flag{
 	Value:        &pullArch,
 	DefaultValue: runtime.GOARCH,
 	Name:         "arch",
-	Usage:        "architecture to pull from library",
+	Usage:        "architecture to pull from library or docker/OCI registries",
 	EnvKeys:      []string{"PULL_ARCH"},
 }
 
+// --variant
+var pullVariantFlag = cmdline.Flag{
+	ID:           "pullVariantFlag",
+	Value:        &pullVariant,
+	DefaultValue: "",
+	Name:         "variant",
+	Usage:        "architecture variant to pull from docker/OCI registries, as v7 for arm",
+	EnvKeys:      []string{"PULL_VARIANT"},
+}
+
 // --library
 var pullLibraryURIFlag = cmdline.Flag{
 	ID:           "pullLibraryURIFlag",
@@ -152,6 +167,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&pullAllowUnsignedFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullAllowUnauthenticatedFlag, PullCmd)
 		cmdManager.RegisterFlagForCmd(&pullArchFlag, PullCmd)
+		cmdManager.RegisterFlagForCmd(&pullVariantFlag, PullCmd)
 	})
 }
 
@@ -205,6 +221,10 @@ func pullRun(cmd *cobra.Command, args []string) {
 		}
 	}
 
+	if pullVariant != "" && oci.IsSupported(transport) == "" {
+		sylog.Fatalf("--variant is only supported for docker and OCI images")
+	}
+
 	switch transport {
 	case LibraryProtocol, "":
 		handlePullFlags(cmd)
@@ -248,7 +268,15 @@ func pullRun(cmd *cobra.Command, args []string) {
 			sylog.Fatalf("While creating Docker credentials: %v", err)
 		}
 
-		_, err = oci.PullToFile(ctx, imgCache, pullTo, pullFrom, tmpDir, ociAuth, noHTTPS, buildArgs.noCleanUp)
+		// the host platform is selected by default from multi-platform
+		// images, an explicit architecture is also checked for single
+		// platform images
+		arch := ""
+		if cmd.Flags().Lookup("arch").Changed {
+			arch = pullArch
+		}
+
+		_, err = oci.PullToFile(ctx, imgCache, pullTo, pullFrom, arch, pullVariant, tmpDir, ociAuth, noHTTPS, buildArgs.noCleanUp)
 		if err != nil {
 			sylog.Fatalf("While making image from oci registry: %v", err)
 		}
@@ -13,6 +13,7 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/sylabs/singularity/docs"
 	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/internal/pkg/client/oci"
 	"github.com/sylabs/singularity/internal/pkg/client/oras"
 	scs "github.com/sylabs/singularity/internal/pkg/remote"
 	"github.com/sylabs/singularity/internal/pkg/util/uri"
@@ -26,6 +27,9 @@ var (
 
 	// unauthenticatedPush when true; will never ask to push a unsigned container
 	unauthenticatedPush bool
+
+	// pushOCI converts the image to an OCI image before pushing it
+	pushOCI bool
 )
 
 // --library
@@ -49,12 +53,25 @@ var pushAllowUnsignedFlag = cmdline.Flag{
 	EnvKeys:      []string{"ALLOW_UNSIGNED"},
 }
 
+// --oci
+var pushOCIFlag = cmdline.Flag{
+	ID:           "pushOCIFlag",
+	Value:        &pushOCI,
+	DefaultValue: false,
+	Name:         "oci",
+	Usage:        "convert the image to an OCI image for docker/podman, implied by docker:// URIs",
+	EnvKeys:      []string{"PUSH_OCI"},
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(PushCmd)
 
 		cmdManager.RegisterFlagForCmd(&pushLibraryURIFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&pushAllowUnsignedFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&pushOCIFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&commonNoHTTPSFlag, PushCmd)
+		cmdManager.RegisterFlagForCmd(&commonTmpDirFlag, PushCmd)
 
 		cmdManager.RegisterFlagForCmd(&dockerUsernameFlag, PushCmd)
 		cmdManager.RegisterFlagForCmd(&dockerPasswordFlag, PushCmd)
@@ -76,6 +93,10 @@ var PushCmd = &cobra.Command{
 			sylog.Fatalf("bad uri %s", dest)
 		}
 
+		if pushOCI && transport != OrasProtocol && transport != DockerProtocol {
+			sylog.Fatalf("--oci is only supported with oras:// and docker:// URIs")
+		}
+
 		switch transport {
 		case LibraryProtocol, "": // Handle pushing to a library
 			handlePushFlags(cmd)
@@ -89,12 +110,20 @@ var PushCmd = &cobra.Command{
 			} else if err != nil {
 				sylog.Fatalf("Unable to push image to library: %v", err)
 			}
-		case OrasProtocol:
+		case OrasProtocol, DockerProtocol:
 			ociAuth, err := makeDockerCredentials(cmd)
 			if err != nil {
 				sylog.Fatalf("Unable to make docker oci credentials: %s", err)
 			}
 
+			if pushOCI || transport == DockerProtocol {
+				if err := oci.Push(ctx, file, ref, tmpDir, ociAuth, noHTTPS); err != nil {
+					sylog.Fatalf("Unable to push OCI image to registry: %v", err)
+				}
+				sylog.Infof("Upload complete")
+				return
+			}
+
 			if err := oras.UploadImage(file, ref, ociAuth); err != nil {
 				sylog.Fatalf("Unable to push image to oci registry: %v", err)
 			}
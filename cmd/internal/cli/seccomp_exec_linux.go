@@ -0,0 +1,37 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterCmd(SeccompExecCmd)
+	})
+}
+
+// SeccompExecCmd executes a command under a seccomp profile, it's used
+// by build to run the %pre and %setup sections with --seccomp-profile.
+var SeccompExecCmd = &cobra.Command{
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := singularity.SeccompExec(args[0], args[1:]); err != nil {
+			sylog.Fatalf("While executing %s: %s", args[1], err)
+		}
+	},
+	DisableFlagsInUseLine: true,
+	// the command arguments are passed as is
+	DisableFlagParsing: true,
+
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(2),
+	Use:    "seccomp-exec <profile> <command> [args...]",
+	Short:  "Execute a command under a seccomp profile",
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifDigestCmd)
+	})
+}
+
+// SifDigestCmd singularity sif digest
+var SifDigestCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		d, err := singularity.GetSIFDigest(args[0])
+		if err != nil {
+			sylog.Fatalf("Failed to compute digest: %s", err)
+		}
+		fmt.Printf("SHA256: %s\n", d.SHA256)
+		fmt.Printf("UUID: %s\n", d.UUID)
+	},
+
+	Use:     docs.SifDigestUse,
+	Short:   docs.SifDigestShort,
+	Long:    docs.SifDigestLong,
+	Example: docs.SifDigestExample,
+}
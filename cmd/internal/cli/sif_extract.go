@@ -0,0 +1,92 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+var (
+	sifExtractName   string
+	sifExtractOutput string
+)
+
+// --name
+var sifExtractNameFlag = cmdline.Flag{
+	ID:           "sifExtractNameFlag",
+	Value:        &sifExtractName,
+	DefaultValue: "",
+	Name:         "name",
+	Usage:        "name of the data file to extract",
+}
+
+// -o|--output
+var sifExtractOutputFlag = cmdline.Flag{
+	ID:           "sifExtractOutputFlag",
+	Value:        &sifExtractOutput,
+	DefaultValue: "",
+	Name:         "output",
+	ShortHand:    "o",
+	Usage:        "write the data file to this path, '-' for standard output (default: data file name in the current directory)",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifExtractCmd)
+
+		cmdManager.RegisterFlagForCmd(&sifExtractNameFlag, SifExtractCmd)
+		cmdManager.RegisterFlagForCmd(&sifExtractOutputFlag, SifExtractCmd)
+	})
+}
+
+// SifExtractCmd singularity sif extract
+var SifExtractCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if sifExtractName == "" {
+			sylog.Fatalf("A data file name must be provided with --name")
+		}
+
+		output := sifExtractOutput
+		if output == "" {
+			output = sifExtractName
+		}
+
+		if output == "-" {
+			if err := singularity.ExtractDataFile(args[0], sifExtractName, os.Stdout); err != nil {
+				sylog.Fatalf("Failed to extract data file: %s", err)
+			}
+			return
+		}
+
+		f, err := os.OpenFile(output, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err != nil {
+			sylog.Fatalf("Failed to create %s: %s", output, err)
+		}
+		if err := singularity.ExtractDataFile(args[0], sifExtractName, f); err != nil {
+			f.Close()
+			os.Remove(output)
+			sylog.Fatalf("Failed to extract data file: %s", err)
+		}
+		if err := f.Close(); err != nil {
+			sylog.Fatalf("Failed to write %s: %s", output, err)
+		}
+		sylog.Infof("Data file %s extracted to %s", sifExtractName, output)
+	},
+
+	Use:     docs.SifExtractUse,
+	Short:   docs.SifExtractShort,
+	Long:    docs.SifExtractLong,
+	Example: docs.SifExtractExample,
+}
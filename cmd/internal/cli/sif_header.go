@@ -0,0 +1,99 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+var sifHeaderJSON bool
+
+// --json
+var sifHeaderJSONFlag = cmdline.Flag{
+	ID:           "sifHeaderJSONFlag",
+	Value:        &sifHeaderJSON,
+	DefaultValue: false,
+	Name:         "json",
+	ShortHand:    "j",
+	Usage:        "print the header fields in JSON format",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		// replace the siftool header command, which loads the whole
+		// image and fails on malformed images
+		for _, c := range SiftoolCmd.Commands() {
+			if c.Name() == "header" {
+				SiftoolCmd.RemoveCommand(c)
+			}
+		}
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifHeaderCmd)
+
+		cmdManager.RegisterFlagForCmd(&sifHeaderJSONFlag, SifHeaderCmd)
+	})
+}
+
+// SifHeaderCmd singularity sif header
+var SifHeaderCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		h, err := singularity.GetSIFHeader(args[0])
+		if err != nil {
+			sylog.Fatalf("Failed to read SIF header: %s", err)
+		}
+
+		if sifHeaderJSON {
+			b, err := json.MarshalIndent(h, "", "\t")
+			if err != nil {
+				sylog.Fatalf("Could not format SIF header: %s", err)
+			}
+			fmt.Println(string(b))
+			return
+		}
+
+		magic := h.Magic
+		if magic != sif.HdrMagic {
+			magic += " (invalid)"
+		}
+		version := h.Version
+		if version != sif.HdrVersion {
+			version += " (unsupported)"
+		}
+
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(tw, "Launch:\t%s\n", h.Launch)
+		fmt.Fprintf(tw, "Magic:\t%s\n", magic)
+		fmt.Fprintf(tw, "SIF spec version:\t%s\n", version)
+		fmt.Fprintf(tw, "Arch:\t%s (%s)\n", h.Arch, h.GoArch)
+		fmt.Fprintf(tw, "UUID:\t%s\n", h.UUID)
+		fmt.Fprintf(tw, "Created:\t%s\n", time.Unix(h.Ctime, 0).UTC())
+		fmt.Fprintf(tw, "Modified:\t%s\n", time.Unix(h.Mtime, 0).UTC())
+		fmt.Fprintf(tw, "Descriptors:\t%d used, %d free, %d total\n", h.DescrTotal-h.DescrFree, h.DescrFree, h.DescrTotal)
+		fmt.Fprintf(tw, "Descriptors offset:\t%d\n", h.DescrOffset)
+		fmt.Fprintf(tw, "Descriptors length:\t%d\n", h.DescrLength)
+		fmt.Fprintf(tw, "Data offset:\t%d\n", h.DataOffset)
+		fmt.Fprintf(tw, "Data length:\t%d\n", h.DataLength)
+		tw.Flush()
+	},
+
+	Use:     docs.SifHeaderUse,
+	Short:   docs.SifHeaderShort,
+	Long:    docs.SifHeaderLong,
+	Example: docs.SifHeaderExample,
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+var sifListJSON bool
+
+// siftoolListCmd is the siftool list command, still used for the
+// table output.
+var siftoolListCmd *cobra.Command
+
+// --json
+var sifListJSONFlag = cmdline.Flag{
+	ID:           "sifListJSONFlag",
+	Value:        &sifListJSON,
+	DefaultValue: false,
+	Name:         "json",
+	ShortHand:    "j",
+	Usage:        "print the descriptors in JSON format",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		// replace the siftool list command, which has no
+		// structured output
+		for _, c := range SiftoolCmd.Commands() {
+			if c.Name() == "list" {
+				siftoolListCmd = c
+				SiftoolCmd.RemoveCommand(c)
+			}
+		}
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifListCmd)
+
+		cmdManager.RegisterFlagForCmd(&sifListJSONFlag, SifListCmd)
+	})
+}
+
+// SifListCmd singularity sif list
+var SifListCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if !sifListJSON && siftoolListCmd != nil {
+			if siftoolListCmd.RunE != nil {
+				if err := siftoolListCmd.RunE(siftoolListCmd, args); err != nil {
+					sylog.Fatalf("Failed to list SIF descriptors: %s", err)
+				}
+				return
+			}
+			siftoolListCmd.Run(siftoolListCmd, args)
+			return
+		}
+
+		descrs, err := singularity.SIFDescriptors(args[0])
+		if err != nil {
+			sylog.Fatalf("Failed to list SIF descriptors: %s", err)
+		}
+		b, err := json.MarshalIndent(descrs, "", "\t")
+		if err != nil {
+			sylog.Fatalf("Could not format SIF descriptors: %s", err)
+		}
+		fmt.Println(string(b))
+	},
+
+	Use:     docs.SifListUse,
+	Short:   docs.SifListShort,
+	Long:    docs.SifListLong,
+	Example: docs.SifListExample,
+}
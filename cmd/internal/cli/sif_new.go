@@ -0,0 +1,45 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		// replace the siftool new command, which overwrites
+		// existing files
+		for _, c := range SiftoolCmd.Commands() {
+			if c.Name() == "new" {
+				SiftoolCmd.RemoveCommand(c)
+			}
+		}
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifNewCmd)
+	})
+}
+
+// SifNewCmd singularity sif new
+var SifNewCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := singularity.NewSIF(args[0]); err != nil {
+			sylog.Fatalf("Failed to create SIF image: %s", err)
+		}
+		sylog.Infof("Created empty SIF image %s", args[0])
+	},
+
+	Use:     docs.SifNewUse,
+	Short:   docs.SifNewShort,
+	Long:    docs.SifNewLong,
+	Example: docs.SifNewExample,
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"strconv"
+
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		// replace the siftool setprim command, which doesn't check
+		// the partition file system type
+		for _, c := range SiftoolCmd.Commands() {
+			if c.Name() == "setprim" {
+				SiftoolCmd.RemoveCommand(c)
+			}
+		}
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifSetPrimCmd)
+	})
+}
+
+// SifSetPrimCmd singularity sif setprim
+var SifSetPrimCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(2),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		id, err := strconv.ParseUint(args[0], 10, 32)
+		if err != nil || id == 0 {
+			sylog.Fatalf("Invalid descriptor ID %s", args[0])
+		}
+		if err := singularity.SetPrimaryPartition(args[1], uint32(id)); err != nil {
+			sylog.Fatalf("Failed to set primary partition: %s", err)
+		}
+		sylog.Infof("Descriptor %d is now the primary partition of %s", id, args[1])
+	},
+
+	Use:     docs.SifSetPrimUse,
+	Short:   docs.SifSetPrimShort,
+	Long:    docs.SifSetPrimLong,
+	Example: docs.SifSetPrimExample,
+}
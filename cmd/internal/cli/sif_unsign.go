@@ -0,0 +1,57 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+var sifUnsignGroupID uint32
+
+// -g|--group-id
+var sifUnsignGroupIDFlag = cmdline.Flag{
+	ID:           "sifUnsignGroupIDFlag",
+	Value:        &sifUnsignGroupID,
+	DefaultValue: uint32(0),
+	Name:         "group-id",
+	ShortHand:    "g",
+	Usage:        "only remove the signatures of objects with the specified group ID",
+}
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifUnsignCmd)
+
+		cmdManager.RegisterFlagForCmd(&sifUnsignGroupIDFlag, SifUnsignCmd)
+	})
+}
+
+// SifUnsignCmd singularity sif unsign
+var SifUnsignCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		n, err := singularity.UnsignSIF(args[0], sifUnsignGroupID)
+		if err != nil {
+			sylog.Fatalf("Failed to remove signatures: %s", err)
+		}
+		if n == 0 {
+			sylog.Infof("No signature found in %s", args[0])
+			return
+		}
+		sylog.Infof("Removed %d signature(s) from %s", n, args[0])
+	},
+
+	Use:     docs.SifUnsignUse,
+	Short:   docs.SifUnsignShort,
+	Long:    docs.SifUnsignLong,
+	Example: docs.SifUnsignExample,
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cli
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/sylabs/singularity/docs"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	"github.com/sylabs/singularity/pkg/cmdline"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+func init() {
+	addCmdInit(func(cmdManager *cmdline.CommandManager) {
+		cmdManager.RegisterSubCmd(SiftoolCmd, SifVerifyLayoutCmd)
+	})
+}
+
+// SifVerifyLayoutCmd singularity sif verify-layout
+var SifVerifyLayoutCmd = &cobra.Command{
+	DisableFlagsInUseLine: true,
+	Args:                  cobra.ExactArgs(1),
+
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := singularity.VerifySIFLayout(args[0]); err != nil {
+			sylog.Fatalf("SIF image %s is corrupted: %s", args[0], err)
+		}
+		sylog.Infof("SIF image %s layout is valid", args[0])
+	},
+
+	Use:     docs.SifVerifyLayoutUse,
+	Short:   docs.SifVerifyLayoutShort,
+	Long:    docs.SifVerifyLayoutLong,
+	Example: docs.SifVerifyLayoutExample,
+}
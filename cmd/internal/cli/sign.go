@@ -17,8 +17,9 @@ import (
 )
 
 var (
-	privKey int // -k encryption key (index from 'keys list') specification
-	signAll bool
+	privKey          int // -k encryption key (index from 'keys list') specification
+	signAll          bool
+	signRemoveLegacy bool
 )
 
 // -g|--group-id
@@ -82,6 +83,15 @@ var signAllFlag = cmdline.Flag{
 	Deprecated:   "now the default behavior",
 }
 
+// --remove-legacy
+var signRemoveLegacyFlag = cmdline.Flag{
+	ID:           "signRemoveLegacyFlag",
+	Value:        &signRemoveLegacy,
+	DefaultValue: false,
+	Name:         "remove-legacy",
+	Usage:        "remove the (insecure) legacy signatures of the image before signing it",
+}
+
 func init() {
 	addCmdInit(func(cmdManager *cmdline.CommandManager) {
 		cmdManager.RegisterCmd(SignCmd)
@@ -92,6 +102,7 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&signSifDescIDFlag, SignCmd)
 		cmdManager.RegisterFlagForCmd(&signKeyIdxFlag, SignCmd)
 		cmdManager.RegisterFlagForCmd(&signAllFlag, SignCmd)
+		cmdManager.RegisterFlagForCmd(&signRemoveLegacyFlag, SignCmd)
 	})
 }
 
@@ -134,6 +145,11 @@ func doSignCmd(cmd *cobra.Command, cpath string) {
 		opts = append(opts, singularity.OptSignObjects(sifDescID))
 	}
 
+	// Set remove legacy option, if applicable.
+	if signRemoveLegacy {
+		opts = append(opts, singularity.OptSignRemoveLegacy())
+	}
+
 	// Sign the image.
 	fmt.Printf("Signing image: %s\n", cpath)
 	if err := singularity.Sign(cpath, opts...); err != nil {
@@ -16,16 +16,23 @@ import (
 	scs "github.com/sylabs/singularity/internal/pkg/remote"
 	"github.com/sylabs/singularity/pkg/cmdline"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/sypgp"
 	useragent "github.com/sylabs/singularity/pkg/util/user-agent"
 )
 
+// legacyVerifyExitCode is the exit status of a successful verification of
+// legacy signatures, so it can't be mistaken for a regular verification.
+const legacyVerifyExitCode = 3
+
 var (
-	sifGroupID   uint32 // -g groupid specification
-	sifDescID    uint32 // -i id specification
-	localVerify  bool   // -l flag
-	jsonVerify   bool   // -j flag
-	verifyAll    bool
-	verifyLegacy bool
+	sifGroupID      uint32 // -g groupid specification
+	sifDescID       uint32 // -i id specification
+	localVerify     bool   // -l flag
+	jsonVerify      bool   // -j flag
+	verifyAll       bool
+	verifyLegacy    bool
+	verifyThreshold int
+	verifyKeyRing   string
 )
 
 // -u|--url
@@ -116,7 +123,25 @@ var verifyLegacyFlag = cmdline.Flag{
 	Value:        &verifyLegacy,
 	DefaultValue: false,
 	Name:         "legacy-insecure",
-	Usage:        "enable verification of (insecure) legacy signatures",
+	Usage:        "enable verification of (insecure) legacy signatures, exits with status 3 on success",
+}
+
+// --threshold
+var verifyThresholdFlag = cmdline.Flag{
+	ID:           "verifyThresholdFlag",
+	Value:        &verifyThreshold,
+	DefaultValue: 0,
+	Name:         "threshold",
+	Usage:        "require signed objects to be signed by at least this number of distinct keys",
+}
+
+// --keyring
+var verifyKeyRingFlag = cmdline.Flag{
+	ID:           "verifyKeyRingFlag",
+	Value:        &verifyKeyRing,
+	DefaultValue: "",
+	Name:         "keyring",
+	Usage:        "only verify with the public key(s) found in this keyring file",
 }
 
 func init() {
@@ -132,6 +157,8 @@ func init() {
 		cmdManager.RegisterFlagForCmd(&verifyJSONFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyAllFlag, VerifyCmd)
 		cmdManager.RegisterFlagForCmd(&verifyLegacyFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyThresholdFlag, VerifyCmd)
+		cmdManager.RegisterFlagForCmd(&verifyKeyRingFlag, VerifyCmd)
 	})
 }
 
@@ -155,8 +182,14 @@ var VerifyCmd = &cobra.Command{
 func doVerifyCmd(cmd *cobra.Command, cpath string) {
 	var opts []singularity.VerifyOpt
 
-	// Set keyserver option, if applicable.
-	if !localVerify {
+	// Set keyring or keyserver option, if applicable.
+	if verifyKeyRing != "" {
+		kr, err := sypgp.KeyRingFromFile(verifyKeyRing)
+		if err != nil {
+			sylog.Fatalf("Failed to load keyring %s: %v", verifyKeyRing, err)
+		}
+		opts = append(opts, singularity.OptVerifyUseKeyRing(kr))
+	} else if !localVerify {
 		handleVerifyFlags(cmd)
 
 		c := client.Config{
@@ -184,13 +217,24 @@ func doVerifyCmd(cmd *cobra.Command, cpath string) {
 
 	// Set legacy option, if applicable.
 	if verifyLegacy {
+		sylog.Warningf("INSECURE: verifying legacy signatures, which don't cover the SIF header and descriptors.")
+		sylog.Warningf("Use this mode only to confirm the provenance of an image before re-signing it with 'singularity sign --remove-legacy'.")
 		opts = append(opts, singularity.OptVerifyLegacy())
 	}
 
+	var kl keyList
+
+	// Set threshold option, if applicable.
+	if cmd.Flag(verifyThresholdFlag.Name).Changed {
+		var cb singularity.ThresholdCallback = outputThreshold
+		if jsonVerify {
+			cb = getJSONThresholdCallback(&kl)
+		}
+		opts = append(opts, singularity.OptVerifyThreshold(verifyThreshold, cb))
+	}
+
 	// Set callback option.
 	if jsonVerify {
-		var kl keyList
-
 		opts = append(opts, singularity.OptVerifyCallback(getJSONCallback(&kl)))
 
 		verifyErr := singularity.Verify(cmd.Context(), cpath, opts...)
@@ -214,6 +258,11 @@ func doVerifyCmd(cmd *cobra.Command, cpath string) {
 
 		fmt.Printf("Container verified: %s\n", cpath)
 	}
+
+	if verifyLegacy {
+		sylog.Warningf("Container verified with INSECURE legacy signatures: %s", cpath)
+		os.Exit(legacyVerifyExitCode)
+	}
 }
 
 func handleVerifyFlags(cmd *cobra.Command) {
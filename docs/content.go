@@ -40,11 +40,26 @@ Enterprise Performance Computing (EPC)`
 
       default:    The compressed Singularity read only image format (default)
       sandbox:    This is a read-write container within a directory structure
+      oci:        An OCI image layout directory holding a single layer image
+      tar:        A tar archive of the container root filesystem
 
   note: It is a common workflow to use the "sandbox" mode for development of the
   container, and then build it as a default Singularity image for production 
   use. The default format is immutable.
 
+  The format is selected with --output-format sif|sandbox|oci|tar, --sandbox 
+  being an alias of --output-format sandbox. The OCI image is tagged 
+  "latest", its configuration being built from the environment, runscript, 
+  labels and --annotate values. The %help, %test, %actions and %datafile 
+  sections, the --default-bind paths, the startscript and SCIF apps can't 
+  be represented in OCI images and tar archives, they are reported by a 
+  W023 build warning and only kept in the image filesystem when present 
+  there. Files of OCI images and tar archives keep their ownership, all 
+  of them are owned by root under --fakeroot or with --root-owner. A tar 
+  output ending with .tar.gz or .tgz is gzip compressed. Only SIF and 
+  sandbox images can be built remotely, and only SIF images can be 
+  layered or built for several platforms.
+
   BUILD SPEC:
 
   The build spec target is a definition (def) file, local image, or URI that can 
@@ -61,7 +76,540 @@ Enterprise Performance Computing (EPC)`
       library://  an image library (default https://cloud.sylabs.io/library)
       docker://   a Docker registry (default Docker Hub)
       shub://     a Singularity registry (default Singularity Hub)
-      oras://     a supporting OCI registry`
+      oras://     a supporting OCI registry
+      tar://      a root filesystem tar archive, tar://- for the standard input
+
+  Library references may name the library host, as 
+  library://library.example.com/project/collection/image:tag. References 
+  without a host are resolved against the host set with --library-host or 
+  by the 'library default host' directive of singularity.conf, otherwise 
+  against the library of the default remote endpoint. The resolved reference 
+  is recorded in the org.label-schema.usage.singularity.library.ref label. 
+  The library authentication token is only sent to the configured library, 
+  images of other library hosts are pulled anonymously.
+
+  Library images can be pinned by content digest, as 
+  library://project/image@sha256:<digest> or project/image:sha256.<digest>, 
+  for reproducible bootstraps. The image is fetched by digest, whatever the 
+  tags of the library, and its digest is verified after the download. The 
+  reference of such images is recorded with the @sha256:<digest> form.
+
+  With --verify-base, the signature of library and docker bootstrap images 
+  is verified with the public keys of the keyring file set by 
+  --base-keyring before the image is used, and the build is aborted if it 
+  can't be verified. Library SIF images are verified as by 'singularity 
+  verify --keyring'. Docker images must be signed with GPG simple signing 
+  signatures, as created by 'skopeo copy --sign-by', stored by the registry 
+  or in a lookaside storage configured in registries.d. Cosign signatures 
+  are not supported, and a PEM encoded cosign key set as --base-keyring 
+  aborts the build. The verified image is fetched by digest, and its 
+  digest is recorded in the 
+  org.label-schema.usage.singularity.verified-base.digest label. Stages 
+  built from a local image, directory or archive, or from scratch, have no 
+  signature to verify, while the other bootstrap agents pulling a remote 
+  base can't be used with --verify-base.
+
+  --library-no-https and --shub-no-https disable HTTPS for the library:// 
+  and shub:// bootstrap sources of a single build, for internal endpoints 
+  without a certificate. Transport security is disabled, so a warning is 
+  displayed and these flags shouldn't be used with untrusted networks.
+
+  A build spec of '-' reads the definition file, including multi-stage 
+  definitions, from the standard input. In this case, relative paths found in 
+  the %files section or used by %setup are resolved relative to the current 
+  working directory.
+
+  A build spec of 'dockerfile://<path>' converts a Dockerfile to a definition, 
+  the directory holding the Dockerfile being the build context. FROM sets the 
+  bootstrap image, RUN instructions are run by %post, each one in a subshell 
+  starting in the WORKDIR, COPY and ADD become %files entries, ENV is set in 
+  %environment and for the following RUN instructions, LABEL and MAINTAINER 
+  become %labels, and ENTRYPOINT and CMD the %runscript. COPY --from copies 
+  from a previous stage, ADD neither fetches URLs nor extracts archives, and 
+  ARG only provides default values. EXPOSE, HEALTHCHECK, ONBUILD, SHELL, 
+  STOPSIGNAL, USER and VOLUME are ignored with a warning. As with %files, 
+  files are copied before any RUN instruction is run.
+
+  With --dry-run, the definition file is parsed and validated, including 
+  bootstrap agents and stages referenced by '%files from', but no image is 
+  built and IMAGE PATH is ignored.
+
+  With --write-deffile, the definition embedded in the image, with included 
+  files expanded, conditional sections resolved and all stages, is written 
+  to the given path before the build starts, so it is also written if the 
+  build fails. Combined with --dry-run, it is written without building. 
+  {{ NAME }} build argument references are replaced by their --build-arg 
+  or --build-args-file values. It is not supported with --batch or 
+  --platform.
+
+  Files listed in the %datafile section, one '<path> [name]' entry per line, 
+  are stored under their name in a squashfs data partition alongside the 
+  root filesystem, a relative path being resolved from the build context 
+  or from the definition file directory. They can be read back with 
+  'singularity sif extract' or exposed read-only in a container directory 
+  at runtime with the --datafiles option, which mounts the partition from 
+  the image without copying them.
+
+  Registry credentials for docker:// and oras:// sources can be read from a 
+  podman/skopeo compatible auth.json file with --authfile, or the file set 
+  by the REGISTRY_AUTH_FILE environment variable. Credentials are looked up 
+  by registry host, docker.io and index.docker.io being the same registry, 
+  and the --docker-username/--docker-password options take precedence.
+
+  With --layered, the bootstrapped root filesystem is stored as is in the 
+  SIF root filesystem partition, while the changes made by %setup, %files, 
+  %post and %test are stored in a SIF overlay partition on top of it, so the 
+  base partition can be shared by the images derived from the same base. 
+  Running layered images requires overlay support on the host. The 
+  --squash-layers option flattens the layers in a single root filesystem 
+  partition.
+
+  With --keep-layers, which implies --layered, each layer of a docker or OCI 
+  source image is stored in its own SIF partition, named after the layer 
+  digest, so unchanged layers can be shared between images. A layers index 
+  records the stacking order, and the layers are mounted as a stacked overlay 
+  at runtime. The --squash option flattens them back in a single root 
+  filesystem partition.
+
+  Values of the %labels section can be computed at build time: {{ NAME }} 
+  references are substituted with --build-arg values, a '< PATH' value is 
+  read from the host file PATH and a '$(COMMAND)' value is the output of 
+  COMMAND executed on the host, which is only allowed with 
+  --allow-label-exec. A relative PATH is resolved from the --build-context 
+  directory, or from the directory of the definition file.
+
+  Build arguments can also be read from a file with --build-args-file, 
+  holding one KEY=VALUE definition per line, the value being the rest of 
+  the line without surrounding blanks. Empty lines and lines starting with 
+  '#' are ignored. A malformed line or a variable defined twice in the file 
+  fails the build with an error citing the line number. --build-arg values 
+  take precedence over the file ones. Like --build-arg values, they are 
+  only substituted in the definition and are not set in the container 
+  environment.
+
+  The --labels-file option adds the labels of a JSON file holding a single 
+  object of string values, any other content is rejected. Labels are 
+  applied in this order, later ones taking precedence: labels of the source 
+  image, build labels, %labels section (overriding existing labels only 
+  with --force), then the labels file, which always overrides them.
+
+  The --label-prefix option namespaces the labels of the %labels section and 
+  of the labels file with a lowercase reverse domain name, as com.example., 
+  a trailing dot being added if missing. A label already part of the 
+  namespace is kept as is, and the built-in org.label-schema labels are 
+  never prefixed. 'singularity inspect --labels' shows the prefixed names.
+
+  The --annotate KEY=VALUE option, which can be repeated, records an 
+  annotation in a dedicated JSON object of the SIF image, following the 
+  OCI annotation conventions, as org.opencontainers.image.authors. Unlike 
+  labels, a value holding valid JSON, as an object, an array or a number, 
+  is stored as is, any other value is stored as a string, quote a value 
+  with '"' to store it as a string. Annotations are kept apart from the 
+  labels, shown by 'singularity inspect --annotations' and become the 
+  manifest annotations of images pushed as OCI images. They are not 
+  supported with --sandbox.
+
+  With --source-date-epoch SECONDS, or the SOURCE_DATE_EPOCH environment 
+  variable when the option is not set, the org.label-schema.build-date 
+  label is the given Unix time in UTC instead of the current time, and the 
+  SIF header and descriptor timestamps are set to it. It is also passed to 
+  mksquashfs, which uses it for the file timestamps from version 4.4. An 
+  explicit org.label-schema.build-date label of the labels file or of the 
+  %labels section still takes precedence.
+
+  The --exclude-paths option, which can be repeated, removes the container 
+  paths matching a glob pattern, as '/var/cache/apt/*' or '/usr/share/man', 
+  after the %post and %test sections of the last stage and before 
+  packaging, reporting the bytes reclaimed. Patterns must be absolute and 
+  can't remove the root directory or /.singularity.d. Paths resolved 
+  outside of the container through absolute symbolic links are skipped. 
+  With --layered, files of the base layer are hidden, not removed from it.
+
+  The --setcap option, which can be repeated, sets file capabilities on a 
+  container file after the %post and %test sections of the last stage, as 
+  '/usr/bin/server=cap_net_bind_service+ep'. Capabilities are comma 
+  separated, with or without the cap_ prefix, and added to the effective 
+  (e), inheritable (i) and permitted (p) sets given after '+', 'ep' by 
+  default. Extended attributes, which hold the file capabilities, are kept 
+  when packaging the image, so capabilities set by setcap in %post are 
+  kept as well, OCI images and tar archives keeping the file capabilities 
+  only. Setting capabilities requires building as root or with 
+  --fakeroot, and they are only effective if the container filesystem is 
+  not mounted with nosuid.
+
+  The --max-size option fails the build when the packaged image exceeds the 
+  given size in MiB, without replacing an existing image file at the 
+  destination. The --warn-size option raises warning W022 instead. When a 
+  limit is exceeded, the largest directories of the root filesystem are 
+  reported to guide slimming the image, for example with --exclude-paths.
+
+  Once built, the image runscript is checked: a missing runscript raises 
+  warning W017, a runscript that is empty, not executable or runs an 
+  interpreter or program missing from the image, as the entrypoint of a 
+  docker image, raises warning W018. Both fail the build with 
+  --warn-as-error, and --no-runscript-check disables the check.
+
+  A runscript or startscript run by a shell executes its commands as child 
+  processes of the shell, which doesn't forward the signals sent to the 
+  container, as SIGTERM on 'instance stop'. With --no-runscript-wrap, the 
+  last command of such scripts is run with exec and replaces the shell, so 
+  that it receives the signals and can act as the container init process. 
+  A last command which can't be run with exec, as a shell builtin, a 
+  function, a pipeline or a background command, raises warning W021.
+
+  With --progress json, the build streams newline-delimited JSON events as 
+  it runs, on the standard error or the file descriptor set by --progress-fd. 
+  Each event has a "time" and a "type": section-start and section-end (with 
+  "stage", "section", "status" and "duration" in seconds) for the %pre, 
+  %setup, %files, %post and %test sections, layer-download-progress ("layer", 
+  "current" and "total" bytes) for docker and OCI image layers, 
+  packaging-progress ("step" and "status"), warning ("code" and "message") 
+  and error ("message"). Events are distinct from the --json-report final 
+  report, and log lines written to the standard error are not JSON objects.
+
+  The --stats option reports the resource usage of a local build at its 
+  end, and in the "stats" field of the --json-report report: the peak 
+  memory in bytes of the build process and of its largest child process, 
+  the CPU time of the build and of its finished child processes, as the 
+  section scripts, the bytes downloaded by the bootstrap agents and for 
+  %files URL sources, the bytes of the file systems and images written by 
+  the packaging steps, and the wall time of each %pre, %setup, %files, 
+  %post and %test section. Layers already in the cache are not counted as 
+  downloaded.
+
+  With --fakeroot, container ID 0 is your user and container IDs starting 
+  from 1 are mapped onto your subordinate ID range, as allocated in 
+  /etc/subuid and /etc/subgid. The --fakeroot-uidmap and --fakeroot-gidmap 
+  options instead map up to 4 comma separated <container id>:<size> ranges, 
+  which take consecutive IDs of the subordinate range in order, as '1000:1' 
+  to only have UID 1000 available to chown files in %post. The ranges can't 
+  hold more IDs than the subordinate range, which is usually 65536 IDs, and 
+  chown to container IDs out of the ranges fails. Packaged images record the 
+  container IDs, sandboxes are owned on the host by the mapped subordinate 
+  IDs.
+
+  The --http-proxy, --https-proxy and --no-proxy options set the proxies of 
+  a single build, overriding the HTTP_PROXY, HTTPS_PROXY and NO_PROXY 
+  environment variables for all the bootstrap agents and the tools they 
+  run. Proxy URLs must start with http://, https:// or socks5://, their 
+  credentials are never shown in the build output.
+
+  Downloads of bootstrap images from busybox mirrors, Docker registries, the 
+  library and Singularity Hub are bounded by --max-download-size (64GiB by 
+  default) and --download-timeout (2 hours by default). A download exceeding 
+  a limit is aborted, its partial data removed and the source URL reported. 
+  Docker image layer sizes are checked against the size limit before any 
+  layer is fetched.
+
+  The --section-shell-flags option applies shell flags, as for example 
+  "-uo pipefail", to the %pre, %setup, %post and %test scripts on top of the 
+  default -e flag. With a section interpreter selected by '-c', the flags are 
+  passed to the interpreter only if it's a POSIX sh compatible shell (sh, 
+  bash, dash, ash, ksh or zsh), they are ignored for other interpreters.
+
+  With --no-net, the %post and %test sections run in an isolated network 
+  namespace with only a loopback interface, so they can't fetch undeclared 
+  dependencies. Bootstrap sources and %files are still fetched from the 
+  host, and %pre and %setup, running on the host, are not affected. The 
+  --net option restores network access when --no-net is set from the 
+  SINGULARITY_NO_NET environment variable. Access restricted to a list of 
+  hosts is not supported.
+
+  Network access can be set for each section with --net-post, --no-net-post, 
+  --net-test and --no-net-test, which take precedence over --net and 
+  --no-net. For example '--net-post --no-net-test' lets %post fetch packages 
+  while %test runs offline, to catch tests which depend on the network. The 
+  network namespace is only created for the sections without network access.
+
+  The %post and %test sections use the host /etc/resolv.conf and /etc/hosts, 
+  bound over the files of the image for the duration of the sections. With 
+  --dns, as in --dns 1.1.1.1, a resolv.conf using the given name servers is 
+  bound in place of the host one. A missing /etc/resolv.conf or /etc/hosts 
+  is created empty as bind target and removed once the sections are run, 
+  even if they fail, so the build host files never end up in the image.
+
+  With --from-cache-only, library, docker, shub and oras bootstrap sources 
+  are taken from the image cache without querying their source, the build 
+  failing with the name of the image or blob missing from the cache. Images 
+  are found in the cache by the reference, and platform, they were pulled 
+  or built from, so a cache populated with 'singularity pull' or a previous 
+  build allows hermetic offline builds. The debootstrap, yum, zypper, arch 
+  and busybox bootstrap agents always fetch from the network and can't be 
+  used, as well as %files URL sources. %post and %test network access is controlled separately with 
+  --no-net.
+
+  The image cache used by all bootstrap sources of the build can be set with 
+  --cache-dir, created with owner only permissions if absent, to keep the 
+  cache of a project isolated from the other builds of the node and clean 
+  it independently. The cache directory is taken from --cache-dir, then 
+  from the SINGULARITY_CACHEDIR environment variable, and defaults to the 
+  user configuration directory ($HOME/.singularity).
+
+  With --cleanup-on-success-only, the build bundles are removed after a 
+  successful build but preserved when the build fails, which is the 
+  recommended mode to debug builds. Mount points are always unmounted, the 
+  location of the preserved root filesystem is displayed with the 
+  'singularity shell --writable' command to inspect it. --no-cleanup 
+  preserves the bundles of successful builds too.
+
+  The %post section can be split in ordered named fragments, as %post:base, 
+  %post:deps and %post:app, which can't be combined with a plain %post 
+  section. A snapshot of the root filesystem is stored in the image cache 
+  after each fragment, under a key computed over the fragment and the 
+  content of its --env-file, chained with the key of the previous one, the 
+  first one with a digest of the root filesystem before %post. A build 
+  restores the snapshot of the last fragment found in the cache and only 
+  runs the following ones, so editing a fragment or its environment file, 
+  or anything before %post, re-runs it and all the following ones. Without cache, all fragments are run. Snapshots are removed with 
+  'singularity cache clean --type post'.
+
+  --verify-idempotent is a testing aid to check that %post, or each 
+  %post:NAME fragment, is idempotent before relying on fragment caching, 
+  for example in CI. Each fragment is run a second time on the root 
+  filesystem produced by its first run, and the build fails if this second 
+  run added, removed or modified any file, the changed files being 
+  reported. Files rewritten with the same content are not changes. Cached 
+  snapshots are not restored so that every fragment is checked, it is not 
+  meant for normal builds.
+
+  --scan-secrets scans the final root filesystem, including the image 
+  labels and environment scripts, and the JSON data objects of the image 
+  for secrets left behind by %post or %files: AWS keys, PEM private keys, 
+  GitHub and Slack tokens and high entropy values assigned to token, 
+  secret, password or key variables. Binary files and files larger than 
+  8 MiB are skipped. Each possible secret raises a W024 build warning 
+  listing its file and line, never its value, and --scan-secrets-strict 
+  fails the build when any is found. False positives are ignored with 
+  --scan-secrets-ignore, a file of absolute container path glob patterns, 
+  one per line, a matching directory being ignored with its content. It 
+  is a lightweight safety net, not an exhaustive secret detection.
+
+  A %actions NAME section, NAME being exec, run, shell, start or test, 
+  replaces the action script of /.singularity.d/actions run by the matching 
+  singularity command, the other actions keeping their default behaviour. 
+  Like %runscript, the section accepts a '-c <interpreter>' argument or a 
+  shebang, /bin/sh by default, and the interpreter must be an executable of 
+  the image given with an absolute path. The script is written executable 
+  and is run once the container environment is set up, the environment 
+  scripts sourced and the SINGULARITYENV_ variables applied, with the 
+  arguments of the action: the command and its arguments for exec, the 
+  arguments passed to the runscript, test or startscript for run, test and 
+  start, and to the shell for shell. The script must exec the target 
+  program, as 'exec /.singularity.d/runscript "$@"' or 'exec "$@"', so that 
+  it receives the signals sent to the container, and its exit code is the 
+  one of the singularity command.
+
+  With --retry-post N, a %post section exiting with a non zero status is run 
+  again up to N times, waiting 5 seconds before the first retry and twice as 
+  long before each following one, up to 2 minutes. Each attempt is logged. 
+  The whole section runs again on the partially modified root filesystem, so 
+  retries are disabled by default and should only be enabled for %post 
+  sections which can safely be run several times, for example to survive 
+  transient package mirror failures.
+
+  With --interactive-post, a failing %post section, once its retries are 
+  exhausted, starts an interactive shell in the build root filesystem, with 
+  the bind mounts, environment and network settings of the section, to 
+  inspect its state and test fixes. The section script is available as 
+  /.post.script. Exiting the shell with status 0 resumes the build with the 
+  root filesystem as left by the shell, any other status aborts the build. 
+  The option is ignored when the standard input is not a terminal.
+
+  With --seccomp-profile, the %pre, %setup, %post and %test sections run 
+  under the given OCI seccomp profile, as used by 'singularity exec 
+  --security seccomp:<path>'. System calls blocked by the profile fail with 
+  "Operation not permitted" and the failure of a section is reported with 
+  the profile in use. The default-deny profile installed as 
+  seccomp-profiles/build.json in the Singularity configuration directory 
+  denies mounts, namespace changes, tracing, kernel modules and other 
+  privileged system calls and can be used as a starting point. The option 
+  is ignored with a warning on hosts without seccomp support.
+
+  The yum and zypper bootstrap agents fail when the package manager exits 
+  with a non zero status. Both tools may still succeed when requested 
+  packages are not found or a repository can't be reached, the missing 
+  packages being silently skipped. With --strict-packages, the package 
+  manager output is checked for such messages, as "No match for argument", 
+  "Nothing to do" or "Problem retrieving files", which fail the bootstrap.
+
+  The %post and %test sections get /proc, /sys and the host /dev mounted 
+  by default. --bind-mount-proc=false and --bind-mount-sys=false disable 
+  the /proc and /sys mounts, --bind-mount-dev selects the /dev mount: yes 
+  for the host /dev, minimal for a /dev holding only null, zero, random, 
+  urandom, tty and shm devices, or no. --bind-mount-devpts adds a devpts 
+  instance for pseudo terminals to a minimal /dev. The mounts are made in a 
+  private mount namespace, they are released when the section ends, 
+  whether it succeeds or fails. Note that with the host /dev, section 
+  scripts have access to all the host devices, including disks when the 
+  build runs as root, use a minimal /dev for untrusted definition files.
+
+  The %post and %test scripts start in the container root directory by 
+  default. A '-w DIR' section option, as in '%post -w /build', sets another 
+  absolute working directory, created in the container if absent.
+
+  A '--env-file FILE' section option, as in '%post --env-file build.env', 
+  loads the KEY=VALUE lines of FILE, resolved from the build context, in the 
+  environment of the %post or %test section, as the --env-file option of 
+  'singularity exec'. Unlike %environment, these variables are only set 
+  while the section runs and are not stored in the image.
+
+  A '%include PATH' line in a definition file is replaced by the content 
+  of the definition fragment PATH, resolved relative to the including file 
+  (or the current directory for a definition read from standard input). 
+  Fragments hold sections only and may include other fragments, circular 
+  includes being rejected. Sections found several times, as %environment 
+  and %labels shared by several recipes, are merged in include order. The 
+  text following an include must start with a section. The definition 
+  file recorded in the image is the expanded one.
+
+  A section line ending with conditions in parentheses, as 
+  '%post (arch=arm64)' or '%post -c /bin/bash (arch!=arm64, FLAVOR=slim)', 
+  is a conditional section, kept only when all the comma separated 
+  NAME=VALUE or NAME!=VALUE conditions hold and removed with its content 
+  otherwise. NAME is arch, the Go name of the build architecture, or a 
+  --build-arg variable, which must be defined. Kept sections are merged 
+  with sections of the same name in file order. With --platform, the 
+  conditions are resolved separately for each platform image, so one 
+  definition file can install architecture specific packages. The 
+  definition file recorded in the image is the resolved one.
+
+  The directory bootstrap agent uses an existing root filesystem directory, 
+  as built by mmdebstrap or nix, as the base of the container. The directory 
+  is copied, never modified, then the container metadata and actions are 
+  installed and the definition sections are applied as for any other 
+  agent. Unlike localimage, the directory doesn't need to be a Singularity 
+  sandbox.
+
+  The tar bootstrap agent extracts a root filesystem tar archive, possibly 
+  gzip compressed, as the base of the container. The archive is read from 
+  the standard input when From is '-', so a build spec of 'tar://-' builds 
+  an image from an archive piped by another tool, as with 
+  'mmdebstrap stable - | singularity build debian.sif tar://-', without 
+  intermediate files. The ownership, permissions and symlinks recorded in 
+  the archive are kept when built as root, otherwise all the files are 
+  owned by the current user. Then the container metadata and actions are 
+  installed and the definition sections applied as for the directory agent.
+
+  Relative %files sources are resolved from the current directory, or 
+  from the --build-context directory when set. With --build-context, 
+  relative include paths of every include level are also resolved from 
+  this directory instead of the including file directory, and relative 
+  COPY sources of a dockerfile:// build from it instead of the Dockerfile 
+  directory. It gives a location to definitions read from standard input 
+  with 'build IMAGE -', whose relative paths are otherwise resolved from 
+  the current directory. Absolute paths, %files from a previous stage and 
+  %setup scripts are unaffected.
+
+  A %files source may be an http:// or https:// URL, downloaded on the host 
+  to the destination, which is required, without network access or tools 
+  in the container. The destination may be followed by the SHA-256 
+  checksum of the file, as 'sha256:<hex>', and a directory destination 
+  receives the file under the last element of the URL path. A failed 
+  download or a checksum mismatch aborts the build.
+
+  The --files-jobs option copies up to N %files entries of a stage 
+  concurrently, for images with many large independent copies. Entries 
+  whose destinations are the same path, or one below the other after 
+  resolving symbolic links in the container, are copied in their order in 
+  the section, so that later entries still overwrite earlier ones and the 
+  resulting tree is the same as with a serial copy. '%files from' entries 
+  are always copied serially.
+
+  With --logfile, the whole build output, including the output of the 
+  section scripts, is also written to a log file, each line prefixed by a 
+  timestamp with millisecond precision and stripped of terminal colors and 
+  progress bar updates. The terminal output, including the --json-report 
+  output on standard output, is unchanged. The build runs in a separate 
+  process so the log file is complete even if the build fails or is 
+  interrupted. With --logfile-max-size, the log file is moved to 
+  <path>.1 once it exceeds the given size in MiB, replacing any previous 
+  rotated log file.
+
+  With --platform, as in --platform linux/amd64,linux/arm64, a single SIF 
+  image holding a root filesystem partition for each platform is built, the 
+  runtime selecting the partition matching the host architecture. Each 
+  platform is built separately from docker, library or scratch bootstrap 
+  sources, %post and %test sections of foreign platforms requiring the 
+  emulation of their architecture to be enabled in binfmt_misc. The host 
+  platform, or the first one if not requested, provides the primary root 
+  filesystem partition and the image metadata. A platforms.json SIF object 
+  indexes the root filesystem partitions. Docker and OCI sources are 
+  checked before any layer download: a platform missing from the source 
+  manifest list aborts the build with the list of available platforms, and 
+  a single architecture source image built for another architecture 
+  raises warning W019.
+
+  With --platform-manifest, the base image of each platform is pulled by 
+  digest rather than resolved from its tag, for reproducible multi-platform 
+  images. The file holds a JSON object mapping each platform to the digest 
+  of its base image, as {"linux/amd64": "sha256:<hex>"}, platforms not 
+  requested with --platform being ignored. The build fails before any 
+  download if a requested platform is missing from the file. The digest 
+  replaces the tag of the docker or library From: header, all the stages 
+  pinned must use the same base image.
+
+  A docker From: header without a registry or a tag, as 'From: ubuntu', 
+  relies on the docker.io registry and latest tag defaults and raises 
+  warning W020 with the resolved reference, as docker.io/library/ubuntu:latest, 
+  which fails the build with --warn-as-error. The resolved reference is 
+  recorded in the org.label-schema.usage.singularity.docker.ref label and 
+  in the "sources" field of the --json-report report.
+
+  With --fs ext3, the root filesystem partition of a SIF image is a writable 
+  ext3 filesystem created with mkfs.ext3 instead of a squashfs filesystem. 
+  Its size defaults to the root filesystem size plus 25%, at least 64MiB, 
+  and may be set in MiB with --fs-size. --fs ext3 can't be combined with 
+  --layered, --encrypt or a sandbox build. With --partition-name, the root 
+  filesystem partition is given the requested name, shown by sif list, 
+  instead of the default root filesystem path.
+
+  A SIF image is written to a hidden temporary file of the destination 
+  directory, or of the directory set by --tmp-sandbox, and renamed to the 
+  destination once complete, so that a failed or interrupted build never 
+  leaves a partial image at the destination, an existing image being kept 
+  until it is replaced. If the --tmp-sandbox directory is on another 
+  filesystem, the image is copied to the destination directory and synced 
+  before being renamed, with a warning.
+
+  With --pre-build-hook and --post-build-hook, a shell command is run 
+  before the build and once the image is written, with the image path as 
+  first argument. The SINGULARITY_BUILD_HOOK, SINGULARITY_BUILD_SPEC and 
+  SINGULARITY_BUILD_IMAGE environment variables hold the hook stage, the 
+  build spec and the image path, SINGULARITY_BUILD_SHA256 and 
+  SINGULARITY_BUILD_UUID are also set for the post-build hook of SIF images. 
+  A failing pre-build hook aborts the build. A failing post-build hook makes 
+  the build fail, the image being already written, so a hook can veto the 
+  publication of the image by a build pipeline.
+
+  Before packaging a SIF image, a warning is displayed if the filesystems 
+  holding the temporary directory and the image may lack space for the root 
+  filesystem size plus 10%. A build running out of disk space while 
+  packaging removes the partial image, reports the full filesystem with the 
+  estimated and available space, and exits with status 28 (ENOSPC).
+
+  When the build target already exists, --force overwrites it and 
+  --no-clobber makes the build fail immediately, the two flags can't be 
+  combined. Without either flag, the build asks whether to overwrite the 
+  target when the standard input is a terminal and fails otherwise, so 
+  scripted builds never replace an image by accident. 
+
+  The environment scripts of /.singularity.d/env are sourced in the order 
+  of their two digit prefix: 01-base.sh, 10-docker2singularity.sh (docker 
+  and OCI sources), 90-environment.sh (%environment), 91-environment.sh 
+  ($SINGULARITY_ENVIRONMENT in %post), 94-appsbase.sh, 95-apps.sh, 
+  99-base.sh and 99-runtimevars.sh. Exports repeating the previous export 
+  of the same variable are removed, so rebuilding from an image with the 
+  same environment doesn't add duplicate PATH entries. 
+
+  BATCH BUILDS:
+
+  With --batch, IMAGE PATH is an output directory and BUILD SPEC a directory 
+  containing definition files. An image named after each definition file 
+  (*.def) is built in the output directory, overwriting any existing image, 
+  along with a log file holding the build output. Up to --jobs images are 
+  built concurrently and share the image cache, a summary of the builds is 
+  displayed once they are all done and the command exits with a non-zero 
+  status if any of them failed.`
 
 	BuildExample string = `
 
@@ -85,16 +633,26 @@ Enterprise Performance Computing (EPC)`
           OSVersion: 7
           MirrorURL: http://mirror.centos.org/centos-%{OSVERSION}/%{OSVERSION}/os/x86_64/
           Include: yum
+          YumOptions: --setopt=tsflags=nodocs # Extra yum/dnf options
 
       Debian/Ubuntu:
           Bootstrap: debootstrap
           OSVersion: trusty
           MirrorURL: http://us.archive.ubuntu.com/ubuntu/
+          DebootstrapOptions: --variant=buildd # Extra debootstrap options
 
       Local Image:
           Bootstrap: localimage
           From: /home/dave/starter.img
 
+      Directory:
+          Bootstrap: directory
+          From: /home/dave/rootfs # Root filesystem built by other tools
+
+      Tar:
+          Bootstrap: tar
+          From: rootfs.tar.gz # Root filesystem archive, '-' for the standard input
+
       Scratch:
           Bootstrap: scratch # Populate the container with a minimal rootfs in %setup
 
@@ -134,6 +692,7 @@ Enterprise Performance Computing (EPC)`
       %files
           /path/on/host/file.txt /path/on/container/file.txt
           relative_file.txt /path/on/container/relative_file.txt
+          https://example.com/LICENSE /opt/LICENSE sha256:<hex checksum>
 
       %environment
           LUKE=goodguy
@@ -155,7 +714,22 @@ Enterprise Performance Computing (EPC)`
       Build a base sandbox from DockerHub, make changes to it, then build sif
           $ singularity build --sandbox /tmp/debian docker://debian:latest
           $ singularity exec --writable /tmp/debian apt-get install python
-          $ singularity build /tmp/debian2.sif /tmp/debian`
+          $ singularity build /tmp/debian2.sif /tmp/debian
+
+      Build a sif file from a definition file read from the standard input:
+          $ generate-def | singularity build /tmp/debian3.sif -
+
+      Validate a definition file read from the standard input without building:
+          $ generate-def | singularity build --dry-run /tmp/debian3.sif -
+
+      Build a sif file from a Dockerfile, its directory being the build context:
+          $ singularity build /tmp/app.sif dockerfile://./Dockerfile
+
+      Build a sif file for each definition file found in /path/to/defs, 4 at a time:
+          $ singularity build --batch --jobs 4 /tmp/images /path/to/defs
+
+      Build an OCI image layout directory from a definition file:
+          $ singularity build --output-format oci /tmp/debian-oci debian.def`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Cache
@@ -204,6 +778,26 @@ Enterprise Performance Computing (EPC)`
   $ singularity help cache list --type=library,oci
   $ singularity cache list --help`
 
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// Cache Verify
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	CacheVerifyUse   string = `verify [verify options...]`
+	CacheVerifyShort string = `Verify the entries of your local Singularity cache`
+	CacheVerifyLong  string = `
+  This will check the entries of your local cache (stored at 
+  $HOME/.singularity/cache if SINGULARITY_CACHEDIR is not set) to detect the 
+  ones corrupted, as truncated by a power loss. The SHA-256 checksum of OCI 
+  blobs is compared with the digest naming them, the one of other entries 
+  with the checksum recorded when they were cached. Entries cached by 
+  previous versions have no recorded checksum and are reported as unverified, 
+  except library and oras images named after their digest. Corrupted entries 
+  are reported and the command fails, use --fix to remove them so that they 
+  are downloaded or built again when needed.`
+	CacheVerifyExample string = `
+  $ singularity cache verify
+  $ singularity cache verify --type=blob,library
+  $ singularity cache verify --fix`
+
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// key
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
@@ -459,10 +1053,24 @@ Enterprise Performance Computing (EPC)`
   shub://*            A container hosted on Singularity Hub
 
   oras://*            A container hosted on a supporting OCI registry`
+	environment string = `
+
+  Environment variables are set in the container with the following 
+  precedence, from lowest to highest: the host environment (unless --cleanenv 
+  is set), the image %environment, the SINGULARITYENV_ prefixed host 
+  variables, the variables of --env-file and the --env KEY=VALUE options, the 
+  last one winning for a key repeated with --env.
+
+  With --app, the image environment is completed by the %appenv section of 
+  the selected SCIF app only, along with its SCIF_APPNAME, SCIF_APPROOT, 
+  SCIF_APPBIN and other active app variables. The %appenv sections of the 
+  other apps are never sourced, and SCIF_APP* variables of the host 
+  environment are not passed to the container, so that an app started from 
+  another app doesn't inherit its variables.`
 	ExecUse   string = `exec [exec options...] <container> <command>`
 	ExecShort string = `Run a command within a container`
 	ExecLong  string = `
-  singularity exec supports the following formats:` + formats
+  singularity exec supports the following formats:` + formats + environment
 	ExecExamples string = `
   $ singularity exec /tmp/debian.sif cat /etc/debian_version
   $ singularity exec /tmp/debian.sif python ./hello_world.py
@@ -520,9 +1128,12 @@ Enterprise Performance Computing (EPC)`
   existing container image that will begin running in the background. If a
   startscript is defined in the container metadata the commands in that script
   will be executed with the instance start command as well. You can optionally
-  pass arguments to startscript
+  pass arguments to startscript, available as "$@" in the script.
+
+  With --app, the startscript of the SCIF app defined by the %appstart section
+  is executed instead, with the app environment.
 
-  singularity instance start accepts the following container formats` + formats
+  singularity instance start accepts the following container formats` + formats + environment
 	InstanceStartExample string = `
   $ singularity instance start /tmp/my-sql.sif mysql
 
@@ -537,7 +1148,9 @@ Enterprise Performance Computing (EPC)`
   Singularity my-sql.sif>
 
   $ singularity instance stop /tmp/my-sql.sif mysql
-  Stopping /tmp/my-sql.sif mysql`
+  Stopping /tmp/my-sql.sif mysql
+
+  $ singularity instance start --app server /tmp/services.sif web 8080`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// instance stop
@@ -586,7 +1199,20 @@ Enterprise Performance Computing (EPC)`
       oras://registry/namespace/image:tag
 
   http, https: Pull an image using the http(s?) protocol
-      https://library.sylabs.io/v1/imagefile/library/default/alpine:latest`
+      https://library.sylabs.io/v1/imagefile/library/default/alpine:latest
+
+  Library and docker images can be pinned by digest, as in 
+  library://alpine:sha256.<digest>, library://alpine@sha256:<digest> or 
+  docker://alpine@sha256:<digest>.
+
+  The image for the host architecture is pulled by default, --arch selects 
+  another architecture from library images and multi-platform docker/OCI 
+  images, --variant selecting the architecture variant, as v7 for arm, of 
+  docker/OCI images. The pull fails if the requested architecture is not 
+  available. Docker/OCI images are cached by digest of the selected 
+  platform manifest, so pulls for different architectures don't share 
+  their cache entries and pulling a reference again gives the same image 
+  until the reference changes upstream.`
 	PullExample string = `
   From Sylabs cloud library
   $ singularity pull alpine.sif library://alpine:latest
@@ -594,6 +1220,9 @@ Enterprise Performance Computing (EPC)`
   From Docker
   $ singularity pull tensorflow.sif docker://tensorflow/tensorflow:latest
 
+  From Docker, for the arm64 architecture
+  $ singularity pull --arch arm64 alpine_arm64.sif docker://alpine:3.12
+
   From Shub
   $ singularity pull singularity-images.sif shub://vsoch/singularity-images
 
@@ -615,6 +1244,21 @@ Enterprise Performance Computing (EPC)`
   oras:
       oras://registry/namespace/repo:tag
 
+  docker:
+      docker://registry/namespace/repo:tag
+
+  Images pushed to docker:// URIs, or with the --oci option, are converted 
+  to single layer OCI images usable by docker and podman. The variables set 
+  by %environment and the docker/OCI source image become the image Env, the 
+  labels become the image Labels, and the runscript becomes the Entrypoint, 
+  except for the runscripts generated from a docker/OCI source image whose 
+  Entrypoint and Cmd are kept. The startscript, the SCIF apps and the %test 
+  and %help sections can't be represented in OCI images, they are only kept 
+  in the image filesystem. The annotations recorded with 'singularity build 
+  --annotate' become the manifest annotations, non string values being 
+  stored as their JSON text. Images with overlay partitions and encrypted 
+  images can't be converted.
+
 
   NOTE: It's always good practice to sign your containers before
   pushing them to the library. An auth token is required to push to the library,
@@ -624,7 +1268,10 @@ Enterprise Performance Computing (EPC)`
   $ singularity push /home/user/my.sif library://user/collection/my.sif:latest
 
   To supported OCI registry
-  $ singularity push /home/user/my.sif oras://registry/namespace/image:tag`
+  $ singularity push /home/user/my.sif oras://registry/namespace/image:tag
+
+  As an OCI image, to a docker registry
+  $ singularity push /home/user/my.sif docker://registry/namespace/image:tag`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// search
@@ -651,7 +1298,7 @@ Enterprise Performance Computing (EPC)`
   automatically. All arguments following the container name will be passed
   directly to the runscript.
 
-  singularity run accepts the following container formats:` + formats
+  singularity run accepts the following container formats:` + formats + environment
 	RunExamples string = `
   # Here we see that the runscript prints "Hello world: "
   $ singularity exec /tmp/debian.sif cat /singularity
@@ -671,7 +1318,7 @@ Enterprise Performance Computing (EPC)`
 	ShellUse   string = `shell [shell options...] <container>`
 	ShellShort string = `Run a shell within a container`
 	ShellLong  string = `
-  singularity shell supports the following formats:` + formats
+  singularity shell supports the following formats:` + formats + environment
 	ShellExamples string = `
   $ singularity shell /tmp/Debian.sif
   Singularity/Debian.sif> pwd
@@ -707,10 +1354,19 @@ Enterprise Performance Computing (EPC)`
   The sign command allows a user to add one or more digital signatures to a SIF
   image. By default, one digital signature is added for each object group in
   the file.
+
+  The --remove-legacy option removes the legacy signatures of the image, 
+  accepted by 'singularity verify --legacy-insecure' only, before signing 
+  it, to migrate an image whose provenance was confirmed to the current 
+  signature format.
   
   To generate a keypair, see 'singularity help key newpair'`
 	SignExample string = `
-  $ singularity sign container.sif`
+  $ singularity sign container.sif
+
+  Replace the legacy signatures of an image:
+  $ singularity verify --legacy-insecure container.sif
+  $ singularity sign --remove-legacy container.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// verify
@@ -723,9 +1379,193 @@ Enterprise Performance Computing (EPC)`
   multiple data objects signed. By default the command searches for the primary 
   partition signature. If found, a list of all verification blocks applied on 
   the primary partition is gathered so that data integrity (hashing) and 
-  signature verification is done for all those blocks.`
+  signature verification is done for all those blocks.
+
+  Objects may be signed by several keys, one signature per key. With the 
+  --threshold option, verification succeeds only if signed objects carry 
+  valid signatures from at least that number of distinct keys, signatures 
+  from unknown or invalid keys are then reported but don't cause 
+  verification to fail, unlike signatures not matching the signed objects. 
+  The --keyring option restricts the trusted keys to those found in a 
+  keyring file.
+
+  Images signed by Singularity versions prior to 3.6 carry legacy 
+  signatures, which don't cover the SIF header and descriptors and are 
+  rejected by default. The --legacy-insecure option verifies these 
+  signatures instead, as a migration aid to confirm the provenance of an 
+  image before re-signing it with 'singularity sign --remove-legacy'. A 
+  warning is displayed and a successful verification exits with status 3 
+  instead of 0, so it can't be mistaken for a regular one.`
 	VerifyExample string = `
-  $ singularity verify container.sif`
+  $ singularity verify container.sif
+
+  Require valid signatures from at least 2 of the keys in team.asc:
+  $ singularity verify --threshold 2 --keyring team.asc container.sif`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif digest
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifDigestUse   string = `digest <sif path>`
+	SifDigestShort string = `Display the digest and UUID of a SIF image`
+	SifDigestLong  string = `
+  The sif digest command displays the SHA-256 digest computed over the 
+  content of a SIF image file, along with the UUID found in the SIF global 
+  header. Those are the same values reported at the end of a successful 
+  build.`
+	SifDigestExample string = `
+  $ singularity sif digest container.sif`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif extract
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifExtractUse   string = `extract --name <name> [extract options...] <sif path>`
+	SifExtractShort string = `Extract a named data file from a SIF image`
+	SifExtractLong  string = `
+  The sif extract command writes the content of a data file stored by the 
+  %datafile definition section in the data files partition of a SIF image, 
+  which requires unsquashfs. By default, 
+  the data file is written in the current directory with its name as file 
+  name, an existing file is never overwritten.`
+	SifExtractExample string = `
+  $ singularity sif extract --name model container.sif
+
+  $ singularity sif extract --name model --output - container.sif | sha256sum`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif header
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifHeaderUse   string = `header [header options...] <sif path>`
+	SifHeaderShort string = `Display the raw fields of the SIF global header`
+	SifHeaderLong  string = `
+  The sif header command displays the fields of the SIF global header as 
+  found in the image: the magic, the SIF specification version the image 
+  claims to conform to, the architecture, the UUID, the descriptor counts 
+  and the offsets and lengths of the descriptor table and of the data 
+  section. Only the global header is read, so the command also works on 
+  images with an unsupported version or a corrupted descriptor table, use 
+  'singularity sif verify-layout' to locate such problems.`
+	SifHeaderExample string = `
+  $ singularity sif header container.sif
+
+  $ singularity sif header --json container.sif`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif list
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifListUse   string = `list [list options...] <sif path>`
+	SifListShort string = `List the data object descriptors of a SIF image`
+	SifListLong  string = `
+  The sif list command displays the used data object descriptors of a SIF 
+  image as a table. With --json, the descriptors are printed instead as a 
+  JSON array where each descriptor has its ID, group ID, data type, name, 
+  data offset and size in the file and creation Unix time, partitions 
+  also have their file system type, partition type and architecture.`
+	SifListExample string = `
+  $ singularity sif list container.sif
+
+  $ singularity sif list --json container.sif | jq '.[] | select(.partType == "primary system")'`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif new
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifNewUse   string = `new <sif path>`
+	SifNewShort string = `Create an empty SIF image`
+	SifNewLong  string = `
+  The sif new command creates an empty SIF image holding only the global 
+  header and an empty descriptor table. Data objects are then added with 
+  'singularity sif add' and the primary system partition is selected with 
+  'singularity sif setprim', which allows to assemble custom images outside 
+  of the build. An existing file is never overwritten and the layout of the 
+  created image is checked as by 'singularity sif verify-layout'.`
+	SifNewExample string = `
+  $ singularity sif new container.sif
+  $ singularity sif add --datatype 4 --parttype 1 --partfs 1 --partarch 2 \
+      container.sif rootfs.squashfs
+  $ singularity sif list container.sif
+  $ singularity sif setprim 1 container.sif`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif setprim
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifSetPrimUse   string = `setprim <descriptor id> <sif path>`
+	SifSetPrimShort string = `Set the primary system partition of a SIF image`
+	SifSetPrimLong  string = `
+  The sif setprim command marks the system partition identified by its 
+  descriptor ID as the primary partition, the one mounted by the runtime as 
+  the container root filesystem. The previous primary partition becomes a 
+  regular system partition and the SIF header architecture is updated. The 
+  partition must hold a squashfs, ext3 or encrypted squashfs file system. 
+  Descriptor IDs are displayed by 'singularity sif list'.`
+	SifSetPrimExample string = `
+  $ singularity sif add --datatype 4 --parttype 1 --partfs 1 --partarch 2 \
+      container.sif rootfs.squashfs
+  $ singularity sif list container.sif
+  $ singularity sif setprim 5 container.sif`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif unsign
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifUnsignUse   string = `unsign [unsign options...] <sif path>`
+	SifUnsignShort string = `Remove the signatures of a SIF image`
+	SifUnsignLong  string = `
+  The sif unsign command removes the signature descriptors of a SIF image, 
+  for example before signing it again with a new key. With --group-id, only 
+  the signatures of the objects of this group are removed, legacy signatures 
+  being matched by the group of the object they sign. The other descriptors 
+  and their data are left untouched, the data of the removed signatures is 
+  truncated from the end of the image and the global header is updated, so 
+  the image still passes 'singularity sif verify-layout'.`
+	SifUnsignExample string = `
+  $ singularity sif unsign container.sif
+  $ singularity sign --keyidx 1 container.sif
+
+  $ singularity sif unsign --group-id 1 container.sif`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// sif verify-layout
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	SifVerifyLayoutUse   string = `verify-layout <sif path>`
+	SifVerifyLayoutShort string = `Check the structure of a SIF image for corruption`
+	SifVerifyLayoutLong  string = `
+  The sif verify-layout command checks that a SIF image file is structurally 
+  sound: the global header magic and version, the bounds of the descriptor 
+  table and of the data section, that every used descriptor references data 
+  within the data section, and that the file length matches the end of the 
+  last descriptor data, which reveals truncated images. The first problem 
+  found is reported with its byte offset in the file.
+
+  SIF images don't store checksums of their data objects outside of 
+  signatures, the integrity of the data itself is checked by 'singularity 
+  verify'.`
+	SifVerifyLayoutExample string = `
+  $ singularity sif verify-layout container.sif`
+
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	// lint
+	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
+	LintUse   string = `lint [lint options...] <sif path>`
+	LintShort string = `Check a SIF image against a set of lint rules`
+	LintLong  string = `
+  The lint command checks a SIF image with a squashfs root filesystem against
+  the following rules and reports a pass, warn or fail status for each of them:
+
+    runscript    the image provides an executable runscript
+    labels       the image labels are valid JSON with well-formed names
+    setuid       the image doesn't contain world-writable setuid/setgid files
+    environment  the environment scripts are syntactically valid
+    size         the image size is within the budget set with --max-size
+
+  Rules can be disabled individually with --skip. The command exits with a
+  non-zero status if any of the checked rules fails, warnings don't affect
+  the exit status.`
+	LintExample string = `
+  $ singularity lint container.sif
+
+  Check the image is smaller than 500 MiB, without checking labels:
+  $ singularity lint --max-size 500 --skip labels container.sif
+
+  Print the results in JSON format:
+  $ singularity lint --json container.sif`
 
 	// ~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~~
 	// Run-help
@@ -768,6 +1608,49 @@ Enterprise Performance Computing (EPC)`
   Inspect will show you labels, environment variables, apps and scripts associated 
   with the image determined by the flags you pass. By default, they will be shown in 
   plain text. If you would like to list them in json format, you should use the --json flag.
+
+  The --deffile flag shows the definition file embedded in the image at build time. 
+  With --json, it is reported along with its SHA-256 digest and the build 
+  arguments it references with the values recorded in SIF images at build 
+  time, which must be provided to rebuild the image. Values are empty for 
+  images without recorded build arguments. 
+  Inspecting an image without embedded definition file fails with an explicit error. 
+  The definition file of a SIF image is checked against the checksum recorded 
+  when it was written, a corrupted definition file is reported as an error.
+
+  The --list-archs flag lists the architectures of the root filesystems stored in a 
+  SIF image, starting with the primary one, as built with 'singularity build --platform'.
+
+  The --layers flag lists the digests of the docker/OCI source image layers kept in a 
+  SIF image, bottom layer first, as built with 'singularity build --keep-layers'.
+
+  The --annotations flag shows the annotations recorded in a SIF image with 
+  'singularity build --annotate', string values as plain text and other 
+  values as JSON. With --json, the "annotations" object holds their JSON values.
+
+  The --list-apps flag lists the SCIF apps installed under /scif/apps, each 
+  with the first line of its help and its labels. With --json, the "apps" 
+  object holds the labels and full help of each app, and is empty for 
+  images without apps.
+
+  The --environment flag shows the environment variables set by the image 
+  environment scripts as KEY=VALUE lines, sorted by name. The scripts are 
+  evaluated without running any command in the order they are sourced at 
+  runtime, the docker environment first, then the %environment section and 
+  the SINGULARITY_ENVIRONMENT script, and the ones of the app with --app. 
+  Appended values, as PATH=/opt/bin:$PATH, are resolved against the previous 
+  scripts and the default PATH. References to variables only known at runtime 
+  are kept as ${NAME} and such values, as the ones set by a script running a 
+  command, are flagged as depending on the runtime environment. With --json, 
+  the "variables" array lists each variable with its value, the script 
+  setting it and a "runtime" flag, the "environment" object holding the 
+  scripts themselves.
+
+  The --json output lists the signatures of a SIF image in a "signatures" array, 
+  empty for unsigned images, with the signed object group or object, the signing 
+  key fingerprint and the signing time. With --keyring, each signature is also 
+  verified against the public keys of a keyring file, its result and any error 
+  being reported. Legacy signatures are listed but not verified.
   `
 	InspectExample string = `
   $ singularity inspect ubuntu.sif
@@ -14,6 +14,7 @@ import (
 	"os"
 	"path/filepath"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -1535,6 +1536,179 @@ func (c actionTests) exitSignals(t *testing.T) {
 	}
 }
 
+// actionRunApp checks that run --app forwards the arguments verbatim to the
+// app runscript and exits with the app exit code.
+func (c actionTests) actionRunApp(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	tests := []struct {
+		name   string
+		argv   []string
+		output string
+		exit   int
+	}{
+		{
+			name:   "NoArgs",
+			argv:   []string{"--app", "args", c.env.ImagePath},
+			output: "",
+			exit:   0,
+		},
+		{
+			name:   "Args",
+			argv:   []string{"--app", "args", c.env.ImagePath, "one", "two"},
+			output: "[one]\n[two]",
+			exit:   0,
+		},
+		{
+			name:   "ArgsWithSpaces",
+			argv:   []string{"--app", "args", c.env.ImagePath, "one two", " three ", ""},
+			output: "[one two]\n[ three ]\n[]",
+			exit:   0,
+		},
+		{
+			name:   "ArgsWithQuotes",
+			argv:   []string{"--app", "args", c.env.ImagePath, `"quoted"`, "it's", "$HOME", "*"},
+			output: "[\"quoted\"]\n[it's]\n[$HOME]\n[*]",
+			exit:   0,
+		},
+		{
+			name:   "OptionLikeArgs",
+			argv:   []string{"--app", "args", c.env.ImagePath, "--app", "foo", "-c"},
+			output: "[--app]\n[foo]\n[-c]",
+			exit:   0,
+		},
+		{
+			// a double dash before the image ends the singularity options
+			name:   "DoubleDashBeforeImage",
+			argv:   []string{"--app", "args", "--", c.env.ImagePath, "one"},
+			output: "[one]",
+			exit:   0,
+		},
+		{
+			// a double dash after the image is an argument of the app
+			name:   "DoubleDashAfterImage",
+			argv:   []string{"--app", "args", c.env.ImagePath, "--", "one"},
+			output: "[--]\n[one]",
+			exit:   0,
+		},
+		{
+			name:   "Exit1",
+			argv:   []string{"--app", "args", c.env.ImagePath, "--exit", "1"},
+			output: "[--exit]\n[1]",
+			exit:   1,
+		},
+		{
+			name:   "Exit42",
+			argv:   []string{"--app", "args", c.env.ImagePath, "--exit", "42"},
+			output: "[--exit]\n[42]",
+			exit:   42,
+		},
+		{
+			name:   "Exit255",
+			argv:   []string{"--app", "args", c.env.ImagePath, "--exit", "255"},
+			output: "[--exit]\n[255]",
+			exit:   255,
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("run"),
+			e2e.WithArgs(tt.argv...),
+			e2e.ExpectExit(
+				tt.exit,
+				e2e.ExpectOutput(e2e.ExactMatch, tt.output),
+			),
+		)
+	}
+
+	// an app without runscript fails instead of running the container runscript
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("AppWithoutRunscript"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("run"),
+		e2e.WithArgs("--app", "bar", c.env.ImagePath, "true"),
+		e2e.ExpectExit(
+			1,
+			e2e.ExpectError(e2e.ContainMatch, "no runscript for contained app: bar"),
+		),
+	)
+}
+
+// actionAppEnv checks that exec --app sets the environment of the selected
+// app only, without the %appenv variables of the other apps or the SCIF app
+// variables of the host environment, which are kept without --app.
+func (c actionTests) actionAppEnv(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	// as set by an app starting another container app
+	hostEnv := append(os.Environ(), "SCIF_APPNAME=foo", "SCIF_APPROOT=/scif/apps/foo")
+
+	tests := []struct {
+		name     string
+		argv     []string
+		wanted   []string
+		unwanted []string
+	}{
+		{
+			name:     "TestApp",
+			argv:     []string{"--app", "testapp"},
+			wanted:   []string{"TESTAPP=testapp", "SCIF_APPNAME=testapp", "SCIF_APPROOT=/scif/apps/testapp", "SCIF_APPBIN=/scif/apps/testapp/bin"},
+			unwanted: []string{"HELLOTHISIS="},
+		},
+		{
+			name:     "Foo",
+			argv:     []string{"--app", "foo"},
+			wanted:   []string{"HELLOTHISIS=foo", "SCIF_APPNAME=foo", "SCIF_APPROOT=/scif/apps/foo"},
+			unwanted: []string{"TESTAPP="},
+		},
+		{
+			name:     "NoApp",
+			wanted:   []string{"SCIF_APPNAME=foo", "SCIF_APPROOT=/scif/apps/foo"},
+			unwanted: []string{"TESTAPP=", "HELLOTHISIS="},
+		},
+	}
+
+	for _, tt := range tests {
+		wanted, unwanted := tt.wanted, tt.unwanted
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithEnv(hostEnv),
+			e2e.WithArgs(append(tt.argv, c.env.ImagePath, "env")...),
+			e2e.ExpectExit(0, func(t *testing.T, r *e2e.SingularityCmdResult) {
+				vars := strings.Split(string(r.Stdout), "\n")
+				for _, w := range wanted {
+					if !hasEnvLine(vars, w) {
+						t.Errorf("%s not found in the app environment", w)
+					}
+				}
+				for _, u := range unwanted {
+					if hasEnvLine(vars, u) {
+						t.Errorf("unexpected %s found in the app environment", u)
+					}
+				}
+			}),
+		)
+	}
+}
+
+// hasEnvLine returns if one of the env output lines vars starts with prefix.
+func hasEnvLine(vars []string, prefix string) bool {
+	for _, v := range vars {
+		if strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
 func (c actionTests) fuseMount(t *testing.T) {
 	require.Filesystem(t, "fuse")
 
@@ -2068,6 +2242,8 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 		"network":               c.actionNetwork,       // test basic networking
 		"binds":                 c.actionBinds,         // test various binds
 		"exit and signals":      c.exitSignals,         // test exit and signals propagation
+		"run app":               c.actionRunApp,        // test run --app arguments and exit code
+		"app env":               c.actionAppEnv,        // test exec --app environment isolation
 		"fuse mount":            c.fuseMount,           // test fusemount option
 		"bind image":            c.bindImage,           // test bind image
 	}
@@ -6,6 +6,8 @@
 package inspect
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"io"
 	"os"
@@ -172,6 +174,16 @@ func (c ctx) singularityInspect(t *testing.T) {
 				if !reflect.DeepEqual(apps, out) {
 					t.Errorf("unexpected apps returned, got %v instead of %v", apps, out)
 				}
+				hello := meta.Attributes.Apps["hello"]
+				if hello == nil {
+					return
+				}
+				if v := hello.Labels["HELLOTHISIS"]; v != "hello" {
+					t.Errorf("unexpected hello app label HELLOTHISIS, got %q instead of %q", v, "hello")
+				}
+				if v := hello.Helpfile; v != "This is the help for hello!" {
+					t.Errorf("unexpected hello app help, got %q instead of %q", v, "This is the help for hello!")
+				}
 			},
 		},
 		{
@@ -287,6 +299,29 @@ func (c ctx) singularityInspect(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:    "deffile digest",
+			insType: "--deffile",
+			compareFn: func(t *testing.T, meta *inspect.Metadata) {
+				if meta.Attributes.Deffile == "" {
+					t.Fatalf("empty deffile")
+				}
+				sum := sha256.Sum256([]byte(meta.Attributes.Deffile))
+				if v, out := meta.Attributes.DeffileDigest, "sha256:"+hex.EncodeToString(sum[:]); v != out {
+					t.Errorf("unexpected deffile digest, got %s instead of %s", v, out)
+				}
+			},
+		},
+		{
+			name:    "signatures",
+			insType: "--labels",
+			compareFn: func(t *testing.T, meta *inspect.Metadata) {
+				// unsigned images report an empty array
+				if v := meta.Attributes.Signatures; v == nil || len(v) != 0 {
+					t.Errorf("unexpected signatures, got %v instead of an empty array", v)
+				}
+			},
+		},
 		{
 			name:    "runscript app world",
 			insType: "--runscript",
@@ -335,6 +335,34 @@ func (c *ctx) applyCgroupsInstance(t *testing.T) {
 	)
 }
 
+// Test that instance start runs the startscript of the app selected with
+// --app, with the app environment and the startscript arguments.
+func (c *ctx) testAppStartscript(t *testing.T) {
+	const instanceName = "appstart"
+	const message = "startscript-argument"
+	port := instanceStartPort + 20
+
+	c.env.RunSingularity(
+		t,
+		e2e.WithProfile(c.profile),
+		e2e.WithCommand("instance start"),
+		e2e.WithArgs("--app", "foo", c.env.ImagePath, instanceName, strconv.Itoa(port), message),
+		e2e.PostRun(func(t *testing.T) {
+			if t.Failed() {
+				return
+			}
+			defer c.stopInstance(t, instanceName)
+
+			// the process spawned by the startscript answers with the
+			// app environment and the second argument
+			if response := readLine(t, port); response != "foo "+message+"\n" {
+				t.Errorf("Bad response %q, expected %q", response, "foo "+message+"\n")
+			}
+		}),
+		e2e.ExpectExit(0),
+	)
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := &ctx{
@@ -361,6 +389,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 				{"InstanceFromURI", c.testInstanceFromURI},
 				{"CreateManyInstances", c.testCreateManyInstances},
 				{"StopAll", c.testStopAll},
+				{"AppStartscript", c.testAppStartscript},
 				{"GhostInstance", c.testGhostInstance},
 				{"ApplyCgroupsInstance", c.applyCgroupsInstance},
 			}
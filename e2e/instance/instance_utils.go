@@ -135,3 +135,26 @@ func echo(t *testing.T, port int) {
 		break
 	}
 }
+
+// Reads the first line sent by a server listening on port.
+func readLine(t *testing.T, port int) string {
+	// give it some time for responding, attempt 10 times by
+	// waiting 100 millisecond between each try
+	for retries := 0; ; retries++ {
+		sock, sockErr := net.Dial("tcp", "127.0.0.1:"+strconv.Itoa(port))
+		if sockErr != nil && retries < 10 {
+			time.Sleep(100 * time.Millisecond)
+			continue
+		} else if sockErr != nil {
+			t.Errorf("Failed to dial server: %v", sockErr)
+			return ""
+		}
+		defer sock.Close()
+
+		response, err := bufio.NewReader(sock).ReadString('\n')
+		if err != nil {
+			t.Errorf("Failed to read response: %v", err)
+		}
+		return response
+	}
+}
@@ -572,6 +572,49 @@ func (c ctx) testPullUmask(t *testing.T) {
 	}
 }
 
+// testPullArch pulls docker images for a foreign architecture and
+// checks the architecture recorded in the pulled image.
+func (c ctx) testPullArch(t *testing.T) {
+	imgPath := filepath.Join(c.env.TestDir, "alpine-arm64.sif")
+	defer os.Remove(imgPath)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("Arm64"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("pull"),
+		e2e.WithArgs("--force", "--arch", "arm64", imgPath, "docker://alpine:3.12"),
+		e2e.ExpectExit(0),
+	)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("Arm64Arch"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("inspect"),
+		e2e.WithArgs("--list-archs", imgPath),
+		e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ExactMatch, "arm64")),
+	)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("UnavailableArch"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("pull"),
+		e2e.WithArgs("--force", "--arch", "riscv64", imgPath, "docker://alpine:3.12"),
+		e2e.ExpectExit(255, e2e.ExpectError(e2e.ContainMatch, "requested platform not available")),
+	)
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("LibraryVariant"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("pull"),
+		e2e.WithArgs("--force", "--variant", "v7", imgPath, "library://alpine:3.11"),
+		e2e.ExpectExit(255, e2e.ExpectError(e2e.ContainMatch, "--variant is only supported")),
+	)
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := ctx{
@@ -590,6 +633,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 
 			t.Run("pull", c.testPullCmd)
 			t.Run("pullDisableCache", c.testPullDisableCacheCmd)
+			t.Run("pullArch", c.testPullArch)
 		}),
 	}
 }
@@ -3,7 +3,7 @@
 // LICENSE.md file distributed with the sources of this project regarding your
 // rights to use or distribute this software.
 
-// Package push tests only test the oras transport (and a invalid transport) against a local registry
+// Package push tests only test the oras and docker transports (and a invalid transport) against a local registry
 package push
 
 import (
@@ -131,6 +131,76 @@ func (c ctx) testPushCmd(t *testing.T) {
 	}
 }
 
+func (c ctx) testPushOCI(t *testing.T) {
+	e2e.EnsureImage(t, c.env)
+
+	e2e.PrepRegistry(t, c.env)
+
+	tmpdir, err := ioutil.TempDir(c.env.TestDir, "push_oci.")
+	if err != nil {
+		t.Fatalf("Failed to create temporary directory for push test: %+v", err)
+	}
+	defer os.RemoveAll(tmpdir)
+
+	tests := []struct {
+		desc   string
+		args   []string
+		dstURI string
+	}{
+		{
+			desc:   "docker transport",
+			dstURI: fmt.Sprintf("docker://%s/oci_export:docker", c.env.TestRegistry),
+		},
+		{
+			desc:   "oras transport",
+			args:   []string{"--oci"},
+			dstURI: fmt.Sprintf("oras://%s/oci_export:oras", c.env.TestRegistry),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.desc+"/push"),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("push"),
+			e2e.WithArgs(append(tt.args, "--nohttps", c.env.ImagePath, tt.dstURI)...),
+			e2e.ExpectExit(0),
+		)
+
+		// the pushed image is an OCI image pulled like any docker image
+		pullURI := "docker://" + strings.SplitN(tt.dstURI, "://", 2)[1]
+		imagePath := filepath.Join(tmpdir, strings.Replace(tt.desc, " ", "_", -1)+".sif")
+
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.desc+"/pull"),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("pull"),
+			e2e.WithArgs("--nohttps", imagePath, pullURI),
+			e2e.ExpectExit(0),
+		)
+
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.desc+"/exec"),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand("exec"),
+			e2e.WithArgs(imagePath, "/bin/sh", "-c", "test -x /.singularity.d/runscript && echo $PATH"),
+			e2e.ExpectExit(0, e2e.ExpectOutput(e2e.ContainMatch, "/usr/bin")),
+		)
+	}
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("library transport"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("push"),
+		e2e.WithArgs("--oci", c.env.ImagePath, "library://user/collection/oci_export:test"),
+		e2e.ExpectExit(255, e2e.ExpectError(e2e.ContainMatch, "--oci is only supported")),
+	)
+}
+
 // E2ETests is the main func to trigger the test suite
 func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	c := ctx{
@@ -140,5 +210,6 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 	return testhelper.Tests{
 		"invalid transport": c.testInvalidTransport,
 		"oras":              c.testPushCmd,
+		"oci":               c.testPushOCI,
 	}
 }
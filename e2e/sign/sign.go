@@ -197,6 +197,77 @@ func (c ctx) singularitySignKeyidxOption(t *testing.T) {
 	)
 }
 
+// singularitySifUnsign checks that signatures removed by 'sif unsign' are
+// no longer reported, that the image layout stays valid and that the image
+// can be signed again.
+func (c ctx) singularitySifUnsign(t *testing.T) {
+	imgPath, cleanup := c.prepareImage(t)
+	defer cleanup(t)
+
+	c.env.KeyringDir = c.keyringDir
+	c.env.ImgCacheDir = c.imgCache
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("sign"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sign"),
+		e2e.WithArgs(imgPath),
+		e2e.ConsoleRun(c.passphraseInput...),
+		e2e.ExpectExit(0),
+	)
+
+	tests := []struct {
+		name     string
+		cmd      string
+		args     []string
+		expectOp e2e.SingularityCmdResultOp
+	}{
+		{
+			name:     "unsign other group",
+			cmd:      "sif unsign",
+			args:     []string{"--group-id", "5", imgPath},
+			expectOp: e2e.ExpectError(e2e.ContainMatch, "No signature found"),
+		},
+		{
+			name:     "unsign",
+			cmd:      "sif unsign",
+			args:     []string{imgPath},
+			expectOp: e2e.ExpectError(e2e.ContainMatch, "Removed 1 signature(s)"),
+		},
+		{
+			name:     "verify layout",
+			cmd:      "sif verify-layout",
+			args:     []string{imgPath},
+			expectOp: e2e.ExpectError(e2e.ContainMatch, "layout is valid"),
+		},
+	}
+
+	for _, tt := range tests {
+		c.env.RunSingularity(
+			t,
+			e2e.AsSubtest(tt.name),
+			e2e.WithProfile(e2e.UserProfile),
+			e2e.WithCommand(tt.cmd),
+			e2e.WithArgs(tt.args...),
+			e2e.ExpectExit(0, tt.expectOp),
+		)
+	}
+
+	c.env.RunSingularity(
+		t,
+		e2e.AsSubtest("sign again"),
+		e2e.WithProfile(e2e.UserProfile),
+		e2e.WithCommand("sign"),
+		e2e.WithArgs(imgPath),
+		e2e.ConsoleRun(c.passphraseInput...),
+		e2e.ExpectExit(
+			0,
+			e2e.ExpectOutput(e2e.ContainMatch, "Signature created and applied to "+imgPath),
+		),
+	)
+}
+
 func (c *ctx) generateKeypair(t *testing.T) {
 	keyGenInput := []e2e.SingularityConsoleOp{
 		e2e.ConsoleSendLine("e2e sign test key"),
@@ -260,6 +331,7 @@ func E2ETests(env e2e.TestEnv) testhelper.Tests {
 			t.Run("singularitySignIDOption", c.singularitySignIDOption)
 			t.Run("singularitySignGroupIDOption", c.singularitySignGroupIDOption)
 			t.Run("singularitySignKeyidxOption", c.singularitySignKeyidxOption)
+			t.Run("singularitySifUnsign", c.singularitySifUnsign)
 		},
 	}
 }
@@ -34,6 +34,10 @@ type verifyOutput struct {
 const successURL = "library://sylabs/tests/verify_success:1.0.2"
 const corruptedURL = "library://sylabs/tests/verify_corrupted:1.0.1"
 
+// legacyExitCode is the exit status of a successful verification with
+// --legacy-insecure.
+const legacyExitCode = 3
+
 func getNameJSON(keyNum int) []string {
 	return []string{"SignerKeys", fmt.Sprintf("[%d]", keyNum), "Signer", "Name"}
 }
@@ -76,7 +80,7 @@ func (c ctx) singularityVerifyAllKeyNum(t *testing.T) {
 			expectNumOut: 2,
 			imageURL:     successURL,
 			imagePath:    c.successImage,
-			expectExit:   0,
+			expectExit:   legacyExitCode,
 		},
 	}
 
@@ -144,7 +148,7 @@ func (c ctx) singularityVerifySigner(t *testing.T) {
 			verifyLocal: false,
 			imageURL:    successURL,
 			imagePath:   c.successImage,
-			expectExit:  0,
+			expectExit:  legacyExitCode,
 			expectOutput: []verifyOutput{
 				{
 					name:        "WestleyK (Testing key; used for signing test containers) \u003cwestley@sylabs.io\u003e",
@@ -251,7 +255,7 @@ func (c ctx) checkGroupidOption(t *testing.T) {
 		e2e.WithCommand("verify"),
 		e2e.WithArgs(cmdArgs...),
 		e2e.ExpectExit(
-			0,
+			legacyExitCode,
 			e2e.ExpectOutput(e2e.RegexMatch, "Container verified: .*/verify_success.sif"),
 		),
 	)
@@ -265,7 +269,7 @@ func (c ctx) checkIDOption(t *testing.T) {
 		e2e.WithCommand("verify"),
 		e2e.WithArgs(cmdArgs...),
 		e2e.ExpectExit(
-			0,
+			legacyExitCode,
 			e2e.ExpectOutput(e2e.RegexMatch, "Container verified: .*/verify_success.sif"),
 		),
 	)
@@ -279,7 +283,7 @@ func (c ctx) checkAllOption(t *testing.T) {
 		e2e.WithCommand("verify"),
 		e2e.WithArgs(cmdArgs...),
 		e2e.ExpectExit(
-			0,
+			legacyExitCode,
 			e2e.ExpectOutput(e2e.RegexMatch, "Container verified: .*/verify_success.sif"),
 		),
 	)
@@ -297,7 +301,7 @@ func (c ctx) checkURLOption(t *testing.T) {
 		e2e.WithCommand("verify"),
 		e2e.WithArgs(cmdArgs...),
 		e2e.ExpectExit(
-			0,
+			legacyExitCode,
 			e2e.ExpectOutput(e2e.RegexMatch, "Container verified: .*/verify_success.sif"),
 		),
 	)
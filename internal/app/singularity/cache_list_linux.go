@@ -55,10 +55,15 @@ func listTypeCache(printList bool, name, cachePath string) (int, int64, error) {
 	}
 
 	var (
+		count     int
 		totalSize int64
 	)
 
 	for _, entry := range cacheEntries {
+		// lock files of entries being created are not entries
+		if strings.HasSuffix(entry.Name(), cache.LockSuffix) {
+			continue
+		}
 
 		if printList {
 			fmt.Printf("%-24.22s %-22s %-16s %s\n",
@@ -67,10 +72,11 @@ func listTypeCache(printList bool, name, cachePath string) (int, int64, error) {
 				findSize(entry.Size()),
 				name)
 		}
+		count++
 		totalSize += entry.Size()
 	}
 
-	return len(cacheEntries), totalSize, nil
+	return count, totalSize, nil
 }
 
 // ListSingularityCache will list the local singularity cache for the
@@ -0,0 +1,59 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"fmt"
+
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// VerifySingularityCache checks the checksum of the entries of the cache
+// types listed in cacheVerifyTypes, all types if it contains "all", and
+// reports the corrupted entries. If fix is true, the corrupted entries
+// are removed. The number of corrupted entries left in the cache is
+// returned.
+func VerifySingularityCache(imgCache *cache.Handle, cacheVerifyTypes []string, fix bool) (int, error) {
+	if imgCache == nil {
+		return 0, errInvalidCacheHandle
+	}
+
+	cachesToVerify := append(cache.OciCacheTypes, cache.FileCacheTypes...)
+	if len(cacheVerifyTypes) > 0 && !stringInSlice("all", cacheVerifyTypes) {
+		cachesToVerify = cacheVerifyTypes
+	}
+
+	var valid, corrupted, removed, unverified int
+	for _, cacheType := range cachesToVerify {
+		sylog.Debugf("Verifying %s cache...", cacheType)
+		entries, err := imgCache.VerifyCache(cacheType, fix)
+		if err != nil {
+			return corrupted - removed, err
+		}
+
+		for _, e := range entries {
+			switch e.Status {
+			case cache.EntryValid:
+				valid++
+			case cache.EntryUnverified:
+				sylog.Verbosef("No checksum recorded for %s cache entry %s", e.CacheType, e.Name)
+				unverified++
+			case cache.EntryCorrupted:
+				corrupted++
+				if e.Removed {
+					removed++
+					fmt.Printf("Removed corrupted %s cache entry %s\n", e.CacheType, e.Name)
+				} else {
+					fmt.Printf("Corrupted %s cache entry %s\n", e.CacheType, e.Name)
+				}
+			}
+		}
+	}
+
+	fmt.Printf("%d valid, %d corrupted (%d removed) and %d unverified cache entries\n", valid, corrupted, removed, unverified)
+	return corrupted - removed, nil
+}
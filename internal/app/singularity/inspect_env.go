@@ -0,0 +1,160 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/env"
+	"github.com/sylabs/singularity/internal/pkg/util/shell/interpreter"
+	"github.com/sylabs/singularity/pkg/inspect"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+var (
+	// envNameRegexp matches the name of an environment variable.
+	envNameRegexp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// runtimeRefRegexp matches the references to runtime variables
+	// kept in the resolved values.
+	runtimeRefRegexp = regexp.MustCompile(`\$\{([a-zA-Z_][a-zA-Z0-9_]*)\}`)
+)
+
+// EnvironmentVariables evaluates the container environment scripts,
+// scripts holding their content indexed by path, in the order of their
+// file names and returns the variables they set sorted by name. Scripts
+// are evaluated without command execution, on top of the variables base
+// set by previous scripts and of the default PATH. The references to
+// variables not set by previous scripts, only known at runtime, are kept
+// as ${NAME} in the values, which are marked as runtime dependent. The
+// variables set by a script which can't be evaluated, as one running a
+// command, are given the shell word found in the script, marked as runtime
+// dependent unless it is a literal.
+func EnvironmentVariables(scripts map[string]string, base []inspect.Variable) []inspect.Variable {
+	paths := make([]string, 0, len(scripts))
+	for path := range scripts {
+		paths = append(paths, path)
+	}
+	sort.SliceStable(paths, func(i, j int) bool {
+		return filepath.Base(paths[i]) < filepath.Base(paths[j])
+	})
+
+	current := map[string]string{"PATH": env.DefaultPath}
+	runtimeRefs := make(map[string]bool)
+	vars := make(map[string]inspect.Variable)
+	for _, v := range base {
+		current[v.Name] = v.Value
+		vars[v.Name] = v
+		for _, m := range runtimeRefRegexp.FindAllStringSubmatch(v.Value, -1) {
+			runtimeRefs[m[1]] = true
+		}
+	}
+
+	for _, path := range paths {
+		content := scripts[path]
+		f, err := syntax.NewParser().Parse(strings.NewReader(content), path)
+		if err != nil {
+			sylog.Warningf("Ignoring environment script %s: %s", path, err)
+			continue
+		}
+
+		// references to unknown variables are resolved to ${NAME}
+		seed := make([]string, 0, len(current))
+		for k, v := range current {
+			seed = append(seed, k+"="+v)
+		}
+		for name := range referencedVars(f) {
+			if _, ok := current[name]; !ok {
+				runtimeRefs[name] = true
+				seed = append(seed, name+"=${"+name+"}")
+			}
+		}
+
+		set, err := interpreter.EvaluateEnv([]byte(content), nil, seed)
+		if err != nil {
+			sylog.Debugf("Unable to evaluate environment script %s: %s", path, err)
+			for _, v := range assignedVars(f) {
+				v.Source = path
+				vars[v.Name] = v
+				current[v.Name] = v.Value
+			}
+			continue
+		}
+
+		for _, e := range set {
+			kv := strings.SplitN(e, "=", 2)
+			if kv[1] == "${"+kv[0]+"}" {
+				continue
+			}
+			if prev, ok := current[kv[0]]; ok && prev == kv[1] && vars[kv[0]].Name == "" {
+				// default PATH exported as is
+				continue
+			}
+			v := inspect.Variable{Name: kv[0], Value: kv[1], Source: path}
+			for _, m := range runtimeRefRegexp.FindAllStringSubmatch(kv[1], -1) {
+				if runtimeRefs[m[1]] {
+					v.Runtime = true
+					break
+				}
+			}
+			vars[kv[0]] = v
+			current[kv[0]] = kv[1]
+		}
+	}
+
+	list := make([]inspect.Variable, 0, len(vars))
+	for _, v := range vars {
+		list = append(list, v)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
+
+// referencedVars returns the names of the variables referenced by the
+// script f.
+func referencedVars(f *syntax.File) map[string]bool {
+	names := make(map[string]bool)
+	syntax.Walk(f, func(node syntax.Node) bool {
+		if p, ok := node.(*syntax.ParamExp); ok && p.Param != nil {
+			if envNameRegexp.MatchString(p.Param.Value) {
+				names[p.Param.Value] = true
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// assignedVars returns the variables assigned by the script f with the
+// shell word of their value.
+func assignedVars(f *syntax.File) []inspect.Variable {
+	var vars []inspect.Variable
+	printer := syntax.NewPrinter()
+	syntax.Walk(f, func(node syntax.Node) bool {
+		a, ok := node.(*syntax.Assign)
+		if !ok || a.Name == nil || a.Naked {
+			return true
+		}
+		v := inspect.Variable{Name: a.Name.Value}
+		if a.Value != nil {
+			var b bytes.Buffer
+			if err := printer.Print(&b, a.Value); err != nil {
+				return true
+			}
+			v.Value = b.String()
+			v.Runtime = a.Value.Lit() == ""
+		}
+		vars = append(vars, v)
+		return true
+	})
+	return vars
+}
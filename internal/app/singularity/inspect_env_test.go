@@ -0,0 +1,91 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/util/env"
+	"github.com/sylabs/singularity/pkg/inspect"
+)
+
+func TestEnvironmentVariables(t *testing.T) {
+	const (
+		docker      = "/.singularity.d/env/10-docker2singularity.sh"
+		environment = "/.singularity.d/env/90-environment.sh"
+		app         = "/scif/apps/foo/scif/env/90-environment.sh"
+	)
+
+	tests := []struct {
+		name    string
+		scripts map[string]string
+		base    []inspect.Variable
+		want    []inspect.Variable
+	}{
+		{
+			name:    "Empty",
+			scripts: map[string]string{environment: "#!/bin/sh\n# Custom environment shell code should follow\n"},
+			want:    []inspect.Variable{},
+		},
+		{
+			name: "Order",
+			scripts: map[string]string{
+				environment: "export FOO=$FOO:b\nexport BAR=bar",
+				docker:      "export FOO=\"a\"",
+			},
+			want: []inspect.Variable{
+				{Name: "BAR", Value: "bar", Source: environment},
+				{Name: "FOO", Value: "a:b", Source: environment},
+			},
+		},
+		{
+			name:    "AppendPath",
+			scripts: map[string]string{environment: "export PATH=/opt/bin:$PATH"},
+			want: []inspect.Variable{
+				{Name: "PATH", Value: "/opt/bin:" + env.DefaultPath, Source: environment},
+			},
+		},
+		{
+			name:    "Runtime",
+			scripts: map[string]string{environment: "export DATA=$HOME/data\nexport FOO=foo"},
+			want: []inspect.Variable{
+				{Name: "DATA", Value: "${HOME}/data", Source: environment, Runtime: true},
+				{Name: "FOO", Value: "foo", Source: environment},
+			},
+		},
+		{
+			name:    "Command",
+			scripts: map[string]string{environment: "export FOO=foo\nexport DATA=$HOME\nmkdir -p /data"},
+			want: []inspect.Variable{
+				{Name: "DATA", Value: "$HOME", Source: environment, Runtime: true},
+				{Name: "FOO", Value: "foo", Source: environment},
+			},
+		},
+		{
+			name:    "Base",
+			scripts: map[string]string{app: "export FOO=$FOO:b\nexport BAR=$DATA/bar"},
+			base: []inspect.Variable{
+				{Name: "DATA", Value: "${HOME}/data", Source: environment, Runtime: true},
+				{Name: "FOO", Value: "a", Source: environment},
+			},
+			want: []inspect.Variable{
+				{Name: "BAR", Value: "${HOME}/data/bar", Source: app, Runtime: true},
+				{Name: "DATA", Value: "${HOME}/data", Source: environment, Runtime: true},
+				{Name: "FOO", Value: "a:b", Source: app},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := EnvironmentVariables(tt.scripts, tt.base)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("unexpected variables:\nwant %+v\ngot  %+v", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/image"
+	"github.com/sylabs/singularity/pkg/image/unpacker"
+)
+
+// LintStatus is the result status of a lint rule.
+type LintStatus string
+
+const (
+	// LintPass is reported when the image complies with the rule.
+	LintPass LintStatus = "pass"
+	// LintWarn is reported for non fatal rule violations.
+	LintWarn LintStatus = "warn"
+	// LintFail is reported for rule violations.
+	LintFail LintStatus = "fail"
+)
+
+// LintResult holds the result of a lint rule.
+type LintResult struct {
+	Rule    string     `json:"rule"`
+	Status  LintStatus `json:"status"`
+	Message string     `json:"message"`
+}
+
+// LintOptions holds the lint rules configuration.
+type LintOptions struct {
+	// Disabled are the names of the rules not checked.
+	Disabled []string
+	// MaxSize is the image size budget in bytes, zero
+	// means there is no budget.
+	MaxSize int64
+}
+
+// lintImage holds the image content used by lint rules.
+type lintImage struct {
+	// path of the SIF image
+	path string
+	// rootfs is the directory where the image
+	// metadata directory has been extracted
+	rootfs string
+	// files lists the image root filesystem files
+	files []unpacker.FileInfo
+	opts  LintOptions
+}
+
+// lintRule describes a lint rule.
+type lintRule struct {
+	name        string
+	description string
+	check       func(*lintImage) (LintStatus, string)
+}
+
+// lintRules lists all the lint rules, in the order they are checked.
+var lintRules = []lintRule{
+	{
+		name:        "runscript",
+		description: "image provides an executable runscript",
+		check:       lintRunscript,
+	},
+	{
+		name:        "labels",
+		description: "image labels are valid JSON with well-formed names",
+		check:       lintLabels,
+	},
+	{
+		name:        "setuid",
+		description: "no world-writable setuid/setgid file",
+		check:       lintSetuid,
+	},
+	{
+		name:        "environment",
+		description: "environment scripts are syntactically valid",
+		check:       lintEnvironment,
+	},
+	{
+		name:        "size",
+		description: "image size within the size budget",
+		check:       lintSize,
+	},
+}
+
+// Lint checks the lint rules over the SIF image found at path and returns
+// the result of each enabled rule.
+func Lint(path string, opts LintOptions) ([]LintResult, error) {
+	for _, name := range opts.Disabled {
+		found := false
+		for _, r := range lintRules {
+			if r.name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown lint rule %s", name)
+		}
+	}
+
+	s, err := image.OpenSIF(path)
+	if err != nil {
+		return nil, err
+	}
+	defer s.Close()
+
+	part, err := s.Image().GetRootFsPartition()
+	if err != nil {
+		return nil, err
+	}
+
+	dir, err := ioutil.TempDir("", "lint-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// stage the root filesystem partition once for all
+	// the unsquashfs invocations
+	staging, err := os.Create(filepath.Join(dir, "rootfs.squashfs"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create staging file: %s", err)
+	}
+	defer staging.Close()
+
+	reader := io.NewSectionReader(s.Image().File, int64(part.Offset), int64(part.Size))
+	if _, err := io.Copy(staging, reader); err != nil {
+		return nil, fmt.Errorf("failed to copy root filesystem in staging file: %s", err)
+	}
+
+	img := &lintImage{
+		path:   path,
+		rootfs: filepath.Join(dir, "rootfs"),
+		opts:   opts,
+	}
+
+	u := unpacker.NewSquashfs()
+
+	if _, err := staging.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img.files, err = u.List(staging, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := staging.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	if err := u.ExtractFiles([]string{".singularity.d"}, staging, img.rootfs); err != nil {
+		return nil, err
+	}
+
+	var results []LintResult
+
+	for _, r := range lintRules {
+		disabled := false
+		for _, name := range opts.Disabled {
+			if name == r.name {
+				disabled = true
+				break
+			}
+		}
+		if disabled {
+			continue
+		}
+		status, msg := r.check(img)
+		results = append(results, LintResult{
+			Rule:    r.name,
+			Status:  status,
+			Message: msg,
+		})
+	}
+
+	return results, nil
+}
+
+func lintRunscript(img *lintImage) (LintStatus, string) {
+	fi, err := os.Lstat(filepath.Join(img.rootfs, ".singularity.d", "runscript"))
+	if err != nil {
+		return LintFail, "no runscript found"
+	} else if !fi.Mode().IsRegular() {
+		return LintFail, "runscript is not a regular file"
+	} else if fi.Mode().Perm()&0111 == 0 {
+		return LintFail, "runscript is not executable"
+	} else if fi.Size() == 0 {
+		return LintWarn, "runscript is empty"
+	}
+	return LintPass, "runscript found"
+}
+
+// labelNameRegexp matches well-formed label names.
+var labelNameRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/-]*$`)
+
+func lintLabels(img *lintImage) (LintStatus, string) {
+	data, err := ioutil.ReadFile(filepath.Join(img.rootfs, ".singularity.d", "labels.json"))
+	if os.IsNotExist(err) {
+		return LintWarn, "no labels found"
+	} else if err != nil {
+		return LintFail, fmt.Sprintf("could not read labels: %s", err)
+	}
+
+	labels := make(map[string]string)
+	if err := json.Unmarshal(data, &labels); err != nil {
+		return LintFail, fmt.Sprintf("labels are not valid JSON: %s", err)
+	}
+
+	var invalid []string
+	for name := range labels {
+		if !labelNameRegexp.MatchString(name) {
+			invalid = append(invalid, fmt.Sprintf("%q", name))
+		}
+	}
+	if len(invalid) > 0 {
+		sort.Strings(invalid)
+		return LintFail, fmt.Sprintf("malformed label name(s): %s", strings.Join(invalid, ", "))
+	}
+
+	return LintPass, fmt.Sprintf("%d label(s) found", len(labels))
+}
+
+func lintSetuid(img *lintImage) (LintStatus, string) {
+	var found []string
+	for _, f := range img.files {
+		if f.IsSetuid() && f.IsWorldWritable() {
+			found = append(found, f.Path)
+		}
+	}
+	if len(found) > 0 {
+		return LintFail, fmt.Sprintf("world-writable setuid/setgid file(s): %s", strings.Join(found, ", "))
+	}
+	return LintPass, "no world-writable setuid/setgid file found"
+}
+
+func lintEnvironment(img *lintImage) (LintStatus, string) {
+	scripts, err := filepath.Glob(filepath.Join(img.rootfs, ".singularity.d", "env", "*.sh"))
+	if err != nil {
+		return LintFail, err.Error()
+	}
+	if len(scripts) == 0 {
+		return LintWarn, "no environment script found"
+	}
+
+	var invalid []string
+	for _, script := range scripts {
+		fi, err := os.Lstat(script)
+		if err != nil || !fi.Mode().IsRegular() {
+			continue
+		}
+		// check syntax only without executing commands
+		if out, err := exec.Command("/bin/sh", "-n", script).CombinedOutput(); err != nil {
+			msg := strings.TrimSpace(strings.Replace(string(out), img.rootfs, "", -1))
+			invalid = append(invalid, fmt.Sprintf("%s (%s)", filepath.Base(script), msg))
+		}
+	}
+	if len(invalid) > 0 {
+		return LintFail, fmt.Sprintf("invalid environment script(s): %s", strings.Join(invalid, ", "))
+	}
+
+	return LintPass, fmt.Sprintf("%d environment script(s) checked", len(scripts))
+}
+
+func lintSize(img *lintImage) (LintStatus, string) {
+	fi, err := os.Stat(img.path)
+	if err != nil {
+		return LintFail, err.Error()
+	}
+	if img.opts.MaxSize <= 0 {
+		return LintPass, fmt.Sprintf("image size is %d bytes, no size budget set", fi.Size())
+	}
+	if fi.Size() > img.opts.MaxSize {
+		return LintFail, fmt.Sprintf("image size %d bytes exceeds the %d bytes budget", fi.Size(), img.opts.MaxSize)
+	}
+	return LintPass, fmt.Sprintf("image size %d bytes within the %d bytes budget", fi.Size(), img.opts.MaxSize)
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/image/unpacker"
+)
+
+func newLintRootfs(t *testing.T, files map[string]string) (string, func()) {
+	dir, err := ioutil.TempDir("", "lint-test-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, ".singularity.d", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+			t.Fatalf("failed to write %s: %s", path, err)
+		}
+	}
+	return dir, func() { os.RemoveAll(dir) }
+}
+
+func TestLintRules(t *testing.T) {
+	tests := []struct {
+		name   string
+		files  map[string]string
+		list   []unpacker.FileInfo
+		check  func(*lintImage) (LintStatus, string)
+		status LintStatus
+	}{
+		{
+			name:   "RunscriptPass",
+			files:  map[string]string{"runscript": "#!/bin/sh\n"},
+			check:  lintRunscript,
+			status: LintPass,
+		},
+		{
+			name:   "RunscriptEmpty",
+			files:  map[string]string{"runscript": ""},
+			check:  lintRunscript,
+			status: LintWarn,
+		},
+		{
+			name:   "RunscriptMissing",
+			check:  lintRunscript,
+			status: LintFail,
+		},
+		{
+			name:   "LabelsPass",
+			files:  map[string]string{"labels.json": `{"org.label-schema.version": "1.0"}`},
+			check:  lintLabels,
+			status: LintPass,
+		},
+		{
+			name:   "LabelsMissing",
+			check:  lintLabels,
+			status: LintWarn,
+		},
+		{
+			name:   "LabelsInvalidJSON",
+			files:  map[string]string{"labels.json": `{"version": `},
+			check:  lintLabels,
+			status: LintFail,
+		},
+		{
+			name:   "LabelsMalformedName",
+			files:  map[string]string{"labels.json": `{"my label": "value"}`},
+			check:  lintLabels,
+			status: LintFail,
+		},
+		{
+			name:   "EnvironmentPass",
+			files:  map[string]string{"env/90-environment.sh": "export FOO=bar\n"},
+			check:  lintEnvironment,
+			status: LintPass,
+		},
+		{
+			name:   "EnvironmentInvalid",
+			files:  map[string]string{"env/90-environment.sh": "if true; then\n"},
+			check:  lintEnvironment,
+			status: LintFail,
+		},
+		{
+			name:   "EnvironmentMissing",
+			check:  lintEnvironment,
+			status: LintWarn,
+		},
+		{
+			name: "SetuidPass",
+			list: []unpacker.FileInfo{
+				{Path: "/bin/su", Mode: "-rwsr-xr-x"},
+				{Path: "/tmp", Mode: "drwxrwxrwt"},
+			},
+			check:  lintSetuid,
+			status: LintPass,
+		},
+		{
+			name: "SetuidWorldWritable",
+			list: []unpacker.FileInfo{
+				{Path: "/bin/su", Mode: "-rwsr-xrwx"},
+			},
+			check:  lintSetuid,
+			status: LintFail,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			rootfs, cleanup := newLintRootfs(t, tt.files)
+			defer cleanup()
+
+			img := &lintImage{rootfs: rootfs, files: tt.list}
+			status, msg := tt.check(img)
+			if status != tt.status {
+				t.Errorf("unexpected status %s, wanted %s: %s", status, tt.status, msg)
+			}
+		})
+	}
+}
+
+func TestLintSize(t *testing.T) {
+	f, err := ioutil.TempFile("", "lint-size-")
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	f.Write(make([]byte, 1024))
+	f.Close()
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		status  LintStatus
+	}{
+		{"NoBudget", 0, LintPass},
+		{"WithinBudget", 2048, LintPass},
+		{"ExceedsBudget", 512, LintFail},
+	}
+
+	for _, tt := range tests {
+		img := &lintImage{path: f.Name(), opts: LintOptions{MaxSize: tt.maxSize}}
+		if status, msg := lintSize(img); status != tt.status {
+			t.Errorf("%s: unexpected status %s, wanted %s: %s", tt.name, status, tt.status, msg)
+		}
+	}
+}
+
+func TestLintUnknownRule(t *testing.T) {
+	if _, err := Lint("image.sif", LintOptions{Disabled: []string{"unknown"}}); err == nil {
+		t.Errorf("unexpected success with unknown rule")
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package singularity
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+
+	"github.com/sylabs/singularity/internal/pkg/runtime/engine/config/oci/generate"
+	"github.com/sylabs/singularity/internal/pkg/security/seccomp"
+)
+
+// SeccompExec loads the seccomp filter of the OCI profile and executes
+// args in place of the current process. Blocked system calls fail with
+// EPERM. It's used by the build to run the host sections under the
+// profile given with --seccomp-profile.
+func SeccompExec(profile string, args []string) error {
+	generator := generate.New(nil)
+	if err := seccomp.LoadProfileFromFile(profile, generator); err != nil {
+		return fmt.Errorf("while loading seccomp profile %s: %s", profile, err)
+	}
+
+	path, err := exec.LookPath(args[0])
+	if err != nil {
+		return err
+	}
+
+	// the filter is loaded for the current thread only, which must
+	// be the one executing the command
+	runtime.LockOSThread()
+
+	if err := seccomp.LoadSeccompConfig(generator.Config.Linux.Seccomp, true, int16(syscall.EPERM)); err != nil {
+		return err
+	}
+
+	return syscall.Exec(path, args, os.Environ())
+}
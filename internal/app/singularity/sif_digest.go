@@ -0,0 +1,50 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// SIFDigest holds the identity of a SIF image.
+type SIFDigest struct {
+	// SHA256 is the hex encoded SHA-256 digest of the image file.
+	SHA256 string `json:"sha256"`
+	// UUID is the unique identifier found in the SIF global header.
+	UUID string `json:"uuid"`
+}
+
+// GetSIFDigest returns the SHA-256 digest computed over the content of the SIF image found at
+// path, along with the UUID of the SIF image.
+func GetSIFDigest(path string) (SIFDigest, error) {
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		return SIFDigest{}, fmt.Errorf("while loading SIF image %s: %s", path, err)
+	}
+	defer f.UnloadContainer()
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return SIFDigest{}, err
+	}
+	defer fp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, fp); err != nil {
+		return SIFDigest{}, fmt.Errorf("while computing digest of %s: %s", path, err)
+	}
+
+	return SIFDigest{
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+		UUID:   f.Header.ID.String(),
+	}, nil
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetSIFDigest(t *testing.T) {
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "Image",
+			path: filepath.Join("testdata", "images", "one-group.sif"),
+		},
+		{
+			name:    "NotSIF",
+			path:    filepath.Join("testdata", "keys", "private.asc"),
+			wantErr: true,
+		},
+		{
+			name:    "NotFound",
+			path:    filepath.Join("testdata", "images", "not-found.sif"),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			d, err := GetSIFDigest(tt.path)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			b, err := ioutil.ReadFile(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sum := sha256.Sum256(b)
+
+			if got, want := d.SHA256, hex.EncodeToString(sum[:]); got != want {
+				t.Errorf("got digest %v, want %v", got, want)
+			}
+			if d.UUID == "" {
+				t.Errorf("empty UUID")
+			}
+		})
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sylabs/singularity/pkg/image"
+	"github.com/sylabs/singularity/pkg/image/unpacker"
+)
+
+// ExtractDataFile writes to w the content of the data file identified by name, stored by the
+// %datafile definition section in the data files partition of the SIF image found at path.
+func ExtractDataFile(path, name string, w io.Writer) error {
+	if name == "" || filepath.Base(name) != name {
+		return fmt.Errorf("invalid data file name %q", name)
+	}
+
+	img, err := image.Init(path, false)
+	if err != nil {
+		return fmt.Errorf("while loading image %s: %s", path, err)
+	}
+	defer img.File.Close()
+
+	if img.Type != image.SIF {
+		return fmt.Errorf("%s is not a SIF image", path)
+	}
+
+	part, err := image.GetDataFilesPartition(img)
+	if err != nil {
+		return err
+	}
+	if part == nil {
+		return fmt.Errorf("no data files found in image %s", path)
+	}
+
+	dir, err := ioutil.TempDir("", "datafiles-")
+	if err != nil {
+		return fmt.Errorf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := unpacker.NewSquashfs()
+	r := io.NewSectionReader(img.File, int64(part.Offset), int64(part.Size))
+	dest := filepath.Join(dir, "datafiles")
+	if err := s.ExtractFiles([]string{"/" + name}, r, dest); err != nil {
+		return fmt.Errorf("while extracting data file %s: %s", name, err)
+	}
+
+	f, err := os.Open(filepath.Join(dest, name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("no data file %s found in image %s", name, path)
+	} else if err != nil {
+		return fmt.Errorf("while extracting data file %s: %s", name, err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(w, f); err != nil {
+		return fmt.Errorf("while extracting data file %s: %s", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// SIFHeader holds the raw fields of a SIF global header.
+type SIFHeader struct {
+	// Launch is the launch script found at the beginning of the image.
+	Launch string `json:"launch"`
+	// Magic is the SIF magic, sif.HdrMagic for a valid image.
+	Magic string `json:"magic"`
+	// Version is the SIF specification version the image claims to conform to.
+	Version string `json:"version"`
+	// Arch is the SIF architecture code and GoArch the matching Go architecture.
+	Arch   string `json:"arch"`
+	GoArch string `json:"goArch"`
+	UUID   string `json:"uuid"`
+	// Ctime and Mtime are the creation and modification Unix times.
+	Ctime int64 `json:"ctime"`
+	Mtime int64 `json:"mtime"`
+	// DescrFree and DescrTotal are the number of free and total descriptors.
+	DescrFree  int64 `json:"descrFree"`
+	DescrTotal int64 `json:"descrTotal"`
+	// DescrOffset and DescrLength locate the descriptor table in the file.
+	DescrOffset int64 `json:"descrOffset"`
+	DescrLength int64 `json:"descrLength"`
+	// DataOffset and DataLength locate the data section in the file.
+	DataOffset int64 `json:"dataOffset"`
+	DataLength int64 `json:"dataLength"`
+}
+
+// GetSIFHeader returns the global header of the SIF image found at path. Only the global header
+// is read and its fields are returned as found, so that the header of images the SIF library can't
+// load, as images with a bad magic, an unsupported version or a corrupted descriptor table, can
+// still be displayed.
+func GetSIFHeader(path string) (SIFHeader, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return SIFHeader{}, err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return SIFHeader{}, err
+	}
+	return getSIFHeader(f, fi.Size())
+}
+
+func getSIFHeader(r io.ReaderAt, size int64) (SIFHeader, error) {
+	var h sif.Header
+	hdrLen := int64(binary.Size(h))
+	if size < hdrLen {
+		return SIFHeader{}, fmt.Errorf("file truncated in the global header (%d bytes, %d expected)", size, hdrLen)
+	}
+	if err := binary.Read(io.NewSectionReader(r, 0, hdrLen), binary.LittleEndian, &h); err != nil {
+		return SIFHeader{}, fmt.Errorf("while reading global header: %s", err)
+	}
+
+	arch := cstring(h.Arch[:])
+
+	return SIFHeader{
+		Launch:      strings.TrimSpace(cstring(h.Launch[:])),
+		Magic:       cstring(h.Magic[:]),
+		Version:     cstring(h.Version[:]),
+		Arch:        arch,
+		GoArch:      sif.GetGoArch(arch),
+		UUID:        h.ID.String(),
+		Ctime:       h.Ctime,
+		Mtime:       h.Mtime,
+		DescrFree:   h.Dfree,
+		DescrTotal:  h.Dtotal,
+		DescrOffset: h.Descroff,
+		DescrLength: h.Descrlen,
+		DataOffset:  h.Dataoff,
+		DataLength:  h.Datalen,
+	}, nil
+}
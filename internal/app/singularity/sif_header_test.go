@@ -0,0 +1,66 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+func TestGetSIFHeader(t *testing.T) {
+	path := filepath.Join("testdata", "images", "one-group.sif")
+
+	h, err := GetSIFHeader(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h.Magic != sif.HdrMagic {
+		t.Errorf("got magic %q, want %q", h.Magic, sif.HdrMagic)
+	}
+	if h.Version != sif.HdrVersion {
+		t.Errorf("got version %q, want %q", h.Version, sif.HdrVersion)
+	}
+	if h.DescrTotal == 0 || h.DescrFree >= h.DescrTotal {
+		t.Errorf("unexpected descriptor count: %d free of %d", h.DescrFree, h.DescrTotal)
+	}
+
+	d, err := GetSIFDigest(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h.UUID != d.UUID {
+		t.Errorf("got UUID %s, want %s", h.UUID, d.UUID)
+	}
+
+	orig, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the header of a malformed image is still returned
+	b := append([]byte{}, orig...)
+	copy(b[hdrVersionOffset:], "99")
+	binary.LittleEndian.PutUint64(b[hdrDescroffOffset:], uint64(len(b)))
+	h, err = getSIFHeader(bytes.NewReader(b), int64(len(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h.Version != "99" {
+		t.Errorf("got version %q, want %q", h.Version, "99")
+	}
+	if h.DescrOffset != int64(len(b)) {
+		t.Errorf("got descriptor offset %d, want %d", h.DescrOffset, len(b))
+	}
+
+	if _, err := getSIFHeader(bytes.NewReader(orig[:64]), 64); err == nil {
+		t.Errorf("unexpected success with a truncated header")
+	}
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"fmt"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// SIFDescriptor describes a data object descriptor of a SIF image.
+type SIFDescriptor struct {
+	ID uint32 `json:"id"`
+	// GroupID is the ID of the object group, 0 for objects without group.
+	GroupID uint32 `json:"groupId"`
+	Type    string `json:"type"`
+	// FsType, PartType and Arch are only set for partitions.
+	FsType   string `json:"fsType,omitempty"`
+	PartType string `json:"partType,omitempty"`
+	Arch     string `json:"arch,omitempty"`
+	Name     string `json:"name"`
+	// Offset and Size locate the object data in the file.
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
+	// Ctime is the creation Unix time.
+	Ctime int64 `json:"ctime"`
+}
+
+// SIFDescriptors returns the used data object descriptors of the SIF image found at path, in
+// descriptor table order.
+func SIFDescriptors(path string) ([]SIFDescriptor, error) {
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		return nil, fmt.Errorf("while loading SIF image %s: %s", path, err)
+	}
+	defer f.UnloadContainer()
+
+	descrs := []SIFDescriptor{}
+	for _, d := range f.DescrArr {
+		if !d.Used {
+			continue
+		}
+		descr := SIFDescriptor{
+			ID:     d.ID,
+			Type:   d.Datatype.String(),
+			Name:   d.GetName(),
+			Offset: d.Fileoff,
+			Size:   d.Filelen,
+			Ctime:  d.Ctime,
+		}
+		if d.Groupid != sif.DescrUnusedGroup {
+			descr.GroupID = d.Groupid &^ sif.DescrGroupMask
+		}
+		if d.Datatype == sif.DataPartition {
+			if fstype, err := d.GetFsType(); err == nil {
+				descr.FsType = fsTypeName(fstype)
+			}
+			if ptype, err := d.GetPartType(); err == nil {
+				descr.PartType = partTypeName(ptype)
+			}
+			if arch, err := d.GetArch(); err == nil {
+				descr.Arch = sif.GetGoArch(string(arch[:sif.HdrArchLen-1]))
+			}
+		}
+		descrs = append(descrs, descr)
+	}
+	return descrs, nil
+}
+
+func partTypeName(ptype sif.Parttype) string {
+	switch ptype {
+	case sif.PartSystem:
+		return "system"
+	case sif.PartPrimSys:
+		return "primary system"
+	case sif.PartData:
+		return "data"
+	case sif.PartOverlay:
+		return "overlay"
+	}
+	return "unknown"
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestSIFDescriptors(t *testing.T) {
+	descrs, err := SIFDescriptors(filepath.Join("testdata", "images", "one-group.sif"))
+	if err != nil {
+		t.Fatalf("failed to list descriptors: %s", err)
+	}
+	if len(descrs) != 2 {
+		t.Fatalf("got %d descriptors, want 2", len(descrs))
+	}
+
+	for i, d := range descrs {
+		if d.ID != uint32(i+1) {
+			t.Errorf("descriptor %d: got ID %d", i, d.ID)
+		}
+		if d.GroupID != 1 {
+			t.Errorf("descriptor %d: got group ID %d, want 1", i, d.GroupID)
+		}
+		if d.Size <= 0 || d.Offset <= 0 {
+			t.Errorf("descriptor %d: unexpected offset %d and size %d", i, d.Offset, d.Size)
+		}
+	}
+	// the primary system partition, see TestSetPrimaryPartition
+	if d := descrs[1]; d.PartType != "primary system" || d.FsType == "" {
+		t.Errorf("unexpected partition descriptor %+v", d)
+	}
+
+	if _, err := SIFDescriptors(filepath.Join("testdata", "images", "missing.sif")); err == nil {
+		t.Errorf("unexpected success listing a missing image")
+	}
+}
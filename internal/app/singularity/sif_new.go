@@ -0,0 +1,41 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"fmt"
+	"os"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// NewSIF creates at path an empty SIF image, holding only the global header and an empty
+// descriptor table, to be populated with 'sif add'. An existing file is never overwritten. The
+// layout of the created image is checked with VerifySIFLayout.
+func NewSIF(path string) error {
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	} else if !os.IsNotExist(err) {
+		return err
+	}
+
+	cinfo := sif.CreateInfo{
+		Pathname:   path,
+		Launchstr:  sif.HdrLaunch,
+		Sifversion: sif.HdrVersion,
+		ID:         uuid.NewV4(),
+	}
+	if _, err := sif.CreateContainer(cinfo); err != nil {
+		return fmt.Errorf("while creating SIF image %s: %s", path, err)
+	}
+
+	if err := VerifySIFLayout(path); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("created SIF image %s has an invalid layout: %s", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+func TestNewSIF(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sif-new-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "new.sif")
+	if err := NewSIF(path); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		t.Fatalf("while loading created image: %s", err)
+	}
+	defer f.UnloadContainer()
+
+	if n := f.Header.Dtotal - f.Header.Dfree; n != 0 {
+		t.Errorf("got %d used descriptors, want 0", n)
+	}
+
+	if err := NewSIF(path); err == nil {
+		t.Errorf("unexpected success overwriting %s", path)
+	}
+}
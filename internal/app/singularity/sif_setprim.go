@@ -0,0 +1,74 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"fmt"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// SetPrimaryPartition marks the partition identified by the descriptor id as the primary system
+// partition of the SIF image found at path, the previous primary partition becomes a system
+// partition. Only partitions holding a file system supported by the runtime are accepted.
+func SetPrimaryPartition(path string, id uint32) error {
+	f, err := sif.LoadContainer(path, false)
+	if err != nil {
+		return fmt.Errorf("while loading SIF image %s: %s", path, err)
+	}
+	defer f.UnloadContainer()
+
+	desc, _, err := f.GetFromDescrID(id)
+	if err != nil {
+		return fmt.Errorf("descriptor %d: %s", id, err)
+	}
+	if desc.Datatype != sif.DataPartition {
+		return fmt.Errorf("descriptor %d is not a partition", id)
+	}
+
+	fstype, err := desc.GetFsType()
+	if err != nil {
+		return fmt.Errorf("while reading descriptor %d file system type: %s", id, err)
+	}
+	switch fstype {
+	case sif.FsSquash, sif.FsExt3, sif.FsEncryptedSquashfs:
+	default:
+		return fmt.Errorf("descriptor %d holds an unsupported %s file system", id, fsTypeName(fstype))
+	}
+
+	ptype, err := desc.GetPartType()
+	if err != nil {
+		return fmt.Errorf("while reading descriptor %d partition type: %s", id, err)
+	}
+	switch ptype {
+	case sif.PartPrimSys:
+		return nil
+	case sif.PartSystem:
+	default:
+		return fmt.Errorf("descriptor %d is not a system partition", id)
+	}
+
+	if err := f.SetPrimPart(id); err != nil {
+		return fmt.Errorf("while setting primary partition: %s", err)
+	}
+	return nil
+}
+
+func fsTypeName(fstype sif.Fstype) string {
+	switch fstype {
+	case sif.FsSquash:
+		return "squashfs"
+	case sif.FsExt3:
+		return "ext3"
+	case sif.FsImmuObj:
+		return "immutable object"
+	case sif.FsRaw:
+		return "raw"
+	case sif.FsEncryptedSquashfs:
+		return "encrypted squashfs"
+	}
+	return "unknown"
+}
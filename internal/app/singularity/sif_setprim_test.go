@@ -0,0 +1,75 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSetPrimaryPartition(t *testing.T) {
+	tests := []struct {
+		name    string
+		id      uint32
+		wantErr bool
+	}{
+		{
+			name: "AlreadyPrimary",
+			id:   2,
+		},
+		{
+			name:    "RawPartition",
+			id:      1,
+			wantErr: true,
+		},
+		{
+			name:    "NotFound",
+			id:      3,
+			wantErr: true,
+		},
+	}
+
+	src := filepath.Join("testdata", "images", "one-group.sif")
+	orig, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "setprim-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.Write(orig); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			err = SetPrimaryPartition(f.Name(), tt.id)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+			} else if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			b, err := ioutil.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(b, orig) {
+				t.Errorf("image modified")
+			}
+		})
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/sylabs/sif/pkg/integrity"
+	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/pkg/inspect"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/clearsign"
+	"golang.org/x/crypto/openpgp/packet"
+)
+
+// SIFSignatures returns the signatures found in the SIF image at path, in descriptor order. An
+// image without signatures returns an empty slice. If kr is not nil, each signature is verified
+// against kr, signatures not checked by the verification, as legacy ones, being reported as not
+// verified.
+func SIFSignatures(ctx context.Context, path string, kr openpgp.KeyRing) ([]inspect.Signature, error) {
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		return nil, err
+	}
+	defer f.UnloadContainer()
+
+	sigs := []inspect.Signature{}
+	for _, d := range f.DescrArr {
+		if !d.Used || d.Datatype != sif.DataSignature {
+			continue
+		}
+		s := inspect.Signature{ID: d.ID}
+		if d.Link&sif.DescrGroupMask == sif.DescrGroupMask {
+			s.GroupID = d.Link &^ sif.DescrGroupMask
+		} else {
+			s.ObjectID = d.Link
+		}
+		if fp, err := d.GetEntityString(); err == nil {
+			s.Fingerprint = strings.ToUpper(fp)
+		}
+		if t, ok := signatureTime(d.GetData(&f)); ok {
+			s.Time = &t
+		}
+		sigs = append(sigs, s)
+	}
+
+	if kr == nil || len(sigs) == 0 {
+		return sigs, nil
+	}
+
+	results := make(map[uint32]error)
+	cb := func(_ *sif.FileImage, r integrity.VerifyResult) bool {
+		results[r.Signature().ID] = r.Error()
+		// Report all the signatures instead of stopping at the first failure.
+		return true
+	}
+	verr := Verify(ctx, path, OptVerifyUseKeyRing(kr), OptVerifyCallback(cb))
+
+	for i := range sigs {
+		verified := false
+		err, ok := results[sigs[i].ID]
+		switch {
+		case ok && err == nil:
+			verified = true
+		case ok:
+			sigs[i].VerifyError = err.Error()
+		case verr != nil:
+			sigs[i].VerifyError = verr.Error()
+		default:
+			sigs[i].VerifyError = "signature not checked"
+		}
+		sigs[i].Verified = &verified
+	}
+	return sigs, nil
+}
+
+// signatureTime returns the creation time of the clear signed message data.
+func signatureTime(data []byte) (time.Time, bool) {
+	b, _ := clearsign.Decode(data)
+	if b == nil {
+		return time.Time{}, false
+	}
+	p, err := packet.Read(b.ArmoredSignature.Body)
+	if err != nil {
+		return time.Time{}, false
+	}
+	switch sig := p.(type) {
+	case *packet.Signature:
+		return sig.CreationTime.UTC(), true
+	case *packet.SignatureV3:
+		return sig.CreationTime.UTC(), true
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/openpgp"
+)
+
+func TestSIFSignatures(t *testing.T) {
+	e := getTestEntity(t)
+
+	const fingerprint = "12045C8C0B1004D058DE4BEDA20C27EE7FF7BA84"
+	verified, notVerified := true, false
+
+	tests := []struct {
+		name         string
+		path         string
+		kr           openpgp.KeyRing
+		wantCount    int
+		wantGroupID  uint32
+		wantObjectID uint32
+		wantTime     time.Time
+		wantVerified *bool
+	}{
+		{
+			name:      "Unsigned",
+			path:      filepath.Join("testdata", "images", "one-group.sif"),
+			wantCount: 0,
+		},
+		{
+			name:        "Signed",
+			path:        filepath.Join("testdata", "images", "one-group-signed.sif"),
+			wantCount:   1,
+			wantGroupID: 1,
+			wantTime:    time.Date(2020, 6, 30, 0, 1, 56, 0, time.UTC),
+		},
+		{
+			name:         "SignedVerified",
+			path:         filepath.Join("testdata", "images", "one-group-signed.sif"),
+			kr:           openpgp.EntityList{e},
+			wantCount:    1,
+			wantGroupID:  1,
+			wantTime:     time.Date(2020, 6, 30, 0, 1, 56, 0, time.UTC),
+			wantVerified: &verified,
+		},
+		{
+			name:         "SignedUnknownKey",
+			path:         filepath.Join("testdata", "images", "one-group-signed.sif"),
+			kr:           openpgp.EntityList{},
+			wantCount:    1,
+			wantGroupID:  1,
+			wantTime:     time.Date(2020, 6, 30, 0, 1, 56, 0, time.UTC),
+			wantVerified: &notVerified,
+		},
+		{
+			name:         "Legacy",
+			path:         filepath.Join("testdata", "images", "one-group-signed-legacy.sif"),
+			kr:           openpgp.EntityList{e},
+			wantCount:    1,
+			wantObjectID: 2,
+			wantTime:     time.Date(2020, 6, 20, 20, 16, 39, 0, time.UTC),
+			wantVerified: &notVerified,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			sigs, err := SIFSignatures(context.Background(), tt.path, tt.kr)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if sigs == nil {
+				t.Fatalf("nil signatures, empty slice expected")
+			}
+			if got := len(sigs); got != tt.wantCount {
+				t.Fatalf("got %d signatures, want %d", got, tt.wantCount)
+			}
+			if tt.wantCount == 0 {
+				return
+			}
+
+			s := sigs[0]
+			if s.ID != 3 {
+				t.Errorf("got signature ID %d, want 3", s.ID)
+			}
+			if s.GroupID != tt.wantGroupID || s.ObjectID != tt.wantObjectID {
+				t.Errorf("got group %d object %d, want group %d object %d",
+					s.GroupID, s.ObjectID, tt.wantGroupID, tt.wantObjectID)
+			}
+			if s.Fingerprint != fingerprint {
+				t.Errorf("got fingerprint %s, want %s", s.Fingerprint, fingerprint)
+			}
+			if s.Time == nil || !s.Time.Equal(tt.wantTime) {
+				t.Errorf("got time %v, want %v", s.Time, tt.wantTime)
+			}
+
+			switch {
+			case tt.wantVerified == nil && s.Verified != nil:
+				t.Errorf("unexpected verification result %v", *s.Verified)
+			case tt.wantVerified != nil && s.Verified == nil:
+				t.Errorf("missing verification result")
+			case tt.wantVerified != nil && *s.Verified != *tt.wantVerified:
+				t.Errorf("got verified %v, want %v", *s.Verified, *tt.wantVerified)
+			case s.Verified != nil && !*s.Verified && s.VerifyError == "":
+				t.Errorf("missing verification error")
+			}
+		})
+	}
+}
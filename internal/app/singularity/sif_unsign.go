@@ -0,0 +1,112 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// UnsignSIF removes the signatures of the SIF image found at path and returns their number. If
+// groupID is not zero, only the signatures of the objects of this group are removed, legacy
+// signatures being matched by the group of the object they sign. Other descriptors are left
+// untouched and the data of the removed signatures is truncated from the end of the image.
+func UnsignSIF(path string, groupID uint32) (int, error) {
+	f, err := sif.LoadContainer(path, false)
+	if err != nil {
+		return 0, fmt.Errorf("while loading SIF image %s: %s", path, err)
+	}
+
+	var ids []uint32
+	for _, d := range f.DescrArr {
+		if !d.Used || d.Datatype != sif.DataSignature {
+			continue
+		}
+		if groupID != 0 && signatureGroupID(&f, d) != groupID {
+			continue
+		}
+		ids = append(ids, d.ID)
+	}
+
+	for _, id := range ids {
+		if err := f.DeleteObject(id, sif.DelZero); err != nil {
+			f.UnloadContainer()
+			return 0, fmt.Errorf("while removing signature %d: %s", id, err)
+		}
+	}
+
+	h := f.Header
+	end := h.Descroff + h.Descrlen
+	for _, d := range f.DescrArr {
+		if d.Used && d.Fileoff+d.Filelen > end {
+			end = d.Fileoff + d.Filelen
+		}
+	}
+	if err := f.UnloadContainer(); err != nil {
+		return 0, err
+	}
+
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	if err := truncateSIFData(path, h, end); err != nil {
+		return 0, fmt.Errorf("while truncating SIF image %s: %s", path, err)
+	}
+	return len(ids), nil
+}
+
+// signatureGroupID returns the group ID of the objects signed by the signature descriptor d, zero
+// if it can't be found.
+func signatureGroupID(f *sif.FileImage, d sif.Descriptor) uint32 {
+	if d.Link&sif.DescrGroupMask == sif.DescrGroupMask {
+		return d.Link &^ sif.DescrGroupMask
+	}
+	// legacy signatures are linked to the object they sign
+	od, _, err := f.GetFromDescrID(d.Link)
+	if err != nil {
+		return 0
+	}
+	return od.Groupid &^ sif.DescrGroupMask
+}
+
+// truncateSIFData truncates the SIF image found at path at end, the end of its last data object,
+// and updates the data section length of its global header h accordingly.
+func truncateSIFData(path string, h sif.Header, end int64) error {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if fi.Size() <= end {
+		return nil
+	}
+
+	h.Datalen = end - h.Dataoff
+	if h.Datalen < 0 {
+		h.Datalen = 0
+	}
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, h); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY, 0)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteAt(buf.Bytes(), 0); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Truncate(end); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
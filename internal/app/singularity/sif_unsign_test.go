@@ -0,0 +1,111 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestUnsignSIF(t *testing.T) {
+	// the data objects of the test images, before their signatures
+	const dataStart, dataEnd = 32768, 36868
+
+	tests := []struct {
+		name        string
+		path        string
+		groupID     uint32
+		wantRemoved int
+	}{
+		{
+			name:        "Unsigned",
+			path:        filepath.Join("testdata", "images", "one-group.sif"),
+			wantRemoved: 0,
+		},
+		{
+			name:        "Signed",
+			path:        filepath.Join("testdata", "images", "one-group-signed.sif"),
+			wantRemoved: 1,
+		},
+		{
+			name:        "SignedGroup",
+			path:        filepath.Join("testdata", "images", "one-group-signed.sif"),
+			groupID:     1,
+			wantRemoved: 1,
+		},
+		{
+			name:        "SignedOtherGroup",
+			path:        filepath.Join("testdata", "images", "one-group-signed.sif"),
+			groupID:     2,
+			wantRemoved: 0,
+		},
+		{
+			name:        "LegacyGroup",
+			path:        filepath.Join("testdata", "images", "one-group-signed-legacy-all.sif"),
+			groupID:     1,
+			wantRemoved: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			orig, err := ioutil.ReadFile(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			f, err := ioutil.TempFile("", "unsign-")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.Write(orig); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			n, err := UnsignSIF(f.Name(), tt.groupID)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if n != tt.wantRemoved {
+				t.Errorf("got %d signature(s) removed, want %d", n, tt.wantRemoved)
+			}
+
+			b, err := ioutil.ReadFile(f.Name())
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.wantRemoved == 0 {
+				if !bytes.Equal(b, orig) {
+					t.Errorf("image modified")
+				}
+				return
+			}
+
+			if err := VerifySIFLayout(f.Name()); err != nil {
+				t.Errorf("unexpected layout error: %s", err)
+			}
+			if len(b) != dataEnd {
+				t.Errorf("got image size %d, want %d", len(b), dataEnd)
+			}
+			if !bytes.Equal(b[dataStart:dataEnd], orig[dataStart:dataEnd]) {
+				t.Errorf("data objects modified")
+			}
+			sigs, err := SIFSignatures(context.Background(), f.Name(), nil)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(sigs) != 0 {
+				t.Errorf("got %d signature(s) left", len(sigs))
+			}
+		})
+	}
+}
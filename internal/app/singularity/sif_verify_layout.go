@@ -0,0 +1,150 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// offsets of the global header fields checked by VerifySIFLayout.
+const (
+	hdrMagicOffset    = sif.HdrLaunchLen
+	hdrVersionOffset  = hdrMagicOffset + sif.HdrMagicLen
+	hdrDfreeOffset    = hdrVersionOffset + sif.HdrVersionLen + sif.HdrArchLen + 16 + 16
+	hdrDescroffOffset = hdrDfreeOffset + 16
+	hdrDataoffOffset  = hdrDescroffOffset + 16
+)
+
+// offset of the Fileoff field in a descriptor.
+const descrFileoffOffset = 4 + 1 + 4 + 4 + 4
+
+// SIFLayoutError reports a structural problem found in a SIF image.
+type SIFLayoutError struct {
+	// Offset is the byte offset of the problem in the image file.
+	Offset int64
+	Msg    string
+}
+
+func (e *SIFLayoutError) Error() string {
+	return fmt.Sprintf("at byte offset %d: %s", e.Offset, e.Msg)
+}
+
+func layoutErrorf(offset int64, format string, a ...interface{}) error {
+	return &SIFLayoutError{Offset: offset, Msg: fmt.Sprintf(format, a...)}
+}
+
+// VerifySIFLayout checks the structure of the SIF image found at path: the global header magic
+// and version, the descriptor table and data section bounds, that every used descriptor
+// references data within the data section and that the file ends with the last descriptor
+// data. The first problem found is returned as a SIFLayoutError. The image is read without the
+// SIF library, which fails on corrupted images without locating the problem.
+func VerifySIFLayout(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return verifySIFLayout(f, fi.Size())
+}
+
+func verifySIFLayout(r io.ReaderAt, size int64) error {
+	var h sif.Header
+	hdrLen := int64(binary.Size(h))
+	if size < hdrLen {
+		return layoutErrorf(size, "file truncated in the global header (%d bytes, %d expected)", size, hdrLen)
+	}
+	if err := binary.Read(io.NewSectionReader(r, 0, hdrLen), binary.LittleEndian, &h); err != nil {
+		return fmt.Errorf("while reading global header: %s", err)
+	}
+
+	if magic := cstring(h.Magic[:]); magic != sif.HdrMagic {
+		return layoutErrorf(hdrMagicOffset, "bad magic %q, %q expected", magic, sif.HdrMagic)
+	}
+	if version := cstring(h.Version[:]); version != sif.HdrVersion {
+		return layoutErrorf(hdrVersionOffset, "unsupported version %q, %q expected", version, sif.HdrVersion)
+	}
+	if h.Dtotal < 0 || h.Dfree < 0 || h.Dfree > h.Dtotal {
+		return layoutErrorf(hdrDfreeOffset, "bad descriptor counts: %d free of %d", h.Dfree, h.Dtotal)
+	}
+
+	descrLen := int64(binary.Size(sif.Descriptor{}))
+	if h.Descrlen != h.Dtotal*descrLen {
+		return layoutErrorf(hdrDescroffOffset+8, "descriptor table length %d doesn't hold %d descriptors", h.Descrlen, h.Dtotal)
+	}
+	if h.Descroff < hdrLen || h.Descroff+h.Descrlen > size {
+		return layoutErrorf(hdrDescroffOffset, "descriptor table [%d, %d) lies outside of the file of %d bytes",
+			h.Descroff, h.Descroff+h.Descrlen, size)
+	}
+	if h.Dataoff < h.Descroff+h.Descrlen || h.Datalen < 0 {
+		return layoutErrorf(hdrDataoffOffset, "data section [%d, %d) overlaps the descriptor table", h.Dataoff, h.Dataoff+h.Datalen)
+	}
+
+	// the data section is only allocated with the first data object
+	dataEnd := h.Dataoff + h.Datalen
+	end := h.Descroff + h.Descrlen
+	used := int64(0)
+	ids := make(map[uint32]bool)
+
+	for i := int64(0); i < h.Dtotal; i++ {
+		off := h.Descroff + i*descrLen
+
+		var d sif.Descriptor
+		if err := binary.Read(io.NewSectionReader(r, off, descrLen), binary.LittleEndian, &d); err != nil {
+			return fmt.Errorf("while reading descriptor %d: %s", i+1, err)
+		}
+		if !d.Used {
+			continue
+		}
+		used++
+
+		if d.ID == 0 || ids[d.ID] {
+			return layoutErrorf(off, "descriptor %d has an invalid or duplicate ID %d", i+1, d.ID)
+		}
+		ids[d.ID] = true
+
+		objEnd := d.Fileoff + d.Filelen
+		if d.Filelen < 0 || d.Fileoff < h.Dataoff || objEnd > dataEnd {
+			return layoutErrorf(off+descrFileoffOffset, "descriptor %d data [%d, %d) lies outside of the data section [%d, %d)",
+				d.ID, d.Fileoff, objEnd, h.Dataoff, dataEnd)
+		}
+		if objEnd > size {
+			return layoutErrorf(size, "file truncated in descriptor %d data, %d bytes missing", d.ID, objEnd-size)
+		}
+		if objEnd > end {
+			end = objEnd
+		}
+	}
+
+	if used != h.Dtotal-h.Dfree {
+		return layoutErrorf(hdrDfreeOffset, "%d used descriptors found, %d expected", used, h.Dtotal-h.Dfree)
+	}
+	if h.Datalen > 0 && dataEnd > size {
+		return layoutErrorf(size, "file truncated, %d bytes missing at the end of the data section", dataEnd-size)
+	}
+	if size != end {
+		return layoutErrorf(end, "file length %d doesn't match the end of the last descriptor data", size)
+	}
+	return nil
+}
+
+// cstring returns the content of the NUL terminated string b.
+func cstring(b []byte) string {
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return string(b)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the LICENSE.md file
+// distributed with the sources of this project regarding your rights to use or distribute this
+// software.
+
+package singularity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifySIFLayout(t *testing.T) {
+	for _, name := range []string{"empty.sif", "one-group.sif", "one-group-signed.sif"} {
+		if err := VerifySIFLayout(filepath.Join("testdata", "images", name)); err != nil {
+			t.Errorf("%s: unexpected error: %s", name, err)
+		}
+	}
+
+	orig, err := ioutil.ReadFile(filepath.Join("testdata", "images", "one-group.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	size := int64(len(orig))
+
+	tests := []struct {
+		name   string
+		modify func(b []byte) []byte
+		offset int64
+	}{
+		{
+			name:   "TruncatedHeader",
+			modify: func(b []byte) []byte { return b[:64] },
+			offset: 64,
+		},
+		{
+			name: "BadMagic",
+			modify: func(b []byte) []byte {
+				copy(b[hdrMagicOffset:], "NOT_MAGIC")
+				return b
+			},
+			offset: hdrMagicOffset,
+		},
+		{
+			name: "BadVersion",
+			modify: func(b []byte) []byte {
+				copy(b[hdrVersionOffset:], "99")
+				return b
+			},
+			offset: hdrVersionOffset,
+		},
+		{
+			name: "DescriptorOutOfFile",
+			modify: func(b []byte) []byte {
+				binary.LittleEndian.PutUint64(b[hdrDescroffOffset:], uint64(size))
+				return b
+			},
+			offset: hdrDescroffOffset,
+		},
+		{
+			name:   "TruncatedData",
+			modify: func(b []byte) []byte { return b[:size-100] },
+			offset: size - 100,
+		},
+		{
+			name:   "TrailingData",
+			modify: func(b []byte) []byte { return append(b, 0) },
+			offset: size,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.modify(append([]byte{}, orig...))
+			err := verifySIFLayout(bytes.NewReader(b), int64(len(b)))
+			lerr, ok := err.(*SIFLayoutError)
+			if !ok {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if lerr.Offset != tt.offset {
+				t.Errorf("got offset %d, want %d: %s", lerr.Offset, tt.offset, lerr)
+			}
+		})
+	}
+}
@@ -6,13 +6,21 @@
 package singularity
 
 import (
+	"bytes"
+
 	"github.com/sylabs/sif/pkg/integrity"
 	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/pkg/sylog"
 	"github.com/sylabs/singularity/pkg/sypgp"
+	"golang.org/x/crypto/openpgp/clearsign"
 )
 
+// legacySignaturePrefix starts the clear signed message of legacy signatures.
+var legacySignaturePrefix = []byte("SIFHASH:\n")
+
 type signer struct {
-	opts []integrity.SignerOpt
+	opts         []integrity.SignerOpt
+	removeLegacy bool
 }
 
 // SignOpt are used to configure s.
@@ -52,6 +60,15 @@ func OptSignObjects(ids ...uint32) SignOpt {
 	}
 }
 
+// OptSignRemoveLegacy specifies that the legacy signatures found in the image be removed before the
+// new signature(s) are applied, to re-sign an image verified with OptVerifyLegacy.
+func OptSignRemoveLegacy() SignOpt {
+	return func(s *signer) error {
+		s.removeLegacy = true
+		return nil
+	}
+}
+
 // Sign adds one or more digital signatures to the SIF image found at path, according to opts. Key
 // material must be provided via OptSignEntitySelector.
 //
@@ -73,6 +90,15 @@ func Sign(path string, opts ...SignOpt) error {
 	}
 	defer f.UnloadContainer()
 
+	// Remove legacy signature(s), if applicable.
+	if s.removeLegacy {
+		n, err := removeLegacySignatures(&f)
+		if err != nil {
+			return err
+		}
+		sylog.Infof("Removed %d legacy signature(s)", n)
+	}
+
 	// Apply signature(s).
 	is, err := integrity.NewSigner(&f, s.opts...)
 	if err != nil {
@@ -80,3 +106,26 @@ func Sign(path string, opts ...SignOpt) error {
 	}
 	return is.Sign()
 }
+
+// isLegacySignature returns true if data holds a legacy signature.
+func isLegacySignature(data []byte) bool {
+	b, _ := clearsign.Decode(data)
+	return b != nil && bytes.HasPrefix(b.Plaintext, legacySignaturePrefix)
+}
+
+// removeLegacySignatures removes the legacy signatures found in f and returns their number.
+func removeLegacySignatures(f *sif.FileImage) (int, error) {
+	var ids []uint32
+	for _, d := range f.DescrArr {
+		if d.Used && d.Datatype == sif.DataSignature && isLegacySignature(d.GetData(f)) {
+			ids = append(ids, d.ID)
+		}
+	}
+
+	for _, id := range ids {
+		if err := f.DeleteObject(id, sif.DelZero); err != nil {
+			return 0, err
+		}
+	}
+	return len(ids), nil
+}
@@ -6,6 +6,7 @@
 package singularity
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
@@ -100,3 +101,63 @@ func TestSign(t *testing.T) {
 		})
 	}
 }
+
+func TestSignRemoveLegacy(t *testing.T) {
+	mockEntityOpt := OptSignEntitySelector(mockEntitySelector(t))
+
+	tests := []struct {
+		name     string
+		path     string
+		opts     []SignOpt
+		wantSigs int
+	}{
+		{
+			name:     "KeepLegacy",
+			path:     filepath.Join("testdata", "images", "one-group-signed-legacy.sif"),
+			opts:     []SignOpt{mockEntityOpt},
+			wantSigs: 2,
+		},
+		{
+			name:     "RemoveLegacy",
+			path:     filepath.Join("testdata", "images", "one-group-signed-legacy.sif"),
+			opts:     []SignOpt{mockEntityOpt, OptSignRemoveLegacy()},
+			wantSigs: 1,
+		},
+		{
+			name:     "RemoveLegacyAll",
+			path:     filepath.Join("testdata", "images", "one-group-signed-legacy-all.sif"),
+			opts:     []SignOpt{mockEntityOpt, OptSignRemoveLegacy()},
+			wantSigs: 1,
+		},
+		{
+			name:     "KeepCurrent",
+			path:     filepath.Join("testdata", "images", "one-group-signed.sif"),
+			opts:     []SignOpt{mockEntityOpt, OptSignRemoveLegacy()},
+			wantSigs: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			// Signing modifies the file, so work with a temporary file.
+			path, err := tempFileFrom(tt.path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(path)
+
+			if err := Sign(path, tt.opts...); err != nil {
+				t.Fatalf("failed to sign: %v", err)
+			}
+
+			sigs, err := SIFSignatures(context.Background(), path, nil)
+			if err != nil {
+				t.Fatalf("failed to get signatures: %v", err)
+			}
+			if got := len(sigs); got != tt.wantSigs {
+				t.Errorf("got %d signatures, want %d", got, tt.wantSigs)
+			}
+		})
+	}
+}
@@ -6,7 +6,10 @@
 package singularity
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/sylabs/scs-key-client/client"
 	"github.com/sylabs/sif/pkg/integrity"
@@ -17,13 +20,30 @@ import (
 
 type VerifyCallback func(*sif.FileImage, integrity.VerifyResult) bool
 
+// ThresholdCallback is called once per signed object(s), identified by the link of their
+// signatures, with the distinct entities whose signatures have been successfully verified.
+type ThresholdCallback func(f *sif.FileImage, link uint32, signers []*openpgp.Entity)
+
+// ErrThresholdNotMet is returned when signed object(s) are not signed by enough distinct keys.
+var ErrThresholdNotMet = errors.New("signature threshold not met")
+
+// ErrObjectIntegrity is returned by threshold verification when signed object(s) don't match
+// one or more of their signatures, whatever the number of valid signatures.
+var ErrObjectIntegrity = errors.New("signed object integrity check failed")
+
+var errThresholdValue = errors.New("signature threshold must be greater than zero")
+
 type verifier struct {
 	c         *client.Config
+	kr        openpgp.KeyRing
 	groupIDs  []uint32
 	objectIDs []uint32
 	all       bool
 	legacy    bool
+	threshold int
 	cb        VerifyCallback
+	tcb       ThresholdCallback
+	signers   *signers
 }
 
 // VerifyOpt are used to configure v.
@@ -38,6 +58,15 @@ func OptVerifyUseKeyServer(c *client.Config) VerifyOpt {
 	}
 }
 
+// OptVerifyUseKeyRing specifies that kr be used as the only source of key material, the local
+// public keyring and keyserver are not used.
+func OptVerifyUseKeyRing(kr openpgp.KeyRing) VerifyOpt {
+	return func(v *verifier) error {
+		v.kr = kr
+		return nil
+	}
+}
+
 // OptVerifyGroup adds a verification task for the group with the specified groupID. This may be
 // called multliple times to request verification of more than one group.
 func OptVerifyGroup(groupID uint32) VerifyOpt {
@@ -82,6 +111,22 @@ func OptVerifyCallback(cb VerifyCallback) VerifyOpt {
 	}
 }
 
+// OptVerifyThreshold requires that signed object(s) be signed by at least n distinct keys found
+// in the key material. Signatures from unknown keys, or whose signer can't be verified, don't
+// cause verification to fail as long as the threshold is met, while an object integrity failure
+// always does. If cb is not nil, it is called with the signing entities that satisfied the
+// threshold.
+func OptVerifyThreshold(n int, cb ThresholdCallback) VerifyOpt {
+	return func(v *verifier) error {
+		if n < 1 {
+			return errThresholdValue
+		}
+		v.threshold = n
+		v.tcb = cb
+		return nil
+	}
+}
+
 // newVerifier constructs a new verifier based on opts.
 func newVerifier(opts []VerifyOpt) (verifier, error) {
 	v := verifier{}
@@ -90,6 +135,9 @@ func newVerifier(opts []VerifyOpt) (verifier, error) {
 			return verifier{}, err
 		}
 	}
+	if v.threshold > 0 {
+		v.signers = &signers{}
+	}
 	return v, nil
 }
 
@@ -99,7 +147,9 @@ func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.Ve
 
 	// Add keyring.
 	var kr openpgp.KeyRing
-	if v.c != nil {
+	if v.kr != nil {
+		kr = v.kr
+	} else if v.c != nil {
 		hkr, err := sypgp.NewHybridKeyRing(ctx, v.c)
 		if err != nil {
 			return nil, err
@@ -142,10 +192,19 @@ func (v verifier) getOpts(ctx context.Context, f *sif.FileImage) ([]integrity.Ve
 		}
 	}
 
-	// Add callback, if applicable.
-	if v.cb != nil {
+	// Add callback, if applicable.
+	if v.cb != nil || v.signers != nil {
 		fn := func(r integrity.VerifyResult) bool {
-			return v.cb(f, r)
+			ignoreError := false
+			if v.cb != nil {
+				ignoreError = v.cb(f, r)
+			}
+			if v.signers != nil {
+				v.signers.add(r)
+				// Threshold is checked once all signatures are verified.
+				ignoreError = true
+			}
+			return ignoreError
 		}
 		iopts = append(iopts, integrity.OptVerifyCallback(fn))
 	}
@@ -184,5 +243,75 @@ func Verify(ctx context.Context, path string, opts ...VerifyOpt) error {
 	if err != nil {
 		return err
 	}
-	return iv.Verify()
+	if err := iv.Verify(); err != nil {
+		return err
+	}
+
+	// Check signature threshold, if applicable.
+	if v.signers != nil {
+		return v.signers.check(&f, v.threshold, v.tcb)
+	}
+	return nil
+}
+
+// signers records the distinct entities with a valid signature, per signature link.
+type signers struct {
+	links    []uint32
+	entities map[uint32][]*openpgp.Entity
+	// signerFailures counts the signatures whose signer can't be verified.
+	signerFailures map[uint32]int
+	// integrityFailures counts the signatures not matching the signed object(s).
+	integrityFailures map[uint32]int
+}
+
+// add records the signing entity of r if its signature has been successfully verified, or the
+// kind of verification failure otherwise.
+func (s *signers) add(r integrity.VerifyResult) {
+	link := r.Signature().Link
+
+	if s.entities == nil {
+		s.entities = make(map[uint32][]*openpgp.Entity)
+		s.signerFailures = make(map[uint32]int)
+		s.integrityFailures = make(map[uint32]int)
+	}
+	if _, ok := s.entities[link]; !ok {
+		s.links = append(s.links, link)
+		s.entities[link] = nil
+	}
+
+	var integrityError *integrity.ObjectIntegrityError
+	if err := r.Error(); errors.As(err, &integrityError) {
+		s.integrityFailures[link]++
+		return
+	}
+
+	e := r.Entity()
+	if r.Error() != nil || e == nil {
+		s.signerFailures[link]++
+		return
+	}
+	for _, se := range s.entities[link] {
+		if bytes.Equal(se.PrimaryKey.Fingerprint[:], e.PrimaryKey.Fingerprint[:]) {
+			return
+		}
+	}
+	s.entities[link] = append(s.entities[link], e)
+}
+
+// check returns an error if signed object(s) failed an integrity check, or have less than
+// threshold distinct signing entities.
+func (s *signers) check(f *sif.FileImage, threshold int, cb ThresholdCallback) error {
+	for _, link := range s.links {
+		if n := s.integrityFailures[link]; n > 0 {
+			return fmt.Errorf("%w: %d signature(s) not matching the signed object(s)", ErrObjectIntegrity, n)
+		}
+		es := s.entities[link]
+		if len(es) < threshold {
+			return fmt.Errorf("%w: %d valid signature(s) from distinct keys, %d required (%d signature(s) from unknown or invalid keys)", ErrThresholdNotMet, len(es), threshold, s.signerFailures[link])
+		}
+		if cb != nil {
+			cb(f, link, es)
+		}
+	}
+	return nil
 }
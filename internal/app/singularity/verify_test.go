@@ -100,6 +100,22 @@ func Test_newVerifier(t *testing.T) {
 			opts:         []VerifyOpt{OptVerifyLegacy()},
 			wantVerifier: verifier{legacy: true},
 		},
+		{
+			name:         "OptVerifyUseKeyRing",
+			opts:         []VerifyOpt{OptVerifyUseKeyRing(openpgp.EntityList{})},
+			wantVerifier: verifier{kr: openpgp.EntityList{}},
+		},
+		{
+			name:         "OptVerifyThreshold",
+			opts:         []VerifyOpt{OptVerifyThreshold(2, nil)},
+			wantVerifier: verifier{threshold: 2, signers: &signers{}},
+		},
+		{
+			name:         "OptVerifyThresholdZero",
+			opts:         []VerifyOpt{OptVerifyThreshold(0, nil)},
+			wantErr:      errThresholdValue,
+			wantVerifier: verifier{},
+		},
 	}
 
 	for _, tt := range tests {
@@ -366,3 +382,140 @@ func TestVerify(t *testing.T) {
 		})
 	}
 }
+
+func TestVerifyThreshold(t *testing.T) {
+	e := getTestEntity(t)
+
+	tests := []struct {
+		name        string
+		path        string
+		kr          openpgp.KeyRing
+		threshold   int
+		wantSigners int
+		wantErr     error
+	}{
+		{
+			name:        "ThresholdMet",
+			path:        filepath.Join("testdata", "images", "one-group-signed.sif"),
+			kr:          openpgp.EntityList{e},
+			threshold:   1,
+			wantSigners: 1,
+		},
+		{
+			name:      "ThresholdNotMet",
+			path:      filepath.Join("testdata", "images", "one-group-signed.sif"),
+			kr:        openpgp.EntityList{e},
+			threshold: 2,
+			wantErr:   ErrThresholdNotMet,
+		},
+		{
+			name:      "UnknownKey",
+			path:      filepath.Join("testdata", "images", "one-group-signed.sif"),
+			kr:        openpgp.EntityList{},
+			threshold: 1,
+			wantErr:   ErrThresholdNotMet,
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			var gotSigners int
+
+			cb := func(f *sif.FileImage, link uint32, signers []*openpgp.Entity) {
+				gotSigners = len(signers)
+			}
+			opts := []VerifyOpt{OptVerifyUseKeyRing(tt.kr), OptVerifyThreshold(tt.threshold, cb)}
+
+			err := Verify(context.Background(), tt.path, opts...)
+
+			if got, want := err, tt.wantErr; !errors.Is(got, want) {
+				t.Errorf("got error %v, want %v", got, want)
+			}
+
+			if got, want := gotSigners, tt.wantSigners; got != want {
+				t.Errorf("got %v signers, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestVerifyThresholdMultipleSignatures(t *testing.T) {
+	e := getTestEntity(t)
+
+	e2, err := openpgp.NewEntity("Second Signer", "", "second@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Signing modifies the file, so work with a temporary file.
+	path, err := tempFileFrom(filepath.Join("testdata", "images", "one-group-signed.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	selector := func(openpgp.EntityList) (*openpgp.Entity, error) {
+		return e2, nil
+	}
+	if err := Sign(path, OptSignEntitySelector(selector)); err != nil {
+		t.Fatal(err)
+	}
+
+	var gotSigners int
+
+	cb := func(f *sif.FileImage, link uint32, signers []*openpgp.Entity) {
+		gotSigners = len(signers)
+	}
+	opts := []VerifyOpt{OptVerifyUseKeyRing(openpgp.EntityList{e, e2}), OptVerifyThreshold(2, cb)}
+
+	if err := Verify(context.Background(), path, opts...); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got, want := gotSigners, 2; got != want {
+		t.Errorf("got %v signers, want %v", got, want)
+	}
+}
+
+func TestVerifyThresholdIntegrity(t *testing.T) {
+	e := getTestEntity(t)
+
+	path, err := tempFileFrom(filepath.Join("testdata", "images", "one-group-signed.sif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(path)
+
+	// Corrupt the first signed object.
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	od, _, err := f.GetFromDescrID(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	off := od.Fileoff
+	f.UnloadContainer()
+
+	fp, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fp.Close()
+
+	b := make([]byte, 1)
+	if _, err := fp.ReadAt(b, off); err != nil {
+		t.Fatal(err)
+	}
+	b[0] ^= 0xff
+	if _, err := fp.WriteAt(b, off); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := []VerifyOpt{OptVerifyUseKeyRing(openpgp.EntityList{e}), OptVerifyThreshold(1, nil)}
+
+	if got, want := Verify(context.Background(), path, opts...), ErrObjectIntegrity; !errors.Is(got, want) {
+		t.Errorf("got error %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// ParseAnnotations returns the annotations of the --annotate values specs,
+// of the form KEY=VALUE. A value holding valid JSON, as an object, an array
+// or a number, is kept as is, any other value is stored as a JSON string.
+// A key set more than once takes its last value.
+func ParseAnnotations(specs []string) (map[string]json.RawMessage, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	annotations := make(map[string]json.RawMessage, len(specs))
+	for _, spec := range specs {
+		kv := strings.SplitN(spec, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return nil, fmt.Errorf("annotation %q must have the format KEY=VALUE", spec)
+		}
+		key := strings.TrimSpace(kv[0])
+		if strings.ContainsAny(key, " \t\n") {
+			return nil, fmt.Errorf("annotation key %q contains blanks", key)
+		}
+
+		value := json.RawMessage(strings.TrimSpace(kv[1]))
+		if !json.Valid(value) {
+			b, err := json.Marshal(kv[1])
+			if err != nil {
+				return nil, err
+			}
+			value = b
+		}
+		annotations[key] = value
+	}
+	return annotations, nil
+}
+
+// insertAnnotations stores the build annotations in the annotations JSON
+// object of the bundle, added to SIF images as a JSON data object.
+func insertAnnotations(b *types.Bundle) error {
+	if len(b.Opts.Annotations) == 0 {
+		return nil
+	}
+	sylog.Infof("Adding image annotations")
+	data, err := json.Marshal(b.Opts.Annotations)
+	if err != nil {
+		return err
+	}
+	b.JSONObjects[types.AnnotationsJSON] = data
+	return nil
+}
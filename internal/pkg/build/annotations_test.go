@@ -0,0 +1,71 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"testing"
+)
+
+func TestParseAnnotations(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    map[string]string
+		wantErr bool
+	}{
+		{name: "None", specs: nil, want: nil},
+		{
+			name:  "String",
+			specs: []string{"org.opencontainers.image.authors=John Doe"},
+			want:  map[string]string{"org.opencontainers.image.authors": `"John Doe"`},
+		},
+		{
+			name:  "JSON",
+			specs: []string{`org.example.build={"id": 42, "tags": ["a", "b"]}`, "org.example.count=3", `org.example.quoted="3"`},
+			want: map[string]string{
+				"org.example.build":  `{"id": 42, "tags": ["a", "b"]}`,
+				"org.example.count":  "3",
+				"org.example.quoted": `"3"`,
+			},
+		},
+		{
+			name:  "Empty",
+			specs: []string{"org.example.empty="},
+			want:  map[string]string{"org.example.empty": `""`},
+		},
+		{
+			name:  "Override",
+			specs: []string{"org.example.key=first", "org.example.key=second"},
+			want:  map[string]string{"org.example.key": `"second"`},
+		},
+		{name: "NoSeparator", specs: []string{"org.example.key"}, wantErr: true},
+		{name: "NoKey", specs: []string{"=value"}, wantErr: true},
+		{name: "BlankKey", specs: []string{"org.example key=value"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseAnnotations(tt.specs)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d annotations, want %d", len(got), len(tt.want))
+			}
+			for k, v := range tt.want {
+				if string(got[k]) != v {
+					t.Errorf("got %s=%s, want %s", k, got[k], v)
+				}
+			}
+		})
+	}
+}
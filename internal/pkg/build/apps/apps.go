@@ -33,6 +33,7 @@ const (
 	sectionTest    = "apptest"
 	sectionHelp    = "apphelp"
 	sectionRun     = "apprun"
+	sectionStart   = "appstart"
 	sectionLabels  = "applabels"
 )
 
@@ -44,6 +45,7 @@ var (
 		sectionTest:    true,
 		sectionHelp:    true,
 		sectionRun:     true,
+		sectionStart:   true,
 		sectionLabels:  true,
 	}
 )
@@ -68,6 +70,8 @@ export SCIF_APPDATA_%[1]s SCIF_APPMETA_%[1]s SCIF_APPROOT_%[1]s SCIF_APPBIN_%[1]
 `
 	globalEnv94AppRun = `export SCIF_APPRUN_%[1]s="/scif/apps/%[1]s/scif/runscript"
 `
+	globalEnv94AppStart = `export SCIF_APPSTART_%[1]s="/scif/apps/%[1]s/scif/startscript"
+`
 
 	scifEnv01Base = `#!/bin/sh
 
@@ -79,10 +83,24 @@ SCIF_APPDATA="/scif/data/%[1]s"
 SCIF_APPINPUT="/scif/data/%[1]s/input"
 SCIF_APPOUTPUT="/scif/data/%[1]s/output"
 export SCIF_APPDATA SCIF_APPNAME SCIF_APPROOT SCIF_APPMETA SCIF_APPINPUT SCIF_APPOUTPUT SCIF_DATA
+
+SCIF_APPBIN="/scif/apps/%[1]s/bin"
+SCIF_APPLIB="/scif/apps/%[1]s/lib"
+SCIF_APPENV="/scif/apps/%[1]s/scif/env/90-environment.sh"
+SCIF_APPLABELS="/scif/apps/%[1]s/scif/labels.json"
+SCIF_APPRUN="/scif/apps/%[1]s/scif/runscript"
+SCIF_APPSTART="/scif/apps/%[1]s/scif/startscript"
+SCIF_APPHELP="/scif/apps/%[1]s/scif/runscript.help"
+SCIF_APPTEST="/scif/apps/%[1]s/scif/test"
+export SCIF_APPBIN SCIF_APPLIB SCIF_APPENV SCIF_APPLABELS SCIF_APPRUN SCIF_APPSTART SCIF_APPHELP SCIF_APPTEST
 `
 
 	scifRunscriptBase = `#!/bin/sh
 
+%s
+`
+	scifStartscriptBase = `#!/bin/sh
+
 %s
 `
 	scifTestBase = `#!/bin/sh
@@ -110,6 +128,7 @@ type App struct {
 	Test    string
 	Help    string
 	Run     string
+	Start   string
 	Labels  string
 }
 
@@ -155,6 +174,8 @@ func (pl *BuildApp) HandleSection(ident, section string) {
 		app.Help = section
 	case sectionRun:
 		app.Run = section
+	case sectionStart:
+		app.Start = section
 	case sectionLabels:
 		app.Labels = section
 	default:
@@ -176,6 +197,7 @@ func (pl *BuildApp) initApp(name string) {
 			Test:    "",
 			Help:    "",
 			Run:     "",
+			Start:   "",
 		}
 	}
 }
@@ -225,6 +247,10 @@ func (pl *BuildApp) createAllApps(b *types.Bundle) error {
 			return err
 		}
 
+		if err := writeStartscriptFile(b, app); err != nil {
+			return err
+		}
+
 		if err := writeTestFile(b, app); err != nil {
 			return err
 		}
@@ -310,6 +336,10 @@ func globalAppEnv(b *types.Bundle, a *App) string {
 		content += fmt.Sprintf(globalEnv94AppRun, name)
 	}
 
+	if _, err := os.Stat(filepath.Join(appMeta(b, a), "/startscript")); err == nil {
+		content += fmt.Sprintf(globalEnv94AppStart, name)
+	}
+
 	return content
 }
 
@@ -323,6 +353,16 @@ func writeRunscriptFile(b *types.Bundle, a *App) error {
 	return ioutil.WriteFile(filepath.Join(appMeta(b, a), "/runscript"), []byte(content), 0755)
 }
 
+// %appstart
+func writeStartscriptFile(b *types.Bundle, a *App) error {
+	if a.Start == "" {
+		return nil
+	}
+
+	content := fmt.Sprintf(scifStartscriptBase, a.Start)
+	return ioutil.WriteFile(filepath.Join(appMeta(b, a), "/startscript"), []byte(content), 0755)
+}
+
 // %apptest
 func writeTestFile(b *types.Bundle, a *App) error {
 	if a.Test == "" {
@@ -0,0 +1,79 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// deffileChecksumPrefix prefixes the checksum of the definition file
+// stored as the name of its SIF descriptor.
+const deffileChecksumPrefix = "sha256:"
+
+// ErrCorruptDeffile is returned when the definition file of a SIF image
+// doesn't match the size or the checksum of its descriptor.
+var ErrCorruptDeffile = errors.New("definition file descriptor is corrupted")
+
+// DeffileChecksum returns the checksum of definition stored as the name
+// of the SIF definition file descriptor.
+func DeffileChecksum(definition []byte) string {
+	sum := sha256.Sum256(definition)
+	return deffileChecksumPrefix + hex.EncodeToString(sum[:])
+}
+
+// CheckDeffile checks the definition read from a SIF definition file
+// descriptor named name against the checksum of the descriptor. The
+// descriptors of images built by previous versions have no checksum
+// and are not checked.
+func CheckDeffile(name string, definition []byte) error {
+	if !strings.HasPrefix(name, deffileChecksumPrefix) {
+		return nil
+	}
+	if DeffileChecksum(definition) != name {
+		return fmt.Errorf("%w: checksum mismatch for the %d bytes read", ErrCorruptDeffile, len(definition))
+	}
+	return nil
+}
+
+// verifyDeffile reads back the definition file descriptor of the SIF
+// image at path and checks it against definition.
+func verifyDeffile(path string, definition []byte) error {
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		return fmt.Errorf("while loading SIF image: %v", err)
+	}
+	defer f.UnloadContainer()
+
+	for _, d := range f.DescrArr {
+		if !d.Used || d.Datatype != sif.DataDeffile {
+			continue
+		}
+		if d.Filelen != int64(len(definition)) {
+			return fmt.Errorf("%w: %d bytes written instead of %d", ErrCorruptDeffile, d.Filelen, len(definition))
+		}
+
+		fp, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+
+		data := make([]byte, d.Filelen)
+		if _, err := fp.ReadAt(data, d.Fileoff); err != nil {
+			return fmt.Errorf("while reading definition file descriptor: %v", err)
+		}
+		return CheckDeffile(d.GetName(), data)
+	}
+
+	return fmt.Errorf("%w: descriptor not found", ErrCorruptDeffile)
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+func TestCheckDeffile(t *testing.T) {
+	def := []byte("Bootstrap: scratch\n")
+
+	tests := []struct {
+		name       string
+		descriptor string
+		data       []byte
+		corrupt    bool
+	}{
+		{name: "Valid", descriptor: DeffileChecksum(def), data: def},
+		{name: "Truncated", descriptor: DeffileChecksum(def), data: def[:4], corrupt: true},
+		{name: "Modified", descriptor: DeffileChecksum(def), data: []byte("Bootstrap: docker\n"), corrupt: true},
+		{name: "NoChecksum", data: def},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckDeffile(tt.descriptor, tt.data)
+			if corrupt := errors.Is(err, ErrCorruptDeffile); corrupt != tt.corrupt {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyDeffile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sif-deffile-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "image.sif")
+	def := []byte("Bootstrap: scratch\n")
+	cinfo := sif.CreateInfo{
+		Pathname:   path,
+		Launchstr:  sif.HdrLaunch,
+		Sifversion: sif.HdrVersion,
+		ID:         uuid.NewV4(),
+		InputDescr: []sif.DescriptorInput{
+			{
+				Datatype: sif.DataDeffile,
+				Groupid:  sif.DescrDefaultGroup,
+				Link:     sif.DescrUnusedLink,
+				Fname:    DeffileChecksum(def),
+				Data:     def,
+				Size:     int64(len(def)),
+			},
+		},
+	}
+	if _, err := sif.CreateContainer(cinfo); err != nil {
+		t.Fatalf("failed to create SIF image: %v", err)
+	}
+
+	if err := verifyDeffile(path, def); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := verifyDeffile(path, []byte("Bootstrap: docker\n")); !errors.Is(err, ErrCorruptDeffile) {
+		t.Errorf("unexpected error for a different definition: %v", err)
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+const (
+	// ext3MinSize is the minimum size of the computed ext3 image sizes.
+	ext3MinSize = 64 << 20
+	// ext3InodeSize and ext3BlockSize are the default inode and block
+	// sizes of mkfs.ext3.
+	ext3InodeSize = 256
+	ext3BlockSize = 4096
+)
+
+// ext3 creates a writable ext3 image populated with the content of the
+// directory src in the bundle temporary directory and returns its path.
+// The image size is a.FsSize, or the size of src with a quarter of free
+// space when zero. It requires mkfs.ext3 from e2fsprogs 1.43 or later.
+func (a *SIFAssembler) ext3(b *types.Bundle, src string) (string, error) {
+	size, entries, err := treeUsage(src)
+	if err != nil {
+		return "", fmt.Errorf("while computing %s size: %v", src, err)
+	}
+	// leave room for the inode tables and for writes
+	inodes := entries + entries/4 + 1024
+	needed := size + inodes*ext3InodeSize
+	if a.FsSize == 0 {
+		a.FsSize = needed + needed/4
+		if a.FsSize < ext3MinSize {
+			a.FsSize = ext3MinSize
+		}
+		// round up to a MiB
+		a.FsSize = (a.FsSize + 1<<20 - 1) &^ (1<<20 - 1)
+	} else if a.FsSize < needed {
+		return "", fmt.Errorf("ext3 partition size of %d MiB is too small for the %d MiB root filesystem", a.FsSize>>20, (needed+1<<20-1)>>20)
+	}
+	sylog.Verbosef("Creating ext3 partition of %d MiB with %d inodes", a.FsSize>>20, inodes)
+
+	f, err := ioutil.TempFile(b.TmpDir, "ext3-")
+	if err != nil {
+		return "", fmt.Errorf("while creating temporary file for ext3: %v", err)
+	}
+	fsPath := f.Name()
+	err = f.Truncate(a.FsSize)
+	f.Close()
+	if err != nil {
+		os.Remove(fsPath)
+		return "", fmt.Errorf("while allocating ext3 image: %v", err)
+	}
+
+	var stderr bytes.Buffer
+	cmd := exec.Command(a.Mkfsext3Path, "-q", "-F", "-N", fmt.Sprint(inodes), "-d", src, fsPath)
+	cmd.Stderr = &stderr
+
+	progress.Packaging("ext3", progress.StatusStarted, 0)
+	if err := cmd.Run(); err != nil {
+		progress.Packaging("ext3", progress.StatusFailure, 0)
+		os.Remove(fsPath)
+		msg := strings.TrimSpace(stderr.String())
+		if strings.Contains(msg, "invalid option") {
+			return "", fmt.Errorf("while creating ext3 image: %s does not support -d, e2fsprogs 1.43 or later is required", a.Mkfsext3Path)
+		}
+		return "", fmt.Errorf("while creating ext3 image: %v: %s", err, msg)
+	}
+	progress.Packaging("ext3", progress.StatusSuccess, a.FsSize)
+
+	return fsPath, nil
+}
+
+// treeUsage returns the size in bytes of the ext3 blocks used by the
+// regular files and directories found in dir and its number of entries.
+func treeUsage(dir string) (size int64, entries int64, err error) {
+	err = filepath.Walk(dir, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		entries++
+		if fi.Mode().IsRegular() || fi.IsDir() {
+			size += (fi.Size() + ext3BlockSize - 1) &^ (ext3BlockSize - 1)
+		}
+		return nil
+	})
+	return size, entries, err
+}
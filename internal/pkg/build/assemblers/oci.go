@@ -0,0 +1,73 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/singularity/internal/pkg/build/export"
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+	"github.com/sylabs/singularity/internal/pkg/util/machine"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// OCITag is the tag of the image stored in the OCI image layouts
+// created by the OCI assembler.
+const OCITag = "latest"
+
+// OCIAssembler assembles an OCI image layout directory holding a single
+// layer image, converted as images pushed with 'singularity push --oci'.
+type OCIAssembler struct{}
+
+// Assemble creates an OCI image layout from a Bundle.
+func (a *OCIAssembler) Assemble(b *types.Bundle, path string) (err error) {
+	sylog.Infof("Creating OCI image layout...")
+
+	progress.Packaging("oci", progress.StatusStarted, 0)
+	defer func() {
+		if err != nil {
+			progress.Packaging("oci", progress.StatusFailure, 0)
+		} else {
+			progress.Packaging("oci", progress.StatusSuccess, 0)
+		}
+	}()
+
+	var base *imgspecv1.ImageConfig
+	if data, ok := b.JSONObjects[types.OCIConfigJSON]; ok {
+		base = new(imgspecv1.ImageConfig)
+		if err := json.Unmarshal(data, base); err != nil {
+			return fmt.Errorf("while decoding OCI configuration: %s", err)
+		}
+	}
+	conf, err := export.ImageConfig(b.RootfsPath, base)
+	if err != nil {
+		return err
+	}
+	annotations, err := export.ManifestAnnotations(b.Opts.Annotations)
+	if err != nil {
+		return err
+	}
+
+	arch := machine.ArchFromContainer(b.RootfsPath)
+	if arch == "" && b.Opts.Arch != "" {
+		arch = b.Opts.Arch
+	} else if arch == "" {
+		arch = runtime.GOARCH
+	}
+
+	if err := os.RemoveAll(path); err != nil {
+		return err
+	}
+	if err := export.WriteLayout(path, OCITag, b.RootfsPath, arch, conf, annotations, rootOwner(b)); err != nil {
+		return fmt.Errorf("while creating OCI image layout: %s", err)
+	}
+	return nil
+}
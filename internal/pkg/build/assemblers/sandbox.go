@@ -11,6 +11,7 @@ import (
 	"os"
 	"os/exec"
 
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
 	"github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -24,6 +25,15 @@ type SandboxAssembler struct {
 func (a *SandboxAssembler) Assemble(b *types.Bundle, path string) (err error) {
 	sylog.Infof("Creating sandbox directory...")
 
+	progress.Packaging("sandbox", progress.StatusStarted, 0)
+	defer func() {
+		if err != nil {
+			progress.Packaging("sandbox", progress.StatusFailure, 0)
+		} else {
+			progress.Packaging("sandbox", progress.StatusSuccess, 0)
+		}
+	}()
+
 	if _, err := os.Stat(path); err == nil {
 		os.RemoveAll(path)
 	}
@@ -6,19 +6,31 @@
 package assemblers
 
 import (
+	"bytes"
+	"crypto/sha256"
 	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"os"
+	"path/filepath"
 	"regexp"
 	"runtime"
+	"sort"
 	"strconv"
+	"strings"
 	"syscall"
 
 	uuid "github.com/satori/go.uuid"
 	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/internal/pkg/util/machine"
 	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/image"
 	"github.com/sylabs/singularity/pkg/image/packer"
 	"github.com/sylabs/singularity/pkg/sylog"
 	"github.com/sylabs/singularity/pkg/util/crypt"
@@ -26,24 +38,89 @@ import (
 
 // SIFAssembler doesn't store anything.
 type SIFAssembler struct {
-	GzipFlag        bool
+	// CompFlags are the mksquashfs flags selecting the compression
+	// algorithm and level, mksquashfs defaults are used when empty.
+	CompFlags       []string
 	MksquashfsProcs uint
 	MksquashfsMem   string
 	MksquashfsPath  string
+
+	// Mkfsext3Path is the path of mkfs.ext3, when set the primary system
+	// partition is a writable ext3 image of FsSize bytes, computed from
+	// the root filesystem size when zero, instead of a squashfs image.
+	Mkfsext3Path string
+	FsSize       int64
+	// PartName is the name of the primary system partition descriptor,
+	// the name of the temporary partition image when empty.
+	PartName string
+
+	// Layers holds the base and overlay layers of layered builds,
+	// the root filesystem is squashed in a single partition when nil.
+	Layers *Layers
+
+	// SHA256 is the hex encoded SHA-256 digest of the assembled
+	// SIF image and UUID is its unique identifier, both are set
+	// by Assemble.
+	SHA256 string
+	UUID   string
+}
+
+// Layers describes the root filesystem of a layered build, stored
+// in SIF as an immutable base partition and an overlay partition
+// holding the changes made on top of it.
+type Layers struct {
+	// Base is the squashfs image of the bootstrapped root filesystem.
+	Base string
+	// BaseDigest is the digest of the source image layer stored in
+	// Base and Lower are the source image layers stacked on top of it,
+	// bottom layer first, for builds keeping the source layers.
+	BaseDigest string
+	Lower      []LayerImage
+	// Upper is the directory holding the entries added or modified
+	// on top of the base root filesystem.
+	Upper string
+	// Whiteouts are the paths, relative to Upper, of the entries
+	// removed from the base root filesystem.
+	Whiteouts []string
+}
+
+// LayerImage is the squashfs image of a kept source image layer.
+type LayerImage struct {
+	// Digest is the digest of the layer blob.
+	Digest string
+	// Path is the path of the squashfs image.
+	Path string
 }
 
+// overlayPartition is an overlay partition added to a SIF image.
+type overlayPartition struct {
+	name string
+	path string
+}
+
+// systemPartition is the primary system partition of a SIF image.
+type systemPartition struct {
+	name   string
+	path   string
+	fsType sif.Fstype
+}
+
+// createSIFAttempts is the number of attempts to create a SIF image
+// whose definition file fails verification.
+const createSIFAttempts = 2
+
 type encryptionOptions struct {
 	keyInfo   crypt.KeyInfo
 	plaintext []byte
 }
 
-func createSIF(path string, definition, ociConf []byte, squashfile string, encOpts *encryptionOptions, arch string) (err error) {
+func createSIF(path string, definition []byte, jsonObjects map[string][]byte, dataPart string, syspart systemPartition, overlays []overlayPartition, encOpts *encryptionOptions, arch string, id uuid.UUID) (err error) {
 	// general info for the new SIF file creation
 	cinfo := sif.CreateInfo{
 		Pathname:   path,
 		Launchstr:  sif.HdrLaunch,
 		Sifversion: sif.HdrVersion,
-		ID:         uuid.NewV4(),
+		ID:         id,
 	}
 
 	// data we need to create a definition file descriptor
@@ -52,25 +129,64 @@ func createSIF(path string, definition, ociConf []byte, squashfile string, encOp
 		Groupid:  sif.DescrDefaultGroup,
 		Link:     sif.DescrUnusedLink,
 		Data:     definition,
+		// the checksum is verified once written and by inspect
+		Fname: DeffileChecksum(definition),
 	}
 	definput.Size = int64(binary.Size(definput.Data))
 
 	// add this descriptor input element to creation descriptor slice
 	cinfo.InputDescr = append(cinfo.InputDescr, definput)
 
-	if len(ociConf) > 0 {
-		// data we need to create a definition file descriptor
-		ociInput := sif.DescriptorInput{
+	// add JSON objects in a stable order
+	names := make([]string, 0, len(jsonObjects))
+	for name := range jsonObjects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if len(jsonObjects[name]) == 0 {
+			continue
+		}
+		// data we need to create a JSON object descriptor
+		jsonInput := sif.DescriptorInput{
 			Datatype: sif.DataGenericJSON,
 			Groupid:  sif.DescrDefaultGroup,
 			Link:     sif.DescrUnusedLink,
-			Data:     ociConf,
-			Fname:    "oci-config.json",
+			Data:     jsonObjects[name],
+			Fname:    name + ".json",
 		}
-		ociInput.Size = int64(binary.Size(ociInput.Data))
+		jsonInput.Size = int64(binary.Size(jsonInput.Data))
 
 		// add this descriptor input element to creation descriptor slice
-		cinfo.InputDescr = append(cinfo.InputDescr, ociInput)
+		cinfo.InputDescr = append(cinfo.InputDescr, jsonInput)
+	}
+
+	// add the data files squashfs image as a named data partition
+	if dataPart != "" {
+		fp, err := os.Open(dataPart)
+		if err != nil {
+			return fmt.Errorf("while opening data files partition: %s", err)
+		}
+		defer fp.Close()
+
+		fi, err := fp.Stat()
+		if err != nil {
+			return fmt.Errorf("while calling stat on data files partition: %s", err)
+		}
+
+		dataInput := sif.DescriptorInput{
+			Datatype: sif.DataPartition,
+			Groupid:  sif.DescrDefaultGroup,
+			Link:     sif.DescrUnusedLink,
+			Fname:    image.DataFilesPartition,
+			Fp:       fp,
+			Size:     fi.Size(),
+		}
+		if err := dataInput.SetPartExtra(sif.FsSquash, sif.PartData, sif.GetSIFArch(arch)); err != nil {
+			return err
+		}
+		cinfo.InputDescr = append(cinfo.InputDescr, dataInput)
 	}
 
 	// data we need to create a system partition descriptor
@@ -78,10 +194,10 @@ func createSIF(path string, definition, ociConf []byte, squashfile string, encOp
 		Datatype: sif.DataPartition,
 		Groupid:  sif.DescrDefaultGroup,
 		Link:     sif.DescrUnusedLink,
-		Fname:    squashfile,
+		Fname:    syspart.name,
 	}
 	// open up the data object file for this descriptor
-	fp, err := os.Open(parinput.Fname)
+	fp, err := os.Open(syspart.path)
 	if err != nil {
 		return fmt.Errorf("while opening partition file: %s", err)
 	}
@@ -96,7 +212,7 @@ func createSIF(path string, definition, ociConf []byte, squashfile string, encOp
 	parinput.Fp = fp
 	parinput.Size = fi.Size()
 
-	sifType := sif.FsSquash
+	sifType := syspart.fsType
 
 	if encOpts != nil {
 		sifType = sif.FsEncryptedSquashfs
@@ -110,6 +226,34 @@ func createSIF(path string, definition, ociConf []byte, squashfile string, encOp
 	// add this descriptor input element to the list
 	cinfo.InputDescr = append(cinfo.InputDescr, parinput)
 
+	// overlay partitions are stacked in order at runtime
+	for _, ov := range overlays {
+		ovinput := sif.DescriptorInput{
+			Datatype: sif.DataPartition,
+			Groupid:  sif.DescrDefaultGroup,
+			Link:     sif.DescrUnusedLink,
+			Fname:    ov.name,
+		}
+		fp, err := os.Open(ov.path)
+		if err != nil {
+			return fmt.Errorf("while opening overlay partition file: %s", err)
+		}
+		defer fp.Close()
+
+		fi, err := fp.Stat()
+		if err != nil {
+			return fmt.Errorf("while calling stat on overlay partition file: %s", err)
+		}
+
+		ovinput.Fp = fp
+		ovinput.Size = fi.Size()
+
+		if err := ovinput.SetPartExtra(sif.FsSquash, sif.PartOverlay, sif.GetSIFArch(arch)); err != nil {
+			return err
+		}
+		cinfo.InputDescr = append(cinfo.InputDescr, ovinput)
+	}
+
 	if encOpts != nil {
 		data, err := crypt.EncryptKey(encOpts.keyInfo, encOpts.plaintext)
 		if err != nil {
@@ -141,9 +285,23 @@ func createSIF(path string, definition, ociConf []byte, squashfile string, encOp
 
 	// test container creation with two partition input descriptors
 	if _, err := sif.CreateContainer(cinfo); err != nil {
+		// don't leave a partial image behind
+		os.Remove(path)
+		if isNoSpace(err) {
+			var size uint64
+			for _, input := range cinfo.InputDescr {
+				size += uint64(input.Size)
+			}
+			return fmt.Errorf("while creating container: %w", noSpaceError(err, path, size))
+		}
 		return fmt.Errorf("while creating container: %s", err)
 	}
 
+	if err := verifyDeffile(path, definition); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("while verifying definition file: %w", err)
+	}
+
 	// chown the sif file to the calling user
 	if uid, gid, ok := changeOwner(); ok {
 		if err := os.Chown(path, uid, gid); err != nil {
@@ -154,48 +312,181 @@ func createSIF(path string, definition, ociConf []byte, squashfile string, encOp
 	return nil
 }
 
-// Assemble creates a SIF image from a Bundle.
-func (a *SIFAssembler) Assemble(b *types.Bundle, path string) error {
-	sylog.Infof("Creating SIF file...")
-
+// squashfs creates a squashfs image of the directory src in the bundle
+// temporary directory and returns its path, pseudo is an optional
+// mksquashfs pseudo definitions file.
+func (a *SIFAssembler) squashfs(b *types.Bundle, src, pseudo string) (string, error) {
 	s := packer.NewSquashfs()
 	s.MksquashfsPath = a.MksquashfsPath
+	// mksquashfs 4.4 and later use it for the filesystem and files timestamps
+	if !b.Opts.SourceDateEpoch.IsZero() {
+		s.Env = []string{fmt.Sprintf("SOURCE_DATE_EPOCH=%d", b.Opts.SourceDateEpoch.Unix())}
+	}
 
 	f, err := ioutil.TempFile(b.TmpDir, "squashfs-")
 	if err != nil {
-		return fmt.Errorf("while creating temporary file for squashfs: %v", err)
+		return "", fmt.Errorf("while creating temporary file for squashfs: %v", err)
 	}
 
 	fsPath := f.Name()
 	f.Close()
-	defer os.Remove(fsPath)
 
-	flags := []string{"-noappend"}
+	// extended attributes hold the file capabilities
+	flags := []string{"-noappend", "-xattrs"}
 	// build squashfs with all-root flag when building as a user
 	if syscall.Getuid() != 0 {
 		flags = append(flags, "-all-root")
 	}
 	// specify compression if needed
-	if a.GzipFlag {
-		flags = append(flags, "-comp", "gzip")
-	}
+	flags = append(flags, a.CompFlags...)
 	if a.MksquashfsMem != "" {
 		flags = append(flags, "-mem", a.MksquashfsMem)
 	}
 	if a.MksquashfsProcs != 0 {
 		flags = append(flags, "-processors", fmt.Sprint(a.MksquashfsProcs))
 	}
+	if pseudo != "" {
+		flags = append(flags, "-pf", pseudo)
+	}
+
+	progress.Packaging("squashfs", progress.StatusStarted, 0)
+	if err := s.Create([]string{src}, fsPath, flags); err != nil {
+		progress.Packaging("squashfs", progress.StatusFailure, 0)
+		os.Remove(fsPath)
+		if isNoSpace(err) {
+			size, _ := dirSize(src)
+			return "", fmt.Errorf("while creating squashfs: %w", noSpaceError(err, fsPath, size))
+		}
+		return "", fmt.Errorf("while creating squashfs: %v", err)
+	}
+	progress.Packaging("squashfs", progress.StatusSuccess, fileSize(fsPath))
+	return fsPath, nil
+}
+
+// dataFiles creates the squashfs image holding the files of the %datafile
+// section of the bundle definition, each one stored under its data file
+// name, and returns its path, empty when there are no data files.
+func (a *SIFAssembler) dataFiles(b *types.Bundle) (string, error) {
+	dataFiles := b.Recipe.BuildData.DataFiles
+	if len(dataFiles) == 0 {
+		return "", nil
+	}
+
+	dir, err := ioutil.TempDir(b.TmpDir, "datafiles-")
+	if err != nil {
+		return "", fmt.Errorf("while creating data files directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, df := range dataFiles {
+		fi, err := os.Stat(df.Src)
+		if err != nil {
+			return "", fmt.Errorf("while calling stat on data file: %v", err)
+		}
+		if !fi.Mode().IsRegular() {
+			return "", fmt.Errorf("data file %s is not a regular file", df.Src)
+		}
+		// avoid copying large data files when possible
+		dst := filepath.Join(dir, df.Name)
+		if err := os.Link(df.Src, dst); err == nil {
+			continue
+		}
+		if err := fs.CopyFile(df.Src, dst, 0644); err != nil {
+			return "", fmt.Errorf("while copying data file %s: %v", df.Src, err)
+		}
+	}
+
+	sylog.Infof("Adding data files...")
+	return a.squashfs(b, dir, "")
+}
+
+// CreateBaseLayer creates the squashfs image of the bundle root
+// filesystem used as immutable base layer by layered builds.
+func (a *SIFAssembler) CreateBaseLayer(b *types.Bundle) (string, error) {
+	sylog.Infof("Creating base layer...")
+	return a.squashfs(b, b.RootfsPath, "")
+}
+
+// CreateSourceLayer creates the squashfs image of the source image layer
+// extracted in dir, whiteouts are the paths of the lower layers entries
+// removed by the layer.
+func (a *SIFAssembler) CreateSourceLayer(b *types.Bundle, dir string, whiteouts []string) (string, error) {
+	pseudo, err := writeWhiteouts(b.TmpDir, whiteouts)
+	if err != nil {
+		return "", err
+	}
+	if pseudo != "" {
+		defer os.Remove(pseudo)
+	}
+	return a.squashfs(b, dir, pseudo)
+}
+
+// Assemble creates a SIF image from a Bundle.
+func (a *SIFAssembler) Assemble(b *types.Bundle, path string) error {
+	sylog.Infof("Creating SIF file...")
+
 	arch := machine.ArchFromContainer(b.RootfsPath)
-	if arch == "" {
+	if arch == "" && b.Opts.Arch != "" {
+		arch = b.Opts.Arch
+	} else if arch == "" {
 		sylog.Infof("Architecture not recognized, use native")
 		arch = runtime.GOARCH
 	}
 	sylog.Verbosef("Set SIF container architecture to %s", arch)
 
-	if err := s.Create([]string{b.RootfsPath}, fsPath, flags); err != nil {
-		return fmt.Errorf("while creating squashfs: %v", err)
+	if size, err := dirSize(b.RootfsPath); err == nil {
+		checkSpace(b.TmpDir, path, size)
 	}
 
+	var fsPath string
+	var overlays []overlayPartition
+	fsType := sif.FsSquash
+
+	if a.Layers != nil {
+		pseudo, err := writeWhiteouts(b.TmpDir, a.Layers.Whiteouts)
+		if err != nil {
+			return err
+		}
+		if pseudo != "" {
+			defer os.Remove(pseudo)
+		}
+		overlayPath, err := a.squashfs(b, a.Layers.Upper, pseudo)
+		if err != nil {
+			return fmt.Errorf("while creating overlay layer: %w", err)
+		}
+		defer os.Remove(overlayPath)
+		fsPath = a.Layers.Base
+
+		if a.Layers.BaseDigest != "" {
+			index := []types.LayerEntry{{Digest: a.Layers.BaseDigest, Primary: true}}
+			for _, l := range a.Layers.Lower {
+				defer os.Remove(l.Path)
+				overlays = append(overlays, overlayPartition{name: l.Digest, path: l.Path})
+				index = append(index, types.LayerEntry{Digest: l.Digest, Partition: l.Digest})
+			}
+			data, err := json.Marshal(index)
+			if err != nil {
+				return fmt.Errorf("while encoding layers index: %v", err)
+			}
+			b.JSONObjects[types.LayersJSON] = data
+		}
+		overlays = append(overlays, overlayPartition{name: overlayPath, path: overlayPath})
+	} else if a.Mkfsext3Path != "" {
+		var err error
+		fsPath, err = a.ext3(b, b.RootfsPath)
+		if err != nil {
+			return err
+		}
+		fsType = sif.FsExt3
+	} else {
+		var err error
+		fsPath, err = a.squashfs(b, b.RootfsPath, "")
+		if err != nil {
+			return err
+		}
+	}
+	defer os.Remove(fsPath)
+
 	var encOpts *encryptionOptions
 
 	if b.Opts.EncryptionKeyInfo != nil {
@@ -226,14 +517,156 @@ func (a *SIFAssembler) Assemble(b *types.Bundle, path string) error {
 
 	}
 
-	err = createSIF(path, b.Recipe.Raw, b.JSONObjects[types.OCIConfigJSON], fsPath, encOpts, arch)
+	dataPart, err := a.dataFiles(b)
+	if err != nil {
+		return fmt.Errorf("while creating data files partition: %w", err)
+	}
+	if dataPart != "" {
+		defer os.Remove(dataPart)
+	}
+
+	id := uuid.NewV4()
+
+	progress.Packaging("sif", progress.StatusStarted, 0)
+	syspart := systemPartition{name: fsPath, path: fsPath, fsType: fsType}
+	if a.PartName != "" {
+		syspart.name = a.PartName
+	}
+	for attempt := 1; ; attempt++ {
+		err = createSIF(path, b.Recipe.Raw, b.JSONObjects, dataPart, syspart, overlays, encOpts, arch, id)
+		if err == nil || !errors.Is(err, ErrCorruptDeffile) || attempt == createSIFAttempts {
+			break
+		}
+		sylog.Warningf("%s, creating SIF file again", err)
+	}
+	if err != nil {
+		progress.Packaging("sif", progress.StatusFailure, 0)
+		return fmt.Errorf("while creating SIF: %w", err)
+	}
+	progress.Packaging("sif", progress.StatusSuccess, fileSize(path))
+
+	if !b.Opts.SourceDateEpoch.IsZero() {
+		if err := setSIFTimes(path, b.Opts.SourceDateEpoch.Unix()); err != nil {
+			return fmt.Errorf("while setting SIF timestamps: %v", err)
+		}
+	}
+
+	// the image is still in the page cache, compute its digest right
+	// away rather than reloading it once the build is done
+	digest, err := sha256File(path)
 	if err != nil {
-		return fmt.Errorf("while creating SIF: %v", err)
+		return fmt.Errorf("while computing SIF digest: %v", err)
 	}
+	a.SHA256 = digest
+	a.UUID = id.String()
 
 	return nil
 }
 
+// writeWhiteouts writes the mksquashfs pseudo definitions file creating
+// the overlay whiteouts, character devices with 0/0 device number, of
+// the removed paths and returns its path, if any.
+func writeWhiteouts(dir string, whiteouts []string) (string, error) {
+	if len(whiteouts) == 0 {
+		return "", nil
+	}
+
+	f, err := ioutil.TempFile(dir, "whiteouts-")
+	if err != nil {
+		return "", fmt.Errorf("while creating whiteouts file: %v", err)
+	}
+	defer f.Close()
+
+	for _, path := range whiteouts {
+		if strings.ContainsAny(path, " \t\n\"\\") {
+			os.Remove(f.Name())
+			return "", fmt.Errorf("can't record removal of %q in overlay layer, use --squash-layers", path)
+		}
+		if _, err := fmt.Fprintf(f, "%s c 0000 0 0 0 0\n", path); err != nil {
+			os.Remove(f.Name())
+			return "", fmt.Errorf("while writing whiteouts file: %v", err)
+		}
+	}
+	return f.Name(), nil
+}
+
+// fileSize returns the size of the file at path, 0 if unknown.
+func fileSize(path string) int64 {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return 0
+	}
+	return fi.Size()
+}
+
+// setSIFTimes sets the creation and modification times of the global header
+// and of the used descriptors of the SIF image found at path to the Unix
+// time t, the SIF library always records the current time.
+func setSIFTimes(path string, t int64) error {
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var h sif.Header
+	if err := readAt(f, 0, &h); err != nil {
+		return fmt.Errorf("while reading global header: %v", err)
+	}
+	h.Ctime, h.Mtime = t, t
+	if err := writeAt(f, 0, &h); err != nil {
+		return fmt.Errorf("while writing global header: %v", err)
+	}
+
+	var d sif.Descriptor
+	size := int64(binary.Size(d))
+	for i := int64(0); i < h.Dtotal; i++ {
+		off := h.Descroff + i*size
+		if err := readAt(f, off, &d); err != nil {
+			return fmt.Errorf("while reading descriptor: %v", err)
+		}
+		if !d.Used {
+			continue
+		}
+		d.Ctime, d.Mtime = t, t
+		if err := writeAt(f, off, &d); err != nil {
+			return fmt.Errorf("while writing descriptor: %v", err)
+		}
+	}
+
+	return f.Sync()
+}
+
+// readAt decodes the little endian structure v found at offset off of f.
+func readAt(f *os.File, off int64, v interface{}) error {
+	return binary.Read(io.NewSectionReader(f, off, int64(binary.Size(v))), binary.LittleEndian, v)
+}
+
+// writeAt encodes the structure v in little endian at offset off of f.
+func writeAt(f *os.File, off int64, v interface{}) error {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, v); err != nil {
+		return err
+	}
+	_, err := f.WriteAt(buf.Bytes(), off)
+	return err
+}
+
+// sha256File returns the hex encoded SHA-256 digest of the file found at path.
+func sha256File(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
 // changeOwner check the command being called with sudo with the environment
 // variable SUDO_COMMAND. Pattern match that for the singularity bin.
 func changeOwner() (int, int, bool) {
@@ -0,0 +1,68 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	uuid "github.com/satori/go.uuid"
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+func TestSetSIFTimes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "sif-times-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "image.sif")
+	def := []byte("Bootstrap: scratch\n")
+	cinfo := sif.CreateInfo{
+		Pathname:   path,
+		Launchstr:  sif.HdrLaunch,
+		Sifversion: sif.HdrVersion,
+		ID:         uuid.NewV4(),
+		InputDescr: []sif.DescriptorInput{
+			{
+				Datatype: sif.DataDeffile,
+				Groupid:  sif.DescrDefaultGroup,
+				Link:     sif.DescrUnusedLink,
+				Data:     def,
+				Size:     int64(len(def)),
+			},
+		},
+	}
+	if _, err := sif.CreateContainer(cinfo); err != nil {
+		t.Fatalf("failed to create SIF image: %v", err)
+	}
+
+	const epoch = 1234567890
+	if err := setSIFTimes(path, epoch); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		t.Fatalf("failed to load SIF image: %v", err)
+	}
+	defer f.UnloadContainer()
+
+	if f.Header.Ctime != epoch || f.Header.Mtime != epoch {
+		t.Errorf("got header times %d/%d, want %d", f.Header.Ctime, f.Header.Mtime, epoch)
+	}
+	for _, d := range f.DescrArr {
+		if !d.Used {
+			continue
+		}
+		if d.Ctime != epoch || d.Mtime != epoch {
+			t.Errorf("got descriptor %d times %d/%d, want %d", d.ID, d.Ctime, d.Mtime, epoch)
+		}
+	}
+}
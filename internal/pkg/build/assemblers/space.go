@@ -0,0 +1,177 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+// ErrNoSpace is wrapped by the errors returned when the image packaging
+// runs out of disk space.
+var ErrNoSpace = errors.New("no space left on device")
+
+// spaceHeadroom is the fraction of the root filesystem size added to
+// the space estimated for the image packaging.
+const spaceHeadroom = 0.1
+
+// NoSpaceError reports the filesystem which ran out of space while
+// writing a file during the image packaging.
+type NoSpaceError struct {
+	// Path is the file being written.
+	Path string
+	// Filesystem is the mount point of the filesystem holding Path.
+	Filesystem string
+	// Needed is the estimated size of the file and Available the
+	// space available on the filesystem, in bytes.
+	Needed    uint64
+	Available uint64
+}
+
+func (e *NoSpaceError) Error() string {
+	return fmt.Sprintf("no space left on %s while writing %s: about %d MiB needed, %d MiB available",
+		e.Filesystem, e.Path, toMiB(e.Needed), toMiB(e.Available))
+}
+
+// Unwrap returns ErrNoSpace.
+func (e *NoSpaceError) Unwrap() error {
+	return ErrNoSpace
+}
+
+func toMiB(n uint64) uint64 {
+	return (n + 1<<20 - 1) >> 20
+}
+
+// isNoSpace returns if err, or the output of the command which failed
+// with err, reports that a device is full.
+func isNoSpace(err error) bool {
+	if errors.Is(err, syscall.ENOSPC) {
+		return true
+	}
+	return strings.Contains(strings.ToLower(err.Error()), strings.ToLower(syscall.ENOSPC.Error()))
+}
+
+// noSpaceError returns the NoSpaceError for the file path of
+// estimated size needed, the original error is logged.
+func noSpaceError(err error, path string, needed uint64) error {
+	sylog.Debugf("Out of space while writing %s: %v", path, err)
+
+	dir := filepath.Dir(path)
+	available, _ := availableSpace(dir)
+	return &NoSpaceError{
+		Path:       path,
+		Filesystem: mountPoint(dir),
+		Needed:     needed,
+		Available:  available,
+	}
+}
+
+// availableSpace returns the space in bytes available to unprivileged
+// users on the filesystem holding dir.
+func availableSpace(dir string) (uint64, error) {
+	var st unix.Statfs_t
+	if err := unix.Statfs(dir, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Bavail) * uint64(st.Bsize), nil
+}
+
+// mountPoint returns the mount point of the filesystem holding path,
+// path itself is returned if it can't be determined.
+func mountPoint(path string) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	dev, err := device(path)
+	if err != nil {
+		return path
+	}
+	for {
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		if pdev, err := device(parent); err != nil || pdev != dev {
+			return path
+		}
+		path = parent
+	}
+}
+
+func device(path string) (uint64, error) {
+	var st unix.Stat_t
+	if err := unix.Stat(path, &st); err != nil {
+		return 0, err
+	}
+	return uint64(st.Dev), nil
+}
+
+// dirSize returns the cumulated size in bytes of the regular files found
+// in dir, hard links are counted once.
+func dirSize(dir string) (uint64, error) {
+	var size uint64
+	seen := make(map[uint64]bool)
+
+	err := filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && st.Nlink > 1 {
+			if seen[uint64(st.Ino)] {
+				return nil
+			}
+			seen[uint64(st.Ino)] = true
+		}
+		size += uint64(fi.Size())
+		return nil
+	})
+	return size, err
+}
+
+// checkSpace warns when the filesystems holding the squashfs image
+// created in tmpDir and the image created at path may not have enough
+// space for an image of the root filesystem of the given size.
+func checkSpace(tmpDir, path string, size uint64) {
+	needed := size + uint64(float64(size)*spaceHeadroom)
+
+	tmpDev, err := device(tmpDir)
+	if err != nil {
+		return
+	}
+	dstDir := filepath.Dir(path)
+	dstDev, err := device(dstDir)
+	if err != nil {
+		return
+	}
+
+	check := func(dir string, needed uint64) {
+		available, err := availableSpace(dir)
+		if err != nil || available >= needed {
+			return
+		}
+		sylog.Warningf("Image packaging may run out of space on %s: about %d MiB needed, %d MiB available",
+			mountPoint(dir), toMiB(needed), toMiB(available))
+	}
+
+	// the squashfs image is copied in the SIF image before being removed
+	if tmpDev == dstDev {
+		check(dstDir, 2*needed)
+		return
+	}
+	check(tmpDir, needed)
+	check(dstDir, needed)
+}
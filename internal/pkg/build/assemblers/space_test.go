@@ -0,0 +1,63 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestIsNoSpace(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "Errno",
+			err:  &os.PathError{Op: "write", Path: "/tmp/image.sif", Err: syscall.ENOSPC},
+			want: true,
+		},
+		{
+			name: "Mksquashfs",
+			err:  fmt.Errorf("create command failed: exit status 1: Write failed because No space left on device"),
+			want: true,
+		},
+		{
+			name: "Other",
+			err:  &os.PathError{Op: "write", Path: "/tmp/image.sif", Err: syscall.EIO},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isNoSpace(tt.err); got != tt.want {
+				t.Errorf("isNoSpace(%v) = %v, wanted %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoSpaceError(t *testing.T) {
+	err := fmt.Errorf("while creating SIF: %w", &NoSpaceError{
+		Path:       "/data/image.sif",
+		Filesystem: "/data",
+		Needed:     3 << 20,
+		Available:  1<<20 + 1,
+	})
+
+	if !errors.Is(err, ErrNoSpace) {
+		t.Errorf("%v doesn't wrap ErrNoSpace", err)
+	}
+	want := "while creating SIF: no space left on /data while writing /data/image.sif: about 3 MiB needed, 2 MiB available"
+	if err.Error() != want {
+		t.Errorf("unexpected error %q, wanted %q", err, want)
+	}
+}
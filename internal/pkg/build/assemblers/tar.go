@@ -0,0 +1,98 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/build/export"
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/namespaces"
+)
+
+// TarAssembler assembles a tar archive of the root filesystem.
+type TarAssembler struct {
+	// Gzip compresses the archive with gzip.
+	Gzip bool
+}
+
+// IsGzipTarPath returns if path has a gzip compressed tar archive
+// extension, .tar.gz or .tgz.
+func IsGzipTarPath(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// Assemble creates a tar archive from a Bundle.
+func (a *TarAssembler) Assemble(b *types.Bundle, path string) (err error) {
+	sylog.Infof("Creating tar archive...")
+
+	var size int64
+	progress.Packaging("tar", progress.StatusStarted, 0)
+	defer func() {
+		if err != nil {
+			progress.Packaging("tar", progress.StatusFailure, 0)
+		} else {
+			progress.Packaging("tar", progress.StatusSuccess, size)
+		}
+	}()
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return fmt.Errorf("while creating tar archive: %s", err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	var gz *gzip.Writer
+	if a.Gzip {
+		gz = gzip.NewWriter(f)
+		w = gz
+	}
+	if err := export.WriteTar(w, b.RootfsPath, rootOwner(b)); err != nil {
+		return fmt.Errorf("while writing tar archive: %s", err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	// path may be a temporary file already created with a 0600 mode
+	if err := os.Chmod(path, 0644); err != nil {
+		return fmt.Errorf("while changing tar archive mode: %s", err)
+	}
+	// chown the tar archive to the calling user
+	if uid, gid, ok := changeOwner(); ok {
+		if err := os.Chown(path, uid, gid); err != nil {
+			return fmt.Errorf("while changing tar archive ownership: %s", err)
+		}
+	}
+
+	if fi, err := os.Stat(path); err == nil {
+		size = fi.Size()
+	}
+	return nil
+}
+
+// rootOwner returns if the files of the tar and OCI images of b are
+// owned by root, either on --root-owner request or under --fakeroot,
+// where the root filesystem ownership is the one of the user namespace.
+func rootOwner(b *types.Bundle) bool {
+	if b.Opts.RootOwner {
+		return true
+	}
+	insideUserNs, _ := namespaces.IsInsideUserNamespace(os.Getpid())
+	return insideUserNs && os.Geteuid() == 0
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package assemblers
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestIsGzipTarPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{path: "/tmp/rootfs.tar", want: false},
+		{path: "/tmp/rootfs.tar.gz", want: true},
+		{path: "/tmp/rootfs.tgz", want: true},
+		{path: "/tmp/rootfs.gz", want: false},
+		{path: "rootfs", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := IsGzipTarPath(tt.path); got != tt.want {
+			t.Errorf("IsGzipTarPath(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTarAssemblerMode(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar-assembler-")
+	if err != nil {
+		t.Fatalf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootfs := filepath.Join(dir, "rootfs")
+	if err := os.MkdirAll(filepath.Join(rootfs, "etc"), 0755); err != nil {
+		t.Fatalf("while creating root filesystem: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "etc", "hostname"), []byte("test\n"), 0644); err != nil {
+		t.Fatalf("while creating root filesystem: %s", err)
+	}
+
+	// as assembleAtomic does, the archive is written to a temporary file
+	f, err := ioutil.TempFile(dir, "rootfs.tar.tmp-")
+	if err != nil {
+		t.Fatalf("while creating temporary file: %s", err)
+	}
+	f.Close()
+
+	b := &types.Bundle{RootfsPath: rootfs}
+	if err := (&TarAssembler{}).Assemble(b, f.Name()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	fi, err := os.Stat(f.Name())
+	if err != nil {
+		t.Fatalf("while getting archive information: %s", err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0644 {
+		t.Errorf("got archive mode %o, want 644", mode)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// assembleAtomic calls assemble with a temporary path in dir, the
+// directory of dest if empty, and moves the image to dest once assemble
+// succeeds, so a failed or interrupted build never leaves a partial image
+// at dest. An image at dest is kept until it is replaced.
+func assembleAtomic(dest, dir string, assemble func(path string) error) error {
+	if dir == "" {
+		dir = filepath.Dir(dest)
+	}
+
+	f, err := ioutil.TempFile(dir, "."+filepath.Base(dest)+".tmp-")
+	if err != nil {
+		return fmt.Errorf("while creating temporary image: %v", err)
+	}
+	f.Close()
+	tmp := f.Name()
+	// no-op once the image is moved
+	defer os.Remove(tmp)
+
+	if err := assemble(tmp); err != nil {
+		return err
+	}
+
+	sylog.Debugf("Moving image from %s to %s", tmp, dest)
+	if err := MoveImage(tmp, dest); err != nil {
+		return fmt.Errorf("while moving image to %s: %v", dest, err)
+	}
+	return nil
+}
+
+// MoveImage renames the image src to dest. If src and dest are not on the
+// same filesystem, src is copied to a temporary file of the dest directory,
+// synced and renamed to dest instead, keeping the ownership of src.
+func MoveImage(src, dest string) error {
+	// an existing sandbox is only overwritten with --force
+	if fi, err := os.Lstat(dest); err == nil && fi.IsDir() {
+		if err := os.RemoveAll(dest); err != nil {
+			return err
+		}
+	}
+
+	err := os.Rename(src, dest)
+	if !errors.Is(err, syscall.EXDEV) {
+		return err
+	}
+
+	sylog.Warningf("%s is not on the same filesystem as %s, copying the image instead of moving it", src, filepath.Dir(dest))
+	fi, err := os.Stat(src)
+	if err != nil {
+		return err
+	}
+	if err := fs.CopyFileAtomic(src, dest, fi.Mode()); err != nil {
+		return err
+	}
+	// keep the ownership set by the assembler
+	if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+		if err := os.Chown(dest, int(st.Uid), int(st.Gid)); err != nil {
+			return fmt.Errorf("while changing image ownership: %v", err)
+		}
+	}
+	return nil
+}
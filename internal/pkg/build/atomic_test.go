@@ -0,0 +1,132 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// killedBuildEnv is set to the destination of the build run by the
+// test binary itself in TestAssembleAtomicKilled.
+const killedBuildEnv = "SINGULARITY_TEST_KILLED_BUILD_DEST"
+
+func TestAssembleAtomic(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing bool
+		fail     bool
+		want     string
+	}{
+		{name: "New", want: "image"},
+		{name: "Overwrite", existing: true, want: "image"},
+		{name: "Failure", fail: true},
+		{name: "FailureKeepExisting", existing: true, fail: true, want: "previous"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "atomic-test-")
+			if err != nil {
+				t.Fatalf("while creating temporary directory: %s", err)
+			}
+			defer os.RemoveAll(dir)
+
+			dest := filepath.Join(dir, "image.sif")
+			if tt.existing {
+				if err := ioutil.WriteFile(dest, []byte("previous"), 0644); err != nil {
+					t.Fatalf("while writing image: %s", err)
+				}
+			}
+
+			err = assembleAtomic(dest, "", func(path string) error {
+				if filepath.Dir(path) != dir {
+					t.Errorf("temporary image %s not in the destination directory", path)
+				}
+				if err := ioutil.WriteFile(path, []byte("partial"), 0644); err != nil {
+					return err
+				}
+				if tt.fail {
+					return errors.New("assembler failure")
+				}
+				return ioutil.WriteFile(path, []byte("image"), 0644)
+			})
+			if tt.fail && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.fail && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+
+			b, err := ioutil.ReadFile(dest)
+			if tt.want == "" && !os.IsNotExist(err) {
+				t.Errorf("unexpected image at destination: %q, %v", b, err)
+			} else if tt.want != "" && string(b) != tt.want {
+				t.Errorf("unexpected image content %q, want %q: %v", b, tt.want, err)
+			}
+
+			entries, err := ioutil.ReadDir(dir)
+			if err != nil {
+				t.Fatalf("while reading directory: %s", err)
+			}
+			for _, e := range entries {
+				if e.Name() != "image.sif" {
+					t.Errorf("temporary image %s left behind", e.Name())
+				}
+			}
+		})
+	}
+}
+
+func TestAssembleAtomicKilled(t *testing.T) {
+	if dest := os.Getenv(killedBuildEnv); dest != "" {
+		// build run by the test, writes a partial image and waits
+		// to be killed
+		assembleAtomic(dest, "", func(path string) error {
+			if err := ioutil.WriteFile(path, []byte("partial"), 0644); err != nil {
+				return err
+			}
+			fmt.Println("assembling")
+			time.Sleep(time.Minute)
+			return nil
+		})
+		os.Exit(1)
+	}
+
+	dir, err := ioutil.TempDir("", "atomic-test-")
+	if err != nil {
+		t.Fatalf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+	dest := filepath.Join(dir, "image.sif")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestAssembleAtomicKilled$")
+	cmd.Env = append(os.Environ(), killedBuildEnv+"="+dest)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatalf("while creating pipe: %s", err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("while starting build: %s", err)
+	}
+
+	line, err := bufio.NewReader(stdout).ReadString('\n')
+	cmd.Process.Kill()
+	cmd.Wait()
+	if err != nil || line != "assembling\n" {
+		t.Fatalf("unexpected build output %q: %v", line, err)
+	}
+
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Errorf("image found at destination after the build was killed: %v", err)
+	}
+}
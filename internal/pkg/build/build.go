@@ -11,8 +11,12 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"os/signal"
 	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
 	"syscall"
 
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
@@ -22,7 +26,10 @@ import (
 	uuid "github.com/satori/go.uuid"
 	"github.com/sylabs/singularity/internal/pkg/build/apps"
 	"github.com/sylabs/singularity/internal/pkg/build/assemblers"
+	"github.com/sylabs/singularity/internal/pkg/build/export"
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
 	"github.com/sylabs/singularity/internal/pkg/build/sources"
+	"github.com/sylabs/singularity/internal/pkg/client"
 	"github.com/sylabs/singularity/internal/pkg/util/fs/squashfs"
 	"github.com/sylabs/singularity/internal/pkg/util/uri"
 	"github.com/sylabs/singularity/pkg/build/types"
@@ -32,6 +39,9 @@ import (
 	"github.com/sylabs/singularity/pkg/sylog"
 )
 
+// StdinSpec is the build spec reading the definition from the standard input.
+const StdinSpec = "-"
+
 // Build is an abstracted way to look at the entire build process.
 // For example calling NewBuild() will return this object.
 // From there we can call Full() on this build object, which will:
@@ -49,18 +59,25 @@ type Build struct {
 type Config struct {
 	// Dest is the location for container after build is complete.
 	Dest string
-	// Format is the format of built container, e.g. SIF, sandbox.
+	// Format is the format of built container: sif, sandbox, oci for an
+	// OCI image layout directory or tar for a root filesystem archive.
 	Format string
 	// NoCleanUp allows a user to prevent a bundle from being cleaned
-	// up after a failed build, useful for debugging.
+	// up after a build, useful for debugging failed builds.
 	NoCleanUp bool
+	// CleanUpOnSuccessOnly preserves the bundles of failed builds only,
+	// bundles are removed after a successful build.
+	CleanUpOnSuccessOnly bool
+	// TmpSandbox is the directory where a SIF image is written before
+	// being moved to Dest, the directory of Dest if empty.
+	TmpSandbox string
 	// Opts for bundles.
 	Opts types.Options
 }
 
 // NewBuild creates a new Build struct from a spec (URI, definition file, etc...).
 func NewBuild(spec string, conf Config) (*Build, error) {
-	def, err := makeDef(spec)
+	def, err := makeDef(spec, conf.Opts.BuildContext, conf.Opts.Arch, conf.Opts.BuildVars)
 	if err != nil {
 		return nil, fmt.Errorf("unable to parse spec %v: %v", spec, err)
 	}
@@ -89,6 +106,90 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 		conf.Format = "sandbox"
 	}
 
+	// squashfs compression only applies to SIF images and
+	// defaults to gzip for compatibility with older kernels
+	if conf.Format != "sif" || conf.Opts.FsType == FsTypeExt3 {
+		conf.Opts.Compression = ""
+		conf.Opts.CompressionLevel = 0
+	} else if conf.Opts.Compression == "" {
+		conf.Opts.Compression = "gzip"
+	}
+
+	// everything lost by OCI images and tar archives is reported
+	// by a single warning
+	exported := isExportFormat(conf.Format)
+	if exported && len(defs) > 0 {
+		if lost := exportLostSections(defs[len(defs)-1], conf.Opts); len(lost) > 0 {
+			err := conf.Opts.Warnf(types.WarnOCIOutput, "%s can't be represented in %s images, ignored or only kept in the image filesystem", strings.Join(lost, ", "), conf.Format)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if conf.Format != "sif" && len(conf.Opts.DefaultBinds) > 0 {
+		if !exported {
+			if err := conf.Opts.Warnf(types.WarnIgnoredDefaultBinds, "Default bind paths are only recorded in SIF images, ignoring them"); err != nil {
+				return nil, err
+			}
+		}
+		conf.Opts.DefaultBinds = nil
+	}
+
+	if conf.Format != "sif" && !exported && len(defs) > 0 && len(defs[len(defs)-1].BuildData.DataFiles) > 0 {
+		if err := conf.Opts.Warnf(types.WarnIgnoredDataFiles, "Data files are only stored in SIF images, ignoring %%datafile section"); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Format == "sif" && len(defs) > 0 {
+		if err := resolveDataFiles(defs[len(defs)-1].BuildData.DataFiles, conf.Opts); err != nil {
+			return nil, err
+		}
+	}
+	if conf.Opts.EncryptionKeyInfo != nil && (conf.Format == "oci" || conf.Format == "tar") {
+		return nil, fmt.Errorf("%s images can't be encrypted", conf.Format)
+	}
+
+	// kept source layers are stacked below the layered build overlay
+	if conf.Opts.KeepLayers {
+		conf.Opts.Layered = true
+	}
+
+	if conf.Opts.Layered {
+		if conf.Format != "sif" {
+			return nil, fmt.Errorf("layered builds are only supported for SIF images")
+		}
+		if conf.Opts.EncryptionKeyInfo != nil {
+			return nil, fmt.Errorf("layered builds can't be encrypted")
+		}
+		// flattened layers are identical to a regular build
+		if conf.Opts.SquashLayers {
+			conf.Opts.Layered = false
+			conf.Opts.KeepLayers = false
+		}
+	}
+
+	if err := checkPartitionOptions(conf); err != nil {
+		return nil, err
+	}
+
+	if _, err := resolvConf(conf.Opts.DNS); err != nil {
+		return nil, err
+	}
+
+	if conf.Opts.BaseDigest != "" {
+		if err := pinBaseDigest(defs, conf.Opts.BaseDigest); err != nil {
+			return nil, err
+		}
+	}
+
+	if conf.Opts.Arch != "" && conf.Opts.Arch != runtime.GOARCH {
+		if err := checkForeignArch(defs, conf.Opts, conf.Opts.Arch); err != nil {
+			return nil, err
+		}
+	}
+
 	b := &Build{
 		Conf: conf,
 	}
@@ -136,8 +237,11 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 			// provided
 			if s.b.RootfsPath != rootfs {
 				sandboxCopy = true
-				sylog.Warningf("The underlying filesystem on which resides %q won't allow to set ownership, "+
+				err := conf.Opts.Warnf(types.WarnOwnership, "The underlying filesystem on which resides %q won't allow to set ownership, "+
 					"as a consequence the sandbox could not preserve image's files/directories ownerships", conf.Dest)
+				if err != nil {
+					return nil, err
+				}
 			} else {
 				// check if the final sandbox directory doesn't have noexec set
 				destEntry, err := proc.FindParentMountEntry(rootfsParent, entries)
@@ -160,13 +264,36 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 			for _, opt := range tmpdirEntry.Options {
 				switch opt {
 				case "nodev":
-					sylog.Warningf("'nodev' mount option set on %s, it could be a source of failure during build process", tmpdirEntry.Point)
+					err := conf.Opts.Warnf(types.WarnNodevTmpDir, "'nodev' mount option set on %s, it could be a source of failure during build process", tmpdirEntry.Point)
+					if err != nil {
+						return nil, err
+					}
 				case "noexec":
 					return nil, fmt.Errorf("'noexec' mount option set on %s, temporary root filesystem won't be usable at this location", tmpdirEntry.Point)
 				}
 			}
 		}
 
+		if err := sources.CheckConveyorOptions(d); err != nil {
+			return nil, err
+		}
+		if conf.Opts.FromCacheOnly {
+			if err := sources.CheckFromCacheOnly(d); err != nil {
+				return nil, err
+			}
+			if err := checkNoURLTransfers(d); err != nil {
+				return nil, err
+			}
+		}
+		if conf.Opts.VerifyBase {
+			if err := sources.CheckVerifyBase(d); err != nil {
+				return nil, err
+			}
+		}
+		if err := checkDefinition(d, conf.Opts); err != nil {
+			return nil, err
+		}
+
 		s.b.Opts = conf.Opts
 		// dont need to get cp if we're skipping bootstrap
 		if !conf.Opts.Update || conf.Opts.Force {
@@ -177,6 +304,13 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 			}
 		}
 
+		// only the source layers of the last stage are kept
+		if lastStageIndex != i {
+			s.b.Opts.KeepLayers = false
+		} else if _, ok := s.c.(*sources.OCIConveyorPacker); conf.Opts.KeepLayers && !ok {
+			return nil, fmt.Errorf("keeping source layers requires a docker or OCI bootstrap source, not %s", d.Header["bootstrap"])
+		}
+
 		b.stages = append(b.stages, s)
 	}
 
@@ -185,15 +319,35 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 	case "sandbox":
 		b.stages[lastStageIndex].a = &assemblers.SandboxAssembler{Copy: sandboxCopy}
 	case "sif":
+		if conf.Opts.FsType == FsTypeExt3 {
+			mkfsPath, err := exec.LookPath("mkfs.ext3")
+			if err != nil {
+				return nil, fmt.Errorf("while searching for mkfs.ext3, required for ext3 partitions: %v", err)
+			}
+			b.stages[lastStageIndex].a = &assemblers.SIFAssembler{
+				Mkfsext3Path: mkfsPath,
+				FsSize:       conf.Opts.FsSize,
+				PartName:     conf.Opts.PartitionName,
+			}
+			break
+		}
+
 		mksquashfsPath, err := squashfs.GetPath()
 		if err != nil {
 			return nil, fmt.Errorf("while searching for mksquashfs: %v", err)
 		}
 
-		flag, err := ensureGzipComp(b.stages[lastStageIndex].b.TmpDir, mksquashfsPath)
+		compFlags, err := ensureComp(b.stages[lastStageIndex].b.TmpDir, mksquashfsPath, conf.Opts.Compression, conf.Opts.CompressionLevel)
 		if err != nil {
 			return nil, fmt.Errorf("while ensuring correct compression algorithm: %v", err)
 		}
+		// uncompressed squashfs images are supported by all kernels
+		if conf.Opts.Compression != "gzip" && conf.Opts.Compression != "none" {
+			err := conf.Opts.Warnf(types.WarnCompression, "Images compressed with %s can only be run on hosts with a kernel supporting %s compressed squashfs", conf.Opts.Compression, conf.Opts.Compression)
+			if err != nil {
+				return nil, err
+			}
+		}
 		mksquashfsProcs, err := squashfs.GetProcs()
 		if err != nil {
 			return nil, fmt.Errorf("while searching for mksquashfs processor limits: %v", err)
@@ -203,11 +357,16 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 			return nil, fmt.Errorf("while searching for mksquashfs mem limits: %v", err)
 		}
 		b.stages[lastStageIndex].a = &assemblers.SIFAssembler{
-			GzipFlag:        flag,
+			CompFlags:       compFlags,
 			MksquashfsProcs: mksquashfsProcs,
 			MksquashfsMem:   mksquashfsMem,
 			MksquashfsPath:  mksquashfsPath,
+			PartName:        conf.Opts.PartitionName,
 		}
+	case "oci":
+		b.stages[lastStageIndex].a = &assemblers.OCIAssembler{}
+	case "tar":
+		b.stages[lastStageIndex].a = &assemblers.TarAssembler{Gzip: assemblers.IsGzipTarPath(conf.Dest)}
 	default:
 		return nil, fmt.Errorf("unrecognized output format %s", conf.Format)
 	}
@@ -215,40 +374,144 @@ func newBuild(defs []types.Definition, conf Config) (*Build, error) {
 	return b, nil
 }
 
-// ensureGzipComp builds dummy squashfs images and checks the type of compression used
-// to deduce if we can successfully build with gzip compression. It returns an error
-// if we cannot and a boolean to indicate if the `-comp` flag is needed to specify
-// gzip compression when the final squashfs is built
-func ensureGzipComp(tmpdir, mksquashfsPath string) (bool, error) {
-	sylog.Debugf("Ensuring gzip compression for mksquashfs")
+// resolveDataFiles resolves the sources of the %datafile entries
+// dataFiles in place from the build context or the definition directory,
+// and checks they are regular files before anything is built.
+func resolveDataFiles(dataFiles []types.DataFile, opts types.Options) error {
+	for i, df := range dataFiles {
+		path, err := hostFilePath(opts, df.Src)
+		if err != nil {
+			return fmt.Errorf("while resolving data file %s: %s", df.Name, err)
+		}
+		fi, err := os.Stat(path)
+		if err != nil {
+			return fmt.Errorf("while resolving data file %s: %s", df.Name, err)
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("data file %s is not a regular file", path)
+		}
+		dataFiles[i].Src = path
+	}
+	return nil
+}
+
+// isExportFormat returns if format is an image format converted from the
+// root filesystem without the Singularity metadata, OCI or tar.
+func isExportFormat(format string) bool {
+	return format == "oci" || format == "tar"
+}
+
+// exportLostSections returns the sections of the definition def and the
+// build options which can't be represented in OCI images and tar
+// archives. The startscript and SCIF apps, which may come from the source
+// image, are reported from the root filesystem by checkExportRootfs.
+func exportLostSections(def types.Definition, opts types.Options) []string {
+	var lost []string
+	if def.ImageData.Help.Script != "" {
+		lost = append(lost, "%help")
+	}
+	if def.ImageData.Test.Script != "" {
+		lost = append(lost, "%test")
+	}
+	if len(def.ImageData.Actions) > 0 {
+		lost = append(lost, "%actions")
+	}
+	if len(def.BuildData.DataFiles) > 0 {
+		lost = append(lost, "%datafile")
+	}
+	if len(opts.DefaultBinds) > 0 {
+		lost = append(lost, "--default-bind")
+	}
+	return lost
+}
+
+// checkExportRootfs raises a build warning when the root filesystem rootfs
+// converted to an OCI image or a tar archive holds a startscript or SCIF
+// apps, which are only kept in the image filesystem.
+func checkExportRootfs(opts types.Options, format, rootfs string) error {
+	if lost := export.LostContent(rootfs); len(lost) > 0 {
+		return opts.Warnf(types.WarnOCIOutput, "%s can't be represented in %s images, only kept in the image filesystem", strings.Join(lost, ", "), format)
+	}
+	return nil
+}
+
+// compressionLevels holds the range of levels accepted by mksquashfs
+// -Xcompression-level for each algorithm supporting it.
+var compressionLevels = map[string][2]int{
+	"gzip": {1, 9},
+	"lzo":  {1, 9},
+	"zstd": {1, 22},
+}
+
+// compressionFlags returns the mksquashfs flags required to select the
+// given compression algorithm and level, without checking mksquashfs
+// support for it.
+func compressionFlags(comp string, level int) ([]string, error) {
+	var flags []string
+
+	switch comp {
+	case "none":
+		flags = []string{"-noI", "-noD", "-noF", "-noX"}
+	case "gzip", "lzo", "xz", "zstd":
+		flags = []string{"-comp", comp}
+	default:
+		return nil, fmt.Errorf("unsupported compression algorithm %q (supported: gzip, lzo, xz, zstd, none)", comp)
+	}
+
+	if level != 0 {
+		r, ok := compressionLevels[comp]
+		if !ok {
+			return nil, fmt.Errorf("compression level is not supported with %s compression", comp)
+		}
+		if level < r[0] || level > r[1] {
+			return nil, fmt.Errorf("%s compression level must be between %d and %d", comp, r[0], r[1])
+		}
+		flags = append(flags, "-Xcompression-level", strconv.Itoa(level))
+	}
+
+	return flags, nil
+}
+
+// ensureComp builds dummy squashfs images and checks the type of compression used
+// to deduce if we can successfully build with the requested compression algorithm
+// and level. It returns an error if we cannot, and the mksquashfs flags which are
+// needed to select the compression when the final squashfs is built
+func ensureComp(tmpdir, mksquashfsPath, comp string, level int) ([]string, error) {
+	sylog.Debugf("Ensuring %s compression for mksquashfs", comp)
+
+	compFlags, err := compressionFlags(comp, level)
+	if err != nil {
+		return nil, err
+	}
 
-	var err error
 	s := packer.NewSquashfs()
 	s.MksquashfsPath = mksquashfsPath
 
-	srcf, err := ioutil.TempFile(tmpdir, "squashfs-gzip-comp-test-src")
+	srcf, err := ioutil.TempFile(tmpdir, "squashfs-comp-test-src")
 	if err != nil {
-		return false, fmt.Errorf("while creating temporary file for squashfs source: %v", err)
+		return nil, fmt.Errorf("while creating temporary file for squashfs source: %v", err)
 	}
+	defer os.Remove(srcf.Name())
 
 	srcf.Write([]byte("Test File Content"))
 	srcf.Close()
 
-	f, err := ioutil.TempFile(tmpdir, "squashfs-gzip-comp-test-")
+	f, err := ioutil.TempFile(tmpdir, "squashfs-comp-test-")
 	if err != nil {
-		return false, fmt.Errorf("while creating temporary file for squashfs: %v", err)
+		return nil, fmt.Errorf("while creating temporary file for squashfs: %v", err)
 	}
 	f.Close()
+	defer os.Remove(f.Name())
 
 	flags := []string{"-noappend"}
 
 	mksquashfsProcs, err := squashfs.GetProcs()
 	if err != nil {
-		return false, fmt.Errorf("while searching for mksquashfs processor limits: %v", err)
+		return nil, fmt.Errorf("while searching for mksquashfs processor limits: %v", err)
 	}
 	mksquashfsMem, err := squashfs.GetMem()
 	if err != nil {
-		return false, fmt.Errorf("while searching for mksquashfs mem limits: %v", err)
+		return nil, fmt.Errorf("while searching for mksquashfs mem limits: %v", err)
 	}
 	if mksquashfsMem != "" {
 		flags = append(flags, "-mem", mksquashfsMem)
@@ -257,58 +520,87 @@ func ensureGzipComp(tmpdir, mksquashfsPath string) (bool, error) {
 		flags = append(flags, "-processors", fmt.Sprint(mksquashfsProcs))
 	}
 
-	if err := s.Create([]string{srcf.Name()}, f.Name(), flags); err != nil {
-		return false, fmt.Errorf("while creating squashfs: %v", err)
-	}
+	// gzip is the mksquashfs default compression, check first if
+	// we get it without specifying the -comp flag
+	if comp == "gzip" && level == 0 {
+		if err := s.Create([]string{srcf.Name()}, f.Name(), flags); err != nil {
+			return nil, fmt.Errorf("while creating squashfs: %v", err)
+		}
 
-	content, err := ioutil.ReadFile(f.Name())
-	if err != nil {
-		return false, fmt.Errorf("while reading test squashfs: %v", err)
-	}
+		content, err := ioutil.ReadFile(f.Name())
+		if err != nil {
+			return nil, fmt.Errorf("while reading test squashfs: %v", err)
+		}
 
-	comp, err := image.GetSquashfsComp(content)
-	if err != nil {
-		return false, fmt.Errorf("could not verify squashfs compression type: %v", err)
-	}
+		c, err := image.GetSquashfsComp(content)
+		if err != nil {
+			return nil, fmt.Errorf("could not verify squashfs compression type: %v", err)
+		}
 
-	if comp == "gzip" {
-		sylog.Debugf("Gzip compression by default ensured")
-		return false, nil
+		if c == "gzip" {
+			sylog.Debugf("Gzip compression by default ensured")
+			return nil, nil
+		}
 	}
 
-	// Now force add `-comp gzip` in addition to -noappend -mem -processors
-	flags = append(flags, "-comp", "gzip")
+	// Now force add compression flags in addition to -noappend -mem -processors
+	flags = append(flags, compFlags...)
 
 	if err := s.Create([]string{srcf.Name()}, f.Name(), flags); err != nil {
-		return false, fmt.Errorf("could not build squashfs with required gzip compression")
+		return nil, fmt.Errorf("mksquashfs at %s does not support the requested %s compression: %v", mksquashfsPath, comp, err)
+	}
+
+	// uncompressed images still report the default compressor in the
+	// super block, there is nothing more to verify
+	if comp == "none" {
+		return compFlags, nil
 	}
 
-	content, err = ioutil.ReadFile(f.Name())
+	content, err := ioutil.ReadFile(f.Name())
 	if err != nil {
-		return false, fmt.Errorf("while reading test squashfs: %v", err)
+		return nil, fmt.Errorf("while reading test squashfs: %v", err)
 	}
 
-	comp, err = image.GetSquashfsComp(content)
+	c, err := image.GetSquashfsComp(content)
 	if err != nil {
-		return false, fmt.Errorf("could not verify squashfs compression type: %v", err)
+		return nil, fmt.Errorf("could not verify squashfs compression type: %v", err)
 	}
 
-	if comp == "gzip" {
-		sylog.Debugf("Gzip compression with -comp flag ensured")
-		return true, nil
+	if c == comp {
+		sylog.Debugf("%s compression with -comp flag ensured", comp)
+		return compFlags, nil
 	}
 
-	return false, fmt.Errorf("could not build squashfs with required gzip compression")
+	return nil, fmt.Errorf("mksquashfs at %s could not build squashfs with requested %s compression", mksquashfsPath, comp)
 }
 
-// cleanUp removes remnants of build from file system unless NoCleanUp is specified.
-func (b Build) cleanUp() {
-	if b.Conf.NoCleanUp {
-		var bundlePaths []string
+// cleanUp removes remnants of build from file system unless NoCleanUp is specified,
+// or the build failed with CleanUpOnSuccessOnly, in which case bundle(s) are preserved
+// and their location is displayed. Mount points left under bundle(s) are unmounted in
+// all cases.
+func (b Build) cleanUp(failed bool) {
+	b.unmountBundles()
+
+	if b.Conf.NoCleanUp || (failed && b.Conf.CleanUpOnSuccessOnly) {
+		if failed && !b.Conf.NoCleanUp {
+			sylog.Infof("Build failed with clean up on success only option, preserving build bundle(s)")
+		} else if failed {
+			sylog.Infof("Build failed with no clean up option, preserving build bundle(s)")
+		} else {
+			sylog.Infof("Build performed with no clean up option, preserving build bundle(s)")
+		}
 		for _, s := range b.stages {
-			bundlePaths = append(bundlePaths, s.b.RootfsPath, s.b.TmpDir)
+			name := s.name
+			if name == "" {
+				name = "default"
+			}
+			sylog.Infof("Stage %s root filesystem located at: %s", name, s.b.RootfsPath)
+			sylog.Infof("Stage %s temporary files located at: %s", name, s.b.TmpDir)
+		}
+		if len(b.stages) > 0 {
+			rootfs := b.stages[len(b.stages)-1].b.RootfsPath
+			sylog.Infof("Use 'singularity shell --writable %s' to inspect it", rootfs)
 		}
-		sylog.Infof("Build performed with no clean up option, build bundle(s) located at: %v", bundlePaths)
 		return
 	}
 
@@ -321,6 +613,36 @@ func (b Build) cleanUp() {
 	}
 }
 
+// unmountBundles unmounts any mount point remaining under bundle(s)
+// directories, this is a lazy unmount to not fail on busy mount points.
+func (b Build) unmountBundles() {
+	entries, err := proc.GetMountInfoEntry("/proc/self/mountinfo")
+	if err != nil {
+		sylog.Warningf("Could not read mount points: %v", err)
+		return
+	}
+
+	var dirs []string
+	for _, s := range b.stages {
+		dirs = append(dirs, s.b.RootfsPath, s.b.TmpDir)
+	}
+
+	// unmount in reverse order to unmount nested mount points first
+	for i := len(entries) - 1; i >= 0; i-- {
+		point := entries[i].Point
+		for _, dir := range dirs {
+			if dir == "" || (point != dir && !strings.HasPrefix(point, dir+"/")) {
+				continue
+			}
+			sylog.Debugf("Unmounting %s", point)
+			if err := syscall.Unmount(point, syscall.MNT_DETACH); err != nil {
+				sylog.Warningf("Could not unmount %s: %v", point, err)
+			}
+			break
+		}
+	}
+}
+
 // Full runs a standard build from start to finish.
 func (b *Build) Full(ctx context.Context) error {
 	sylog.Infof("Starting build...")
@@ -330,12 +652,23 @@ func (b *Build) Full(ctx context.Context) error {
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
 	go func() {
 		<-c
-		b.cleanUp()
+		b.cleanUp(true)
 		os.Exit(1)
 	}()
-	// clean up build normally
-	defer b.cleanUp()
 
+	ctx = client.WithDownloadLimits(ctx, client.DownloadLimits{
+		MaxSize: b.Conf.Opts.MaxDownloadSize,
+		Timeout: b.Conf.Opts.DownloadTimeout,
+	})
+
+	err := b.full(ctx)
+	b.cleanUp(err != nil)
+	return err
+}
+
+// full executes the build sections of each stage and assembles
+// the container image from the last stage.
+func (b *Build) full(ctx context.Context) error {
 	oldumask := syscall.Umask(0002)
 
 	// generate the default configuration
@@ -346,7 +679,12 @@ func (b *Build) Full(ctx context.Context) error {
 	config.BindPath = nil
 	config.ConfigResolvConf = false
 	config.MountHome = false
-	config.MountDevPts = false
+	config.MountProc = !b.Conf.Opts.NoMountProc
+	config.MountSys = !b.Conf.Opts.NoMountSys
+	config.MountDevPts = b.Conf.Opts.MountDevPts
+	if b.Conf.Opts.MountDev != "" {
+		config.MountDev = b.Conf.Opts.MountDev
+	}
 
 	var buffer bytes.Buffer
 
@@ -390,6 +728,14 @@ func (b *Build) Full(ctx context.Context) error {
 			}
 		}
 
+		// the bootstrapped root filesystem of the last stage is the
+		// immutable base layer of layered builds
+		if stage.b.Opts.Layered && i == len(b.stages)-1 {
+			if err := stage.createBaseLayer(); err != nil {
+				return fmt.Errorf("while creating base layer: %w", err)
+			}
+		}
+
 		// create apps in bundle
 		a := apps.New()
 		for k, v := range stage.b.Recipe.CustomData {
@@ -401,11 +747,20 @@ func (b *Build) Full(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("unable to get app post information: %v", err)
 		}
-		stage.b.Recipe.BuildData.Post.Script += appPost
+		// app installs run after the last %post:NAME fragment, as
+		// part of it, so that they are cached along with it
+		if fragments := stage.b.Recipe.BuildData.PostFragments; len(fragments) > 0 {
+			fragments[len(fragments)-1].Script.Script += appPost
+		} else {
+			stage.b.Recipe.BuildData.Post.Script += appPost
+		}
 
 		// copy potential files from previous stage
-		if stage.b.RunSection("files") {
-			if err := stage.copyFilesFrom(b); err != nil {
+		if stage.b.RunSection("files") && stage.hasFiles(true) {
+			err := progress.Section(stage.name, "files", func() error {
+				return stage.copyFilesFrom(b)
+			})
+			if err != nil {
 				return fmt.Errorf("unable to copy files from stage to container fs: %v", err)
 			}
 		}
@@ -415,53 +770,74 @@ func (b *Build) Full(ctx context.Context) error {
 		}
 
 		// copy files from host
-		if stage.b.RunSection("files") {
-			if err := stage.copyFiles(); err != nil {
+		if stage.b.RunSection("files") && stage.hasFiles(false) {
+			err := progress.Section(stage.name, "files", func() error {
+				return stage.copyFiles(ctx)
+			})
+			if err != nil {
 				return fmt.Errorf("unable to copy files from host to container fs: %v", err)
 			}
 		}
 
-		// create stage file for /etc/resolv.conf and /etc/hosts
-		sessionResolv, err := createStageFile("/etc/resolv.conf", stage.b, "Name resolution could fail")
-		if err != nil {
+		if err := stage.runScripts(configData); err != nil {
 			return err
-		} else if sessionResolv != "" {
-			defer os.Remove(sessionResolv)
-		}
-		sessionHosts, err := createStageFile("/etc/hosts", stage.b, "Host resolution could fail")
-		if err != nil {
-			return err
-		} else if sessionHosts != "" {
-			defer os.Remove(sessionHosts)
 		}
+	}
+
+	syscall.Umask(oldumask)
 
-		// write the build configuration used for %post and %test sections
-		configFile := filepath.Join(stage.b.TmpDir, "singularity.conf")
-		if err := ioutil.WriteFile(configFile, configData, 0644); err != nil {
-			return fmt.Errorf("while creating %s: %s", configFile, err)
+	if specs := b.Conf.Opts.FileCaps; len(specs) > 0 {
+		if err := setFileCaps(b.stages[len(b.stages)-1].b.RootfsPath, specs); err != nil {
+			return fmt.Errorf("while setting file capabilities: %v", err)
 		}
-		defer os.Remove(configFile)
+	}
 
-		if stage.b.Recipe.BuildData.Post.Script != "" {
-			if err := stage.runPostScript(configFile, sessionResolv, sessionHosts); err != nil {
-				return fmt.Errorf("while running engine: %v", err)
-			}
+	if patterns := b.Conf.Opts.ExcludePaths; len(patterns) > 0 {
+		reclaimed, err := excludePaths(b.stages[len(b.stages)-1].b.RootfsPath, patterns)
+		if err != nil {
+			return fmt.Errorf("while excluding paths: %v", err)
 		}
+		sylog.Infof("Excluded paths: %d bytes reclaimed", reclaimed)
+	}
 
-		sylog.Debugf("Inserting Metadata")
-		if err := stage.insertMetadata(); err != nil {
-			return fmt.Errorf("while inserting metadata to bundle: %v", err)
+	if !b.Conf.Opts.NoRunscriptCheck {
+		if err := checkRunscript(b.Conf.Opts, b.stages[len(b.stages)-1].b.RootfsPath); err != nil {
+			return err
 		}
+	}
 
-		if err := stage.runTestScript(configFile, sessionResolv, sessionHosts); err != nil {
-			return fmt.Errorf("failed to execute %%test script: %v", err)
+	if b.Conf.Opts.ScanSecrets {
+		if err := scanSecrets(b.stages[len(b.stages)-1].b); err != nil {
+			return err
 		}
 	}
 
-	syscall.Umask(oldumask)
+	if stage := b.stages[len(b.stages)-1]; stage.base != nil {
+		if err := stage.createOverlayLayer(); err != nil {
+			return fmt.Errorf("while creating overlay layer: %v", err)
+		}
+	}
 
 	sylog.Debugf("Calling assembler")
-	if err := b.stages[len(b.stages)-1].Assemble(b.Conf.Dest); err != nil {
+	last := b.stages[len(b.stages)-1]
+	if isExportFormat(b.Conf.Format) {
+		if err := checkExportRootfs(b.Conf.Opts, b.Conf.Format, last.b.RootfsPath); err != nil {
+			return err
+		}
+	}
+	assemble := func(path string) error {
+		if err := last.Assemble(path); err != nil {
+			return err
+		}
+		return checkImageSize(b.Conf.Opts, b.Conf.Format, path, last.b.RootfsPath)
+	}
+	// directories are not assembled atomically
+	if b.Conf.Format == "sandbox" || b.Conf.Format == "oci" {
+		err = assemble(b.Conf.Dest)
+	} else {
+		err = assembleAtomic(b.Conf.Dest, b.Conf.TmpSandbox, assemble)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -469,8 +845,18 @@ func (b *Build) Full(ctx context.Context) error {
 	return nil
 }
 
-// makeDef gets a definition object from a spec.
-func makeDef(spec string) (types.Definition, error) {
+// makeDef gets a definition object from a spec, relative includes are
+// resolved from contextDir if set and conditional sections for the
+// architecture arch and the build variables vars.
+func makeDef(spec, contextDir, arch string, vars map[string]string) (types.Definition, error) {
+	if strings.HasPrefix(spec, parser.DockerfilePrefix) {
+		defs, err := dockerfileDefs(strings.TrimPrefix(spec, parser.DockerfilePrefix), contextDir)
+		if err != nil {
+			return types.Definition{}, err
+		}
+		return defs[len(defs)-1], nil
+	}
+
 	if ok, err := uri.IsValid(spec); ok && err == nil {
 		// URI passed as spec
 		return types.NewDefinitionFromURI(spec)
@@ -482,13 +868,16 @@ func makeDef(spec string) (types.Definition, error) {
 	}
 
 	// default to reading file as definition
-	defFile, err := os.Open(spec)
+	raw, err := parser.ExpandIncludesContext(spec, contextDir)
 	if err != nil {
-		return types.Definition{}, fmt.Errorf("unable to open file %s: %v", spec, err)
+		return types.Definition{}, fmt.Errorf("unable to read definition %s: %v", spec, err)
+	}
+	raw, err = parser.ResolveConditions(raw, arch, vars)
+	if err != nil {
+		return types.Definition{}, fmt.Errorf("while resolving conditional sections of %s: %v", spec, err)
 	}
-	defer defFile.Close()
 
-	d, err := parser.ParseDefinitionFile(defFile)
+	d, err := parser.ParseDefinitionFile(bytes.NewReader(raw))
 	if err != nil {
 		return types.Definition{}, fmt.Errorf("while parsing definition: %s: %v", spec, err)
 	}
@@ -496,8 +885,40 @@ func makeDef(spec string) (types.Definition, error) {
 	return d, nil
 }
 
-// MakeAllDefs gets a definition object from a spec
-func MakeAllDefs(spec string) ([]types.Definition, error) {
+// MakeAllDefs gets a definition object from a spec, the definition
+// is read from the standard input if spec is StdinSpec. Relative
+// includes are resolved from contextDir if set and conditional sections
+// for the architecture arch, the host one if empty, and the build
+// variables vars.
+func MakeAllDefs(spec, contextDir, arch string, vars map[string]string) ([]types.Definition, error) {
+	if spec == StdinSpec {
+		// included fragments are resolved from the current directory
+		// without a build context
+		var raw []byte
+		var err error
+		if contextDir == "" {
+			raw, err = parser.ExpandIncludesReader(os.Stdin, ".")
+		} else {
+			raw, err = parser.ExpandIncludesReaderContext(os.Stdin, contextDir)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("while reading definition from standard input: %v", err)
+		}
+		raw, err = parser.ResolveConditions(raw, arch, vars)
+		if err != nil {
+			return nil, fmt.Errorf("while resolving conditional sections from standard input: %v", err)
+		}
+		d, err := parser.All(bytes.NewReader(raw))
+		if err != nil {
+			return nil, fmt.Errorf("while parsing definition from standard input: %v", err)
+		}
+		return d, nil
+	}
+
+	if strings.HasPrefix(spec, parser.DockerfilePrefix) {
+		return dockerfileDefs(strings.TrimPrefix(spec, parser.DockerfilePrefix), contextDir)
+	}
+
 	if ok, err := uri.IsValid(spec); ok && err == nil {
 		// URI passed as spec
 		d, err := types.NewDefinitionFromURI(spec)
@@ -512,13 +933,16 @@ func MakeAllDefs(spec string) ([]types.Definition, error) {
 	}
 
 	// default to reading file as definition
-	defFile, err := os.Open(spec)
+	raw, err := parser.ExpandIncludesContext(spec, contextDir)
 	if err != nil {
-		return nil, fmt.Errorf("unable to open file %s: %v", spec, err)
+		return nil, fmt.Errorf("unable to read definition %s: %v", spec, err)
+	}
+	raw, err = parser.ResolveConditions(raw, arch, vars)
+	if err != nil {
+		return nil, fmt.Errorf("while resolving conditional sections of %s: %v", spec, err)
 	}
-	defer defFile.Close()
 
-	d, err := parser.All(defFile)
+	d, err := parser.All(bytes.NewReader(raw))
 	if err != nil {
 		return nil, fmt.Errorf("while parsing definition: %s: %v", spec, err)
 	}
@@ -526,6 +950,110 @@ func MakeAllDefs(spec string) ([]types.Definition, error) {
 	return d, nil
 }
 
+// dockerfileDefs returns the definitions converted from the Dockerfile
+// found at path, the build context is contextDir if set or the directory
+// holding the Dockerfile.
+func dockerfileDefs(path, contextDir string) ([]types.Definition, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Dockerfile %s: %v", path, err)
+	}
+	defer f.Close()
+
+	if contextDir == "" {
+		contextDir = filepath.Dir(path)
+	}
+	contextDir, err = filepath.Abs(contextDir)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := parser.ConvertDockerfile(f, contextDir)
+	if err != nil {
+		return nil, fmt.Errorf("while converting Dockerfile %s: %v", path, err)
+	}
+	sylog.Debugf("Definition converted from Dockerfile %s:\n%s", path, raw)
+
+	d, err := parser.All(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("while parsing definition converted from Dockerfile %s: %v", path, err)
+	}
+	return d, nil
+}
+
+// Validate checks the definitions of a build without building anything,
+// it reports the definition errors and warnings a build would report
+// before bootstrapping.
+func Validate(defs []types.Definition, opts types.Options) error {
+	var stages []string
+
+	for _, d := range defs {
+		if d.Header == nil {
+			return fmt.Errorf("multiple stages detected, all must have headers")
+		}
+		if err := sources.CheckConveyorOptions(d); err != nil {
+			return err
+		}
+		if err := checkDefinition(d, opts); err != nil {
+			return err
+		}
+		if _, err := conveyorPacker(d); err != nil {
+			return fmt.Errorf("unable to get conveyorpacker: %s", err)
+		}
+
+		// files can only be copied from a previous stage
+		for _, f := range d.BuildData.Files {
+			if err := checkURLTransfers(f); err != nil {
+				return err
+			}
+			args := strings.Fields(f.Args)
+			if len(args) != 2 {
+				continue
+			}
+			found := false
+			for _, name := range stages {
+				if name == args[1] {
+					found = true
+					break
+				}
+			}
+			if !found {
+				return fmt.Errorf("stage %s was not found", args[1])
+			}
+		}
+		stages = append(stages, d.Header["stage"])
+	}
+
+	return nil
+}
+
+// ImageDigest returns the hex encoded SHA-256 digest and the UUID of the
+// SIF image created by the build, ok is false when the build didn't
+// assemble a SIF image.
+func (b *Build) ImageDigest() (sha256, id string, ok bool) {
+	a, ok := b.stages[len(b.stages)-1].a.(*assemblers.SIFAssembler)
+	if !ok || a.SHA256 == "" {
+		return "", "", false
+	}
+	return a.SHA256, a.UUID, true
+}
+
+// SourceRefs returns the fully resolved references of the library and
+// docker bootstrap images of the build stages, in the stage order.
+func (b *Build) SourceRefs() []string {
+	var refs []string
+	for _, s := range b.stages {
+		switch s.b.Recipe.Header["bootstrap"] {
+		case "library":
+			refs = append(refs, sources.LibrarySourceRef(s.b))
+		case "docker":
+			if ref, _, err := sources.DockerSourceRef(s.b.Recipe); err == nil {
+				refs = append(refs, "docker://"+ref)
+			}
+		}
+	}
+	return refs
+}
+
 func (b *Build) findStageIndex(name string) (int, error) {
 	for i, s := range b.stages {
 		if name == s.name {
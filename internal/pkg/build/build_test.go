@@ -0,0 +1,134 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/image"
+)
+
+func TestValidate(t *testing.T) {
+	filesFrom := func(stage string) types.Definition {
+		d := types.Definition{Header: map[string]string{"bootstrap": "docker", "from": "alpine"}}
+		d.BuildData.Files = []types.Files{
+			{Args: "from " + stage, Files: []types.FileTransport{{Src: "/a", Dst: "/a"}}},
+		}
+		return d
+	}
+	stage := func(name string) types.Definition {
+		return types.Definition{Header: map[string]string{"bootstrap": "docker", "from": "alpine", "stage": name}}
+	}
+
+	tests := []struct {
+		name    string
+		defs    []types.Definition
+		wantErr bool
+	}{
+		{
+			name: "SingleStage",
+			defs: []types.Definition{stage("")},
+		},
+		{
+			name: "MultiStage",
+			defs: []types.Definition{stage("one"), filesFrom("one")},
+		},
+		{
+			name:    "MissingHeader",
+			defs:    []types.Definition{stage("one"), {}},
+			wantErr: true,
+		},
+		{
+			name:    "UnknownBootstrap",
+			defs:    []types.Definition{{Header: map[string]string{"bootstrap": "unknown"}}},
+			wantErr: true,
+		},
+		{
+			name:    "UnknownStage",
+			defs:    []types.Definition{stage("one"), filesFrom("two")},
+			wantErr: true,
+		},
+		{
+			name:    "LaterStage",
+			defs:    []types.Definition{filesFrom("two"), stage("two")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.defs, types.Options{})
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestExportLostSections(t *testing.T) {
+	var def types.Definition
+	if lost := exportLostSections(def, types.Options{}); len(lost) > 0 {
+		t.Errorf("unexpected lost sections %s", strings.Join(lost, ", "))
+	}
+
+	def.ImageData.Help.Script = "help"
+	def.ImageData.Test.Script = "true"
+	def.ImageData.Actions = []types.Action{{}}
+	def.BuildData.DataFiles = []types.DataFile{{Name: "data", Src: "data.txt"}}
+	opts := types.Options{DefaultBinds: []image.DefaultBind{{}}}
+
+	want := []string{"%help", "%test", "%actions", "%datafile", "--default-bind"}
+	if lost := exportLostSections(def, opts); !reflect.DeepEqual(lost, want) {
+		t.Errorf("got lost sections %v, want %v", lost, want)
+	}
+}
+
+func TestResolveDataFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "datafiles-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	model := filepath.Join(dir, "model.bin")
+	if err := ioutil.WriteFile(model, []byte("model"), 0644); err != nil {
+		t.Fatalf("failed to write data file: %s", err)
+	}
+
+	dataFiles := []types.DataFile{{Name: "model", Src: "model.bin"}}
+	if err := resolveDataFiles(dataFiles, types.Options{DefinitionDir: dir}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if dataFiles[0].Src != model {
+		t.Errorf("got data file source %s, want %s", dataFiles[0].Src, model)
+	}
+
+	tests := []struct {
+		name string
+		src  string
+		opts types.Options
+	}{
+		{name: "NoDirectory", src: "model.bin"},
+		{name: "Missing", src: "missing.bin", opts: types.Options{BuildContext: dir}},
+		{name: "Directory", src: dir},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dataFiles := []types.DataFile{{Name: "data", Src: tt.src}}
+			if err := resolveDataFiles(dataFiles, tt.opts); err == nil {
+				t.Errorf("unexpected success")
+			}
+		})
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bufio"
+	"regexp"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/build/sources"
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+// exitRegexp matches an exit command, with an optional status
+// and comment, written at the beginning of a line.
+var exitRegexp = regexp.MustCompile(`^exit(\s+\S+)?\s*(#.*)?$`)
+
+// checkDefinition reports the build warnings raised by the
+// content of the definition d.
+func checkDefinition(d types.Definition, opts types.Options) error {
+	if strings.TrimSpace(d.BuildData.Pre.Script) != "" {
+		err := opts.Warnf(types.WarnDeprecatedSection, "%%pre section is deprecated, use %%setup instead")
+		if err != nil {
+			return err
+		}
+	}
+
+	if line := unreachableLine(d.BuildData.Post.Script); line > 0 {
+		err := opts.Warnf(types.WarnUnreachablePost, "%%post commands starting at line %d are never executed, they follow an unconditional exit", line)
+		if err != nil {
+			return err
+		}
+	}
+	// a reference without registry or tag silently pulls from
+	// docker.io or the latest tag, the resolved one is reported
+	if d.Header["bootstrap"] == "docker" {
+		if ref, implicit, err := sources.DockerSourceRef(d); err == nil && len(implicit) > 0 {
+			err := opts.Warnf(types.WarnImplicitDockerDefaults, "From: %s uses the default %s, resolved to %s",
+				d.Header["from"], strings.Join(implicit, " and "), ref)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, f := range d.BuildData.PostFragments {
+		if line := unreachableLine(f.Script.Script); line > 0 {
+			err := opts.Warnf(types.WarnUnreachablePost, "%%post:%s commands starting at line %d are never executed, they follow an unconditional exit", f.Name, line)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// unreachableLine returns the line number, relative to the section, of the
+// first command following an exit written at the beginning of a line, zero
+// is returned if there is none. An exit indented or following other commands
+// on the same line is considered as conditional.
+func unreachableLine(script string) int {
+	exited := false
+	n := 0
+
+	s := bufio.NewScanner(strings.NewReader(script))
+	for s.Scan() {
+		n++
+		line := s.Text()
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if exited {
+			return n
+		}
+		exited = exitRegexp.MatchString(line)
+	}
+
+	return 0
+}
@@ -0,0 +1,53 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"testing"
+)
+
+func TestUnreachableLine(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		line   int
+	}{
+		{
+			name:   "NoExit",
+			script: "echo a\necho b\n",
+		},
+		{
+			name:   "LastExit",
+			script: "echo a\nexit 0\n\n# comment\n",
+		},
+		{
+			name:   "ConditionalExit",
+			script: "if true; then\n    exit 1\nfi\necho a\n",
+		},
+		{
+			name:   "ExitOnSameLine",
+			script: "test -f /x || exit 1\necho a\n",
+		},
+		{
+			name:   "Unreachable",
+			script: "echo a\nexit\n\necho b\n",
+			line:   4,
+		},
+		{
+			name:   "UnreachableWithStatus",
+			script: "exit 3 # done\necho b\n",
+			line:   2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if line := unreachableLine(tt.script); line != tt.line {
+				t.Errorf("got line %d instead of %d", line, tt.line)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// CheckExcludePaths checks the --exclude-paths patterns, which must be
+// absolute container paths with a valid glob syntax.
+func CheckExcludePaths(patterns []string) error {
+	for _, p := range patterns {
+		if !filepath.IsAbs(p) {
+			return fmt.Errorf("excluded path %s is not an absolute path", p)
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return fmt.Errorf("excluded path %s: %v", p, err)
+		}
+		if isProtectedPath(filepath.Clean(p)) {
+			return fmt.Errorf("excluded path %s would remove %s", p, filepath.Clean(p))
+		}
+	}
+	return nil
+}
+
+// isProtectedPath returns if the container path p is the root directory or
+// holds the container metadata, which can't be excluded.
+func isProtectedPath(p string) bool {
+	return p == "/" || p == "/.singularity.d" || strings.HasPrefix(p, "/.singularity.d/")
+}
+
+// excludePaths removes the paths of rootfs matching the glob patterns and
+// returns the number of bytes reclaimed. A match holding protected paths is
+// an error, matches whose parent directory resolves outside of rootfs, as
+// through absolute symbolic links, are skipped.
+func excludePaths(rootfs string, patterns []string) (int64, error) {
+	realRootfs, err := filepath.EvalSymlinks(rootfs)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, p := range patterns {
+		matches, err := filepath.Glob(filepath.Join(rootfs, p))
+		if err != nil {
+			return total, fmt.Errorf("excluded path %s: %v", p, err)
+		}
+		if len(matches) == 0 {
+			sylog.Warningf("Excluded path %s matches nothing", p)
+			continue
+		}
+
+		var reclaimed int64
+		removed := 0
+		for _, m := range matches {
+			rel, err := filepath.Rel(rootfs, m)
+			if err != nil {
+				return total, err
+			}
+			path := "/" + rel
+			if rel == "." {
+				path = "/"
+			}
+			if isProtectedPath(path) {
+				return total, fmt.Errorf("excluded path %s would remove %s", p, path)
+			}
+
+			parent, err := filepath.EvalSymlinks(filepath.Dir(m))
+			if err != nil {
+				return total, err
+			}
+			if parent != realRootfs && !strings.HasPrefix(parent, realRootfs+string(os.PathSeparator)) {
+				sylog.Warningf("Skipping excluded path %s resolved outside of the container", path)
+				continue
+			}
+
+			size, err := pathSize(m)
+			if err != nil {
+				return total, err
+			}
+			if err := os.RemoveAll(m); err != nil {
+				return total, fmt.Errorf("while removing %s: %v", path, err)
+			}
+			sylog.Debugf("Removed excluded path %s", path)
+			reclaimed += size
+			removed++
+		}
+		sylog.Infof("Excluded %s: %d path(s), %d bytes reclaimed", p, removed, reclaimed)
+		total += reclaimed
+	}
+	return total, nil
+}
+
+// pathSize returns the size of the regular files found at path.
+func pathSize(path string) (int64, error) {
+	var size int64
+	err := filepath.Walk(path, func(_ string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.Mode().IsRegular() {
+			size += fi.Size()
+		}
+		return nil
+	})
+	return size, err
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheckExcludePaths(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		wantErr  bool
+	}{
+		{name: "Valid", patterns: []string{"/var/cache/apt/*", "/usr/share/man"}},
+		{name: "Relative", patterns: []string{"usr/share/man"}, wantErr: true},
+		{name: "BadPattern", patterns: []string{"/usr/[share"}, wantErr: true},
+		{name: "Root", patterns: []string{"/"}, wantErr: true},
+		{name: "RootDot", patterns: []string{"/usr/.."}, wantErr: true},
+		{name: "Metadata", patterns: []string{"/.singularity.d"}, wantErr: true},
+		{name: "MetadataContent", patterns: []string{"/.singularity.d/env/*"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CheckExcludePaths(tt.patterns)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
+
+func TestExcludePaths(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "exclude-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	outside, err := ioutil.TempDir("", "exclude-outside-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(outside)
+
+	files := map[string]string{
+		"var/cache/apt/a.deb":      "12345",
+		"var/cache/apt/b.deb":      "123",
+		"usr/share/man/man1/ls.1":  "1234567890",
+		"usr/bin/ls":               "binary",
+		".singularity.d/runscript": "#!/bin/sh\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(rootfs, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+	// an absolute link resolved on the host would escape the container
+	if err := ioutil.WriteFile(filepath.Join(outside, "keep"), []byte("host"), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+	if err := os.Symlink(outside, filepath.Join(rootfs, "host")); err != nil {
+		t.Fatalf("failed to create symlink: %s", err)
+	}
+
+	reclaimed, err := excludePaths(rootfs, []string{"/var/cache/apt/*", "/usr/share/man", "/host/*", "/missing"})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if reclaimed != 18 {
+		t.Errorf("got %d bytes reclaimed, want 18", reclaimed)
+	}
+	for _, p := range []string{"var/cache/apt/a.deb", "var/cache/apt/b.deb", "usr/share/man"} {
+		if _, err := os.Lstat(filepath.Join(rootfs, p)); !os.IsNotExist(err) {
+			t.Errorf("%s not removed", p)
+		}
+	}
+	for _, p := range []string{"var/cache/apt", "usr/bin/ls", filepath.Join(outside, "keep")} {
+		if !filepath.IsAbs(p) {
+			p = filepath.Join(rootfs, p)
+		}
+		if _, err := os.Lstat(p); err != nil {
+			t.Errorf("%s removed", p)
+		}
+	}
+
+	if _, err := excludePaths(rootfs, []string{"/*"}); err == nil {
+		t.Errorf("unexpected success removing the container metadata")
+	}
+}
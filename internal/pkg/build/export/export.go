@@ -0,0 +1,442 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package export converts container root filesystems to OCI images and
+// tar archives.
+package export
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/opencontainers/go-digest"
+	specs "github.com/opencontainers/image-spec/specs-go"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/singularity/internal/pkg/util/env"
+	"github.com/sylabs/singularity/internal/pkg/util/shell/interpreter"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+// runtimeEnvScripts are the environment scripts of /.singularity.d/env
+// setting up the Singularity runtime environment rather than the
+// container environment.
+var runtimeEnvScripts = map[string]bool{
+	"01-base.sh":        true,
+	"94-appsbase.sh":    true,
+	"95-apps.sh":        true,
+	"99-base.sh":        true,
+	"99-runtimevars.sh": true,
+}
+
+// ociRunscriptMarker is found in the runscripts generated from the
+// entrypoint and command of docker/OCI source images.
+const ociRunscriptMarker = "SINGULARITY_OCI_RUN="
+
+// ImageConfig returns the OCI image configuration of the container root
+// filesystem rootfs. The container environment is evaluated from its
+// environment scripts, its labels are added to the labels of base, the
+// configuration of the docker/OCI image it was built from, if any, and
+// its runscript becomes the entrypoint unless it was generated from
+// the base configuration.
+func ImageConfig(rootfs string, base *imgspecv1.ImageConfig) (imgspecv1.ImageConfig, error) {
+	var conf imgspecv1.ImageConfig
+	if base != nil {
+		conf = *base
+	}
+
+	envs, err := containerEnv(rootfs)
+	if err != nil {
+		return conf, err
+	}
+	conf.Env = envs
+
+	labels := make(map[string]string)
+	for k, v := range conf.Labels {
+		labels[k] = v
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(rootfs, ".singularity.d", "labels.json")); err == nil {
+		var l map[string]string
+		if err := json.Unmarshal(b, &l); err != nil {
+			return conf, fmt.Errorf("while decoding labels: %s", err)
+		}
+		for k, v := range l {
+			labels[k] = v
+		}
+	}
+	if len(labels) > 0 {
+		conf.Labels = labels
+	}
+
+	runscript, err := ioutil.ReadFile(filepath.Join(rootfs, ".singularity.d", "runscript"))
+	if err != nil && !os.IsNotExist(err) {
+		return conf, fmt.Errorf("while reading runscript: %s", err)
+	}
+	if base == nil || !bytes.Contains(runscript, []byte(ociRunscriptMarker)) {
+		conf.Entrypoint = nil
+		conf.Cmd = nil
+		if len(runscript) > 0 {
+			conf.Entrypoint = []string{"/.singularity.d/runscript"}
+		}
+	}
+
+	return conf, nil
+}
+
+// LostContent returns the content of the container root filesystem rootfs
+// which has no equivalent in OCI images and tar archives, the startscript
+// and SCIF apps, only kept in the image filesystem.
+func LostContent(rootfs string) []string {
+	var lost []string
+	if hasScript(filepath.Join(rootfs, ".singularity.d", "startscript")) {
+		lost = append(lost, "startscript")
+	}
+	if apps, _ := ioutil.ReadDir(filepath.Join(rootfs, "scif", "apps")); len(apps) > 0 {
+		lost = append(lost, "SCIF apps")
+	}
+	return lost
+}
+
+// containerEnv evaluates the container environment scripts and returns
+// the environment variables they set.
+func containerEnv(rootfs string) ([]string, error) {
+	dir := filepath.Join(rootfs, ".singularity.d", "env")
+	scripts, err := filepath.Glob(filepath.Join(dir, "*.sh"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(scripts)
+
+	current := []string{"PATH=" + env.DefaultPath}
+
+	// ignore the variables set by the shell interpreter itself
+	internal := make(map[string]bool)
+	internalEnv, err := interpreter.EvaluateEnv(nil, nil, current)
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range internalEnv {
+		internal[strings.SplitN(e, "=", 2)[0]] = true
+	}
+
+	envs := make(map[string]string)
+	for _, script := range scripts {
+		if runtimeEnvScripts[filepath.Base(script)] {
+			continue
+		}
+		content, err := ioutil.ReadFile(script)
+		if err != nil {
+			return nil, fmt.Errorf("while reading environment script: %s", err)
+		}
+		set, err := interpreter.EvaluateEnv(content, nil, current)
+		if err != nil {
+			sylog.Warningf("Ignoring environment script %s: %s", filepath.Base(script), err)
+			continue
+		}
+		for _, e := range set {
+			kv := strings.SplitN(e, "=", 2)
+			if internal[kv[0]] {
+				continue
+			}
+			envs[kv[0]] = kv[1]
+			current = append(current, e)
+		}
+	}
+	if _, ok := envs["PATH"]; !ok {
+		envs["PATH"] = env.DefaultPath
+	}
+
+	list := make([]string, 0, len(envs))
+	for k, v := range envs {
+		list = append(list, k+"="+v)
+	}
+	sort.Strings(list)
+	return list, nil
+}
+
+// hasScript returns if the script found at path holds anything else
+// than comments.
+func hasScript(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			return true
+		}
+	}
+	return false
+}
+
+// ManifestAnnotations converts the image annotations raw to OCI manifest
+// annotations, whose values are strings. String values are used as is,
+// other values are converted to their compact JSON text.
+func ManifestAnnotations(raw map[string]json.RawMessage) (map[string]string, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	annotations := make(map[string]string, len(raw))
+	for k, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err == nil {
+			annotations[k] = s
+			continue
+		}
+		var b bytes.Buffer
+		if err := json.Compact(&b, v); err != nil {
+			return nil, fmt.Errorf("invalid value of annotation %s: %s", k, err)
+		}
+		annotations[k] = b.String()
+	}
+	return annotations, nil
+}
+
+// WriteLayout writes in dir an OCI image layout holding an image made
+// of a single layer with the content of rootfs, tagged with tag. The
+// image manifest carries the annotations, if any. With rootOwner the
+// layer files are owned by root, see WriteTar.
+func WriteLayout(dir, tag, rootfs, arch string, conf imgspecv1.ImageConfig, annotations map[string]string, rootOwner bool) error {
+	blobs := filepath.Join(dir, "blobs", "sha256")
+	if err := os.MkdirAll(blobs, 0755); err != nil {
+		return err
+	}
+
+	layer, diffID, err := writeLayer(blobs, rootfs, rootOwner)
+	if err != nil {
+		return fmt.Errorf("while creating image layer: %s", err)
+	}
+
+	created := time.Now().UTC()
+	config, err := writeJSONBlob(blobs, imgspecv1.MediaTypeImageConfig, imgspecv1.Image{
+		Created:      &created,
+		Architecture: arch,
+		OS:           "linux",
+		Config:       conf,
+		RootFS: imgspecv1.RootFS{
+			Type:    "layers",
+			DiffIDs: []digest.Digest{diffID},
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	manifest, err := writeJSONBlob(blobs, imgspecv1.MediaTypeImageManifest, imgspecv1.Manifest{
+		Versioned:   specs.Versioned{SchemaVersion: 2},
+		Config:      config,
+		Layers:      []imgspecv1.Descriptor{layer},
+		Annotations: annotations,
+	})
+	if err != nil {
+		return err
+	}
+	manifest.Annotations = map[string]string{imgspecv1.AnnotationRefName: tag}
+
+	index, err := json.Marshal(imgspecv1.Index{
+		Versioned: specs.Versioned{SchemaVersion: 2},
+		Manifests: []imgspecv1.Descriptor{manifest},
+	})
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "index.json"), index, 0644); err != nil {
+		return err
+	}
+
+	layout, err := json.Marshal(imgspecv1.ImageLayout{Version: imgspecv1.ImageLayoutVersion})
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(dir, imgspecv1.ImageLayoutFile), layout, 0644)
+}
+
+// writeJSONBlob writes the JSON encoding of v as a blob of blobs
+// and returns its descriptor.
+func writeJSONBlob(blobs, mediaType string, v interface{}) (imgspecv1.Descriptor, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	d := digest.FromBytes(b)
+	if err := ioutil.WriteFile(filepath.Join(blobs, d.Encoded()), b, 0644); err != nil {
+		return imgspecv1.Descriptor{}, err
+	}
+	return imgspecv1.Descriptor{
+		MediaType: mediaType,
+		Digest:    d,
+		Size:      int64(len(b)),
+	}, nil
+}
+
+// writeLayer writes the gzip compressed tar archive of rootfs as a
+// blob of blobs and returns its descriptor and the digest of the
+// uncompressed archive.
+func writeLayer(blobs, rootfs string, rootOwner bool) (imgspecv1.Descriptor, digest.Digest, error) {
+	f, err := ioutil.TempFile(blobs, "layer-")
+	if err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	compressed := sha256.New()
+	uncompressed := sha256.New()
+	counter := &countWriter{w: io.MultiWriter(f, compressed)}
+
+	gz := gzip.NewWriter(counter)
+	if err := WriteTar(io.MultiWriter(gz, uncompressed), rootfs, rootOwner); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	if err := gz.Close(); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	if err := f.Close(); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+
+	d := digest.NewDigestFromEncoded(digest.SHA256, hex.EncodeToString(compressed.Sum(nil)))
+	if err := os.Rename(f.Name(), filepath.Join(blobs, d.Encoded())); err != nil {
+		return imgspecv1.Descriptor{}, "", err
+	}
+	diffID := digest.NewDigestFromEncoded(digest.SHA256, hex.EncodeToString(uncompressed.Sum(nil)))
+
+	return imgspecv1.Descriptor{
+		MediaType: imgspecv1.MediaTypeImageLayerGzip,
+		Digest:    d,
+		Size:      counter.n,
+	}, diffID, nil
+}
+
+type countWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (c *countWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// capabilityXattr is the extended attribute holding the file
+// capabilities, stored in archives as a SCHILY.xattr PAX record.
+const capabilityXattr = "security.capability"
+
+// fileCapability returns the value of the capability extended attribute
+// of path, nil when it has none.
+func fileCapability(path string) ([]byte, error) {
+	size, err := unix.Lgetxattr(path, capabilityXattr, nil)
+	if err == unix.ENODATA || err == unix.ENOTSUP {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("while reading capabilities of %s: %s", path, err)
+	}
+	value := make([]byte, size)
+	size, err = unix.Lgetxattr(path, capabilityXattr, value)
+	if err != nil {
+		return nil, fmt.Errorf("while reading capabilities of %s: %s", path, err)
+	}
+	return value[:size], nil
+}
+
+// WriteTar writes the tar archive of the content of rootfs to w, hard
+// links are stored once and file capabilities are kept. The ownership
+// of the files is preserved, unless rootOwner is set where everything
+// is owned by root.
+func WriteTar(w io.Writer, rootfs string, rootOwner bool) error {
+	tw := tar.NewWriter(w)
+	links := make(map[uint64]string)
+
+	err := filepath.Walk(rootfs, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(rootfs, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		if fi.Mode()&os.ModeSocket != 0 {
+			return nil
+		}
+
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		hdr, err := tar.FileInfoHeader(fi, link)
+		if err != nil {
+			return err
+		}
+		hdr.Name = rel
+		if fi.IsDir() {
+			hdr.Name += "/"
+		}
+
+		hdr.Uname = ""
+		hdr.Gname = ""
+		if rootOwner {
+			hdr.Uid = 0
+			hdr.Gid = 0
+		}
+		if fi.Mode().IsRegular() {
+			value, err := fileCapability(path)
+			if err != nil {
+				return err
+			}
+			if value != nil {
+				hdr.PAXRecords = map[string]string{"SCHILY.xattr." + capabilityXattr: string(value)}
+			}
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && fi.Mode().IsRegular() && st.Nlink > 1 {
+			if target, ok := links[uint64(st.Ino)]; ok {
+				hdr.Typeflag = tar.TypeLink
+				hdr.Linkname = target
+				hdr.Size = 0
+			} else {
+				links[uint64(st.Ino)] = rel
+			}
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			return nil
+		}
+
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		_, err = io.Copy(tw, f)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return tw.Close()
+}
@@ -0,0 +1,227 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package export
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/singularity/internal/pkg/test"
+	"golang.org/x/sys/unix"
+)
+
+func TestImageConfig(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "export-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	files := map[string]string{
+		"env/10-docker2singularity.sh": "export PATH=\"/opt/bin:/usr/bin:/bin\"\nexport LANG=\"C.UTF-8\"\n",
+		"env/90-environment.sh":        "#!/bin/sh\nexport DATA=/data\nexport DATA_IN=\"$DATA/in\"\n",
+		"env/99-base.sh":               "export PS1=\"Singularity> \"\n",
+		"labels.json":                  `{"maintainer": "me"}`,
+	}
+	for name, content := range files {
+		path := filepath.Join(rootfs, ".singularity.d", name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", path, err)
+		}
+	}
+
+	wantEnv := []string{
+		"DATA=/data",
+		"DATA_IN=/data/in",
+		"LANG=C.UTF-8",
+		"PATH=/opt/bin:/usr/bin:/bin",
+	}
+
+	tests := []struct {
+		name           string
+		runscript      string
+		base           *imgspecv1.ImageConfig
+		wantEntrypoint []string
+		wantCmd        []string
+		wantLabels     map[string]string
+	}{
+		{
+			name:           "Runscript",
+			runscript:      "#!/bin/sh\nexec /opt/bin/app \"$@\"\n",
+			wantEntrypoint: []string{"/.singularity.d/runscript"},
+			wantLabels:     map[string]string{"maintainer": "me"},
+		},
+		{
+			name:      "OCIRunscript",
+			runscript: "#!/bin/sh\nOCI_ENTRYPOINT='\"/entrypoint.sh\"'\nSINGULARITY_OCI_RUN=\"${OCI_ENTRYPOINT}\"\n",
+			base: &imgspecv1.ImageConfig{
+				Entrypoint: []string{"/entrypoint.sh"},
+				Cmd:        []string{"serve"},
+				Labels:     map[string]string{"version": "1.0"},
+			},
+			wantEntrypoint: []string{"/entrypoint.sh"},
+			wantCmd:        []string{"serve"},
+			wantLabels:     map[string]string{"maintainer": "me", "version": "1.0"},
+		},
+		{
+			name:      "OverriddenOCIRunscript",
+			runscript: "#!/bin/sh\nexec /opt/bin/app \"$@\"\n",
+			base: &imgspecv1.ImageConfig{
+				Entrypoint: []string{"/entrypoint.sh"},
+				Cmd:        []string{"serve"},
+			},
+			wantEntrypoint: []string{"/.singularity.d/runscript"},
+			wantLabels:     map[string]string{"maintainer": "me"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ioutil.WriteFile(filepath.Join(rootfs, ".singularity.d", "runscript"), []byte(tt.runscript), 0755); err != nil {
+				t.Fatalf("failed to write runscript: %s", err)
+			}
+
+			conf, err := ImageConfig(rootfs, tt.base)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(conf.Env, wantEnv) {
+				t.Errorf("unexpected Env %v, wanted %v", conf.Env, wantEnv)
+			}
+			if !reflect.DeepEqual(conf.Entrypoint, tt.wantEntrypoint) {
+				t.Errorf("unexpected Entrypoint %v, wanted %v", conf.Entrypoint, tt.wantEntrypoint)
+			}
+			if !reflect.DeepEqual(conf.Cmd, tt.wantCmd) {
+				t.Errorf("unexpected Cmd %v, wanted %v", conf.Cmd, tt.wantCmd)
+			}
+			if !reflect.DeepEqual(conf.Labels, tt.wantLabels) {
+				t.Errorf("unexpected Labels %v, wanted %v", conf.Labels, tt.wantLabels)
+			}
+		})
+	}
+}
+
+func TestManifestAnnotations(t *testing.T) {
+	raw := map[string]json.RawMessage{
+		"org.opencontainers.image.authors": json.RawMessage(`"me"`),
+		"org.example.build":                json.RawMessage(`{ "id": 42, "tags": ["a", "b"] }`),
+		"org.example.count":                json.RawMessage(`3`),
+	}
+	want := map[string]string{
+		"org.opencontainers.image.authors": "me",
+		"org.example.build":                `{"id":42,"tags":["a","b"]}`,
+		"org.example.count":                "3",
+	}
+
+	got, err := ManifestAnnotations(raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	if got, err := ManifestAnnotations(nil); err != nil || got != nil {
+		t.Errorf("unexpected annotations %v: %v", got, err)
+	}
+}
+
+func TestWriteTar(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "export-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "file"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	tests := []struct {
+		name      string
+		rootOwner bool
+		wantUID   int
+		wantGID   int
+	}{
+		{
+			name:    "KeepOwner",
+			wantUID: os.Getuid(),
+			wantGID: os.Getgid(),
+		},
+		{
+			name:      "RootOwner",
+			rootOwner: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteTar(&buf, rootfs, tt.rootOwner); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+
+			tr := tar.NewReader(&buf)
+			hdr, err := tr.Next()
+			if err != nil {
+				t.Fatalf("failed to read archive: %s", err)
+			}
+			if hdr.Name != "file" {
+				t.Errorf("got entry %q, want file", hdr.Name)
+			}
+			if hdr.Uid != tt.wantUID || hdr.Gid != tt.wantGID {
+				t.Errorf("got owner %d:%d, want %d:%d", hdr.Uid, hdr.Gid, tt.wantUID, tt.wantGID)
+			}
+			if _, err := tr.Next(); err != io.EOF {
+				t.Errorf("unexpected archive entry: %v", err)
+			}
+		})
+	}
+}
+
+func TestWriteTarCapability(t *testing.T) {
+	test.EnsurePrivilege(t)
+
+	rootfs, err := ioutil.TempDir("", "export-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	path := filepath.Join(rootfs, "server")
+	if err := ioutil.WriteFile(path, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+	// revision 2 vfs_cap_data, cap_net_bind_service+ep
+	value := []byte{1, 0, 0, 2, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	if err := unix.Setxattr(path, capabilityXattr, value, 0); err != nil {
+		t.Skipf("file capabilities not supported: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteTar(&buf, rootfs, false); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	hdr, err := tar.NewReader(&buf).Next()
+	if err != nil {
+		t.Fatalf("failed to read archive: %s", err)
+	}
+	if got := hdr.PAXRecords["SCHILY.xattr."+capabilityXattr]; got != string(value) {
+		t.Errorf("got capability record %q, want %q", got, value)
+	}
+}
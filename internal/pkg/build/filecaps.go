@@ -0,0 +1,141 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/capabilities"
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// capabilityXattr is the extended attribute holding the file
+	// capabilities, as set by setcap.
+	capabilityXattr = "security.capability"
+	// vfsCapRevision2 and vfsCapFlagsEffective are the revision and
+	// effective flag of the vfs_cap_data structure of the attribute.
+	vfsCapRevision2      = 0x02000000
+	vfsCapFlagsEffective = 0x000001
+	// defaultCapFlags are the capability sets of a --setcap value
+	// without flags, as the common setcap cap_xxx+ep.
+	defaultCapFlags = "ep"
+)
+
+// fileCap describes the capabilities set on a container file.
+type fileCap struct {
+	path        string
+	permitted   uint64
+	inheritable uint64
+	effective   bool
+}
+
+// parseFileCap parses a --setcap value of the form PATH=CAPS[+FLAGS],
+// CAPS being a comma separated list of capability names, with or without
+// the CAP_ prefix, and FLAGS the e (effective), i (inheritable) and p
+// (permitted) sets they are added to, defaultCapFlags if not set.
+func parseFileCap(spec string) (fileCap, error) {
+	kv := strings.SplitN(spec, "=", 2)
+	if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+		return fileCap{}, fmt.Errorf("file capabilities %q must have the format PATH=CAPS[+FLAGS]", spec)
+	}
+	c := fileCap{path: kv[0]}
+	if !filepath.IsAbs(c.path) {
+		return fileCap{}, fmt.Errorf("file capabilities path %s is not an absolute path", c.path)
+	}
+
+	caps, flags := kv[1], defaultCapFlags
+	if i := strings.LastIndex(caps, "+"); i >= 0 {
+		caps, flags = caps[:i], caps[i+1:]
+		if flags == "" {
+			return fileCap{}, fmt.Errorf("missing capability flags in %q", spec)
+		}
+	}
+
+	names, unknown := capabilities.Split(caps)
+	if len(unknown) > 0 {
+		return fileCap{}, fmt.Errorf("unknown capabilities %s in %q", strings.Join(unknown, ","), spec)
+	}
+	var mask uint64
+	for _, name := range names {
+		mask |= 1 << capabilities.Map[name].Value
+	}
+
+	for _, f := range flags {
+		switch f {
+		case 'e':
+			c.effective = true
+		case 'i':
+			c.inheritable = mask
+		case 'p':
+			c.permitted = mask
+		default:
+			return fileCap{}, fmt.Errorf("unknown capability flag %q in %q, must be e, i or p", f, spec)
+		}
+	}
+
+	return c, nil
+}
+
+// CheckFileCaps checks the syntax of the --setcap values.
+func CheckFileCaps(specs []string) error {
+	for _, spec := range specs {
+		if _, err := parseFileCap(spec); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// xattr returns the value of the capability extended attribute of c, a
+// revision 2 vfs_cap_data structure.
+func (c fileCap) xattr() []byte {
+	magic := uint32(vfsCapRevision2)
+	if c.effective {
+		magic |= vfsCapFlagsEffective
+	}
+
+	data := make([]byte, 20)
+	binary.LittleEndian.PutUint32(data[0:], magic)
+	binary.LittleEndian.PutUint32(data[4:], uint32(c.permitted))
+	binary.LittleEndian.PutUint32(data[8:], uint32(c.inheritable))
+	binary.LittleEndian.PutUint32(data[12:], uint32(c.permitted>>32))
+	binary.LittleEndian.PutUint32(data[16:], uint32(c.inheritable>>32))
+	return data
+}
+
+// setFileCaps sets the file capabilities of the --setcap values specs
+// on the files of the root filesystem rootfs. The container paths are
+// resolved in rootfs, symlinks included, and must be regular files.
+func setFileCaps(rootfs string, specs []string) error {
+	for _, spec := range specs {
+		c, err := parseFileCap(spec)
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(rootfs, fs.EvalRelative(c.path, rootfs))
+		fi, err := os.Lstat(path)
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return fmt.Errorf("%s is not a regular file", c.path)
+		}
+
+		sylog.Infof("Setting file capabilities of %s", c.path)
+		if err := unix.Setxattr(path, capabilityXattr, c.xattr(), 0); err != nil {
+			return fmt.Errorf("while setting capabilities of %s: %v", c.path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParseFileCap(t *testing.T) {
+	const (
+		capNetBindService = 1 << 10
+		capNetRaw         = 1 << 13
+		capSysAdmin       = 1 << 21
+	)
+
+	tests := []struct {
+		name    string
+		spec    string
+		want    fileCap
+		wantErr bool
+	}{
+		{
+			name: "Default",
+			spec: "/usr/bin/server=cap_net_bind_service",
+			want: fileCap{path: "/usr/bin/server", permitted: capNetBindService, effective: true},
+		},
+		{
+			name: "Flags",
+			spec: "/usr/bin/ping=CAP_NET_RAW+p",
+			want: fileCap{path: "/usr/bin/ping", permitted: capNetRaw},
+		},
+		{
+			name: "Multiple",
+			spec: "/usr/bin/tool=net_raw,sys_admin+eip",
+			want: fileCap{path: "/usr/bin/tool", permitted: capNetRaw | capSysAdmin, inheritable: capNetRaw | capSysAdmin, effective: true},
+		},
+		{name: "NoCaps", spec: "/usr/bin/server=", wantErr: true},
+		{name: "NoPath", spec: "=cap_net_raw", wantErr: true},
+		{name: "NoSeparator", spec: "/usr/bin/server", wantErr: true},
+		{name: "Relative", spec: "usr/bin/server=cap_net_raw", wantErr: true},
+		{name: "Unknown", spec: "/usr/bin/server=cap_unknown", wantErr: true},
+		{name: "EmptyFlags", spec: "/usr/bin/server=cap_net_raw+", wantErr: true},
+		{name: "BadFlag", spec: "/usr/bin/server=cap_net_raw+x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFileCap(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFileCapXattr(t *testing.T) {
+	// value written by setcap cap_net_bind_service+ep
+	want := []byte{
+		0x01, 0x00, 0x00, 0x02,
+		0x00, 0x04, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+	}
+	c := fileCap{path: "/usr/bin/server", permitted: 1 << 10, effective: true}
+	if got := c.xattr(); !bytes.Equal(got, want) {
+		t.Errorf("got %x, want %x", got, want)
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package files
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// ManifestPath is the path of the %files manifest in a container.
+const ManifestPath = "/.singularity.d/files-manifest.json"
+
+// ManifestEntry records the hash of a %files source for a source/destination
+// pair along with the hash of the destination once copied.
+type ManifestEntry struct {
+	Src     string `json:"src"`
+	Dst     string `json:"dst"`
+	Hash    string `json:"hash"`
+	DstHash string `json:"dstHash"`
+}
+
+// Manifest records the hashes of the %files sources copied in a
+// container and of their destinations, it allows to skip the copy
+// of unchanged sources when updating a sandbox.
+type Manifest struct {
+	Files []ManifestEntry `json:"files"`
+}
+
+// LoadManifest reads the manifest found at path, an empty
+// manifest is returned if there is no manifest at path.
+func LoadManifest(path string) (*Manifest, error) {
+	m := new(Manifest)
+
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return m, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	if err := json.Unmarshal(b, m); err != nil {
+		return nil, fmt.Errorf("while decoding manifest %s: %s", path, err)
+	}
+	return m, nil
+}
+
+// Save writes the manifest at path.
+func (m *Manifest) Save(path string) error {
+	b, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, b, 0644)
+}
+
+// Lookup returns the entry recorded for the src/dst pair, false is
+// returned if there is none.
+func (m *Manifest) Lookup(src, dst string) (ManifestEntry, bool) {
+	for _, e := range m.Files {
+		if e.Src == src && e.Dst == dst {
+			return e, true
+		}
+	}
+	return ManifestEntry{}, false
+}
+
+// Set records the entry e, replacing the entry previously
+// recorded for the same source/destination pair.
+func (m *Manifest) Set(e ManifestEntry) {
+	for i, f := range m.Files {
+		if f.Src == e.Src && f.Dst == e.Dst {
+			m.Files[i] = e
+			return
+		}
+	}
+	m.Files = append(m.Files, e)
+}
+
+// Hash returns a SHA-256 digest computed over the path, the mode and the
+// content of every file found under the paths matching src. Symlinks are
+// followed the same way Copy does when followLinks is true, so that any
+// change affecting the copy result changes the hash.
+func Hash(src string) (string, error) {
+	paths, err := expandPath(src)
+	if err != nil {
+		return "", fmt.Errorf("while expanding source path with bash: %s: %s", src, err)
+	}
+
+	h := sha256.New()
+	for _, p := range paths {
+		fmt.Fprintf(h, "source %s\n", p)
+		if err := hashPath(h, p, ".", true, make(map[[2]uint64]bool)); err != nil {
+			return "", err
+		}
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// HashPath returns a SHA-256 digest computed over the path, the mode and
+// the content of every file found under path. Unlike Hash, path is not
+// expanded and symlinks are not followed, it's used to hash destinations
+// in a container root filesystem where symlinks don't point to host files.
+func HashPath(path string) (string, error) {
+	h := sha256.New()
+	if err := hashPath(h, path, ".", false, nil); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func hashPath(h hash.Hash, path, rel string, followLinks bool, visited map[[2]uint64]bool) error {
+	stat := os.Lstat
+	if followLinks {
+		stat = os.Stat
+	}
+	fi, err := stat(path)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(h, "%s %o %d\n", rel, uint32(fi.Mode()), fi.Size())
+
+	switch {
+	case fi.IsDir():
+		// prevent infinite recursion with symlinks loops
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && visited != nil {
+			id := [2]uint64{uint64(st.Dev), st.Ino}
+			if visited[id] {
+				return nil
+			}
+			visited[id] = true
+		}
+		entries, err := ioutil.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, e := range entries {
+			if err := hashPath(h, filepath.Join(path, e.Name()), filepath.Join(rel, e.Name()), followLinks, visited); err != nil {
+				return err
+			}
+		}
+	case fi.Mode()&os.ModeSymlink != 0:
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(h, "-> %s\n", target)
+	case fi.Mode().IsRegular():
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if _, err := io.Copy(h, f); err != nil {
+			return fmt.Errorf("while hashing %s: %s", path, err)
+		}
+	}
+
+	return nil
+}
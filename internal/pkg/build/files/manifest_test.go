@@ -0,0 +1,141 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package files
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestHash(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hash-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "sub", "file")
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(file, []byte(sourceFileContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := Hash(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if h, _ := Hash(dir); h != ref {
+		t.Errorf("hash of unchanged directory changed")
+	}
+
+	tests := []struct {
+		name   string
+		change func() error
+	}{
+		{
+			name:   "Mode",
+			change: func() error { return os.Chmod(file, 0600) },
+		},
+		{
+			name:   "Content",
+			change: func() error { return ioutil.WriteFile(file, []byte("changed"), 0600) },
+		},
+		{
+			name:   "NewFile",
+			change: func() error { return ioutil.WriteFile(filepath.Join(dir, "new"), nil, 0644) },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.change(); err != nil {
+				t.Fatal(err)
+			}
+			h, err := Hash(dir)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if h == ref {
+				t.Errorf("hash didn't change")
+			}
+			ref = h
+		})
+	}
+}
+
+func TestManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "manifest-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "manifest.json")
+
+	m, err := LoadManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading missing manifest: %s", err)
+	}
+	if len(m.Files) != 0 {
+		t.Fatalf("unexpected entries in missing manifest")
+	}
+
+	m.Set(ManifestEntry{Src: "src", Dst: "dst", Hash: "hash1", DstHash: "dst1"})
+	m.Set(ManifestEntry{Src: "src", Dst: "dst", Hash: "hash2", DstHash: "dst2"})
+	m.Set(ManifestEntry{Src: "src", Dst: "other", Hash: "hash3", DstHash: "dst3"})
+
+	if err := m.Save(path); err != nil {
+		t.Fatalf("unexpected error saving manifest: %s", err)
+	}
+
+	m, err = LoadManifest(path)
+	if err != nil {
+		t.Fatalf("unexpected error loading manifest: %s", err)
+	}
+	if len(m.Files) != 2 {
+		t.Errorf("got %d entries instead of 2", len(m.Files))
+	}
+	if e, ok := m.Lookup("src", "dst"); !ok || e.Hash != "hash2" || e.DstHash != "dst2" {
+		t.Errorf("got entry %+v instead of hash2/dst2", e)
+	}
+	if _, ok := m.Lookup("unknown", "dst"); ok {
+		t.Errorf("unexpected entry for unknown source")
+	}
+}
+
+func TestHashPath(t *testing.T) {
+	dir, err := ioutil.TempDir("", "hash-path-test-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), []byte(sourceFileContent), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// symlinks are not followed, a dangling symlink is hashed too
+	if err := os.Symlink("/non/existent", filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+
+	ref, err := HashPath(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "file"), []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if h, err := HashPath(dir); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if h == ref {
+		t.Errorf("hash didn't change")
+	}
+}
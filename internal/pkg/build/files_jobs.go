@@ -0,0 +1,136 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+)
+
+// filesJob is the copy of a %files entry to the container path dst.
+type filesJob struct {
+	dst string
+	run func() error
+}
+
+// destOverlap returns if the container paths a and b are the same path
+// or if one of them is below the other.
+func destOverlap(a, b string) bool {
+	if a == b || a == "/" || b == "/" {
+		return true
+	}
+	return strings.HasPrefix(a, b+"/") || strings.HasPrefix(b, a+"/")
+}
+
+// groupFilesJobs groups the jobs whose destinations overlap, directly or
+// through other jobs, destinations being resolved in rootfs. Groups and
+// the jobs of a group are in the order of the %files section.
+func groupFilesJobs(rootfs string, jobs []filesJob) [][]filesJob {
+	dsts := make([]string, len(jobs))
+	for i, j := range jobs {
+		dsts[i] = fs.EvalRelative(filepath.Join("/", j.dst), rootfs)
+	}
+
+	// group[i] is the index of the first job of the group of job i
+	group := make([]int, len(jobs))
+	for i := range jobs {
+		group[i] = i
+		for k := 0; k < i; k++ {
+			if !destOverlap(dsts[i], dsts[k]) {
+				continue
+			}
+			// merge the groups of jobs i and k
+			from, to := group[i], group[k]
+			if from < to {
+				from, to = to, from
+			}
+			for l := 0; l <= i; l++ {
+				if group[l] == from {
+					group[l] = to
+				}
+			}
+		}
+	}
+
+	var groups [][]filesJob
+	index := make(map[int]int)
+	for i, j := range jobs {
+		g, ok := index[group[i]]
+		if !ok {
+			g = len(groups)
+			index[group[i]] = g
+			groups = append(groups, nil)
+		}
+		groups[g] = append(groups[g], j)
+	}
+	return groups
+}
+
+// runFilesJobs runs the jobs with up to n groups of jobs running
+// concurrently, the jobs of a group, whose destinations overlap, are run
+// in order so that later entries overwrite earlier ones as with a serial
+// copy. The error of the first failed group is returned, no group is
+// started once a job failed.
+func runFilesJobs(rootfs string, jobs []filesJob, n int) error {
+	if n <= 1 {
+		for _, j := range jobs {
+			if err := j.run(); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	groups := groupFilesJobs(rootfs, jobs)
+	errs := make([]error, len(groups))
+
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		failed bool
+	)
+	next := make(chan int)
+
+	for w := 0; w < n && w < len(groups); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for g := range next {
+				for _, j := range groups[g] {
+					if err := j.run(); err != nil {
+						errs[g] = err
+						mu.Lock()
+						failed = true
+						mu.Unlock()
+						break
+					}
+				}
+			}
+		}()
+	}
+
+	for g := range groups {
+		mu.Lock()
+		stop := failed
+		mu.Unlock()
+		if stop {
+			break
+		}
+		next <- g
+	}
+	close(next)
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
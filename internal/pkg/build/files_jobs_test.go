@@ -0,0 +1,150 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestGroupFilesJobs(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "files-jobs-test-")
+	if err != nil {
+		t.Fatalf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	// /link resolves to /opt in the container
+	if err := os.Mkdir(filepath.Join(rootfs, "opt"), 0755); err != nil {
+		t.Fatalf("while creating directory: %s", err)
+	}
+	if err := os.Symlink("opt", filepath.Join(rootfs, "link")); err != nil {
+		t.Fatalf("while creating symlink: %s", err)
+	}
+
+	tests := []struct {
+		name string
+		dsts []string
+		want [][]string
+	}{
+		{
+			name: "Independent",
+			dsts: []string{"/data/a", "/data/b", "/srv"},
+			want: [][]string{{"/data/a"}, {"/data/b"}, {"/srv"}},
+		},
+		{
+			name: "Same",
+			dsts: []string{"/data", "/srv", "/data/"},
+			want: [][]string{{"/data", "/data/"}, {"/srv"}},
+		},
+		{
+			name: "Nested",
+			dsts: []string{"/data/a/b", "/srv", "/data/a"},
+			want: [][]string{{"/data/a/b", "/data/a"}, {"/srv"}},
+		},
+		{
+			name: "Transitive",
+			dsts: []string{"/a/x", "/b", "/c/y", "/a", "/c", "/b/z"},
+			want: [][]string{{"/a/x", "/a"}, {"/b", "/b/z"}, {"/c/y", "/c"}},
+		},
+		{
+			name: "Merged",
+			dsts: []string{"/a/x", "/a/y", "/a"},
+			want: [][]string{{"/a/x", "/a/y", "/a"}},
+		},
+		{
+			name: "Symlink",
+			dsts: []string{"/opt/a", "/srv", "/link/a"},
+			want: [][]string{{"/opt/a", "/link/a"}, {"/srv"}},
+		},
+		{
+			name: "Prefix",
+			dsts: []string{"/data", "/data2"},
+			want: [][]string{{"/data"}, {"/data2"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs := make([]filesJob, len(tt.dsts))
+			for i, dst := range tt.dsts {
+				jobs[i] = filesJob{dst: dst}
+			}
+			var got [][]string
+			for _, g := range groupFilesJobs(rootfs, jobs) {
+				var dsts []string
+				for _, j := range g {
+					dsts = append(dsts, j.dst)
+				}
+				got = append(got, dsts)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got groups %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunFilesJobs(t *testing.T) {
+	var (
+		mu    sync.Mutex
+		order []int
+	)
+	job := func(dst string, i int, err error) filesJob {
+		return filesJob{dst: dst, run: func() error {
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+			return err
+		}}
+	}
+
+	// jobs of overlapping destinations run in order
+	jobs := []filesJob{
+		job("/a", 0, nil),
+		job("/b", 1, nil),
+		job("/a/x", 2, nil),
+		job("/c", 3, nil),
+		job("/a", 4, nil),
+	}
+	for _, n := range []int{1, 2, 8} {
+		order = nil
+		if err := runFilesJobs("/nonexistent", jobs, n); err != nil {
+			t.Fatalf("unexpected error with %d job(s): %s", n, err)
+		}
+		if len(order) != len(jobs) {
+			t.Fatalf("ran %d job(s), want %d", len(order), len(jobs))
+		}
+		var a []int
+		for _, i := range order {
+			if i == 0 || i == 2 || i == 4 {
+				a = append(a, i)
+			}
+		}
+		if !reflect.DeepEqual(a, []int{0, 2, 4}) {
+			t.Errorf("jobs of /a ran in order %v with %d job(s)", a, n)
+		}
+	}
+
+	failure := errors.New("copy failed")
+	jobs = []filesJob{
+		job("/a", 0, nil),
+		job("/a", 1, failure),
+		job("/a", 2, nil),
+	}
+	order = nil
+	if err := runFilesJobs("/nonexistent", jobs, 4); err != failure {
+		t.Errorf("got error %v, want %v", err, failure)
+	}
+	if !reflect.DeepEqual(order, []int{0, 1}) {
+		t.Errorf("got jobs %v run after a failure, want [0 1]", order)
+	}
+}
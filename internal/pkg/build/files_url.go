@@ -0,0 +1,161 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/build/files"
+	"github.com/sylabs/singularity/internal/pkg/client/net"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// urlChecksumPrefix prefixes the optional SHA-256 checksum following
+// the destination of a %files URL source.
+const urlChecksumPrefix = "sha256:"
+
+// urlTransfer describes a %files entry downloading a http(s) URL.
+type urlTransfer struct {
+	url      string
+	dst      string
+	checksum string
+}
+
+// isURLSource returns if the %files source src is a http(s) URL.
+func isURLSource(src string) bool {
+	return net.IsNetPullRef(src)
+}
+
+// parseURLTransfer returns the URL, destination and optional checksum of
+// the %files entry t, whose source is a URL. The destination is required
+// and may be followed by the hex encoded SHA-256 checksum of the file
+// prefixed by urlChecksumPrefix.
+func parseURLTransfer(t types.FileTransport) (urlTransfer, error) {
+	u := urlTransfer{url: t.Src, dst: t.Dst}
+
+	fields := strings.Fields(t.Dst)
+	if n := len(fields); n > 0 && strings.HasPrefix(fields[n-1], urlChecksumPrefix) {
+		checksum := strings.ToLower(strings.TrimPrefix(fields[n-1], urlChecksumPrefix))
+		if b, err := hex.DecodeString(checksum); err != nil || len(b) != sha256.Size {
+			return u, fmt.Errorf("invalid %s checksum %q of %%files source %s", urlChecksumPrefix, fields[n-1], t.Src)
+		}
+		u.checksum = checksum
+		u.dst = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(t.Dst), fields[n-1]))
+	}
+	if u.dst == "" {
+		return u, fmt.Errorf("%%files source %s requires a destination", t.Src)
+	}
+
+	return u, nil
+}
+
+// checkURLTransfers checks the URL sources of the %files section f.
+func checkURLTransfers(f types.Files) error {
+	if f.Args != "" {
+		return nil
+	}
+	for _, t := range f.Files {
+		if !isURLSource(t.Src) {
+			continue
+		}
+		if _, err := parseURLTransfer(t); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNoURLTransfers returns an error if a %files section of the
+// definition def downloads URL sources, which always require network
+// access and can't be used with --from-cache-only.
+func checkNoURLTransfers(def types.Definition) error {
+	for _, f := range def.BuildData.Files {
+		if f.Args != "" {
+			continue
+		}
+		for _, t := range f.Files {
+			if isURLSource(t.Src) {
+				return fmt.Errorf("%%files source %s is downloaded from the network and can't be used with --from-cache-only", t.Src)
+			}
+		}
+	}
+	return nil
+}
+
+// downloadFile downloads the URL source of u into the root filesystem of
+// the stage. The file is checked against the checksum, when set, before
+// being copied to its destination, a download failure or a checksum
+// mismatch failing the build. A destination which is a directory
+// receives the file under the last element of the URL path.
+func (s *stage) downloadFile(ctx context.Context, u urlTransfer) error {
+	tmp, err := ioutil.TempFile(s.b.TmpDir, "files-url-")
+	if err != nil {
+		return err
+	}
+	tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	sylog.Infof("Downloading %v", u.url)
+	if err := net.DownloadImage(ctx, tmp.Name(), u.url); err != nil {
+		return fmt.Errorf("while downloading %s: %v", u.url, err)
+	}
+
+	if u.checksum != "" {
+		sum, err := fileSHA256(tmp.Name())
+		if err != nil {
+			return fmt.Errorf("while computing checksum of %s: %v", u.url, err)
+		}
+		if sum != u.checksum {
+			return fmt.Errorf("checksum mismatch for %s: got %s%s, expected %s%s", u.url, urlChecksumPrefix, sum, urlChecksumPrefix, u.checksum)
+		}
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	dst := files.AddPrefix(s.b.RootfsPath, u.dst)
+	if strings.HasSuffix(dst, "/") || fs.IsDir(dst) {
+		p, err := url.Parse(u.url)
+		if err != nil {
+			return err
+		}
+		name := path.Base(p.Path)
+		if name == "/" || name == "." {
+			return fmt.Errorf("no file name found in %s for directory destination %s", u.url, u.dst)
+		}
+		dst = filepath.Join(dst, name)
+	}
+
+	sylog.Infof("Copying %v to %v", u.url, dst)
+	return files.Copy(tmp.Name(), dst, true)
+}
+
+// fileSHA256 returns the hex encoded SHA-256 checksum of the file path.
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+// licenseSHA256 is the SHA-256 checksum of "license\n".
+const licenseSHA256 = "c0c56958ef8be5c1979366896b7e0c7206949a5aa2b23f51429c7f56b10990d3"
+
+func TestParseURLTransfer(t *testing.T) {
+	const url = "https://example.com/LICENSE"
+	checksum := "sha256:" + licenseSHA256
+
+	tests := []struct {
+		name    string
+		dst     string
+		want    urlTransfer
+		wantErr bool
+	}{
+		{name: "NoDestination", dst: "", wantErr: true},
+		{name: "Destination", dst: "/opt/LICENSE", want: urlTransfer{url: url, dst: "/opt/LICENSE"}},
+		{name: "Checksum", dst: "/opt/LICENSE " + checksum, want: urlTransfer{url: url, dst: "/opt/LICENSE", checksum: licenseSHA256}},
+		{name: "ChecksumOnly", dst: checksum, wantErr: true},
+		{name: "ShortChecksum", dst: "/opt/LICENSE sha256:c0c5", wantErr: true},
+		{name: "InvalidChecksum", dst: "/opt/LICENSE sha256:" + licenseSHA256[:62] + "zz", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseURLTransfer(types.FileTransport{Src: url, Dst: tt.dst})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckNoURLTransfers(t *testing.T) {
+	var def types.Definition
+	def.BuildData.Files = []types.Files{
+		{Files: []types.FileTransport{{Src: "/etc/hosts", Dst: "/opt/hosts"}}},
+		{Args: "from one", Files: []types.FileTransport{{Src: "/opt/data", Dst: "/opt/data"}}},
+	}
+	if err := checkNoURLTransfers(def); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	def.BuildData.Files = append(def.BuildData.Files, types.Files{
+		Files: []types.FileTransport{{Src: "https://example.com/LICENSE", Dst: "/opt/LICENSE"}},
+	})
+	if err := checkNoURLTransfers(def); err == nil {
+		t.Errorf("unexpected success with a URL source")
+	}
+}
+
+func TestDownloadFile(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/LICENSE" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("license\n"))
+	}))
+	defer srv.Close()
+
+	dir, err := ioutil.TempDir("", "files-url-test-")
+	if err != nil {
+		t.Fatalf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootfs := filepath.Join(dir, "rootfs")
+	if err := os.Mkdir(rootfs, 0755); err != nil {
+		t.Fatalf("while creating root filesystem: %s", err)
+	}
+	s := &stage{b: &types.Bundle{RootfsPath: rootfs, TmpDir: dir}}
+
+	tests := []struct {
+		name     string
+		url      string
+		dst      string
+		checksum string
+		want     string
+		wantErr  bool
+	}{
+		{name: "File", url: srv.URL + "/LICENSE", dst: "/opt/license.txt", want: "opt/license.txt"},
+		{name: "Directory", url: srv.URL + "/LICENSE", dst: "/usr/share/", want: "usr/share/LICENSE"},
+		{name: "Checksum", url: srv.URL + "/LICENSE", dst: "/checked", checksum: licenseSHA256, want: "checked"},
+		{name: "ChecksumMismatch", url: srv.URL + "/LICENSE", dst: "/mismatch", checksum: licenseSHA256[:63] + "0", wantErr: true},
+		{name: "NotFound", url: srv.URL + "/missing", dst: "/missing", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := urlTransfer{url: tt.url, dst: tt.dst, checksum: tt.checksum}
+			err := s.downloadFile(context.Background(), u)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				if _, err := os.Stat(filepath.Join(rootfs, tt.dst)); !os.IsNotExist(err) {
+					t.Errorf("destination %s created by a failed download", tt.dst)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			b, err := ioutil.ReadFile(filepath.Join(rootfs, tt.want))
+			if err != nil || string(b) != "license\n" {
+				t.Errorf("unexpected content %q of %s: %v", b, tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// runPost runs script as the %post section or the %post:NAME fragment
+// designated by section. With the VerifyIdempotent build option, the
+// script is run a second time on the resulting root filesystem and the
+// build fails if this second run changed any file.
+func (s *stage) runPost(section string, script types.Script, configFile, sessionResolv, sessionHosts string) error {
+	if err := s.runPostScript(section, script, configFile, sessionResolv, sessionHosts); err != nil {
+		return err
+	}
+	if !s.b.Opts.VerifyIdempotent || script.Script == "" {
+		return nil
+	}
+
+	files, hashes, err := hashRootfs(s.b.RootfsPath)
+	if err != nil {
+		return fmt.Errorf("while scanning root filesystem after %%%s: %s", section, err)
+	}
+
+	sylog.Infof("Running %s scriptlet again to verify idempotency", section)
+	if err := s.runPostScript(section, script, configFile, sessionResolv, sessionHosts); err != nil {
+		return fmt.Errorf("second run of %%%s failed: %s", section, err)
+	}
+
+	changes, err := diffRootfs(files, hashes, s.b.RootfsPath)
+	if err != nil {
+		return fmt.Errorf("while scanning root filesystem after second run of %%%s: %s", section, err)
+	}
+	if len(changes) > 0 {
+		for _, c := range changes {
+			sylog.Errorf("%%%s second run: %s", section, c)
+		}
+		return fmt.Errorf("%%%s is not idempotent, its second run changed %d file(s)", section, len(changes))
+	}
+
+	sylog.Infof("%%%s is idempotent", section)
+	return nil
+}
+
+// hashRootfs returns the state of each entry found in rootfs, indexed by
+// their path relative to rootfs, and the SHA-256 hash of the content of
+// its regular files.
+func hashRootfs(rootfs string) (map[string]fileState, map[string]string, error) {
+	files, err := scanRootfs(rootfs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hashes := make(map[string]string)
+	for rel, state := range files {
+		if !state.mode.IsRegular() {
+			continue
+		}
+		sum, err := fileSHA256(filepath.Join(rootfs, rel))
+		if err != nil {
+			return nil, nil, err
+		}
+		hashes[rel] = sum
+	}
+	return files, hashes, nil
+}
+
+// rootfsChange describes an entry of the root filesystem added, removed
+// or modified by the second run of a script.
+type rootfsChange struct {
+	kind string
+	path string
+}
+
+func (c rootfsChange) String() string {
+	return c.kind + " /" + c.path
+}
+
+// diffRootfs returns the entries of rootfs added, removed or modified
+// since its state files, sorted by path, the content of regular files
+// being compared with hashes. Modification times are ignored, a file
+// rewritten with the same content is not a change, and so is the size
+// of directories.
+func diffRootfs(files map[string]fileState, hashes map[string]string, rootfs string) ([]rootfsChange, error) {
+	current, err := scanRootfs(rootfs)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []rootfsChange
+	for rel, state := range current {
+		old, ok := files[rel]
+		if !ok {
+			changes = append(changes, rootfsChange{"added", rel})
+			continue
+		}
+		if old == state {
+			continue
+		}
+		if old.mode != state.mode || old.uid != state.uid || old.gid != state.gid || old.link != state.link {
+			changes = append(changes, rootfsChange{"modified", rel})
+			continue
+		}
+		if !state.mode.IsRegular() {
+			continue
+		}
+		if old.size != state.size {
+			changes = append(changes, rootfsChange{"modified", rel})
+			continue
+		}
+		sum, err := fileSHA256(filepath.Join(rootfs, rel))
+		if err != nil {
+			return nil, err
+		}
+		if sum != hashes[rel] {
+			changes = append(changes, rootfsChange{"modified", rel})
+		}
+	}
+	for rel := range files {
+		if _, ok := current[rel]; !ok {
+			changes = append(changes, rootfsChange{"removed", rel})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].path < changes[j].path
+	})
+	return changes, nil
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDiffRootfs(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "idempotent-test-")
+	if err != nil {
+		t.Fatalf("while creating temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	write := func(rel, content string) {
+		path := filepath.Join(rootfs, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("while creating directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("while writing %s: %s", rel, err)
+		}
+	}
+	write("etc/rewritten", "same")
+	write("etc/modified", "before")
+	write("etc/removed", "removed")
+	write("etc/mode", "mode")
+
+	files, hashes, err := hashRootfs(rootfs)
+	if err != nil {
+		t.Fatalf("while hashing root filesystem: %s", err)
+	}
+	if changes, err := diffRootfs(files, hashes, rootfs); err != nil || len(changes) != 0 {
+		t.Fatalf("unexpected changes of an unchanged root filesystem %v: %v", changes, err)
+	}
+
+	// a file rewritten with the same content is not a change
+	write("etc/rewritten", "same")
+	future := time.Now().Add(time.Hour)
+	if err := os.Chtimes(filepath.Join(rootfs, "etc", "rewritten"), future, future); err != nil {
+		t.Fatalf("while changing times: %s", err)
+	}
+	// same size, different content
+	write("etc/modified", "after!")
+	write("var/added", "added")
+	if err := os.Remove(filepath.Join(rootfs, "etc", "removed")); err != nil {
+		t.Fatalf("while removing file: %s", err)
+	}
+	if err := os.Chmod(filepath.Join(rootfs, "etc", "mode"), 0600); err != nil {
+		t.Fatalf("while changing mode: %s", err)
+	}
+
+	changes, err := diffRootfs(files, hashes, rootfs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	want := []rootfsChange{
+		{"modified", "etc/mode"},
+		{"modified", "etc/modified"},
+		{"removed", "etc/removed"},
+		{"added", "var"},
+		{"added", "var/added"},
+	}
+	if !reflect.DeepEqual(changes, want) {
+		t.Errorf("got changes %v, want %v", changes, want)
+	}
+}
@@ -0,0 +1,44 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/sylabs/singularity/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// interactivePost runs an interactive shell in the root filesystem after
+// the %post section, or the %post:NAME fragment designated by section,
+// failed with postErr. The shell is started with the singularity exec
+// arguments execArgs of the section, so that its bind mounts, environment
+// and network isolation are live, and they are released once it exits.
+// The build resumes when the shell exits with a zero status, postErr is
+// returned otherwise.
+func (s *stage) interactivePost(section string, execArgs []string, postErr error) error {
+	sylog.Errorf("%%%s script failed: %s", section, postErr)
+	sylog.Infof("Starting an interactive shell in the build root filesystem, the %%%s script is /.post.script", section)
+	sylog.Infof("Exit the shell with status 0 to resume the build, with any other status to abort it")
+
+	args := append([]string{}, execArgs...)
+	args = append(args, "--env", "PS1=Singularity %"+section+"> ", s.b.RootfsPath, "/bin/sh")
+
+	cmd := exec.Command(filepath.Join(buildcfg.BINDIR, "singularity"), args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Dir = "/"
+	cmd.Env = currentEnvNoSingularity()
+	if err := cmd.Run(); err != nil {
+		sylog.Infof("Interactive shell exited with %s, aborting the build", err)
+		return postErr
+	}
+	sylog.Infof("Resuming the build after the %%%s section", section)
+	return nil
+}
@@ -0,0 +1,409 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/sylabs/singularity/internal/pkg/build/assemblers"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"golang.org/x/sys/unix"
+)
+
+// fileState holds the attributes used to detect the entries
+// modified on top of the base layer.
+type fileState struct {
+	mode  os.FileMode
+	size  int64
+	mtime time.Time
+	uid   uint32
+	gid   uint32
+	link  string
+}
+
+// baseLayer records the state of a bootstrapped root filesystem.
+type baseLayer struct {
+	// image is the squashfs image of the base root filesystem
+	image string
+	// digest is the digest of the source image layer stored in image
+	// and lower are the images of the source layers stacked on top of
+	// it, for builds keeping the source layers
+	digest string
+	lower  []assemblers.LayerImage
+	files  map[string]fileState
+}
+
+// createBaseLayer records the bootstrapped root filesystem state and
+// creates its squashfs image.
+func (s *stage) createBaseLayer() error {
+	a, ok := s.a.(*assemblers.SIFAssembler)
+	if !ok {
+		return fmt.Errorf("layered builds are only supported for SIF images")
+	}
+
+	files, err := scanRootfs(s.b.RootfsPath)
+	if err != nil {
+		return fmt.Errorf("while scanning root filesystem: %s", err)
+	}
+	if s.b.Opts.KeepLayers && len(s.b.Layers) > 0 {
+		return s.createSourceLayers(a, files)
+	}
+	image, err := a.CreateBaseLayer(s.b)
+	if err != nil {
+		return err
+	}
+
+	s.base = &baseLayer{
+		image: image,
+		files: files,
+	}
+	return nil
+}
+
+// createSourceLayers creates the squashfs images of the source image
+// layers, the bottom one being the base layer, and records the state of
+// the root filesystem entries they provide. Entries added or modified by
+// the bootstrap on top of the source layers are part of the overlay layer.
+func (s *stage) createSourceLayers(a *assemblers.SIFAssembler, rootfs map[string]fileState) error {
+	// directory of the topmost layer providing each entry
+	provided := make(map[string]string)
+
+	var images []assemblers.LayerImage
+	for i, l := range s.b.Layers {
+		whiteouts, err := stackLayer(provided, l)
+		if err != nil {
+			return fmt.Errorf("while stacking layer %s: %s", l.Digest, err)
+		}
+		// the base layer has nothing to hide
+		if i == 0 {
+			whiteouts = nil
+		}
+		for _, rel := range whiteouts {
+			if err := copyParents(s.b.RootfsPath, l.Dir, rel); err != nil {
+				if err := os.MkdirAll(filepath.Join(l.Dir, filepath.Dir(rel)), 0755); err != nil {
+					return err
+				}
+			}
+		}
+		sylog.Verbosef("Layer %s removes %d path(s) from lower layers", l.Digest, len(whiteouts))
+
+		image, err := a.CreateSourceLayer(s.b, l.Dir, whiteouts)
+		if err != nil {
+			return fmt.Errorf("while creating layer %s: %w", l.Digest, err)
+		}
+		images = append(images, assemblers.LayerImage{Digest: l.Digest, Path: image})
+	}
+
+	files := make(map[string]fileState)
+	for rel, state := range rootfs {
+		dir, ok := provided[rel]
+		if !ok {
+			continue
+		}
+		fi, err := os.Lstat(filepath.Join(dir, rel))
+		if err != nil {
+			return err
+		}
+		if fi.Mode() != state.mode || (fi.Mode().IsRegular() && fi.Size() != state.size) {
+			continue
+		}
+		files[rel] = state
+	}
+
+	s.base = &baseLayer{
+		image:  images[0].Path,
+		digest: images[0].Digest,
+		lower:  images[1:],
+		files:  files,
+	}
+	return nil
+}
+
+// stackLayer records in provided the entries of layer l stacked on top
+// of it and returns the topmost paths of the lower entries removed by l,
+// either explicitly or by hiding the content of an opaque directory.
+func stackLayer(provided map[string]string, l types.Layer) ([]string, error) {
+	removed := append([]string{}, l.Whiteouts...)
+	for _, dir := range l.Opaques {
+		for rel := range provided {
+			if dir != "." && !strings.HasPrefix(rel, dir+"/") {
+				continue
+			}
+			if _, err := os.Lstat(filepath.Join(l.Dir, rel)); os.IsNotExist(err) {
+				removed = append(removed, rel)
+			}
+		}
+	}
+	sort.Strings(removed)
+
+	var whiteouts []string
+	for _, rel := range removed {
+		n := len(whiteouts)
+		if n > 0 && (rel == whiteouts[n-1] || strings.HasPrefix(rel, whiteouts[n-1]+"/")) {
+			continue
+		}
+		whiteouts = append(whiteouts, rel)
+		for p := range provided {
+			if p == rel || strings.HasPrefix(p, rel+"/") {
+				delete(provided, p)
+			}
+		}
+	}
+
+	err := filepath.Walk(l.Dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(l.Dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		provided[rel] = l.Dir
+		return nil
+	})
+
+	return whiteouts, err
+}
+
+// createOverlayLayer copies the changes made on top of the base layer
+// in an upper directory assembled as the SIF overlay partition.
+func (s *stage) createOverlayLayer() error {
+	upper := filepath.Join(s.b.TmpDir, "upper")
+	if err := os.Mkdir(upper, 0755); err != nil {
+		return err
+	}
+
+	whiteouts, err := diffLayer(s.base.files, s.b.RootfsPath, upper)
+	if err != nil {
+		return err
+	}
+	sylog.Verbosef("Overlay layer holds changes on top of the base layer, %d removed path(s)", len(whiteouts))
+
+	s.a.(*assemblers.SIFAssembler).Layers = &assemblers.Layers{
+		Base:       s.base.image,
+		BaseDigest: s.base.digest,
+		Lower:      s.base.lower,
+		Upper:      upper,
+		Whiteouts:  whiteouts,
+	}
+	return nil
+}
+
+// scanRootfs returns the state of each entry found in rootfs, indexed
+// by their path relative to rootfs.
+func scanRootfs(rootfs string) (map[string]fileState, error) {
+	files := make(map[string]fileState)
+
+	err := filepath.Walk(rootfs, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(rootfs, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		state := fileState{
+			mode:  fi.Mode(),
+			size:  fi.Size(),
+			mtime: fi.ModTime(),
+		}
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok {
+			state.uid = st.Uid
+			state.gid = st.Gid
+		}
+		if fi.Mode()&os.ModeSymlink != 0 {
+			state.link, err = os.Readlink(path)
+			if err != nil {
+				return err
+			}
+		}
+		files[rel] = state
+		return nil
+	})
+
+	return files, err
+}
+
+// diffLayer copies in upper the entries of rootfs added or modified
+// on top of the base layer and returns the paths, relative to rootfs,
+// of the entries removed from it. Directories are copied without their
+// unmodified content, which is merged from the base layer at runtime.
+func diffLayer(base map[string]fileState, rootfs, upper string) ([]string, error) {
+	files, err := scanRootfs(rootfs)
+	if err != nil {
+		return nil, fmt.Errorf("while scanning root filesystem: %s", err)
+	}
+
+	changed := make([]string, 0)
+	for rel, state := range files {
+		if old, ok := base[rel]; !ok || old != state {
+			changed = append(changed, rel)
+		}
+	}
+	// parents are sorted before their children
+	sort.Strings(changed)
+
+	for _, rel := range changed {
+		if err := copyParents(rootfs, upper, rel); err != nil {
+			return nil, err
+		}
+		if err := copyEntry(filepath.Join(rootfs, rel), filepath.Join(upper, rel)); err != nil {
+			return nil, fmt.Errorf("while copying %s in overlay layer: %s", rel, err)
+		}
+	}
+
+	var removed []string
+	for rel := range base {
+		if _, ok := files[rel]; !ok {
+			removed = append(removed, rel)
+		}
+	}
+	sort.Strings(removed)
+
+	// only keep the topmost removed entries, the whiteout of a
+	// directory hides its whole content
+	var whiteouts []string
+	for _, rel := range removed {
+		n := len(whiteouts)
+		if n > 0 && strings.HasPrefix(rel, whiteouts[n-1]+"/") {
+			continue
+		}
+		// a parent replaced by a non directory entry already
+		// hides the base layer content
+		if dir := filepath.Dir(rel); dir != "." && !files[dir].mode.IsDir() {
+			continue
+		}
+		if err := copyParents(rootfs, upper, rel); err != nil {
+			return nil, err
+		}
+		whiteouts = append(whiteouts, rel)
+	}
+
+	// restore directory times once their content has been copied
+	var dirs []string
+	err = filepath.Walk(upper, func(path string, fi os.FileInfo, err error) error {
+		if err == nil && fi.IsDir() && path != upper {
+			dirs = append(dirs, path)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := len(dirs) - 1; i >= 0; i-- {
+		rel, _ := filepath.Rel(upper, dirs[i])
+		if err := copyTimes(filepath.Join(rootfs, rel), dirs[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	return whiteouts, nil
+}
+
+// copyParents creates the missing parent directories of rel in upper
+// with the attributes of their rootfs counterpart.
+func copyParents(rootfs, upper, rel string) error {
+	dir := filepath.Dir(rel)
+	if dir == "." {
+		return nil
+	}
+	if _, err := os.Lstat(filepath.Join(upper, dir)); err == nil {
+		return nil
+	}
+	if err := copyParents(rootfs, upper, dir); err != nil {
+		return err
+	}
+	if err := copyEntry(filepath.Join(rootfs, dir), filepath.Join(upper, dir)); err != nil {
+		return fmt.Errorf("while copying %s in overlay layer: %s", dir, err)
+	}
+	return nil
+}
+
+// copyEntry copies the file, directory, symlink or special file src
+// to dst, directory content is not copied.
+func copyEntry(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	st, _ := fi.Sys().(*syscall.Stat_t)
+
+	switch mode := fi.Mode(); {
+	case mode.IsDir():
+		if err := os.Mkdir(dst, mode.Perm()); err != nil && !os.IsExist(err) {
+			return err
+		}
+	case mode.IsRegular():
+		if err := copyFile(src, dst, mode.Perm()); err != nil {
+			return err
+		}
+	case mode&os.ModeSymlink != 0:
+		target, err := os.Readlink(src)
+		if err != nil {
+			return err
+		}
+		if err := os.Symlink(target, dst); err != nil {
+			return err
+		}
+	default:
+		if st == nil {
+			return fmt.Errorf("unsupported file type")
+		}
+		if err := unix.Mknod(dst, st.Mode, int(st.Rdev)); err != nil {
+			return err
+		}
+	}
+
+	if st != nil && os.Geteuid() == 0 {
+		if err := os.Lchown(dst, int(st.Uid), int(st.Gid)); err != nil {
+			return err
+		}
+	}
+	if fi.Mode()&os.ModeSymlink == 0 {
+		// chmod after chown to restore setuid/setgid bits
+		if err := os.Chmod(dst, fi.Mode()&(os.ModePerm|os.ModeSetuid|os.ModeSetgid|os.ModeSticky)); err != nil {
+			return err
+		}
+		if !fi.Mode().IsDir() {
+			return copyTimes(src, dst)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func copyTimes(src, dst string) error {
+	fi, err := os.Lstat(src)
+	if err != nil {
+		return err
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
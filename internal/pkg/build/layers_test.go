@@ -0,0 +1,157 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestDiffLayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layers-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	rootfs := filepath.Join(dir, "rootfs")
+	upper := filepath.Join(dir, "upper")
+
+	write := func(rel, content string) {
+		path := filepath.Join(rootfs, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", path, err)
+		}
+		// make modifications visible even within the
+		// filesystem timestamp granularity
+		old := time.Now().Add(-time.Hour)
+		os.Chtimes(path, old, old)
+	}
+
+	write("etc/os-release", "base")
+	write("etc/unchanged", "base")
+	write("opt/app/bin", "base")
+	write("opt/app/lib", "base")
+	write("var/keep", "base")
+	write("var/remove", "base")
+	if err := os.Mkdir(upper, 0755); err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+
+	base, err := scanRootfs(rootfs)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// %post changes
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "etc/os-release"), []byte("modified"), 0644); err != nil {
+		t.Fatalf("failed to modify file: %s", err)
+	}
+	if err := os.RemoveAll(filepath.Join(rootfs, "opt/app")); err != nil {
+		t.Fatalf("failed to remove directory: %s", err)
+	}
+	if err := os.Remove(filepath.Join(rootfs, "var/remove")); err != nil {
+		t.Fatalf("failed to remove file: %s", err)
+	}
+	if err := os.MkdirAll(filepath.Join(rootfs, "usr/local"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	if err := os.Symlink("/etc/os-release", filepath.Join(rootfs, "usr/local/release")); err != nil {
+		t.Fatalf("failed to create symlink: %s", err)
+	}
+
+	whiteouts, err := diffLayer(base, rootfs, upper)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if want := []string{"opt/app", "var/remove"}; !reflect.DeepEqual(whiteouts, want) {
+		t.Errorf("unexpected whiteouts %v, wanted %v", whiteouts, want)
+	}
+
+	if b, err := ioutil.ReadFile(filepath.Join(upper, "etc/os-release")); err != nil || string(b) != "modified" {
+		t.Errorf("modified file not found in overlay layer: %v", err)
+	}
+	if target, err := os.Readlink(filepath.Join(upper, "usr/local/release")); err != nil || target != "/etc/os-release" {
+		t.Errorf("added symlink not found in overlay layer: %v", err)
+	}
+	for _, rel := range []string{"etc/unchanged", "var/keep"} {
+		if _, err := os.Lstat(filepath.Join(upper, rel)); !os.IsNotExist(err) {
+			t.Errorf("unmodified file %s found in overlay layer", rel)
+		}
+	}
+	// parent directory of the whiteout is required
+	if fi, err := os.Stat(filepath.Join(upper, "opt")); err != nil || !fi.IsDir() {
+		t.Errorf("whiteout parent directory not found in overlay layer: %v", err)
+	}
+}
+
+func TestStackLayer(t *testing.T) {
+	dir, err := ioutil.TempDir("", "layers-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(layer, rel string) string {
+		path := filepath.Join(dir, layer, rel)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(layer), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", path, err)
+		}
+		return filepath.Join(dir, layer)
+	}
+
+	write("0", "etc/passwd")
+	write("0", "opt/app/bin/run")
+	write("0", "opt/app/lib/libapp.so")
+	lower := write("0", "var/remove")
+	upper := write("1", "opt/app/bin/run")
+
+	provided := make(map[string]string)
+	if _, err := stackLayer(provided, types.Layer{Digest: "sha256:0", Dir: lower}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	whiteouts, err := stackLayer(provided, types.Layer{
+		Digest:    "sha256:1",
+		Dir:       upper,
+		Whiteouts: []string{"var/remove"},
+		Opaques:   []string{"opt/app"},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if want := []string{"opt/app/lib", "var/remove"}; !reflect.DeepEqual(whiteouts, want) {
+		t.Errorf("unexpected whiteouts %v, wanted %v", whiteouts, want)
+	}
+	for rel, want := range map[string]string{
+		"etc/passwd":      lower,
+		"opt/app/bin/run": upper,
+		"var":             lower,
+	} {
+		if provided[rel] != want {
+			t.Errorf("%s provided by %q, wanted %q", rel, provided[rel], want)
+		}
+	}
+	for _, rel := range []string{"opt/app/lib", "opt/app/lib/libapp.so", "var/remove"} {
+		if _, ok := provided[rel]; ok {
+			t.Errorf("removed entry %s still provided", rel)
+		}
+	}
+}
@@ -10,13 +10,18 @@ import (
 	"fmt"
 	"io/ioutil"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/sylabs/singularity/internal/pkg/build/sources"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/internal/pkg/util/fs/files"
 	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/build/types/parser"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
 
@@ -56,6 +61,31 @@ func (s *stage) insertMetadata() error {
 		return fmt.Errorf("while inserting test script: %v", err)
 	}
 
+	// insert action scripts
+	if err := insertActionScripts(s.b); err != nil {
+		return fmt.Errorf("while inserting action scripts: %v", err)
+	}
+
+	// insert default bind paths
+	if err := insertDefaultBinds(s.b); err != nil {
+		return fmt.Errorf("while inserting default bind paths: %v", err)
+	}
+
+	// insert annotations
+	if err := insertAnnotations(s.b); err != nil {
+		return fmt.Errorf("while inserting annotations: %v", err)
+	}
+
+	// insert resolved build arguments
+	if err := insertBuildArgs(s.b); err != nil {
+		return fmt.Errorf("while inserting build arguments: %v", err)
+	}
+
+	// remove repeated exports from environment scripts
+	if err := normalizeEnvScripts(s.b); err != nil {
+		return fmt.Errorf("while normalizing environment scripts: %v", err)
+	}
+
 	return nil
 }
 
@@ -86,8 +116,124 @@ func insertEnvScript(b *types.Bundle) error {
 	return nil
 }
 
-// runscript and starscript should use this function to properly handle args and shebangs
-func handleShebangScript(s types.Script) (string, string) {
+// envScriptName matches the environment script names made of a two digit
+// prefix giving their sourcing order, the scripts generated by the builder
+// are sourced in this order:
+//
+//	01-base.sh                 base environment
+//	10-docker2singularity.sh   environment of the docker/OCI source image
+//	90-environment.sh          %environment section
+//	91-environment.sh          variables added to $SINGULARITY_ENVIRONMENT
+//	94-appsbase.sh             SCIF apps variables
+//	95-apps.sh                 environment of the selected SCIF app
+//	99-base.sh                 runtime base environment
+//	99-runtimevars.sh          runtime variables
+var envScriptName = regexp.MustCompile(`^[0-9]{2}-.+\.sh$`)
+
+// normalizeEnvScripts removes the repeated exports from the environment
+// scripts of the container and its SCIF apps, so that appending the same
+// environment again, from a local image or multiple stages, doesn't add
+// duplicate PATH entries.
+func normalizeEnvScripts(b *types.Bundle) error {
+	var scripts []string
+	for _, pattern := range []string{
+		filepath.Join(b.RootfsPath, ".singularity.d", "env", "*.sh"),
+		filepath.Join(b.RootfsPath, "scif", "apps", "*", "scif", "env", "*.sh"),
+	} {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return err
+		}
+		scripts = append(scripts, matches...)
+	}
+
+	for _, script := range scripts {
+		fi, err := os.Lstat(script)
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			continue
+		}
+		if !envScriptName.MatchString(fi.Name()) {
+			sylog.Warningf("Environment script %s has no two digit prefix, its sourcing order may change", fi.Name())
+		}
+
+		content, err := ioutil.ReadFile(script)
+		if err != nil {
+			return err
+		}
+		dedup := dedupExports(string(content))
+		if dedup == string(content) {
+			continue
+		}
+		sylog.Debugf("Removing repeated exports from %s", script)
+		if err := ioutil.WriteFile(script, []byte(dedup), fi.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dedupExports removes from script the unindented export lines identical
+// to the previous export of the same variable, unless the variable was
+// referenced or a control structure was found in between.
+func dedupExports(script string) string {
+	lines := strings.SplitAfter(script, "\n")
+	last := make(map[string]string)
+	kept := lines[:0]
+
+	for _, line := range lines {
+		trimmed := strings.TrimRight(line, "\n")
+		name := ""
+		if strings.HasPrefix(trimmed, "export ") {
+			name = strings.TrimPrefix(trimmed, "export ")
+			if i := strings.Index(name, "="); i >= 0 {
+				name = name[:i]
+			}
+			if last[name] == trimmed {
+				continue
+			}
+		}
+		if name == "" && isShellControl(trimmed) {
+			last = make(map[string]string)
+		}
+		for n := range last {
+			if n != name && strings.Contains(line, n) {
+				delete(last, n)
+			}
+		}
+		if name != "" {
+			last[name] = trimmed
+		}
+		kept = append(kept, line)
+	}
+	return strings.Join(kept, "")
+}
+
+// shellControl holds the shell keywords starting or separating the
+// branches of control structures.
+var shellControl = map[string]bool{
+	"if": true, "then": true, "elif": true, "else": true, "fi": true,
+	"case": true, "esac": true, "for": true, "while": true, "until": true,
+	"do": true, "done": true, "{": true, "}": true, "(": true, ")": true,
+}
+
+// isShellControl returns if line starts with a shell control keyword or
+// a case pattern, or ends with a line continuation.
+func isShellControl(line string) bool {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return false
+	}
+	first := strings.TrimRight(fields[0], ";")
+	return shellControl[first] || first == "" || strings.HasSuffix(first, ")") || strings.HasSuffix(line, "\\")
+}
+
+// runscript and starscript should use this function to properly handle args and shebangs,
+// a "-c interpreter [args...]" section argument takes precedence over a shebang found in
+// the section content and is written as the script shebang
+func handleShebangScript(opts types.Options, name string, s types.Script) (string, string, error) {
 	shebang := "#!/bin/sh"
 	script := ""
 	if strings.HasPrefix(strings.TrimSpace(s.Script), "#!") {
@@ -101,21 +247,54 @@ func handleShebangScript(s types.Script) (string, string) {
 		script = s.Script
 	}
 
-	if s.Args != "" {
-		// add arg after trimming comments
-		shebang += " " + strings.Split(s.Args, "#")[0]
+	// trim comments from args
+	args := strings.TrimSpace(strings.Split(s.Args, "#")[0])
+	params := strings.Fields(args)
+
+	for i, param := range params {
+		if param != "-c" {
+			continue
+		}
+		if len(params)-1 < i+1 {
+			return "", "", fmt.Errorf("bad %s section '-c' parameter: missing interpreter", name)
+		} else if !filepath.IsAbs(params[i+1]) {
+			return "", "", fmt.Errorf("bad %s section '-c' parameter: interpreter %s must be an absolute path", name, params[i+1])
+		}
+		if strings.HasPrefix(strings.TrimSpace(s.Script), "#!") {
+			err := opts.Warnf(types.WarnShebangOverride, "%s section shebang %q overridden by '-c %s'", name, shebang, strings.Join(params[i+1:], " "))
+			if err != nil {
+				return "", "", err
+			}
+		}
+		return "#!" + strings.Join(params[i+1:], " "), script, nil
+	}
+
+	if args != "" {
+		shebang += " " + args
 	}
-	return shebang, script
+	return shebang, script, nil
 }
 
 func insertRunScript(b *types.Bundle) error {
 	if b.RunSection("runscript") && b.Recipe.ImageData.Runscript.Script != "" {
 		sylog.Infof("Adding runscript")
-		shebang, script := handleShebangScript(b.Recipe.ImageData.Runscript)
-		err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/runscript"), []byte(shebang+"\n\n"+script+"\n"), 0755)
+		shebang, script, err := handleShebangScript(b.Opts, "runscript", b.Recipe.ImageData.Runscript)
 		if err != nil {
 			return err
 		}
+		if b.Opts.NoRunscriptWrap {
+			if script, err = execScript(b.Opts, "runscript", shebang, script); err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(b.RootfsPath, "/.singularity.d/runscript")
+		if err := ioutil.WriteFile(path, []byte(shebang+"\n\n"+script+"\n"), 0755); err != nil {
+			return err
+		}
+		// an existing runscript keeps its permissions with WriteFile
+		if err := os.Chmod(path, 0755); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -123,11 +302,23 @@ func insertRunScript(b *types.Bundle) error {
 func insertStartScript(b *types.Bundle) error {
 	if b.RunSection("startscript") && b.Recipe.ImageData.Startscript.Script != "" {
 		sylog.Infof("Adding startscript")
-		shebang, script := handleShebangScript(b.Recipe.ImageData.Startscript)
-		err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/startscript"), []byte(shebang+"\n\n"+script+"\n"), 0755)
+		shebang, script, err := handleShebangScript(b.Opts, "startscript", b.Recipe.ImageData.Startscript)
 		if err != nil {
 			return err
 		}
+		if b.Opts.NoRunscriptWrap {
+			if script, err = execScript(b.Opts, "startscript", shebang, script); err != nil {
+				return err
+			}
+		}
+		path := filepath.Join(b.RootfsPath, "/.singularity.d/startscript")
+		if err := ioutil.WriteFile(path, []byte(shebang+"\n\n"+script+"\n"), 0755); err != nil {
+			return err
+		}
+		// an existing startscript keeps its permissions with WriteFile
+		if err := os.Chmod(path, 0755); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -143,17 +334,143 @@ func insertTestScript(b *types.Bundle) error {
 	return nil
 }
 
+// insertActionScripts replaces the generated action scripts by the ones
+// of the %actions NAME sections, whose interpreter must be an absolute
+// path to an executable of the image.
+func insertActionScripts(b *types.Bundle) error {
+	if !b.RunSection("actions") {
+		return nil
+	}
+	for _, a := range b.Recipe.ImageData.Actions {
+		sylog.Infof("Adding %s action script", a.Name)
+		section := "actions " + a.Name
+		shebang, script, err := handleShebangScript(b.Opts, section, a.Script)
+		if err != nil {
+			return err
+		}
+		fields := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+		if len(fields) == 0 {
+			return fmt.Errorf("%%%s section has an empty shebang", section)
+		} else if !filepath.IsAbs(fields[0]) {
+			return fmt.Errorf("%%%s section interpreter %s must be an absolute path", section, fields[0])
+		} else if !programExists(b.RootfsPath, fields[0], false) {
+			return fmt.Errorf("%%%s section interpreter %s is missing from the image", section, fields[0])
+		}
+		path := filepath.Join(b.RootfsPath, "/.singularity.d/actions", a.Name)
+		content := shebang + "\n" + files.CustomActionMarker + "\n\n" + script + "\n"
+		if err := ioutil.WriteFile(path, []byte(content), 0755); err != nil {
+			return err
+		}
+		// the generated action scripts keep their permissions with WriteFile
+		if err := os.Chmod(path, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertDefaultBinds(b *types.Bundle) error {
+	if len(b.Opts.DefaultBinds) == 0 {
+		return nil
+	}
+	sylog.Infof("Adding default bind paths")
+	data, err := json.Marshal(b.Opts.DefaultBinds)
+	if err != nil {
+		return err
+	}
+	b.JSONObjects[types.DefaultBindsJSON] = data
+	return nil
+}
+
+// insertBuildArgs stores the values of the build arguments referenced by
+// the definition file in the build arguments JSON object of the bundle, so
+// that inspect reports the values the image was built with.
+func insertBuildArgs(b *types.Bundle) error {
+	names := parser.ReferencedVars(string(b.Recipe.Raw))
+	if len(names) == 0 {
+		return nil
+	}
+	args := make(map[string]string, len(names))
+	for _, name := range names {
+		if v, ok := b.Opts.BuildVars[name]; ok {
+			args[name] = v
+		}
+	}
+	if len(args) == 0 {
+		return nil
+	}
+	sylog.Infof("Adding build arguments")
+	data, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	b.JSONObjects[types.BuildArgsJSON] = data
+	return nil
+}
+
+// helpFile returns the path of the file providing the container help, either
+// set by --help-file or passed as argument of the %help section.
+func helpFile(b *types.Bundle) string {
+	if b.Opts.HelpFile != "" {
+		return b.Opts.HelpFile
+	}
+	// trim potential trailing comment from section arguments, a
+	// comment starts with a '#' preceded by a blank so that paths
+	// containing '#' are preserved
+	args := b.Recipe.ImageData.Help.Args
+	if loc := commentRegexp.FindStringIndex(args); loc != nil {
+		args = args[:loc[0]]
+	}
+	return strings.TrimSpace(args)
+}
+
+// commentRegexp matches the start of a trailing comment in section arguments.
+var commentRegexp = regexp.MustCompile(`(^|\s)#`)
+
+// hasHelp returns true if the bundle provides container help.
+func hasHelp(b *types.Bundle) bool {
+	return b.RunSection("help") && (b.Recipe.ImageData.Help.Script != "" || helpFile(b) != "")
+}
+
+// helpScript returns the container help content with build variables
+// substituted, the content is left unchanged if there is no build variable.
+func helpScript(b *types.Bundle) (string, error) {
+	help := b.Recipe.ImageData.Help.Script + "\n"
+
+	if path := helpFile(b); path != "" {
+		if b.Opts.HelpFile == "" && strings.TrimSpace(b.Recipe.ImageData.Help.Script) != "" {
+			if err := b.Opts.Warnf(types.WarnHelpOverride, "%%help section references %s, ignoring its inline content", path); err != nil {
+				return "", err
+			}
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("while reading help file: %v", err)
+		}
+		help = string(content)
+	}
+
+	if len(b.Opts.BuildVars) == 0 {
+		return help, nil
+	}
+	return parser.SubstituteVars(help, b.Opts.BuildVars)
+}
+
 func insertHelpScript(b *types.Bundle) error {
-	if b.RunSection("help") && b.Recipe.ImageData.Help.Script != "" {
+	if hasHelp(b) {
 		_, err := os.Stat(filepath.Join(b.RootfsPath, "/.singularity.d/runscript.help"))
 		if err != nil || b.Opts.Force {
 			sylog.Infof("Adding help info")
-			err := ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/runscript.help"), []byte(b.Recipe.ImageData.Help.Script+"\n"), 0644)
+			help, err := helpScript(b)
+			if err != nil {
+				return err
+			}
+			err = ioutil.WriteFile(filepath.Join(b.RootfsPath, "/.singularity.d/runscript.help"), []byte(help), 0644)
 			if err != nil {
 				return err
 			}
 		} else {
-			sylog.Warningf("Help message already exists and force option is false, not overwriting")
+			return b.Opts.Warnf(types.WarnHelpExists, "Help message already exists and force option is false, not overwriting")
 		}
 	}
 	return nil
@@ -214,13 +531,21 @@ func insertLabelsJSON(b *types.Bundle) (err error) {
 
 		// add new labels to new map and check for collisions
 		for key, value := range b.Recipe.ImageData.Labels {
-			// check if label already exists
-			if _, ok := labels[key]; ok {
+			value, err := labelValue(b, key, value)
+			if err != nil {
+				return err
+			}
+			key = prefixLabel(b.Opts.LabelPrefix, key)
+			// check if label already exists, the generated build
+			// date being replaced by an explicit one
+			if _, ok := labels[key]; ok && key != buildDateLabel {
 				// overwrite collision if it exists and force flag is set
 				if b.Opts.Force {
 					labels[key] = value
 				} else {
-					sylog.Warningf("Label: %s already exists and force option is false, not overwriting", key)
+					if err := b.Opts.Warnf(types.WarnLabelExists, "Label: %s already exists and force option is false, not overwriting", key); err != nil {
+						return err
+					}
 				}
 			} else {
 				// set if it doesnt
@@ -229,6 +554,17 @@ func insertLabelsJSON(b *types.Bundle) (err error) {
 		}
 	}
 
+	if b.RunSection("labels") && len(b.Opts.Labels) > 0 {
+		sylog.Infof("Adding labels from labels file")
+		for key, value := range b.Opts.Labels {
+			key = prefixLabel(b.Opts.LabelPrefix, key)
+			if old, ok := labels[key]; ok && old != value {
+				sylog.Verbosef("Label %s value %q overridden by labels file", key, old)
+			}
+			labels[key] = value
+		}
+	}
+
 	// make new map into json
 	text, err = json.MarshalIndent(labels, "", "\t")
 	if err != nil {
@@ -239,6 +575,126 @@ func insertLabelsJSON(b *types.Bundle) (err error) {
 	return err
 }
 
+// ReadLabelsFile returns the labels of the JSON file found at path, which
+// must hold a single object of string values.
+func ReadLabelsFile(path string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON object: %v", path, err)
+	}
+	if obj == nil {
+		return nil, fmt.Errorf("%s is not a JSON object", path)
+	}
+
+	labels := make(map[string]string, len(obj))
+	for key, value := range obj {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("label %s in %s is not a string", key, path)
+		}
+		if key == "" {
+			return nil, fmt.Errorf("empty label name in %s", path)
+		}
+		labels[key] = s
+	}
+	return labels, nil
+}
+
+// labelPrefixRe matches a reverse domain name label prefix, made of at
+// least two dot separated components and ending with a dot.
+var labelPrefixRe = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)+\.$`)
+
+// CheckLabelPrefix returns the --label-prefix prefix with a trailing dot,
+// added if missing, or an error if prefix is not a reverse domain name such
+// as com.example. The org.label-schema namespace, reserved for the built-in
+// labels, is rejected.
+func CheckLabelPrefix(prefix string) (string, error) {
+	if !strings.HasSuffix(prefix, ".") {
+		prefix += "."
+	}
+	if !labelPrefixRe.MatchString(prefix) {
+		return "", fmt.Errorf("label prefix %s is not a lowercase reverse domain name such as com.example", prefix)
+	}
+	if strings.HasPrefix(prefix, "org.label-schema.") {
+		return "", fmt.Errorf("label prefix %s is reserved for the built-in labels", prefix)
+	}
+	return prefix, nil
+}
+
+// prefixLabel returns the name of the label key in the prefix namespace,
+// key being returned as is if it's already part of it or if it's part of
+// the org.label-schema namespace of the built-in labels.
+func prefixLabel(prefix, key string) string {
+	if strings.HasPrefix(key, prefix) || strings.HasPrefix(key, "org.label-schema.") {
+		return key
+	}
+	return prefix + key
+}
+
+// labelValue returns the computed value of the label key. Build variables
+// are substituted when build arguments are given, then a value of the form
+// '< PATH' is read from the host file PATH and a value of the form
+// '$(COMMAND)' is the output of COMMAND executed on the host, only allowed
+// with the AllowLabelExec build option.
+func labelValue(b *types.Bundle, key, value string) (string, error) {
+	if len(b.Opts.BuildVars) > 0 {
+		v, err := parser.SubstituteVars(value, b.Opts.BuildVars)
+		if err != nil {
+			return "", fmt.Errorf("while computing label %s: %v", key, err)
+		}
+		value = v
+	}
+
+	switch {
+	case strings.HasPrefix(value, "< "):
+		path, err := hostFilePath(b.Opts, strings.TrimSpace(value[1:]))
+		if err != nil {
+			return "", fmt.Errorf("while reading label %s value: %v", key, err)
+		}
+		sylog.Verbosef("Reading label %s from file %s", key, path)
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("while reading label %s value: %v", key, err)
+		}
+		return strings.TrimSpace(string(content)), nil
+	case strings.HasPrefix(value, "$(") && strings.HasSuffix(value, ")"):
+		if !b.Opts.AllowLabelExec {
+			return "", fmt.Errorf("label %s value is computed by a command, which requires --allow-label-exec", key)
+		}
+		command := value[2 : len(value)-1]
+		sylog.Verbosef("Computing label %s with command: %s", key, command)
+		out, err := exec.Command("/bin/sh", "-c", command).Output()
+		if err != nil {
+			return "", fmt.Errorf("while computing label %s with %q: %v", key, command, err)
+		}
+		return strings.TrimSpace(string(out)), nil
+	}
+
+	return value, nil
+}
+
+// hostFilePath returns the path of a host file referenced by the
+// definition, as a label value or a data file, a relative path being
+// resolved from the build context, or from the directory of the
+// definition file without build context.
+func hostFilePath(opts types.Options, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		return path, nil
+	}
+	dir := opts.BuildContext
+	if dir == "" {
+		dir = opts.DefinitionDir
+	}
+	if dir == "" {
+		return "", fmt.Errorf("relative path %s requires a definition file or --build-context", path)
+	}
+	return filepath.Join(dir, path), nil
+}
+
 func getExistingLabels(labels map[string]string, b *types.Bundle) error {
 	// check for existing labels in bundle
 	if _, err := os.Stat(filepath.Join(b.RootfsPath, "/.singularity.d/labels.json")); err == nil {
@@ -261,25 +717,37 @@ func getExistingLabels(labels map[string]string, b *types.Bundle) error {
 	return nil
 }
 
+// buildDateLabel is the label holding the build date, as set by
+// addBuildLabels unless given by the definition or the labels file.
+const buildDateLabel = "org.label-schema.build-date"
+
 func addBuildLabels(labels map[string]string, b *types.Bundle) error {
 	// schema version
 	labels["org.label-schema.schema-version"] = "1.0"
 
 	// build date and time, lots of time formatting
-	currentTime := time.Now()
+	currentTime := b.Opts.BuildTime()
 	year, month, day := currentTime.Date()
 	date := strconv.Itoa(day) + `_` + month.String() + `_` + strconv.Itoa(year)
 	hour, min, sec := currentTime.Clock()
 	time := strconv.Itoa(hour) + `:` + strconv.Itoa(min) + `:` + strconv.Itoa(sec)
 	zone, _ := currentTime.Zone()
 	timeString := currentTime.Weekday().String() + `_` + date + `_` + time + `_` + zone
-	labels["org.label-schema.build-date"] = timeString
+	labels[buildDateLabel] = timeString
 
 	// singularity version
 	labels["org.label-schema.usage.singularity.version"] = buildcfg.PACKAGE_VERSION
 
+	// squashfs compression of SIF images
+	if b.Opts.Compression != "" {
+		labels["org.label-schema.usage.singularity.squashfs.compression"] = b.Opts.Compression
+		if b.Opts.CompressionLevel != 0 {
+			labels["org.label-schema.usage.singularity.squashfs.compression-level"] = strconv.Itoa(b.Opts.CompressionLevel)
+		}
+	}
+
 	// help info if help exists in the definition and is run in the build
-	if b.RunSection("help") && b.Recipe.ImageData.Help.Script != "" {
+	if hasHelp(b) {
 		labels["org.label-schema.usage"] = "/.singularity.d/runscript.help"
 		labels["org.label-schema.usage.singularity.runscript.help"] = "/.singularity.d/runscript.help"
 	}
@@ -289,6 +757,22 @@ func addBuildLabels(labels map[string]string, b *types.Bundle) error {
 		for key, value := range b.Recipe.Header {
 			labels["org.label-schema.usage.singularity.deffile."+key] = value
 		}
+		// library images are recorded with the library host
+		// short references were resolved against
+		if b.Recipe.Header["bootstrap"] == "library" {
+			labels["org.label-schema.usage.singularity.library.ref"] = sources.LibrarySourceRef(b)
+		}
+		// docker images are recorded with the default registry
+		// and tag applied
+		if b.Recipe.Header["bootstrap"] == "docker" {
+			if ref, _, err := sources.DockerSourceRef(b.Recipe); err == nil {
+				labels["org.label-schema.usage.singularity.docker.ref"] = ref
+			}
+		}
+		// provenance of the bootstrap image verified with --verify-base
+		if b.VerifiedBase != "" {
+			labels["org.label-schema.usage.singularity.verified-base.digest"] = b.VerifiedBase
+		}
 	}
 
 	return nil
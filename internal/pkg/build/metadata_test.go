@@ -0,0 +1,401 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs/files"
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestLabelValue(t *testing.T) {
+	f, err := ioutil.TempFile("", "label-")
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString("1.2.3\n"); err != nil {
+		t.Fatalf("failed to write label file: %s", err)
+	}
+	f.Close()
+
+	tests := []struct {
+		name      string
+		value     string
+		vars      map[string]string
+		allowExec bool
+		defDir    string
+		want      string
+		wantErr   bool
+	}{
+		{
+			name:  "Plain",
+			value: "value",
+			want:  "value",
+		},
+		{
+			name:  "BuildVariable",
+			value: "sha-{{ GIT_SHA }}",
+			vars:  map[string]string{"GIT_SHA": "abc123"},
+			want:  "sha-abc123",
+		},
+		{
+			name:    "UndefinedBuildVariable",
+			value:   "{{ UNDEFINED }}",
+			vars:    map[string]string{"GIT_SHA": "abc123"},
+			wantErr: true,
+		},
+		{
+			name:  "File",
+			value: "< " + f.Name(),
+			want:  "1.2.3",
+		},
+		{
+			name:  "AngleBrackets",
+			value: "<me@example.org>",
+			want:  "<me@example.org>",
+		},
+		{
+			name:   "RelativeFile",
+			value:  "< " + filepath.Base(f.Name()),
+			defDir: filepath.Dir(f.Name()),
+			want:   "1.2.3",
+		},
+		{
+			name:    "RelativeFileWithoutDefinition",
+			value:   "< " + filepath.Base(f.Name()),
+			wantErr: true,
+		},
+		{
+			name:  "FileFromBuildVariable",
+			value: "< {{ VERSION_FILE }}",
+			vars:  map[string]string{"VERSION_FILE": f.Name()},
+			want:  "1.2.3",
+		},
+		{
+			name:    "MissingFile",
+			value:   "< /non/existent/file",
+			wantErr: true,
+		},
+		{
+			name:    "CommandNotAllowed",
+			value:   "$(echo computed)",
+			wantErr: true,
+		},
+		{
+			name:      "Command",
+			value:     "$(echo computed)",
+			allowExec: true,
+			want:      "computed",
+		},
+		{
+			name:      "FailingCommand",
+			value:     "$(false)",
+			allowExec: true,
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		b := &types.Bundle{
+			Opts: types.Options{
+				BuildVars:      tt.vars,
+				AllowLabelExec: tt.allowExec,
+				DefinitionDir:  tt.defDir,
+			},
+		}
+		v, err := labelValue(b, "label", tt.value)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: unexpected success", tt.name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+		} else if v != tt.want {
+			t.Errorf("%s: unexpected value %q, wanted %q", tt.name, v, tt.want)
+		}
+	}
+}
+
+func TestDedupExports(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   string
+	}{
+		{
+			name:   "NoExport",
+			script: "#!/bin/sh\n\nFOO=bar\n",
+			want:   "#!/bin/sh\n\nFOO=bar\n",
+		},
+		{
+			name:   "RepeatedPath",
+			script: "#!/bin/sh\nexport PATH=\"/opt/bin:$PATH\"\nexport FOO=bar\n\nexport PATH=\"/opt/bin:$PATH\"\nexport FOO=bar\n",
+			want:   "#!/bin/sh\nexport PATH=\"/opt/bin:$PATH\"\nexport FOO=bar\n\n",
+		},
+		{
+			name:   "Overridden",
+			script: "export FOO=bar\nexport FOO=baz\nexport FOO=bar\n",
+			want:   "export FOO=bar\nexport FOO=baz\nexport FOO=bar\n",
+		},
+		{
+			name:   "ReferencedInBetween",
+			script: "export FOO=bar\nBAR=$FOO\nexport FOO=bar\n",
+			want:   "export FOO=bar\nBAR=$FOO\nexport FOO=bar\n",
+		},
+		{
+			name:   "ControlStructure",
+			script: "if test -d /opt; then\nexport FOO=bar\nelse\nexport FOO=bar\nfi\n",
+			want:   "if test -d /opt; then\nexport FOO=bar\nelse\nexport FOO=bar\nfi\n",
+		},
+		{
+			name:   "Indented",
+			script: "export FOO=bar\n    export FOO=bar\n",
+			want:   "export FOO=bar\n    export FOO=bar\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := dedupExports(tt.script); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLabelsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "labels-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	tests := []struct {
+		name    string
+		content string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:    "Flat",
+			content: `{"org.example.commit": "abc123", "org.example.tool": "prov"}`,
+			want:    map[string]string{"org.example.commit": "abc123", "org.example.tool": "prov"},
+		},
+		{
+			name:    "Empty",
+			content: `{}`,
+			want:    map[string]string{},
+		},
+		{
+			name:    "Nested",
+			content: `{"org.example": {"commit": "abc123"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "Number",
+			content: `{"org.example.build": 42}`,
+			wantErr: true,
+		},
+		{
+			name:    "Array",
+			content: `["abc123"]`,
+			wantErr: true,
+		},
+		{
+			name:    "Null",
+			content: `null`,
+			wantErr: true,
+		},
+		{
+			name:    "EmptyName",
+			content: `{"": "abc123"}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write labels file: %s", err)
+			}
+			got, err := ReadLabelsFile(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckLabelPrefix(t *testing.T) {
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{prefix: "com.acme.", want: "com.acme."},
+		{prefix: "com.acme", want: "com.acme."},
+		{prefix: "io.github.team-1.", want: "io.github.team-1."},
+		{prefix: "acme.", wantErr: true},
+		{prefix: "com..acme.", wantErr: true},
+		{prefix: "com.-acme.", wantErr: true},
+		{prefix: "Com.Acme.", wantErr: true},
+		{prefix: "com.acme.tool_", wantErr: true},
+		{prefix: "org.label-schema.", wantErr: true},
+		{prefix: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := CheckLabelPrefix(tt.prefix)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: unexpected error: %v", tt.prefix, err)
+		} else if got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
+
+func TestInsertBuildArgs(t *testing.T) {
+	b := &types.Bundle{JSONObjects: make(map[string][]byte)}
+	b.Recipe.Raw = []byte("Bootstrap: docker\nFrom: alpine:{{ VERSION }}\n\n%post\n    echo {{ FLAVOR }}\n")
+	b.Opts.BuildVars = map[string]string{"VERSION": "3.12", "FLAVOR": "slim", "UNUSED": "value"}
+
+	if err := insertBuildArgs(b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	var args map[string]string
+	if err := json.Unmarshal(b.JSONObjects[types.BuildArgsJSON], &args); err != nil {
+		t.Fatalf("failed to decode build arguments: %s", err)
+	}
+	if want := map[string]string{"VERSION": "3.12", "FLAVOR": "slim"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("got build arguments %v, want %v", args, want)
+	}
+
+	b = &types.Bundle{JSONObjects: make(map[string][]byte)}
+	b.Recipe.Raw = []byte("Bootstrap: docker\nFrom: alpine\n")
+	if err := insertBuildArgs(b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if _, ok := b.JSONObjects[types.BuildArgsJSON]; ok {
+		t.Errorf("unexpected build arguments object without references")
+	}
+}
+
+func TestPrefixLabel(t *testing.T) {
+	const prefix = "com.acme."
+
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{key: "version", want: "com.acme.version"},
+		{key: "com.acme.version", want: "com.acme.version"},
+		{key: "org.label-schema.build-arch", want: "org.label-schema.build-arch"},
+		{key: "org.label-schema-version", want: "com.acme.org.label-schema-version"},
+	}
+
+	for _, tt := range tests {
+		if got := prefixLabel(prefix, tt.key); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestInsertActionScripts(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "actions-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	for _, dir := range []string{"bin", ".singularity.d/actions"} {
+		if err := os.MkdirAll(filepath.Join(rootfs, dir), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "bin", "sh"), nil, 0755); err != nil {
+		t.Fatalf("failed to write interpreter: %s", err)
+	}
+	// generated action script
+	run := filepath.Join(rootfs, ".singularity.d", "actions", "run")
+	if err := ioutil.WriteFile(run, []byte("#!/bin/sh\n"), 0644); err != nil {
+		t.Fatalf("failed to write action script: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		action  types.Action
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "Default",
+			action: types.Action{Name: "run", Script: types.Script{Script: "exec /bin/sh"}},
+			want:   "#!/bin/sh\n" + files.CustomActionMarker + "\n\nexec /bin/sh\n",
+		},
+		{
+			name:   "Shebang",
+			action: types.Action{Name: "run", Script: types.Script{Script: "#!/bin/sh -e\nexec /bin/sh"}},
+			want:   "#!/bin/sh -e\n" + files.CustomActionMarker + "\n\nexec /bin/sh\n",
+		},
+		{
+			name:   "Interpreter",
+			action: types.Action{Name: "run", Script: types.Script{Args: "-c /bin/sh", Script: "exec /bin/sh"}},
+			want:   "#!/bin/sh\n" + files.CustomActionMarker + "\n\nexec /bin/sh\n",
+		},
+		{
+			name:    "MissingInterpreter",
+			action:  types.Action{Name: "run", Script: types.Script{Script: "#!/bin/bash\nexec /bin/bash"}},
+			wantErr: true,
+		},
+		{
+			name:    "RelativeInterpreter",
+			action:  types.Action{Name: "run", Script: types.Script{Script: "#!sh\nexec sh"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &types.Bundle{RootfsPath: rootfs}
+			b.Opts.Sections = []string{"all"}
+			b.Recipe.ImageData.Actions = []types.Action{tt.action}
+
+			err := insertActionScripts(b)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			path := filepath.Join(rootfs, ".singularity.d", "actions", tt.action.Name)
+			content, err := ioutil.ReadFile(path)
+			if err != nil {
+				t.Fatalf("failed to read action script: %s", err)
+			}
+			if string(content) != tt.want {
+				t.Errorf("got action script %q, want %q", content, tt.want)
+			}
+			if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0755 {
+				t.Errorf("unexpected action script mode: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"github.com/containers/image/v5/oci/layout"
+	"github.com/containers/image/v5/transports"
+	"github.com/containers/image/v5/types"
+	"github.com/opencontainers/go-digest"
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// CachedReference returns the reference of the image src in the cache,
+// without querying its source, for builds from the cache only. An error
+// naming the image, or the missing blob, is returned if the image was not
+// fetched into the cache before for the platform selected by sys.
+func CachedReference(ctx context.Context, imgCache *cache.Handle, src types.ImageReference, sys *types.SystemContext) (types.ImageReference, error) {
+	if imgCache == nil {
+		return nil, fmt.Errorf("undefined image cache")
+	}
+
+	tag, err := imgCache.LookupRef(cache.OciBlobCacheType, cacheRef(src, sys))
+	if err != nil {
+		return nil, err
+	}
+	cacheDir, err := imgCache.GetOciCacheDir(cache.OciBlobCacheType)
+	if err != nil {
+		return nil, err
+	}
+	c, err := layout.ParseReference(cacheDir + ":" + tag)
+	if err != nil {
+		return nil, err
+	}
+
+	img, err := c.NewImage(ctx, sys)
+	if err != nil {
+		return nil, fmt.Errorf("%s is not in the %s cache: %v", cacheRef(src, sys), cache.OciBlobCacheType, err)
+	}
+	defer img.Close()
+
+	blobs := []digest.Digest{img.ConfigInfo().Digest}
+	for _, l := range img.LayerInfos() {
+		blobs = append(blobs, l.Digest)
+	}
+	for _, d := range blobs {
+		path := filepath.Join(cacheDir, "blobs", d.Algorithm().String(), d.Hex())
+		if _, err := os.Stat(path); err != nil {
+			return nil, fmt.Errorf("blob %s of %s is missing from the %s cache", d, transports.ImageName(src), cache.OciBlobCacheType)
+		}
+	}
+
+	sylog.Infof("Using cached image %s", transports.ImageName(src))
+	return c, nil
+}
+
+// recordRef records the cache tag of the image src fetched into the
+// cache. Only registry images are recorded, other sources are local and
+// don't need to be found without querying them.
+func recordRef(imgCache *cache.Handle, src types.ImageReference, sys *types.SystemContext, tag string) {
+	if imgCache == nil || src.Transport().Name() != "docker" {
+		return
+	}
+	if err := imgCache.SetRef(cache.OciBlobCacheType, cacheRef(src, sys), tag); err != nil {
+		sylog.Warningf("Could not record cache reference of %s: %v", transports.ImageName(src), err)
+	}
+}
+
+// cacheRef returns the reference recorded in the cache for the image src,
+// with the platform selected by sys as the cache holds an image per
+// platform for multi-platform images.
+func cacheRef(src types.ImageReference, sys *types.SystemContext) string {
+	arch, variant := runtime.GOARCH, ""
+	if sys != nil {
+		if sys.ArchitectureChoice != "" {
+			arch = sys.ArchitectureChoice
+		}
+		variant = sys.VariantChoice
+	}
+	platform := "linux/" + arch
+	if variant != "" {
+		platform += "/" + variant
+	}
+	return fmt.Sprintf("%s (%s)", transports.ImageName(src), platform)
+}
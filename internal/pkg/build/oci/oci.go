@@ -11,21 +11,36 @@ import (
 	"crypto/sha256"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
 	"github.com/containers/image/v5/oci/layout"
 	"github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/transports"
 	"github.com/containers/image/v5/types"
 	"github.com/pkg/errors"
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
 	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/internal/pkg/client"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/util/fs/lock"
 )
 
 // ImageReference wraps containers/image ImageReference type
 type ImageReference struct {
 	source types.ImageReference
+	// cacheDir is the OCI cache directory, locked while
+	// the source image is fetched into the cache
+	cacheDir string
+	// imgCache and cacheTag record the source reference
+	// of the fetched image in the cache reference index
+	imgCache *cache.Handle
+	cacheTag string
 	types.ImageReference
 }
 
@@ -54,6 +69,9 @@ func ConvertReference(ctx context.Context, imgCache *cache.Handle, src types.Ima
 
 	return &ImageReference{
 		source:         src,
+		cacheDir:       cacheDir,
+		imgCache:       imgCache,
+		cacheTag:       cacheTag,
 		ImageReference: c,
 	}, nil
 
@@ -71,17 +89,78 @@ func (t *ImageReference) newImageSource(ctx context.Context, sys *types.SystemCo
 		return nil, err
 	}
 
-	// First we are fetching into the cache
-	_, err = copy.Image(ctx, policyCtx, t.ImageReference, t.source, &copy.Options{
+	// The cache is shared by concurrent builds (e.g. build --batch),
+	// serialize fetches so that they don't update the OCI layout index
+	// at the same time
+	fd, err := lock.Exclusive(t.cacheDir)
+	if err != nil {
+		return nil, fmt.Errorf("while locking cache directory %s: %s", t.cacheDir, err)
+	}
+
+	opts := &copy.Options{
 		ReportWriter: w,
 		SourceCtx:    sys,
-	})
+	}
+	var done chan struct{}
+	if progress.Enabled() {
+		ch := make(chan types.ProgressProperties)
+		opts.Progress = ch
+		opts.ProgressInterval = time.Second
+		done = make(chan struct{})
+		go func() {
+			reportLayerProgress(ch)
+			close(done)
+		}()
+	}
+
+	// First we are fetching into the cache, the blobs it didn't hold
+	// yet are counted as downloaded
+	cached := blobSizes(t.cacheDir)
+	_, err = copy.Image(ctx, policyCtx, t.ImageReference, t.source, opts)
+	for digest, size := range blobSizes(t.cacheDir) {
+		if _, ok := cached[digest]; !ok {
+			client.CountDownload(size)
+		}
+	}
+	lock.Release(fd)
+	if done != nil {
+		close(opts.Progress)
+		<-done
+	}
 	if err != nil {
 		return nil, err
 	}
+	recordRef(t.imgCache, t.source, sys, t.cacheTag)
 	return t.ImageReference.NewImageSource(ctx, sys)
 }
 
+// blobSizes returns the size of the blobs of the OCI layout dir indexed
+// by digest.
+func blobSizes(dir string) map[string]int64 {
+	sizes := make(map[string]int64)
+	fis, err := ioutil.ReadDir(filepath.Join(dir, "blobs", "sha256"))
+	if err != nil {
+		return sizes
+	}
+	for _, fi := range fis {
+		sizes[fi.Name()] = fi.Size()
+	}
+	return sizes
+}
+
+// reportLayerProgress emits the layer-download-progress events of the
+// layers fetched into the cache until ch is closed.
+func reportLayerProgress(ch <-chan types.ProgressProperties) {
+	for p := range ch {
+		progress.Emit(progress.Event{
+			Type:    progress.LayerDownloadProgress,
+			Layer:   p.Artifact.Digest.String(),
+			Current: int64(p.Offset),
+			Total:   p.Artifact.Size,
+		})
+	}
+}
+
 // ParseImageName parses a uri (e.g. docker://ubuntu) into it's transport:reference
 // combination and then returns the proper reference
 func ParseImageName(ctx context.Context, imgCache *cache.Handle, uri string, sys *types.SystemContext) (types.ImageReference, error) {
@@ -130,7 +209,7 @@ func calculateRefHash(ctx context.Context, ref types.ImageReference, sys *types.
 		}
 	}()
 
-	man, _, err := source.GetManifest(ctx, nil)
+	man, err := platformManifest(ctx, source, sys)
 	if err != nil {
 		return "", err
 	}
@@ -138,3 +217,45 @@ func calculateRefHash(ctx context.Context, ref types.ImageReference, sys *types.
 	hash = fmt.Sprintf("%x", sha256.Sum256(man))
 	return hash, nil
 }
+
+// platformManifest returns the manifest of the image instance matching
+// the platform selected by sys, the host platform by default, so that
+// cache entries of multi-platform images are specific to a platform.
+// An error is returned if the requested architecture is not available.
+func platformManifest(ctx context.Context, source types.ImageSource, sys *types.SystemContext) ([]byte, error) {
+	man, mimeType, err := source.GetManifest(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.MIMETypeIsMultiImage(mimeType) {
+		list, err := manifest.ListFromBlob(man, mimeType)
+		if err != nil {
+			return nil, err
+		}
+		instance, err := list.ChooseInstance(sys)
+		if err != nil {
+			return nil, fmt.Errorf("requested platform not available: %s", err)
+		}
+		sylog.Debugf("Selected manifest %s from manifest list", instance)
+		man, _, err = source.GetManifest(ctx, &instance)
+		return man, err
+	}
+
+	// single platform image, check its architecture when requested
+	if sys == nil || sys.ArchitectureChoice == "" {
+		return man, nil
+	}
+	img, err := image.FromUnparsedImage(ctx, sys, image.UnparsedInstance(source, nil))
+	if err != nil {
+		return nil, err
+	}
+	info, err := img.Inspect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if info.Architecture != sys.ArchitectureChoice {
+		return nil, fmt.Errorf("requested architecture %s not available: image is a single %s image", sys.ArchitectureChoice, info.Architecture)
+	}
+	return man, nil
+}
@@ -0,0 +1,59 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sylabs/sif/pkg/sif"
+)
+
+// File systems of the primary system partition of SIF images.
+const (
+	FsTypeSquashfs = "squashfs"
+	FsTypeExt3     = "ext3"
+)
+
+// checkPartitionOptions checks the file system type, size and name of
+// the primary system partition requested for the build.
+func checkPartitionOptions(conf Config) error {
+	switch conf.Opts.FsType {
+	case "", FsTypeSquashfs:
+		if conf.Opts.FsSize != 0 {
+			return fmt.Errorf("a partition size can only be set for %s partitions", FsTypeExt3)
+		}
+	case FsTypeExt3:
+		if conf.Format != "sif" {
+			return fmt.Errorf("%s partitions are only supported for SIF images", FsTypeExt3)
+		}
+		if conf.Opts.Layered {
+			return fmt.Errorf("layered builds only support %s partitions", FsTypeSquashfs)
+		}
+		if conf.Opts.EncryptionKeyInfo != nil {
+			return fmt.Errorf("encrypted images only support %s partitions", FsTypeSquashfs)
+		}
+		if conf.Opts.FsSize < 0 {
+			return fmt.Errorf("bad partition size %d", conf.Opts.FsSize)
+		}
+	default:
+		return fmt.Errorf("unsupported partition file system %q: must be %s or %s", conf.Opts.FsType, FsTypeSquashfs, FsTypeExt3)
+	}
+
+	if name := conf.Opts.PartitionName; name != "" {
+		if conf.Format != "sif" {
+			return fmt.Errorf("a partition name can only be set for SIF images")
+		}
+		if len(name) >= sif.DescrNameLen {
+			return fmt.Errorf("partition name %q is longer than %d characters", name, sif.DescrNameLen-1)
+		}
+		if strings.ContainsAny(name, "/\x00") {
+			return fmt.Errorf("partition name %q contains a forbidden character", name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestCheckPartitionOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		format  string
+		opts    types.Options
+		wantErr bool
+	}{
+		{name: "Default", format: "sif"},
+		{name: "Squashfs", format: "sif", opts: types.Options{FsType: "squashfs"}},
+		{name: "Ext3", format: "sif", opts: types.Options{FsType: "ext3"}},
+		{name: "Ext3Size", format: "sif", opts: types.Options{FsType: "ext3", FsSize: 256 << 20}},
+		{name: "Ext3Sandbox", format: "sandbox", opts: types.Options{FsType: "ext3"}, wantErr: true},
+		{name: "Ext3Layered", format: "sif", opts: types.Options{FsType: "ext3", Layered: true}, wantErr: true},
+		{name: "SquashfsSize", format: "sif", opts: types.Options{FsSize: 256 << 20}, wantErr: true},
+		{name: "Unsupported", format: "sif", opts: types.Options{FsType: "xfs"}, wantErr: true},
+		{name: "Name", format: "sif", opts: types.Options{PartitionName: "rootfs"}},
+		{name: "NameSandbox", format: "sandbox", opts: types.Options{PartitionName: "rootfs"}, wantErr: true},
+		{name: "NameSlash", format: "sif", opts: types.Options{PartitionName: "root/fs"}, wantErr: true},
+		{name: "NameTooLong", format: "sif", opts: types.Options{PartitionName: strings.Repeat("a", 128)}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkPartitionOptions(Config{Format: tt.format, Opts: tt.opts})
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/internal/pkg/util/machine"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// PlatformsIndexName is the name of the SIF JSON object listing the
+// system partitions of a multi-architecture image.
+const PlatformsIndexName = "platforms.json"
+
+// PlatformEntry describes a system partition of a multi-architecture
+// image in the platforms index.
+type PlatformEntry struct {
+	// Platform is the partition platform, as linux/arm64.
+	Platform string `json:"platform"`
+	// ID is the SIF descriptor ID of the partition.
+	ID uint32 `json:"id"`
+	// Primary is true for the primary system partition.
+	Primary bool `json:"primary,omitempty"`
+}
+
+// foreignArchSources are the bootstrap agents able to fetch a base
+// image for an architecture other than the host one.
+var foreignArchSources = map[string]bool{
+	"docker":  true,
+	"library": true,
+	"scratch": true,
+}
+
+// ParsePlatform returns the architecture of a platform with the
+// format linux/ARCH.
+func ParsePlatform(platform string) (string, error) {
+	fields := strings.Split(platform, "/")
+	if len(fields) != 2 || fields[0] != "linux" {
+		return "", fmt.Errorf("bad platform %q: must be of the form linux/ARCH", platform)
+	}
+	if sif.GetSIFArch(fields[1]) == sif.HdrArchUnknown {
+		return "", fmt.Errorf("bad platform %q: unsupported architecture %s", platform, fields[1])
+	}
+	return fields[1], nil
+}
+
+// ReadPlatformManifest returns the base image digest of each requested
+// platform, indexed by architecture, from the platform manifest found at
+// path. The manifest is a JSON object mapping platforms, as linux/arm64,
+// to the SHA-256 digest of their base image, as sha256:<hex>. Platforms
+// not requested are ignored, an error is returned if a requested platform
+// is missing.
+func ReadPlatformManifest(path string, platforms []string) (map[string]string, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var manifest map[string]string
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, fmt.Errorf("%s is not a JSON object of string values: %v", path, err)
+	}
+
+	digests := make(map[string]string, len(manifest))
+	for platform, digest := range manifest {
+		arch, err := ParsePlatform(platform)
+		if err != nil {
+			return nil, fmt.Errorf("in %s: %v", path, err)
+		}
+		if err := checkDigest(digest); err != nil {
+			return nil, fmt.Errorf("in %s: platform %s: %v", path, platform, err)
+		}
+		digests[arch] = digest
+	}
+
+	var missing []string
+	for _, platform := range platforms {
+		arch, err := ParsePlatform(platform)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := digests[arch]; !ok {
+			missing = append(missing, platform)
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("%s has no base image digest for platform(s) %s", path, strings.Join(missing, ", "))
+	}
+	return digests, nil
+}
+
+// checkDigest returns an error if digest is not a SHA-256 digest with
+// the format sha256:<hex>.
+func checkDigest(digest string) error {
+	h := strings.TrimPrefix(digest, "sha256:")
+	if b, err := hex.DecodeString(h); h == digest || err != nil || len(b) != 32 {
+		return fmt.Errorf("bad digest %q: must be of the form sha256:<hex>", digest)
+	}
+	return nil
+}
+
+// pinBaseDigest replaces the tag or digest of the docker and library base
+// image references of defs with digest, so that the base image is pulled
+// by digest rather than resolved from its tag. All the pinned stages must
+// bootstrap from the same image.
+func pinBaseDigest(defs []types.Definition, digest string) error {
+	var base string
+	for _, d := range defs {
+		if bs := d.Header["bootstrap"]; bs != "docker" && bs != "library" {
+			continue
+		}
+		name := baseImageName(d.Header["from"])
+		if base != "" && name != base {
+			return fmt.Errorf("base image digest can't be applied to several base images: %s and %s", base, name)
+		}
+		base = name
+		d.Header["from"] = name + "@" + digest
+		sylog.Verbosef("Bootstrapping from %s", d.Header["from"])
+	}
+	if base == "" {
+		return fmt.Errorf("base image digest requires a docker or library bootstrap")
+	}
+	return nil
+}
+
+// baseImageName returns the image reference ref without its tag and
+// digest.
+func baseImageName(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		ref = ref[:i]
+	}
+	return ref
+}
+
+// checkForeignArch checks that the definitions can be built for the
+// foreign architecture arch, the %post and %test sections require the
+// host to emulate arch.
+func checkForeignArch(defs []types.Definition, opts types.Options, arch string) error {
+	for _, d := range defs {
+		if bs := d.Header["bootstrap"]; !foreignArchSources[bs] {
+			return fmt.Errorf("bootstrap agent %s doesn't support building for the %s architecture", bs, arch)
+		}
+		scripts := d.BuildData.Post.Script != "" || len(d.BuildData.PostFragments) > 0 || (!opts.NoTest && d.BuildData.Test.Script != "")
+		if scripts && !machine.CompatibleWith(arch) {
+			return fmt.Errorf("%%post and %%test sections require emulation of the %s architecture, not enabled in /proc/sys/fs/binfmt_misc", arch)
+		}
+	}
+	return nil
+}
+
+// MergePlatforms adds to the SIF image dst the primary system partition
+// of each SIF image found in images, as a system partition tagged with
+// its architecture, along with a platforms index. The primary system
+// partition of dst is left unchanged, the runtime selects the partition
+// matching the host architecture.
+func MergePlatforms(dst string, images []string) error {
+	f, err := sif.LoadContainer(dst, false)
+	if err != nil {
+		return fmt.Errorf("while loading SIF image %s: %s", dst, err)
+	}
+	defer f.UnloadContainer()
+
+	prim, _, err := f.GetPartPrimSys()
+	if err != nil {
+		return fmt.Errorf("while looking for primary partition of %s: %s", dst, err)
+	}
+	index := []PlatformEntry{
+		{Platform: "linux/" + partitionArch(prim), ID: prim.ID, Primary: true},
+	}
+
+	for _, img := range images {
+		arch, err := addPlatformPartition(&f, img)
+		if err != nil {
+			return fmt.Errorf("while adding %s system partition: %s", img, err)
+		}
+		for _, d := range f.DescrArr {
+			if d.Used && d.Datatype == sif.DataPartition && d.GetName() == "rootfs-"+arch {
+				index = append(index, PlatformEntry{Platform: "linux/" + arch, ID: d.ID})
+			}
+		}
+		sylog.Verbosef("Added linux/%s system partition", arch)
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	input := sif.DescriptorInput{
+		Datatype: sif.DataGenericJSON,
+		Groupid:  sif.DescrDefaultGroup,
+		Link:     sif.DescrUnusedLink,
+		Data:     data,
+		Size:     int64(len(data)),
+		Fname:    PlatformsIndexName,
+	}
+	return f.AddObject(input)
+}
+
+// addPlatformPartition copies the primary system partition of the SIF
+// image found at path in f as a system partition and returns its
+// architecture.
+func addPlatformPartition(f *sif.FileImage, path string) (string, error) {
+	src, err := sif.LoadContainer(path, true)
+	if err != nil {
+		return "", err
+	}
+	defer src.UnloadContainer()
+
+	prim, _, err := src.GetPartPrimSys()
+	if err != nil {
+		return "", err
+	}
+	fstype, err := prim.GetFsType()
+	if err != nil {
+		return "", err
+	}
+	arch := partitionArch(prim)
+
+	fp, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+
+	input := sif.DescriptorInput{
+		Datatype: sif.DataPartition,
+		Groupid:  sif.DescrDefaultGroup,
+		Link:     sif.DescrUnusedLink,
+		Fp:       io.NewSectionReader(fp, prim.Fileoff, prim.Filelen),
+		Size:     prim.Filelen,
+		Fname:    "rootfs-" + arch,
+	}
+	if err := input.SetPartExtra(fstype, sif.PartSystem, sif.GetSIFArch(arch)); err != nil {
+		return "", err
+	}
+	return arch, f.AddObject(input)
+}
+
+// partitionArch returns the Go architecture of a partition descriptor.
+func partitionArch(d *sif.Descriptor) string {
+	arch, err := d.GetArch()
+	if err != nil {
+		return ""
+	}
+	return sif.GetGoArch(string(arch[:sif.HdrArchLen-1]))
+}
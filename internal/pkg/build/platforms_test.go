@@ -0,0 +1,227 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestParsePlatform(t *testing.T) {
+	tests := []struct {
+		name     string
+		platform string
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "AMD64",
+			platform: "linux/amd64",
+			want:     "amd64",
+		},
+		{
+			name:     "ARM64",
+			platform: "linux/arm64",
+			want:     "arm64",
+		},
+		{
+			name:     "Variant",
+			platform: "linux/arm/v7",
+			wantErr:  true,
+		},
+		{
+			name:     "OS",
+			platform: "windows/amd64",
+			wantErr:  true,
+		},
+		{
+			name:     "UnknownArch",
+			platform: "linux/sparc",
+			wantErr:  true,
+		},
+		{
+			name:     "Empty",
+			platform: "",
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			arch, err := ParsePlatform(tt.platform)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if arch != tt.want {
+				t.Errorf("got %s instead of %s", arch, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadPlatformManifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "platform-manifest-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	amd64 := "sha256:" + strings.Repeat("a", 64)
+	arm64 := "sha256:" + strings.Repeat("b", 64)
+
+	tests := []struct {
+		name      string
+		content   string
+		platforms []string
+		want      map[string]string
+		wantErr   bool
+	}{
+		{
+			name:      "All",
+			content:   `{"linux/amd64": "` + amd64 + `", "linux/arm64": "` + arm64 + `"}`,
+			platforms: []string{"linux/amd64", "linux/arm64"},
+			want:      map[string]string{"amd64": amd64, "arm64": arm64},
+		},
+		{
+			name:      "Subset",
+			content:   `{"linux/amd64": "` + amd64 + `", "linux/arm64": "` + arm64 + `"}`,
+			platforms: []string{"linux/arm64"},
+			want:      map[string]string{"amd64": amd64, "arm64": arm64},
+		},
+		{
+			name:      "Missing",
+			content:   `{"linux/amd64": "` + amd64 + `"}`,
+			platforms: []string{"linux/amd64", "linux/arm64"},
+			wantErr:   true,
+		},
+		{
+			name:      "BadPlatform",
+			content:   `{"linux/arm/v7": "` + arm64 + `"}`,
+			platforms: []string{"linux/arm64"},
+			wantErr:   true,
+		},
+		{
+			name:      "Tag",
+			content:   `{"linux/amd64": "20.04"}`,
+			platforms: []string{"linux/amd64"},
+			wantErr:   true,
+		},
+		{
+			name:      "ShortDigest",
+			content:   `{"linux/amd64": "sha256:abcd"}`,
+			platforms: []string{"linux/amd64"},
+			wantErr:   true,
+		},
+		{
+			name:      "Array",
+			content:   `["` + amd64 + `"]`,
+			platforms: []string{"linux/amd64"},
+			wantErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name+".json")
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatalf("failed to write platform manifest: %s", err)
+			}
+			got, err := ReadPlatformManifest(path, tt.platforms)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPinBaseDigest(t *testing.T) {
+	digest := "sha256:" + strings.Repeat("a", 64)
+
+	tests := []struct {
+		name    string
+		headers []map[string]string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "DockerTag",
+			headers: []map[string]string{{"bootstrap": "docker", "from": "ubuntu:20.04"}},
+			want:    []string{"ubuntu@" + digest},
+		},
+		{
+			name:    "DockerRegistryPort",
+			headers: []map[string]string{{"bootstrap": "docker", "from": "localhost:5000/ubuntu"}},
+			want:    []string{"localhost:5000/ubuntu@" + digest},
+		},
+		{
+			name:    "DockerDigest",
+			headers: []map[string]string{{"bootstrap": "docker", "from": "ubuntu:20.04@sha256:" + strings.Repeat("b", 64)}},
+			want:    []string{"ubuntu@" + digest},
+		},
+		{
+			name:    "Library",
+			headers: []map[string]string{{"bootstrap": "library", "from": "library://alpine:3.11"}},
+			want:    []string{"library://alpine@" + digest},
+		},
+		{
+			name: "MultiStage",
+			headers: []map[string]string{
+				{"bootstrap": "docker", "from": "ubuntu:20.04"},
+				{"bootstrap": "localimage", "from": "/tmp/base.sif"},
+			},
+			want: []string{"ubuntu@" + digest, "/tmp/base.sif"},
+		},
+		{
+			name: "SeveralBases",
+			headers: []map[string]string{
+				{"bootstrap": "docker", "from": "ubuntu:20.04"},
+				{"bootstrap": "docker", "from": "alpine:3.12"},
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Scratch",
+			headers: []map[string]string{{"bootstrap": "scratch"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defs := make([]types.Definition, len(tt.headers))
+			for i, h := range tt.headers {
+				defs[i].Header = h
+			}
+			err := pinBaseDigest(defs, digest)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if tt.wantErr {
+				return
+			}
+			for i, d := range defs {
+				if d.Header["from"] != tt.want[i] {
+					t.Errorf("stage %d: got %s, want %s", i, d.Header["from"], tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,194 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sylabs/singularity/internal/pkg/build/files"
+	"github.com/sylabs/singularity/internal/pkg/cache"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/image/packer"
+	"github.com/sylabs/singularity/pkg/image/unpacker"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// postFragmentKeys returns the cache keys of the %post:NAME fragments.
+// The key of a fragment is computed over its name, arguments, script and
+// the digest envDigests[i] of the content of its --env-file, empty
+// without one, chained with the key of the previous fragment, the first
+// one being chained with the digest base of the root filesystem before
+// %post. A change in a fragment, in its environment file or in the root
+// filesystem it runs on, changes the keys of this fragment and of all
+// the following ones.
+func postFragmentKeys(base string, fragments []types.PostFragment, envDigests []string) []string {
+	keys := make([]string, len(fragments))
+
+	prev := base
+	for i, f := range fragments {
+		h := sha256.New()
+		fmt.Fprintf(h, "%s\n%s\n%s\n%s\n%s", prev, f.Name, f.Args, envDigests[i], f.Script.Script)
+		keys[i] = hex.EncodeToString(h.Sum(nil))
+		prev = keys[i]
+	}
+
+	return keys
+}
+
+// runPostFragments runs the %post:NAME fragments in order. With the image
+// cache enabled, the root filesystem is restored from the snapshot of the
+// last fragment found in the cache, only the following fragments are run
+// and a snapshot is cached after each of them. Snapshots are not restored
+// with the VerifyIdempotent build option, so that every fragment is run.
+// The placeholders created in the root filesystem as bind targets are not
+// part of the snapshots.
+func (s *stage) runPostFragments(configFile, sessionResolv, sessionHosts string, placeholders []string) error {
+	fragments := s.b.Recipe.BuildData.PostFragments
+	imgCache := s.b.Opts.ImgCache
+
+	start := 0
+	var keys []string
+	if imgCache != nil && !imgCache.IsDisabled() {
+		base, err := files.HashPath(s.b.RootfsPath)
+		if err != nil {
+			return fmt.Errorf("while hashing root filesystem: %s", err)
+		}
+		envDigests, err := s.postEnvDigests(fragments)
+		if err != nil {
+			return err
+		}
+		keys = postFragmentKeys(base, fragments, envDigests)
+
+		if !s.b.Opts.VerifyIdempotent {
+			start, err = s.restorePostFragments(imgCache, keys, placeholders)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i := start; i < len(fragments); i++ {
+		f := fragments[i]
+		if err := s.runPost("post:"+f.Name, f.Script, configFile, sessionResolv, sessionHosts); err != nil {
+			return err
+		}
+		if keys != nil {
+			s.cachePostFragment(imgCache, keys[i], f.Name, placeholders)
+		}
+	}
+
+	return nil
+}
+
+// postEnvDigests returns the digests of the content of the --env-file of
+// the %post:NAME fragments, empty for fragments without one.
+func (s *stage) postEnvDigests(fragments []types.PostFragment) ([]string, error) {
+	digests := make([]string, len(fragments))
+	for i, f := range fragments {
+		envArgs, _, err := s.envFileArgs("post", f.Script)
+		if err != nil {
+			return nil, err
+		}
+		if len(envArgs) == 0 {
+			continue
+		}
+		if digests[i], err = fileSHA256(envArgs[1]); err != nil {
+			return nil, fmt.Errorf("while hashing %%post:%s environment file: %s", f.Name, err)
+		}
+	}
+	return digests, nil
+}
+
+// restorePostFragments restores the root filesystem from the snapshot
+// of the last fragment found in the cache and returns the index of the
+// first fragment to run.
+func (s *stage) restorePostFragments(imgCache *cache.Handle, keys []string, placeholders []string) (int, error) {
+	fragments := s.b.Recipe.BuildData.PostFragments
+
+	for i := len(keys) - 1; i >= 0; i-- {
+		e, err := imgCache.GetEntry(cache.PostCacheType, keys[i])
+		if err != nil {
+			return 0, fmt.Errorf("unable to check if %%post:%s snapshot exists in cache: %v", fragments[i].Name, err)
+		}
+		if !e.Exists {
+			e.CleanTmp()
+			continue
+		}
+
+		sylog.Infof("Using cached root filesystem after %%post:%s, skipping %d fragment(s)", fragments[i].Name, i+1)
+		if err := restoreRootfs(e.Path, s.b.RootfsPath); err != nil {
+			return 0, fmt.Errorf("while restoring %%post:%s snapshot: %s", fragments[i].Name, err)
+		}
+		// the placeholders are bind targets of the remaining fragments
+		for _, p := range placeholders {
+			f, err := os.OpenFile(p, os.O_CREATE|os.O_WRONLY, 0644)
+			if err != nil {
+				return 0, err
+			}
+			f.Close()
+		}
+		return i + 1, nil
+	}
+
+	return 0, nil
+}
+
+// cachePostFragment stores a snapshot of the root filesystem after the
+// fragment name under key. A failure to create the snapshot doesn't fail
+// the build, the fragment will be run again by the next build.
+func (s *stage) cachePostFragment(imgCache *cache.Handle, key, name string, placeholders []string) {
+	e, err := imgCache.GetEntry(cache.PostCacheType, key)
+	if err != nil {
+		sylog.Warningf("Unable to cache %%post:%s snapshot: %v", name, err)
+		return
+	} else if e.Exists {
+		return
+	}
+
+	opts := []string{"-noappend", "-xattrs"}
+	for _, p := range placeholders {
+		if rel, err := filepath.Rel(s.b.RootfsPath, p); err == nil {
+			opts = append(opts, "-e", rel)
+		}
+	}
+
+	sylog.Debugf("Caching root filesystem after %%post:%s in %s", name, e.Path)
+	if err := packer.NewSquashfs().Create([]string{s.b.RootfsPath}, e.TmpPath, opts); err != nil {
+		e.CleanTmp()
+		sylog.Warningf("Unable to cache %%post:%s snapshot: %v", name, err)
+		return
+	}
+	if err := e.Finalize(); err != nil {
+		sylog.Warningf("Unable to cache %%post:%s snapshot: %v", name, err)
+	}
+}
+
+// restoreRootfs replaces the content of the root filesystem rootfs with
+// the content of the squashfs image.
+func restoreRootfs(image, rootfs string) error {
+	entries, err := ioutil.ReadDir(rootfs)
+	if err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if err := os.RemoveAll(filepath.Join(rootfs, e.Name())); err != nil {
+			return err
+		}
+	}
+
+	f, err := os.Open(image)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	return unpacker.NewSquashfs().ExtractAll(f, rootfs)
+}
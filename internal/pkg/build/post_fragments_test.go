@@ -0,0 +1,63 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestPostFragmentKeys(t *testing.T) {
+	fragment := func(name, script string) types.PostFragment {
+		return types.PostFragment{Name: name, Script: types.Script{Script: script}}
+	}
+	fragments := []types.PostFragment{
+		fragment("base", "apk add python3"),
+		fragment("deps", "pip3 install numpy"),
+		fragment("app", "echo hello"),
+	}
+	envDigests := make([]string, len(fragments))
+	keys := postFragmentKeys("base-digest", fragments, envDigests)
+
+	tests := []struct {
+		name string
+		base string
+		// changed is the index of the changed fragment, -1 if none
+		changed int
+		script  string
+		// envDigest is the changed environment file digest of the
+		// changed fragment, its script is changed when empty
+		envDigest string
+		// firstChanged is the index of the first key expected to change
+		firstChanged int
+	}{
+		{name: "Unchanged", base: "base-digest", changed: -1, firstChanged: 3},
+		{name: "ChangedBase", base: "other-digest", changed: -1, firstChanged: 0},
+		{name: "ChangedFirst", base: "base-digest", changed: 0, script: "apk add python3 git", firstChanged: 0},
+		{name: "ChangedMiddle", base: "base-digest", changed: 1, script: "pip3 install scipy", firstChanged: 1},
+		{name: "ChangedLast", base: "base-digest", changed: 2, script: "echo world", firstChanged: 2},
+		{name: "ChangedEnvFile", base: "base-digest", changed: 1, envDigest: "env-digest", firstChanged: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := append([]types.PostFragment{}, fragments...)
+			changedDigests := make([]string, len(fragments))
+			if tt.changed >= 0 && tt.envDigest != "" {
+				changedDigests[tt.changed] = tt.envDigest
+			} else if tt.changed >= 0 {
+				changed[tt.changed].Script.Script = tt.script
+			}
+			got := postFragmentKeys(tt.base, changed, changedDigests)
+			for i := range keys {
+				if same := got[i] == keys[i]; same != (i < tt.firstChanged) {
+					t.Errorf("key of fragment %d: unchanged %v, want %v", i, same, i < tt.firstChanged)
+				}
+			}
+		})
+	}
+}
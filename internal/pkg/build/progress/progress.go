@@ -0,0 +1,122 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+// Package progress emits the build progress events requested with
+// --progress json, as newline-delimited JSON objects.
+package progress
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Types of the build progress events.
+const (
+	SectionStart          = "section-start"
+	SectionEnd            = "section-end"
+	LayerDownloadProgress = "layer-download-progress"
+	PackagingProgress     = "packaging-progress"
+	Warning               = "warning"
+	Error                 = "error"
+)
+
+// Status of section-end and packaging-progress events.
+const (
+	StatusStarted = "started"
+	StatusSuccess = "success"
+	StatusFailure = "failure"
+)
+
+// Event is a build progress event, only the fields relevant to its type
+// are set.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Type     string    `json:"type"`
+	Stage    string    `json:"stage,omitempty"`
+	Section  string    `json:"section,omitempty"`
+	Step     string    `json:"step,omitempty"`
+	Status   string    `json:"status,omitempty"`
+	Duration float64   `json:"duration,omitempty"`
+	Layer    string    `json:"layer,omitempty"`
+	Current  int64     `json:"current,omitempty"`
+	Total    int64     `json:"total,omitempty"`
+	Code     string    `json:"code,omitempty"`
+	Message  string    `json:"message,omitempty"`
+}
+
+var (
+	mu     sync.Mutex
+	writer io.Writer
+)
+
+// SetWriter enables the events, written to w, or disables them if w is nil.
+func SetWriter(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+	writer = w
+}
+
+// Enabled returns if the events are enabled.
+func Enabled() bool {
+	mu.Lock()
+	defer mu.Unlock()
+	return writer != nil
+}
+
+// Emit writes the event e, stamped with the current time, on its own line
+// when the events are enabled.
+func Emit(e Event) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if writer == nil {
+		return
+	}
+	e.Time = time.Now().UTC()
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+	writer.Write(append(b, '\n'))
+}
+
+// Section runs the section of stage with run between section-start and
+// section-end events, the latter reporting the status, the duration and
+// the error returned by run. The duration is recorded for Sections.
+func Section(stage, section string, run func() error) error {
+	Emit(Event{Type: SectionStart, Stage: stage, Section: section})
+
+	start := time.Now()
+	err := run()
+	d := time.Since(start)
+	recordSection(stage, section, d)
+
+	e := Event{
+		Type:     SectionEnd,
+		Stage:    stage,
+		Section:  section,
+		Status:   StatusSuccess,
+		Duration: d.Seconds(),
+	}
+	if err != nil {
+		e.Status = StatusFailure
+		e.Message = err.Error()
+	}
+	Emit(e)
+
+	return err
+}
+
+// Packaging emits a packaging-progress event of the packaging step, size
+// is the size in bytes of the data produced by the step, if known, and
+// is recorded for Written on success.
+func Packaging(step, status string, size int64) {
+	if status == StatusSuccess {
+		recordWritten(size)
+	}
+	Emit(Event{Type: PackagingProgress, Step: step, Status: status, Current: size})
+}
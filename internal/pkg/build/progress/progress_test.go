@@ -0,0 +1,77 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package progress
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func events(t *testing.T, b *bytes.Buffer) []Event {
+	var evs []Event
+	s := bufio.NewScanner(b)
+	for s.Scan() {
+		var e Event
+		if err := json.Unmarshal(s.Bytes(), &e); err != nil {
+			t.Fatalf("bad event line %q: %v", s.Text(), err)
+		}
+		if e.Time.IsZero() {
+			t.Errorf("event %q without timestamp", s.Text())
+		}
+		evs = append(evs, e)
+	}
+	return evs
+}
+
+func TestDisabled(t *testing.T) {
+	SetWriter(nil)
+	if Enabled() {
+		t.Fatalf("events enabled without writer")
+	}
+	// must not panic
+	Emit(Event{Type: Warning})
+	if err := Section("", "post", func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestSection(t *testing.T) {
+	var b bytes.Buffer
+	SetWriter(&b)
+	defer SetWriter(nil)
+
+	if err := Section("devel", "post", func() error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	failure := errors.New("exit status 1")
+	if err := Section("", "test", func() error { return failure }); err != failure {
+		t.Fatalf("got error %v, want %v", err, failure)
+	}
+	Packaging("squashfs", StatusSuccess, 4096)
+
+	want := []Event{
+		{Type: SectionStart, Stage: "devel", Section: "post"},
+		{Type: SectionEnd, Stage: "devel", Section: "post", Status: StatusSuccess},
+		{Type: SectionStart, Section: "test"},
+		{Type: SectionEnd, Section: "test", Status: StatusFailure, Message: "exit status 1"},
+		{Type: PackagingProgress, Step: "squashfs", Status: StatusSuccess, Current: 4096},
+	}
+
+	got := events(t, &b)
+	if len(got) != len(want) {
+		t.Fatalf("got %d events, want %d", len(got), len(want))
+	}
+	for i, e := range got {
+		e.Time = want[i].Time
+		e.Duration = 0
+		if e != want[i] {
+			t.Errorf("event %d: got %+v, want %+v", i, e, want[i])
+		}
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package progress
+
+import (
+	"sync"
+	"time"
+)
+
+// SectionStat is the wall time of a section run by Section.
+type SectionStat struct {
+	Stage    string  `json:"stage,omitempty"`
+	Section  string  `json:"section"`
+	Duration float64 `json:"duration"`
+}
+
+var (
+	statsMu  sync.Mutex
+	sections []SectionStat
+	written  int64
+)
+
+// recordSection records the wall time d of the section of stage.
+func recordSection(stage, section string, d time.Duration) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	sections = append(sections, SectionStat{Stage: stage, Section: section, Duration: d.Seconds()})
+}
+
+// recordWritten adds size to the bytes written by the packaging steps.
+func recordWritten(size int64) {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	written += size
+}
+
+// Sections returns the wall time of the sections run by Section, in the
+// order they completed, whether the events are enabled or not.
+func Sections() []SectionStat {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	return append([]SectionStat(nil), sections...)
+}
+
+// Written returns the number of bytes written by the successful
+// packaging steps reporting their size.
+func Written() int64 {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+	return written
+}
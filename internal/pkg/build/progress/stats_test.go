@@ -0,0 +1,42 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package progress
+
+import (
+	"testing"
+	"time"
+)
+
+func TestStats(t *testing.T) {
+	// stats are recorded with the events disabled
+	SetWriter(nil)
+
+	n := len(Sections())
+	written := Written()
+
+	err := Section("build", "post", func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	Packaging("squashfs", StatusStarted, 0)
+	Packaging("squashfs", StatusSuccess, 4096)
+	Packaging("sif", StatusFailure, 0)
+
+	sections := Sections()
+	if len(sections) != n+1 {
+		t.Fatalf("got %d sections, want %d", len(sections), n+1)
+	}
+	s := sections[n]
+	if s.Stage != "build" || s.Section != "post" || s.Duration < 0.01 {
+		t.Errorf("unexpected section stat %+v", s)
+	}
+	if got := Written() - written; got != 4096 {
+		t.Errorf("got %d bytes written, want 4096", got)
+	}
+}
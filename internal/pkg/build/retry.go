@@ -0,0 +1,46 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"errors"
+	"os/exec"
+	"time"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// retryDelay is the delay before the first retry of a section script,
+// doubled after each attempt up to retryMaxDelay.
+var (
+	retryDelay    = 5 * time.Second
+	retryMaxDelay = 2 * time.Minute
+)
+
+// retryScript calls run, then calls it again up to retries times with an
+// exponential backoff while it fails with a non zero exit status. Other
+// errors, like a failure to start the script, are returned immediately.
+func retryScript(section string, retries int, run func() error) error {
+	delay := retryDelay
+	for attempt := 1; ; attempt++ {
+		if attempt > 1 {
+			sylog.Infof("Running %s scriptlet, attempt %d of %d", section, attempt, retries+1)
+		}
+		err := run()
+		if err == nil || attempt > retries {
+			return err
+		}
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return err
+		}
+		sylog.Warningf("%%%s script failed: %s, retrying in %s", section, err, delay)
+		time.Sleep(delay)
+		if delay *= 2; delay > retryMaxDelay {
+			delay = retryMaxDelay
+		}
+	}
+}
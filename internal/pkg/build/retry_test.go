@@ -0,0 +1,59 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"fmt"
+	"os/exec"
+	"testing"
+	"time"
+)
+
+func TestRetryScript(t *testing.T) {
+	defer func(d time.Duration) { retryDelay = d }(retryDelay)
+	retryDelay = time.Millisecond
+
+	exitErr := exec.Command("/bin/sh", "-c", "exit 3").Run()
+	if exitErr == nil {
+		t.Fatalf("unexpected success of failing command")
+	}
+
+	tests := []struct {
+		name         string
+		retries      int
+		failures     int
+		err          error
+		wantAttempts int
+		wantErr      bool
+	}{
+		{name: "Success", retries: 2, wantAttempts: 1},
+		{name: "NoRetry", retries: 0, failures: 1, err: exitErr, wantAttempts: 1, wantErr: true},
+		{name: "RetrySuccess", retries: 2, failures: 2, err: exitErr, wantAttempts: 3},
+		{name: "RetryFailure", retries: 2, failures: 5, err: exitErr, wantAttempts: 3, wantErr: true},
+		{name: "NotExitError", retries: 2, failures: 5, err: fmt.Errorf("exec failed"), wantAttempts: 1, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attempts := 0
+			err := retryScript("post", tt.retries, func() error {
+				attempts++
+				if attempts <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if attempts != tt.wantAttempts {
+				t.Errorf("got %d attempts, want %d", attempts, tt.wantAttempts)
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+// runscriptSearchPath lists the container directories searched for the
+// OCI entrypoint and command programs given without path.
+var runscriptSearchPath = []string{"/usr/local/sbin", "/usr/local/bin", "/usr/sbin", "/usr/bin", "/sbin", "/bin"}
+
+// checkRunscript warns when the root filesystem has no runscript, or a
+// runscript which would not run anything.
+func checkRunscript(opts types.Options, rootfs string) error {
+	path := filepath.Join(rootfs, fs.EvalRelative("/.singularity.d/runscript", rootfs))
+	fi, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return opts.Warnf(types.WarnMissingRunscript, "The image has no runscript, 'singularity run' will start a shell")
+	} else if err != nil {
+		return fmt.Errorf("while checking runscript: %v", err)
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("while checking runscript: %v", err)
+	}
+	if problem := runscriptProblem(rootfs, content, fi.Mode()); problem != "" {
+		return opts.Warnf(types.WarnBrokenRunscript, "The image runscript %s", problem)
+	}
+	return nil
+}
+
+// runscriptProblem returns why the runscript content found in rootfs would
+// not run anything, or an empty string. The shebang interpreter, the programs
+// run by exec with an absolute path and the OCI entrypoint or command of
+// runscripts generated for docker/OCI images must be found in rootfs.
+func runscriptProblem(rootfs string, content []byte, mode os.FileMode) string {
+	if mode&0111 == 0 {
+		return "is not executable"
+	}
+
+	var commands []string
+	ociArgs := make(map[string]string)
+
+	s := bufio.NewScanner(bytes.NewReader(content))
+	for first := true; s.Scan(); first = false {
+		line := strings.TrimSpace(s.Text())
+		if first && strings.HasPrefix(line, "#!") {
+			fields := strings.Fields(strings.TrimPrefix(line, "#!"))
+			if len(fields) == 0 {
+				return "has an empty shebang"
+			}
+			if !programExists(rootfs, fields[0], false) {
+				return fmt.Sprintf("interpreter %s is missing from the image", fields[0])
+			}
+			continue
+		}
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, v := range []string{"OCI_ENTRYPOINT", "OCI_CMD"} {
+			if strings.HasPrefix(line, v+"=") {
+				ociArgs[v] = strings.Trim(strings.TrimPrefix(line, v+"="), "'")
+			}
+		}
+		commands = append(commands, line)
+	}
+
+	if len(commands) == 0 {
+		return "is empty"
+	}
+
+	if len(ociArgs) > 0 {
+		args := ociArgs["OCI_ENTRYPOINT"]
+		if args == "" {
+			args = ociArgs["OCI_CMD"]
+		}
+		if args == "" {
+			return "is empty, the source image has no entrypoint or command"
+		}
+		program := strings.Trim(strings.Fields(args)[0], `"`)
+		if !programExists(rootfs, program, true) {
+			return fmt.Sprintf("runs %s, missing from the image", program)
+		}
+		return ""
+	}
+
+	for _, c := range commands {
+		fields := strings.Fields(c)
+		if len(fields) < 2 || fields[0] != "exec" {
+			continue
+		}
+		program := strings.Trim(fields[1], `"'`)
+		if filepath.IsAbs(program) && !programExists(rootfs, program, false) {
+			return fmt.Sprintf("runs %s, missing from the image", program)
+		}
+	}
+	return ""
+}
+
+// programExists returns if the program is an executable file of rootfs.
+// Relative programs are searched in runscriptSearchPath if search is set,
+// and are assumed to exist otherwise, like programs set by a variable.
+func programExists(rootfs, program string, search bool) bool {
+	if strings.Contains(program, "$") {
+		return true
+	}
+	if !filepath.IsAbs(program) {
+		if !search || strings.Contains(program, "/") {
+			return true
+		}
+		for _, dir := range runscriptSearchPath {
+			if programExists(rootfs, filepath.Join(dir, program), false) {
+				return true
+			}
+		}
+		return false
+	}
+
+	path := filepath.Join(rootfs, fs.EvalRelative(program, rootfs))
+	fi, err := os.Stat(path)
+	return err == nil && fi.Mode().IsRegular() && fi.Mode()&0111 != 0
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunscriptProblem(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "runscript-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	for _, p := range []string{"bin/sh", "usr/bin/python3", "usr/local/bin/entrypoint"} {
+		path := filepath.Join(rootfs, p)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, nil, 0755); err != nil {
+			t.Fatalf("failed to write %s: %s", p, err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(rootfs, "usr/bin/data"), nil, 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+	// absolute links are resolved within the root filesystem
+	if err := os.Symlink("/usr/bin/python3", filepath.Join(rootfs, "usr/bin/python")); err != nil {
+		t.Fatalf("failed to create symlink: %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		mode    os.FileMode
+		broken  bool
+	}{
+		{name: "Valid", content: "#!/bin/sh\necho hello\n", mode: 0755},
+		{name: "NotExecutable", content: "#!/bin/sh\necho hello\n", mode: 0644, broken: true},
+		{name: "Empty", content: "", mode: 0755, broken: true},
+		{name: "ShebangOnly", content: "#!/bin/sh\n\n# nothing\n", mode: 0755, broken: true},
+		{name: "EmptyShebang", content: "#!\necho hello\n", mode: 0755, broken: true},
+		{name: "MissingInterpreter", content: "#!/bin/bash\necho hello\n", mode: 0755, broken: true},
+		{name: "SymlinkInterpreter", content: "#!/usr/bin/python\nprint('hello')\n", mode: 0755},
+		{name: "ExecMissing", content: "#!/bin/sh\nexec /opt/app/run \"$@\"\n", mode: 0755, broken: true},
+		{name: "ExecNotExecutable", content: "#!/bin/sh\nexec /usr/bin/data\n", mode: 0755, broken: true},
+		{name: "ExecVariable", content: "#!/bin/sh\nexec \"$@\"\n", mode: 0755},
+		{name: "ExecRelative", content: "#!/bin/sh\nexec python3 app.py\n", mode: 0755},
+		{name: "OCIEntrypoint", content: "#!/bin/sh\nOCI_ENTRYPOINT='\"/usr/local/bin/entrypoint\"'\nOCI_CMD='\"missing\"'\n", mode: 0755},
+		{name: "OCICmdSearched", content: "#!/bin/sh\nOCI_ENTRYPOINT=''\nOCI_CMD='\"python3\" \"-V\"'\n", mode: 0755},
+		{name: "OCICmdMissing", content: "#!/bin/sh\nOCI_ENTRYPOINT=''\nOCI_CMD='\"nginx\"'\n", mode: 0755, broken: true},
+		{name: "OCIEmpty", content: "#!/bin/sh\nOCI_ENTRYPOINT=''\nOCI_CMD=''\n", mode: 0755, broken: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			problem := runscriptProblem(rootfs, []byte(tt.content), tt.mode)
+			if tt.broken && problem == "" {
+				t.Errorf("broken runscript not detected")
+			} else if !tt.broken && problem != "" {
+				t.Errorf("unexpected problem: %s", problem)
+			}
+		})
+	}
+}
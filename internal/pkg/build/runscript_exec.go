@@ -0,0 +1,122 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// shellInterpreters are the interpreters whose scripts get their last
+// command run with exec by --no-runscript-wrap.
+var shellInterpreters = map[string]bool{
+	"ash":  true,
+	"bash": true,
+	"dash": true,
+	"ksh":  true,
+	"sh":   true,
+	"zsh":  true,
+}
+
+// shellBuiltins are the shell builtins which can't be run with exec.
+var shellBuiltins = map[string]bool{
+	".":        true,
+	":":        true,
+	"[":        true,
+	"alias":    true,
+	"break":    true,
+	"cd":       true,
+	"command":  true,
+	"continue": true,
+	"declare":  true,
+	"echo":     true,
+	"eval":     true,
+	"exit":     true,
+	"export":   true,
+	"false":    true,
+	"local":    true,
+	"printf":   true,
+	"pwd":      true,
+	"read":     true,
+	"readonly": true,
+	"return":   true,
+	"set":      true,
+	"shift":    true,
+	"source":   true,
+	"test":     true,
+	"trap":     true,
+	"true":     true,
+	"type":     true,
+	"ulimit":   true,
+	"umask":    true,
+	"unset":    true,
+	"wait":     true,
+}
+
+// execLastCommand prefixes the last command of script with exec when
+// its shebang runs a shell, so that the command replaces the shell
+// process and receives the signals sent to the container. It returns
+// false with the unchanged script when the last command can't be run
+// with exec, as a builtin, a function, a pipeline, a list or a command
+// run in background. Scripts of other interpreters aren't wrapped by a
+// shell and are returned unchanged.
+func execLastCommand(shebang, script string) (string, bool) {
+	fields := strings.Fields(strings.TrimPrefix(shebang, "#!"))
+	if len(fields) == 0 {
+		return script, true
+	}
+	interpreter := filepath.Base(fields[0])
+	if interpreter == "env" && len(fields) > 1 {
+		interpreter = filepath.Base(fields[1])
+	}
+	if !shellInterpreters[interpreter] {
+		return script, true
+	}
+
+	f, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil || len(f.Stmts) == 0 {
+		return script, false
+	}
+
+	functions := make(map[string]bool)
+	syntax.Walk(f, func(node syntax.Node) bool {
+		if fn, ok := node.(*syntax.FuncDecl); ok {
+			functions[fn.Name.Value] = true
+		}
+		return true
+	})
+
+	last := f.Stmts[len(f.Stmts)-1]
+	call, ok := last.Cmd.(*syntax.CallExpr)
+	if !ok || last.Background || last.Coprocess || last.Negated || len(call.Args) == 0 {
+		return script, false
+	}
+
+	name := call.Args[0].Lit()
+	if name == "exec" {
+		return script, true
+	} else if shellBuiltins[name] || functions[name] {
+		return script, false
+	}
+
+	// assignments prefixing the command are kept before exec
+	offset := call.Args[0].Pos().Offset()
+	return script[:offset] + "exec " + script[offset:], true
+}
+
+// execScript runs the last command of the runscript or startscript name
+// with exec for --no-runscript-wrap.
+func execScript(opts types.Options, name, shebang, script string) (string, error) {
+	s, ok := execLastCommand(shebang, script)
+	if !ok {
+		err := opts.Warnf(types.WarnRunscriptExec, "%s last command can't be run with exec, signals sent to the container won't reach it", name)
+		return script, err
+	}
+	return s, nil
+}
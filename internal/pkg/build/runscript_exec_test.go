@@ -0,0 +1,92 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"testing"
+)
+
+func TestExecLastCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		shebang string
+		script  string
+		want    string
+		ok      bool
+	}{
+		{
+			name:    "Command",
+			shebang: "#!/bin/sh",
+			script:  "echo starting\n/opt/server --port 80",
+			want:    "echo starting\nexec /opt/server --port 80",
+			ok:      true,
+		},
+		{
+			name:    "Assignments",
+			shebang: "#!/bin/bash",
+			script:  "cd /data\nFOO=bar python3 \"$@\" >/dev/null",
+			want:    "cd /data\nFOO=bar exec python3 \"$@\" >/dev/null",
+			ok:      true,
+		},
+		{
+			name:    "Arguments",
+			shebang: "#!/usr/bin/env sh",
+			script:  "\"$@\"",
+			want:    "exec \"$@\"",
+			ok:      true,
+		},
+		{
+			name:    "Exec",
+			shebang: "#!/bin/sh",
+			script:  "exec /opt/server",
+			want:    "exec /opt/server",
+			ok:      true,
+		},
+		{
+			name:    "Interpreter",
+			shebang: "#!/usr/bin/python3",
+			script:  "print('hello')",
+			want:    "print('hello')",
+			ok:      true,
+		},
+		{
+			name:    "Builtin",
+			shebang: "#!/bin/sh",
+			script:  "/opt/server\necho done",
+			want:    "/opt/server\necho done",
+		},
+		{
+			name:    "Function",
+			shebang: "#!/bin/sh",
+			script:  "run() { /opt/server; }\nrun",
+			want:    "run() { /opt/server; }\nrun",
+		},
+		{
+			name:    "Pipeline",
+			shebang: "#!/bin/sh",
+			script:  "/opt/server | tee log",
+			want:    "/opt/server | tee log",
+		},
+		{
+			name:    "Background",
+			shebang: "#!/bin/sh",
+			script:  "/opt/server &",
+			want:    "/opt/server &",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := execLastCommand(tt.shebang, tt.script)
+			if ok != tt.ok {
+				t.Errorf("got %v, want %v", ok, tt.ok)
+			}
+			if got != tt.want {
+				t.Errorf("got script %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// secretsMaxFileSize is the size above which files are not scanned for
+// secrets, secrets are found in configuration files and scripts.
+const secretsMaxFileSize = 8 << 20
+
+// secretsMinEntropy is the minimum Shannon entropy, in bits per character,
+// of the values assigned to token like variables reported as secrets.
+const secretsMinEntropy = 4.0
+
+// secretRule describes a secret pattern, the first submatch of re being
+// checked for entropy when entropy is set.
+type secretRule struct {
+	name    string
+	re      *regexp.Regexp
+	entropy bool
+}
+
+var secretRules = []secretRule{
+	{
+		name: "AWS access key ID",
+		re:   regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`),
+	},
+	{
+		name: "AWS secret access key",
+		re:   regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?[A-Za-z0-9/+=]{40}\b`),
+	},
+	{
+		name: "private key",
+		re:   regexp.MustCompile(`-----BEGIN (?:[A-Z]+ )?PRIVATE KEY(?: BLOCK)?-----`),
+	},
+	{
+		name: "GitHub token",
+		re:   regexp.MustCompile(`\bgh[opsu]_[A-Za-z0-9]{36}\b`),
+	},
+	{
+		name: "Slack token",
+		re:   regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`),
+	},
+	{
+		name:    "high entropy token",
+		re:      regexp.MustCompile(`(?i)(?:token|secret|passw(?:or)?d|api_?key|access_?key)["']?\s*[:=]\s*["']?([A-Za-z0-9/+_.=-]{20,})`),
+		entropy: true,
+	},
+}
+
+// secretFinding is a possible secret found by the scan, only its location
+// and kind are kept so that secrets never end up in the build output.
+type secretFinding struct {
+	path string
+	line int
+	rule string
+}
+
+func (f secretFinding) String() string {
+	return fmt.Sprintf("%s:%d: possible %s", f.path, f.line, f.rule)
+}
+
+// ReadSecretsIgnoreFile returns the glob patterns of container paths found
+// in the --scan-secrets-ignore file at path, one per line, blank lines and
+// lines starting with # being skipped.
+func ReadSecretsIgnoreFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		p := strings.TrimSpace(s.Text())
+		if p == "" || strings.HasPrefix(p, "#") {
+			continue
+		}
+		if !filepath.IsAbs(p) {
+			return nil, fmt.Errorf("%s:%d: %s is not an absolute path", path, n, p)
+		}
+		if _, err := filepath.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("%s:%d: %s: %s", path, n, p, err)
+		}
+		patterns = append(patterns, filepath.Clean(p))
+	}
+	return patterns, s.Err()
+}
+
+// scanSecrets scans the root filesystem and the JSON data objects of b,
+// which hold the image labels and environment, for secrets and reports
+// each of them as a build warning. With ScanSecretsStrict, the build fails
+// if any secret was found.
+func scanSecrets(b *types.Bundle) error {
+	sylog.Infof("Scanning image for secrets")
+
+	findings, err := findSecrets(b.RootfsPath, b.Opts.ScanSecretsIgnore)
+	if err != nil {
+		return fmt.Errorf("while scanning root filesystem for secrets: %s", err)
+	}
+
+	names := make([]string, 0, len(b.JSONObjects))
+	for name := range b.JSONObjects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		findings = append(findings, scanSecretsText(bytes.NewReader(b.JSONObjects[name]), name+".json data object")...)
+	}
+
+	for _, f := range findings {
+		if err := b.Opts.Warnf(types.WarnSecret, "%s", f); err != nil {
+			return err
+		}
+	}
+	if len(findings) > 0 && b.Opts.ScanSecretsStrict {
+		return fmt.Errorf("%d possible secret(s) found in the image", len(findings))
+	}
+	if len(findings) == 0 {
+		sylog.Infof("No secret found")
+	}
+	return nil
+}
+
+// findSecrets returns the secrets found in the regular files of rootfs,
+// sorted by path, skipping binary files, files larger than
+// secretsMaxFileSize and paths matching the ignore glob patterns.
+func findSecrets(rootfs string, ignore []string) ([]secretFinding, error) {
+	files, err := scanRootfs(rootfs)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([]string, 0, len(files))
+	for rel, state := range files {
+		if state.mode.IsRegular() && state.size > 0 && state.size <= secretsMaxFileSize {
+			paths = append(paths, rel)
+		}
+	}
+	sort.Strings(paths)
+
+	var findings []secretFinding
+	for _, rel := range paths {
+		path := "/" + filepath.ToSlash(rel)
+		if secretIgnored(path, ignore) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(rootfs, rel))
+		if err != nil {
+			return nil, err
+		}
+		r := bufio.NewReader(f)
+		if head, _ := r.Peek(8192); bytes.IndexByte(head, 0) < 0 {
+			findings = append(findings, scanSecretsText(r, path)...)
+		}
+		f.Close()
+	}
+	return findings, nil
+}
+
+// secretIgnored returns if the container path, or one of its parent
+// directories, matches one of the ignore glob patterns.
+func secretIgnored(path string, ignore []string) bool {
+	for p := path; p != "/"; p = filepath.Dir(p) {
+		for _, pattern := range ignore {
+			if ok, _ := filepath.Match(pattern, p); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// scanSecretsText returns the secrets found in the lines read from r,
+// located in name. Lines too long to be read stop the scan.
+func scanSecretsText(r io.Reader, name string) []secretFinding {
+	var findings []secretFinding
+
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; s.Scan(); n++ {
+		line := s.Text()
+		for _, rule := range secretRules {
+			if matchSecretRule(rule, line) {
+				findings = append(findings, secretFinding{path: name, line: n, rule: rule.name})
+				break
+			}
+		}
+	}
+	return findings
+}
+
+func matchSecretRule(rule secretRule, line string) bool {
+	if !rule.entropy {
+		return rule.re.MatchString(line)
+	}
+	for _, m := range rule.re.FindAllStringSubmatch(line, -1) {
+		if shannonEntropy(m[1]) >= secretsMinEntropy {
+			return true
+		}
+	}
+	return false
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	n := float64(len([]rune(s)))
+	var e float64
+	for _, c := range counts {
+		p := float64(c) / n
+		e -= p * math.Log2(p)
+	}
+	return e
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+const (
+	// largestDirsDepth is the depth of the root filesystem directories
+	// reported when an image exceeds its size limit.
+	largestDirsDepth = 2
+	// largestDirsCount is the number of directories reported.
+	largestDirsCount = 10
+)
+
+// dirUsage is the size of the regular files found under a directory.
+type dirUsage struct {
+	path string
+	size int64
+}
+
+// largestDirs returns the n largest directories of rootfs up to the given
+// depth, with their path relative to rootfs.
+func largestDirs(rootfs string, depth, n int) ([]dirUsage, error) {
+	sizes := make(map[string]int64)
+	err := filepath.Walk(rootfs, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(rootfs, path)
+		if err != nil {
+			return err
+		}
+		dir := filepath.Dir(rel)
+		if dir == "." {
+			return nil
+		}
+		elems := strings.Split(dir, string(filepath.Separator))
+		for i := 1; i <= len(elems) && i <= depth; i++ {
+			sizes["/"+filepath.Join(elems[:i]...)] += fi.Size()
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := make([]dirUsage, 0, len(sizes))
+	for path, size := range sizes {
+		dirs = append(dirs, dirUsage{path: path, size: size})
+	}
+	sort.Slice(dirs, func(i, j int) bool {
+		if dirs[i].size == dirs[j].size {
+			return dirs[i].path < dirs[j].path
+		}
+		return dirs[i].size > dirs[j].size
+	})
+	if len(dirs) > n {
+		dirs = dirs[:n]
+	}
+	return dirs, nil
+}
+
+// mib formats size in MiB.
+func mib(size int64) string {
+	return fmt.Sprintf("%.1f MiB", float64(size)/(1<<20))
+}
+
+// imageRootfs returns the root filesystem of the image built at path in
+// format, a sandbox image being the root filesystem rootfs moved to path.
+func imageRootfs(format, path, rootfs string) string {
+	if format == "sandbox" {
+		return path
+	}
+	return rootfs
+}
+
+// checkImageSize checks the size of the image built at path in format
+// against the --max-size and --warn-size limits. The largest directories
+// of the root filesystem rootfs are reported when a limit is exceeded, the
+// build fails if the image exceeds --max-size.
+func checkImageSize(opts types.Options, format, path, rootfs string) error {
+	if opts.MaxImageSize <= 0 && opts.WarnImageSize <= 0 {
+		return nil
+	}
+
+	size, err := pathSize(path)
+	if err != nil {
+		return fmt.Errorf("while computing image size: %v", err)
+	}
+	overMax := opts.MaxImageSize > 0 && size > opts.MaxImageSize
+	overWarn := opts.WarnImageSize > 0 && size > opts.WarnImageSize
+	if !overMax && !overWarn {
+		sylog.Verbosef("Image size: %s", mib(size))
+		return nil
+	}
+
+	if dirs, err := largestDirs(imageRootfs(format, path, rootfs), largestDirsDepth, largestDirsCount); err != nil {
+		sylog.Warningf("Unable to compute the size of the root filesystem directories: %v", err)
+	} else {
+		sylog.Infof("Largest directories of the image root filesystem:")
+		for _, d := range dirs {
+			sylog.Infof("  %12s  %s", mib(d.size), d.path)
+		}
+	}
+
+	if overMax {
+		return fmt.Errorf("image size %s exceeds the %s allowed by --max-size", mib(size), mib(opts.MaxImageSize))
+	}
+	return opts.Warnf(types.WarnImageSize, "image size %s exceeds the %s set by --warn-size", mib(size), mib(opts.WarnImageSize))
+}
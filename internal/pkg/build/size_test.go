@@ -0,0 +1,115 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestLargestDirs(t *testing.T) {
+	rootfs, err := ioutil.TempDir("", "largest-dirs-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(rootfs)
+
+	files := map[string]int{
+		"usr/lib/a/lib.so": 400,
+		"usr/share/doc":    100,
+		"opt/app/bin":      300,
+		"etc/hosts":        10,
+		"top":              1000,
+	}
+	for path, size := range files {
+		path = filepath.Join(rootfs, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %v", err)
+		}
+		if err := ioutil.WriteFile(path, make([]byte, size), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	dirs, err := largestDirs(rootfs, 2, 4)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []dirUsage{
+		{path: "/usr", size: 500},
+		{path: "/usr/lib", size: 400},
+		{path: "/opt", size: 300},
+		{path: "/opt/app", size: 300},
+	}
+	if !reflect.DeepEqual(dirs, want) {
+		t.Errorf("got %+v, want %+v", dirs, want)
+	}
+}
+
+func TestCheckImageSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "image-size-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	image := filepath.Join(dir, "image.sif")
+	if err := ioutil.WriteFile(image, make([]byte, 2048), 0644); err != nil {
+		t.Fatalf("failed to write image: %v", err)
+	}
+
+	// a sandbox image is the root filesystem moved to the image path
+	sandbox := filepath.Join(dir, "sandbox")
+	if err := os.MkdirAll(filepath.Join(sandbox, "opt"), 0755); err != nil {
+		t.Fatalf("failed to create sandbox: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(sandbox, "opt", "data"), make([]byte, 2048), 0644); err != nil {
+		t.Fatalf("failed to write sandbox file: %v", err)
+	}
+	movedRootfs := filepath.Join(dir, "rootfs")
+
+	tests := []struct {
+		name    string
+		format  string
+		path    string
+		rootfs  string
+		opts    types.Options
+		wantErr bool
+	}{
+		{name: "NoLimit", format: "sif", path: image, rootfs: dir},
+		{name: "BelowMax", format: "sif", path: image, rootfs: dir, opts: types.Options{MaxImageSize: 4096}},
+		{name: "OverMax", format: "sif", path: image, rootfs: dir, opts: types.Options{MaxImageSize: 1024}, wantErr: true},
+		{name: "OverWarn", format: "sif", path: image, rootfs: dir, opts: types.Options{WarnImageSize: 1024}},
+		{name: "OverWarnAsError", format: "sif", path: image, rootfs: dir, opts: types.Options{WarnImageSize: 1024, WarnAsError: true}, wantErr: true},
+		{name: "SandboxBelowMax", format: "sandbox", path: sandbox, rootfs: movedRootfs, opts: types.Options{MaxImageSize: 1 << 20}},
+		{name: "SandboxOverMax", format: "sandbox", path: sandbox, rootfs: movedRootfs, opts: types.Options{MaxImageSize: 1024}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkImageSize(tt.opts, tt.format, tt.path, tt.rootfs)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestImageRootfs(t *testing.T) {
+	if got := imageRootfs("sandbox", "/images/sandbox", "/tmp/rootfs"); got != "/images/sandbox" {
+		t.Errorf("got sandbox root filesystem %s, want /images/sandbox", got)
+	}
+	if got := imageRootfs("sif", "/images/image.sif", "/tmp/rootfs"); got != "/tmp/rootfs" {
+		t.Errorf("got SIF root filesystem %s, want /tmp/rootfs", got)
+	}
+}
@@ -93,8 +93,17 @@ for script in /.singularity.d/env/*.sh; do
     fi
 done
 
-if test -x "/.singularity.d/startscript"; then
-    exec "/.singularity.d/startscript"
+if test -n "${SINGULARITY_APPNAME:-}"; then
+
+    if test -x "/scif/apps/${SINGULARITY_APPNAME:-}/scif/startscript"; then
+        exec "/scif/apps/${SINGULARITY_APPNAME:-}/scif/startscript" "$@"
+    else
+        echo "No Singularity startscript for contained app: ${SINGULARITY_APPNAME:-}"
+        exit 1
+    fi
+
+elif test -x "/.singularity.d/startscript"; then
+    exec "/.singularity.d/startscript" "$@"
 fi
 `
 	// Contents of /.singularity.d/actions/test
@@ -0,0 +1,31 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"fmt"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+// networkOnlyAgents are the bootstrap agents installing packages from
+// remote repositories, which are never cached.
+var networkOnlyAgents = map[string]bool{
+	"arch":        true,
+	"busybox":     true,
+	"debootstrap": true,
+	"yum":         true,
+	"zypper":      true,
+}
+
+// CheckFromCacheOnly returns an error if the bootstrap agent of the
+// definition can't build from the image cache without network access.
+func CheckFromCacheOnly(def types.Definition) error {
+	if agent := def.Header["bootstrap"]; networkOnlyAgents[agent] {
+		return fmt.Errorf("the %s bootstrap agent fetches packages from the network and can't be used with --from-cache-only", agent)
+	}
+	return nil
+}
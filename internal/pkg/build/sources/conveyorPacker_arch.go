@@ -68,7 +68,14 @@ func (cp *ArchConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err err
 		}
 	}
 
-	args := []string{"-C", pacConf, "-c", "-d", "-G", "-M", cp.b.RootfsPath, "haveged"}
+	options, err := conveyorOptions(cp.b, "arch")
+	if err != nil {
+		return err
+	}
+
+	args := []string{"-C", pacConf, "-c", "-d", "-G", "-M"}
+	args = append(args, options...)
+	args = append(args, cp.b.RootfsPath, "haveged")
 	args = append(args, instList...)
 
 	pacCmd := exec.Command(pacstrapPath, args...)
@@ -15,6 +15,7 @@ import (
 	"os/exec"
 	"path/filepath"
 
+	"github.com/sylabs/singularity/internal/pkg/client"
 	"github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -49,7 +50,7 @@ func (c *BusyBoxConveyor) Get(ctx context.Context, b *types.Bundle) (err error)
 		return fmt.Errorf("while inserting files: %v", err)
 	}
 
-	busyBoxPath, err := c.insertBusyBox(mirrorurl)
+	busyBoxPath, err := c.insertBusyBox(ctx, mirrorurl)
 	if err != nil {
 		return fmt.Errorf("while inserting busybox: %v", err)
 	}
@@ -92,24 +93,38 @@ func (c *BusyBoxConveyor) insertBaseFiles() error {
 	return nil
 }
 
-func (c *BusyBoxConveyor) insertBusyBox(mirrorurl string) (busyBoxPath string, err error) {
+func (c *BusyBoxConveyor) insertBusyBox(ctx context.Context, mirrorurl string) (busyBoxPath string, err error) {
 	os.Mkdir(filepath.Join(c.b.RootfsPath, "/bin"), 0755)
 
-	resp, err := http.Get(mirrorurl)
+	ctx, cancel := client.WithDownloadTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, mirrorurl, nil)
+	if err != nil {
+		return "", fmt.Errorf("while creating http request: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return "", fmt.Errorf("while performing http request: %v", err)
 	}
 	defer resp.Body.Close()
 
-	f, err := os.Create(filepath.Join(c.b.RootfsPath, "/bin/busybox"))
+	if err := client.CheckDownloadSize(ctx, resp.ContentLength); err != nil {
+		return "", fmt.Errorf("while downloading %s: %v", mirrorurl, err)
+	}
+
+	busyBoxPath = filepath.Join(c.b.RootfsPath, "/bin/busybox")
+
+	f, err := os.Create(busyBoxPath)
 	if err != nil {
 		return
 	}
 	defer f.Close()
 
-	bytesWritten, err := io.Copy(f, resp.Body)
+	bytesWritten, err := io.Copy(f, client.LimitReader(ctx, resp.Body))
 	if err != nil {
-		return
+		os.Remove(busyBoxPath)
+		return "", fmt.Errorf("while downloading %s: %v", mirrorurl, err)
 	}
 
 	//Simple check to make sure file received is the correct size
@@ -122,7 +137,7 @@ func (c *BusyBoxConveyor) insertBusyBox(mirrorurl string) (busyBoxPath string, e
 		return
 	}
 
-	return filepath.Join(c.b.RootfsPath, "/bin/busybox"), nil
+	return busyBoxPath, nil
 }
 
 func (c *BusyBoxConveyor) insertBaseEnv() (err error) {
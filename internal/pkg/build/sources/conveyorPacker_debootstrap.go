@@ -28,6 +28,7 @@ type DebootstrapConveyorPacker struct {
 	mirrorurl string
 	osversion string
 	include   string
+	options   []string
 }
 
 // Get downloads container information from the specified source
@@ -59,10 +60,15 @@ func (cp *DebootstrapConveyorPacker) Get(ctx context.Context, b *types.Bundle) (
 		}
 	}
 
+	// extra options come after the default ones to override them
+	args := []string{`--variant=minbase`, `--exclude=openssl,udev,debconf-i18n,e2fsprogs`, `--include=apt,` + cp.include, `--arch=` + runtime.GOARCH}
+	args = append(args, cp.options...)
+	args = append(args, cp.osversion, cp.b.RootfsPath, cp.mirrorurl)
+
 	// run debootstrap command
-	cmd := exec.Command(debootstrapPath, `--variant=minbase`, `--exclude=openssl,udev,debconf-i18n,e2fsprogs`, `--include=apt,`+cp.include, `--arch=`+runtime.GOARCH, cp.osversion, cp.b.RootfsPath, cp.mirrorurl)
+	cmd := exec.Command(debootstrapPath, args...)
 
-	sylog.Debugf("\n\tDebootstrap Path: %s\n\tIncludes: apt(default),%s\n\tDetected Arch: %s\n\tOSVersion: %s\n\tMirrorURL: %s\n", debootstrapPath, cp.include, runtime.GOARCH, cp.osversion, cp.mirrorurl)
+	sylog.Debugf("\n\tDebootstrap Path: %s\n\tIncludes: apt(default),%s\n\tDetected Arch: %s\n\tOSVersion: %s\n\tMirrorURL: %s\n\tOptions: %q\n", debootstrapPath, cp.include, runtime.GOARCH, cp.osversion, cp.mirrorurl, cp.options)
 
 	// run debootstrap
 	out, err := cmd.CombinedOutput()
@@ -145,7 +151,9 @@ func (cp *DebootstrapConveyorPacker) getRecipeHeaderInfo() (err error) {
 	//convert Requires string to comma separated list
 	cp.include = strings.Replace(include, ` `, `,`, -1)
 
-	return nil
+	cp.options, err = conveyorOptions(cp.b, "debootstrap")
+
+	return err
 }
 
 func (cp *DebootstrapConveyorPacker) insertBaseEnv(b *types.Bundle) (err error) {
@@ -0,0 +1,81 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// DirectoryConveyorPacker uses an existing root filesystem directory,
+// built by other tools, as the base of the container. The directory is
+// copied into the bundle and never modified.
+type DirectoryConveyorPacker struct {
+	src string
+	b   *types.Bundle
+}
+
+// Get checks the root filesystem directory set by the From header,
+// relative paths being resolved from the build context if set.
+func (cp *DirectoryConveyorPacker) Get(ctx context.Context, b *types.Bundle) error {
+	cp.b = b
+
+	src := b.Recipe.Header["from"]
+	if src == "" {
+		return fmt.Errorf("directory bootstrap requires a From header set to a root filesystem directory")
+	}
+	if !filepath.IsAbs(src) && b.Opts.BuildContext != "" {
+		src = filepath.Join(b.Opts.BuildContext, src)
+	}
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return fmt.Errorf("while resolving root filesystem directory: %v", err)
+	}
+	if !fs.IsDir(src) {
+		return fmt.Errorf("root filesystem %s doesn't exist or is not a directory", src)
+	}
+
+	// copying a directory into itself never ends
+	rootfs, err := filepath.Abs(b.RootfsPath)
+	if err != nil {
+		return err
+	}
+	if rootfs == src || strings.HasPrefix(rootfs, src+string(os.PathSeparator)) {
+		return fmt.Errorf("root filesystem %s holds the build directory %s", src, rootfs)
+	}
+
+	cp.src = src
+	return nil
+}
+
+// Pack installs the container metadata and actions in the bundle, then
+// copies the root filesystem directory over them, so that the metadata
+// of the directory, as its runscript, is kept over the default one.
+func (cp *DirectoryConveyorPacker) Pack(context.Context) (*types.Bundle, error) {
+	if err := makeBaseEnv(cp.b.RootfsPath); err != nil {
+		return nil, fmt.Errorf("while inserting base environment: %v", err)
+	}
+
+	sylog.Infof("Copying root filesystem from %s", cp.src)
+
+	var stderr bytes.Buffer
+	cmd := exec.Command("cp", "-a", cp.src+`/.`, cp.b.RootfsPath)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("while copying root filesystem: %v: %v", err, stderr.String())
+	}
+
+	return cp.b, nil
+}
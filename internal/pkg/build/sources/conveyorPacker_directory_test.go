@@ -0,0 +1,140 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/build/sources"
+	"github.com/sylabs/singularity/internal/pkg/test"
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestDirectoryConveyorPacker(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	src, err := ioutil.TempDir("", "directory-rootfs-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(src)
+
+	if err := os.MkdirAll(filepath.Join(src, "etc"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(src, "etc", "os-release"), []byte("ID=test\n"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	// a root filesystem directory with its own runscript
+	srcRunscript, err := ioutil.TempDir("", "directory-runscript-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(srcRunscript)
+
+	const runscript = "#!/bin/sh\necho source runscript\n"
+	if err := os.MkdirAll(filepath.Join(srcRunscript, ".singularity.d"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(srcRunscript, ".singularity.d", "runscript"), []byte(runscript), 0755); err != nil {
+		t.Fatalf("failed to write runscript: %v", err)
+	}
+
+	b, err := types.NewBundle(filepath.Join(os.TempDir(), "sbuild-directory"), os.TempDir())
+	if err != nil {
+		t.Fatalf("failed to create bundle: %v", err)
+	}
+	defer b.Remove()
+
+	tests := []struct {
+		name      string
+		from      string
+		runscript string
+		wantErr   bool
+	}{
+		{
+			name:    "NoFrom",
+			from:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Missing",
+			from:    filepath.Join(src, "missing"),
+			wantErr: true,
+		},
+		{
+			name:    "File",
+			from:    filepath.Join(src, "etc", "os-release"),
+			wantErr: true,
+		},
+		{
+			name:    "BuildDirectory",
+			from:    b.RootfsPath,
+			wantErr: true,
+		},
+		{
+			name: "Directory",
+			from: src,
+		},
+		{
+			name:      "DirectoryRunscript",
+			from:      srcRunscript,
+			runscript: runscript,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b.Recipe = types.Definition{
+				Header: map[string]string{
+					"bootstrap": "directory",
+					"from":      tt.from,
+				},
+			}
+
+			cp := &sources.DirectoryConveyorPacker{}
+			err := cp.Get(context.Background(), b)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to Get from %s: %v", tt.from, err)
+			}
+
+			if _, err := cp.Pack(context.Background()); err != nil {
+				t.Fatalf("failed to Pack from %s: %v", tt.from, err)
+			}
+
+			for _, f := range []string{"etc/os-release", ".singularity.d/actions/run", ".singularity.d/runscript"} {
+				if _, err := os.Stat(filepath.Join(b.RootfsPath, f)); err != nil {
+					t.Errorf("missing %s in bundle: %v", f, err)
+				}
+			}
+			if tt.runscript != "" {
+				content, err := ioutil.ReadFile(filepath.Join(b.RootfsPath, ".singularity.d", "runscript"))
+				if err != nil {
+					t.Fatalf("failed to read runscript: %v", err)
+				}
+				if string(content) != tt.runscript {
+					t.Errorf("got runscript %q, want %q", content, tt.runscript)
+				}
+			}
+			// the source directory is left untouched
+			if _, err := os.Stat(filepath.Join(src, ".singularity.d")); !os.IsNotExist(err) {
+				t.Errorf("source directory modified")
+			}
+		})
+	}
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"runtime"
+	"strings"
 
 	golog "github.com/go-log/log"
 
@@ -35,35 +36,46 @@ func (cp *LibraryConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err
 
 	cp.b = b
 
-	libraryURL := b.Opts.LibraryURL
-	authToken := b.Opts.LibraryAuthToken
-
 	if err = makeBaseEnv(cp.b.RootfsPath); err != nil {
 		return fmt.Errorf("while inserting base environment: %v", err)
 	}
 
-	// check for custom library from definition
-	customLib, ok := b.Recipe.Header["library"]
-	if ok {
-		sylog.Debugf("Using custom library: %v", customLib)
-		libraryURL = customLib
+	libraryURL, imageRef := librarySource(b)
+	authToken := libraryAuthToken(b, libraryURL)
+	if b.Opts.LibraryNoHTTPS {
+		sylog.Warningf("HTTPS disabled for library %s, the image is downloaded without transport security", libraryURL)
 	}
 
 	sylog.Debugf("LibraryURL: %v", libraryURL)
 	sylog.Debugf("LibraryRef: %v", b.Recipe.Header["from"])
 
-	imageRef := library.NormalizeLibraryRef(b.Recipe.Header["from"])
 	libraryConfig := &client.Config{
 		BaseURL:   libraryURL,
 		AuthToken: authToken,
 		Logger:    (golog.Logger)(sylog.DebugLogger{}),
 	}
 
-	imagePath, err := library.Pull(ctx, b.Opts.ImgCache, imageRef, runtime.GOARCH, cp.b.TmpDir, libraryConfig, "")
+	arch := runtime.GOARCH
+	if b.Opts.Arch != "" {
+		arch = b.Opts.Arch
+	}
+
+	var imagePath string
+	if b.Opts.FromCacheOnly {
+		imagePath, err = library.PullFromCache(b.Opts.ImgCache, imageRef, arch, libraryConfig)
+	} else {
+		imagePath, err = library.Pull(ctx, b.Opts.ImgCache, imageRef, arch, cp.b.TmpDir, libraryConfig, "")
+	}
 	if err != nil {
 		return fmt.Errorf("while fetching library image: %v", err)
 	}
 
+	if b.Opts.VerifyBase {
+		if err := verifyLibraryBase(ctx, b, imagePath); err != nil {
+			return err
+		}
+	}
+
 	// insert base metadata before unpacking fs
 	if err = makeBaseEnv(cp.b.RootfsPath); err != nil {
 		return fmt.Errorf("while inserting base environment: %v", err)
@@ -74,6 +86,48 @@ func (cp *LibraryConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err
 	return err
 }
 
+// librarySource returns the library base URL and the normalized image
+// reference of the bootstrap image of b. A host in the reference takes
+// precedence over the library header of the definition, then over the
+// library URL of the build options. The URL uses http when HTTPS is
+// disabled for library sources.
+func librarySource(b *types.Bundle) (libraryURL, imageRef string) {
+	libraryURL = b.Opts.LibraryURL
+
+	// check for custom library from definition
+	if customLib, ok := b.Recipe.Header["library"]; ok {
+		sylog.Debugf("Using custom library: %v", customLib)
+		libraryURL = customLib
+	}
+
+	libraryURL, imageRef = library.ResolveLibraryRef(b.Recipe.Header["from"], libraryURL)
+	if b.Opts.LibraryNoHTTPS && strings.HasPrefix(libraryURL, "https://") {
+		libraryURL = "http://" + strings.TrimPrefix(libraryURL, "https://")
+	}
+	return libraryURL, imageRef
+}
+
+// libraryAuthToken returns the library authentication token of the build
+// options if libraryURL is the configured library, an empty token otherwise
+// so that images of other libraries, named by the definition, are pulled
+// anonymously without leaking the token.
+func libraryAuthToken(b *types.Bundle, libraryURL string) string {
+	if b.Opts.LibraryAuthToken == "" {
+		return ""
+	}
+	if strings.TrimSuffix(libraryURL, "/") != strings.TrimSuffix(b.Opts.LibraryURL, "/") {
+		sylog.Debugf("Pulling from %s anonymously, the authentication token is only sent to %s", libraryURL, b.Opts.LibraryURL)
+		return ""
+	}
+	return b.Opts.LibraryAuthToken
+}
+
+// LibrarySourceRef returns the library:// reference of the bootstrap
+// image of b, including the host of the library it is pulled from.
+func LibrarySourceRef(b *types.Bundle) string {
+	return library.FullLibraryRef(librarySource(b))
+}
+
 // CleanUp removes any files owned by the conveyorPacker on the filesystem.
 func (cp *LibraryConveyorPacker) CleanUp() {
 	cp.b.Remove()
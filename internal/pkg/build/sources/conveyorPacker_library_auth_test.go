@@ -0,0 +1,75 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestLibraryAuthToken(t *testing.T) {
+	tests := []struct {
+		name      string
+		from      string
+		library   string
+		noHTTPS   bool
+		wantToken bool
+	}{
+		{
+			name:      "ConfiguredLibrary",
+			from:      "alpine:3.11",
+			wantToken: true,
+		},
+		{
+			name:      "ConfiguredLibraryHost",
+			from:      "library.example.org/alpine:3.11",
+			wantToken: true,
+		},
+		{
+			name: "OtherHost",
+			from: "evil.example.com/alpine:3.11",
+		},
+		{
+			name:    "OtherHostNoHTTPS",
+			from:    "evil.example.com/alpine:3.11",
+			noHTTPS: true,
+		},
+		{
+			name:    "ConfiguredLibraryNoHTTPS",
+			from:    "alpine:3.11",
+			noHTTPS: true,
+		},
+		{
+			name:    "LibraryHeader",
+			from:    "alpine:3.11",
+			library: "https://evil.example.com",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &types.Bundle{
+				Opts: types.Options{
+					LibraryURL:       "https://library.example.org",
+					LibraryAuthToken: "token",
+					LibraryNoHTTPS:   tt.noHTTPS,
+				},
+				Recipe: types.Definition{
+					Header: map[string]string{"bootstrap": "library", "from": tt.from},
+				},
+			}
+			if tt.library != "" {
+				b.Recipe.Header["library"] = tt.library
+			}
+			libraryURL, _ := librarySource(b)
+			token := libraryAuthToken(b, libraryURL)
+			if got := token != ""; got != tt.wantToken {
+				t.Errorf("token sent to %s: %v, want %v", libraryURL, got, tt.wantToken)
+			}
+		})
+	}
+}
@@ -23,18 +23,61 @@ import (
 	"github.com/containers/image/v5/docker"
 	dockerarchive "github.com/containers/image/v5/docker/archive"
 	dockerdaemon "github.com/containers/image/v5/docker/daemon"
+	"github.com/containers/image/v5/docker/reference"
 	ociarchive "github.com/containers/image/v5/oci/archive"
 	ocilayout "github.com/containers/image/v5/oci/layout"
 	"github.com/containers/image/v5/signature"
 	"github.com/containers/image/v5/types"
 	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/sylabs/singularity/internal/pkg/build/oci"
+	"github.com/sylabs/singularity/internal/pkg/client"
+	"github.com/sylabs/singularity/internal/pkg/util/auth"
 	"github.com/sylabs/singularity/internal/pkg/util/shell"
 	buildTypes "github.com/sylabs/singularity/pkg/build/types"
 	sytypes "github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
 
+// sourceRef returns the image reference of the definition d bootstrap,
+// with the registry and namespace headers if specified.
+func sourceRef(d sytypes.Definition) string {
+	ref := d.Header["from"]
+	if d.Header["namespace"] != "" {
+		ref = d.Header["namespace"] + "/" + ref
+	}
+	if d.Header["registry"] != "" {
+		ref = d.Header["registry"] + "/" + ref
+	}
+	return ref
+}
+
+// DockerSourceRef returns the fully resolved reference of the docker
+// bootstrap image of the definition d, as pulled with the default
+// registry and tag applied, along with the defaults implicitly used
+// by the reference written in the definition file.
+func DockerSourceRef(d sytypes.Definition) (resolved string, implicit []string, err error) {
+	ref := sourceRef(d)
+
+	named, err := reference.ParseNormalizedNamed(ref)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid image reference %s: %v", ref, err)
+	}
+
+	// as docker does, the first component is a registry if it
+	// looks like a host name
+	parts := strings.SplitN(ref, "/", 2)
+	if len(parts) == 1 || (!strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost") {
+		implicit = append(implicit, "registry "+reference.Domain(named))
+	}
+	if _, ok := named.(reference.Tagged); !ok {
+		if _, ok := named.(reference.Digested); !ok {
+			implicit = append(implicit, "tag latest")
+		}
+	}
+
+	return reference.TagNameOnly(named).String(), implicit, nil
+}
+
 // OCIConveyorPacker holds stuff that needs to be packed into the bundle
 type OCIConveyorPacker struct {
 	srcRef    types.ImageReference
@@ -65,19 +108,14 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		OCIInsecureSkipTLSVerify: cp.b.Opts.NoHTTPS,
 		DockerAuthConfig:         cp.b.Opts.DockerAuthConfig,
 		OSChoice:                 "linux",
+		ArchitectureChoice:       cp.b.Opts.Arch,
+		VariantChoice:            cp.b.Opts.ArchVariant,
 	}
 	if cp.b.Opts.NoHTTPS {
 		cp.sysCtx.DockerInsecureSkipTLSVerify = types.NewOptionalBool(true)
 	}
 
-	// add registry and namespace to reference if specified
-	ref := b.Recipe.Header["from"]
-	if b.Recipe.Header["namespace"] != "" {
-		ref = b.Recipe.Header["namespace"] + "/" + ref
-	}
-	if b.Recipe.Header["registry"] != "" {
-		ref = b.Recipe.Header["registry"] + "/" + ref
-	}
+	ref := sourceRef(b.Recipe)
 	sylog.Debugf("Reference: %v", ref)
 
 	switch b.Recipe.Header["bootstrap"] {
@@ -127,7 +165,61 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		return fmt.Errorf("invalid image source: %v", err)
 	}
 
-	if !cp.b.Opts.NoCache {
+	if named := cp.srcRef.DockerReference(); named != nil {
+		registry := reference.Domain(named)
+		if cp.sysCtx.DockerAuthConfig == nil && cp.b.Opts.AuthFile != "" {
+			cp.sysCtx.DockerAuthConfig, err = auth.ReadDockerAuth(cp.b.Opts.AuthFile, registry)
+			if err != nil {
+				return err
+			}
+			if cp.sysCtx.DockerAuthConfig == nil {
+				sylog.Debugf("No credentials found for registry %s in %s", registry, cp.b.Opts.AuthFile)
+			}
+		}
+		defer func() {
+			err = registryAuthError(registry, err)
+		}()
+	}
+
+	// bound the registry download, the layer sizes are checked
+	// against the size limit before fetching any blob
+	if b.Recipe.Header["bootstrap"] == "docker" {
+		var cancel context.CancelFunc
+		ctx, cancel = client.WithDownloadTimeout(ctx)
+		defer cancel()
+	}
+
+	// the image is fetched by digest once its signature is verified
+	if b.Recipe.Header["bootstrap"] == "docker" && cp.b.Opts.VerifyBase {
+		if cp.b.Opts.FromCacheOnly {
+			return fmt.Errorf("the signature of docker images is fetched from the registry and can't be verified with --from-cache-only")
+		}
+		cp.srcRef, err = verifyDockerBase(ctx, cp.b, cp.srcRef, cp.sysCtx)
+		if err != nil {
+			return err
+		}
+	}
+
+	if b.Recipe.Header["bootstrap"] == "docker" && cp.b.Opts.FromCacheOnly {
+		// the registry is never queried, the image was checked
+		// when it was fetched into the cache
+		cp.srcRef, err = oci.CachedReference(ctx, b.Opts.ImgCache, cp.srcRef, cp.sysCtx)
+		if err != nil {
+			return err
+		}
+	} else {
+		if err := cp.checkPlatform(ctx); err != nil {
+			return fmt.Errorf("while checking %s: %v", ref, err)
+		}
+
+		if b.Recipe.Header["bootstrap"] == "docker" {
+			if err := cp.checkImageSize(ctx); err != nil {
+				return fmt.Errorf("while checking %s: %v", ref, err)
+			}
+		}
+	}
+
+	if !cp.b.Opts.NoCache && !cp.b.Opts.FromCacheOnly {
 		// Grab the modified source ref from the cache
 		cp.srcRef, err = oci.ConvertReference(ctx, b.Opts.ImgCache, cp.srcRef, cp.sysCtx)
 		if err != nil {
@@ -149,9 +241,37 @@ func (cp *OCIConveyorPacker) Get(ctx context.Context, b *sytypes.Bundle) (err er
 		return err
 	}
 
+	// apply the entrypoint, cmd and environment overrides, the
+	// runscript and environment sections still take precedence
+	if cp.b.Opts.OCIEntrypoint != nil {
+		sylog.Infof("Overriding image ENTRYPOINT %q with %q", cp.imgConfig.Entrypoint, cp.b.Opts.OCIEntrypoint)
+		cp.imgConfig.Entrypoint = cp.b.Opts.OCIEntrypoint
+	}
+	if cp.b.Opts.OCICmd != nil {
+		sylog.Infof("Overriding image CMD %q with %q", cp.imgConfig.Cmd, cp.b.Opts.OCICmd)
+		cp.imgConfig.Cmd = cp.b.Opts.OCICmd
+	}
+	if cp.b.Opts.DropDockerEnv {
+		sylog.Infof("Discarding image environment")
+		cp.imgConfig.Env = nil
+	}
+
 	return nil
 }
 
+// registryAuthError reports the registry requiring authentication
+// when err is an authorization failure.
+func registryAuthError(registry string, err error) error {
+	if err == nil {
+		return nil
+	}
+	msg := strings.ToLower(err.Error())
+	if strings.Contains(msg, "unauthorized") {
+		return fmt.Errorf("registry %s requires authentication, check your credentials: %v", registry, err)
+	}
+	return err
+}
+
 // Pack puts relevant objects in a Bundle.
 func (cp *OCIConveyorPacker) Pack(ctx context.Context) (*sytypes.Bundle, error) {
 	err := cp.unpackTmpfs(ctx)
@@ -191,6 +311,28 @@ func (cp *OCIConveyorPacker) fetch(ctx context.Context) error {
 	return err
 }
 
+// checkImageSize checks the size of the image layers against the
+// download size limit.
+func (cp *OCIConveyorPacker) checkImageSize(ctx context.Context) error {
+	if client.GetDownloadLimits(ctx).MaxSize <= 0 {
+		return nil
+	}
+
+	img, err := cp.srcRef.NewImage(ctx, cp.sysCtx)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	size := int64(0)
+	for _, layer := range img.LayerInfos() {
+		if layer.Size > 0 {
+			size += layer.Size
+		}
+	}
+	return client.CheckDownloadSize(ctx, size)
+}
+
 func (cp *OCIConveyorPacker) getConfig(ctx context.Context) (imgspecv1.ImageConfig, error) {
 	img, err := cp.srcRef.NewImage(ctx, cp.sysCtx)
 	if err != nil {
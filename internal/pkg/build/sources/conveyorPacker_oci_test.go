@@ -14,6 +14,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/sylabs/singularity/internal/pkg/build/sources"
@@ -296,3 +297,63 @@ func getTestTar(url string) (path string, err error) {
 
 	return dl.Name(), nil
 }
+
+func TestDockerSourceRef(t *testing.T) {
+	digest := "sha256:7fcfef4c2a27cd05b9ed6b5a3eb1a2a85ef9bc18f71c9e1c6dfb7ffc0ee4a7a0"
+
+	tests := []struct {
+		name         string
+		header       map[string]string
+		wantRef      string
+		wantImplicit []string
+	}{
+		{
+			name:         "NameOnly",
+			header:       map[string]string{"from": "ubuntu"},
+			wantRef:      "docker.io/library/ubuntu:latest",
+			wantImplicit: []string{"registry docker.io", "tag latest"},
+		},
+		{
+			name:         "NoRegistry",
+			header:       map[string]string{"from": "ubuntu:20.04"},
+			wantRef:      "docker.io/library/ubuntu:20.04",
+			wantImplicit: []string{"registry docker.io"},
+		},
+		{
+			name:         "NoTag",
+			header:       map[string]string{"from": "quay.io/centos/centos"},
+			wantRef:      "quay.io/centos/centos:latest",
+			wantImplicit: []string{"tag latest"},
+		},
+		{
+			name:    "Digest",
+			header:  map[string]string{"from": "quay.io/centos/centos@" + digest},
+			wantRef: "quay.io/centos/centos@" + digest,
+		},
+		{
+			name:    "Localhost",
+			header:  map[string]string{"from": "localhost/app:1.0"},
+			wantRef: "localhost/app:1.0",
+		},
+		{
+			name:    "RegistryHeader",
+			header:  map[string]string{"from": "centos:8", "namespace": "centos", "registry": "registry.example.com:5000"},
+			wantRef: "registry.example.com:5000/centos/centos:8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ref, implicit, err := sources.DockerSourceRef(types.Definition{Header: tt.header})
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if ref != tt.wantRef {
+				t.Errorf("got reference %s, want %s", ref, tt.wantRef)
+			}
+			if strings.Join(implicit, ",") != strings.Join(tt.wantImplicit, ",") {
+				t.Errorf("got implicit defaults %v, want %v", implicit, tt.wantImplicit)
+			}
+		})
+	}
+}
@@ -10,6 +10,7 @@ import (
 	"fmt"
 
 	"github.com/sylabs/singularity/internal/pkg/client/oras"
+	"github.com/sylabs/singularity/internal/pkg/util/auth"
 	"github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -29,9 +30,23 @@ func (cp *OrasConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err err
 	// full uri for name determination and output
 	fullRef := "oras:" + ref
 
-	imagePath, err := oras.Pull(ctx, b.Opts.ImgCache, fullRef, b.Opts.TmpDir, b.Opts.DockerAuthConfig)
+	authConf := b.Opts.DockerAuthConfig
+	registry := auth.NormalizeRegistry(b.Recipe.Header["from"])
+	if authConf == nil && b.Opts.AuthFile != "" {
+		authConf, err = auth.ReadDockerAuth(b.Opts.AuthFile, registry)
+		if err != nil {
+			return err
+		}
+	}
+
+	var imagePath string
+	if b.Opts.FromCacheOnly {
+		imagePath, err = oras.PullFromCache(b.Opts.ImgCache, fullRef)
+	} else {
+		imagePath, err = oras.Pull(ctx, b.Opts.ImgCache, fullRef, b.Opts.TmpDir, authConf)
+	}
 	if err != nil {
-		return fmt.Errorf("while fetching library image: %v", err)
+		return fmt.Errorf("while fetching library image: %v", registryAuthError(registry, err))
 	}
 
 	// insert base metadata before unpacking fs
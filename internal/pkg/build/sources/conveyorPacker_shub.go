@@ -29,7 +29,17 @@ func (cp *ShubConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err err
 
 	src := `shub://` + b.Recipe.Header["from"]
 
-	imagePath, err := shub.Pull(ctx, b.Opts.ImgCache, src, b.Opts.TmpDir, b.Opts.NoHTTPS)
+	noHTTPS := b.Opts.NoHTTPS || b.Opts.ShubNoHTTPS
+	if b.Opts.ShubNoHTTPS {
+		sylog.Warningf("HTTPS disabled for %s, the image is downloaded without transport security", src)
+	}
+
+	var imagePath string
+	if b.Opts.FromCacheOnly {
+		imagePath, err = shub.PullFromCache(b.Opts.ImgCache, src)
+	} else {
+		imagePath, err = shub.Pull(ctx, b.Opts.ImgCache, src, b.Opts.TmpDir, noHTTPS)
+	}
 	if err != nil {
 		return fmt.Errorf("while fetching library image: %v", err)
 	}
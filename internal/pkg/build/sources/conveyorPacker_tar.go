@@ -0,0 +1,121 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// TarStdin is the From value of the tar bootstrap reading the root
+// filesystem archive from the standard input.
+const TarStdin = "-"
+
+// gzipMagic starts the gzip compressed archives.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// TarConveyorPacker uses a root filesystem tar archive, built by other
+// tools, as the base of the container. The archive is read from a file
+// or from the standard input and may be gzip compressed.
+type TarConveyorPacker struct {
+	src string
+	b   *types.Bundle
+}
+
+// Get checks the root filesystem archive set by the From header, the
+// archive is read from the standard input when set to TarStdin and
+// relative paths are resolved from the build context if set.
+func (cp *TarConveyorPacker) Get(ctx context.Context, b *types.Bundle) error {
+	cp.b = b
+
+	src := b.Recipe.Header["from"]
+	if src == "" {
+		return fmt.Errorf("tar bootstrap requires a From header set to a root filesystem archive or %s for the standard input", TarStdin)
+	}
+	if src == TarStdin {
+		cp.src = src
+		return nil
+	}
+
+	if !filepath.IsAbs(src) && b.Opts.BuildContext != "" {
+		src = filepath.Join(b.Opts.BuildContext, src)
+	}
+	src, err := filepath.Abs(src)
+	if err != nil {
+		return fmt.Errorf("while resolving root filesystem archive: %v", err)
+	}
+	if !fs.IsFile(src) {
+		return fmt.Errorf("root filesystem archive %s doesn't exist or is not a regular file", src)
+	}
+
+	cp.src = src
+	return nil
+}
+
+// Pack extracts the root filesystem archive into the bundle, keeping the
+// ownership, permissions and symlinks recorded in the archive, and
+// installs the container metadata and actions, a default runscript is
+// added when the archive doesn't have one.
+func (cp *TarConveyorPacker) Pack(context.Context) (*types.Bundle, error) {
+	var r io.Reader = os.Stdin
+	if cp.src == TarStdin {
+		sylog.Infof("Extracting root filesystem from standard input")
+	} else {
+		sylog.Infof("Extracting root filesystem from %s", cp.src)
+		f, err := os.Open(cp.src)
+		if err != nil {
+			return nil, fmt.Errorf("while opening root filesystem archive: %v", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	r, err := decompressTar(r)
+	if err != nil {
+		return nil, fmt.Errorf("while reading root filesystem archive: %v", err)
+	}
+	if err := unpackTar(r, cp.b.RootfsPath); err != nil {
+		return nil, fmt.Errorf("while extracting root filesystem: %v", err)
+	}
+
+	if err := makeBaseEnv(cp.b.RootfsPath); err != nil {
+		return nil, fmt.Errorf("while inserting base environment: %v", err)
+	}
+
+	runscript := filepath.Join(cp.b.RootfsPath, ".singularity.d", "runscript")
+	if _, err := os.Lstat(runscript); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(runscript, []byte("#!/bin/sh\n"), 0755); err != nil {
+			return nil, fmt.Errorf("while inserting runscript: %v", err)
+		}
+	}
+
+	return cp.b, nil
+}
+
+// decompressTar returns a reader of the uncompressed content of the
+// archive r, gzip compressed archives being detected by their header.
+func decompressTar(r io.Reader) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(gzipMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	if !bytes.Equal(magic, gzipMagic) {
+		return br, nil
+	}
+	return gzip.NewReader(br)
+}
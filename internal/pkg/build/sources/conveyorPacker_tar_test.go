@@ -0,0 +1,148 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources_test
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/singularity/internal/pkg/build/sources"
+	"github.com/sylabs/singularity/internal/pkg/test"
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+// writeRootfsArchive writes a root filesystem tar archive at path,
+// gzip compressed if compress is set.
+func writeRootfsArchive(t *testing.T, path string, compress bool) {
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("failed to create archive: %v", err)
+	}
+	defer f.Close()
+
+	var w io.Writer = f
+	if compress {
+		gz := gzip.NewWriter(f)
+		defer gz.Close()
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	defer tw.Close()
+
+	content := []byte("ID=test\n")
+	headers := []*tar.Header{
+		{Name: "etc/", Typeflag: tar.TypeDir, Mode: 0755},
+		{Name: "etc/os-release", Typeflag: tar.TypeReg, Mode: 0640, Size: int64(len(content))},
+		{Name: "os-release", Typeflag: tar.TypeSymlink, Linkname: "etc/os-release"},
+	}
+	for _, hdr := range headers {
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("failed to write archive header: %v", err)
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(content); err != nil {
+				t.Fatalf("failed to write archive content: %v", err)
+			}
+		}
+	}
+}
+
+func TestTarConveyorPacker(t *testing.T) {
+	test.DropPrivilege(t)
+	defer test.ResetPrivilege(t)
+
+	dir, err := ioutil.TempDir("", "tar-rootfs-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	archive := filepath.Join(dir, "rootfs.tar")
+	writeRootfsArchive(t, archive, false)
+	compressed := filepath.Join(dir, "rootfs.tar.gz")
+	writeRootfsArchive(t, compressed, true)
+
+	tests := []struct {
+		name    string
+		from    string
+		wantErr bool
+	}{
+		{
+			name:    "NoFrom",
+			from:    "",
+			wantErr: true,
+		},
+		{
+			name:    "Missing",
+			from:    filepath.Join(dir, "missing.tar"),
+			wantErr: true,
+		},
+		{
+			name:    "Directory",
+			from:    dir,
+			wantErr: true,
+		},
+		{
+			name: "Archive",
+			from: archive,
+		},
+		{
+			name: "Compressed",
+			from: compressed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := types.NewBundle(filepath.Join(os.TempDir(), "sbuild-tar"), os.TempDir())
+			if err != nil {
+				t.Fatalf("failed to create bundle: %v", err)
+			}
+			defer b.Remove()
+
+			b.Recipe = types.Definition{
+				Header: map[string]string{
+					"bootstrap": "tar",
+					"from":      tt.from,
+				},
+			}
+
+			cp := &sources.TarConveyorPacker{}
+			err = cp.Get(context.Background(), b)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("failed to Get from %s: %v", tt.from, err)
+			}
+
+			if _, err := cp.Pack(context.Background()); err != nil {
+				t.Fatalf("failed to Pack from %s: %v", tt.from, err)
+			}
+
+			for _, f := range []string{"etc/os-release", ".singularity.d/actions/run", ".singularity.d/runscript"} {
+				if _, err := os.Stat(filepath.Join(b.RootfsPath, f)); err != nil {
+					t.Errorf("missing %s in bundle: %v", f, err)
+				}
+			}
+			if fi, err := os.Stat(filepath.Join(b.RootfsPath, "etc", "os-release")); err != nil || fi.Mode().Perm() != 0640 {
+				t.Errorf("unexpected permissions of etc/os-release: %v", err)
+			}
+			if link, err := os.Readlink(filepath.Join(b.RootfsPath, "os-release")); err != nil || link != "etc/os-release" {
+				t.Errorf("unexpected os-release symlink %q: %v", link, err)
+			}
+		})
+	}
+}
@@ -36,6 +36,7 @@ type YumConveyor struct {
 	osversion string
 	include   string
 	gpg       string
+	options   []string
 }
 
 // YumConveyorPacker only needs to hold the conveyor to have the needed data to pack
@@ -78,15 +79,15 @@ func (c *YumConveyor) Get(ctx context.Context, b *types.Bundle) (err error) {
 		return fmt.Errorf("while copying pseudo devices: %v", err)
 	}
 
-	args := []string{`--noplugins`, `-c`, filepath.Join(c.b.RootfsPath, yumConf), `--installroot`, c.b.RootfsPath, `--releasever=` + c.osversion, `-y`, `install`}
+	args := []string{`--noplugins`, `-c`, filepath.Join(c.b.RootfsPath, yumConf), `--installroot`, c.b.RootfsPath, `--releasever=` + c.osversion, `-y`}
+	args = append(args, c.options...)
+	args = append(args, `install`)
 	args = append(args, strings.Fields(c.include)...)
 
 	// Do the install
-	sylog.Debugf("\n\tInstall Command Path: %s\n\tDetected Arch: %s\n\tOSVersion: %s\n\tMirrorURL: %s\n\tUpdateURL: %s\n\tIncludes: %s\n", installCommandPath, runtime.GOARCH, c.osversion, c.mirrorurl, c.updateurl, c.include)
+	sylog.Debugf("\n\tInstall Command Path: %s\n\tDetected Arch: %s\n\tOSVersion: %s\n\tMirrorURL: %s\n\tUpdateURL: %s\n\tIncludes: %s\n\tOptions: %q\n", installCommandPath, runtime.GOARCH, c.osversion, c.mirrorurl, c.updateurl, c.include, c.options)
 	cmd := exec.Command(installCommandPath, args...)
-	// cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err = cmd.Run(); err != nil {
+	if err = runPackageTool(cmd, nil, c.b.Opts.StrictPackages); err != nil {
 		return fmt.Errorf("while bootstrapping: %v", err)
 	}
 
@@ -195,7 +196,9 @@ func (c *YumConveyor) getBootstrapOptions() (err error) {
 
 	c.include = include
 
-	return nil
+	c.options, err = conveyorOptions(c.b, "yum")
+
+	return err
 }
 
 func (c *YumConveyor) genYumConfig() (err error) {
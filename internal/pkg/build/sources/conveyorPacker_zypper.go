@@ -312,20 +312,28 @@ func (cp *ZypperConveyorPacker) Get(ctx context.Context, b *types.Bundle) (err e
 		}
 	}
 
-	args := []string{`--non-interactive`, `-c`, filepath.Join(cp.b.RootfsPath, zypperConf), `--root`, cp.b.RootfsPath, `--releasever=` + osversion, `-n`, `install`, `--auto-agree-with-licenses`, `--download-in-advance`}
+	options, err := conveyorOptions(cp.b, "zypper")
+	if err != nil {
+		return err
+	}
+
+	// extra options are zypper global options
+	args := []string{`--non-interactive`, `-c`, filepath.Join(cp.b.RootfsPath, zypperConf), `--root`, cp.b.RootfsPath, `--releasever=` + osversion, `-n`}
+	args = append(args, options...)
+	args = append(args, `install`, `--auto-agree-with-licenses`, `--download-in-advance`)
 	args = append(args, strings.Fields(include)...)
 
 	// Zypper install command
 	cmd := exec.Command(zypperPath, args...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
 
 	sylog.Debugf("\n\tZypper Path: %s\n\tDetected Arch: %s\n\tOSVersion: %s\n\tMirrorURL: %s\n\tIncludes: %s\n", zypperPath, runtime.GOARCH, osversion, mirrorurl, include)
 
-	// run zypper
-	if err = cmd.Run(); err != nil {
-		if ret, _ := system.GetExitCode(err); ret == 107 {
-			sylog.Warningf("Bootstrap succeeded, some RPM scripts failed")
+	// run zypper, exit status 107 is reported when only RPM scripts failed
+	if err = runPackageTool(cmd, os.Stdout, cp.b.Opts.StrictPackages); err != nil {
+		if ret, _ := system.GetExitCode(err); ret == 107 && !cp.b.Opts.StrictPackages {
+			if err := cp.b.Opts.Warnf(types.WarnRPMScripts, "Bootstrap succeeded, some RPM scripts failed"); err != nil {
+				return err
+			}
 		} else {
 			return fmt.Errorf("while bootstrapping from zypper: %v", err)
 		}
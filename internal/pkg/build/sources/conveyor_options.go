@@ -0,0 +1,48 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"fmt"
+
+	"github.com/sylabs/singularity/internal/pkg/util/shell"
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+// conveyorOptionsHeaders maps bootstrap agents to the definition
+// header keyword holding extra options for their underlying tool.
+var conveyorOptionsHeaders = map[string]string{
+	"arch":        "pacstrapoptions",
+	"debootstrap": "debootstrapoptions",
+	"yum":         "yumoptions",
+	"zypper":      "zypperoptions",
+}
+
+// CheckConveyorOptions returns an error if the definition header sets
+// extra options for a bootstrap agent other than the one in use.
+func CheckConveyorOptions(def types.Definition) error {
+	bootstrap := def.Header["bootstrap"]
+
+	for agent, key := range conveyorOptionsHeaders {
+		if _, ok := def.Header[key]; ok && agent != bootstrap {
+			return fmt.Errorf("%s header keyword is only supported by the %s bootstrap agent", key, agent)
+		}
+	}
+	return nil
+}
+
+// conveyorOptions returns the extra options set in the definition
+// header for the underlying tool of the given bootstrap agent. Options
+// are split with shell quoting rules but never interpreted by a shell.
+func conveyorOptions(b *types.Bundle, agent string) ([]string, error) {
+	key := conveyorOptionsHeaders[agent]
+
+	opts, err := shell.Split(b.Recipe.Header[key])
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s header: %v", key, err)
+	}
+	return opts, nil
+}
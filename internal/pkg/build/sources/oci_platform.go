@@ -0,0 +1,87 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/containers/image/v5/manifest"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	sytypes "github.com/sylabs/singularity/pkg/build/types"
+)
+
+// checkPlatform checks that the source image provides the requested
+// platform before any layer is downloaded. The platforms of a manifest
+// list are listed when the requested one is missing, a single architecture
+// image not matching the requested platform raises a warning.
+func (cp *OCIConveyorPacker) checkPlatform(ctx context.Context) error {
+	arch := cp.sysCtx.ArchitectureChoice
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+	variant := cp.sysCtx.VariantChoice
+
+	src, err := cp.srcRef.NewImageSource(ctx, cp.sysCtx)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	raw, mimeType, err := src.GetManifest(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("while getting manifest: %v", err)
+	}
+
+	if manifest.MIMETypeIsMultiImage(mimeType) {
+		// OCI indexes and docker manifest lists share the same layout
+		var index imgspecv1.Index
+		if err := json.Unmarshal(raw, &index); err != nil {
+			return fmt.Errorf("while decoding manifest list: %v", err)
+		}
+		var available []string
+		for _, m := range index.Manifests {
+			if m.Platform == nil {
+				continue
+			}
+			p := *m.Platform
+			if p.OS == "linux" && p.Architecture == arch && (variant == "" || p.Variant == variant) {
+				return nil
+			}
+			available = append(available, platformString(p.OS, p.Architecture, p.Variant))
+		}
+		return fmt.Errorf("platform %s is not provided by the image, available platforms: %s",
+			platformString("linux", arch, variant), strings.Join(available, ", "))
+	}
+
+	img, err := cp.srcRef.NewImage(ctx, cp.sysCtx)
+	if err != nil {
+		return err
+	}
+	defer img.Close()
+
+	config, err := img.OCIConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("while getting image configuration: %v", err)
+	}
+	if config.Architecture != "" && config.Architecture != arch {
+		return cp.b.Opts.Warnf(sytypes.WarnPlatformMismatch, "Requested platform %s but the image is a single %s image",
+			platformString("linux", arch, variant), platformString(config.OS, config.Architecture, ""))
+	}
+	return nil
+}
+
+// platformString returns the os/arch[/variant] form of a platform.
+func platformString(os, arch, variant string) string {
+	p := os + "/" + arch
+	if variant != "" {
+		p += "/" + variant
+	}
+	return p
+}
@@ -10,16 +10,23 @@
 package sources
 
 import (
+	"archive/tar"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	apexlog "github.com/apex/log"
 	"github.com/containers/image/v5/types"
 	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
 	"github.com/opencontainers/umoci"
+	"github.com/opencontainers/umoci/oci/casext"
 	umocilayer "github.com/opencontainers/umoci/oci/layer"
 	"github.com/opencontainers/umoci/pkg/idtools"
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
@@ -94,6 +101,13 @@ func unpackRootfs(ctx context.Context, b *sytypes.Bundle, tmpfsRef types.ImageRe
 		return fmt.Errorf("error unpacking rootfs: %s", err)
 	}
 
+	// Extract each layer separately when they are kept in the image
+	if b.Opts.KeepLayers {
+		if err := unpackLayers(ctx, b, engineExt, manifest, &mapOptions); err != nil {
+			return fmt.Errorf("error unpacking layers: %s", err)
+		}
+	}
+
 	// If the `--fix-perms` flag was used, then modify the permissions so that
 	// content has owner rwX and we're done
 	if b.Opts.FixPerms {
@@ -115,6 +129,93 @@ func unpackRootfs(ctx context.Context, b *sytypes.Bundle, tmpfsRef types.ImageRe
 
 }
 
+// whiteoutPrefix prefixes the name of the entries marking the removal of
+// lower layers content, opaqueWhiteout marks directories whose lower
+// layers content is hidden.
+const (
+	whiteoutPrefix = ".wh."
+	opaqueWhiteout = whiteoutPrefix + whiteoutPrefix + ".opq"
+)
+
+// unpackLayers extracts each layer of the manifest in its own directory
+// and records them, with their whiteouts, in the bundle.
+func unpackLayers(ctx context.Context, b *sytypes.Bundle, engineExt casext.Engine, manifest imgspecv1.Manifest, mapOptions *umocilayer.MapOptions) error {
+	b.Layers = nil
+
+	for i, desc := range manifest.Layers {
+		dir := filepath.Join(b.TmpDir, "layers", strconv.Itoa(i))
+		if err := os.MkdirAll(filepath.Dir(dir), 0755); err != nil {
+			return err
+		}
+
+		sylog.Debugf("Extracting layer %s in %s", desc.Digest, dir)
+		err := withLayerReader(ctx, engineExt, desc, func(r io.Reader) error {
+			return umocilayer.UnpackLayer(dir, r, mapOptions)
+		})
+		if err != nil {
+			return fmt.Errorf("while extracting layer %s: %s", desc.Digest, err)
+		}
+
+		// whiteouts are applied, and dropped, by the extraction, read
+		// them again from the layer archive
+		layer := sytypes.Layer{
+			Digest: desc.Digest.String(),
+			Dir:    dir,
+		}
+		err = withLayerReader(ctx, engineExt, desc, func(r io.Reader) error {
+			tr := tar.NewReader(r)
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					return nil
+				} else if err != nil {
+					return err
+				}
+				name := filepath.Clean(strings.TrimPrefix(hdr.Name, "/"))
+				dir, base := filepath.Split(name)
+				dir = filepath.Clean(dir)
+				switch {
+				case base == opaqueWhiteout:
+					layer.Opaques = append(layer.Opaques, dir)
+				case strings.HasPrefix(base, whiteoutPrefix):
+					layer.Whiteouts = append(layer.Whiteouts, filepath.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+				}
+			}
+		})
+		if err != nil {
+			return fmt.Errorf("while reading whiteouts of layer %s: %s", desc.Digest, err)
+		}
+
+		b.Layers = append(b.Layers, layer)
+	}
+	return nil
+}
+
+// withLayerReader calls fn with a reader of the uncompressed content of
+// the layer blob described by desc.
+func withLayerReader(ctx context.Context, engineExt casext.Engine, desc imgspecv1.Descriptor, fn func(io.Reader) error) error {
+	blob, err := engineExt.GetBlob(ctx, desc.Digest)
+	if err != nil {
+		return err
+	}
+	defer blob.Close()
+
+	var r io.Reader = blob
+	switch desc.MediaType {
+	case imgspecv1.MediaTypeImageLayer, imgspecv1.MediaTypeImageLayerNonDistributable:
+	case imgspecv1.MediaTypeImageLayerGzip, imgspecv1.MediaTypeImageLayerNonDistributableGzip:
+		gz, err := gzip.NewReader(blob)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	default:
+		return fmt.Errorf("unsupported layer media type %s", desc.MediaType)
+	}
+	return fn(r)
+}
+
 // fixPerms will work through the rootfs of this bundle, making sure that all
 // files and directories have permissions set such that the owner can read,
 // modify, delete. This brings us to the situation of <=3.4
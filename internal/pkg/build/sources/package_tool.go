@@ -0,0 +1,78 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// skippedPackageMessages are messages of yum, dnf and zypper reporting
+// packages or repositories which were skipped, these tools may still
+// exit with a zero status in such cases.
+var skippedPackageMessages = []string{
+	// yum and dnf
+	"No package",
+	"No match for argument",
+	"Unable to find a match",
+	"Nothing to do",
+	"Cannot download repomd.xml",
+	"Failed to download metadata",
+	"Cannot find a valid baseurl",
+	// zypper
+	"not found in package names",
+	"No provider of",
+	"Problem retrieving files from",
+	"Skipping repository",
+	"' is invalid",
+}
+
+// runPackageTool runs the package manager command cmd. Its standard output
+// is copied to stdout when not nil and its error output to the standard
+// error. With strict, the output of a successful command is checked for
+// skipped packages or repositories, which are reported as an error.
+func runPackageTool(cmd *exec.Cmd, stdout io.Writer, strict bool) error {
+	var out bytes.Buffer
+
+	if stdout != nil {
+		cmd.Stdout = io.MultiWriter(stdout, &out)
+	} else {
+		cmd.Stdout = &out
+	}
+	cmd.Stderr = io.MultiWriter(os.Stderr, &out)
+
+	if err := cmd.Run(); err != nil {
+		return err
+	}
+	if !strict {
+		return nil
+	}
+	if msg := skippedPackageMessage(&out); msg != "" {
+		return fmt.Errorf("%s skipped packages or repositories: %s", filepath.Base(cmd.Path), msg)
+	}
+	return nil
+}
+
+// skippedPackageMessage returns the first line of r holding one of the
+// skippedPackageMessages, or an empty string if there is none.
+func skippedPackageMessage(r io.Reader) string {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		for _, msg := range skippedPackageMessages {
+			if strings.Contains(line, msg) {
+				return line
+			}
+		}
+	}
+	return ""
+}
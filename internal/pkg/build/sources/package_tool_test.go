@@ -0,0 +1,67 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"bytes"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestRunPackageTool(t *testing.T) {
+	// the outputs of package managers hitting an unreachable repository
+	tests := []struct {
+		name    string
+		script  string
+		strict  bool
+		wantErr string
+	}{
+		{
+			name:   "Success",
+			script: "echo 'Installing: coreutils'; echo 'Complete!'",
+			strict: true,
+		},
+		{
+			name:    "ExitStatus",
+			script:  "echo 'Error: Failed to download metadata for repo base' >&2; exit 1",
+			wantErr: "exit status 1",
+		},
+		{
+			name:   "SkippedNotStrict",
+			script: "echo 'No match for argument: coreutils' >&2; echo 'Nothing to do.'",
+		},
+		{
+			name:    "SkippedYum",
+			script:  "echo 'No match for argument: coreutils' >&2; echo 'Nothing to do.'",
+			strict:  true,
+			wantErr: "No match for argument: coreutils",
+		},
+		{
+			name:    "SkippedZypper",
+			script:  "echo \"Problem retrieving files from 'repo'.\"; echo \"Package 'vim' not found.\"",
+			strict:  true,
+			wantErr: "Problem retrieving files from 'repo'.",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout bytes.Buffer
+
+			cmd := exec.Command("/bin/sh", "-c", tt.script)
+			err := runPackageTool(cmd, &stdout, tt.strict)
+			if tt.wantErr == "" && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			} else if tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Fatalf("got error %v, want %q", err, tt.wantErr)
+			}
+			if tt.name == "Success" && !strings.Contains(stdout.String(), "Complete!") {
+				t.Errorf("command output not copied: %q", stdout.String())
+			}
+		})
+	}
+}
@@ -73,5 +73,19 @@ func unpackSIF(b *types.Bundle, img *image.Image) (err error) {
 		}
 		b.JSONObjects[types.OCIConfigJSON] = ociConfig
 	}
+
+	// keep default bind paths of the base image
+	bindsReader, err := image.NewSectionReader(img, image.DefaultBindsSection, -1)
+	if err == image.ErrNoSection {
+		sylog.Debugf("No %s section found", image.DefaultBindsSection)
+	} else if err != nil {
+		return fmt.Errorf("could not get default bind paths section reader: %v", err)
+	} else {
+		binds, err := ioutil.ReadAll(bindsReader)
+		if err != nil {
+			return fmt.Errorf("could not read default bind paths: %v", err)
+		}
+		b.JSONObjects[types.DefaultBindsJSON] = binds
+	}
 	return nil
 }
@@ -0,0 +1,41 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// builtinConveyorPackers maps the builtin bootstrap agents to
+// their corresponding ConveyorPacker.
+var builtinConveyorPackers = map[string]types.ConveyorPackerFunc{
+	"library":        func() types.ConveyorPacker { return &LibraryConveyorPacker{} },
+	"oras":           func() types.ConveyorPacker { return &OrasConveyorPacker{} },
+	"shub":           func() types.ConveyorPacker { return &ShubConveyorPacker{} },
+	"docker":         func() types.ConveyorPacker { return &OCIConveyorPacker{} },
+	"docker-archive": func() types.ConveyorPacker { return &OCIConveyorPacker{} },
+	"docker-daemon":  func() types.ConveyorPacker { return &OCIConveyorPacker{} },
+	"oci":            func() types.ConveyorPacker { return &OCIConveyorPacker{} },
+	"oci-archive":    func() types.ConveyorPacker { return &OCIConveyorPacker{} },
+	"busybox":        func() types.ConveyorPacker { return &BusyBoxConveyorPacker{} },
+	"debootstrap":    func() types.ConveyorPacker { return &DebootstrapConveyorPacker{} },
+	"arch":           func() types.ConveyorPacker { return &ArchConveyorPacker{} },
+	"localimage":     func() types.ConveyorPacker { return &LocalConveyorPacker{} },
+	"yum":            func() types.ConveyorPacker { return &YumConveyorPacker{} },
+	"zypper":         func() types.ConveyorPacker { return &ZypperConveyorPacker{} },
+	"scratch":        func() types.ConveyorPacker { return &ScratchConveyorPacker{} },
+	"directory":      func() types.ConveyorPacker { return &DirectoryConveyorPacker{} },
+	"tar":            func() types.ConveyorPacker { return &TarConveyorPacker{} },
+}
+
+func init() {
+	for agent, fn := range builtinConveyorPackers {
+		if err := types.RegisterConveyorPacker(agent, fn); err != nil {
+			sylog.Fatalf("While registering bootstrap agent %s: %s", agent, err)
+		}
+	}
+}
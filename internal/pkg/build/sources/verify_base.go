@@ -0,0 +1,152 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"context"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	"github.com/containers/image/v5/image"
+	"github.com/containers/image/v5/manifest"
+	"github.com/containers/image/v5/signature"
+	"github.com/containers/image/v5/types"
+	"github.com/sylabs/singularity/internal/app/singularity"
+	sytypes "github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/sylog"
+	"github.com/sylabs/singularity/pkg/sypgp"
+)
+
+// verifyBaseAgents are the bootstrap agents whose base image signature
+// can be verified with --verify-base.
+var verifyBaseAgents = map[string]bool{
+	"docker":  true,
+	"library": true,
+}
+
+// localBaseAgents are the bootstrap agents building from a local image,
+// directory or archive, or from no image at all, which don't pull a base
+// image and have no signature to verify with --verify-base.
+var localBaseAgents = map[string]bool{
+	"directory":      true,
+	"docker-archive": true,
+	"docker-daemon":  true,
+	"localimage":     true,
+	"oci":            true,
+	"oci-archive":    true,
+	"scratch":        true,
+	"tar":            true,
+}
+
+// CheckVerifyBase returns an error if the bootstrap agent of the
+// definition pulls a remote base image whose signature can't be verified.
+// Stages built from a local source are not verified.
+func CheckVerifyBase(def sytypes.Definition) error {
+	agent := def.Header["bootstrap"]
+	if !verifyBaseAgents[agent] && !localBaseAgents[agent] {
+		return fmt.Errorf("the signature of %s bootstrap images can't be verified with --verify-base, only library and docker images", agent)
+	}
+	return nil
+}
+
+// checkBaseKeyring returns an error if the base keyring file at path holds
+// a PEM encoded key, as used by cosign, instead of a GPG keyring: cosign
+// signatures are not supported by --verify-base.
+func checkBaseKeyring(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("while reading base keyring %s: %v", path, err)
+	}
+	if block, _ := pem.Decode(data); block != nil && !strings.HasPrefix(block.Type, "PGP ") {
+		return fmt.Errorf("base keyring %s holds a %s, as used by cosign: cosign signatures are not supported by --verify-base, only GPG keyrings", path, strings.ToLower(block.Type))
+	}
+	return nil
+}
+
+// verifyLibraryBase verifies the signatures of the library SIF image at
+// path with the public keys of the base keyring of b, and records its
+// SHA-256 digest as the verified base of b.
+func verifyLibraryBase(ctx context.Context, b *sytypes.Bundle, path string) error {
+	if err := checkBaseKeyring(b.Opts.BaseKeyring); err != nil {
+		return err
+	}
+	kr, err := sypgp.KeyRingFromFile(b.Opts.BaseKeyring)
+	if err != nil {
+		return fmt.Errorf("while loading base keyring %s: %v", b.Opts.BaseKeyring, err)
+	}
+
+	sylog.Infof("Verifying base image signatures")
+	if err := singularity.Verify(ctx, path, singularity.OptVerifyUseKeyRing(kr)); err != nil {
+		return fmt.Errorf("while verifying base image: %v", err)
+	}
+
+	d, err := singularity.GetSIFDigest(path)
+	if err != nil {
+		return err
+	}
+	b.VerifiedBase = "sha256:" + d.SHA256
+	sylog.Infof("Verified base image %s", b.VerifiedBase)
+	return nil
+}
+
+// verifyDockerBase verifies that the manifest of the docker image ref is
+// signed by a key of the base keyring of b, using the signatures of the
+// registry or of its lookaside storage configured in registries.d, and
+// records the manifest digest as the verified base of b. The returned
+// reference is ref pinned to the verified digest, so that the fetched
+// image is the verified one even if its tag is moved meanwhile.
+func verifyDockerBase(ctx context.Context, b *sytypes.Bundle, ref types.ImageReference, sys *types.SystemContext) (types.ImageReference, error) {
+	named := ref.DockerReference()
+	if named == nil {
+		return nil, fmt.Errorf("no docker reference for %s", ref.StringWithinTransport())
+	}
+
+	if err := checkBaseKeyring(b.Opts.BaseKeyring); err != nil {
+		return nil, err
+	}
+	req, err := signature.NewPRSignedByKeyPath(signature.SBKeyTypeGPGKeys, b.Opts.BaseKeyring, signature.NewPRMMatchRepoDigestOrExact())
+	if err != nil {
+		return nil, fmt.Errorf("while loading base keyring %s: %v", b.Opts.BaseKeyring, err)
+	}
+	policyCtx, err := signature.NewPolicyContext(&signature.Policy{Default: []signature.PolicyRequirement{req}})
+	if err != nil {
+		return nil, err
+	}
+	defer policyCtx.Destroy()
+
+	src, err := ref.NewImageSource(ctx, sys)
+	if err != nil {
+		return nil, err
+	}
+	defer src.Close()
+
+	sylog.Infof("Verifying base image signatures")
+	unparsed := image.UnparsedInstance(src, nil)
+	if ok, err := policyCtx.IsRunningImageAllowed(ctx, unparsed); !ok {
+		return nil, fmt.Errorf("while verifying base image %s: %v", named, err)
+	}
+
+	raw, _, err := unparsed.Manifest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	dgst, err := manifest.Digest(raw)
+	if err != nil {
+		return nil, err
+	}
+	b.VerifiedBase = dgst.String()
+	sylog.Infof("Verified base image %s", b.VerifiedBase)
+
+	pinned, err := reference.WithDigest(reference.TrimNamed(named), dgst)
+	if err != nil {
+		return nil, err
+	}
+	return docker.NewReference(pinned)
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package sources
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestCheckVerifyBase(t *testing.T) {
+	tests := []struct {
+		agent   string
+		wantErr bool
+	}{
+		{agent: "library"},
+		{agent: "docker"},
+		{agent: "localimage"},
+		{agent: "oci-archive"},
+		{agent: "scratch"},
+		{agent: "shub", wantErr: true},
+		{agent: "oras", wantErr: true},
+		{agent: "debootstrap", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.agent, func(t *testing.T) {
+			def := types.Definition{Header: map[string]string{"bootstrap": tt.agent}}
+			err := CheckVerifyBase(def)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestCheckBaseKeyring(t *testing.T) {
+	tests := []struct {
+		name    string
+		key     string
+		wantErr bool
+	}{
+		{name: "Empty", key: ""},
+		{name: "GPG", key: "-----BEGIN PGP PUBLIC KEY BLOCK-----\n\nmQENBF9=\n=abcd\n-----END PGP PUBLIC KEY BLOCK-----\n"},
+		{name: "Cosign", key: "-----BEGIN PUBLIC KEY-----\nMFkwEwYHKoZIzj0CAQYIKoZIzj0DAQcDQgAE\n-----END PUBLIC KEY-----\n", wantErr: true},
+	}
+
+	dir, err := ioutil.TempDir("", "base-keyring-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, tt.name)
+			if err := ioutil.WriteFile(path, []byte(tt.key), 0644); err != nil {
+				t.Fatalf("failed to write keyring: %s", err)
+			}
+			err := checkBaseKeyring(path)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
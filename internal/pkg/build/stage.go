@@ -6,7 +6,9 @@
 package build
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -14,7 +16,9 @@ import (
 	"syscall"
 
 	"github.com/sylabs/singularity/internal/pkg/build/files"
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
 	"github.com/sylabs/singularity/internal/pkg/buildcfg"
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/pkg/build/types"
 	"github.com/sylabs/singularity/pkg/sylog"
 )
@@ -29,6 +33,8 @@ type stage struct {
 	a Assembler
 	// b is an intermediate structure that encapsulates all information for the container, e.g., metadata, filesystems.
 	b *types.Bundle
+	// base is the bootstrapped root filesystem of layered builds.
+	base *baseLayer
 }
 
 const sEnvironment = "SINGULARITY_ENVIRONMENT=/.singularity.d/env/91-environment.sh"
@@ -45,6 +51,17 @@ func (s *stage) runSectionScript(name string, script types.Script) error {
 			return fmt.Errorf("attempted to build with scripts as non-root user or without --fakeroot")
 		}
 
+		if dir, _, err := getSectionWorkDir(name, script); err != nil {
+			return err
+		} else if dir != "" {
+			return fmt.Errorf("bad %s section '-w' parameter: only supported by %%post and %%test sections", name)
+		}
+		if envFile, _, err := getSectionEnvFile(name, script); err != nil {
+			return err
+		} else if envFile != "" {
+			return fmt.Errorf("bad %s section '--env-file' parameter: only supported by %%post and %%test sections", name)
+		}
+
 		sRootfs := "SINGULARITY_ROOTFS=" + s.b.RootfsPath
 
 		scriptPath := filepath.Join(s.b.TmpDir, name)
@@ -53,10 +70,16 @@ func (s *stage) runSectionScript(name string, script types.Script) error {
 		}
 		defer os.Remove(scriptPath)
 
-		args, err := getSectionScriptArgs(name, scriptPath, script)
+		args, err := getSectionScriptArgs(name, scriptPath, script, s.b.Opts.TraceScripts, s.b.Opts.ShellFlags)
 		if err != nil {
 			return fmt.Errorf("while processing section %%%s arguments: %s", name, err)
 		}
+		if s.b.Opts.SeccompProfile != "" {
+			// host sections are run through a helper loading the
+			// seccomp filter before executing them
+			exe := filepath.Join(buildcfg.BINDIR, "singularity")
+			args = append([]string{exe, "seccomp-exec", s.b.Opts.SeccompProfile}, args...)
+		}
 
 		// Run script section here
 		cmd := exec.Command(args[0], args[1:]...)
@@ -64,19 +87,106 @@ func (s *stage) runSectionScript(name string, script types.Script) error {
 		cmd.Stderr = os.Stderr
 		cmd.Env = os.Environ()
 		cmd.Env = append(cmd.Env, sEnvironment, sRootfs)
+		if s.b.Opts.TraceScripts {
+			cmd.Env = append(cmd.Env, tracePrefix(name))
+		}
 
 		sylog.Infof("Running %s scriptlet", name)
-		if err := cmd.Run(); err != nil {
-			return fmt.Errorf("failed to run %%%s script: %v", name, err)
+		if err := progress.Section(s.name, name, cmd.Run); err != nil {
+			return fmt.Errorf("failed to run %%%s script: %v", name, s.seccompError(err))
 		}
 	}
 	return nil
 }
 
-func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts string) error {
+// runScripts runs the %post section, inserts the metadata and runs the
+// %test section. The sections use the host /etc/resolv.conf and /etc/hosts,
+// or a resolv.conf using the --dns name servers, bound in the root
+// filesystem. Bind targets created for the sections are removed before
+// returning, even on failure, so that they are not left in the image.
+func (s *stage) runScripts(configData []byte) error {
+	dns, err := resolvConf(s.b.Opts.DNS)
+	if err != nil {
+		return err
+	}
+
+	// placeholders created in the root filesystem as bind targets
+	var placeholders []string
+
+	// create stage file for /etc/resolv.conf and /etc/hosts
+	sessionResolv, placeholder, err := createStageFile("/etc/resolv.conf", dns, s.b, "Name resolution could fail")
+	if err != nil {
+		return err
+	} else if sessionResolv != "" {
+		defer os.Remove(sessionResolv)
+	}
+	if placeholder != "" {
+		defer os.Remove(placeholder)
+		placeholders = append(placeholders, placeholder)
+	}
+	sessionHosts, placeholder, err := createStageFile("/etc/hosts", nil, s.b, "Host resolution could fail")
+	if err != nil {
+		return err
+	} else if sessionHosts != "" {
+		defer os.Remove(sessionHosts)
+	}
+	if placeholder != "" {
+		defer os.Remove(placeholder)
+		placeholders = append(placeholders, placeholder)
+	}
+
+	// write the build configuration used for %post and %test sections
+	configFile := filepath.Join(s.b.TmpDir, "singularity.conf")
+	if err := ioutil.WriteFile(configFile, configData, 0644); err != nil {
+		return fmt.Errorf("while creating %s: %s", configFile, err)
+	}
+	defer os.Remove(configFile)
+
 	if s.b.Recipe.BuildData.Post.Script != "" {
-		cmdArgs := []string{"-s", "-c", configFile, "exec", "--pwd", "/", "--writable"}
+		if err := s.runPost("post", s.b.Recipe.BuildData.Post, configFile, sessionResolv, sessionHosts); err != nil {
+			return fmt.Errorf("while running engine: %v", err)
+		}
+	} else if len(s.b.Recipe.BuildData.PostFragments) > 0 {
+		if err := s.runPostFragments(configFile, sessionResolv, sessionHosts, placeholders); err != nil {
+			return fmt.Errorf("while running engine: %v", err)
+		}
+	}
+
+	sylog.Debugf("Inserting Metadata")
+	if err := s.insertMetadata(); err != nil {
+		return fmt.Errorf("while inserting metadata to bundle: %v", err)
+	}
+
+	if err := s.runTestScript(configFile, sessionResolv, sessionHosts); err != nil {
+		return fmt.Errorf("failed to execute %%test script: %v", err)
+	}
+	return nil
+}
+
+// runPostScript runs script in the root filesystem as the %post section,
+// or as the %post:NAME fragment designated by section.
+func (s *stage) runPostScript(section string, script types.Script, configFile, sessionResolv, sessionHosts string) error {
+	if script.Script != "" {
+		dir, script, err := getSectionWorkDir("post", script)
+		if err != nil {
+			return err
+		}
+		if dir, err = s.createWorkDir(dir); err != nil {
+			return fmt.Errorf("while creating %%post working directory: %s", err)
+		}
+		envArgs, script, err := s.envFileArgs("post", script)
+		if err != nil {
+			return err
+		}
+
+		cmdArgs := []string{"-s", "-c", configFile, "exec", "--pwd", dir, "--writable"}
 		cmdArgs = append(cmdArgs, "--cleanenv", "--env", sEnvironment)
+		cmdArgs = append(cmdArgs, envArgs...)
+		cmdArgs = append(cmdArgs, s.networkArgs("post")...)
+		cmdArgs = append(cmdArgs, s.seccompArgs()...)
+		if s.b.Opts.TraceScripts {
+			cmdArgs = append(cmdArgs, "--env", tracePrefix("post"))
+		}
 
 		if sessionResolv != "" {
 			cmdArgs = append(cmdArgs, "-B", sessionResolv+":/etc/resolv.conf")
@@ -85,37 +195,75 @@ func (s *stage) runPostScript(configFile, sessionResolv, sessionHosts string) er
 			cmdArgs = append(cmdArgs, "-B", sessionHosts+":/etc/hosts")
 		}
 
-		script := s.b.Recipe.BuildData.Post
 		scriptPath := filepath.Join(s.b.RootfsPath, ".post.script")
 		if err := createScript(scriptPath, []byte(script.Script)); err != nil {
 			return fmt.Errorf("while creating post script: %s", err)
 		}
 		defer os.Remove(scriptPath)
 
-		args, err := getSectionScriptArgs("post", "/.post.script", script)
+		args, err := getSectionScriptArgs("post", "/.post.script", script, s.b.Opts.TraceScripts, s.b.Opts.ShellFlags)
 		if err != nil {
 			return fmt.Errorf("while processing section %%post arguments: %s", err)
 		}
 
 		exe := filepath.Join(buildcfg.BINDIR, "singularity")
 
-		cmdArgs = append(cmdArgs, s.b.RootfsPath)
-		cmdArgs = append(cmdArgs, args...)
-		cmd := exec.Command(exe, cmdArgs...)
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Dir = "/"
-		cmd.Env = currentEnvNoSingularity()
+		runArgs := append([]string{}, cmdArgs...)
+		runArgs = append(runArgs, s.b.RootfsPath)
+		runArgs = append(runArgs, args...)
+
+		// a command can only be run once, a new one is created for each attempt
+		run := func() error {
+			cmd := exec.Command(exe, runArgs...)
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			cmd.Dir = "/"
+			cmd.Env = currentEnvNoSingularity()
+			return progress.Section(s.name, section, cmd.Run)
+		}
 
-		sylog.Infof("Running post scriptlet")
-		return cmd.Run()
+		sylog.Infof("Running %s scriptlet", section)
+		if err := retryScript(section, s.b.Opts.RetryPost, run); err != nil {
+			err = s.seccompError(s.networkError("post", err))
+			if s.b.Opts.InteractivePost {
+				return s.interactivePost(section, cmdArgs, err)
+			}
+			return err
+		}
 	}
 	return nil
 }
 
 func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts string) error {
 	if !s.b.Opts.NoTest && s.b.Recipe.BuildData.Test.Script != "" {
-		cmdArgs := []string{"-s", "-c", configFile, "test", "--pwd", "/"}
+		dir, _, err := getSectionWorkDir("test", s.b.Recipe.BuildData.Test)
+		if err != nil {
+			return err
+		}
+		if dir, err = s.createWorkDir(dir); err != nil {
+			return fmt.Errorf("while creating %%test working directory: %s", err)
+		}
+		envArgs, _, err := s.envFileArgs("test", s.b.Recipe.BuildData.Test)
+		if err != nil {
+			return err
+		}
+
+		cmdArgs := []string{"-s", "-c", configFile, "test", "--pwd", dir}
+		shellArgs := append([]string{}, s.b.Opts.ShellFlags...)
+		if s.b.Opts.TraceScripts {
+			shellArgs = append([]string{"-x"}, shellArgs...)
+		}
+		if len(shellArgs) > 0 {
+			// run the test script through exec to trace its commands
+			// or apply the shell flags
+			cmdArgs = []string{"-s", "-c", configFile, "exec", "--pwd", dir}
+			if s.b.Opts.TraceScripts {
+				cmdArgs = append(cmdArgs, "--env", tracePrefix("test"))
+			}
+		}
+		cmdArgs = append(cmdArgs, envArgs...)
+		cmdArgs = append(cmdArgs, s.networkArgs("test")...)
+		cmdArgs = append(cmdArgs, s.seccompArgs()...)
 
 		if sessionResolv != "" {
 			cmdArgs = append(cmdArgs, "-B", sessionResolv+":/etc/resolv.conf")
@@ -127,6 +275,11 @@ func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts string) er
 		exe := filepath.Join(buildcfg.BINDIR, "singularity")
 
 		cmdArgs = append(cmdArgs, s.b.RootfsPath)
+		if len(shellArgs) > 0 {
+			cmdArgs = append(cmdArgs, "/bin/sh")
+			cmdArgs = append(cmdArgs, shellArgs...)
+			cmdArgs = append(cmdArgs, "/.singularity.d/test")
+		}
 		cmd := exec.Command(exe, cmdArgs...)
 		cmd.Stdout = os.Stdout
 		cmd.Stderr = os.Stderr
@@ -134,11 +287,101 @@ func (s *stage) runTestScript(configFile, sessionResolv, sessionHosts string) er
 		cmd.Env = currentEnvNoSingularity()
 
 		sylog.Infof("Running testscript")
-		return cmd.Run()
+		if err := progress.Section(s.name, "test", cmd.Run); err != nil {
+			return s.seccompError(s.networkError("test", err))
+		}
 	}
 	return nil
 }
 
+// createWorkDir creates the section working directory dir in the root
+// filesystem if absent, the root directory is used by default.
+func (s *stage) createWorkDir(dir string) (string, error) {
+	if dir == "" {
+		return "/", nil
+	}
+	path := filepath.Join(s.b.RootfsPath, fs.EvalRelative(dir, s.b.RootfsPath))
+	return dir, os.MkdirAll(path, 0755)
+}
+
+// envFileArgs returns the arguments loading the environment file set
+// with the --env-file option of the %post or %test section, resolved
+// from the build context, and the section without the option. The
+// variables are only set in the section environment.
+func (s *stage) envFileArgs(section string, script types.Script) ([]string, types.Script, error) {
+	envFile, script, err := getSectionEnvFile(section, script)
+	if err != nil || envFile == "" {
+		return nil, script, err
+	}
+
+	if !filepath.IsAbs(envFile) {
+		envFile = filepath.Join(s.b.Opts.BuildContext, envFile)
+		if envFile, err = filepath.Abs(envFile); err != nil {
+			return nil, script, err
+		}
+	}
+	if !fs.IsFile(envFile) {
+		return nil, script, fmt.Errorf("bad %s section '--env-file' parameter: %s is not a file", section, envFile)
+	}
+	sylog.Debugf("Loading %%%s environment from %s", section, envFile)
+	return []string{"--env-file", envFile}, script, nil
+}
+
+// networkArgs returns the arguments isolating the %post or %test
+// section in a network namespace with only a loopback interface
+// when network access is disabled for it.
+func (s *stage) networkArgs(section string) []string {
+	if !s.noNetwork(section) {
+		return nil
+	}
+	return []string{"--net", "--network", "none"}
+}
+
+// networkError notes in err that network access was disabled for the
+// section, as the most likely cause of a failure to fetch dependencies.
+func (s *stage) networkError(section string, err error) error {
+	if !s.noNetwork(section) {
+		return err
+	}
+	return fmt.Errorf("%s (network access is disabled for %%%s)", err, section)
+}
+
+// seccompArgs returns the arguments applying the --seccomp-profile
+// profile to the %post and %test sections.
+func (s *stage) seccompArgs() []string {
+	if s.b.Opts.SeccompProfile == "" {
+		return nil
+	}
+	return []string{"--security", "seccomp:" + s.b.Opts.SeccompProfile}
+}
+
+// seccompError notes in err that the section ran under a seccomp
+// profile, blocked system calls failing with "Operation not permitted".
+func (s *stage) seccompError(err error) error {
+	if s.b.Opts.SeccompProfile == "" {
+		return err
+	}
+	return fmt.Errorf("%s (the section ran under the seccomp profile %s, blocked system calls fail with \"Operation not permitted\")", err, s.b.Opts.SeccompProfile)
+}
+
+func (s *stage) noNetwork(section string) bool {
+	if section == "post" {
+		return s.b.Opts.NoNetworkPost
+	}
+	return s.b.Opts.NoNetworkTest
+}
+
+// hasFiles returns if the %files sections of the stage copy files from
+// other stages, with fromStage set, or from the host.
+func (s *stage) hasFiles(fromStage bool) bool {
+	for _, f := range s.b.Recipe.BuildData.Files {
+		if (f.Args != "") == fromStage && len(f.Files) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
 func (s *stage) copyFilesFrom(b *Build) error {
 	def := s.b.Recipe
 	for _, f := range def.BuildData.Files {
@@ -161,7 +404,9 @@ func (s *stage) copyFilesFrom(b *Build) error {
 		for _, transfer := range f.Files {
 			// sanity
 			if transfer.Src == "" {
-				sylog.Warningf("Attempt to copy file with no name, skipping.")
+				if err := s.b.Opts.Warnf(types.WarnEmptyFilesSource, "Attempt to copy file with no name, skipping."); err != nil {
+					return err
+				}
 				continue
 			}
 			// dest = source if not specified
@@ -184,7 +429,7 @@ func (s *stage) copyFilesFrom(b *Build) error {
 	return nil
 }
 
-func (s *stage) copyFiles() error {
+func (s *stage) copyFiles(ctx context.Context) error {
 	def := s.b.Recipe
 	filesSection := types.Files{}
 	for _, f := range def.BuildData.Files {
@@ -192,24 +437,139 @@ func (s *stage) copyFiles() error {
 			filesSection = f
 		}
 	}
+
+	// when updating a sandbox, the manifest records the hash of copied
+	// sources and destinations, sources are skipped if both are unchanged
+	var manifest *files.Manifest
+	manifestPath := filepath.Join(s.b.RootfsPath, files.ManifestPath)
+	if s.b.Opts.SandboxTarget && s.b.Opts.Update && !s.b.Opts.Force {
+		m, err := files.LoadManifest(manifestPath)
+		if err != nil {
+			if err := s.b.Opts.Warnf(types.WarnFilesManifest, "Ignoring %%files manifest: %s", err); err != nil {
+				return err
+			}
+			m = new(files.Manifest)
+		}
+		manifest = m
+	}
+
+	copied, skipped := 0, 0
+	var updated []files.ManifestEntry
+	// with --files-jobs, entries are copied once all of them are
+	// listed, they are copied as they are listed otherwise
+	var jobs []filesJob
+	addJob := func(j filesJob) error {
+		if s.b.Opts.FilesJobs > 1 {
+			jobs = append(jobs, j)
+			return nil
+		}
+		return j.run()
+	}
+
 	// iterate through filetransfers
 	for _, transfer := range filesSection.Files {
 		// sanity
 		if transfer.Src == "" {
-			sylog.Warningf("Attempt to copy file with no name, skipping.")
+			if err := s.b.Opts.Warnf(types.WarnEmptyFilesSource, "Attempt to copy file with no name, skipping."); err != nil {
+				return err
+			}
+			continue
+		}
+		// URL sources are downloaded, never recorded in the manifest
+		if isURLSource(transfer.Src) {
+			u, err := parseURLTransfer(transfer)
+			if err != nil {
+				return err
+			}
+			err = addJob(filesJob{
+				dst: u.dst,
+				run: func() error { return s.downloadFile(ctx, u) },
+			})
+			if err != nil {
+				return err
+			}
+			copied++
 			continue
 		}
 		// dest = source if not specified
 		if transfer.Dst == "" {
 			transfer.Dst = transfer.Src
 		}
+		if s.b.Opts.BuildContext != "" && !filepath.IsAbs(transfer.Src) {
+			transfer.Src = filepath.Join(s.b.Opts.BuildContext, transfer.Src)
+		}
+
+		var hash string
+		if manifest != nil {
+			h, err := files.Hash(transfer.Src)
+			if err != nil {
+				if err := s.b.Opts.Warnf(types.WarnFilesHash, "Could not hash %s: %s", transfer.Src, err); err != nil {
+					return err
+				}
+			}
+			hash = h
+			if e, ok := manifest.Lookup(transfer.Src, transfer.Dst); ok && hash != "" && e.Hash == hash {
+				// the destination may have been modified since the copy
+				dstHash, err := files.HashPath(filepath.Join(s.b.RootfsPath, transfer.Dst))
+				if err == nil && dstHash == e.DstHash {
+					sylog.Infof("Skipping unchanged %v", transfer.Src)
+					skipped++
+					continue
+				}
+			}
+		}
+		src, dst := transfer.Src, transfer.Dst
+
 		// copy each file into bundle rootfs
 		// copying from host to container should follow symlinks
-		transfer.Dst = files.AddPrefix(s.b.RootfsPath, transfer.Dst)
-		sylog.Infof("Copying %v to %v", transfer.Src, transfer.Dst)
-		if err := files.Copy(transfer.Src, transfer.Dst, true); err != nil {
+		fullDst := files.AddPrefix(s.b.RootfsPath, transfer.Dst)
+		err := addJob(filesJob{
+			dst: dst,
+			run: func() error {
+				sylog.Infof("Copying %v to %v", src, fullDst)
+				return files.Copy(src, fullDst, true)
+			},
+		})
+		if err != nil {
 			return err
 		}
+
+		copied++
+
+		if manifest != nil && hash != "" {
+			updated = append(updated, files.ManifestEntry{Src: src, Dst: dst, Hash: hash})
+		}
+	}
+
+	if len(jobs) > 0 {
+		sylog.Infof("Copying %d %%files source(s) with up to %d job(s)", len(jobs), s.b.Opts.FilesJobs)
+		if err := runFilesJobs(s.b.RootfsPath, jobs, s.b.Opts.FilesJobs); err != nil {
+			return err
+		}
+	}
+
+	// destinations are hashed once all sources are copied as
+	// several sources may be copied in the same directory
+	for _, e := range updated {
+		dstHash, err := files.HashPath(filepath.Join(s.b.RootfsPath, e.Dst))
+		if err != nil {
+			if err := s.b.Opts.Warnf(types.WarnFilesHash, "Could not hash %s: %s", e.Dst, err); err != nil {
+				return err
+			}
+			continue
+		}
+		e.DstHash = dstHash
+		manifest.Set(e)
+	}
+
+	if skipped > 0 {
+		sylog.Infof("%%files: %d source(s) copied, %d unchanged source(s) skipped", copied, skipped)
+	}
+
+	if manifest != nil && len(manifest.Files) > 0 {
+		if err := manifest.Save(manifestPath); err != nil {
+			return fmt.Errorf("while writing %%files manifest: %s", err)
+		}
 	}
 
 	return nil
@@ -6,11 +6,14 @@
 package build
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"os"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	ocitypes "github.com/containers/image/v5/types"
@@ -23,7 +26,7 @@ import (
 )
 
 // ConvertOciToSIf will convert an OCI source into a SIF using the build routines
-func ConvertOciToSIF(ctx context.Context, imgCache *cache.Handle, image, cachedImgPath, tmpDir string, noHTTPS, noCleanUp bool, authConf *ocitypes.DockerAuthConfig) error {
+func ConvertOciToSIF(ctx context.Context, imgCache *cache.Handle, image, cachedImgPath, arch, variant, tmpDir string, noHTTPS, noCleanUp bool, authConf *ocitypes.DockerAuthConfig) error {
 	if imgCache == nil {
 		return fmt.Errorf("image cache is undefined")
 	}
@@ -41,6 +44,8 @@ func ConvertOciToSIF(ctx context.Context, imgCache *cache.Handle, image, cachedI
 				NoHTTPS:          noHTTPS,
 				DockerAuthConfig: authConf,
 				ImgCache:         imgCache,
+				Arch:             arch,
+				ArchVariant:      variant,
 			},
 		},
 	)
@@ -51,29 +56,64 @@ func ConvertOciToSIF(ctx context.Context, imgCache *cache.Handle, image, cachedI
 	return b.Full(ctx)
 }
 
-func createStageFile(source string, b *types.Bundle, warnMsg string) (string, error) {
+// createStageFile creates a session copy of the host file source, or a file
+// holding content if not nil, to be bound on source in the root filesystem
+// during the %post and %test sections. A missing bind target is created
+// empty and returned as placeholder, to be removed once the sections are
+// run so that it is not left in the image.
+func createStageFile(source string, content []byte, b *types.Bundle, warnMsg string) (sessionFile, placeholder string, err error) {
 	dest := filepath.Join(b.RootfsPath, source)
+	created := ""
+	if _, err := os.Lstat(dest); os.IsNotExist(err) {
+		if f, err := os.OpenFile(dest, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644); err == nil {
+			f.Close()
+			created = dest
+		}
+	}
+	defer func() {
+		if sessionFile == "" && created != "" {
+			os.Remove(created)
+		}
+	}()
+
 	if err := unix.Access(dest, unix.R_OK); err != nil {
-		sylog.Warningf("%s: while accessing to %s: %s", warnMsg, dest, err)
-		return "", nil
+		return "", "", b.Opts.Warnf(types.WarnStageFile, "%s: while accessing to %s: %s", warnMsg, dest, err)
 	}
 
-	sessionFile := filepath.Join(b.TmpDir, filepath.Base(source))
+	sessionFile = filepath.Join(b.TmpDir, filepath.Base(source))
 	stageFile, err := os.Create(sessionFile)
 	if err != nil {
-		return "", fmt.Errorf("failed to create staging %s file: %s", sessionFile, err)
+		return "", "", fmt.Errorf("failed to create staging %s file: %s", sessionFile, err)
 	}
 	defer stageFile.Close()
 
-	content, err := ioutil.ReadFile(source)
-	if err != nil {
-		return "", fmt.Errorf("failed to read %s: %s", source, err)
+	if content == nil {
+		content, err = ioutil.ReadFile(source)
+		if err != nil {
+			return "", "", fmt.Errorf("failed to read %s: %s", source, err)
+		}
 	}
 	if _, err := stageFile.Write(content); err != nil {
-		return "", fmt.Errorf("failed to copy %s content to %s: %s", source, sessionFile, err)
+		return "", "", fmt.Errorf("failed to copy %s content to %s: %s", source, sessionFile, err)
 	}
 
-	return sessionFile, nil
+	return sessionFile, created, nil
+}
+
+// resolvConf returns the content of a resolv.conf file using the given
+// name servers, or nil without name servers.
+func resolvConf(servers []string) ([]byte, error) {
+	if len(servers) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	for _, s := range servers {
+		if net.ParseIP(s) == nil {
+			return nil, fmt.Errorf("invalid DNS server address %q", s)
+		}
+		fmt.Fprintf(&buf, "nameserver %s\n", s)
+	}
+	return buf.Bytes(), nil
 }
 
 func createScript(path string, content []byte) error {
@@ -94,7 +134,18 @@ func createScript(path string, content []byte) error {
 	return nil
 }
 
-func getSectionScriptArgs(name string, script string, s types.Script) ([]string, error) {
+// traceShells are the section interpreters supporting the -x option
+// to trace commands.
+var traceShells = map[string]bool{
+	"sh":   true,
+	"bash": true,
+	"dash": true,
+	"ash":  true,
+	"ksh":  true,
+	"zsh":  true,
+}
+
+func getSectionScriptArgs(name string, script string, s types.Script, trace bool, shellFlags []string) ([]string, error) {
 	args := []string{"/bin/sh", "-ex"}
 	// trim potential trailing comment from args and append to args list
 	sectionParams := strings.Fields(strings.Split(s.Args, "#")[0])
@@ -108,15 +159,37 @@ func getSectionScriptArgs(name string, script string, s types.Script) ([]string,
 			if len(sectionParams)-1 < i+1 {
 				return nil, fmt.Errorf("bad %s section '-c' parameter: missing arguments", name)
 			}
+			interpreter := sectionParams[i+1]
+			interpreterArgs := sectionParams[i+2:]
+			shell := traceShells[filepath.Base(interpreter)]
+			// shell flags only apply to POSIX shell compatible interpreters
+			if len(shellFlags) > 0 {
+				if shell {
+					interpreterArgs = append(append([]string{}, shellFlags...), interpreterArgs...)
+				} else {
+					sylog.Verbosef("Ignoring shell flags for %%%s section %s interpreter", name, interpreter)
+				}
+			}
+			// the outer shell only traces the interpreter execution,
+			// the interpreter itself must trace the script commands
+			if trace {
+				if !shell {
+					return nil, fmt.Errorf("%s interpreter doesn't support command tracing requested by --debug-post", interpreter)
+				}
+				interpreterArgs = append([]string{"-x"}, interpreterArgs...)
+			}
 			// replace shell "[args...]" arguments list by single
 			// argument "shell [args...] script"
-			shellArgs := strings.Join(sectionParams[i+1:], " ")
+			shellArgs := strings.Join(append([]string{interpreter}, interpreterArgs...), " ")
 			sectionParams = append(sectionParams[0:i+1], shellArgs+" "+script)
 			commandOption = true
 			break
 		}
 	}
 
+	if !commandOption {
+		args = append(args, shellFlags...)
+	}
 	args = append(args, sectionParams...)
 	if !commandOption {
 		args = append(args, script)
@@ -125,6 +198,91 @@ func getSectionScriptArgs(name string, script string, s types.Script) ([]string,
 	return args, nil
 }
 
+// getSectionWorkDir extracts from the section arguments the -w option
+// setting the initial working directory of the section script, it returns
+// the working directory, empty if not set, and the section without the
+// option.
+func getSectionWorkDir(name string, s types.Script) (string, types.Script, error) {
+	dir, s, err := getSectionOption(name, "-w", "directory", s)
+	if err != nil || dir == "" {
+		return "", s, err
+	}
+	if !filepath.IsAbs(dir) {
+		return "", s, fmt.Errorf("bad %s section '-w' parameter: %s is not an absolute path", name, dir)
+	}
+	return filepath.Clean(dir), s, nil
+}
+
+// getSectionEnvFile extracts from the section arguments the --env-file
+// option loading build-only environment variables in the section, it
+// returns the environment file path, empty if not set, and the section
+// without the option.
+func getSectionEnvFile(name string, s types.Script) (string, types.Script, error) {
+	return getSectionOption(name, "--env-file", "file", s)
+}
+
+// getSectionOption extracts from the section arguments the option taking
+// a value, what names the value in errors. Options found after -c belong
+// to the interpreter.
+func getSectionOption(name, option, what string, s types.Script) (string, types.Script, error) {
+	value := ""
+	sectionParams := strings.Fields(strings.Split(s.Args, "#")[0])
+
+	for i := 0; i < len(sectionParams) && sectionParams[i] != "-c"; i++ {
+		if sectionParams[i] != option {
+			continue
+		}
+		if i+1 >= len(sectionParams) {
+			return "", s, fmt.Errorf("bad %s section '%s' parameter: missing %s", name, option, what)
+		}
+		value = sectionParams[i+1]
+		sectionParams = append(sectionParams[:i], sectionParams[i+2:]...)
+		i--
+	}
+
+	if value == "" {
+		return "", s, nil
+	}
+	s.Args = strings.Join(sectionParams, " ")
+	return value, s, nil
+}
+
+// shellFlagRegexp matches a group of shell option flags.
+var shellFlagRegexp = regexp.MustCompile(`^[-+][a-zA-Z]+$`)
+
+// shellOptionRegexp matches a shell option name set with -o/+o.
+var shellOptionRegexp = regexp.MustCompile(`^[a-z]+$`)
+
+// ParseShellFlags splits the shell flags applied to the build scripts,
+// as for example "-euo pipefail", and checks their syntax.
+func ParseShellFlags(flags string) ([]string, error) {
+	fields := strings.Fields(flags)
+	for i, f := range fields {
+		if shellFlagRegexp.MatchString(f) {
+			// -c, -i and -s would change the way scripts are executed
+			if strings.ContainsAny(f[1:], "cis") {
+				return nil, fmt.Errorf("shell flag %q is not allowed", f)
+			}
+			if strings.HasSuffix(f, "o") && i == len(fields)-1 {
+				return nil, fmt.Errorf("missing option name after shell flag %q", f)
+			}
+			continue
+		}
+		// option name following a -o/+o flag
+		if i > 0 && strings.HasSuffix(fields[i-1], "o") && shellFlagRegexp.MatchString(fields[i-1]) && shellOptionRegexp.MatchString(f) {
+			continue
+		}
+		return nil, fmt.Errorf("invalid shell flag %q", f)
+	}
+	return fields, nil
+}
+
+// tracePrefix returns the PS4 environment variable prefixing the
+// trace output of the named section commands.
+func tracePrefix(name string) string {
+	return fmt.Sprintf("PS4=+ [%%%s] ", name)
+}
+
 func currentEnvNoSingularity() []string {
 	envs := make([]string, 0)
 
@@ -0,0 +1,337 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/sylabs/singularity/pkg/build/types"
+)
+
+func TestGetSectionScriptArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    string
+		trace   bool
+		flags   []string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Default",
+			want: []string{"/bin/sh", "-ex", "/script"},
+		},
+		{
+			name:  "DefaultTrace",
+			trace: true,
+			want:  []string{"/bin/sh", "-ex", "/script"},
+		},
+		{
+			name: "Interpreter",
+			args: "-c /bin/bash -e",
+			want: []string{"/bin/sh", "-ex", "-c", "/bin/bash -e /script"},
+		},
+		{
+			name:  "InterpreterTrace",
+			args:  "-c /bin/bash -e",
+			trace: true,
+			want:  []string{"/bin/sh", "-ex", "-c", "/bin/bash -x -e /script"},
+		},
+		{
+			name:    "InterpreterTraceUnsupported",
+			args:    "-c /usr/bin/python3",
+			trace:   true,
+			wantErr: true,
+		},
+		{
+			name:  "DefaultFlags",
+			flags: []string{"-u", "-o", "pipefail"},
+			want:  []string{"/bin/sh", "-ex", "-u", "-o", "pipefail", "/script"},
+		},
+		{
+			name:  "InterpreterFlags",
+			args:  "-c /bin/bash -e",
+			flags: []string{"-u"},
+			trace: true,
+			want:  []string{"/bin/sh", "-ex", "-c", "/bin/bash -x -u -e /script"},
+		},
+		{
+			name:  "InterpreterFlagsIgnored",
+			args:  "-c /usr/bin/python3",
+			flags: []string{"-u"},
+			want:  []string{"/bin/sh", "-ex", "-c", "/usr/bin/python3 /script"},
+		},
+		{
+			name:    "MissingInterpreter",
+			args:    "-c",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args, err := getSectionScriptArgs("post", "/script", types.Script{Args: tt.args}, tt.trace, tt.flags)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(args, tt.want) {
+				t.Errorf("got %q instead of %q", args, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseShellFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		flags   string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "Empty",
+			want: []string{},
+		},
+		{
+			name:  "Flags",
+			flags: "-eu +x",
+			want:  []string{"-eu", "+x"},
+		},
+		{
+			name:  "Option",
+			flags: "-euo pipefail",
+			want:  []string{"-euo", "pipefail"},
+		},
+		{
+			name:    "OptionWithoutFlag",
+			flags:   "-e pipefail",
+			wantErr: true,
+		},
+		{
+			name:    "Command",
+			flags:   "-c id",
+			wantErr: true,
+		},
+		{
+			name:    "MissingOption",
+			flags:   "-eo",
+			wantErr: true,
+		},
+		{
+			name:    "Injection",
+			flags:   "-e;id",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			flags, err := ParseShellFlags(tt.flags)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(flags, tt.want) {
+				t.Errorf("got %q instead of %q", flags, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetSectionWorkDir(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		wantDir  string
+		wantArgs string
+		wantErr  bool
+	}{
+		{
+			name: "Default",
+		},
+		{
+			name:     "Interpreter",
+			args:     "-c /bin/bash",
+			wantArgs: "-c /bin/bash",
+		},
+		{
+			name:    "WorkDir",
+			args:    "-w /build/",
+			wantDir: "/build",
+		},
+		{
+			name:     "WorkDirInterpreter",
+			args:     "-w /build -c /bin/bash -w",
+			wantDir:  "/build",
+			wantArgs: "-c /bin/bash -w",
+		},
+		{
+			name:     "InterpreterOption",
+			args:     "-c /bin/bash -w /build",
+			wantArgs: "-c /bin/bash -w /build",
+		},
+		{
+			name:    "RelativeDir",
+			args:    "-w build",
+			wantErr: true,
+		},
+		{
+			name:    "MissingDir",
+			args:    "-w",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir, s, err := getSectionWorkDir("post", types.Script{Args: tt.args})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if dir != tt.wantDir {
+				t.Errorf("got directory %q instead of %q", dir, tt.wantDir)
+			}
+			if s.Args != tt.wantArgs {
+				t.Errorf("got arguments %q instead of %q", s.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestGetSectionEnvFile(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     string
+		wantFile string
+		wantArgs string
+		wantErr  bool
+	}{
+		{
+			name:     "Default",
+			args:     "-c /bin/bash",
+			wantArgs: "-c /bin/bash",
+		},
+		{
+			name:     "EnvFile",
+			args:     "--env-file build.env -w /build -c /bin/bash",
+			wantFile: "build.env",
+			wantArgs: "-w /build -c /bin/bash",
+		},
+		{
+			name:     "InterpreterOption",
+			args:     "-c /bin/bash --env-file build.env",
+			wantArgs: "-c /bin/bash --env-file build.env",
+		},
+		{
+			name:    "MissingFile",
+			args:    "--env-file",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, s, err := getSectionEnvFile("post", types.Script{Args: tt.args})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if file != tt.wantFile {
+				t.Errorf("got file %q instead of %q", file, tt.wantFile)
+			}
+			if s.Args != tt.wantArgs {
+				t.Errorf("got arguments %q instead of %q", s.Args, tt.wantArgs)
+			}
+		})
+	}
+}
+
+func TestResolvConf(t *testing.T) {
+	tests := []struct {
+		name    string
+		servers []string
+		want    string
+		wantErr bool
+	}{
+		{name: "None"},
+		{name: "IPv4", servers: []string{"1.1.1.1"}, want: "nameserver 1.1.1.1\n"},
+		{name: "Several", servers: []string{"1.1.1.1", "2606:4700:4700::1111"}, want: "nameserver 1.1.1.1\nnameserver 2606:4700:4700::1111\n"},
+		{name: "Hostname", servers: []string{"dns.example.com"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolvConf(tt.servers)
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreateStageFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "stage-file-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := &types.Bundle{RootfsPath: filepath.Join(dir, "rootfs"), TmpDir: dir}
+	if err := os.MkdirAll(filepath.Join(b.RootfsPath, "etc"), 0755); err != nil {
+		t.Fatalf("failed to create rootfs: %s", err)
+	}
+
+	content := []byte("nameserver 1.1.1.1\n")
+	session, placeholder, err := createStageFile("/etc/resolv.conf", content, b, "")
+	if err != nil {
+		t.Fatalf("failed to create stage file: %s", err)
+	}
+	if got, err := ioutil.ReadFile(session); err != nil || string(got) != string(content) {
+		t.Errorf("unexpected session file content %q (%v)", got, err)
+	}
+	// the missing bind target is created and returned for removal
+	if want := filepath.Join(b.RootfsPath, "etc/resolv.conf"); placeholder != want {
+		t.Errorf("got placeholder %q, want %q", placeholder, want)
+	}
+
+	// an existing bind target is kept
+	if _, placeholder, err = createStageFile("/etc/resolv.conf", content, b, ""); err != nil {
+		t.Fatalf("failed to create stage file: %s", err)
+	} else if placeholder != "" {
+		t.Errorf("unexpected placeholder %q for existing bind target", placeholder)
+	}
+}
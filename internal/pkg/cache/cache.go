@@ -50,6 +50,8 @@ const (
 	OrasCacheType = "oras"
 	// The Net cache holds images pulled from http(s) internet sources
 	NetCacheType = "net"
+	// The Post cache holds root filesystem snapshots taken after %post:NAME build fragments
+	PostCacheType = "post"
 )
 
 var (
@@ -59,6 +61,7 @@ var (
 		ShubCacheType,
 		OrasCacheType,
 		NetCacheType,
+		PostCacheType,
 	}
 	OciCacheTypes = []string{
 		OciBlobCacheType,
@@ -108,7 +111,7 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 		return nil, nil
 	}
 
-	e = &Entry{}
+	e = &Entry{lockFd: -1}
 
 	cacheDir, err := h.GetFileCacheDir(cacheType)
 	if err != nil {
@@ -116,6 +119,13 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 	}
 
 	e.Path = filepath.Join(cacheDir, hash)
+	e.checksumPath = h.checksumPath(cacheType, hash)
+
+	// Concurrent processes requesting the same entry wait for the first one
+	// to create it, the lock is held until the entry is finalized or cleaned
+	if err := e.lock(); err != nil {
+		return nil, fmt.Errorf("could not lock cache entry '%s': %v", e.Path, err)
+	}
 
 	// If there is a directory it's from an older version of Singularity
 	// We need to remove it as we work with single files per hash only now
@@ -124,6 +134,7 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 		err := os.RemoveAll(e.Path)
 		// Allow IsNotExist in case a concurrent process already removed it
 		if err != nil && !os.IsNotExist(err) {
+			e.unlock()
 			return nil, fmt.Errorf("could not remove old cache directory '%s': %v", e.Path, err)
 		}
 	}
@@ -132,6 +143,7 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 	// to use and then Finalize
 	pathExists, err := fs.PathExists(e.Path)
 	if err != nil {
+		e.unlock()
 		return nil, fmt.Errorf("could not check for cache entry '%s': %v", e.Path, err)
 	}
 
@@ -139,16 +151,24 @@ func (h *Handle) GetEntry(cacheType string, hash string) (e *Entry, err error) {
 		e.Exists = false
 		f, err := fs.MakeTmpFile(cacheDir, "tmp_", 0700)
 		if err != nil {
+			e.unlock()
 			return nil, err
 		}
+		e.TmpPath = f.Name()
 		err = f.Close()
+		if err == nil {
+			err = e.setOwner()
+		}
 		if err != nil {
+			e.CleanTmp()
 			return nil, err
 		}
-		e.TmpPath = f.Name()
 		return e, nil
 	}
 
+	// The entry was created, possibly by the process we waited for
+	e.unlock()
+
 	// Double check that there isn't something else weird there
 	if !fs.IsFile(e.Path) {
 		return nil, fmt.Errorf("path '%s' exists but is not a file", e.Path)
@@ -186,6 +206,7 @@ func (h *Handle) CleanCache(cacheType string, dryRun bool, days int) (err error)
 				sylog.Errorf("Could not remove cache entry '%s': %v", f.Name(), err)
 				errCount = errCount + 1
 			}
+			os.Remove(h.checksumPath(cacheType, f.Name()))
 		}
 	}
 
@@ -193,6 +214,16 @@ func (h *Handle) CleanCache(cacheType string, dryRun bool, days int) (err error)
 		return fmt.Errorf("failed to remove %d cache entries", errCount)
 	}
 
+	// references to the entries of a cleaned cache type are obsolete
+	if days < 0 && !dryRun {
+		if err := h.cleanRefs(cacheType); err != nil {
+			sylog.Errorf("Could not remove %s cache references: %v", cacheType, err)
+		}
+		if err := h.cleanChecksums(cacheType); err != nil {
+			sylog.Errorf("Could not remove %s cache checksums: %v", cacheType, err)
+		}
+	}
+
 	return err
 }
 
@@ -208,6 +239,12 @@ func (h *Handle) cleanAllCaches() {
 		if err := os.RemoveAll(dir); err != nil {
 			sylog.Verbosef("unable to clean %s cache, directory %s: %v", ct, dir, err)
 		}
+		if err := h.cleanRefs(ct); err != nil {
+			sylog.Verbosef("unable to clean %s cache references: %v", ct, err)
+		}
+		if err := h.cleanChecksums(ct); err != nil {
+			sylog.Verbosef("unable to clean %s cache checksums: %v", ct, err)
+		}
 	}
 
 }
@@ -7,13 +7,22 @@
 package cache
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
 	"github.com/sylabs/singularity/pkg/sylog"
+	"golang.org/x/sys/unix"
 )
 
+// LockSuffix is appended to the path of an entry to name the lock file held
+// by the process creating it.
+const LockSuffix = ".lock"
+
 // Entry is a structure representing an entry in the cache. An entry is a file under the
 // CacheType subdir within the Cache rootDir
 type Entry struct {
@@ -27,10 +36,22 @@ type Entry struct {
 	// tmpPath is the temporary location that should be used for a new cache entry as it
 	// is created
 	TmpPath string
+	// lockFd is the descriptor of the locked entry lock file, or -1
+	lockFd int
+	// checksumPath is the location where the checksum of the entry is
+	// recorded when it is finalized
+	checksumPath string
 }
 
 // Finalize an entry by renaming it to its permanent path atomically
 func (e *Entry) Finalize() error {
+	// The checksum is recorded first, an entry is never found without
+	// its checksum
+	if e.checksumPath != "" {
+		if err := writeChecksum(e.TmpPath, e.checksumPath); err != nil {
+			return fmt.Errorf("could not record checksum of cached file: %v", err)
+		}
+	}
 	// Try to rename the temporary file to its permanent path
 	// This is a file, so we won't have an IsExist error since...
 	//   If newpath already exists and is not a directory, Rename replaces it.
@@ -39,11 +60,13 @@ func (e *Entry) Finalize() error {
 	if err != nil {
 		return fmt.Errorf("could not finalize cached file: %v", err)
 	}
+	e.unlock()
 	return nil
 }
 
 // CleanTmp should be defer'd when an Entry is created and will remove any temporary file
 func (e *Entry) CleanTmp() {
+	defer e.unlock()
 	// If there is no TmpPath / file there then there is nothing to clean up
 	if e.TmpPath == "" || !fs.IsFile(e.TmpPath) {
 		return
@@ -53,3 +76,107 @@ func (e *Entry) CleanTmp() {
 		sylog.Errorf("Could not remove cache temporary file '%s': %v", e.TmpPath, err)
 	}
 }
+
+// lock acquires the exclusive lock of the entry, waiting for a concurrent
+// process creating the same entry to finalize or clean it. The lock file is
+// removed before being unlocked, a lock file found with an owner comes from
+// a crashed process, whose lock was released by the kernel, and its leftover
+// temporary file is removed.
+func (e *Entry) lock() error {
+	e.lockFd = -1
+	lockPath := e.Path + LockSuffix
+
+	for {
+		fd, err := unix.Open(lockPath, unix.O_RDWR|unix.O_CREAT|unix.O_CLOEXEC, 0600)
+		if err != nil {
+			return err
+		}
+
+		err = unix.Flock(fd, unix.LOCK_EX|unix.LOCK_NB)
+		if err == unix.EWOULDBLOCK {
+			sylog.Infof("Waiting for another process to create cache entry %s", e.Path)
+			err = unix.Flock(fd, unix.LOCK_EX)
+		}
+		if err == unix.ENOLCK || err == unix.EOPNOTSUPP {
+			sylog.Verbosef("Could not lock cache entry %s, underlying filesystem seems to not support lock", e.Path)
+			unix.Close(fd)
+			return nil
+		} else if err != nil {
+			unix.Close(fd)
+			return err
+		}
+
+		// the previous owner removed the lock file before unlocking it,
+		// lock the new one
+		var locked, current unix.Stat_t
+		if err := unix.Fstat(fd, &locked); err != nil {
+			unix.Close(fd)
+			return err
+		}
+		if err := unix.Stat(lockPath, &current); err != nil || locked.Ino != current.Ino || locked.Dev != current.Dev {
+			unix.Close(fd)
+			continue
+		}
+
+		e.lockFd = fd
+		break
+	}
+
+	owner := make([]byte, 4096)
+	n, err := unix.Pread(e.lockFd, owner, 0)
+	if err != nil {
+		e.unlock()
+		return err
+	}
+	if n > 0 {
+		e.recoverStaleLock(owner[:n])
+	}
+	return nil
+}
+
+// recoverStaleLock removes the temporary file recorded in the lock file of
+// a crashed process.
+func (e *Entry) recoverStaleLock(owner []byte) {
+	fields := strings.SplitN(string(bytes.TrimSpace(owner)), "\n", 2)
+	pid, _ := strconv.Atoi(fields[0])
+	sylog.Warningf("Recovering stale lock of cache entry %s left by process %d", e.Path, pid)
+
+	if len(fields) < 2 {
+		return
+	}
+	tmpPath := strings.TrimSpace(fields[1])
+	// only remove a temporary file of the entry cache directory
+	if filepath.Dir(tmpPath) != filepath.Dir(e.Path) || !strings.HasPrefix(filepath.Base(tmpPath), "tmp_") {
+		return
+	}
+	if err := os.Remove(tmpPath); err != nil && !os.IsNotExist(err) {
+		sylog.Warningf("Could not remove stale cache temporary file '%s': %v", tmpPath, err)
+	}
+}
+
+// setOwner records the current process and the temporary file it creates
+// in the lock file, to recover them if the process crashes.
+func (e *Entry) setOwner() error {
+	if e.lockFd < 0 {
+		return nil
+	}
+	owner := fmt.Sprintf("%d\n%s\n", os.Getpid(), e.TmpPath)
+	if err := unix.Ftruncate(e.lockFd, 0); err != nil {
+		return err
+	}
+	_, err := unix.Pwrite(e.lockFd, []byte(owner), 0)
+	return err
+}
+
+// unlock removes and releases the lock file of the entry, if locked.
+func (e *Entry) unlock() {
+	if e.lockFd < 0 {
+		return
+	}
+	if err := os.Remove(e.Path + LockSuffix); err != nil && !os.IsNotExist(err) {
+		sylog.Warningf("Could not remove cache lock file: %v", err)
+	}
+	unix.Flock(e.lockFd, unix.LOCK_UN)
+	unix.Close(e.lockFd)
+	e.lockFd = -1
+}
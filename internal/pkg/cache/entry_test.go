@@ -0,0 +1,154 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestHandle(t *testing.T) (*Handle, func()) {
+	dir, err := ioutil.TempDir("", "cache-entry-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	h, err := New(Config{ParentDir: dir})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatalf("failed to create cache: %v", err)
+	}
+	return h, func() { os.RemoveAll(dir) }
+}
+
+func TestGetEntryConcurrent(t *testing.T) {
+	h, cleanup := newTestHandle(t)
+	defer cleanup()
+
+	const (
+		builds  = 8
+		hash    = "0123456789abcdef"
+		content = "complete image content"
+	)
+
+	var downloads int32
+	var wg sync.WaitGroup
+	errs := make(chan error, builds)
+
+	for i := 0; i < builds; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			e, err := h.GetEntry(NetCacheType, hash)
+			if err != nil {
+				errs <- err
+				return
+			}
+			defer e.CleanTmp()
+
+			if !e.Exists {
+				atomic.AddInt32(&downloads, 1)
+				// write slowly so others have to wait for the entry
+				for _, c := range content {
+					f, err := os.OpenFile(e.TmpPath, os.O_WRONLY|os.O_APPEND, 0)
+					if err != nil {
+						errs <- err
+						return
+					}
+					f.WriteString(string(c))
+					f.Close()
+					time.Sleep(time.Millisecond)
+				}
+				if err := e.Finalize(); err != nil {
+					errs <- err
+					return
+				}
+			}
+
+			b, err := ioutil.ReadFile(e.Path)
+			if err != nil {
+				errs <- err
+				return
+			}
+			if string(b) != content {
+				errs <- fmt.Errorf("got entry content %q, want %q", b, content)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if downloads != 1 {
+		t.Errorf("entry downloaded %d times, want 1", downloads)
+	}
+
+	if _, err := os.Stat(filepath.Join(h.getCacheTypeDir(NetCacheType), hash+LockSuffix)); !os.IsNotExist(err) {
+		t.Errorf("lock file left in cache: %v", err)
+	}
+	files, err := ioutil.ReadDir(h.getCacheTypeDir(NetCacheType))
+	if err != nil {
+		t.Fatalf("failed to read cache directory: %v", err)
+	}
+	if len(files) != 1 {
+		t.Errorf("got %d files in cache directory, want 1", len(files))
+	}
+}
+
+func TestGetEntryStaleLock(t *testing.T) {
+	h, cleanup := newTestHandle(t)
+	defer cleanup()
+
+	const hash = "fedcba9876543210"
+	dir := h.getCacheTypeDir(NetCacheType)
+
+	// lock file and temporary file left by a crashed process
+	tmp, err := ioutil.TempFile(dir, "tmp_")
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %v", err)
+	}
+	tmp.Close()
+	owner := fmt.Sprintf("%d\n%s\n", 1<<22+1, tmp.Name())
+	if err := ioutil.WriteFile(filepath.Join(dir, hash+LockSuffix), []byte(owner), 0600); err != nil {
+		t.Fatalf("failed to write lock file: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		e, err := h.GetEntry(NetCacheType, hash)
+		if err == nil {
+			if e.Exists {
+				err = fmt.Errorf("unexpected existing entry")
+			}
+			e.CleanTmp()
+		}
+		done <- err
+	}()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("failed to get entry: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("stale lock not recovered")
+	}
+
+	if _, err := os.Stat(tmp.Name()); !os.IsNotExist(err) {
+		t.Errorf("stale temporary file not removed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, hash+LockSuffix)); !os.IsNotExist(err) {
+		t.Errorf("lock file left in cache: %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+)
+
+// refsDir is the cache subdirectory holding the reference index, which maps
+// the source references of cached images to the name of their cache entry,
+// so that cached images can be found without querying their source.
+const refsDir = "refs"
+
+// ErrRefNotCached is returned by LookupRef for references without a
+// cache entry.
+type ErrRefNotCached struct {
+	CacheType string
+	Ref       string
+}
+
+func (e *ErrRefNotCached) Error() string {
+	return fmt.Sprintf("%s is not in the %s cache", e.Ref, e.CacheType)
+}
+
+// refPath returns the path of the reference index file of ref.
+func (h *Handle) refPath(cacheType, ref string) string {
+	return filepath.Join(h.rootDir, refsDir, cacheType, fmt.Sprintf("%x", sha256.Sum256([]byte(ref))))
+}
+
+// SetRef records that the image with the source reference ref is
+// cached in the entry named hash of the given cache type.
+func (h *Handle) SetRef(cacheType, ref, hash string) error {
+	if h.disabled {
+		return nil
+	}
+	if !stringInSlice(cacheType, FileCacheTypes) && !stringInSlice(cacheType, OciCacheTypes) {
+		return ErrInvalidCacheType
+	}
+
+	path := h.refPath(cacheType, ref)
+	if err := initCacheDir(filepath.Dir(path)); err != nil {
+		return err
+	}
+	// written to a temporary file first so that a concurrent
+	// lookup never reads a partial entry name
+	f, err := fs.MakeTmpFile(filepath.Dir(path), "tmp_", 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(hash + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), path)
+}
+
+// LookupRef returns the name of the entry of the given cache type
+// recorded for the source reference ref, an ErrRefNotCached error is
+// returned if ref was never cached or if its entry was removed.
+func (h *Handle) LookupRef(cacheType, ref string) (string, error) {
+	notCached := &ErrRefNotCached{CacheType: cacheType, Ref: ref}
+	if h.disabled {
+		return "", notCached
+	}
+
+	b, err := ioutil.ReadFile(h.refPath(cacheType, ref))
+	if os.IsNotExist(err) {
+		return "", notCached
+	} else if err != nil {
+		return "", fmt.Errorf("while reading cache reference of %s: %s", ref, err)
+	}
+	hash := strings.TrimSpace(string(b))
+
+	// entries of OCI caches are tags of a shared OCI layout, their
+	// existence is checked by the caller
+	if stringInSlice(cacheType, FileCacheTypes) && !fs.IsFile(filepath.Join(h.getCacheTypeDir(cacheType), hash)) {
+		return "", notCached
+	}
+	return hash, nil
+}
+
+// cleanRefs removes the reference index of the given cache type.
+func (h *Handle) cleanRefs(cacheType string) error {
+	return os.RemoveAll(filepath.Join(h.rootDir, refsDir, cacheType))
+}
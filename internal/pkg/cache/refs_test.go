@@ -0,0 +1,74 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestRefs(t *testing.T) {
+	h, cleanup := newTestHandle(t)
+	defer cleanup()
+
+	const (
+		ref  = "library://alpine:latest amd64"
+		hash = "sha256.0123456789abcdef"
+	)
+
+	if _, err := h.LookupRef(LibraryCacheType, ref); err == nil {
+		t.Fatalf("unexpected success looking up unrecorded reference")
+	} else if _, ok := err.(*ErrRefNotCached); !ok {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := h.SetRef(LibraryCacheType, ref, hash); err != nil {
+		t.Fatalf("failed to record reference: %s", err)
+	}
+	// the entry itself is missing
+	if _, err := h.LookupRef(LibraryCacheType, ref); err == nil {
+		t.Errorf("unexpected success looking up reference without cache entry")
+	}
+
+	e, err := h.GetEntry(LibraryCacheType, hash)
+	if err != nil {
+		t.Fatalf("failed to get cache entry: %s", err)
+	}
+	if err := ioutil.WriteFile(e.TmpPath, []byte("image"), 0600); err != nil {
+		t.Fatalf("failed to write cache entry: %s", err)
+	}
+	if err := e.Finalize(); err != nil {
+		t.Fatalf("failed to finalize cache entry: %s", err)
+	}
+
+	got, err := h.LookupRef(LibraryCacheType, ref)
+	if err != nil {
+		t.Fatalf("failed to look up reference: %s", err)
+	}
+	if got != hash {
+		t.Errorf("got entry %q, want %q", got, hash)
+	}
+
+	// OCI cache entries are not checked
+	if err := h.SetRef(OciBlobCacheType, ref, "tag"); err != nil {
+		t.Fatalf("failed to record reference: %s", err)
+	}
+	if got, err := h.LookupRef(OciBlobCacheType, ref); err != nil || got != "tag" {
+		t.Errorf("got entry %q (%v), want %q", got, err, "tag")
+	}
+
+	if err := h.SetRef("bad", ref, hash); err != ErrInvalidCacheType {
+		t.Errorf("unexpected error for invalid cache type: %v", err)
+	}
+
+	if err := h.CleanCache(LibraryCacheType, false, -1); err != nil {
+		t.Fatalf("failed to clean cache: %s", err)
+	}
+	if _, err := os.Stat(h.refPath(LibraryCacheType, ref)); !os.IsNotExist(err) {
+		t.Errorf("reference not removed with the cache entries: %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/util/fs"
+)
+
+// checksumsDir is the cache subdirectory holding the SHA-256 checksums of
+// the file cache entries, recorded when they are finalized, as most of
+// them are not named after their content digest.
+const checksumsDir = "checksums"
+
+// EntryStatus is the status of a verified cache entry.
+type EntryStatus int
+
+const (
+	// EntryValid is the status of an entry matching its checksum.
+	EntryValid EntryStatus = iota
+	// EntryCorrupted is the status of an entry not matching its checksum.
+	EntryCorrupted
+	// EntryUnverified is the status of an entry without checksum,
+	// created by a previous version.
+	EntryUnverified
+)
+
+// VerifiedEntry describes a cache entry checked by VerifyCache.
+type VerifiedEntry struct {
+	CacheType string
+	Name      string
+	Path      string
+	Status    EntryStatus
+	// Removed is true for a corrupted entry removed by VerifyCache.
+	Removed bool
+}
+
+// checksumPath returns the path of the checksum of the entry name.
+func (h *Handle) checksumPath(cacheType, name string) string {
+	return filepath.Join(h.rootDir, checksumsDir, cacheType, name)
+}
+
+// cleanChecksums removes the checksums of the given cache type.
+func (h *Handle) cleanChecksums(cacheType string) error {
+	return os.RemoveAll(filepath.Join(h.rootDir, checksumsDir, cacheType))
+}
+
+// fileChecksum returns the hexadecimal SHA-256 checksum of the file at path.
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// writeChecksum records the checksum of the file at path in checksumPath.
+func writeChecksum(path, checksumPath string) error {
+	sum, err := fileChecksum(path)
+	if err != nil {
+		return err
+	}
+	if err := initCacheDir(filepath.Dir(checksumPath)); err != nil {
+		return err
+	}
+	f, err := fs.MakeTmpFile(filepath.Dir(checksumPath), "tmp_", 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(sum + "\n"); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), checksumPath)
+}
+
+// expectedChecksum returns the checksum expected for the entry name of
+// cacheType: the recorded checksum, or the digest naming library and
+// oras entries created before checksums were recorded. An empty string
+// is returned for entries without checksum.
+func (h *Handle) expectedChecksum(cacheType, name string) (string, error) {
+	b, err := ioutil.ReadFile(h.checksumPath(cacheType, name))
+	if err == nil {
+		return strings.TrimSpace(string(b)), nil
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if cacheType == LibraryCacheType || cacheType == OrasCacheType {
+		for _, prefix := range []string{"sha256.", "sha256:"} {
+			if strings.HasPrefix(name, prefix) {
+				return strings.TrimPrefix(name, prefix), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// VerifyCache recomputes the SHA-256 checksum of the entries of the given
+// cache type and compares it with the digest naming OCI blobs, or with
+// the checksum recorded when file entries were created. With fix, the
+// corrupted entries are removed.
+func (h *Handle) VerifyCache(cacheType string, fix bool) ([]VerifiedEntry, error) {
+	if h.disabled {
+		return nil, nil
+	}
+
+	var dir string
+	blob := stringInSlice(cacheType, OciCacheTypes)
+	if blob {
+		dir = filepath.Join(h.getCacheTypeDir(cacheType), "blobs", "sha256")
+	} else if stringInSlice(cacheType, FileCacheTypes) {
+		dir = h.getCacheTypeDir(cacheType)
+	} else {
+		return nil, ErrInvalidCacheType
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("unable to read %s cache directory: %v", cacheType, err)
+	}
+
+	var entries []VerifiedEntry
+	for _, f := range files {
+		// lock and temporary files of entries being created
+		if !f.Mode().IsRegular() || strings.HasSuffix(f.Name(), LockSuffix) || strings.HasPrefix(f.Name(), "tmp_") {
+			continue
+		}
+
+		e := VerifiedEntry{
+			CacheType: cacheType,
+			Name:      f.Name(),
+			Path:      filepath.Join(dir, f.Name()),
+		}
+
+		expected := f.Name()
+		if !blob {
+			expected, err = h.expectedChecksum(cacheType, f.Name())
+			if err != nil {
+				return entries, fmt.Errorf("unable to read checksum of %s cache entry %s: %v", cacheType, f.Name(), err)
+			}
+		}
+		if expected == "" {
+			e.Status = EntryUnverified
+			entries = append(entries, e)
+			continue
+		}
+
+		sum, err := fileChecksum(e.Path)
+		if err != nil {
+			return entries, fmt.Errorf("unable to compute checksum of %s cache entry %s: %v", cacheType, f.Name(), err)
+		}
+		if sum == expected {
+			e.Status = EntryValid
+			entries = append(entries, e)
+			continue
+		}
+
+		e.Status = EntryCorrupted
+		if fix {
+			if err := os.Remove(e.Path); err != nil {
+				return entries, fmt.Errorf("unable to remove corrupted %s cache entry %s: %v", cacheType, f.Name(), err)
+			}
+			if !blob {
+				os.Remove(h.checksumPath(cacheType, f.Name()))
+			}
+			e.Removed = true
+		}
+		entries = append(entries, e)
+	}
+
+	return entries, nil
+}
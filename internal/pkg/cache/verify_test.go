@@ -0,0 +1,115 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package cache
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestVerifyCache(t *testing.T) {
+	h, cleanup := newTestHandle(t)
+	defer cleanup()
+
+	addEntry := func(cacheType, name, content string) string {
+		e, err := h.GetEntry(cacheType, name)
+		if err != nil {
+			t.Fatalf("failed to get cache entry: %s", err)
+		}
+		if err := ioutil.WriteFile(e.TmpPath, []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write cache entry: %s", err)
+		}
+		if err := e.Finalize(); err != nil {
+			t.Fatalf("failed to finalize cache entry: %s", err)
+		}
+		return e.Path
+	}
+
+	addEntry(NetCacheType, "valid", "complete image")
+	truncated := addEntry(NetCacheType, "truncated", "complete image")
+	if err := os.Truncate(truncated, 8); err != nil {
+		t.Fatalf("failed to truncate cache entry: %s", err)
+	}
+	// entry of a previous version without checksum
+	legacy := addEntry(NetCacheType, "legacy", "complete image")
+	os.Remove(h.checksumPath(NetCacheType, "legacy"))
+
+	// library entries are named after their digest
+	libraryName := fmt.Sprintf("sha256.%x", sha256.Sum256([]byte("library image")))
+	addEntry(LibraryCacheType, libraryName, "library image")
+	os.Remove(h.checksumPath(LibraryCacheType, libraryName))
+
+	blobDir := filepath.Join(h.getCacheTypeDir(OciBlobCacheType), "blobs", "sha256")
+	if err := os.MkdirAll(blobDir, 0700); err != nil {
+		t.Fatalf("failed to create blob directory: %s", err)
+	}
+	validBlob := fmt.Sprintf("%x", sha256.Sum256([]byte("layer")))
+	corruptedBlob := fmt.Sprintf("%x", sha256.Sum256([]byte("other layer")))
+	for name, content := range map[string]string{validBlob: "layer", corruptedBlob: "other"} {
+		if err := ioutil.WriteFile(filepath.Join(blobDir, name), []byte(content), 0600); err != nil {
+			t.Fatalf("failed to write blob: %s", err)
+		}
+	}
+
+	tests := []struct {
+		cacheType string
+		want      map[string]EntryStatus
+	}{
+		{
+			cacheType: NetCacheType,
+			want:      map[string]EntryStatus{"valid": EntryValid, "truncated": EntryCorrupted, "legacy": EntryUnverified},
+		},
+		{
+			cacheType: LibraryCacheType,
+			want:      map[string]EntryStatus{libraryName: EntryValid},
+		},
+		{
+			cacheType: OciBlobCacheType,
+			want:      map[string]EntryStatus{validBlob: EntryValid, corruptedBlob: EntryCorrupted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cacheType, func(t *testing.T) {
+			entries, err := h.VerifyCache(tt.cacheType, false)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if len(entries) != len(tt.want) {
+				t.Errorf("got %d entries, want %d", len(entries), len(tt.want))
+			}
+			for _, e := range entries {
+				if want, ok := tt.want[e.Name]; !ok || e.Status != want {
+					t.Errorf("entry %s: got status %d, want %d", e.Name, e.Status, want)
+				}
+			}
+		})
+	}
+
+	entries, err := h.VerifyCache(NetCacheType, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, e := range entries {
+		if e.Removed != (e.Name == "truncated") {
+			t.Errorf("entry %s: removed %v", e.Name, e.Removed)
+		}
+	}
+	if _, err := os.Stat(truncated); !os.IsNotExist(err) {
+		t.Errorf("corrupted entry not removed: %v", err)
+	}
+	if _, err := os.Stat(legacy); err != nil {
+		t.Errorf("unverified entry removed: %v", err)
+	}
+
+	if _, err := h.VerifyCache("bad", false); err != ErrInvalidCacheType {
+		t.Errorf("unexpected error for invalid cache type: %v", err)
+	}
+}
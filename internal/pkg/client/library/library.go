@@ -7,7 +7,10 @@ package library
 
 import (
 	"context"
+	"encoding/hex"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
 	"strings"
 
@@ -17,18 +20,85 @@ import (
 	"github.com/sylabs/singularity/internal/pkg/client"
 )
 
-const defaultTag = "latest"
+const (
+	defaultTag = "latest"
+	// digestPrefix introduces the SHA-256 digest of an image reference
+	// pinned as docker references are, image@sha256:<hex>.
+	digestPrefix = "@sha256:"
+	// hashTagPrefix prefixes the tag selecting an image by its SHA-256
+	// digest in the library, image:sha256.<hex>.
+	hashTagPrefix = "sha256."
+)
 
 // NormalizeLibraryRef strips off leading "library://" prefix, if any, and
-// appends the default tag (latest) if none specified.
+// appends the default tag (latest) if none specified. A digest reference,
+// image@sha256:<hex>, is converted to the image:sha256.<hex> hash tag
+// form used by the library.
 func NormalizeLibraryRef(libraryRef string) string {
 	ir := strings.TrimPrefix(libraryRef, "library://")
+	if i := strings.Index(ir, digestPrefix); i >= 0 {
+		return ir[:i] + ":" + hashTagPrefix + ir[i+len(digestPrefix):]
+	}
 	if !strings.Contains(ir, ":") {
 		return ir + ":" + defaultTag
 	}
 	return ir
 }
 
+// ImageRefHash returns the library image hash, sha256.<hex>, selected by
+// the hash tag of the normalized image reference imageRef, or an empty
+// string if imageRef has another tag. An error is returned if the hash
+// is not a valid SHA-256 digest.
+func ImageRefHash(imageRef string) (string, error) {
+	i := strings.LastIndex(imageRef, ":")
+	if i < 0 || !strings.HasPrefix(imageRef[i+1:], hashTagPrefix) {
+		return "", nil
+	}
+	hash := imageRef[i+1:]
+	if b, err := hex.DecodeString(strings.TrimPrefix(hash, hashTagPrefix)); err != nil || len(b) != 32 {
+		return "", fmt.Errorf("invalid SHA-256 digest %q in library reference %s", hash, imageRef)
+	}
+	return hash, nil
+}
+
+// ResolveLibraryRef returns the library base URL and the normalized image
+// reference of the library reference libraryRef. As for docker references,
+// the first component of libraryRef names the library host when it holds
+// a dot or a port or is localhost, defaultURL is returned otherwise.
+func ResolveLibraryRef(libraryRef, defaultURL string) (baseURL, imageRef string) {
+	ir := strings.TrimPrefix(libraryRef, "library://")
+	if i := strings.Index(ir, "/"); i > 0 {
+		host := ir[:i]
+		if strings.ContainsAny(host, ".:") || host == "localhost" {
+			return LibraryHostURL(host), NormalizeLibraryRef(ir[i+1:])
+		}
+	}
+	return defaultURL, NormalizeLibraryRef(ir)
+}
+
+// LibraryHostURL returns the base URL of the library host, which may
+// already be a URL, https being assumed when no scheme is given.
+func LibraryHostURL(host string) string {
+	if strings.Contains(host, "://") {
+		return strings.TrimSuffix(host, "/")
+	}
+	return "https://" + strings.TrimSuffix(host, "/")
+}
+
+// FullLibraryRef returns the library:// reference of the normalized image
+// reference imageRef including the host of the library at baseURL. Images
+// selected by hash are referenced by digest, image@sha256:<hex>.
+func FullLibraryRef(baseURL, imageRef string) string {
+	host := strings.TrimSuffix(baseURL, "/")
+	if u, err := url.Parse(baseURL); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	if hash, err := ImageRefHash(imageRef); err == nil && hash != "" {
+		imageRef = strings.TrimSuffix(imageRef, ":"+hash) + digestPrefix + strings.TrimPrefix(hash, hashTagPrefix)
+	}
+	return "library://" + host + "/" + imageRef
+}
+
 // DownloadImage is a helper function to wrap library image download operation
 func DownloadImage(ctx context.Context, c *scslibrary.Client, imagePath, arch, libraryRef string, callback client.ProgressCallback) error {
 	// reassemble "stripped" library ref for scs-library-client
@@ -52,6 +122,19 @@ func DownloadImage(ctx context.Context, c *scslibrary.Client, imagePath, arch, l
 		tag = r.Tags[0]
 	}
 
+	ctx, cancel := client.WithDownloadTimeout(ctx)
+	defer cancel()
+
+	// enforce the download size limit without progress bar
+	if callback == nil {
+		callback = func(totalSize int64, r io.Reader, w io.Writer) error {
+			if err := client.CheckDownloadSize(ctx, totalSize); err != nil {
+				return err
+			}
+			return client.CopyWithContext(ctx, w, client.LimitReader(ctx, r))
+		}
+	}
+
 	// call library client to download image
 	err = c.DownloadImage(ctx, f, arch, r.Path, tag, callback)
 	if err != nil {
@@ -62,7 +145,7 @@ func DownloadImage(ctx context.Context, c *scslibrary.Client, imagePath, arch, l
 			sylog.Errorf("Error while removing incomplete download: %v", err)
 		}
 
-		return fmt.Errorf("error downloading image: %v", err)
+		return fmt.Errorf("error downloading image %s: %v", validLibraryRef, err)
 	}
 
 	return nil
@@ -11,6 +11,8 @@ import (
 	"github.com/sylabs/scs-library-client/client"
 )
 
+const testHash = "5c439fd262095766693dae95fb81334c3a02a7f0e4dc6291e0648ed4ddc61c6c"
+
 func TestNormalizeLibraryRef(t *testing.T) {
 	tests := []struct {
 		name        string
@@ -22,6 +24,8 @@ func TestNormalizeLibraryRef(t *testing.T) {
 		{"fully qualified with tag", "library://user/collection/container:2.0.0", "user/collection/container:2.0.0", "2.0.0"},
 		{"without tag", "library://alpine", "alpine:latest", "latest"},
 		{"with tag variation", "library://alpine:1.0.1", "alpine:1.0.1", "1.0.1"},
+		{"with hash", "library://alpine:sha256." + testHash, "alpine:sha256." + testHash, "sha256." + testHash},
+		{"with digest", "library://user/collection/container@sha256:" + testHash, "user/collection/container:sha256." + testHash, "sha256." + testHash},
 	}
 
 	for _, tt := range tests {
@@ -45,3 +49,70 @@ func TestNormalizeLibraryRef(t *testing.T) {
 		})
 	}
 }
+
+func TestResolveLibraryRef(t *testing.T) {
+	const defaultURL = "https://library.example.com"
+
+	tests := []struct {
+		name         string
+		libraryRef   string
+		expectedURL  string
+		expectedRef  string
+		expectedFull string
+	}{
+		{"short", "library://alpine", defaultURL, "alpine:latest", "library://library.example.com/alpine:latest"},
+		{"short fully qualified", "library://user/collection/container:2.0.0", defaultURL, "user/collection/container:2.0.0", "library://library.example.com/user/collection/container:2.0.0"},
+		{"host", "library://cloud.sylabs.io/user/collection/container:2.0.0", "https://cloud.sylabs.io", "user/collection/container:2.0.0", "library://cloud.sylabs.io/user/collection/container:2.0.0"},
+		{"host with port", "library://lib:8443/user/collection/container", "https://lib:8443", "user/collection/container:latest", "library://lib:8443/user/collection/container:latest"},
+		{"localhost", "library://localhost/alpine", "https://localhost", "alpine:latest", "library://localhost/alpine:latest"},
+		{"digest", "library://lib:8443/alpine@sha256:" + testHash, "https://lib:8443", "alpine:sha256." + testHash, "library://lib:8443/alpine@sha256:" + testHash},
+		{"hash", "library://alpine:sha256." + testHash, defaultURL, "alpine:sha256." + testHash, "library://library.example.com/alpine@sha256:" + testHash},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL, imageRef := ResolveLibraryRef(tt.libraryRef, defaultURL)
+			if baseURL != tt.expectedURL {
+				t.Errorf("expected URL %s, got %s", tt.expectedURL, baseURL)
+			}
+			if imageRef != tt.expectedRef {
+				t.Errorf("expected reference %s, got %s", tt.expectedRef, imageRef)
+			}
+			if ref := FullLibraryRef(baseURL, imageRef); ref != tt.expectedFull {
+				t.Errorf("expected full reference %s, got %s", tt.expectedFull, ref)
+			}
+		})
+	}
+}
+
+func TestImageRefHash(t *testing.T) {
+	tests := []struct {
+		name     string
+		imageRef string
+		expected string
+		wantErr  bool
+	}{
+		{"tag", "alpine:latest", "", false},
+		{"hash", "user/collection/container:sha256." + testHash, "sha256." + testHash, false},
+		{"short hash", "alpine:sha256.5c439fd2", "", true},
+		{"invalid hash", "alpine:sha256." + testHash[:62] + "zz", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hash, err := ImageRefHash(tt.imageRef)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if hash != tt.expected {
+				t.Errorf("expected hash %q, got %q", tt.expected, hash)
+			}
+		})
+	}
+}
@@ -10,6 +10,9 @@ import (
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 
 	keyclient "github.com/sylabs/scs-key-client/client"
 	scs "github.com/sylabs/scs-library-client/client"
@@ -30,6 +33,12 @@ var (
 func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string, arch string, scsConfig *scs.Config, keystoreURI string) (imagePath string, err error) {
 	imageRef := NormalizeLibraryRef(pullFrom)
 
+	// images pulled by digest must be the exact image requested
+	hash, err := ImageRefHash(imageRef)
+	if err != nil {
+		return "", err
+	}
+
 	sylog.GetLevel()
 
 	c, err := scs.NewClient(scsConfig)
@@ -44,12 +53,23 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 	if err != nil {
 		return "", err
 	}
+	if hash != "" && libraryImage.Hash != hash {
+		return "", fmt.Errorf("library returned image %s for %s", libraryImage.Hash, imageRef)
+	}
 
 	if directTo != "" {
 		sylog.Infof("Downloading library image")
 		if err = DownloadImage(ctx, c, directTo, arch, imageRef, client.ProgressBarCallback(ctx)); err != nil {
 			return "", fmt.Errorf("unable to download image: %v", err)
 		}
+		if hash != "" {
+			if fileHash, err := scs.ImageHash(directTo); err != nil {
+				return "", fmt.Errorf("error getting image hash: %v", err)
+			} else if fileHash != hash {
+				os.Remove(directTo)
+				return "", fmt.Errorf("downloaded image hash(%s) and expected hash(%s) does not match", fileHash, hash)
+			}
+		}
 		imagePath = directTo
 
 	} else {
@@ -78,6 +98,9 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 		} else {
 			sylog.Infof("Using cached image")
 		}
+		if err := imgCache.SetRef(cache.LibraryCacheType, cacheRef(scsConfig, imageRef, arch), libraryImage.Hash); err != nil {
+			sylog.Warningf("Could not record cache reference of %s: %v", imageRef, err)
+		}
 		imagePath = cacheEntry.Path
 	}
 
@@ -101,6 +124,44 @@ func Pull(ctx context.Context, imgCache *cache.Handle, pullFrom string, arch str
 	return pull(ctx, imgCache, directTo, pullFrom, arch, scsConfig, keystoreURI)
 }
 
+// PullFromCache returns the path of the cached library image pullFrom for
+// arch, without querying the library. An error naming the image is returned
+// if it was not pulled into the cache before.
+func PullFromCache(imgCache *cache.Handle, pullFrom, arch string, scsConfig *scs.Config) (imagePath string, err error) {
+	imageRef := NormalizeLibraryRef(pullFrom)
+
+	cacheDir, err := imgCache.GetFileCacheDir(cache.LibraryCacheType)
+	if err != nil {
+		return "", err
+	}
+
+	// library images are cached by hash, an image pulled by
+	// digest is found in the cache whatever its reference
+	hash, err := ImageRefHash(imageRef)
+	if err != nil {
+		return "", err
+	}
+	if hash != "" {
+		if imgCache.IsDisabled() || !fs.IsFile(filepath.Join(cacheDir, hash)) {
+			return "", &cache.ErrRefNotCached{CacheType: cache.LibraryCacheType, Ref: imageRef}
+		}
+	} else {
+		hash, err = imgCache.LookupRef(cache.LibraryCacheType, cacheRef(scsConfig, imageRef, arch))
+		if err != nil {
+			return "", err
+		}
+	}
+	sylog.Infof("Using cached image %s", hash)
+	return filepath.Join(cacheDir, hash), nil
+}
+
+// cacheRef returns the reference recorded in the cache for the library
+// image imageRef, as a tag may point to different images over time or
+// across libraries and architectures.
+func cacheRef(scsConfig *scs.Config, imageRef, arch string) string {
+	return fmt.Sprintf("%s/%s (%s)", strings.TrimSuffix(scsConfig.BaseURL, "/"), imageRef, arch)
+}
+
 // PullToFile will pull a library image to the specified location, through the cache, or directly if cache is disabled
 func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom, arch string, tmpDir string, scsConfig *scs.Config, keystoreURI string) (imagePath string, err error) {
 
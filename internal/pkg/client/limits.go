@@ -0,0 +1,102 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultMaxDownloadSize is the default maximum size of a download (64GiB).
+	DefaultMaxDownloadSize = 64 << 30
+	// DefaultDownloadTimeout is the default maximum duration of a download.
+	DefaultDownloadTimeout = 2 * time.Hour
+)
+
+// DownloadLimits bounds the size and duration of downloads.
+type DownloadLimits struct {
+	// MaxSize is the maximum number of bytes of a download,
+	// zero means no limit.
+	MaxSize int64
+	// Timeout is the maximum duration of a download request,
+	// zero means no timeout.
+	Timeout time.Duration
+}
+
+type limitsKey struct{}
+
+// WithDownloadLimits returns a copy of ctx carrying the download limits.
+func WithDownloadLimits(ctx context.Context, limits DownloadLimits) context.Context {
+	return context.WithValue(ctx, limitsKey{}, limits)
+}
+
+// GetDownloadLimits returns the download limits carried by ctx, if any.
+func GetDownloadLimits(ctx context.Context) DownloadLimits {
+	limits, _ := ctx.Value(limitsKey{}).(DownloadLimits)
+	return limits
+}
+
+// WithDownloadTimeout returns a copy of ctx canceled once the download
+// timeout carried by ctx elapsed, if any.
+func WithDownloadTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if timeout := GetDownloadLimits(ctx).Timeout; timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// SizeLimitError is returned when a download exceeds its size limit.
+type SizeLimitError struct {
+	Limit int64
+}
+
+func (e *SizeLimitError) Error() string {
+	return fmt.Sprintf("download exceeds the %d bytes size limit", e.Limit)
+}
+
+// CheckDownloadSize returns a *SizeLimitError if size exceeds the
+// maximum download size carried by ctx.
+func CheckDownloadSize(ctx context.Context, size int64) error {
+	if max := GetDownloadLimits(ctx).MaxSize; max > 0 && size > max {
+		return &SizeLimitError{Limit: max}
+	}
+	return nil
+}
+
+// downloaded is the number of bytes downloaded by the process.
+var downloaded int64
+
+// CountDownload adds n to the number of bytes downloaded, for downloads
+// not going through LimitReader.
+func CountDownload(n int64) {
+	atomic.AddInt64(&downloaded, n)
+}
+
+// Downloaded returns the number of bytes downloaded by the process.
+func Downloaded() int64 {
+	return atomic.LoadInt64(&downloaded)
+}
+
+// LimitReader returns a reader failing with a *SizeLimitError once more
+// than the maximum download size carried by ctx has been read from r.
+// The bytes read are counted in Downloaded.
+func LimitReader(ctx context.Context, r io.Reader) io.Reader {
+	max := GetDownloadLimits(ctx).MaxSize
+	read := int64(0)
+	return readerFunc(func(p []byte) (int, error) {
+		n, err := r.Read(p)
+		CountDownload(int64(n))
+		read += int64(n)
+		if max > 0 && read > max {
+			return n, &SizeLimitError{Limit: max}
+		}
+		return n, err
+	})
+}
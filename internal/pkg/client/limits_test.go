@@ -0,0 +1,70 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDownloadLimits(t *testing.T) {
+	ctx := WithDownloadLimits(context.Background(), DownloadLimits{MaxSize: 4})
+
+	var sizeErr *SizeLimitError
+
+	if err := CheckDownloadSize(ctx, 4); err != nil {
+		t.Errorf("unexpected error for a size within the limit: %s", err)
+	}
+	// unknown content length is only checked while reading
+	if err := CheckDownloadSize(ctx, -1); err != nil {
+		t.Errorf("unexpected error for an unknown size: %s", err)
+	}
+	if err := CheckDownloadSize(ctx, 5); !errors.As(err, &sizeErr) {
+		t.Errorf("unexpected error for a size exceeding the limit: %v", err)
+	}
+
+	if b, err := ioutil.ReadAll(LimitReader(ctx, strings.NewReader("data"))); err != nil || string(b) != "data" {
+		t.Errorf("unexpected result %q for a download within the limit: %v", b, err)
+	}
+	if _, err := ioutil.ReadAll(LimitReader(ctx, strings.NewReader("data!"))); !errors.As(err, &sizeErr) {
+		t.Errorf("unexpected error for a download exceeding the limit: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := ProgressBarCallback(ctx)(-1, strings.NewReader("data!"), &buf); !errors.As(err, &sizeErr) {
+		t.Errorf("unexpected error for a download exceeding the limit: %v", err)
+	}
+
+	// no limits
+	if err := CheckDownloadSize(context.Background(), 1<<40); err != nil {
+		t.Errorf("unexpected error without limit: %s", err)
+	}
+
+	ctx = WithDownloadLimits(context.Background(), DownloadLimits{Timeout: time.Millisecond})
+	ctx, cancel := WithDownloadTimeout(ctx)
+	defer cancel()
+	select {
+	case <-ctx.Done():
+	case <-time.After(time.Second):
+		t.Errorf("download timeout not applied")
+	}
+}
+
+func TestDownloaded(t *testing.T) {
+	before := Downloaded()
+	if _, err := ioutil.ReadAll(LimitReader(context.Background(), strings.NewReader("data"))); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	CountDownload(2)
+	if got := Downloaded() - before; got != 6 {
+		t.Errorf("got %d bytes downloaded, want 6", got)
+	}
+}
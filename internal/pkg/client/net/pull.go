@@ -55,6 +55,9 @@ func DownloadImage(ctx context.Context, filePath string, netURL string) error {
 		Timeout: pullTimeout * time.Second,
 	}
 
+	ctx, cancel := client.WithDownloadTimeout(ctx)
+	defer cancel()
+
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
 	if err != nil {
 		return err
@@ -102,7 +105,7 @@ func DownloadImage(ctx context.Context, filePath string, netURL string) error {
 		if err := os.Remove(filePath); err != nil {
 			sylog.Errorf("Error while removing incomplete download: %v", err)
 		}
-		return err
+		return fmt.Errorf("while downloading %s: %v", url, err)
 	}
 
 	sylog.Debugf("Download complete\n")
@@ -0,0 +1,200 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package oci
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/containers/image/v5/copy"
+	"github.com/containers/image/v5/docker"
+	"github.com/containers/image/v5/docker/reference"
+	ocilayout "github.com/containers/image/v5/oci/layout"
+	"github.com/containers/image/v5/signature"
+	ocitypes "github.com/containers/image/v5/types"
+	imgspecv1 "github.com/opencontainers/image-spec/specs-go/v1"
+	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/internal/pkg/build/export"
+	"github.com/sylabs/singularity/pkg/build/types"
+	"github.com/sylabs/singularity/pkg/image"
+	"github.com/sylabs/singularity/pkg/image/unpacker"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// Push converts the SIF image found at path to a single layer OCI image
+// and pushes it to the registry reference ref, as //registry/name:tag.
+func Push(ctx context.Context, path, ref, tmpDir string, ociAuth *ocitypes.DockerAuthConfig, noHTTPS bool) error {
+	named, err := reference.ParseNormalizedNamed(strings.TrimPrefix(ref, "//"))
+	if err != nil {
+		return fmt.Errorf("bad reference %s: %s", ref, err)
+	}
+	if _, ok := named.(reference.Digested); ok {
+		return fmt.Errorf("bad reference %s: images can't be pushed to a digest", ref)
+	}
+	named = reference.TagNameOnly(named)
+	destRef, err := docker.NewReference(named)
+	if err != nil {
+		return fmt.Errorf("bad reference %s: %s", ref, err)
+	}
+
+	img, err := image.Init(path, false)
+	if err != nil {
+		return err
+	}
+	defer img.File.Close()
+
+	if img.Type != image.SIF {
+		return fmt.Errorf("%s is not a SIF image", path)
+	}
+	if overlays, err := img.GetOverlayPartitions(); err != nil {
+		return err
+	} else if len(overlays) > 0 {
+		return fmt.Errorf("images with overlay partitions can't be converted to OCI images, build them with --squash-layers")
+	}
+	part, err := img.GetRootFsPartition()
+	if err != nil {
+		return err
+	}
+	if part.Type != image.SQUASHFS {
+		return fmt.Errorf("only squashfs root filesystems can be converted to OCI images")
+	}
+
+	dir, err := ioutil.TempDir(tmpDir, "oci-export-")
+	if err != nil {
+		return fmt.Errorf("could not create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	sylog.Infof("Extracting root filesystem")
+	rootfs := filepath.Join(dir, "rootfs")
+	reader := io.NewSectionReader(img.File, int64(part.Offset), int64(part.Size))
+	if err := unpacker.NewSquashfs().ExtractAll(reader, rootfs); err != nil {
+		return fmt.Errorf("while extracting root filesystem: %s", err)
+	}
+
+	base, err := baseImageConfig(img)
+	if err != nil {
+		return err
+	}
+	conf, err := export.ImageConfig(rootfs, base)
+	if err != nil {
+		return err
+	}
+	if lost := export.LostContent(rootfs); len(lost) > 0 {
+		sylog.Warningf("%s can't be represented in OCI images, only kept in the image filesystem", strings.Join(lost, ", "))
+	}
+
+	annotations, err := imageAnnotations(img)
+	if err != nil {
+		return err
+	}
+
+	sylog.Infof("Creating OCI image")
+	tag := named.(reference.Tagged).Tag()
+	layout := filepath.Join(dir, "layout")
+	if err := export.WriteLayout(layout, tag, rootfs, imageArch(path), conf, annotations, false); err != nil {
+		return fmt.Errorf("while creating OCI image: %s", err)
+	}
+	srcRef, err := ocilayout.ParseReference(layout + ":" + tag)
+	if err != nil {
+		return err
+	}
+
+	policy := &signature.Policy{Default: []signature.PolicyRequirement{signature.NewPRInsecureAcceptAnything()}}
+	policyCtx, err := signature.NewPolicyContext(policy)
+	if err != nil {
+		return err
+	}
+	defer policyCtx.Destroy()
+
+	// see pull for the handling of DockerInsecureSkipTLSVerify
+	sysCtx := &ocitypes.SystemContext{
+		DockerAuthConfig: ociAuth,
+	}
+	if noHTTPS {
+		sysCtx.DockerInsecureSkipTLSVerify = ocitypes.NewOptionalBool(true)
+	}
+
+	_, err = copy.Image(ctx, policyCtx, destRef, srcRef, &copy.Options{
+		ReportWriter:   sylog.Writer(),
+		DestinationCtx: sysCtx,
+	})
+	if err != nil {
+		return fmt.Errorf("while pushing OCI image: %s", err)
+	}
+	return nil
+}
+
+// baseImageConfig returns the configuration of the docker/OCI image
+// the SIF image was built from, if any.
+func baseImageConfig(img *image.Image) (*imgspecv1.ImageConfig, error) {
+	for i, section := range img.Sections {
+		if section.Type != uint32(sif.DataGenericJSON) || section.Name != types.OCIConfigJSON+".json" {
+			continue
+		}
+		r, err := image.NewSectionReader(img, "", i)
+		if err != nil {
+			return nil, fmt.Errorf("while reading SIF section: %s", err)
+		}
+		conf := new(imgspecv1.ImageConfig)
+		if err := json.NewDecoder(r).Decode(conf); err != nil {
+			return nil, fmt.Errorf("while decoding OCI configuration: %s", err)
+		}
+		return conf, nil
+	}
+	return nil, nil
+}
+
+// imageAnnotations returns the annotations recorded in the SIF image
+// with build --annotate as OCI manifest annotations, non string values
+// being converted to their JSON text.
+func imageAnnotations(img *image.Image) (map[string]string, error) {
+	for i, section := range img.Sections {
+		if section.Type != uint32(sif.DataGenericJSON) || section.Name != types.AnnotationsJSON+".json" {
+			continue
+		}
+		r, err := image.NewSectionReader(img, "", i)
+		if err != nil {
+			return nil, fmt.Errorf("while reading SIF section: %s", err)
+		}
+		var raw map[string]json.RawMessage
+		if err := json.NewDecoder(r).Decode(&raw); err != nil {
+			return nil, fmt.Errorf("while decoding annotations: %s", err)
+		}
+		return export.ManifestAnnotations(raw)
+	}
+	return nil, nil
+}
+
+// imageArch returns the architecture of the primary system partition
+// of the SIF image found at path, or the host architecture.
+func imageArch(path string) string {
+	f, err := sif.LoadContainer(path, true)
+	if err != nil {
+		return runtime.GOARCH
+	}
+	defer f.UnloadContainer()
+
+	for _, desc := range f.DescrArr {
+		if !desc.Used || desc.Datatype != sif.DataPartition {
+			continue
+		}
+		if ptype, err := desc.GetPartType(); err != nil || ptype != sif.PartPrimSys {
+			continue
+		}
+		if b, err := desc.GetArch(); err == nil {
+			return sif.GetGoArch(string(b[:sif.HdrArchLen-1]))
+		}
+	}
+	return runtime.GOARCH
+}
@@ -9,6 +9,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"path/filepath"
 
 	ocitypes "github.com/containers/image/v5/types"
 	"github.com/sylabs/singularity/internal/pkg/cache"
@@ -56,6 +57,9 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 		} else {
 			sylog.Infof("Using cached SIF image")
 		}
+		if err := imgCache.SetRef(cache.OrasCacheType, pullFrom, hash); err != nil {
+			sylog.Warningf("Could not record cache reference of %s: %v", pullFrom, err)
+		}
 		imagePath = cacheEntry.Path
 	}
 
@@ -103,3 +107,19 @@ func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom, t
 
 	return pullTo, nil
 }
+
+// PullFromCache returns the path of the cached oras image pullFrom, without
+// querying the registry. An error naming the image is returned if it was
+// not pulled into the cache before.
+func PullFromCache(imgCache *cache.Handle, pullFrom string) (imagePath string, err error) {
+	hash, err := imgCache.LookupRef(cache.OrasCacheType, pullFrom)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := imgCache.GetFileCacheDir(cache.OrasCacheType)
+	if err != nil {
+		return "", err
+	}
+	sylog.Infof("Using cached SIF image %s", hash)
+	return filepath.Join(cacheDir, hash), nil
+}
@@ -22,14 +22,25 @@ func (rf readerFunc) Read(p []byte) (n int, err error) { return rf(p) }
 // ProgressCallback is a function that provides progress information copying from a Reader to a Writer
 type ProgressCallback func(int64, io.Reader, io.Writer) error
 
-// ProgressBarCallback returns a progress bar callback unless e.g. --quiet or lower loglevel is set
+// ProgressBarCallback returns a progress bar callback unless e.g. --quiet or lower loglevel is set,
+// the download limits carried by ctx are enforced in both cases
 func ProgressBarCallback(ctx context.Context) ProgressCallback {
 
 	if sylog.GetLevel() <= -1 {
-		return nil
+		return func(totalSize int64, r io.Reader, w io.Writer) error {
+			if err := CheckDownloadSize(ctx, totalSize); err != nil {
+				return err
+			}
+			return CopyWithContext(ctx, w, LimitReader(ctx, r))
+		}
 	}
 
 	return func(totalSize int64, r io.Reader, w io.Writer) error {
+		if err := CheckDownloadSize(ctx, totalSize); err != nil {
+			return err
+		}
+		r = LimitReader(ctx, r)
+
 		p := mpb.New()
 		bar := p.AddBar(totalSize,
 			mpb.PrependDecorators(
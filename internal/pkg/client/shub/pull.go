@@ -11,6 +11,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/sylabs/singularity/internal/pkg/client"
@@ -55,7 +56,10 @@ func DownloadImage(ctx context.Context, manifest APIResponse, filePath, shubRef
 		Timeout: pullTimeout * time.Second,
 	}
 
-	req, err := http.NewRequest(http.MethodGet, manifest.Image, nil)
+	ctx, cancel := client.WithDownloadTimeout(ctx)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, manifest.Image, nil)
 	if err != nil {
 		return err
 	}
@@ -107,7 +111,7 @@ func DownloadImage(ctx context.Context, manifest APIResponse, filePath, shubRef
 		if err := os.Remove(filePath); err != nil {
 			sylog.Errorf("Error while removing incomplete download: %v", err)
 		}
-		return err
+		return fmt.Errorf("while downloading %s: %v", req.URL, err)
 	}
 	out.Close()
 
@@ -170,6 +174,9 @@ func pull(ctx context.Context, imgCache *cache.Handle, directTo, pullFrom string
 			sylog.Infof("Use cached image")
 			imagePath = cacheEntry.Path
 		}
+		if err := imgCache.SetRef(cache.ShubCacheType, pullFrom, manifest.Commit); err != nil {
+			sylog.Warningf("Could not record cache reference of %s: %v", pullFrom, err)
+		}
 
 	}
 
@@ -194,6 +201,22 @@ func Pull(ctx context.Context, imgCache *cache.Handle, pullFrom, tmpDir string,
 
 }
 
+// PullFromCache returns the path of the cached shub image pullFrom, without
+// querying Singularity Hub. An error naming the image is returned if it was
+// not pulled into the cache before.
+func PullFromCache(imgCache *cache.Handle, pullFrom string) (imagePath string, err error) {
+	hash, err := imgCache.LookupRef(cache.ShubCacheType, pullFrom)
+	if err != nil {
+		return "", err
+	}
+	cacheDir, err := imgCache.GetFileCacheDir(cache.ShubCacheType)
+	if err != nil {
+		return "", err
+	}
+	sylog.Infof("Use cached image %s", hash)
+	return filepath.Join(cacheDir, hash), nil
+}
+
 // PullToFile will pull a shub image to the specified location, through the cache, or directly if cache is disabled
 func PullToFile(ctx context.Context, imgCache *cache.Handle, pullTo, pullFrom, tmpDir string, noHTTPS bool) (imagePath string, err error) {
 
@@ -0,0 +1,87 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+// maxIDRanges is the maximum number of ID ranges, older kernels accept up
+// to 5 mapping lines and the user is always mapped to container ID 0.
+const maxIDRanges = 4
+
+// ParseIDMap parses a comma separated list of <container id>:<size> ranges
+// of container IDs to map during a fakeroot build. The returned mappings
+// have no host ID, which is set by MapIDRanges.
+func ParseIDMap(s string) ([]specs.LinuxIDMapping, error) {
+	var ranges []specs.LinuxIDMapping
+
+	list := strings.Split(s, ",")
+	if len(list) > maxIDRanges {
+		return nil, fmt.Errorf("too many ID ranges: %d, at most %d can be mapped", len(list), maxIDRanges)
+	}
+
+	for _, r := range list {
+		fields := strings.Split(strings.TrimSpace(r), fieldSeparator)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("bad ID range %q: must be <container id>:<size>", r)
+		}
+		id, err := strconv.ParseUint(fields[0], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("bad container ID in range %q: %v", r, err)
+		}
+		size, err := strconv.ParseUint(fields[1], 10, 32)
+		if err != nil || size == 0 {
+			return nil, fmt.Errorf("bad size in range %q: must be a positive number", r)
+		}
+		if id == 0 {
+			return nil, fmt.Errorf("bad ID range %q: container ID 0 is always mapped to your user", r)
+		}
+		if id+size-1 > uint64(maxUID) {
+			return nil, fmt.Errorf("bad ID range %q: exceeds the highest ID", r)
+		}
+		ranges = append(ranges, specs.LinuxIDMapping{
+			ContainerID: uint32(id),
+			Size:        uint32(size),
+		})
+	}
+
+	for i, a := range ranges {
+		for _, b := range ranges[i+1:] {
+			if a.ContainerID < b.ContainerID+b.Size && b.ContainerID < a.ContainerID+a.Size {
+				return nil, fmt.Errorf("ID ranges %d:%d and %d:%d overlap", a.ContainerID, a.Size, b.ContainerID, b.Size)
+			}
+		}
+	}
+
+	return ranges, nil
+}
+
+// MapIDRanges returns the mappings of the container ID ranges onto the
+// subordinate ID range idRange, the ranges taking consecutive subordinate
+// IDs in order. The ranges can't map more IDs than idRange holds.
+func MapIDRanges(idRange *specs.LinuxIDMapping, ranges []specs.LinuxIDMapping) ([]specs.LinuxIDMapping, error) {
+	mappings := make([]specs.LinuxIDMapping, 0, len(ranges))
+
+	var offset uint64
+	for _, r := range ranges {
+		if offset+uint64(r.Size) > uint64(idRange.Size) {
+			return nil, fmt.Errorf("ID range %d:%d exceeds the %d subordinate IDs allocated to your user", r.ContainerID, r.Size, idRange.Size)
+		}
+		mappings = append(mappings, specs.LinuxIDMapping{
+			ContainerID: r.ContainerID,
+			HostID:      idRange.HostID + uint32(offset),
+			Size:        r.Size,
+		})
+		offset += uint64(r.Size)
+	}
+
+	return mappings, nil
+}
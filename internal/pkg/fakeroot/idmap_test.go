@@ -0,0 +1,157 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package fakeroot
+
+import (
+	"reflect"
+	"testing"
+
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
+func TestParseIDMap(t *testing.T) {
+	tests := []struct {
+		name    string
+		idMap   string
+		want    []specs.LinuxIDMapping
+		wantErr bool
+	}{
+		{
+			name:  "Single",
+			idMap: "1000:1",
+			want:  []specs.LinuxIDMapping{{ContainerID: 1000, Size: 1}},
+		},
+		{
+			name:  "Multiple",
+			idMap: "1:999, 1000:10",
+			want: []specs.LinuxIDMapping{
+				{ContainerID: 1, Size: 999},
+				{ContainerID: 1000, Size: 10},
+			},
+		},
+		{
+			name:    "Empty",
+			idMap:   "",
+			wantErr: true,
+		},
+		{
+			name:    "NoSize",
+			idMap:   "1000",
+			wantErr: true,
+		},
+		{
+			name:    "ZeroSize",
+			idMap:   "1000:0",
+			wantErr: true,
+		},
+		{
+			name:    "Root",
+			idMap:   "0:10",
+			wantErr: true,
+		},
+		{
+			name:    "Negative",
+			idMap:   "-1:10",
+			wantErr: true,
+		},
+		{
+			name:    "HighestID",
+			idMap:   "4294967295:2",
+			wantErr: true,
+		},
+		{
+			name:    "Overlap",
+			idMap:   "1:1000,999:10",
+			wantErr: true,
+		},
+		{
+			name:    "TooMany",
+			idMap:   "1:1,2:1,3:1,4:1,5:1",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseIDMap(tt.idMap)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMapIDRanges(t *testing.T) {
+	idRange := &specs.LinuxIDMapping{
+		ContainerID: 1,
+		HostID:      100000,
+		Size:        65536,
+	}
+
+	tests := []struct {
+		name    string
+		ranges  []specs.LinuxIDMapping
+		want    []specs.LinuxIDMapping
+		wantErr bool
+	}{
+		{
+			name:   "Single",
+			ranges: []specs.LinuxIDMapping{{ContainerID: 1000, Size: 1}},
+			want:   []specs.LinuxIDMapping{{ContainerID: 1000, HostID: 100000, Size: 1}},
+		},
+		{
+			name: "Consecutive",
+			ranges: []specs.LinuxIDMapping{
+				{ContainerID: 1000, Size: 10},
+				{ContainerID: 1, Size: 999},
+			},
+			want: []specs.LinuxIDMapping{
+				{ContainerID: 1000, HostID: 100000, Size: 10},
+				{ContainerID: 1, HostID: 100010, Size: 999},
+			},
+		},
+		{
+			name:   "Whole",
+			ranges: []specs.LinuxIDMapping{{ContainerID: 100000, Size: 65536}},
+			want:   []specs.LinuxIDMapping{{ContainerID: 100000, HostID: 100000, Size: 65536}},
+		},
+		{
+			name: "Exceeding",
+			ranges: []specs.LinuxIDMapping{
+				{ContainerID: 1, Size: 65536},
+				{ContainerID: 70000, Size: 1},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MapIDRanges(idRange, tt.ranges)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success, got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,10 @@
 
 package fakeroot
 
+import (
+	specs "github.com/opencontainers/runtime-spec/specs-go"
+)
+
 // Name of the engine
 const Name = "fakeroot"
 
@@ -15,4 +19,9 @@ type EngineConfig struct {
 	Envs     []string `json:"envs"`
 	Home     string   `json:"home"`
 	BuildEnv bool     `json:"buildEnv"`
+	// UIDMap and GIDMap are the container ID ranges mapped onto the
+	// subordinate ID ranges, the whole subordinate ranges are mapped
+	// from container ID 1 if empty
+	UIDMap []specs.LinuxIDMapping `json:"uidMap,omitempty"`
+	GIDMap []specs.LinuxIDMapping `json:"gidMap,omitempty"`
 }
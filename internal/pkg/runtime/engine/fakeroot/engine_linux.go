@@ -118,7 +118,13 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 	if err != nil {
 		return fmt.Errorf("could not use fakeroot: %s", err)
 	}
-	g.AddLinuxUIDMapping(idRange.HostID, idRange.ContainerID, idRange.Size)
+	mappings, err := idMappings(idRange, e.EngineConfig.UIDMap)
+	if err != nil {
+		return fmt.Errorf("could not use fakeroot UID mapping: %s", err)
+	}
+	for _, m := range mappings {
+		g.AddLinuxUIDMapping(m.HostID, m.ContainerID, m.Size)
+	}
 	starterConfig.AddUIDMappings(g.Config.Linux.UIDMappings)
 
 	g.AddLinuxGIDMapping(gid, 0, 1)
@@ -126,7 +132,13 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 	if err != nil {
 		return fmt.Errorf("could not use fakeroot: %s", err)
 	}
-	g.AddLinuxGIDMapping(idRange.HostID, idRange.ContainerID, idRange.Size)
+	mappings, err = idMappings(idRange, e.EngineConfig.GIDMap)
+	if err != nil {
+		return fmt.Errorf("could not use fakeroot GID mapping: %s", err)
+	}
+	for _, m := range mappings {
+		g.AddLinuxGIDMapping(m.HostID, m.ContainerID, m.Size)
+	}
 	starterConfig.AddGIDMappings(g.Config.Linux.GIDMappings)
 
 	starterConfig.SetHybridWorkflow(true)
@@ -150,6 +162,15 @@ func (e *EngineOperations) PrepareConfig(starterConfig *starter.Config) error {
 	return nil
 }
 
+// idMappings returns the mappings of the subordinate ID range idRange, the
+// whole range by default or the container ID ranges requested.
+func idMappings(idRange *specs.LinuxIDMapping, ranges []specs.LinuxIDMapping) ([]specs.LinuxIDMapping, error) {
+	if len(ranges) == 0 {
+		return []specs.LinuxIDMapping{*idRange}, nil
+	}
+	return fakerootutil.MapIDRanges(idRange, ranges)
+}
+
 // CreateContainer does nothing for the fakeroot engine.
 func (e *EngineOperations) CreateContainer(context.Context, int, net.Conn) error {
 	return nil
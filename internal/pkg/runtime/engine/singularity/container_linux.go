@@ -235,6 +235,9 @@ func create(ctx context.Context, engine *EngineOperations, rpcOps *client.RPC, p
 	if err := c.addFilesMount(system); err != nil {
 		return err
 	}
+	if err := c.addDataFilesMount(system); err != nil {
+		return err
+	}
 	if err := c.addResolvConfMount(system); err != nil {
 		return err
 	}
@@ -1101,6 +1104,10 @@ func (c *container) addImageBindMount(system *mount.System) error {
 					return fmt.Errorf("while getting data partition for %s: %s", img.Path, err)
 				}
 				for _, part := range partitions {
+					// the %datafile partition is only exposed by --datafiles
+					if part.Name == image.DataFilesPartition {
+						continue
+					}
 					data = &part
 					break
 				}
@@ -2072,6 +2079,58 @@ func (c *container) addFilesMount(system *mount.System) error {
 	return nil
 }
 
+// addDataFilesMount exposes the data files stored in the SIF image
+// read-only in the requested container directory, by mounting the
+// squashfs data partition holding them from its offset in the image.
+func (c *container) addDataFilesMount(system *mount.System) error {
+	dir := c.engine.EngineConfig.GetDataFilesDir()
+	if dir == "" {
+		return nil
+	}
+
+	img := c.engine.EngineConfig.GetImageList()[0]
+	part, err := image.GetDataFilesPartition(&img)
+	if err != nil {
+		return fmt.Errorf("while getting data files partition for %s: %s", img.Path, err)
+	}
+	if part == nil {
+		sylog.Warningf("No data files found in image, ignoring --datafiles")
+		return nil
+	}
+
+	sessionDest := "/datafiles"
+	if err := c.session.AddDir(sessionDest); err != nil {
+		return fmt.Errorf("failed to create session directory for data files: %s", err)
+	}
+	imgDest, _ := c.session.GetPath(sessionDest)
+
+	flags := uintptr(syscall.MS_NOSUID | syscall.MS_NODEV | syscall.MS_RDONLY)
+
+	err = system.Points.AddImage(
+		mount.PreLayerTag,
+		img.Source,
+		imgDest,
+		"squashfs",
+		flags,
+		part.Offset,
+		part.Size,
+		nil,
+	)
+	if err != nil {
+		return fmt.Errorf("while adding data files partition from %s: %s", img.Path, err)
+	}
+
+	sylog.Debugf("Adding data files directory %s to mount list", dir)
+	if err := system.Points.AddBind(mount.FilesTag, imgDest, dir, flags|syscall.MS_BIND); err != nil {
+		return fmt.Errorf("unable to add %s to mount list: %s", dir, err)
+	}
+	if err := system.Points.AddRemount(mount.FilesTag, dir, flags|syscall.MS_BIND); err != nil {
+		return fmt.Errorf("unable to add %s to mount list: %s", dir, err)
+	}
+
+	return nil
+}
+
 func (c *container) addIdentityMount(system *mount.System) error {
 	if (os.Geteuid() == 0 && c.engine.EngineConfig.GetTargetUID() == 0) ||
 		c.engine.EngineConfig.GetFakeroot() {
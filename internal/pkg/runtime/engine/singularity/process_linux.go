@@ -759,6 +759,7 @@ func hashBuiltin(ctx context.Context, argv []string) error {
 func runActionScript(engineConfig *singularityConfig.EngineConfig) ([]string, []string, error) {
 	args := engineConfig.OciConfig.Process.Args
 	env := append(engineConfig.OciConfig.Process.Env, "SINGULARITY_COMMAND="+filepath.Base(args[0]))
+	action := args
 
 	b := bytes.NewBufferString(files.ActionScript)
 
@@ -806,6 +807,12 @@ func runActionScript(engineConfig *singularityConfig.EngineConfig) ([]string, []
 		return nil, nil, err
 	}
 
+	// a custom action script is run in place of the action command
+	// with the environment set up by the action script
+	if isCustomAction(action[0]) {
+		return action, env, nil
+	}
+
 	if len(args) > 0 && args[0] == "/.singularity.d/runscript" {
 		b, err := getDockerRunscript(args[0])
 		if err != nil {
@@ -829,6 +836,27 @@ func runActionScript(engineConfig *singularityConfig.EngineConfig) ([]string, []
 	return args, env, nil
 }
 
+// isCustomAction returns if the action script found at path was set
+// by a %actions section of the definition file.
+func isCustomAction(path string) bool {
+	if filepath.Dir(path) != "/.singularity.d/actions" {
+		return false
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for i := 0; i < 2 && scanner.Scan(); i++ {
+		if i == 1 && scanner.Text() == files.CustomActionMarker {
+			return true
+		}
+	}
+	return false
+}
+
 // getDockerRunscript returns the content as a reader of
 // the default runscript set for docker images if any.
 func getDockerRunscript(path string) (io.Reader, error) {
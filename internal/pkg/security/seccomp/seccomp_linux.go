@@ -73,6 +73,11 @@ func Enabled() bool {
 	return true
 }
 
+// Supported returns whether seccomp filters are supported by the kernel
+func Supported() bool {
+	return prctl(syscall.PR_GET_SECCOMP, 0, 0, 0, 0) != syscall.EINVAL
+}
+
 // LoadSeccompConfig loads seccomp configuration filter for the current process
 func LoadSeccompConfig(config *specs.LinuxSeccomp, noNewPrivs bool, errNo int16) error {
 	if err := prctl(syscall.PR_GET_SECCOMP, 0, 0, 0, 0); err == syscall.EINVAL {
@@ -20,6 +20,12 @@ func Enabled() bool {
 	return false
 }
 
+// Supported returns whether seccomp filters are supported, they are not
+// without seccomp support at compilation time
+func Supported() bool {
+	return false
+}
+
 // LoadSeccompConfig returns an error for unsupported platforms or without seccomp support
 func LoadSeccompConfig(config *specs.LinuxSeccomp, noNewPrivs bool, errNo int16) error {
 	if runtime.GOOS == "linux" {
@@ -0,0 +1,91 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package auth
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	ocitypes "github.com/containers/image/v5/types"
+)
+
+// DockerHubRegistry is the canonical name of the Docker Hub registry.
+const DockerHubRegistry = "docker.io"
+
+// dockerHubAliases are the registry names resolving to Docker Hub.
+var dockerHubAliases = []string{
+	"index.docker.io",
+	"registry-1.docker.io",
+}
+
+// authFile is the content of a podman/skopeo compatible auth.json file.
+type authFile struct {
+	Auths map[string]authEntry `json:"auths"`
+}
+
+type authEntry struct {
+	Auth string `json:"auth"`
+}
+
+// NormalizeRegistry returns the registry host used as key for the
+// credentials of registry, scheme and path are discarded and Docker
+// Hub aliases are resolved to docker.io.
+func NormalizeRegistry(registry string) string {
+	if i := strings.Index(registry, "://"); i >= 0 {
+		registry = registry[i+3:]
+	}
+	if i := strings.Index(registry, "/"); i >= 0 {
+		registry = registry[:i]
+	}
+	registry = strings.ToLower(registry)
+	for _, alias := range dockerHubAliases {
+		if registry == alias {
+			return DockerHubRegistry
+		}
+	}
+	return registry
+}
+
+// ReadDockerAuth returns the credentials stored for registry in the
+// podman/skopeo compatible auth file found at path. A nil value is
+// returned if the file holds no credentials for this registry.
+func ReadDockerAuth(path, registry string) (*ocitypes.DockerAuthConfig, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read auth file: %s", err)
+	}
+
+	var af authFile
+	if err := json.Unmarshal(b, &af); err != nil {
+		return nil, fmt.Errorf("could not parse auth file %s: %s", path, err)
+	}
+
+	registry = NormalizeRegistry(registry)
+
+	for key, entry := range af.Auths {
+		if NormalizeRegistry(key) != registry {
+			continue
+		}
+		// do not report the decoded value, it holds the credentials
+		dec, err := base64.StdEncoding.DecodeString(entry.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credentials for registry %s in auth file %s", registry, path)
+		}
+		creds := strings.SplitN(string(dec), ":", 2)
+		if len(creds) != 2 {
+			return nil, fmt.Errorf("invalid credentials for registry %s in auth file %s", registry, path)
+		}
+		return &ocitypes.DockerAuthConfig{
+			Username: creds[0],
+			Password: creds[1],
+		}, nil
+	}
+
+	return nil, nil
+}
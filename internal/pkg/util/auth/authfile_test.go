@@ -0,0 +1,73 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package auth
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+const testAuthFile = `{
+	"auths": {
+		"https://index.docker.io/v1/": {"auth": "aHViOmh1YnBhc3M="},
+		"quay.io": {"auth": "cXVheTpxdWF5OnBhc3M="},
+		"bad.example.com": {"auth": "bm9jb2xvbg=="}
+	}
+}`
+
+func TestReadDockerAuth(t *testing.T) {
+	f, err := ioutil.TempFile("", "auth-")
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(testAuthFile); err != nil {
+		t.Fatalf("failed to write auth file: %s", err)
+	}
+	f.Close()
+
+	tests := []struct {
+		name     string
+		registry string
+		username string
+		password string
+		wantErr  bool
+	}{
+		{"DockerHub", "docker.io", "hub", "hubpass", false},
+		{"DockerHubAlias", "index.docker.io", "hub", "hubpass", false},
+		{"PasswordWithColon", "quay.io", "quay", "quay:pass", false},
+		{"NoCredentials", "ghcr.io", "", "", false},
+		{"InvalidCredentials", "bad.example.com", "", "", true},
+	}
+
+	for _, tt := range tests {
+		auth, err := ReadDockerAuth(f.Name(), tt.registry)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("%s: unexpected success", tt.name)
+			}
+			continue
+		} else if err != nil {
+			t.Errorf("%s: unexpected error: %s", tt.name, err)
+			continue
+		}
+		if tt.username == "" {
+			if auth != nil {
+				t.Errorf("%s: unexpected credentials found", tt.name)
+			}
+			continue
+		}
+		if auth == nil || auth.Username != tt.username || auth.Password != tt.password {
+			t.Errorf("%s: unexpected credentials returned", tt.name)
+		}
+	}
+
+	if _, err := ReadDockerAuth("/no/such/file", "docker.io"); err == nil {
+		t.Errorf("unexpected success with a missing auth file")
+	}
+}
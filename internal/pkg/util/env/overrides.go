@@ -0,0 +1,59 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package env
+
+import (
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// ApplyOverrides returns the host environment hostEnv where the variables
+// of an --env-file, fileEnv, and of --env options, cliEnv, are set as
+// SINGULARITYENV_ prefixed variables. The container environment is then
+// built with the following precedence, from lowest to highest:
+//
+//   - host environment variables
+//   - variables set by the image %environment
+//   - SINGULARITYENV_ prefixed host environment variables
+//   - variables of the --env-file
+//   - variables of --env options, the last one for a repeated key
+//
+// Keys given with the SINGULARITYENV_ prefix are accepted as well, variables
+// without '=' are ignored with a warning.
+func ApplyOverrides(hostEnv, fileEnv, cliEnv []string) []string {
+	environ := make([]string, len(hostEnv))
+	copy(environ, hostEnv)
+
+	index := make(map[string]int)
+	for i, env := range environ {
+		if e := strings.SplitN(env, "=", 2); len(e) == 2 {
+			index[e[0]] = i
+		}
+	}
+
+	apply := func(envs []string, source string) {
+		for _, env := range envs {
+			e := strings.SplitN(env, "=", 2)
+			if len(e) != 2 {
+				sylog.Warningf("Ignore environment variable %q: '=' is missing", env)
+				continue
+			}
+			key := SingularityEnvPrefix + strings.TrimPrefix(e[0], SingularityEnvPrefix)
+			if i, ok := index[key]; ok {
+				sylog.Verbosef("Overriding %s environment variable with %s", key, source)
+				environ[i] = key + "=" + e[1]
+				continue
+			}
+			index[key] = len(environ)
+			environ = append(environ, key+"="+e[1])
+		}
+	}
+	apply(fileEnv, "--env-file")
+	apply(cliEnv, "--env")
+
+	return environ
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package env
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestApplyOverrides(t *testing.T) {
+	tests := []struct {
+		name    string
+		hostEnv []string
+		fileEnv []string
+		cliEnv  []string
+		want    []string
+	}{
+		{
+			name:    "NoOverrides",
+			hostEnv: []string{"FOO=host", "SINGULARITYENV_BAR=host"},
+			want:    []string{"FOO=host", "SINGULARITYENV_BAR=host"},
+		},
+		{
+			name:    "FileOverridesHost",
+			hostEnv: []string{"FOO=host", "SINGULARITYENV_FOO=host"},
+			fileEnv: []string{"FOO=file", "BAR=file"},
+			want:    []string{"FOO=host", "SINGULARITYENV_FOO=file", "SINGULARITYENV_BAR=file"},
+		},
+		{
+			name:    "CliOverridesFile",
+			hostEnv: []string{"SINGULARITYENV_FOO=host"},
+			fileEnv: []string{"FOO=file", "BAR=file"},
+			cliEnv:  []string{"FOO=cli"},
+			want:    []string{"SINGULARITYENV_FOO=cli", "SINGULARITYENV_BAR=file"},
+		},
+		{
+			name:   "LastCliWins",
+			cliEnv: []string{"FOO=one", "FOO=two"},
+			want:   []string{"SINGULARITYENV_FOO=two"},
+		},
+		{
+			name:   "Prefixed",
+			cliEnv: []string{"SINGULARITYENV_FOO=cli", "FOO=again"},
+			want:   []string{"SINGULARITYENV_FOO=again"},
+		},
+		{
+			name:   "Invalid",
+			cliEnv: []string{"FOO", "BAR=a=b"},
+			want:   []string{"SINGULARITYENV_BAR=a=b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hostEnv := append([]string(nil), tt.hostEnv...)
+
+			got := ApplyOverrides(tt.hostEnv, tt.fileEnv, tt.cliEnv)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+			if !reflect.DeepEqual(hostEnv, tt.hostEnv) {
+				t.Errorf("host environment modified: %q", tt.hostEnv)
+			}
+		})
+	}
+}
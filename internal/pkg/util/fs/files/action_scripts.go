@@ -5,6 +5,11 @@
 
 package files
 
+// CustomActionMarker is the second line of the action scripts of
+// /.singularity.d/actions set by a %actions section, run by the runtime
+// in place of the action command.
+const CustomActionMarker = "# singularity custom action script"
+
 var ActionScript = `#!/bin/sh
 
 declare -r __exported_env__=$(getallenv)
@@ -58,6 +63,16 @@ restore_env() {
     # variables are also unset
     for e in ${__exported_env__}; do
         key=$(getenvkey "${e}")
+        case "${key}" in
+        SCIF_APP*)
+            # with --app, SCIF app variables only come from the
+            # container for the selected app, host values set for
+            # another app must not leak into its environment
+            if test -n "${SINGULARITY_APPNAME:-}"; then
+                continue
+            fi
+            ;;
+        esac
         if ! test -v "${key}"; then
             export "$(unescape ${e})"
         elif test -z "${!key}"; then
@@ -182,7 +197,13 @@ test)
     sylog info "No test script found in container, exiting"
     exit 0 ;;
 start)
-    if test -x "/.singularity.d/startscript"; then
+    if test -n "${SINGULARITY_APPNAME:-}"; then
+        if test -x "/scif/apps/${SINGULARITY_APPNAME:-}/scif/startscript"; then
+            exec "/scif/apps/${SINGULARITY_APPNAME:-}/scif/startscript" "$@"
+        fi
+        sylog error "no startscript for contained app: ${SINGULARITY_APPNAME:-}"
+        exit 1
+    elif test -x "/.singularity.d/startscript"; then
         exec "/.singularity.d/startscript" "$@"
     fi
 
@@ -345,8 +345,8 @@ func CopyFile(from, to string, mode os.FileMode) (err error) {
 	return nil
 }
 
-// CopyFileAtomic copies file to a temporary file in the same destination directory
-// and the renames to the final name. This is useful to avoid races where concurrent copies
+// CopyFileAtomic copies file to a temporary file in the same destination directory,
+// syncs it and then renames it to the final name. This is useful to avoid races where concurrent copies
 // could happen to the same destination. It makes sure the resulting
 // file has permission bits set to the mode prior to umask. To honor umask
 // correctly the resulting file must not exist.
@@ -381,6 +381,9 @@ func CopyFileAtomic(from, to string, mode os.FileMode) (err error) {
 		return fmt.Errorf("could not copy file: %v", err)
 	}
 	srcFile.Close()
+	if err := tmpFile.Sync(); err != nil {
+		return fmt.Errorf("could not sync file: %v", err)
+	}
 	tmpFile.Close()
 
 	err = os.Rename(tmpFile.Name(), to)
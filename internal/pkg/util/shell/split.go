@@ -0,0 +1,77 @@
+// Copyright (c) 2020, Sylabs, Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license.  Please
+// consult LICENSE.md file distributed with the sources of this project regarding
+// your rights to use or distribute this software.
+
+package shell
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Split splits s into a list of arguments following the shell quoting
+// rules for single quotes, double quotes and backslashes. No expansion
+// of any kind is performed, the resulting arguments are meant to be
+// passed as is to a command executed without a shell.
+func Split(s string) ([]string, error) {
+	var (
+		args    []string
+		arg     strings.Builder
+		inArg   bool
+		escaped bool
+		quote   rune
+	)
+
+	for _, r := range s {
+		switch {
+		case escaped:
+			// within double quotes a backslash only escapes
+			// characters having a special meaning
+			if quote == '"' && !strings.ContainsRune("\"\\$`", r) {
+				arg.WriteRune('\\')
+			}
+			arg.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\\':
+			escaped = true
+			inArg = true
+		case quote == '"':
+			if r == '"' {
+				quote = 0
+			} else {
+				arg.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteRune(r)
+			inArg = true
+		}
+	}
+
+	if escaped {
+		return nil, fmt.Errorf("trailing backslash in %q", s)
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote in %q", quote, s)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+
+	return args, nil
+}
@@ -0,0 +1,50 @@
+// Copyright (c) 2020, Sylabs, Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license.  Please
+// consult LICENSE.md file distributed with the sources of this project regarding
+// your rights to use or distribute this software.
+
+package shell
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	var splitTests = []struct {
+		name     string
+		input    string
+		expected []string
+		wantErr  bool
+	}{
+		{"Empty", ``, nil, false},
+		{"Spaces", `  `, nil, false},
+		{"Single arg", `--variant=buildd`, []string{`--variant=buildd`}, false},
+		{"Two args", ` --setopt=a=1   --nogpgcheck `, []string{`--setopt=a=1`, `--nogpgcheck`}, false},
+		{"Single quotes", `'a b' 'c"d'`, []string{`a b`, `c"d`}, false},
+		{"Double quotes", `"a 'b'" "\$x\y"`, []string{`a 'b'`, `$x\y`}, false},
+		{"Backslash", `a\ b \'c`, []string{`a b`, `'c`}, false},
+		{"Empty quotes", `'' ""`, []string{``, ``}, false},
+		{"No expansion", `$(id) ; rm -rf /`, []string{`$(id)`, `;`, `rm`, `-rf`, `/`}, false},
+		{"Unterminated quote", `"abc`, nil, true},
+		{"Trailing backslash", `abc\`, nil, true},
+	}
+
+	for _, test := range splitTests {
+		t.Run(test.name, func(t *testing.T) {
+			args, err := Split(test.input)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("unexpected success for %s", test.input)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(args, test.expected) {
+				t.Errorf("got %q, expected %q", args, test.expected)
+			}
+		})
+	}
+}
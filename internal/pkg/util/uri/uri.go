@@ -35,6 +35,7 @@ var validURIs = map[string]bool{
 	"http":           true,
 	"https":          true,
 	"oras":           true,
+	"tar":            true,
 }
 
 // IsValid returns whether or not the given source is valid
@@ -75,6 +76,13 @@ func GetName(uri string) string {
 	tags := []string{"latest"}
 	container := refSplit[len(refSplit)-1]
 
+	// image pinned by digest, the digest replaces the tag
+	if i := strings.Index(container, "@"); i >= 0 {
+		digest := strings.Replace(container[i+1:], ":", ".", 1)
+		container = strings.SplitN(container[:i], ":", 2)[0]
+		return fmt.Sprintf("%s_%s.sif", container, digest)
+	}
+
 	if strings.Contains(container, ":") {
 		imageParts := strings.Split(container, ":")
 		container = imageParts[0]
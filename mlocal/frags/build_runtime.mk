@@ -93,6 +93,17 @@ $(seccomp_profile_INSTALL): $(seccomp_profile)
 
 INSTALLFILES += $(seccomp_profile_INSTALL)
 
+# seccomp build profile example
+seccomp_build_profile := $(SOURCEDIR)/etc/seccomp-profiles/build.json
+
+seccomp_build_profile_INSTALL := $(DESTDIR)$(SYSCONFDIR)/singularity/seccomp-profiles/build.json
+$(seccomp_build_profile_INSTALL): $(seccomp_build_profile)
+	@echo " INSTALL" $@
+	$(V)umask 0022 && mkdir -p $(@D)
+	$(V)install -m 0644 $< $@
+
+INSTALLFILES += $(seccomp_build_profile_INSTALL)
+
 
 # nvidia liblist config file
 nvidia_liblist := $(SOURCEDIR)/etc/nvliblist.conf
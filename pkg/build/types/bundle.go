@@ -6,15 +6,18 @@
 package types
 
 import (
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	ocitypes "github.com/containers/image/v5/types"
 	"github.com/sylabs/singularity/internal/pkg/cache"
 	"github.com/sylabs/singularity/internal/pkg/util/fs"
+	"github.com/sylabs/singularity/pkg/image"
 	"github.com/sylabs/singularity/pkg/sylog"
 	"github.com/sylabs/singularity/pkg/util/crypt"
 	"golang.org/x/sys/unix"
@@ -22,6 +25,33 @@ import (
 
 const OCIConfigJSON = "oci-config"
 
+// DefaultBindsJSON is the name of JSON object holding the default bind paths
+const DefaultBindsJSON = "default-binds"
+
+// LayersJSON is the name of the JSON object listing the source image
+// layers kept by a build, bottom layer first.
+const LayersJSON = "layers"
+
+// AnnotationsJSON is the name of the JSON object holding the image
+// annotations, as an object of JSON values.
+const AnnotationsJSON = "annotations"
+
+// BuildArgsJSON is the name of the JSON object holding the build
+// arguments referenced by the definition file, as an object of the
+// values they were resolved to.
+const BuildArgsJSON = "build-args"
+
+// LayerEntry describes a kept source image layer in the layers index.
+type LayerEntry struct {
+	// Digest is the digest of the layer blob.
+	Digest string `json:"digest"`
+	// Partition is the name of the SIF overlay partition holding the
+	// layer, the bottom layer is held by the primary system partition.
+	Partition string `json:"partition,omitempty"`
+	// Primary is true for the layer held by the primary system partition.
+	Primary bool `json:"primary,omitempty"`
+}
+
 // Bundle is the temporary environment used during the image building process.
 type Bundle struct {
 	JSONObjects map[string][]byte `json:"jsonObjects"`
@@ -30,6 +60,29 @@ type Bundle struct {
 
 	RootfsPath string `json:"rootfsPath"` // where actual fs to chroot will appear
 	TmpDir     string `json:"tmpPath"`    // where temp files required during build will appear
+
+	// Layers are the layers of OCI/Docker source images extracted
+	// separately for builds keeping them, bottom layer first.
+	Layers []Layer `json:"layers"`
+
+	// VerifiedBase is the digest of the bootstrap image whose
+	// signature was verified with --verify-base.
+	VerifiedBase string `json:"verifiedBase"`
+}
+
+// Layer is a layer of an OCI/Docker source image extracted in its own
+// directory.
+type Layer struct {
+	// Digest is the digest of the layer blob.
+	Digest string `json:"digest"`
+	// Dir is the directory holding the layer content.
+	Dir string `json:"dir"`
+	// Whiteouts are the paths, relative to Dir, removed from the
+	// lower layers.
+	Whiteouts []string `json:"whiteouts"`
+	// Opaques are the directories, relative to Dir, whose lower
+	// layers content is hidden.
+	Opaques []string `json:"opaques"`
 }
 
 // Options defines build time behavior to be executed on the bundle.
@@ -44,6 +97,10 @@ type Options struct {
 	LibraryAuthToken string `json:"libraryAuthToken"`
 	// contains docker credentials if specified.
 	DockerAuthConfig *ocitypes.DockerAuthConfig
+	// AuthFile is the path of a podman/skopeo compatible auth.json
+	// file holding registry credentials, used when DockerAuthConfig
+	// is not set.
+	AuthFile string `json:"authFile"`
 	// EncryptionKeyInfo specifies the key used for filesystem
 	// encryption if applicable.
 	// A nil value indicates encryption should not occur.
@@ -58,6 +115,10 @@ type Options struct {
 	Update bool `json:"update"`
 	// NoHTTPS instructs builder not to use secure connection.
 	NoHTTPS bool `json:"noHTTPS"`
+	// LibraryNoHTTPS and ShubNoHTTPS disable secure connections for
+	// the library and shub bootstrap sources only.
+	LibraryNoHTTPS bool `json:"libraryNoHTTPS"`
+	ShubNoHTTPS    bool `json:"shubNoHTTPS"`
 	// NoCleanUp allows a user to prevent a bundle from being cleaned up after a failed build.
 	// useful for debugging.
 	NoCleanUp bool `json:"noCleanUp"`
@@ -70,6 +131,186 @@ type Options struct {
 	// To warn when the above is needed, we need to know if the target of this
 	// bundle will be a sandbox
 	SandboxTarget bool
+	// Compression is the squashfs compression algorithm used for the
+	// root filesystem partition of SIF images (gzip, lzo, xz, zstd, none).
+	Compression string `json:"compression"`
+	// CompressionLevel is the squashfs compression level, 0 lets
+	// mksquashfs pick its default level for the selected algorithm.
+	CompressionLevel int `json:"compressionLevel"`
+	// BuildVars holds the build arguments substituted to the
+	// {{ NAME }} references found in the definition.
+	BuildVars map[string]string `json:"buildVars"`
+	// HelpFile is the path of a file whose content is used as
+	// container help in place of the %help section.
+	HelpFile string `json:"helpFile"`
+	// ExcludePaths are glob patterns of container paths removed from
+	// the final stage root filesystem before packaging.
+	ExcludePaths []string `json:"excludePaths"`
+	// FileCaps are the file capabilities, as PATH=CAPS[+FLAGS], set on
+	// the final stage root filesystem before packaging.
+	FileCaps []string `json:"fileCaps"`
+	// RootOwner stores all the files of OCI images and tar archives
+	// as owned by root, in place of their root filesystem ownership.
+	RootOwner bool `json:"rootOwner"`
+	// FilesJobs is the maximum number of %files entries copied
+	// concurrently, entries with overlapping destinations are
+	// always copied in order.
+	FilesJobs int `json:"filesJobs"`
+	// VerifyBase verifies the signature of the bootstrap image with
+	// the public keys of BaseKeyring before using it.
+	VerifyBase bool `json:"verifyBase"`
+	// BaseKeyring is the path of the public keyring file used to
+	// verify the bootstrap image signature.
+	BaseKeyring string `json:"baseKeyring"`
+	// NoRunscriptCheck disables the check of the built image runscript.
+	NoRunscriptCheck bool `json:"noRunscriptCheck"`
+	// NoRunscriptWrap runs the last command of shell runscripts and
+	// startscripts with exec, replacing the shell process.
+	NoRunscriptWrap bool `json:"noRunscriptWrap"`
+	// BuildContext is the directory from which relative %files
+	// sources are resolved, the current directory if empty.
+	BuildContext string `json:"buildContext"`
+	// DefinitionDir is the directory of the definition file, empty
+	// when the definition is not read from a file.
+	DefinitionDir string `json:"definitionDir"`
+	// TraceScripts enables tracing of the commands executed by
+	// the %setup, %post and %test sections.
+	TraceScripts bool `json:"traceScripts"`
+	// ShellFlags are the shell flags applied to the %pre, %setup,
+	// %post and %test sections, they are ignored for sections using
+	// a non shell interpreter with -c.
+	ShellFlags []string `json:"shellFlags"`
+	// Arch is the architecture of the built image, the host
+	// architecture if empty.
+	Arch string `json:"arch"`
+	// ArchVariant is the architecture variant selected from
+	// multi-platform OCI/Docker images, as v7 for arm.
+	ArchVariant string `json:"archVariant"`
+	// BaseDigest pins the docker or library base image to this
+	// digest, as sha256:<hex>, replacing the tag of its reference.
+	BaseDigest string `json:"baseDigest"`
+	// NoNetworkPost and NoNetworkTest run the %post and %test sections
+	// in a network namespace with only a loopback interface.
+	NoNetworkPost bool `json:"noNetworkPost"`
+	NoNetworkTest bool `json:"noNetworkTest"`
+	// NoMountProc and NoMountSys disable the /proc and /sys mounts
+	// of the %post and %test sections.
+	NoMountProc bool `json:"noMountProc"`
+	NoMountSys  bool `json:"noMountSys"`
+	// MountDev is the /dev mount of the %post and %test sections,
+	// yes for the host /dev, minimal or no, yes if empty.
+	MountDev string `json:"mountDev"`
+	// MountDevPts mounts a devpts instance in the minimal /dev of
+	// the %post and %test sections.
+	MountDevPts bool `json:"mountDevPts"`
+	// DefaultBinds are the bind paths recorded in SIF images and
+	// applied by default when running the container.
+	DefaultBinds []image.DefaultBind `json:"defaultBinds"`
+	// OCIEntrypoint overrides the ENTRYPOINT of OCI/Docker source
+	// images when not nil, an empty list clears it.
+	OCIEntrypoint []string `json:"ociEntrypoint"`
+	// OCICmd overrides the CMD of OCI/Docker source images when
+	// not nil, an empty list clears it.
+	OCICmd []string `json:"ociCmd"`
+	// DropDockerEnv discards the environment variables defined
+	// by OCI/Docker source images.
+	DropDockerEnv bool `json:"dropDockerEnv"`
+	// Layered stores the changes made on top of the bootstrapped
+	// root filesystem in a SIF overlay partition, leaving the base
+	// root filesystem partition untouched.
+	Layered bool `json:"layered"`
+	// SquashLayers flattens the layers of layered builds in a
+	// single root filesystem partition.
+	SquashLayers bool `json:"squashLayers"`
+	// KeepLayers stores each layer of OCI/Docker source images in its
+	// own SIF partition, stacked below the layered build overlay.
+	KeepLayers bool `json:"keepLayers"`
+	// Labels are added to the image labels, overriding the labels of
+	// the source image and of the %labels section.
+	Labels map[string]string `json:"labels"`
+	// LabelPrefix is prepended to the names of the labels of the
+	// %labels section and of Labels, built-in labels are left as is.
+	LabelPrefix string `json:"labelPrefix"`
+	// Annotations are the image annotations, stored apart from the
+	// labels, whose values may be any JSON value.
+	Annotations map[string]json.RawMessage `json:"annotations"`
+	// AllowLabelExec allows label values computed by a command
+	// executed on the host.
+	AllowLabelExec bool `json:"allowLabelExec"`
+	// MaxDownloadSize is the maximum size in bytes of a bootstrap
+	// source download, zero means no limit.
+	MaxDownloadSize int64 `json:"maxDownloadSize"`
+	// DownloadTimeout is the maximum duration of a bootstrap source
+	// download request, zero means no timeout.
+	DownloadTimeout time.Duration `json:"downloadTimeout"`
+	// MaxImageSize is the size in bytes above which the built image
+	// fails the build, zero means no limit.
+	MaxImageSize int64 `json:"maxImageSize"`
+	// WarnImageSize is the size in bytes above which the built image
+	// raises a warning, zero means no limit.
+	WarnImageSize int64 `json:"warnImageSize"`
+	// WarnAsError promotes build warnings to errors.
+	WarnAsError bool `json:"warnAsError"`
+	// AllowedWarnings are the build warnings never promoted
+	// to errors with WarnAsError.
+	AllowedWarnings []WarningID `json:"allowedWarnings"`
+	// FsType is the file system of the primary system partition of SIF
+	// images, squashfs or ext3, squashfs if empty.
+	FsType string `json:"fsType"`
+	// FsSize is the size in bytes of an ext3 primary system partition,
+	// computed from the root filesystem size when zero.
+	FsSize int64 `json:"fsSize"`
+	// PartitionName is the name of the primary system partition of SIF
+	// images.
+	PartitionName string `json:"partitionName"`
+	// FromCacheOnly makes bootstrap sources use images already in the
+	// cache without any network access, the build fails if an image
+	// was not pulled before.
+	FromCacheOnly bool `json:"fromCacheOnly"`
+	// DNS are the name servers of the resolv.conf file bound in the
+	// root filesystem for %post and %test, in place of the host one.
+	DNS []string `json:"dns"`
+	// RetryPost is the number of times the %post section is run again
+	// when it exits with a non zero status, for idempotent scripts
+	// failing on transient network errors.
+	RetryPost int `json:"retryPost"`
+	// InteractivePost starts an interactive shell in the root
+	// filesystem when the %post section fails, the build resuming
+	// if the shell exits with a zero status.
+	InteractivePost bool `json:"interactivePost"`
+	// StrictPackages makes the yum and zypper bootstrap agents fail
+	// when their tool reports skipped packages or repositories, even
+	// if it exits with a zero status.
+	StrictPackages bool `json:"strictPackages"`
+	// VerifyIdempotent runs the %post section, or each %post:NAME
+	// fragment, a second time on the resulting root filesystem and
+	// fails the build if this second run changed any file.
+	VerifyIdempotent bool `json:"verifyIdempotent"`
+	// ScanSecrets scans the final stage root filesystem and the image
+	// labels and environment for secrets, like private keys and access
+	// tokens, each possible secret raising a build warning.
+	ScanSecrets bool `json:"scanSecrets"`
+	// ScanSecretsStrict fails the build when ScanSecrets finds secrets.
+	ScanSecretsStrict bool `json:"scanSecretsStrict"`
+	// ScanSecretsIgnore are glob patterns of container paths, and of
+	// their content, not scanned for secrets.
+	ScanSecretsIgnore []string `json:"scanSecretsIgnore"`
+	// SeccompProfile is the path of the OCI seccomp profile applied to
+	// the %pre, %setup, %post and %test sections, when set.
+	SeccompProfile string `json:"seccompProfile"`
+	// SourceDateEpoch is the time recorded in the image metadata and
+	// timestamps in place of the current time, when not zero, as set by
+	// the SOURCE_DATE_EPOCH environment variable.
+	SourceDateEpoch time.Time `json:"sourceDateEpoch"`
+}
+
+// BuildTime returns the time recorded in the image metadata, the
+// SourceDateEpoch time in UTC when set or the current time.
+func (o Options) BuildTime() time.Time {
+	if !o.SourceDateEpoch.IsZero() {
+		return o.SourceDateEpoch.UTC()
+	}
+	return time.Now()
 }
 
 // NewEncryptedBundle creates an Encrypted Bundle environment.
@@ -0,0 +1,86 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package types
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// Conveyor is responsible for downloading from remote sources (library, shub, docker...).
+type Conveyor interface {
+	Get(context.Context, *Bundle) error
+}
+
+// Packer is the type which is responsible for installing the chroot directory,
+// metadata directory, and potentially other files/directories within the Bundle.
+type Packer interface {
+	Pack(context.Context) (*Bundle, error)
+}
+
+// ConveyorPacker describes an interface that a ConveyorPacker type must implement.
+type ConveyorPacker interface {
+	Conveyor
+	Packer
+}
+
+// ConveyorPackerFunc returns a new ConveyorPacker instance, a new
+// instance is requested for each build stage.
+type ConveyorPackerFunc func() ConveyorPacker
+
+var conveyorPackers = struct {
+	sync.Mutex
+	agents map[string]ConveyorPackerFunc
+}{
+	agents: make(map[string]ConveyorPackerFunc),
+}
+
+// RegisterConveyorPacker registers a ConveyorPacker for the bootstrap
+// agent name, as found in the definition file `Bootstrap:` header
+// keyword. This is used to register the builtin bootstrap agents and
+// could be called by plugins to provide custom bootstrap agents.
+func RegisterConveyorPacker(agent string, fn ConveyorPackerFunc) error {
+	conveyorPackers.Lock()
+	defer conveyorPackers.Unlock()
+
+	if agent == "" {
+		return fmt.Errorf("empty bootstrap agent name")
+	} else if _, ok := conveyorPackers.agents[agent]; ok {
+		return fmt.Errorf("bootstrap agent %s is already registered", agent)
+	} else if fn == nil {
+		return fmt.Errorf("nil conveyorpacker function for bootstrap agent %s", agent)
+	}
+	conveyorPackers.agents[agent] = fn
+	return nil
+}
+
+// GetConveyorPacker returns a new ConveyorPacker instance for the
+// bootstrap agent name or nil if there is no such agent registered.
+func GetConveyorPacker(agent string) ConveyorPacker {
+	conveyorPackers.Lock()
+	defer conveyorPackers.Unlock()
+
+	fn, ok := conveyorPackers.agents[agent]
+	if !ok {
+		return nil
+	}
+	return fn()
+}
+
+// ConveyorPackers returns the sorted list of registered bootstrap agents.
+func ConveyorPackers() []string {
+	conveyorPackers.Lock()
+	defer conveyorPackers.Unlock()
+
+	agents := make([]string, 0, len(conveyorPackers.agents))
+	for agent := range conveyorPackers.agents {
+		agents = append(agents, agent)
+	}
+	sort.Strings(agents)
+	return agents
+}
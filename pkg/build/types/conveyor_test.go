@@ -0,0 +1,85 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package types
+
+import (
+	"context"
+	"testing"
+)
+
+type testConveyorPacker struct{}
+
+func (cp *testConveyorPacker) Get(context.Context, *Bundle) error {
+	return nil
+}
+
+func (cp *testConveyorPacker) Pack(context.Context) (*Bundle, error) {
+	return nil, nil
+}
+
+func TestRegisterConveyorPacker(t *testing.T) {
+	fn := func() ConveyorPacker { return &testConveyorPacker{} }
+
+	tt := []struct {
+		name        string
+		agent       string
+		fn          ConveyorPackerFunc
+		expectError bool
+	}{
+		{
+			name:        "empty agent",
+			agent:       "",
+			fn:          fn,
+			expectError: true,
+		},
+		{
+			name:        "nil function",
+			agent:       "test-nil",
+			fn:          nil,
+			expectError: true,
+		},
+		{
+			name:        "valid agent",
+			agent:       "test",
+			fn:          fn,
+			expectError: false,
+		},
+		{
+			name:        "already registered",
+			agent:       "test",
+			fn:          fn,
+			expectError: true,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := RegisterConveyorPacker(tc.agent, tc.fn)
+			if tc.expectError && err == nil {
+				t.Fatalf("unexpected success")
+			} else if !tc.expectError && err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+		})
+	}
+
+	if GetConveyorPacker("test") == nil {
+		t.Fatalf("no conveyorpacker returned for registered agent")
+	}
+	if GetConveyorPacker("test-nil") != nil {
+		t.Fatalf("unexpected conveyorpacker returned for unregistered agent")
+	}
+
+	found := false
+	for _, agent := range ConveyorPackers() {
+		if agent == "test" {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("registered agent not listed")
+	}
+}
@@ -41,13 +41,23 @@ type ImageScripts struct {
 	Runscript   Script `json:"runScript"`
 	Test        Script `json:"test"`
 	Startscript Script `json:"startScript"`
+	// Actions are the %actions NAME sections replacing the
+	// generated action scripts.
+	Actions []Action `json:"actions,omitempty"`
+}
+
+// Action describes a %actions NAME section of a definition.
+type Action struct {
+	Name   string `json:"name"`
+	Script `json:"script"`
 }
 
 // Data contains any scripts, metadata, etc... that the Builder may
 // need to know only at build time to build the image.
 type Data struct {
-	Files   []Files `json:"files"`
-	Scripts `json:"buildScripts"`
+	Files     []Files    `json:"files"`
+	DataFiles []DataFile `json:"dataFiles,omitempty"`
+	Scripts   `json:"buildScripts"`
 }
 
 // Scripts defines scripts that are used at build time.
@@ -56,6 +66,15 @@ type Scripts struct {
 	Setup Script `json:"setup"`
 	Post  Script `json:"post"`
 	Test  Script `json:"test"`
+	// PostFragments are the named %post:NAME sections, run in order
+	// in place of %post.
+	PostFragments []PostFragment `json:"postFragments,omitempty"`
+}
+
+// PostFragment describes a named %post:NAME section of a definition.
+type PostFragment struct {
+	Name   string `json:"name"`
+	Script `json:"script"`
 }
 
 // Files describes a %files section of a definition.
@@ -70,6 +89,13 @@ type FileTransport struct {
 	Dst string `json:"destination"`
 }
 
+// DataFile describes a file of a %datafile section, stored as a
+// named SIF data object rather than in the root filesystem.
+type DataFile struct {
+	Name string `json:"name"`
+	Src  string `json:"source"`
+}
+
 // Script describes any script section of a definition.
 type Script struct {
 	Args   string `json:"args"`
@@ -150,6 +176,16 @@ func writeFilesIfExists(w io.Writer, f []Files) {
 	}
 }
 
+func writeDataFilesIfExists(w io.Writer, f []DataFile) {
+	if len(f) > 0 {
+		fmt.Fprintf(w, "%%datafile\n")
+		for _, df := range f {
+			fmt.Fprintf(w, "\t%s\t%s\n", df.Src, df.Name)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
 func writeLabelsIfExists(w io.Writer, l map[string]string) {
 	if len(l) > 0 {
 		fmt.Fprintln(w, "%labels")
@@ -180,13 +216,20 @@ func populateRaw(d *Definition, w io.Writer) {
 
 	writeLabelsIfExists(w, d.ImageData.Labels)
 	writeFilesIfExists(w, d.BuildData.Files)
+	writeDataFilesIfExists(w, d.BuildData.DataFiles)
 
 	writeSectionIfExists(w, "help", d.ImageData.Help)
 	writeSectionIfExists(w, "environment", d.ImageData.Environment)
 	writeSectionIfExists(w, "runscript", d.ImageData.Runscript)
 	writeSectionIfExists(w, "test", d.ImageData.Test)
 	writeSectionIfExists(w, "startscript", d.ImageData.Startscript)
+	for _, a := range d.ImageData.Actions {
+		writeSectionIfExists(w, "actions "+a.Name, a.Script)
+	}
 	writeSectionIfExists(w, "pre", d.BuildData.Pre)
 	writeSectionIfExists(w, "setup", d.BuildData.Setup)
 	writeSectionIfExists(w, "post", d.BuildData.Post)
+	for _, f := range d.BuildData.PostFragments {
+		writeSectionIfExists(w, "post:"+f.Name, f.Script)
+	}
 }
@@ -0,0 +1,126 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+// ArchCondition is the name of the build architecture in section
+// conditions.
+const ArchCondition = "arch"
+
+// conditionRegexp matches the (CONDITION, ...) suffix of a section line.
+var conditionRegexp = regexp.MustCompile(`\s*\(([^()]*)\)\s*$`)
+
+// isSectionHeader returns if word, the first word of a definition line,
+// is a known section name, other lines starting with % being section
+// content, as the %define lines of an RPM spec written by %post.
+func isSectionHeader(word string) bool {
+	if !strings.HasPrefix(word, "%") {
+		return false
+	}
+	name := strings.ToLower(word[1:])
+	if i := strings.Index(name, "("); i >= 0 {
+		name = name[:i]
+	}
+	return validSections[name] || appSections[name] || name == actionsSection || strings.HasPrefix(name, postFragmentPrefix)
+}
+
+// ResolveConditions returns the definition data with the conditional
+// sections resolved for the build architecture arch, the host one if
+// empty, and the build variables vars. A section line ending with a list
+// of conditions, as `%post (arch=arm64)`, is kept without its conditions
+// when they all hold and removed along with its content otherwise.
+// Conditions are NAME=VALUE or NAME!=VALUE, NAME being arch or the name
+// of a build argument, which must be defined.
+func ResolveConditions(data []byte, arch string, vars map[string]string) ([]byte, error) {
+	if arch == "" {
+		arch = runtime.GOARCH
+	}
+
+	var buf bytes.Buffer
+
+	// skip is set while the lines of an excluded section are read
+	skip := false
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, len(data)+1)
+	for s.Scan() {
+		line := s.Text()
+		fields := strings.Fields(line)
+
+		if len(fields) > 0 && isSectionHeader(fields[0]) {
+			skip = false
+			if m := conditionRegexp.FindStringSubmatchIndex(line); m != nil {
+				ok, err := evalConditions(line[m[2]:m[3]], arch, vars)
+				if err != nil {
+					return nil, fmt.Errorf("%q: %v", line, err)
+				}
+				if !ok {
+					skip = true
+					continue
+				}
+				line = line[:m[0]]
+			}
+		} else if len(fields) > 0 && bootstrapRegexp.MatchString(fields[0]) {
+			// a new stage header ends an excluded section
+			skip = false
+		}
+
+		if !skip {
+			buf.WriteString(line + "\n")
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// evalConditions returns if all the comma separated conditions hold.
+func evalConditions(conds, arch string, vars map[string]string) (bool, error) {
+	result := true
+
+	for _, c := range strings.Split(conds, ",") {
+		c = strings.TrimSpace(c)
+
+		negate := false
+		kv := strings.SplitN(c, "!=", 2)
+		if len(kv) == 2 {
+			negate = true
+		} else {
+			kv = strings.SplitN(c, "=", 2)
+		}
+		if len(kv) != 2 {
+			return false, fmt.Errorf("bad condition %q: must be NAME=VALUE or NAME!=VALUE", c)
+		}
+
+		name, value := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if !varRegexp.MatchString("{{" + name + "}}") {
+			return false, fmt.Errorf("bad condition name %q", name)
+		}
+
+		v := arch
+		if name != ArchCondition {
+			var ok bool
+			if v, ok = vars[name]; !ok {
+				return false, fmt.Errorf("condition on undefined build argument %s", name)
+			}
+		}
+		if (v == value) == negate {
+			result = false
+		}
+	}
+
+	return result, nil
+}
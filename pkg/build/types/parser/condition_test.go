@@ -0,0 +1,112 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"testing"
+)
+
+func TestResolveConditions(t *testing.T) {
+	vars := map[string]string{
+		"FLAVOR": "slim",
+	}
+
+	def := "Bootstrap: docker\nFrom: alpine\n" +
+		"%post\n    echo all\n" +
+		"%post (arch=arm64)\n    echo arm64\n" +
+		"%post (arch!=arm64, FLAVOR=slim)\n    echo other slim\n" +
+		"%environment\n    export A=1\n"
+
+	tests := []struct {
+		name    string
+		arch    string
+		def     string
+		result  string
+		wantErr bool
+	}{
+		{
+			name: "Arm64",
+			arch: "arm64",
+			def:  def,
+			result: "Bootstrap: docker\nFrom: alpine\n" +
+				"%post\n    echo all\n" +
+				"%post\n    echo arm64\n" +
+				"%environment\n    export A=1\n",
+		},
+		{
+			name: "Amd64",
+			arch: "amd64",
+			def:  def,
+			result: "Bootstrap: docker\nFrom: alpine\n" +
+				"%post\n    echo all\n" +
+				"%post\n    echo other slim\n" +
+				"%environment\n    export A=1\n",
+		},
+		{
+			name: "ExcludedBeforeStage",
+			arch: "amd64",
+			def: "Bootstrap: docker\nFrom: alpine\nStage: one\n%post (arch=arm64)\n    echo arm64\n" +
+				"Bootstrap: docker\nFrom: alpine\n%post\n    echo two\n",
+			result: "Bootstrap: docker\nFrom: alpine\nStage: one\n" +
+				"Bootstrap: docker\nFrom: alpine\n%post\n    echo two\n",
+		},
+		{
+			name:   "SectionArgs",
+			arch:   "amd64",
+			def:    "%post -c /bin/bash (arch=amd64)\n    echo bash\n",
+			result: "%post -c /bin/bash\n    echo bash\n",
+		},
+		{
+			name: "ContentInExcludedSection",
+			arch: "amd64",
+			def: "%post (arch=arm64)\n    cat > app.spec <<EOF\n%define version 1.0\n%build\nmake\nEOF\n" +
+				"%environment\n    export A=1\n",
+			result: "%environment\n    export A=1\n",
+		},
+		{
+			name:   "ContentInKeptSection",
+			arch:   "amd64",
+			def:    "%post (arch=amd64)\n    cat > app.spec <<EOF\n%define version 1.0\n%build (make)\nEOF\n",
+			result: "%post\n    cat > app.spec <<EOF\n%define version 1.0\n%build (make)\nEOF\n",
+		},
+		{
+			name:    "UndefinedArg",
+			arch:    "amd64",
+			def:     "%post (VERSION=1)\n    echo 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "BadCondition",
+			arch:    "amd64",
+			def:     "%post (arch)\n    echo 1\n",
+			wantErr: true,
+		},
+		{
+			name:    "BadName",
+			arch:    "amd64",
+			def:     "%post (not a name=1)\n    echo 1\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ResolveConditions([]byte(tt.def), tt.arch, vars)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(res) != tt.result {
+				t.Fatalf("got %q instead of %q", res, tt.result)
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path/filepath"
 	"reflect"
 	"regexp"
 	"strings"
@@ -222,12 +223,82 @@ func parseTokenSection(tok string, sections map[string]*types.Script, files *[]t
 	return nil
 }
 
+// parsePostFragment parses a %post:NAME section token and appends it to
+// fragments, which are kept in the order of the definition file.
+func parsePostFragment(tok string, fragments *[]types.PostFragment) error {
+	split := strings.SplitN(tok, "\n", 2)
+	if len(split) != 2 {
+		return fmt.Errorf("section %v: could not be split into section name and body", split[0])
+	}
+
+	sectionSplit := strings.SplitN(strings.TrimLeft(split[0], "%"), " ", 2)
+	f := types.PostFragment{
+		Name: strings.TrimSpace(sectionSplit[0][len(postFragmentPrefix):]),
+	}
+	if !nameRegexp.MatchString(f.Name) {
+		return fmt.Errorf("invalid %%post fragment name %q", f.Name)
+	}
+	for _, ef := range *fragments {
+		if ef.Name == f.Name {
+			return fmt.Errorf("duplicate %%post:%s section", f.Name)
+		}
+	}
+	if len(sectionSplit) == 2 {
+		f.Args = sectionSplit[1]
+	}
+	f.Script.Script = split[1]
+
+	*fragments = append(*fragments, f)
+	return nil
+}
+
+// parseAction parses a %actions NAME section token and appends it to
+// actions, the arguments following the action name are kept as the
+// section arguments.
+func parseAction(tok string, actions *[]types.Action) error {
+	split := strings.SplitN(tok, "\n", 2)
+	if len(split) != 2 {
+		return fmt.Errorf("section %v: could not be split into section name and body", split[0])
+	}
+
+	sectionSplit := strings.Fields(strings.TrimLeft(split[0], "%"))
+	if len(sectionSplit) < 2 {
+		return fmt.Errorf("%%actions section: missing action name")
+	}
+	a := types.Action{Name: sectionSplit[1]}
+	if !validActions[a.Name] {
+		return fmt.Errorf("invalid %%actions name %q, must be one of exec, run, shell, start or test", a.Name)
+	}
+	for _, ea := range *actions {
+		if ea.Name == a.Name {
+			return fmt.Errorf("duplicate %%actions %s section", a.Name)
+		}
+	}
+	a.Args = strings.Join(sectionSplit[2:], " ")
+	a.Script.Script = split[1]
+
+	*actions = append(*actions, a)
+	return nil
+}
+
 func doSections(s *bufio.Scanner, d *types.Definition) error {
 	sectionsMap := make(map[string]*types.Script)
 	files := []types.Files{}
 	appOrder := []string{}
+	var fragments []types.PostFragment
+	var actions []types.Action
 	tok := strings.TrimSpace(s.Text())
 
+	parseSection := func(tok string) error {
+		if strings.HasPrefix(getSectionName(tok), postFragmentPrefix) {
+			return parsePostFragment(tok, &fragments)
+		}
+		if getSectionName(tok) == actionsSection {
+			return parseAction(tok, &actions)
+		}
+		return parseTokenSection(tok, sectionsMap, &files, &appOrder)
+	}
+
 	// skip initial token parsing if it is empty after trimming whitespace
 	if tok != "" {
 		// check if first thing parsed is a header/comment or just a section
@@ -237,7 +308,7 @@ func doSections(s *bufio.Scanner, d *types.Definition) error {
 			}
 		} else {
 			// this is a section
-			if err := parseTokenSection(tok, sectionsMap, &files, &appOrder); err != nil {
+			if err := parseSection(tok); err != nil {
 				return err
 			}
 		}
@@ -252,7 +323,7 @@ func doSections(s *bufio.Scanner, d *types.Definition) error {
 		tok := s.Text()
 
 		// Parse each token -> section
-		if err := parseTokenSection(tok, sectionsMap, &files, &appOrder); err != nil {
+		if err := parseSection(tok); err != nil {
 			return err
 		}
 	}
@@ -261,6 +332,14 @@ func doSections(s *bufio.Scanner, d *types.Definition) error {
 		return err
 	}
 
+	if len(fragments) > 0 {
+		if post, ok := sectionsMap["post"]; ok && strings.TrimSpace(post.Script) != "" {
+			return fmt.Errorf("%%post and %%post:NAME sections cannot be used together")
+		}
+		d.BuildData.PostFragments = fragments
+	}
+	d.ImageData.Actions = actions
+
 	return populateDefinition(sectionsMap, &files, &appOrder, d)
 }
 
@@ -302,15 +381,23 @@ func populateDefinition(sections map[string]*types.Script, files *[]types.Files,
 			Runscript:   *sections["runscript"],
 			Test:        *sections["test"],
 			Startscript: *sections["startscript"],
+			// %actions NAME sections are parsed apart
+			Actions: d.ImageData.Actions,
 		},
 		Labels: labels,
 	}
 	d.BuildData.Files = *files
+	d.BuildData.DataFiles, err = parseDataFiles(sections["datafile"].Script)
+	if err != nil {
+		return err
+	}
 	d.BuildData.Scripts = types.Scripts{
 		Pre:   *sections["pre"],
 		Setup: *sections["setup"],
 		Post:  *sections["post"],
 		Test:  *sections["test"],
+		// %post:NAME sections are parsed apart to keep their order
+		PostFragments: d.BuildData.PostFragments,
 	}
 
 	// remove standard sections from map
@@ -507,7 +594,12 @@ func IsValidDefinition(source string) (valid bool, err error) {
 		return false, nil
 	}
 
-	_, err = ParseDefinitionFile(defFile)
+	raw, err := ExpandIncludes(source)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = ParseDefinitionFile(bytes.NewReader(raw))
 	if err != nil {
 		return false, err
 	}
@@ -531,10 +623,50 @@ func isEmpty(d types.Definition) bool {
 	return reflect.DeepEqual(d, emptyDef)
 }
 
+// nameRegexp matches valid %datafile and %post fragment names.
+var nameRegexp = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9._-]*$`)
+
+// parseDataFiles parses the lines of a %datafile section, each line holds
+// the path of a file followed by an optional name, the name defaults to the
+// file base name without extension.
+func parseDataFiles(script string) ([]types.DataFile, error) {
+	var dataFiles []types.DataFile
+
+	for _, line := range strings.Split(strings.TrimSpace(script), "\n") {
+		if line = strings.TrimSpace(line); line == "" || strings.Index(line, "#") == 0 {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("invalid %%datafile line %q: expected a path and an optional name", line)
+		}
+
+		df := types.DataFile{Src: fields[0]}
+		if len(fields) == 2 {
+			df.Name = fields[1]
+		} else {
+			base := filepath.Base(df.Src)
+			df.Name = strings.TrimSuffix(base, filepath.Ext(base))
+		}
+		if !nameRegexp.MatchString(df.Name) {
+			return nil, fmt.Errorf("invalid %%datafile name %q for %s", df.Name, df.Src)
+		}
+		for _, e := range dataFiles {
+			if e.Name == df.Name {
+				return nil, fmt.Errorf("duplicate %%datafile name %q", df.Name)
+			}
+		}
+		dataFiles = append(dataFiles, df)
+	}
+
+	return dataFiles, nil
+}
+
 // validSections just contains a list of all the valid sections a definition file
 // could contain. If any others are found, an error will generate
 var validSections = map[string]bool{
 	"help":        true,
+	"datafile":    true,
 	"setup":       true,
 	"files":       true,
 	"labels":      true,
@@ -546,6 +678,22 @@ var validSections = map[string]bool{
 	"startscript": true,
 }
 
+// postFragmentPrefix is the prefix of the named %post:NAME sections.
+const postFragmentPrefix = "post:"
+
+// actionsSection is the name of the %actions NAME sections.
+const actionsSection = "actions"
+
+// validActions lists the action scripts of /.singularity.d/actions
+// which can be replaced by a %actions NAME section.
+var validActions = map[string]bool{
+	"exec":  true,
+	"run":   true,
+	"shell": true,
+	"start": true,
+	"test":  true,
+}
+
 var appSections = map[string]bool{
 	"appinstall": true,
 	"applabels":  true,
@@ -554,6 +702,7 @@ var appSections = map[string]bool{
 	"apptest":    true,
 	"apphelp":    true,
 	"apprun":     true,
+	"appstart":   true,
 }
 
 // validHeaders just contains a list of all the valid headers a definition file
@@ -577,4 +726,9 @@ var validHeaders = map[string]bool{
 	"registerurl": true,
 	"modules":     true,
 	"otherurl&n":  true,
+	// extra options for bootstrap agent tools
+	"debootstrapoptions": true,
+	"pacstrapoptions":    true,
+	"yumoptions":         true,
+	"zypperoptions":      true,
 }
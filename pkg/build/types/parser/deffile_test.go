@@ -187,6 +187,9 @@ func TestParseDefinitionFile(t *testing.T) {
 		{"SectionArgs", "testdata_good/sectionargs/sectionargs", "testdata_good/sectionargs/sectionargs.json"},
 		{"MultipleFiless", "testdata_good/multiplefiles/multiplefiles", "testdata_good/multiplefiles/multiplefiles.json"},
 		{"Shebang", "testdata_good/shebang/shebang", "testdata_good/shebang/shebang.json"},
+		{"DataFile", "testdata_good/datafile/datafile", "testdata_good/datafile/datafile.json"},
+		{"PostFragments", "testdata_good/postfragments/postfragments", "testdata_good/postfragments/postfragments.json"},
+		{"Actions", "testdata_good/actions/actions", "testdata_good/actions/actions.json"},
 	}
 
 	for _, tt := range tests {
@@ -230,6 +233,13 @@ func TestParseDefinitionFileFailure(t *testing.T) {
 		{"JSONInput2", "testdata_bad/json_input_2"},
 		{"Empty", "testdata_bad/empty"},
 		{"EmptyComments", "testdata_bad/emptycomments"},
+		{"DataFileDuplicate", "testdata_bad/datafile_duplicate"},
+		{"DataFileName", "testdata_bad/datafile_name"},
+		{"PostFragmentsMixed", "testdata_bad/post_fragments_mixed"},
+		{"PostFragmentsDuplicate", "testdata_bad/post_fragments_duplicate"},
+		{"PostFragmentsName", "testdata_bad/post_fragments_name"},
+		{"ActionsDuplicate", "testdata_bad/actions_duplicate"},
+		{"ActionsName", "testdata_bad/actions_name"},
 	}
 
 	for _, tt := range tests {
@@ -0,0 +1,608 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// DockerfilePrefix is the build spec prefix of a Dockerfile path, the
+// Dockerfile is converted to a definition by ConvertDockerfile.
+const DockerfilePrefix = "dockerfile://"
+
+// ignoredInstructions are the Dockerfile instructions without
+// equivalent in definitions, with the reason they are ignored.
+var ignoredInstructions = map[string]string{
+	"EXPOSE":      "network ports are not isolated",
+	"HEALTHCHECK": "health checks are not supported",
+	"ONBUILD":     "triggers are not supported",
+	"SHELL":       "RUN instructions always run with /bin/sh",
+	"STOPSIGNAL":  "instances are stopped with SIGTERM",
+	"USER":        "RUN instructions run as root and containers as the calling user",
+	"VOLUME":      "volumes are not supported, use bind mounts",
+}
+
+// archiveExt matches the archives extracted by ADD in Docker builds.
+var archiveExt = regexp.MustCompile(`\.(tar|tar\.gz|tgz|tar\.bz2|tbz2|tar\.xz|txz)$`)
+
+// heredoc matches the heredocs of RUN instructions.
+var heredoc = regexp.MustCompile(`^<<-?["']?[A-Za-z_]`)
+
+type dockerInstruction struct {
+	line int
+	cmd  string
+	args string
+}
+
+// dockerCommand is the exec or shell form of ENTRYPOINT and CMD.
+type dockerCommand struct {
+	exec  []string
+	shell string
+}
+
+func (c *dockerCommand) argv() []string {
+	if c == nil {
+		return nil
+	}
+	if c.exec != nil {
+		return c.exec
+	}
+	return []string{"/bin/sh", "-c", c.shell}
+}
+
+type dockerStage struct {
+	name       string
+	from       string
+	post       []string
+	env        []string
+	labels     []string
+	files      map[string][]string
+	filesOrder []string
+	workdir    string
+	entrypoint *dockerCommand
+	cmd        *dockerCommand
+}
+
+func (s *dockerStage) addFile(args, src, dst string) {
+	if _, ok := s.files[args]; !ok {
+		s.filesOrder = append(s.filesOrder, args)
+	}
+	s.files[args] = append(s.files[args], src+" "+dst)
+}
+
+// ConvertDockerfile returns the definition equivalent to the Dockerfile
+// read from r, COPY and ADD sources being relative to the build context
+// directory contextDir. FROM is converted to the bootstrap header, RUN to
+// %post, COPY and ADD to %files, ENV to %environment and %post, LABEL
+// to %labels, and ENTRYPOINT and CMD to %runscript. Instructions without
+// equivalent are ignored with a warning.
+func ConvertDockerfile(r io.Reader, contextDir string) ([]byte, error) {
+	instructions, err := readDockerfile(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var stages []*dockerStage
+	globalArgs := make(map[string]string)
+
+	for _, in := range instructions {
+		errorf := func(format string, a ...interface{}) error {
+			return fmt.Errorf("Dockerfile line %d: %s: %s", in.line, in.cmd, fmt.Sprintf(format, a...))
+		}
+
+		if in.cmd == "FROM" {
+			s, err := convertFrom(in.args, globalArgs, stages)
+			if err != nil {
+				return nil, errorf("%s", err)
+			}
+			stages = append(stages, s)
+			continue
+		}
+		if len(stages) == 0 {
+			if in.cmd != "ARG" {
+				return nil, errorf("only ARG can precede the first FROM")
+			}
+			words, err := splitWords(in.args)
+			if err != nil {
+				return nil, errorf("%s", err)
+			}
+			for _, w := range words {
+				kv := strings.SplitN(w, "=", 2)
+				if len(kv) == 2 {
+					globalArgs[kv[0]] = kv[1]
+				} else {
+					globalArgs[kv[0]] = ""
+				}
+			}
+			continue
+		}
+
+		s := stages[len(stages)-1]
+		if reason, ok := ignoredInstructions[in.cmd]; ok {
+			sylog.Warningf("Ignoring Dockerfile instruction %s at line %d: %s", in.cmd, in.line, reason)
+			continue
+		}
+
+		var err error
+		switch in.cmd {
+		case "RUN":
+			err = convertRun(s, in.args)
+		case "ENV":
+			err = convertEnv(s, in.args)
+		case "ARG":
+			err = convertArg(s, in.args)
+		case "LABEL":
+			err = convertLabel(s, in.args)
+		case "MAINTAINER":
+			s.labels = append(s.labels, "maintainer "+in.args)
+		case "WORKDIR":
+			wd := in.args
+			if !path.IsAbs(wd) {
+				wd = path.Join(s.workingDir(), wd)
+			}
+			s.workdir = path.Clean(wd)
+			s.post = append(s.post, "mkdir -p "+quote(s.workdir))
+		case "COPY", "ADD":
+			err = convertCopy(s, in.cmd, in.args, contextDir, stages)
+		case "ENTRYPOINT":
+			s.entrypoint = parseCommand(in.args)
+			// a new entrypoint resets the command of the base image
+			s.cmd = nil
+		case "CMD":
+			s.cmd = parseCommand(in.args)
+		default:
+			err = fmt.Errorf("unknown instruction")
+		}
+		if err != nil {
+			return nil, errorf("%s", err)
+		}
+	}
+
+	if len(stages) == 0 {
+		return nil, fmt.Errorf("Dockerfile has no FROM instruction")
+	}
+
+	var buf bytes.Buffer
+	for i, s := range stages {
+		if i > 0 {
+			buf.WriteString("\n")
+		}
+		writeStage(&buf, s, len(stages) > 1)
+	}
+	return buf.Bytes(), nil
+}
+
+// readDockerfile returns the instructions of the Dockerfile read from r
+// with line continuations joined and comments removed.
+func readDockerfile(r io.Reader) ([]dockerInstruction, error) {
+	var instructions []dockerInstruction
+	var cur strings.Builder
+	start := 0
+
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+
+		if n == 1 && strings.HasPrefix(strings.ToLower(strings.Replace(trimmed, " ", "", -1)), "#escape=") {
+			if !strings.HasSuffix(trimmed, "\\") {
+				return nil, fmt.Errorf("Dockerfile line 1: only the backslash escape character is supported")
+			}
+			continue
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if cur.Len() == 0 {
+			start = n
+		}
+
+		if strings.HasSuffix(trimmed, "\\") {
+			cur.WriteString(strings.TrimSuffix(strings.TrimRight(line, " \t"), "\\"))
+			continue
+		}
+		cur.WriteString(line)
+
+		content := strings.TrimSpace(cur.String())
+		cur.Reset()
+		in := dockerInstruction{line: start, cmd: strings.ToUpper(content)}
+		if i := strings.IndexAny(content, " \t"); i > 0 {
+			in.cmd = strings.ToUpper(content[:i])
+			in.args = strings.TrimSpace(content[i:])
+		}
+		instructions = append(instructions, in)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("while reading Dockerfile: %s", err)
+	}
+	if cur.Len() > 0 {
+		return nil, fmt.Errorf("Dockerfile line %d: unterminated line continuation", start)
+	}
+	return instructions, nil
+}
+
+// instructionFlags returns the --name=value flags found at the start
+// of args and the remaining arguments.
+func instructionFlags(args string) (map[string]string, string) {
+	flags := make(map[string]string)
+	for strings.HasPrefix(args, "--") {
+		split := strings.SplitN(args, " ", 2)
+		kv := strings.SplitN(strings.TrimPrefix(split[0], "--"), "=", 2)
+		if len(kv) == 2 {
+			flags[kv[0]] = kv[1]
+		} else {
+			flags[kv[0]] = ""
+		}
+		args = ""
+		if len(split) == 2 {
+			args = strings.TrimSpace(split[1])
+		}
+	}
+	return flags, args
+}
+
+func convertFrom(args string, globalArgs map[string]string, stages []*dockerStage) (*dockerStage, error) {
+	flags, args := instructionFlags(args)
+	if p, ok := flags["platform"]; ok {
+		sylog.Warningf("Ignoring FROM --platform=%s, use build --arch instead", p)
+	}
+
+	args = os.Expand(args, func(name string) string {
+		return globalArgs[name]
+	})
+	fields := strings.Fields(args)
+	s := &dockerStage{
+		name:  fmt.Sprintf("stage%d", len(stages)),
+		files: make(map[string][]string),
+	}
+	switch {
+	case len(fields) == 1:
+	case len(fields) == 3 && strings.EqualFold(fields[1], "AS"):
+		s.name = fields[2]
+	default:
+		return nil, fmt.Errorf("expected an image and an optional stage name")
+	}
+	s.from = fields[0]
+
+	for _, prev := range stages {
+		if prev.name == s.from {
+			return nil, fmt.Errorf("building on top of the previous stage %s is not supported", s.from)
+		}
+	}
+	return s, nil
+}
+
+func (s *dockerStage) workingDir() string {
+	if s.workdir == "" {
+		return "/"
+	}
+	return s.workdir
+}
+
+// convertRun adds a RUN instruction to %post, in a subshell started in
+// the working directory, so that directory changes and variables
+// don't leak into the following instructions as in Docker builds.
+func convertRun(s *dockerStage, args string) error {
+	flags, args := instructionFlags(args)
+	for name := range flags {
+		sylog.Warningf("Ignoring RUN --%s option", name)
+	}
+	if heredoc.MatchString(args) {
+		return fmt.Errorf("heredocs are not supported")
+	}
+
+	command := args
+	if exec, ok := jsonArray(args); ok {
+		quoted := make([]string, len(exec))
+		for i, a := range exec {
+			quoted[i] = quote(a)
+		}
+		command = strings.Join(quoted, " ")
+	}
+
+	run := "(\n"
+	if s.workdir != "" {
+		run += "cd " + quote(s.workdir) + "\n"
+	}
+	s.post = append(s.post, run+command+"\n)")
+	return nil
+}
+
+func convertEnv(s *dockerStage, args string) error {
+	words, err := splitWords(args)
+	if err != nil {
+		return err
+	}
+	var exports []string
+	if len(words) > 0 && !strings.Contains(words[0], "=") {
+		// legacy ENV name value form
+		split := strings.SplitN(args, " ", 2)
+		if len(split) != 2 {
+			return fmt.Errorf("missing value")
+		}
+		exports = append(exports, fmt.Sprintf("export %s=%s", split[0], dquote(strings.TrimSpace(split[1]))))
+	} else {
+		for _, w := range words {
+			kv := strings.SplitN(w, "=", 2)
+			if len(kv) != 2 {
+				return fmt.Errorf("%s is not a name=value pair", w)
+			}
+			exports = append(exports, fmt.Sprintf("export %s=%s", kv[0], dquote(kv[1])))
+		}
+	}
+	s.env = append(s.env, exports...)
+	s.post = append(s.post, exports...)
+	return nil
+}
+
+// convertArg exports build arguments with a default value to the
+// following RUN instructions, their value can't be overridden.
+func convertArg(s *dockerStage, args string) error {
+	words, err := splitWords(args)
+	if err != nil {
+		return err
+	}
+	for _, w := range words {
+		kv := strings.SplitN(w, "=", 2)
+		if len(kv) != 2 {
+			sylog.Warningf("Ignoring ARG %s without default value", kv[0])
+			continue
+		}
+		s.post = append(s.post, fmt.Sprintf("export %s=%s", kv[0], dquote(kv[1])))
+	}
+	return nil
+}
+
+func convertLabel(s *dockerStage, args string) error {
+	words, err := splitWords(args)
+	if err != nil {
+		return err
+	}
+	for _, w := range words {
+		kv := strings.SplitN(w, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("%s is not a key=value pair", w)
+		}
+		if strings.ContainsAny(kv[0], " \t") {
+			return fmt.Errorf("label key %q contains spaces", kv[0])
+		}
+		s.labels = append(s.labels, kv[0]+" "+strings.Replace(kv[1], "\n", " ", -1))
+	}
+	return nil
+}
+
+func convertCopy(s *dockerStage, cmd, args, contextDir string, stages []*dockerStage) error {
+	flags, args := instructionFlags(args)
+	from, fromStage := flags["from"]
+	delete(flags, "from")
+	for name := range flags {
+		sylog.Warningf("Ignoring %s --%s option", cmd, name)
+	}
+
+	words, ok := jsonArray(args)
+	if !ok {
+		var err error
+		if words, err = splitWords(args); err != nil {
+			return err
+		}
+	}
+	if len(words) < 2 {
+		return fmt.Errorf("expected at least one source and a destination")
+	}
+	srcs, dst := words[:len(words)-1], words[len(words)-1]
+
+	if !path.IsAbs(dst) {
+		dir := strings.HasSuffix(dst, "/")
+		dst = path.Join(s.workingDir(), dst)
+		if dir {
+			dst += "/"
+		}
+	}
+	if len(srcs) > 1 && !strings.HasSuffix(dst, "/") {
+		return fmt.Errorf("destination of multiple sources must end with /")
+	}
+
+	filesArgs := ""
+	if fromStage {
+		if n, err := strconv.Atoi(from); err == nil && n >= 0 && n < len(stages)-1 {
+			from = stages[n].name
+		}
+		found := false
+		for _, prev := range stages[:len(stages)-1] {
+			found = found || prev.name == from
+		}
+		if !found {
+			return fmt.Errorf("--from=%s is not a previous stage, copying from images is not supported", from)
+		}
+		filesArgs = "from " + from
+	}
+
+	for _, src := range srcs {
+		if strings.ContainsAny(src, " \t") {
+			return fmt.Errorf("source %q contains spaces", src)
+		}
+		if fromStage {
+			if !path.IsAbs(src) {
+				src = "/" + src
+			}
+			s.addFile(filesArgs, src, dst)
+			continue
+		}
+
+		if strings.HasPrefix(src, "http://") || strings.HasPrefix(src, "https://") {
+			if cmd == "COPY" {
+				return fmt.Errorf("source %s is an URL", src)
+			}
+			sylog.Warningf("Ignoring ADD source %s: URLs are not supported", src)
+			continue
+		}
+		if cmd == "ADD" && archiveExt.MatchString(src) {
+			sylog.Warningf("ADD source %s is copied without being extracted", src)
+		}
+
+		hostSrc := filepath.Join(contextDir, src)
+		if rel, err := filepath.Rel(contextDir, hostSrc); err != nil || strings.HasPrefix(rel, "..") {
+			return fmt.Errorf("source %s is outside of the build context %s", src, contextDir)
+		}
+		// directory contents are copied, not the directory itself
+		if fi, err := os.Stat(hostSrc); err == nil && fi.IsDir() {
+			hostSrc += "/."
+		}
+		s.addFile(filesArgs, hostSrc, dst)
+	}
+	return nil
+}
+
+func parseCommand(args string) *dockerCommand {
+	if exec, ok := jsonArray(args); ok {
+		return &dockerCommand{exec: exec}
+	}
+	return &dockerCommand{shell: args}
+}
+
+// jsonArray returns the strings of the JSON array args, used by the exec
+// form of instructions.
+func jsonArray(args string) ([]string, bool) {
+	if !strings.HasPrefix(args, "[") {
+		return nil, false
+	}
+	var a []string
+	if err := json.Unmarshal([]byte(args), &a); err != nil {
+		return nil, false
+	}
+	if a == nil {
+		a = []string{}
+	}
+	return a, true
+}
+
+// splitWords splits args in words separated by white spaces, handling
+// quotes and backslash escapes like Docker.
+func splitWords(args string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var q rune
+
+	runes := []rune(args)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case q == 0 && (c == ' ' || c == '\t'):
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+			continue
+		case c == '\\' && q != '\'' && i+1 < len(runes):
+			i++
+			c = runes[i]
+		case q == 0 && (c == '"' || c == '\''):
+			q = c
+			inWord = true
+			continue
+		case c == q:
+			q = 0
+			continue
+		}
+		cur.WriteRune(c)
+		inWord = true
+	}
+	if q != 0 {
+		return nil, fmt.Errorf("unterminated quote")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// quote returns s single quoted for the shell.
+func quote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
+
+// dquote returns s double quoted for the shell, variable references are
+// still expanded as in Docker builds.
+func dquote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "`", "\\`")
+	return `"` + r.Replace(s) + `"`
+}
+
+func writeStage(w io.Writer, s *dockerStage, multiStage bool) {
+	if s.from == "scratch" {
+		fmt.Fprintf(w, "Bootstrap: scratch\n")
+	} else {
+		fmt.Fprintf(w, "Bootstrap: docker\nFrom: %s\n", s.from)
+	}
+	if multiStage {
+		fmt.Fprintf(w, "Stage: %s\n", s.name)
+	}
+
+	for _, args := range s.filesOrder {
+		fmt.Fprintf(w, "\n%s\n", strings.TrimSpace("%files "+args))
+		for _, f := range s.files[args] {
+			fmt.Fprintf(w, "    %s\n", f)
+		}
+	}
+	writeSection(w, "post", s.post)
+	writeSection(w, "environment", s.env)
+	writeSection(w, "labels", s.labels)
+
+	entrypoint := s.entrypoint.argv()
+	cmd := s.cmd.argv()
+	if entrypoint == nil && cmd == nil {
+		return
+	}
+	var runscript []string
+	if s.workdir != "" {
+		runscript = append(runscript, "cd "+quote(s.workdir))
+	}
+	exec := func(args []string, extra ...string) string {
+		words := []string{"exec"}
+		for _, a := range args {
+			words = append(words, quote(a))
+		}
+		return strings.Join(append(words, extra...), " ")
+	}
+	if s.entrypoint != nil && s.entrypoint.exec == nil {
+		// the shell form of ENTRYPOINT ignores CMD and arguments
+		runscript = append(runscript, exec(entrypoint))
+	} else {
+		// arguments replace CMD
+		runscript = append(runscript, "if [ $# -gt 0 ]; then "+exec(entrypoint, `"$@"`)+"; fi")
+		if len(entrypoint)+len(cmd) > 0 {
+			runscript = append(runscript, exec(append(append([]string{}, entrypoint...), cmd...)))
+		}
+	}
+	writeSection(w, "runscript", runscript)
+}
+
+func writeSection(w io.Writer, name string, lines []string) {
+	if len(lines) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%%%s\n", name)
+	for _, l := range lines {
+		for _, sub := range strings.Split(l, "\n") {
+			fmt.Fprintf(w, "    %s\n", sub)
+		}
+	}
+}
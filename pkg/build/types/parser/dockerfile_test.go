@@ -0,0 +1,163 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConvertDockerfile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dockerfile-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "src"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.conf"), []byte("conf"), 0644); err != nil {
+		t.Fatalf("failed to write file: %s", err)
+	}
+
+	tests := []struct {
+		name       string
+		dockerfile string
+		expected   string
+		wantErr    bool
+	}{
+		{
+			name: "SingleStage",
+			dockerfile: `# comment
+ARG TAG=3.12
+FROM alpine:${TAG}
+LABEL org.example.name="my app" version=1.0
+ENV APP_HOME=/app PATH="/app/bin:$PATH"
+WORKDIR /app
+COPY src app.conf ./
+RUN apk add --no-cache \
+    curl
+EXPOSE 8080
+ENTRYPOINT ["/app/bin/run", "--verbose"]
+CMD ["serve"]
+`,
+			expected: `Bootstrap: docker
+From: alpine:3.12
+
+%files
+    ` + dir + `/src/. /app/
+    ` + dir + `/app.conf /app/
+
+%post
+    export APP_HOME="/app"
+    export PATH="/app/bin:$PATH"
+    mkdir -p '/app'
+    (
+    cd '/app'
+    apk add --no-cache     curl
+    )
+
+%environment
+    export APP_HOME="/app"
+    export PATH="/app/bin:$PATH"
+
+%labels
+    org.example.name my app
+    version 1.0
+
+%runscript
+    cd '/app'
+    if [ $# -gt 0 ]; then exec '/app/bin/run' '--verbose' "$@"; fi
+    exec '/app/bin/run' '--verbose' 'serve'
+`,
+		},
+		{
+			name: "MultiStage",
+			dockerfile: `FROM golang:1.14 AS build
+RUN ["go", "build", "-o", "/out/app", "."]
+FROM scratch
+COPY --from=build /out/app /app
+CMD /app
+`,
+			expected: `Bootstrap: docker
+From: golang:1.14
+Stage: build
+
+%post
+    (
+    'go' 'build' '-o' '/out/app' '.'
+    )
+
+Bootstrap: scratch
+Stage: stage1
+
+%files from build
+    /out/app /app
+
+%runscript
+    if [ $# -gt 0 ]; then exec "$@"; fi
+    exec '/bin/sh' '-c' '/app'
+`,
+		},
+		{
+			name:       "NoFrom",
+			dockerfile: "RUN true\n",
+			wantErr:    true,
+		},
+		{
+			name:       "UnknownInstruction",
+			dockerfile: "FROM alpine\nFOO bar\n",
+			wantErr:    true,
+		},
+		{
+			name:       "OutsideContext",
+			dockerfile: "FROM alpine\nCOPY ../secret /\n",
+			wantErr:    true,
+		},
+		{
+			name:       "FromPreviousStage",
+			dockerfile: "FROM alpine AS base\nFROM base\n",
+			wantErr:    true,
+		},
+		{
+			name:       "CopyFromImage",
+			dockerfile: "FROM alpine\nCOPY --from=nginx /etc/nginx /etc/nginx\n",
+			wantErr:    true,
+		},
+		{
+			name:       "Heredoc",
+			dockerfile: "FROM alpine\nRUN <<EOF\necho hello\nEOF\n",
+			wantErr:    true,
+		},
+		{
+			name:       "MultipleSourcesToFile",
+			dockerfile: "FROM alpine\nCOPY a b /dst\n",
+			wantErr:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			def, err := ConvertDockerfile(strings.NewReader(tt.dockerfile), dir)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success:\n%s", def)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(def) != tt.expected {
+				t.Errorf("unexpected definition:\n%s\nexpected:\n%s", def, tt.expected)
+			}
+		})
+	}
+}
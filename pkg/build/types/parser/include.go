@@ -0,0 +1,147 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// includeDirective includes a definition fragment in a definition file.
+const includeDirective = "%include"
+
+// ExpandIncludes reads the definition file found at path and returns
+// its content with the %include directives replaced by the content of
+// the included definition fragments, relative paths being resolved from
+// the including file directory. Fragments hold sections only and may
+// include other fragments, circular includes are rejected. Sections
+// found several times are merged by the parser in include order.
+func ExpandIncludes(path string) ([]byte, error) {
+	return expandFile(path, "", nil)
+}
+
+// ExpandIncludesContext is ExpandIncludes with relative paths of all
+// the include levels resolved from contextDir, the including file
+// directory is used when contextDir is empty.
+func ExpandIncludesContext(path, contextDir string) ([]byte, error) {
+	return expandFile(path, contextDir, nil)
+}
+
+// ExpandIncludesReader is ExpandIncludes for a definition read from r,
+// relative paths being resolved from dir.
+func ExpandIncludesReader(r io.Reader, dir string) ([]byte, error) {
+	return expandReader(r, dir, "")
+}
+
+// ExpandIncludesReaderContext is ExpandIncludesContext for a definition
+// read from r.
+func ExpandIncludesReaderContext(r io.Reader, contextDir string) ([]byte, error) {
+	return expandReader(r, contextDir, contextDir)
+}
+
+func expandReader(r io.Reader, dir, contextDir string) ([]byte, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("while attempting to read in definition: %v", err)
+	}
+	return expand(data, dir, contextDir, nil, false)
+}
+
+// expandFile expands the file found at path, relative includes being
+// resolved from contextDir if set. stack lists the files being expanded
+// to detect circular includes.
+func expandFile(path, contextDir string, stack []string) ([]byte, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for i, p := range stack {
+		if p == abs {
+			chain := append(stack[i:], abs)
+			return nil, fmt.Errorf("circular include: %s", strings.Join(chain, " -> "))
+		}
+	}
+
+	data, err := ioutil.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("while reading %s: %v", path, err)
+	}
+	dir := contextDir
+	if dir == "" {
+		dir = filepath.Dir(abs)
+	}
+	return expand(data, dir, contextDir, append(stack, abs), len(stack) > 0)
+}
+
+// bootstrapRegexp matches the first header keyword of a stage.
+var bootstrapRegexp = regexp.MustCompile(`(?i)^bootstrap:`)
+
+// expand replaces the %include directives of data. The text following
+// a directive must start a new section, as it would otherwise be
+// appended to the last section of the included fragment, or a new
+// stage header. Fragments can't have a header. Relative paths are
+// resolved from dir, contextDir is passed to the included files.
+func expand(data []byte, dir, contextDir string, stack []string, fragment bool) ([]byte, error) {
+	var buf bytes.Buffer
+
+	// sectionRequired is set when the next significant line must be
+	// a section or a directive
+	sectionRequired := fragment
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	s.Buffer(nil, len(data)+1)
+	for s.Scan() {
+		line := s.Text()
+		fields := strings.Fields(line)
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			buf.WriteString(line + "\n")
+			continue
+		}
+		if !strings.HasPrefix(fields[0], "%") {
+			// a new stage header may follow a directive
+			if !fragment && bootstrapRegexp.MatchString(fields[0]) {
+				sectionRequired = false
+			}
+			if sectionRequired {
+				return nil, fmt.Errorf("%q: definition fragments and text following %s must start with a section", line, includeDirective)
+			}
+			buf.WriteString(line + "\n")
+			continue
+		}
+
+		sectionRequired = false
+		if strings.ToLower(fields[0]) != includeDirective {
+			buf.WriteString(line + "\n")
+			continue
+		}
+
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%q: %s requires a single file path", line, includeDirective)
+		}
+		path := fields[1]
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+		included, err := expandFile(path, contextDir, stack)
+		if err != nil {
+			return nil, fmt.Errorf("while including %s: %v", fields[1], err)
+		}
+		buf.Write(included)
+		sectionRequired = true
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestExpandIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"env.def":            "# shared environment\n%environment\n    export FOO=bar\n",
+		"labels.def":         "%include env.def\n%labels\n    Maintainer me\n",
+		"header.def":         "Bootstrap: docker\nFrom: alpine\n",
+		"header-include.def": "%include header.def\n",
+		"loop-a.def":         "%include loop-b.def\n",
+		"loop-b.def":         "%include loop-a.def\n",
+		"recipe.def":         "Bootstrap: docker\nFrom: alpine\n\n%include sub/labels.def\n%environment\n    export BAR=baz\n",
+		"sub/labels.def":     "%include ../env.def\n%labels\n    Maintainer me\n",
+		"trailing.def":       "Bootstrap: docker\nFrom: alpine\n\n%include env.def\n    echo lost\n",
+		"stages.def":         "Bootstrap: docker\nFrom: alpine\n\n%include env.def\nBootstrap: docker\nFrom: busybox\n",
+		"args.def":           "%include env.def labels.def\n",
+	}
+	if err := os.Mkdir(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatalf("failed to create directory: %s", err)
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+
+	tests := []struct {
+		name     string
+		file     string
+		expected string
+		wantErr  bool
+	}{
+		{
+			name:     "NoInclude",
+			file:     "env.def",
+			expected: files["env.def"],
+		},
+		{
+			name: "Nested",
+			file: "recipe.def",
+			expected: "Bootstrap: docker\nFrom: alpine\n\n" +
+				"# shared environment\n%environment\n    export FOO=bar\n" +
+				"%labels\n    Maintainer me\n" +
+				"%environment\n    export BAR=baz\n",
+		},
+		{
+			name:     "Stages",
+			file:     "stages.def",
+			expected: "Bootstrap: docker\nFrom: alpine\n\n" + files["env.def"] + "Bootstrap: docker\nFrom: busybox\n",
+		},
+		{
+			name:    "Circular",
+			file:    "loop-a.def",
+			wantErr: true,
+		},
+		{
+			name:    "FragmentHeader",
+			file:    "header-include.def",
+			wantErr: true,
+		},
+		{
+			name:    "TextAfterInclude",
+			file:    "trailing.def",
+			wantErr: true,
+		},
+		{
+			name:    "Arguments",
+			file:    "args.def",
+			wantErr: true,
+		},
+		{
+			name:    "Missing",
+			file:    "missing.def",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := ExpandIncludes(filepath.Join(dir, tt.file))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if string(data) != tt.expected {
+				t.Errorf("unexpected expansion %q, expected %q", data, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExpandIncludesMerge(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	fragment := "%environment\n    export FOO=bar\n%labels\n    Shared yes\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, "common.def"), []byte(fragment), 0644); err != nil {
+		t.Fatalf("failed to write fragment: %s", err)
+	}
+
+	def := "Bootstrap: docker\nFrom: alpine\n\n%include common.def\n%environment\n    export BAR=baz\n%labels\n    Own yes\n"
+	data, err := ExpandIncludesReader(bytes.NewReader([]byte(def)), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	d, err := ParseDefinitionFile(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("unexpected parse error: %s", err)
+	}
+	if env := d.ImageData.Environment.Script; env != "    export FOO=bar\n    export BAR=baz\n" {
+		t.Errorf("unexpected merged environment %q", env)
+	}
+	if d.ImageData.Labels["Shared"] != "yes" || d.ImageData.Labels["Own"] != "yes" {
+		t.Errorf("unexpected merged labels %v", d.ImageData.Labels)
+	}
+}
+
+func TestExpandIncludesContext(t *testing.T) {
+	dir, err := ioutil.TempDir("", "include-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// fragments live in the context directory, the definition and its
+	// nested fragment in a sub directory without any of them
+	files := map[string]string{
+		"ctx/env.def":      "%environment\n    export FOO=bar\n",
+		"ctx/labels.def":   "%include env.def\n%labels\n    Maintainer me\n",
+		"defs/recipe.def":  "Bootstrap: docker\nFrom: alpine\n\n%include labels.def\n",
+		"defs/labels.def":  "%labels\n    Maintainer other\n",
+		"defs/missing.def": "Bootstrap: docker\nFrom: alpine\n\n%include nested.def\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("failed to create directory: %s", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write %s: %s", name, err)
+		}
+	}
+
+	contextDir := filepath.Join(dir, "ctx")
+	expected := "Bootstrap: docker\nFrom: alpine\n\n" +
+		files["ctx/env.def"] + "%labels\n    Maintainer me\n"
+
+	data, err := ExpandIncludesContext(filepath.Join(dir, "defs/recipe.def"), contextDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data) != expected {
+		t.Errorf("unexpected expansion %q, expected %q", data, expected)
+	}
+
+	data, err = ExpandIncludesReaderContext(bytes.NewReader([]byte(files["defs/recipe.def"])), contextDir)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if string(data) != expected {
+		t.Errorf("unexpected expansion from reader %q, expected %q", data, expected)
+	}
+
+	// the including file directory isn't searched with a context
+	if _, err := ExpandIncludesContext(filepath.Join(dir, "defs/missing.def"), contextDir); err == nil {
+		t.Errorf("unexpected success")
+	}
+}
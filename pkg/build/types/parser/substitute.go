@@ -0,0 +1,124 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// varRegexp matches the {{ NAME }} build variable references.
+var varRegexp = regexp.MustCompile(`{{\s*([A-Za-z_][A-Za-z0-9_]*)\s*}}`)
+
+// ParseBuildArgs converts a list of KEY=VALUE strings, as passed to
+// the --build-arg flag, into a map of build variables.
+func ParseBuildArgs(args []string) (map[string]string, error) {
+	vars := make(map[string]string, len(args))
+
+	for _, arg := range args {
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("bad build argument %q: must be of the form KEY=VALUE", arg)
+		}
+		if !varRegexp.MatchString("{{" + kv[0] + "}}") {
+			return nil, fmt.Errorf("bad build argument name %q", kv[0])
+		}
+		vars[kv[0]] = kv[1]
+	}
+
+	return vars, nil
+}
+
+// ReadBuildArgsFile returns the build variables defined in the file
+// found at path, as passed to the --build-args-file flag.
+func ReadBuildArgsFile(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return parseBuildArgsFile(f, path)
+}
+
+// parseBuildArgsFile reads build variables from r, holding one KEY=VALUE
+// definition per line, the value being the rest of the line. Empty lines
+// and lines starting with '#' are ignored. Errors report the line number
+// in the file name, defining a variable twice is an error.
+func parseBuildArgsFile(r io.Reader, name string) (map[string]string, error) {
+	vars := make(map[string]string)
+	lines := make(map[string]int)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		kv := strings.SplitN(line, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return nil, fmt.Errorf("%s:%d: bad build argument %q: must be of the form KEY=VALUE", name, n, line)
+		}
+		if !varRegexp.MatchString("{{" + kv[0] + "}}") {
+			return nil, fmt.Errorf("%s:%d: bad build argument name %q", name, n, kv[0])
+		}
+		if prev, ok := lines[kv[0]]; ok {
+			return nil, fmt.Errorf("%s:%d: build argument %s already defined at line %d", name, n, kv[0], prev)
+		}
+		vars[kv[0]] = kv[1]
+		lines[kv[0]] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("while reading %s: %s", name, err)
+	}
+
+	return vars, nil
+}
+
+// ReferencedVars returns the sorted names of the build variables
+// referenced in text.
+func ReferencedVars(text string) []string {
+	var names []string
+	seen := make(map[string]bool)
+
+	for _, m := range varRegexp.FindAllStringSubmatch(text, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// SubstituteVars replaces every {{ NAME }} reference found in text by
+// the value of the corresponding build variable, it returns an error
+// if text references a variable which is not defined.
+func SubstituteVars(text string, vars map[string]string) (string, error) {
+	var undefined []string
+
+	res := varRegexp.ReplaceAllStringFunc(text, func(m string) string {
+		name := varRegexp.FindStringSubmatch(m)[1]
+		v, ok := vars[name]
+		if !ok {
+			undefined = append(undefined, name)
+			return m
+		}
+		return v
+	})
+
+	if len(undefined) > 0 {
+		return "", fmt.Errorf("undefined build argument(s): %s", strings.Join(undefined, ", "))
+	}
+
+	return res, nil
+}
@@ -0,0 +1,200 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package parser
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBuildArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		vars    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "Empty",
+			args: nil,
+			vars: map[string]string{},
+		},
+		{
+			name: "Valid",
+			args: []string{"FOO=bar", "EMPTY=", "EQUAL=a=b"},
+			vars: map[string]string{"FOO": "bar", "EMPTY": "", "EQUAL": "a=b"},
+		},
+		{
+			name:    "NoValue",
+			args:    []string{"FOO"},
+			wantErr: true,
+		},
+		{
+			name:    "NoName",
+			args:    []string{"=bar"},
+			wantErr: true,
+		},
+		{
+			name:    "BadName",
+			args:    []string{"1FOO=bar"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := ParseBuildArgs(tt.args)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(vars, tt.vars) {
+				t.Fatalf("got %v instead of %v", vars, tt.vars)
+			}
+		})
+	}
+}
+
+func TestParseBuildArgsFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		vars    map[string]string
+		err     string
+	}{
+		{
+			name:    "Empty",
+			content: "",
+			vars:    map[string]string{},
+		},
+		{
+			name:    "Valid",
+			content: "# comment\nFOO=bar\n\n  EMPTY=\nEQUAL=a=b\nSPACES=a b  \n",
+			vars:    map[string]string{"FOO": "bar", "EMPTY": "", "EQUAL": "a=b", "SPACES": "a b"},
+		},
+		{
+			name:    "NoValue",
+			content: "FOO=bar\nBAR\n",
+			err:     "args:2: bad build argument",
+		},
+		{
+			name:    "BadName",
+			content: "\n1FOO=bar\n",
+			err:     "args:2: bad build argument name",
+		},
+		{
+			name:    "Duplicate",
+			content: "FOO=bar\n# comment\nFOO=baz\n",
+			err:     "args:3: build argument FOO already defined at line 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			vars, err := parseBuildArgsFile(strings.NewReader(tt.content), "args")
+			if tt.err != "" {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				if !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("unexpected error %q, expected %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if !reflect.DeepEqual(vars, tt.vars) {
+				t.Fatalf("got %v instead of %v", vars, tt.vars)
+			}
+		})
+	}
+}
+
+func TestSubstituteVars(t *testing.T) {
+	vars := map[string]string{
+		"NAME":    "alpine",
+		"VERSION": "3.12",
+	}
+
+	tests := []struct {
+		name    string
+		text    string
+		result  string
+		wantErr bool
+	}{
+		{
+			name:   "NoVariable",
+			text:   "plain text",
+			result: "plain text",
+		},
+		{
+			name:   "Variables",
+			text:   "{{NAME}}:{{ VERSION }} {{  NAME  }}",
+			result: "alpine:3.12 alpine",
+		},
+		{
+			name:   "NotAVariable",
+			text:   "{{ not a variable }}",
+			result: "{{ not a variable }}",
+		},
+		{
+			name:    "Undefined",
+			text:    "{{ UNDEFINED }}",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := SubstituteVars(tt.text, vars)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if res != tt.result {
+				t.Fatalf("got %q instead of %q", res, tt.result)
+			}
+		})
+	}
+}
+
+func TestReferencedVars(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{
+			name: "None",
+			text: "From: alpine:3.11",
+		},
+		{
+			name: "Vars",
+			text: "From: alpine:{{ TAG }}\n%post\n    echo {{VERSION}} {{ TAG }}\n",
+			want: []string{"TAG", "VERSION"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ReferencedVars(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v instead of %v", got, tt.want)
+			}
+		})
+	}
+}
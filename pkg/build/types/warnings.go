@@ -0,0 +1,160 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package types
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sylabs/singularity/internal/pkg/build/progress"
+	"github.com/sylabs/singularity/pkg/sylog"
+)
+
+// WarningID is the stable identifier of a build warning, it is composed
+// of a code (e.g. W001) followed by a short description.
+type WarningID string
+
+// Build warnings identifiers, identifiers must never be renumbered
+// as users may refer to them with --allow-warning.
+const (
+	// WarnDeprecatedSection is raised for deprecated definition file sections.
+	WarnDeprecatedSection WarningID = "W001_deprecated_section"
+	// WarnRPMScripts is raised when some RPM scripts failed during
+	// a zypper bootstrap.
+	WarnRPMScripts WarningID = "W002_rpm_scripts"
+	// WarnUnreachablePost is raised when %post commands follow an
+	// unconditional exit.
+	WarnUnreachablePost WarningID = "W003_unreachable_post"
+	// WarnEmptyFilesSource is raised for %files entries without source.
+	WarnEmptyFilesSource WarningID = "W004_empty_files_source"
+	// WarnIgnoredDefaultBinds is raised when default bind paths are set
+	// for an image format other than SIF.
+	WarnIgnoredDefaultBinds WarningID = "W005_ignored_default_binds"
+	// WarnCompression is raised for squashfs compression algorithms not
+	// supported by all kernels.
+	WarnCompression WarningID = "W006_compression"
+	// WarnHelpOverride is raised when the %help section inline content
+	// is ignored.
+	WarnHelpOverride WarningID = "W007_help_override"
+	// WarnHelpExists is raised when the existing container help is kept.
+	WarnHelpExists WarningID = "W008_help_exists"
+	// WarnLabelExists is raised when an existing container label is kept.
+	WarnLabelExists WarningID = "W009_label_exists"
+	// WarnShebangOverride is raised when a script shebang is overridden
+	// by the section interpreter argument.
+	WarnShebangOverride WarningID = "W010_shebang_override"
+	// WarnOwnership is raised when a sandbox can't preserve ownership.
+	WarnOwnership WarningID = "W011_ownership"
+	// WarnNodevTmpDir is raised when the temporary directory is mounted
+	// with the nodev option.
+	WarnNodevTmpDir WarningID = "W012_nodev_tmpdir"
+	// WarnStageFile is raised when a host file staged in the container
+	// during the build, like /etc/resolv.conf, is not accessible.
+	WarnStageFile WarningID = "W013_stage_file"
+	// WarnFilesManifest is raised when the %files manifest of a sandbox
+	// can't be read.
+	WarnFilesManifest WarningID = "W014_files_manifest"
+	// WarnFilesHash is raised when a %files source can't be hashed to
+	// detect changes.
+	WarnFilesHash WarningID = "W015_files_hash"
+	// WarnIgnoredDataFiles is raised when %datafile entries are set
+	// for an image format other than SIF.
+	WarnIgnoredDataFiles WarningID = "W016_ignored_data_files"
+	// WarnMissingRunscript is raised when the built image has no
+	// runscript.
+	WarnMissingRunscript WarningID = "W017_missing_runscript"
+	// WarnBrokenRunscript is raised when the runscript of the built
+	// image is empty, not executable or runs a missing program.
+	WarnBrokenRunscript WarningID = "W018_broken_runscript"
+	// WarnPlatformMismatch is raised when a single architecture source
+	// image doesn't match the requested platform.
+	WarnPlatformMismatch WarningID = "W019_platform_mismatch"
+	// WarnImplicitDockerDefaults is raised when a docker bootstrap
+	// image reference relies on the default registry or tag.
+	WarnImplicitDockerDefaults WarningID = "W020_implicit_docker_defaults"
+	// WarnRunscriptExec is raised when the last command of the
+	// runscript or startscript can't be run with exec as requested
+	// by --no-runscript-wrap.
+	WarnRunscriptExec WarningID = "W021_runscript_exec"
+	// WarnImageSize is raised when the built image exceeds the size
+	// set by --warn-size.
+	WarnImageSize WarningID = "W022_image_size"
+	// WarnOCIOutput is raised when an image built in the OCI or tar
+	// format has sections, build options or content which can't be
+	// represented in OCI images and tar archives.
+	WarnOCIOutput WarningID = "W023_oci_output"
+	// WarnSecret is raised for each possible secret found in the
+	// image by --scan-secrets.
+	WarnSecret WarningID = "W024_secret"
+)
+
+// warningIDs lists all the build warnings identifiers.
+var warningIDs = []WarningID{
+	WarnDeprecatedSection,
+	WarnRPMScripts,
+	WarnUnreachablePost,
+	WarnEmptyFilesSource,
+	WarnIgnoredDefaultBinds,
+	WarnCompression,
+	WarnHelpOverride,
+	WarnHelpExists,
+	WarnLabelExists,
+	WarnShebangOverride,
+	WarnOwnership,
+	WarnNodevTmpDir,
+	WarnStageFile,
+	WarnFilesManifest,
+	WarnFilesHash,
+	WarnIgnoredDataFiles,
+	WarnMissingRunscript,
+	WarnBrokenRunscript,
+	WarnPlatformMismatch,
+	WarnImplicitDockerDefaults,
+	WarnRunscriptExec,
+	WarnImageSize,
+	WarnOCIOutput,
+	WarnSecret,
+}
+
+// Code returns the code of the warning identifier (e.g. W001).
+func (id WarningID) Code() string {
+	return strings.SplitN(string(id), "_", 2)[0]
+}
+
+// ParseWarningID returns the warning identifier corresponding to s,
+// which is either a full warning identifier or its code.
+func ParseWarningID(s string) (WarningID, error) {
+	for _, id := range warningIDs {
+		if strings.EqualFold(s, string(id)) || strings.EqualFold(s, id.Code()) {
+			return id, nil
+		}
+	}
+	return "", fmt.Errorf("unknown build warning %s", s)
+}
+
+// Warnf logs the build warning id. If warnings are promoted to errors
+// with WarnAsError and id is not part of AllowedWarnings, the warning is
+// returned as an error instead and the build must be aborted.
+func (o Options) Warnf(id WarningID, format string, a ...interface{}) error {
+	msg := fmt.Sprintf(format, a...)
+
+	if o.WarnAsError {
+		allowed := false
+		for _, w := range o.AllowedWarnings {
+			if w == id {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%s [%s] (warning promoted to error by --warn-as-error)", msg, id)
+		}
+	}
+
+	sylog.Warningf("%s [%s]", msg, id)
+	progress.Emit(progress.Event{Type: progress.Warning, Code: string(id), Message: msg})
+	return nil
+}
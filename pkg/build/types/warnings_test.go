@@ -0,0 +1,101 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package types
+
+import (
+	"testing"
+)
+
+func TestParseWarningID(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		id      WarningID
+		wantErr bool
+	}{
+		{
+			name: "Code",
+			s:    "W001",
+			id:   WarnDeprecatedSection,
+		},
+		{
+			name: "LowerCaseCode",
+			s:    "w003",
+			id:   WarnUnreachablePost,
+		},
+		{
+			name: "Identifier",
+			s:    "W002_rpm_scripts",
+			id:   WarnRPMScripts,
+		},
+		{
+			name:    "Unknown",
+			s:       "W999",
+			wantErr: true,
+		},
+		{
+			name:    "BadIdentifier",
+			s:       "W001_bad",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := ParseWarningID(tt.s)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if id != tt.id {
+				t.Fatalf("got %s instead of %s", id, tt.id)
+			}
+		})
+	}
+}
+
+func TestWarnf(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{
+			name: "Warning",
+			opts: Options{},
+		},
+		{
+			name:    "Error",
+			opts:    Options{WarnAsError: true},
+			wantErr: true,
+		},
+		{
+			name:    "OtherAllowed",
+			opts:    Options{WarnAsError: true, AllowedWarnings: []WarningID{WarnRPMScripts}},
+			wantErr: true,
+		},
+		{
+			name: "Allowed",
+			opts: Options{WarnAsError: true, AllowedWarnings: []WarningID{WarnDeprecatedSection}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.Warnf(WarnDeprecatedSection, "test %s", "warning")
+			if tt.wantErr && err == nil {
+				t.Errorf("unexpected success")
+			} else if !tt.wantErr && err != nil {
+				t.Errorf("unexpected error: %s", err)
+			}
+		})
+	}
+}
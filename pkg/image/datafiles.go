@@ -0,0 +1,31 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+// DataFilesPartition is the name of the SIF data partition holding the
+// files of the %datafile definition section, a squashfs image with one
+// file per data file name mounted read-only at runtime.
+const DataFilesPartition = "datafiles"
+
+// GetDataFilesPartition returns the partition holding the data files
+// stored in the image by the %datafile definition section, or nil if
+// there is none, only SIF images contain data files.
+func GetDataFilesPartition(img *Image) (*Section, error) {
+	if img.Type != SIF {
+		return nil, nil
+	}
+
+	partitions, err := img.GetDataPartitions()
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range partitions {
+		if p.Name == DataFilesPartition && p.Type == SQUASHFS {
+			return &p, nil
+		}
+	}
+	return nil, nil
+}
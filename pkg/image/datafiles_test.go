@@ -0,0 +1,51 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func TestGetDataFilesPartition(t *testing.T) {
+	f, err := ioutil.TempFile("", "datafiles-")
+	if err != nil {
+		t.Fatalf("failed to create temporary file: %s", err)
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	img := &Image{
+		Path:  f.Name(),
+		Type:  SIF,
+		File:  f,
+		Usage: RootFsUsage | DataUsage,
+		Partitions: []Section{
+			{Name: RootFs, Type: SQUASHFS, Offset: 0, Size: 4096, AllowedUsage: RootFsUsage},
+			{Name: "data", Type: EXT3, Offset: 4096, Size: 4096, AllowedUsage: DataUsage},
+			{Name: DataFilesPartition, Type: SQUASHFS, Offset: 8192, Size: 4096, AllowedUsage: DataUsage},
+		},
+	}
+
+	part, err := GetDataFilesPartition(img)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if part == nil || part.Offset != 8192 {
+		t.Fatalf("unexpected data files partition: %+v", part)
+	}
+
+	img.Partitions = img.Partitions[:2]
+	if part, err := GetDataFilesPartition(img); err != nil || part != nil {
+		t.Errorf("unexpected data files partition %+v (%v) without data files", part, err)
+	}
+
+	img.Type = SANDBOX
+	if part, err := GetDataFilesPartition(img); err != nil || part != nil {
+		t.Errorf("unexpected data files partition %+v (%v) for a sandbox", part, err)
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+// DefaultBindsSection is the name of the image section holding
+// the default bind paths recorded at build time.
+const DefaultBindsSection = "default-binds.json"
+
+// requiredBindOption marks a default bind path as required.
+const requiredBindOption = "required"
+
+// DefaultBind describes a bind path applied by default when
+// running a container.
+type DefaultBind struct {
+	// Bind is the bind path specification src[:dest[:opts]].
+	Bind string `json:"bind"`
+	// Required aborts the container execution if the bind
+	// path source doesn't exist on the host.
+	Required bool `json:"required"`
+}
+
+// Source returns the host path of the default bind path.
+func (b DefaultBind) Source() string {
+	return strings.SplitN(b.Bind, ":", 2)[0]
+}
+
+// ParseDefaultBind parses a default bind path specification with the
+// format src[:dest[:opts]], opts is a comma separated list of the ro and
+// rw bind options, it may also contain "required" to mark the bind path
+// as required. Unlike --bind, a single bind path is accepted.
+func ParseDefaultBind(spec string) (DefaultBind, error) {
+	var b DefaultBind
+
+	splitted := strings.SplitN(spec, ":", 3)
+	if splitted[0] == "" {
+		return b, fmt.Errorf("empty bind source for default bind path %q", spec)
+	}
+	for _, path := range splitted[:2] {
+		if strings.Contains(path, ",") {
+			return b, fmt.Errorf("default bind path %q must contain a single bind path", spec)
+		}
+	}
+
+	if len(splitted) == 3 {
+		var opts []string
+		for _, opt := range strings.Split(splitted[2], ",") {
+			switch opt {
+			case requiredBindOption:
+				b.Required = true
+			case "ro", "rw":
+				opts = append(opts, opt)
+			default:
+				return b, fmt.Errorf("invalid option %q for default bind path %q", opt, spec)
+			}
+		}
+		splitted = splitted[:2]
+		if len(opts) > 0 {
+			splitted = append(splitted, strings.Join(opts, ","))
+		}
+	}
+	b.Bind = strings.Join(splitted, ":")
+
+	return b, nil
+}
+
+// GetDefaultBinds returns the default bind paths recorded in the
+// image, nil is returned if the image doesn't contain any.
+func GetDefaultBinds(img *Image) ([]DefaultBind, error) {
+	r, err := NewSectionReader(img, DefaultBindsSection, -1)
+	if err == ErrNoSection {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("while reading %s section: %s", DefaultBindsSection, err)
+	}
+
+	var binds []DefaultBind
+	if err := json.Unmarshal(data, &binds); err != nil {
+		return nil, fmt.Errorf("while decoding %s section: %s", DefaultBindsSection, err)
+	}
+	return binds, nil
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"testing"
+)
+
+func TestParseDefaultBind(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		bind    DefaultBind
+		source  string
+		wantErr bool
+	}{
+		{
+			name:   "Source",
+			spec:   "/data",
+			bind:   DefaultBind{Bind: "/data"},
+			source: "/data",
+		},
+		{
+			name:   "Destination",
+			spec:   "/data:/mnt",
+			bind:   DefaultBind{Bind: "/data:/mnt"},
+			source: "/data",
+		},
+		{
+			name:   "Options",
+			spec:   "/data:/mnt:ro",
+			bind:   DefaultBind{Bind: "/data:/mnt:ro"},
+			source: "/data",
+		},
+		{
+			name:   "Required",
+			spec:   "/data:/mnt:required",
+			bind:   DefaultBind{Bind: "/data:/mnt", Required: true},
+			source: "/data",
+		},
+		{
+			name:   "RequiredOptions",
+			spec:   "/data:/mnt:ro,required",
+			bind:   DefaultBind{Bind: "/data:/mnt:ro", Required: true},
+			source: "/data",
+		},
+		{
+			name:    "EmptySource",
+			spec:    ":/mnt",
+			wantErr: true,
+		},
+		{
+			name:    "MultipleBinds",
+			spec:    "/data,/opt",
+			wantErr: true,
+		},
+		{
+			name:    "MultipleBindsDestination",
+			spec:    "/data:/mnt,/opt",
+			wantErr: true,
+		},
+		{
+			name:    "MultipleBindsOptions",
+			spec:    "/data:/mnt:ro,/opt",
+			wantErr: true,
+		},
+		{
+			name:    "UnknownOption",
+			spec:    "/data:/mnt:rx",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := ParseDefaultBind(tt.spec)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("unexpected success")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if b != tt.bind {
+				t.Errorf("got %+v instead of %+v", b, tt.bind)
+			}
+			if b.Source() != tt.source {
+				t.Errorf("got source %s instead of %s", b.Source(), tt.source)
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/sylabs/singularity/pkg/image/unpacker"
+	"github.com/sylabs/singularity/pkg/util/loop"
+)
+
+// SIFImage provides read access to the squashfs root filesystem
+// partition of a SIF image, it allows external tools to inspect
+// the image content the same way Singularity does.
+type SIFImage struct {
+	img  *Image
+	part *Section
+}
+
+// OpenSIF opens the SIF image found at path for read, the image must
+// contain a squashfs root filesystem partition. The returned SIFImage
+// must be closed with Close once done.
+func OpenSIF(path string) (*SIFImage, error) {
+	img, err := Init(path, false)
+	if err != nil {
+		return nil, fmt.Errorf("while opening image %s: %s", path, err)
+	}
+
+	if img.Type != SIF {
+		img.File.Close()
+		return nil, fmt.Errorf("%s is not a SIF image", path)
+	}
+
+	part, err := img.GetRootFsPartition()
+	if err != nil {
+		img.File.Close()
+		return nil, fmt.Errorf("while getting root filesystem in %s: %s", path, err)
+	}
+	if part.Type != SQUASHFS {
+		img.File.Close()
+		return nil, fmt.Errorf("unsupported root filesystem type in %s: only squashfs is supported", path)
+	}
+
+	return &SIFImage{img: img, part: part}, nil
+}
+
+// Image returns the underlying image.
+func (s *SIFImage) Image() *Image {
+	return s.img
+}
+
+// Close closes the SIF image.
+func (s *SIFImage) Close() error {
+	return s.img.File.Close()
+}
+
+// MountSquashfs mounts the squashfs root filesystem partition read-only
+// on mountpoint through a loop device, this requires privileges to
+// mount filesystems. It returns a function to unmount the partition,
+// the loop device is automatically released once unmounted.
+func (s *SIFImage) MountSquashfs(ctx context.Context, mountpoint string) (func() error, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	loopDev := &loop.Device{
+		MaxLoopDevices: 256,
+		Shared:         true,
+		Info: &loop.Info64{
+			SizeLimit: s.part.Size,
+			Offset:    s.part.Offset,
+			Flags:     loop.FlagsAutoClear | loop.FlagsReadOnly,
+		},
+	}
+	idx := 0
+	if err := loopDev.AttachFromFile(s.img.File, os.O_RDONLY, &idx); err != nil {
+		return nil, fmt.Errorf("failed to attach image %s: %s", s.img.Path, err)
+	}
+	path := fmt.Sprintf("/dev/loop%d", idx)
+
+	// the mount holds a reference on the loop device, closing the loop
+	// device is enough to release it on error or once unmounted
+	defer loopDev.Close()
+
+	if err := syscall.Mount(path, mountpoint, "squashfs", syscall.MS_RDONLY|syscall.MS_NODEV, "errors=remount-ro"); err != nil {
+		return nil, fmt.Errorf("failed to mount %s on %s: %s", path, mountpoint, err)
+	}
+
+	return func() error {
+		if err := syscall.Unmount(mountpoint, syscall.MNT_DETACH); err != nil {
+			return fmt.Errorf("failed to unmount %s: %s", mountpoint, err)
+		}
+		return nil
+	}, nil
+}
+
+// ReadFile returns the content of the regular file found at path in the
+// squashfs root filesystem partition. The file is extracted with unsquashfs
+// and doesn't require privileges.
+func (s *SIFImage) ReadFile(path string) ([]byte, error) {
+	name := strings.TrimPrefix(filepath.Clean("/"+path), "/")
+	if name == "" {
+		return nil, fmt.Errorf("%s is not a file", path)
+	}
+
+	dir, err := ioutil.TempDir("", "sif-read-")
+	if err != nil {
+		return nil, fmt.Errorf("could not create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	dest := filepath.Join(dir, "rootfs")
+	reader := getSectionReader(s.img.File, *s.part)
+	if err := unpacker.NewSquashfs().ExtractFiles([]string{name}, reader, dest); err != nil {
+		return nil, fmt.Errorf("while extracting %s: %s", path, err)
+	}
+
+	file := filepath.Join(dest, name)
+	// don't follow symlinks pointing to host files
+	fi, err := os.Lstat(file)
+	if err != nil {
+		return nil, fmt.Errorf("while reading %s: %s", path, err)
+	} else if !fi.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+
+	return ioutil.ReadFile(file)
+}
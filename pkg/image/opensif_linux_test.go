@@ -0,0 +1,137 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the sources of this project regarding your
+// rights to use or distribute this software.
+
+package image
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/sylabs/sif/pkg/sif"
+	"github.com/sylabs/singularity/internal/pkg/test"
+	"github.com/sylabs/singularity/pkg/image/unpacker"
+)
+
+// testSquashFile is the file found in testSquash along with its content prefix.
+const (
+	testSquashFile    = "examplefile"
+	testSquashContent = "Example File Contents"
+)
+
+func createRootfsSIF(t *testing.T) string {
+	fp, err := os.Open(testSquash)
+	if err != nil {
+		t.Fatalf("failed to open %s: %s", testSquash, err)
+	}
+	defer fp.Close()
+
+	primPart := sif.DescriptorInput{
+		Datatype: sif.DataPartition,
+		Groupid:  sif.DescrDefaultGroup,
+		Link:     sif.DescrUnusedLink,
+		Fname:    "primPart",
+		Fp:       fp,
+		Extra: *bytes.NewBuffer([]byte{
+			0x01, 0x00, 0x00, 0x00, // fstype
+			0x02, 0x00, 0x00, 0x00, // part type
+		}),
+	}
+	primPart.Extra.WriteString(sif.GetSIFArch(runtime.GOARCH))
+
+	return createSIF(t, []sif.DescriptorInput{primPart}, false)
+}
+
+func TestOpenSIF(t *testing.T) {
+	tests := []struct {
+		name string
+		path string
+	}{
+		{
+			name: "NotFound",
+			path: "./testdata/not-found.sif",
+		},
+		{
+			name: "NotSIF",
+			path: "./testdata/squashfs.v4",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := OpenSIF(tt.path)
+			if err == nil {
+				s.Close()
+				t.Fatalf("unexpected success opening %s", tt.path)
+			}
+		})
+	}
+}
+
+func TestSIFImageReadFile(t *testing.T) {
+	if !unpacker.NewSquashfs().HasUnsquashfs() {
+		t.Skip("unsquashfs not found")
+	}
+
+	path := createRootfsSIF(t)
+	defer os.Remove(path)
+
+	s, err := OpenSIF(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening %s: %s", path, err)
+	}
+	defer s.Close()
+
+	b, err := s.ReadFile("/" + testSquashFile)
+	if err != nil {
+		t.Fatalf("unexpected error reading %s: %s", testSquashFile, err)
+	}
+	if !bytes.HasPrefix(b, []byte(testSquashContent)) {
+		t.Errorf("unexpected content %q for %s", b, testSquashFile)
+	}
+
+	if _, err := s.ReadFile("/not-found"); err == nil {
+		t.Errorf("unexpected success reading /not-found")
+	}
+}
+
+func TestSIFImageMountSquashfs(t *testing.T) {
+	test.EnsurePrivilege(t)
+
+	path := createRootfsSIF(t)
+	defer os.Remove(path)
+
+	s, err := OpenSIF(path)
+	if err != nil {
+		t.Fatalf("unexpected error opening %s: %s", path, err)
+	}
+	defer s.Close()
+
+	mnt, err := ioutil.TempDir("", "sif-mount-")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %s", err)
+	}
+	defer os.RemoveAll(mnt)
+
+	umount, err := s.MountSquashfs(context.Background(), mnt)
+	if err != nil {
+		t.Fatalf("unexpected error mounting %s: %s", path, err)
+	}
+
+	b, err := ioutil.ReadFile(filepath.Join(mnt, testSquashFile))
+	if err != nil {
+		t.Errorf("unexpected error reading %s: %s", testSquashFile, err)
+	} else if !bytes.HasPrefix(b, []byte(testSquashContent)) {
+		t.Errorf("unexpected content %q for %s", b, testSquashFile)
+	}
+
+	if err := umount(); err != nil {
+		t.Errorf("unexpected error unmounting %s: %s", mnt, err)
+	}
+}
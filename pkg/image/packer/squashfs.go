@@ -8,12 +8,16 @@ package packer
 import (
 	"bytes"
 	"fmt"
+	"os"
 	"os/exec"
 )
 
 // Squashfs represents a squashfs packer
 type Squashfs struct {
 	MksquashfsPath string
+	// Env holds the environment variables added to the mksquashfs
+	// environment, as KEY=VALUE strings.
+	Env []string
 }
 
 // NewSquashfs initializes and returns a Squashfs packer instance
@@ -41,6 +45,9 @@ func (s Squashfs) create(files []string, dest string, opts []string) error {
 	args = append(args, opts...)
 
 	cmd := exec.Command(s.MksquashfsPath, args...)
+	if len(s.Env) > 0 {
+		cmd.Env = append(os.Environ(), s.Env...)
+	}
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
 		return fmt.Errorf("create command failed: %v: %s", err, stderr.String())
@@ -65,22 +65,11 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 
 	groupID := -1
 
-	// Get the default system partition image
-	for _, desc := range fimg.DescrArr {
-		if !desc.Used {
-			continue
-		}
-		ptype, err := desc.GetPartType()
-		if err != nil {
-			continue
-		}
-		if ptype != sif.PartPrimSys {
-			continue
-		}
-		fstype, err := desc.GetFsType()
-		if err != nil {
-			continue
-		}
+	// Get the default system partition image, or the system partition
+	// matching the host architecture for multi-architecture images
+	if desc := rootfsDescriptor(&fimg); desc != nil {
+		// file system type was checked by rootfsDescriptor
+		fstype, _ := desc.GetFsType()
 
 		// checks if the partition length is greater that the file
 		// size which may reveal a corrupted image (see issue #3996)
@@ -97,7 +86,10 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 		// CompatibleWith call will also check that the current machine
 		// has persistent emulation enabled in /proc/sys/fs/binfmt_misc to
 		// be able to execute container process correctly
-		sifArch := string(fimg.Header.Arch[:sif.HdrArchLen-1])
+		sifArch := partitionArch(desc)
+		if sifArch == "" {
+			sifArch = string(fimg.Header.Arch[:sif.HdrArchLen-1])
+		}
 		goArch := sif.GetGoArch(sifArch)
 		if sifArch != sif.HdrArchUnknown && !machine.CompatibleWith(goArch) {
 			return fmt.Errorf("the image's architecture (%s) could not run on the host's (%s)", goArch, runtime.GOARCH)
@@ -115,7 +107,6 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 		}
 
 		groupID = int(desc.Groupid)
-		break
 	}
 
 	for _, desc := range fimg.DescrArr {
@@ -189,6 +180,50 @@ func (f *sifFormat) initializer(img *Image, fi os.FileInfo) error {
 	return nil
 }
 
+// rootfsDescriptor returns the primary system partition descriptor or,
+// if the primary system partition doesn't match the host architecture,
+// a system partition matching it, as found in multi-architecture images.
+func rootfsDescriptor(fimg *sif.FileImage) *sif.Descriptor {
+	var prim, native *sif.Descriptor
+
+	for i, desc := range fimg.DescrArr {
+		if !desc.Used {
+			continue
+		}
+		ptype, err := desc.GetPartType()
+		if err != nil {
+			continue
+		}
+		if _, err := desc.GetFsType(); err != nil {
+			continue
+		}
+		switch ptype {
+		case sif.PartPrimSys:
+			if prim == nil {
+				prim = &fimg.DescrArr[i]
+			}
+		case sif.PartSystem:
+			if native == nil && sif.GetGoArch(partitionArch(&desc)) == runtime.GOARCH {
+				native = &fimg.DescrArr[i]
+			}
+		}
+	}
+
+	if prim != nil && native != nil && sif.GetGoArch(partitionArch(prim)) != runtime.GOARCH {
+		return native
+	}
+	return prim
+}
+
+// partitionArch returns the SIF architecture of a partition descriptor.
+func partitionArch(desc *sif.Descriptor) string {
+	arch, err := desc.GetArch()
+	if err != nil {
+		return ""
+	}
+	return string(arch[:sif.HdrArchLen-1])
+}
+
 func (f *sifFormat) openMode(writable bool) int {
 	if writable {
 		return os.O_RDWR
@@ -12,6 +12,7 @@ import (
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 )
 
 // Squashfs represents a squashfs unpacker.
@@ -31,9 +32,12 @@ func (s *Squashfs) HasUnsquashfs() bool {
 	return s.UnsquashfsPath != ""
 }
 
-func (s *Squashfs) extract(files []string, reader io.Reader, dest string) error {
+// run executes unsquashfs with options followed by the squashfs filesystem
+// read from reader and the files arguments, a staging file is created in
+// tmpdir if reader is not a file.
+func (s *Squashfs) run(options []string, files []string, reader io.Reader, tmpdir string) ([]byte, error) {
 	if !s.HasUnsquashfs() {
-		return fmt.Errorf("could not extract squashfs data, unsquashfs not found")
+		return nil, fmt.Errorf("unsquashfs not found")
 	}
 
 	// pipe over stdin by default
@@ -41,35 +45,44 @@ func (s *Squashfs) extract(files []string, reader io.Reader, dest string) error
 	filename := "/proc/self/fd/0"
 
 	if _, ok := reader.(*os.File); !ok {
-		// use the destination parent directory to store the
-		// temporary archive
-		tmpdir := filepath.Dir(dest)
-
 		// unsquashfs doesn't support to send file content over
 		// a stdin pipe since it use lseek for every read it does
 		tmp, err := ioutil.TempFile(tmpdir, "archive-")
 		if err != nil {
-			return fmt.Errorf("failed to create staging file: %s", err)
+			return nil, fmt.Errorf("failed to create staging file: %s", err)
 		}
 		filename = tmp.Name()
 		stdin = false
 		defer os.Remove(filename)
 
 		if _, err := io.Copy(tmp, reader); err != nil {
-			return fmt.Errorf("failed to copy content in staging file: %s", err)
+			return nil, fmt.Errorf("failed to copy content in staging file: %s", err)
 		}
 		if err := tmp.Close(); err != nil {
-			return fmt.Errorf("failed to close staging file: %s", err)
+			return nil, fmt.Errorf("failed to close staging file: %s", err)
 		}
 	}
 
-	args := []string{"-f", "-d", dest, filename}
+	args := append([]string{}, options...)
+	args = append(args, filename)
 	args = append(args, files...)
 	cmd := exec.Command(s.UnsquashfsPath, args...)
 	if stdin {
 		cmd.Stdin = reader
 	}
-	if o, err := cmd.CombinedOutput(); err != nil {
+	return cmd.CombinedOutput()
+}
+
+func (s *Squashfs) extract(files []string, reader io.Reader, dest string) error {
+	if !s.HasUnsquashfs() {
+		return fmt.Errorf("could not extract squashfs data, unsquashfs not found")
+	}
+
+	// use the destination parent directory to store the
+	// temporary archive
+	tmpdir := filepath.Dir(dest)
+
+	if o, err := s.run([]string{"-f", "-d", dest}, files, reader, tmpdir); err != nil {
 		return fmt.Errorf("extract command failed: %s: %s", string(o), err)
 	}
 	return nil
@@ -89,3 +102,80 @@ func (s *Squashfs) ExtractFiles(files []string, reader io.Reader, dest string) e
 	}
 	return s.extract(files, reader, dest)
 }
+
+// FileInfo describes a file found in a squashfs filesystem.
+type FileInfo struct {
+	// Path is the absolute path of the file in the filesystem.
+	Path string
+	// Mode is the file mode string as displayed by ls (e.g. -rwxr-xr-x).
+	Mode string
+	// Owner is the numeric owner of the file with the uid/gid format.
+	Owner string
+}
+
+// IsSetuid returns true if the file has the set-user-ID or
+// set-group-ID bit set.
+func (fi FileInfo) IsSetuid() bool {
+	return len(fi.Mode) == 10 && strings.ContainsAny(fi.Mode[3:4]+fi.Mode[6:7], "sS")
+}
+
+// IsWorldWritable returns true if the file is writable by others,
+// symlinks are never considered writable.
+func (fi FileInfo) IsWorldWritable() bool {
+	return len(fi.Mode) == 10 && fi.Mode[0] != 'l' && fi.Mode[8] == 'w'
+}
+
+// List lists the files found in a squashfs filesystem read from reader,
+// a staging file may be created in tmpdir.
+func (s *Squashfs) List(reader io.Reader, tmpdir string) ([]FileInfo, error) {
+	if !s.HasUnsquashfs() {
+		return nil, fmt.Errorf("could not list squashfs data, unsquashfs not found")
+	}
+
+	o, err := s.run([]string{"-lln"}, nil, reader, tmpdir)
+	if err != nil {
+		return nil, fmt.Errorf("list command failed: %s: %s", string(o), err)
+	}
+
+	return parseList(string(o)), nil
+}
+
+// listRoot is the root directory name displayed by unsquashfs.
+const listRoot = "squashfs-root"
+
+// parseList parses the unsquashfs -lln output with lines formatted like:
+// -rwxr-xr-x 0/0 1234 2020-01-01 00:00 squashfs-root/bin/busybox
+func parseList(output string) []FileInfo {
+	var list []FileInfo
+
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		// skip header and summary lines
+		if len(fields) < 6 || len(fields[0]) != 10 || !strings.Contains(fields[1], "/") {
+			continue
+		}
+		// device files report major,minor instead of size
+		// which could be separated by spaces
+		idx := strings.Index(line, fields[1]) + len(fields[1])
+		rest := strings.Fields(line[idx:])
+		for len(rest) > 0 && !strings.Contains(rest[0], "-") {
+			rest = rest[1:]
+		}
+		// rest starts with date, then time and path
+		if len(rest) < 3 {
+			continue
+		}
+		path := strings.Join(rest[2:], " ")
+		if fields[0][0] == 'l' {
+			path = strings.SplitN(path, " -> ", 2)[0]
+		}
+		path = "/" + strings.TrimPrefix(strings.TrimPrefix(path, listRoot), "/")
+		list = append(list, FileInfo{
+			Path:  path,
+			Mode:  fields[0],
+			Owner: fields[1],
+		})
+	}
+
+	return list
+}
@@ -106,3 +106,42 @@ func TestSquashfs(t *testing.T) {
 		t.Errorf("file extraction failed, %s is missing", path)
 	}
 }
+
+func TestParseList(t *testing.T) {
+	output := `Parallel unsquashfs: Using 4 processors
+3 inodes (2 blocks) to write
+
+drwxr-xr-x 0/0                56 2020-07-01 10:00 squashfs-root
+-rwsr-xr-x 0/0           1071224 2020-07-01 10:00 squashfs-root/bin/busy box
+crw-rw-rw- 0/0             1,  3 2020-07-01 10:00 squashfs-root/dev/null
+lrwxrwxrwx 0/0                12 2020-07-01 10:00 squashfs-root/bin/sh -> /bin/busybox
+drwxrwxrwt 0/0                 3 2020-07-01 10:00 squashfs-root/tmp
+`
+	want := []FileInfo{
+		{Path: "/", Mode: "drwxr-xr-x", Owner: "0/0"},
+		{Path: "/bin/busy box", Mode: "-rwsr-xr-x", Owner: "0/0"},
+		{Path: "/dev/null", Mode: "crw-rw-rw-", Owner: "0/0"},
+		{Path: "/bin/sh", Mode: "lrwxrwxrwx", Owner: "0/0"},
+		{Path: "/tmp", Mode: "drwxrwxrwt", Owner: "0/0"},
+	}
+
+	list := parseList(output)
+	if len(list) != len(want) {
+		t.Fatalf("got %d entries instead of %d: %v", len(list), len(want), list)
+	}
+	for i := range want {
+		if list[i] != want[i] {
+			t.Errorf("got %+v instead of %+v", list[i], want[i])
+		}
+	}
+
+	if !list[1].IsSetuid() || list[1].IsWorldWritable() {
+		t.Errorf("wrong setuid/writable status for %s", list[1].Path)
+	}
+	if list[3].IsWorldWritable() {
+		t.Errorf("symlink reported as world writable")
+	}
+	if !list[4].IsWorldWritable() || list[4].IsSetuid() {
+		t.Errorf("wrong setuid/writable status for %s", list[4].Path)
+	}
+}
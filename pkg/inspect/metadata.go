@@ -5,12 +5,18 @@
 
 package inspect
 
+import (
+	"encoding/json"
+	"time"
+)
+
 // ContainerType defines the container type (used by default).
 const ContainerType = "container"
 
 // AppAttributes describes app metadata attributes.
 type AppAttributes struct {
 	Environment map[string]string `json:"environment,omitempty"`
+	Variables   []Variable        `json:"variables,omitempty"`
 	Labels      map[string]string `json:"labels,omitempty"`
 	Runscript   string            `json:"runscript,omitempty"`
 	Test        string            `json:"test,omitempty"`
@@ -19,14 +25,50 @@ type AppAttributes struct {
 
 // Attributes describes metadata attributes of Singularity containers.
 type Attributes struct {
-	Apps        map[string]*AppAttributes `json:"apps,omitempty"`
-	Environment map[string]string         `json:"environment,omitempty"`
-	Labels      map[string]string         `json:"labels,omitempty"`
-	Runscript   string                    `json:"runscript,omitempty"`
-	Test        string                    `json:"test,omitempty"`
-	Helpfile    string                    `json:"helpfile,omitempty"`
-	Deffile     string                    `json:"deffile,omitempty"`
-	Startscript string                    `json:"startscript,omitempty"`
+	Apps          map[string]*AppAttributes  `json:"apps,omitempty"`
+	Environment   map[string]string          `json:"environment,omitempty"`
+	Variables     []Variable                 `json:"variables,omitempty"`
+	Labels        map[string]string          `json:"labels,omitempty"`
+	Runscript     string                     `json:"runscript,omitempty"`
+	Test          string                     `json:"test,omitempty"`
+	Helpfile      string                     `json:"helpfile,omitempty"`
+	Deffile       string                     `json:"deffile,omitempty"`
+	Startscript   string                     `json:"startscript,omitempty"`
+	DeffileDigest string                     `json:"deffileDigest,omitempty"`
+	DeffileArgs   map[string]string          `json:"deffileArgs,omitempty"`
+	Architectures []string                   `json:"architectures,omitempty"`
+	Layers        []string                   `json:"layers,omitempty"`
+	Annotations   map[string]json.RawMessage `json:"annotations,omitempty"`
+	Signatures    []Signature                `json:"signatures"`
+}
+
+// Variable describes an environment variable set by the environment
+// scripts of a container.
+type Variable struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+	// Source is the path of the environment script setting the variable.
+	Source string `json:"source"`
+	// Runtime is set when the value depends on the runtime environment,
+	// references to runtime variables being kept as ${NAME}.
+	Runtime bool `json:"runtime,omitempty"`
+}
+
+// Signature describes a signature of SIF object(s).
+type Signature struct {
+	// ID is the ID of the signature object.
+	ID uint32 `json:"id"`
+	// GroupID or ObjectID identifies the signed object(s).
+	GroupID  uint32 `json:"groupId,omitempty"`
+	ObjectID uint32 `json:"objectId,omitempty"`
+	// Fingerprint is the fingerprint of the signing key.
+	Fingerprint string `json:"fingerprint"`
+	// Time is the signing time, if found in the signature.
+	Time *time.Time `json:"time,omitempty"`
+	// Verified is set when the signature has been checked against a
+	// keyring, VerifyError describing the failure if not verified.
+	Verified    *bool  `json:"verified,omitempty"`
+	VerifyError string `json:"verifyError,omitempty"`
 }
 
 // Data holds the container metadata attributes.
@@ -41,6 +83,10 @@ type Metadata struct {
 }
 
 func (m *Metadata) AddApp(name string) {
+	// the apps map is only set for images with apps
+	if m.Attributes.Apps == nil {
+		m.Attributes.Apps = make(map[string]*AppAttributes)
+	}
 	if _, ok := m.Attributes.Apps[name]; !ok {
 		attr := &AppAttributes{}
 		attr.Environment = make(map[string]string)
@@ -55,6 +101,5 @@ func NewMetadata() *Metadata {
 	format.Type = ContainerType
 	format.Attributes.Labels = make(map[string]string)
 	format.Attributes.Environment = make(map[string]string)
-	format.Attributes.Apps = make(map[string]*AppAttributes)
 	return format
 }
@@ -0,0 +1,15 @@
+// Copyright (c) 2020, Sylabs Inc. All rights reserved.
+// This software is licensed under a 3-clause BSD license. Please consult the
+// LICENSE.md file distributed with the URIs of this project regarding your
+// rights to use or distribute this software.
+
+package build
+
+// RegisterConveyorPacker callback is called before a local build
+// starts to register custom bootstrap agents with
+// types.RegisterConveyorPacker from pkg/build/types. The registered
+// agent is then selected with the definition file `Bootstrap:` header
+// keyword.
+// This callback is called in:
+// - cmd/internal/cli/build_linux.go
+type RegisterConveyorPacker func() error
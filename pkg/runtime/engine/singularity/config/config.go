@@ -128,6 +128,7 @@ type JSONConfig struct {
 	Hostname          string            `json:"hostname,omitempty"`
 	Network           string            `json:"network,omitempty"`
 	DNS               string            `json:"dns,omitempty"`
+	DataFilesDir      string            `json:"dataFilesDir,omitempty"`
 	Cwd               string            `json:"cwd,omitempty"`
 	SessionLayer      string            `json:"sessionLayer,omitempty"`
 	ConfigurationFile string            `json:"configurationFile,omitempty"`
@@ -587,6 +588,18 @@ func (e *EngineConfig) GetDNS() string {
 	return e.JSON.DNS
 }
 
+// SetDataFilesDir sets the container directory where the data files
+// stored in the SIF image are exposed, an empty path disables it.
+func (e *EngineConfig) SetDataFilesDir(dir string) {
+	e.JSON.DataFilesDir = dir
+}
+
+// GetDataFilesDir retrieves the container directory where the data
+// files stored in the SIF image are exposed.
+func (e *EngineConfig) GetDataFilesDir() string {
+	return e.JSON.DataFilesDir
+}
+
 // SetImageList sets image list containing opened images.
 func (e *EngineConfig) SetImageList(list []image.Image) {
 	e.JSON.ImageList = list
@@ -23,6 +23,12 @@ func PublicKeyRing() (openpgp.KeyRing, error) {
 	return NewHandle("").LoadPubKeyring()
 }
 
+// KeyRingFromFile retrieves a KeyRing from the file at path, the file might be
+// in binary or ascii armored format.
+func KeyRingFromFile(path string) (openpgp.KeyRing, error) {
+	return loadKeysFromFile(path)
+}
+
 // hybridKeyRing is keyring made up of a local keyring as well as a keyserver. The type satisfies
 // the openpgp.KeyRing interface.
 type hybridKeyRing struct {
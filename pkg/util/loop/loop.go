@@ -10,6 +10,8 @@ type Device struct {
 	MaxLoopDevices int
 	Shared         bool
 	Info           *Info64
+	// fd is the loop device file descriptor, set once attached
+	fd *int
 }
 
 // Loop device flags values
@@ -94,6 +94,7 @@ func (loop *Device) AttachFromFile(image *os.File, mode int, number *int) error
 				// be sure that the loop device won't be released between this
 				// check and the mount of the filesystem
 				sylog.Debugf("Sharing loop device %d", device)
+				loop.fd = &loopFd
 				return nil
 			}
 			syscall.Close(loopFd)
@@ -108,6 +109,8 @@ func (loop *Device) AttachFromFile(image *os.File, mode int, number *int) error
 	}
 
 	if _, _, err := syscall.Syscall(syscall.SYS_FCNTL, uintptr(loopFd), syscall.F_SETFD, syscall.FD_CLOEXEC); err != 0 {
+		syscall.Syscall(syscall.SYS_IOCTL, uintptr(loopFd), CmdClrFd, 0)
+		syscall.Close(loopFd)
 		return fmt.Errorf("failed to set close-on-exec on loop device %s: %s", path, err.Error())
 	}
 
@@ -125,14 +128,28 @@ func (loop *Device) AttachFromFile(image *os.File, mode int, number *int) error
 			// best-effort here without error checking because we need the
 			// error from previous ioctl call
 			syscall.Syscall(syscall.SYS_IOCTL, uintptr(loopFd), CmdClrFd, 0)
+			syscall.Close(loopFd)
 			return fmt.Errorf("failed to set loop flags on loop device: %s", syscall.Errno(err))
 		}
 		break
 	}
 
+	loop.fd = &loopFd
 	return nil
 }
 
+// Close closes the loop device file descriptor opened by AttachFromFile
+// or AttachFromPath. A loop device attached with FlagsAutoClear is
+// released once closed if it's not mounted, otherwise once unmounted.
+func (loop *Device) Close() error {
+	if loop.fd == nil {
+		return nil
+	}
+	fd := *loop.fd
+	loop.fd = nil
+	return syscall.Close(fd)
+}
+
 // AttachFromPath finds a free loop device, opens it, and stores file descriptor
 // of opened image path
 func (loop *Device) AttachFromPath(image string, mode int, number *int) error {
@@ -61,6 +61,7 @@ type File struct {
 	MksquashfsMem           string   `directive:"mksquashfs mem"`
 	CryptsetupPath          string   `directive:"cryptsetup path"`
 	ImageDriver             string   `directive:"image driver"`
+	LibraryDefaultHost      string   `directive:"library default host"`
 }
 
 const TemplateAsset = `# SINGULARITY.CONF
@@ -350,4 +351,14 @@ shared loop devices = {{ if eq .SharedLoopDevices true }}yes{{ else }}no{{ end }
 # If the driver name specified has not been registered via a plugin installation
 # the run-time will abort.
 image driver = {{ .ImageDriver }}
+
+# LIBRARY DEFAULT HOST: [STRING]
+# DEFAULT: Undefined
+# This option specifies the library host, or URL, that library:// references
+# without a host are resolved against when building images, as
+# library://project/collection/image:tag. References naming a host, as
+# library://library.example.com/project/collection/image:tag, are not affected.
+# If undefined, the library of the default remote endpoint is used.
+# library default host =
+{{ if ne .LibraryDefaultHost "" }}library default host = {{ .LibraryDefaultHost }}{{ end }}
 `